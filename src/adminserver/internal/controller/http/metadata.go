@@ -5,6 +5,7 @@ import (
 	"adminserver/internal/usecase/pool"
 	"adminserver/internal/usecase/webapi"
 	"common/proto/msg"
+	"common/replication"
 	"common/response"
 	"common/util"
 
@@ -24,8 +25,17 @@ func (mc *MetadataController) Register(r gin.IRouter) {
 		GET("/versions", mc.Versions).
 		POST("/migration", mc.Migration).
 		GET("/slots_detail", mc.SlotsDetail).
+		GET("/slot_rebalance", mc.SlotRebalanceProgress).
+		GET("/slot_rebalance/preview", mc.SlotRebalancePreview).
+		POST("/slot_rebalance", mc.SlotRebalance).
+		GET("/backups", mc.Backups).
+		GET("/replication/rules", mc.ReplicationRules).
+		PUT("/replication/rules", mc.SaveReplicationRule).
+		DELETE("/replication/rules/:bucket", mc.RemoveReplicationRule).
+		GET("/replication/status", mc.ReplicationStatus).
 		GET("/peers", mc.Peers).
 		GET("/buckets", mc.BucketList).
+		GET("/bucket_stat/:name", mc.BucketStat).
 		POST("/create_bucket", mc.CreateBucket).
 		PUT("/update_bucket", mc.UpdateBucket).
 		DELETE("/delete_bucket/:name", mc.DeleteBucket).
@@ -94,6 +104,96 @@ func (mc *MetadataController) SlotsDetail(c *gin.Context) {
 	response.OkJson(detail, c)
 }
 
+// SlotRebalancePreview returns moves to balance slots between groups without executing (dry-run)
+func (mc *MetadataController) SlotRebalancePreview(c *gin.Context) {
+	req := struct {
+		Drain []string `form:"drain"`
+	}{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	plan, err := logic.NewSlotRebalance().Preview(req.Drain)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(plan, c)
+}
+
+func (mc *MetadataController) SlotRebalance(c *gin.Context) {
+	req := struct {
+		Drain []string `json:"drain"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	plan, err := logic.NewSlotRebalance().Start(req.Drain)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(plan, c)
+}
+
+func (mc *MetadataController) SlotRebalanceProgress(c *gin.Context) {
+	plan, err := logic.NewSlotRebalance().Progress()
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(plan, c)
+}
+
+func (mc *MetadataController) Backups(c *gin.Context) {
+	res, err := logic.NewBackup().List(c.Query("group"))
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(res, c)
+}
+
+func (mc *MetadataController) ReplicationRules(c *gin.Context) {
+	res, err := logic.NewReplication().Rules()
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(res, c)
+}
+
+func (mc *MetadataController) SaveReplicationRule(c *gin.Context) {
+	var rule replication.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	if err := logic.NewReplication().SaveRule(&rule); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.Ok(c)
+}
+
+func (mc *MetadataController) RemoveReplicationRule(c *gin.Context) {
+	if err := logic.NewReplication().RemoveRule(c.Param("bucket")); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.Ok(c)
+}
+
+func (mc *MetadataController) ReplicationStatus(c *gin.Context) {
+	res, err := logic.NewReplication().Status()
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(res, c)
+}
+
 func (mc *MetadataController) JoinLeader(c *gin.Context) {
 	req := struct {
 		ServerId string `json:"serverId" binding:"required"`
@@ -157,6 +257,16 @@ func (mc *MetadataController) BucketList(c *gin.Context) {
 		JSON(list)
 }
 
+// BucketStat reports sizes and estimated compression ratio of objects in bucket
+func (mc *MetadataController) BucketStat(c *gin.Context) {
+	stat, err := logic.NewMetadata().BucketStat(c.Param("name"))
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(stat, c)
+}
+
 func (mc *MetadataController) GetConfig(c *gin.Context) {
 	sid := c.Param("serverId")
 	ip, ok := pool.Discovery.GetService(pool.Config.Discovery.MetaServName, sid)
@@ -23,7 +23,10 @@ func (oc *ObjectsController) Register(route gin.IRouter) {
 		PUT("/upload", oc.Upload).
 		POST("/join/:serverId", oc.Join).
 		POST("/leave/:serverId", oc.Leave).
-		GET("/config/:serverId", oc.GetConfig)
+		GET("/config/:serverId", oc.GetConfig).
+		GET("/rebalance", oc.RebalancePlan).
+		POST("/rebalance/pause", oc.PauseRebalance).
+		POST("/rebalance/resume", oc.ResumeRebalance)
 }
 
 func (oc *ObjectsController) Upload(c *gin.Context) {
@@ -98,3 +101,28 @@ func (oc *ObjectsController) GetConfig(c *gin.Context) {
 	}
 	response.Ok(c)
 }
+
+func (oc *ObjectsController) RebalancePlan(c *gin.Context) {
+	plan, err := logic.NewRebalance().Plan()
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(plan, c)
+}
+
+func (oc *ObjectsController) PauseRebalance(c *gin.Context) {
+	if err := logic.NewRebalance().Pause(); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.Ok(c)
+}
+
+func (oc *ObjectsController) ResumeRebalance(c *gin.Context) {
+	if err := logic.NewRebalance().Resume(); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.Ok(c)
+}
@@ -4,6 +4,7 @@ import (
 	"adminserver/internal/entity"
 	"adminserver/internal/usecase/logic"
 	"adminserver/internal/usecase/pool"
+	"common/registry"
 	"common/response"
 	"common/util"
 
@@ -24,7 +25,8 @@ func (ss *ServerStateController) Register(r gin.IRouter) {
 		GET("/overview", ss.Overview).
 		GET("/etcdstat", ss.EtcdStat).
 		GET("/:type/timeline", ss.UsageTimeline).
-		GET("/config", ss.ServerConfig)
+		GET("/config", ss.ServerConfig).
+		GET("/placement", ss.PlacementViolations)
 }
 
 func (ss *ServerStateController) Overview(c *gin.Context) {
@@ -151,3 +153,24 @@ func (ss *ServerStateController) ServerConfig(c *gin.Context) {
 	_, _ = c.Writer.Write(data)
 	response.Ok(c)
 }
+
+func (ss *ServerStateController) PlacementViolations(c *gin.Context) {
+	level := registry.DomainLevel(c.DefaultQuery("level", string(registry.LevelZone)))
+	switch level {
+	case registry.LevelZone, registry.LevelRack, registry.LevelHost:
+	default:
+		response.BadRequestMsg("level must be one of zone, rack and host", c)
+		return
+	}
+	limit := util.ToInt(c.DefaultQuery("limit", "100"))
+	if limit <= 0 {
+		response.BadRequestMsg("limit must gt 0", c)
+		return
+	}
+	res, err := logic.NewPlacement().Violations(level, limit)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(res, c)
+}
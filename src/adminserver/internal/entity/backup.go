@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// BackupManifest describes a backup taken by a metadata group
+type BackupManifest struct {
+	ID        string    `json:"id"`
+	Group     string    `json:"group"`
+	Server    string    `json:"server"`
+	Type      string    `json:"type"`
+	Parent    string    `json:"parent,omitempty"`
+	FromIndex uint64    `json:"fromIndex"`
+	ToIndex   uint64    `json:"toIndex"`
+	LastTime  time.Time `json:"lastTime"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+import "common/proto/msg"
+
+// BucketStat is the aggregate of versions in a bucket on all metadata servers
+type BucketStat struct {
+	*msg.BucketStat
+	Ratio float64 `json:"ratio"` // Ratio is the estimated stored size divided by size of objects
+}
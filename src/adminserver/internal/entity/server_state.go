@@ -2,6 +2,7 @@ package entity
 
 import (
 	"common/datasize"
+	"common/registry"
 	"common/system"
 )
 
@@ -19,3 +20,20 @@ type EtcdStatus struct {
 	Endpoint     string            `json:"endpoint"`
 	IsLearner    bool              `json:"isLearner"`
 }
+
+// PlacementViolation is a stripe whose shards are not spread over enough failure domains
+type PlacementViolation struct {
+	Id         string                      `json:"id"`
+	Version    uint64                      `json:"version"`
+	Hash       string                      `json:"hash"`
+	Locate     []string                    `json:"locate"`
+	Violations []*registry.DomainViolation `json:"violations"`
+}
+
+// StoreStrategy of versions, the same as apiserver's
+const (
+	ECReedSolomon int8 = 1 << iota
+	MultiReplication
+	LocalReconstruction
+	Inline
+)
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"common/hashslot"
+	"time"
+)
+
+// SlotPlan is the moves of hash slots between metadata groups and their progress
+type SlotPlan struct {
+	Drain     []string              `json:"drain"`
+	Groups    []*hashslot.GroupLoad `json:"groups"`
+	Moves     []*hashslot.Move      `json:"moves"`
+	Running   bool                  `json:"running"`
+	CreatedAt time.Time             `json:"createdAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
@@ -0,0 +1,39 @@
+package logic
+
+import (
+	"adminserver/internal/entity"
+	"adminserver/internal/usecase/pool"
+	"common/cst"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type Backup struct{}
+
+func NewBackup() Backup {
+	return Backup{}
+}
+
+// List returns backups of metadata groups ordered by raft index. all groups are listed if group is empty.
+func (Backup) List(group string) (map[string][]*entity.BackupManifest, error) {
+	prefix := cst.EtcdPrefix.FmtBackup(pool.Config.Discovery.Group, group, "")
+	if group == "" {
+		prefix = fmt.Sprintf("%s/%s/", pool.Config.Discovery.Group, cst.EtcdPrefix.Backup)
+	}
+	resp, err := pool.Etcd.Get(context.Background(), prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]*entity.BackupManifest)
+	for _, kv := range resp.Kvs {
+		var m entity.BackupManifest
+		if err = json.Unmarshal(kv.Value, &m); err != nil {
+			return nil, fmt.Errorf("decode backup manifest %s: %w", kv.Key, err)
+		}
+		res[m.Group] = append(res[m.Group], &m)
+	}
+	return res, nil
+}
@@ -176,6 +176,33 @@ func (m Metadata) GetSlotsDetail() (map[string]*hashslot.SlotInfo, error) {
 	return res, nil
 }
 
+// BucketStat aggregates versions of bucket on all metadata servers
+func (m Metadata) BucketStat(bucket string) (*entity.BucketStat, error) {
+	servers := pool.Discovery.GetServicesWith(pool.Config.Discovery.MetaServName, true)
+	total := &msg.BucketStat{Bucket: bucket}
+	mux := sync.Mutex{}
+	dg := util.NewDoneGroup()
+	defer dg.Close()
+	for _, ip := range servers {
+		dg.Add(1)
+		go func(loc string) {
+			defer dg.Done()
+			stat, err := webapi.StatBucket(loc, bucket)
+			if err != nil {
+				dg.Error(err)
+				return
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			total.Merge(stat)
+		}(ip)
+	}
+	if err := dg.WaitUntilError(); err != nil {
+		return nil, err
+	}
+	return &entity.BucketStat{BucketStat: total, Ratio: total.Ratio()}, nil
+}
+
 func (m Metadata) GetMasterServerIds() set.Set {
 	mp := pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true)
 	masters := set.OfMapKeys(mp)
@@ -0,0 +1,77 @@
+package logic
+
+import (
+	"adminserver/internal/entity"
+	"adminserver/internal/usecase/pool"
+	"common/logs"
+	"common/proto/msg"
+	"common/proto/pb"
+	"common/registry"
+	"common/util"
+	"context"
+)
+
+type Placement struct{}
+
+func NewPlacement() Placement {
+	return Placement{}
+}
+
+// Violations scans versions on all metadata servers and returns at most 'limit' stripes
+// which have more shards in a failure domain at level than they can lose.
+func (Placement) Violations(level registry.DomainLevel, limit int) ([]*entity.PlacementViolation, error) {
+	topo := pool.Discovery.GetTopology(pool.Config.Discovery.DataServName)
+	res := make([]*entity.PlacementViolation, 0)
+	for _, addr := range pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true) {
+		cc, err := getConn(addr)
+		if err != nil {
+			return nil, err
+		}
+		cli := pb.NewMetadataApiClient(cc)
+		var cursor string
+		for len(res) < limit {
+			resp, err := cli.ScanVersions(context.Background(), &pb.ScanReq{Cursor: cursor, Limit: 1000})
+			if err != nil {
+				return nil, ResolveErr(err)
+			}
+			for _, item := range resp.Items {
+				var v msg.Version
+				if err = util.DecodeMsgp(&v, item.Msgpack); err != nil {
+					return nil, err
+				}
+				tolerance := int(v.ParityShards)
+				recoverable := func(lost []int) bool { return len(lost) <= tolerance }
+				switch v.StoreStrategy {
+				case entity.MultiReplication:
+					tolerance = int(v.DataShards) - 1
+				case entity.LocalReconstruction:
+					// groups losing only one shard are repaired locally, which are not limited by tolerance
+					recoverable = func(lost []int) bool {
+						return registry.LRCRecoverable(int(v.DataShards), int(v.LocalGroups), int(v.ParityShards), lost)
+					}
+				}
+				if tolerance <= 0 || v.StoreStrategy == entity.Inline {
+					continue
+				}
+				if vs := registry.CheckRecoverable(v.Locate, topo, level, tolerance, recoverable); len(vs) > 0 {
+					logs.Std().Warnf("placement violation: %s version %d (%s) has %d shards in %s '%s'", item.Id, v.Sequence, v.Hash, vs[0].Shards, level, vs[0].Domain)
+					res = append(res, &entity.PlacementViolation{
+						Id:         item.Id,
+						Version:    v.Sequence,
+						Hash:       v.Hash,
+						Locate:     v.Locate,
+						Violations: vs,
+					})
+				}
+			}
+			if len(resp.Items) < 1000 {
+				break
+			}
+			cursor = resp.Cursor
+		}
+	}
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"adminserver/internal/usecase/pool"
+	"common/balance"
+	"common/cst"
+	"context"
+	"encoding/json"
+)
+
+type Rebalance struct{}
+
+func NewRebalance() Rebalance {
+	return Rebalance{}
+}
+
+// Plan returns the latest rebalance plan and its progress. returns an empty plan if not yet planned.
+func (r Rebalance) Plan() (*balance.Plan, error) {
+	ctx := context.Background()
+	var plan balance.Plan
+	resp, err := pool.Etcd.Get(ctx, cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "plan"))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		if err = json.Unmarshal(resp.Kvs[0].Value, &plan); err != nil {
+			return nil, err
+		}
+	}
+	if plan.Paused, err = r.IsPaused(); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (Rebalance) IsPaused() (bool, error) {
+	resp, err := pool.Etcd.Get(context.Background(), cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "paused"))
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Kvs) > 0, nil
+}
+
+// Pause stops the coordinator before the next move. the running move will not be interrupted.
+func (Rebalance) Pause() error {
+	_, err := pool.Etcd.Put(context.Background(), cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "paused"), "true")
+	return err
+}
+
+// Resume lets the coordinator plan again in the next round
+func (Rebalance) Resume() error {
+	_, err := pool.Etcd.Delete(context.Background(), cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "paused"))
+	return err
+}
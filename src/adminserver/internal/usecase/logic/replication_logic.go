@@ -0,0 +1,97 @@
+package logic
+
+import (
+	"adminserver/internal/usecase/pool"
+	"common/cst"
+	"common/replication"
+	"common/response"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// hiddenPassword replaces passwords of listed rules
+const hiddenPassword = "******"
+
+type Replication struct{}
+
+func NewReplication() Replication {
+	return Replication{}
+}
+
+func replicationKey(kind, id string) string {
+	return cst.EtcdPrefix.FmtReplication(pool.Config.Discovery.Group, kind, id)
+}
+
+// Rules returns all replication rules with passwords hidden
+func (Replication) Rules() ([]*replication.Rule, error) {
+	resp, err := pool.Etcd.Get(context.Background(), replicationKey(replication.KindRule, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*replication.Rule, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var r replication.Rule
+		if err = json.Unmarshal(kv.Value, &r); err != nil {
+			return nil, fmt.Errorf("decode replication rule %s: %w", kv.Key, err)
+		}
+		if r.Password != "" {
+			r.Password = hiddenPassword
+		}
+		res = append(res, &r)
+	}
+	return res, nil
+}
+
+// SaveRule creates or replaces the rule of bucket. the saved password is kept if password is empty or hidden.
+func (Replication) SaveRule(rule *replication.Rule) error {
+	if err := rule.Validate(); err != nil {
+		return response.NewError(400, err.Error())
+	}
+	if rule.Password == hiddenPassword {
+		rule.Password = ""
+	}
+	key := replicationKey(replication.KindRule, rule.Bucket)
+	if rule.Password == "" && rule.Username != "" {
+		resp, err := pool.Etcd.Get(context.Background(), key)
+		if err != nil {
+			return err
+		}
+		if len(resp.Kvs) > 0 {
+			var old replication.Rule
+			if err = json.Unmarshal(resp.Kvs[0].Value, &old); err == nil && old.Username == rule.Username {
+				rule.Password = old.Password
+			}
+		}
+	}
+	bt, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Etcd.Put(context.Background(), key, string(bt))
+	return err
+}
+
+func (Replication) RemoveRule(bucket string) error {
+	_, err := pool.Etcd.Delete(context.Background(), replicationKey(replication.KindRule, bucket))
+	return err
+}
+
+// Status returns replication progresses of all metadata groups
+func (Replication) Status() ([]*replication.Status, error) {
+	resp, err := pool.Etcd.Get(context.Background(), replicationKey(replication.KindStatus, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*replication.Status, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var st replication.Status
+		if err = json.Unmarshal(kv.Value, &st); err != nil {
+			return nil, fmt.Errorf("decode replication status %s: %w", kv.Key, err)
+		}
+		res = append(res, &st)
+	}
+	return res, nil
+}
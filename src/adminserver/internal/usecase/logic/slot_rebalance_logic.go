@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"adminserver/internal/entity"
+	"adminserver/internal/usecase/pool"
+	"adminserver/internal/usecase/webapi"
+	"common/balance"
+	"common/cst"
+	"common/graceful"
+	"common/hashslot"
+	"common/logs"
+	"common/response"
+	"common/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+type SlotRebalance struct{}
+
+func NewSlotRebalance() SlotRebalance {
+	return SlotRebalance{}
+}
+
+// Loads returns slots, number of keys and size of data of every master of metadata groups.
+// masters without any slot are included so that new groups can receive slots.
+func (SlotRebalance) Loads() ([]*hashslot.GroupLoad, error) {
+	detail, err := NewMetadata().GetSlotsDetail()
+	if err != nil {
+		return nil, err
+	}
+	masters := pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true)
+	loads := make(map[string]*hashslot.GroupLoad, len(masters))
+	for _, info := range detail {
+		loads[info.ServerID] = &hashslot.GroupLoad{ID: info.ServerID, Slots: info.Slots}
+	}
+	for id, addr := range masters {
+		load, ok := loads[id]
+		if !ok {
+			load = &hashslot.GroupLoad{ID: id, Slots: []string{}}
+			loads[id] = load
+		}
+		stored, err := webapi.GetStoreLoad(addr)
+		if err == nil {
+			load.Keys, load.Bytes = stored.Keys, stored.Bytes
+			continue
+		}
+		// metadata server of old version only reports the number of keys
+		logs.Std().Debugf("get store load of %s err: %s", id, err)
+		_, total, err := webapi.ListMetadata(addr, "", 1)
+		if err != nil {
+			return nil, err
+		}
+		load.Keys = int64(total)
+	}
+	res := make([]*hashslot.GroupLoad, 0, len(loads))
+	for _, v := range loads {
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// Preview computes moves to balance slots without executing them. groups in drain will be moved out all slots.
+func (s SlotRebalance) Preview(drain []string) (*entity.SlotPlan, error) {
+	loads, err := s.Loads()
+	if err != nil {
+		return nil, err
+	}
+	moves, err := hashslot.PlanRebalance(loads, drain)
+	if err != nil {
+		return nil, response.NewError(400, err.Error())
+	}
+	now := time.Now()
+	return &entity.SlotPlan{
+		Drain:     drain,
+		Groups:    loads,
+		Moves:     moves,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Start plans and executes moves in background one by one. only one plan can be executed at the same time in cluster,
+// which is guarded by an etcd lock held until the plan finished.
+func (s SlotRebalance) Start(drain []string) (*entity.SlotPlan, error) {
+	sess, err := concurrency.NewSession(pool.Etcd, concurrency.WithTTL(15))
+	if err != nil {
+		return nil, err
+	}
+	mux := concurrency.NewMutex(sess, cst.EtcdPrefix.FmtLock(pool.Config.Discovery.Group, "slot-rebalance"))
+	if err = mux.TryLock(context.Background()); err != nil {
+		util.LogErr(sess.Close())
+		if errors.Is(err, concurrency.ErrLocked) {
+			return nil, response.NewError(400, "slot rebalance is running")
+		}
+		return nil, err
+	}
+	unlock := func() {
+		util.LogErr(mux.Unlock(context.Background()))
+		util.LogErr(sess.Close())
+	}
+	plan, err := s.Preview(drain)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	plan.Running = true
+	s.save(plan)
+	go func() {
+		defer graceful.Recover()
+		defer unlock()
+		s.execute(plan)
+	}()
+	return plan, nil
+}
+
+// Progress returns the latest executed plan. returns an empty plan if never started.
+func (SlotRebalance) Progress() (*entity.SlotPlan, error) {
+	plan := &entity.SlotPlan{}
+	resp, err := pool.Etcd.Get(context.Background(), cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "slots"))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		if err = json.Unmarshal(resp.Kvs[0].Value, plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// execute moves sequentially, remaining moves will be abandoned once a move failed
+// because later moves may depend on slots received by previous ones.
+func (s SlotRebalance) execute(plan *entity.SlotPlan) {
+	defer func() {
+		plan.Running = false
+		s.save(plan)
+	}()
+	for _, mv := range plan.Moves {
+		mv.State = balance.MoveRunning
+		s.save(plan)
+		if err := NewMetadata().StartMigration(mv.From, mv.To, mv.Slots); err != nil {
+			mv.State, mv.Error = balance.MoveFailed, err.Error()
+			logs.Std().Errorf("migrate slots %v from %s to %s err: %s", mv.Slots, mv.From, mv.To, err)
+			return
+		}
+		mv.State = balance.MoveDone
+	}
+}
+
+func (SlotRebalance) save(plan *entity.SlotPlan) {
+	plan.UpdatedAt = time.Now()
+	bt, err := json.Marshal(plan)
+	if err != nil {
+		logs.Std().Errorf("marshal slot plan err: %s", err)
+		return
+	}
+	_, err = pool.Etcd.Put(context.Background(), cst.EtcdPrefix.FmtRebalance(pool.Config.Discovery.Group, "slots"), string(bt))
+	util.LogErrWithPre("save slot plan", err)
+}
@@ -2,6 +2,7 @@ package webapi
 
 import (
 	"adminserver/internal/usecase/pool"
+	"common/hashslot"
 	"common/proto/msg"
 	"common/response"
 	"common/util"
@@ -44,6 +45,32 @@ func ListBuckets(ip, prefix string, pageSize int) ([]*msg.Bucket, int, error) {
 	return lst, total, err
 }
 
+// StatBucket aggregates versions of bucket on a metadata server
+func StatBucket(ip, bucket string) (*msg.BucketStat, error) {
+	resp, err := pool.Http.Get(fmt.Sprintf("http://%s/metadata/stat?%s", ip, url.Values{"bucket": {bucket}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, response.NewError(resp.StatusCode, response.MessageFromJSONBody(resp.Body))
+	}
+	return util.UnmarshalFromIO[*msg.BucketStat](resp.Body)
+}
+
+// GetStoreLoad returns the number of keys and the size of data stored on a metadata server
+func GetStoreLoad(ip string) (*hashslot.GroupLoad, error) {
+	resp, err := pool.Http.Get(fmt.Sprintf("http://%s/metadata/load", ip))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, response.NewError(resp.StatusCode, response.MessageFromJSONBody(resp.Body))
+	}
+	return util.UnmarshalFromIO[*hashslot.GroupLoad](resp.Body)
+}
+
 func metadataListRest(ip string, param map[string][]string) string {
 	return fmt.Sprintf("http://%s/metadata/list?%s", ip, url.Values(param).Encode())
 }
@@ -12,6 +12,11 @@ async function removeBucket(name: string) {
     await axios.delete(`/metadata/delete_bucket/${name}`)
 }
 
+async function bucketStat(name: string): Promise<BucketStat> {
+    let resp = await axios.get(`/metadata/bucket_stat/${name}`)
+    return resp.data
+}
+
 async function bucketPage(req: BucketReq): Promise<PageResult<Bucket>> {
     let resp = await axios.get("/metadata/buckets", {
         params: req
@@ -72,16 +77,25 @@ async function leaveCluster(servId: string) {
     })
 }
 
+async function backupList(group: string = ""): Promise<{ [key: string]: BackupManifest[] }> {
+    let resp = await axios.get("/metadata/backups", {
+        params: {group: group}
+    })
+    return resp.data
+}
+
 export {
     bucketPage,
     addBucket,
     updateBucket,
     removeBucket,
+    bucketStat,
     metadataPage,
     versionPage,
     slotsDetail,
     startMigrate,
     getPeers,
     joinLeader,
-    leaveCluster
+    leaveCluster,
+    backupList
 }
\ No newline at end of file
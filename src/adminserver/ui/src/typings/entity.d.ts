@@ -6,6 +6,8 @@ declare interface PageResult<T> {
 declare interface Version {
     hash: string
     compress: boolean
+    codec?: string
+    compressRatio?: number
     size: number
     sequence: number
     ts: number
@@ -22,12 +24,33 @@ declare interface Bucket {
     versioning: boolean
     versionRemains: number
     compress: boolean
+    codec?: string
+    autoCompress?: boolean
     storeStrategy: number
     dataShards: number
     parityShards: number
     createTime: number
     updateTime: number
     policies: string[]
+    notification?: Notification
+}
+
+declare interface BucketStat {
+    bucket: string
+    versions: number
+    size: number
+    compressed: number
+    compressedSize: number
+    sampledSize: number
+    sampledStored: number
+    storedSize: number
+    ratio: number
+}
+
+declare interface Notification {
+    events: string[]
+    webhook: string
+    secret: string
 }
 
 declare interface Metadata {
@@ -94,4 +117,17 @@ declare interface EtcdStatus {
     dbSize: number
     dbSizeInUse: number
     isLearner: boolean
-}
\ No newline at end of file
+}
+
+declare interface BackupManifest {
+    id: string
+    group: string
+    server: string
+    type: string
+    parent?: string
+    fromIndex: number
+    toIndex: number
+    lastTime: string
+    size: number
+    createdAt: string
+}
@@ -19,16 +19,18 @@ const (
 )
 
 type Config struct {
-	Port           string             `yaml:"port" env:"PORT" env-default:"8080"`
-	SelectStrategy string             `yaml:"select-strategy" env:"SELECT_STRATEGY" env-default:"random"`
-	Log            logs.Config        `yaml:"log" env-prefix:"LOG"`
-	Etcd           etcd.Config        `yaml:"etcd" env-prefix:"ETCD"`
-	Object         ObjectConfig       `yaml:"object" env-prefix:"OBJECT"`
-	Discovery      DiscoveryConfig    `yaml:"discovery" env-prefix:"DISCOVERY"`
-	Registry       registry.Config    `yaml:"registry" env-prefix:"REGISTRY"`
-	Auth           auth.Config        `yaml:"auth" env-prefix:"AUTH"`
-	Performance    performance.Config `yaml:"performance" env-prefix:"PERFORMANCE"`
-	TLS            TLSConfig          `yaml:"tls" env-prefix:"TLS"`
+	Port              string                  `yaml:"port" env:"PORT" env-default:"8080"`
+	SelectStrategy    string                  `yaml:"select-strategy" env:"SELECT_STRATEGY" env-default:"random"`
+	Log               logs.Config             `yaml:"log" env-prefix:"LOG"`
+	Etcd              etcd.Config             `yaml:"etcd" env-prefix:"ETCD"`
+	Object            ObjectConfig            `yaml:"object" env-prefix:"OBJECT"`
+	Discovery         DiscoveryConfig         `yaml:"discovery" env-prefix:"DISCOVERY"`
+	Registry          registry.Config         `yaml:"registry" env-prefix:"REGISTRY"`
+	Auth              auth.Config             `yaml:"auth" env-prefix:"AUTH"`
+	Performance       performance.Config      `yaml:"performance" env-prefix:"PERFORMANCE"`
+	TLS               TLSConfig               `yaml:"tls" env-prefix:"TLS"`
+	Tiering           TieringConfig           `yaml:"tiering" env-prefix:"TIERING"`
+	BucketReplication BucketReplicationConfig `yaml:"bucket-replication" env-prefix:"BUCKET_REPLICATION"`
 }
 
 func (c *Config) initialize() {
@@ -38,6 +40,15 @@ func (c *Config) initialize() {
 		c.Object.ReedSolomon.BlockPerShard = newSize / c.Object.ReedSolomon.DataShards
 		logs.Std().Warnf("aligned object.reedsolomon.block-per-shard to %d", c.Object.ReedSolomon.BlockPerShard)
 	}
+	if lrc := &c.Object.LocalReconstruction; lrc.LocalGroups <= 0 || lrc.DataShards%lrc.LocalGroups != 0 {
+		logs.Std().Warnf("object.local-reconstruction.data-shards %d is not divisible by local-groups %d, use 1 group", lrc.DataShards, lrc.LocalGroups)
+		lrc.LocalGroups = 1
+	}
+	if i := c.Object.LocalReconstruction.BlockSize() % cst.OS.NetPkgSize; i > 0 {
+		newSize := c.Object.LocalReconstruction.BlockSize() - i + cst.OS.NetPkgSize
+		c.Object.LocalReconstruction.BlockPerShard = newSize / c.Object.LocalReconstruction.DataShards
+		logs.Std().Warnf("aligned object.local-reconstruction.block-per-shard to %d", c.Object.LocalReconstruction.BlockPerShard)
+	}
 	if i := c.Object.Replication.BlockSize % datasize.DataSize(cst.OS.NetPkgSize); i > 0 {
 		c.Object.Replication.BlockSize = c.Object.Replication.BlockSize - i + datasize.DataSize(cst.OS.NetPkgSize)
 		logs.Std().Warnf("aligned object.replication.block-size to %d", c.Object.Replication.BlockSize)
@@ -47,14 +58,62 @@ func (c *Config) initialize() {
 type DiscoveryConfig struct {
 	DataServName string `yaml:"data-serv-name" env:"DATA_SERV_NAME" env-default:"objectserver"`
 	MetaServName string `yaml:"meta-serv-name" env:"META_SERV_NAME" env-default:"metaserver"`
+	ColdServName string `yaml:"cold-serv-name" env:"COLD_SERV_NAME"` // ColdServName is the name of object servers for cold data. empty means no cold servers
 }
 
 type ObjectConfig struct {
-	Checksum        bool              `yaml:"checksum" env:"CHECKSUM"`
-	DistinctSize    datasize.DataSize `yaml:"distinct-size" env:"DISTINCT_SIZE"`
-	DistinctTimeout time.Duration     `yaml:"distinct-timeout" env:"DISTINCT_TIMEOUT" env-default:"200ms"`
-	ReedSolomon     RsConfig          `yaml:"reed-solomon" env-prefix:"REED_SOLOMON"`
-	Replication     ReplicationConfig `yaml:"replication" env-prefix:"REPLICATION"`
+	Checksum     bool               `yaml:"checksum" env:"CHECKSUM"`
+	DistinctSize datasize.DataSize  `yaml:"distinct-size" env:"DISTINCT_SIZE"`
+	ReedSolomon  RsConfig           `yaml:"reed-solomon" env-prefix:"REED_SOLOMON"`
+	Replication  ReplicationConfig  `yaml:"replication" env-prefix:"REPLICATION"`
+	Placement    PlacementConfig    `yaml:"placement" env-prefix:"PLACEMENT"`
+	Bulk         BulkConfig         `yaml:"bulk" env-prefix:"BULK"`
+	AutoCompress AutoCompressConfig `yaml:"auto-compress" env-prefix:"AUTO_COMPRESS"`
+	Hedge        HedgeConfig        `yaml:"hedge" env-prefix:"HEDGE"`
+	// LocalReconstruction is the default layout of objects stored by local reconstruction codes
+	LocalReconstruction LrcConfig `yaml:"local-reconstruction" env-prefix:"LOCAL_RECONSTRUCTION"`
+	// InlineLimit is the default size under which objects are stored in versions on metadata servers, 0 to disable
+	InlineLimit datasize.DataSize `yaml:"inline-limit" env:"INLINE_LIMIT" env-default:"0"`
+	Cache       ObjectCacheConfig `yaml:"cache" env-prefix:"CACHE"`
+}
+
+// ObjectCacheConfig caches decoded objects read frequently in memory, evicted ones spill to DiskPath if it's not empty
+type ObjectCacheConfig struct {
+	Enabled     bool              `yaml:"enabled" env:"ENABLED" env-default:"false"`
+	MaxSize     datasize.DataSize `yaml:"max-size" env:"MAX_SIZE" env-default:"256MB"`
+	MaxItemSize datasize.DataSize `yaml:"max-item-size" env:"MAX_ITEM_SIZE" env-default:"8MB"`
+	DiskPath    string            `yaml:"disk-path" env:"DISK_PATH"`
+	DiskSize    datasize.DataSize `yaml:"disk-size" env:"DISK_SIZE" env-default:"4GB"`
+}
+
+// HedgeConfig reads only the fastest data-shards-number shards of erasure-coded objects,
+// other shards are requested only if some of them are slower than usual or fail
+type HedgeConfig struct {
+	Enabled    bool          `yaml:"enabled" env:"ENABLED" env-default:"true"`
+	Percentile float64       `yaml:"percentile" env:"PERCENTILE" env-default:"0.95"` // Percentile of recent latencies of a server, a shard slower than it is hedged
+	MinDelay   time.Duration `yaml:"min-delay" env:"MIN_DELAY" env-default:"20ms"`   // MinDelay is the least time waiting before hedging, also used for servers without latencies
+	MaxDelay   time.Duration `yaml:"max-delay" env:"MAX_DELAY" env-default:"2s"`     // MaxDelay is the most time waiting before hedging
+}
+
+// AutoCompressConfig decides whether to compress an object in auto mode by sampling the head of it
+type AutoCompressConfig struct {
+	SampleSize datasize.DataSize `yaml:"sample-size" env:"SAMPLE_SIZE" env-default:"64KB"` // SampleSize is the size of data sampled from the head of object
+	MaxRatio   float32           `yaml:"max-ratio" env:"MAX_RATIO" env-default:"0.9"`      // MaxRatio compresses objects whose estimated compressed size divided by size is not greater than it
+	// SkipExts are extensions of already compressed files, never compressed in auto mode
+	SkipExts []string `yaml:"skip-exts" env:"SKIP_EXTS" env-default:"jpg,jpeg,png,gif,webp,heic,avif,mp3,aac,ogg,flac,mp4,mkv,mov,avi,webm,zip,gz,tgz,bz2,xz,7z,rar,zst,br,lz4,jar,apk,docx,xlsx,pptx,pdf"`
+}
+
+// BulkConfig limits uploading small objects in a request
+type BulkConfig struct {
+	MaxCount int               `yaml:"max-count" env:"MAX_COUNT" env-default:"1000"` // MaxCount is the max number of objects in a request
+	MaxSize  datasize.DataSize `yaml:"max-size" env:"MAX_SIZE" env-default:"1MB"`    // MaxSize is the max size of each object
+}
+
+// PlacementConfig constrains that a failure domain holds no more shards of a stripe than the stripe can lose
+// (ParityShards for erasure coding and CopiesCount-1 for replication).
+type PlacementConfig struct {
+	Level  registry.DomainLevel `yaml:"level" env:"LEVEL" env-default:"zone"` // Level is the failure domain level to be constrained: zone, rack or host
+	Strict bool                 `yaml:"strict" env:"STRICT"`                  // Strict rejects writing if constraint can't be satisfied, otherwise only warns
 }
 
 type ReplicationConfig struct {
@@ -97,6 +156,82 @@ func (r *RsConfig) ShardSize(totalSize int64) int {
 	return int((totalSize + dsNum - 1) / dsNum)
 }
 
+// LrcConfig local reconstruction codes. data shards are divided into local groups of the same size, each group has
+// a parity shard to repair one lost shard in it, and global parity shards are computed from all data shards like RS.
+// shards are ordered by data shards, local parity shards of groups and global parity shards.
+type LrcConfig struct {
+	DataShards    int  `yaml:"data-shards" env:"DATA_SHARDS" env-default:"12"`            // DataShards shards number of data part
+	LocalGroups   int  `yaml:"local-groups" env:"LOCAL_GROUPS" env-default:"2"`           // LocalGroups number of local groups, DataShards must be divisible by it
+	GlobalParity  int  `yaml:"global-parity" env:"GLOBAL_PARITY" env-default:"2"`         // GlobalParity shards number of global parity part
+	BlockPerShard int  `yaml:"block-per-shard" env:"BLOCK_PER_SHARD" env-default:"16384"` // BlockPerShard auto increase to make BlockSize is multiple of 16KB
+	RewriteAsync  bool `yaml:"rewrite-async" env:"REWRITE_ASYNC" env-default:"true"`      // RewriteAsync store lost shard asynchronously
+}
+
+func (l *LrcConfig) AllShards() int {
+	return l.DataShards + l.LocalGroups + l.GlobalParity
+}
+
+func (l *LrcConfig) GroupSize() int {
+	return l.DataShards / l.LocalGroups
+}
+
+// Group returns the local group of shard idx, -1 if it's a global parity shard
+func (l *LrcConfig) Group(idx int) int {
+	switch {
+	case idx < l.DataShards:
+		return idx / l.GroupSize()
+	case idx < l.DataShards+l.LocalGroups:
+		return idx - l.DataShards
+	default:
+		return -1
+	}
+}
+
+// LocalParity returns the index of local parity shard of group
+func (l *LrcConfig) LocalParity(group int) int {
+	return l.DataShards + group
+}
+
+func (l *LrcConfig) BlockSize() int {
+	return l.BlockPerShard * l.DataShards
+}
+
+func (l *LrcConfig) ShardSize(totalSize int64) int {
+	dsNum := int64(l.DataShards)
+	return int((totalSize + dsNum - 1) / dsNum)
+}
+
+// RsConfig returns the layout as RS with all parity shards, which splits blocks and reads shards in the same way
+func (l *LrcConfig) RsConfig() *RsConfig {
+	return &RsConfig{
+		DataShards:    l.DataShards,
+		ParityShards:  l.LocalGroups + l.GlobalParity,
+		BlockPerShard: l.BlockPerShard,
+		RewriteAsync:  l.RewriteAsync,
+	}
+}
+
+type TieringConfig struct {
+	Enabled        bool              `yaml:"enabled" env:"ENABLED"`
+	ColdAfter      time.Duration     `yaml:"cold-after" env:"COLD_AFTER" env-default:"720h"`         // ColdAfter objects not read or written for this duration will be transcoded
+	ScanInterval   time.Duration     `yaml:"scan-interval" env:"SCAN_INTERVAL" env-default:"6h"`     // ScanInterval interval of scanning cold objects
+	AccessInterval time.Duration     `yaml:"access-interval" env:"ACCESS_INTERVAL" env-default:"1h"` // AccessInterval minimum interval of updating access time of an object
+	TouchDelay     time.Duration     `yaml:"touch-delay" env:"TOUCH_DELAY" env-default:"10s"`        // TouchDelay read objects are collected for this duration before updating their access time
+	BatchSize      int               `yaml:"batch-size" env:"BATCH_SIZE" env-default:"100"`          // BatchSize number of cold objects fetched from metadata server at once
+	MinSize        datasize.DataSize `yaml:"min-size" env:"MIN_SIZE" env-default:"64KB"`             // MinSize objects smaller than it will not be transcoded
+	DataShards     int               `yaml:"data-shards" env:"DATA_SHARDS" env-default:"10"`         // DataShards data shards number of cold layout
+	ParityShards   int               `yaml:"parity-shards" env:"PARITY_SHARDS" env-default:"4"`      // ParityShards parity shards number of cold layout
+}
+
+type BucketReplicationConfig struct {
+	Enabled   bool          `yaml:"enabled" env:"ENABLED"`
+	Interval  time.Duration `yaml:"interval" env:"INTERVAL" env-default:"10s"`     // Interval of reading change feeds of metadata servers
+	BatchSize int           `yaml:"batch-size" env:"BATCH_SIZE" env-default:"100"` // BatchSize number of changes fetched from metadata server at once
+	MaxRetry  int           `yaml:"max-retry" env:"MAX_RETRY" env-default:"10"`    // MaxRetry attempts of a failed change before giving up
+	RetryBase time.Duration `yaml:"retry-base" env:"RETRY_BASE" env-default:"1m"`  // RetryBase delay before the first retry, doubled for every attempt
+	RetryMax  time.Duration `yaml:"retry-max" env:"RETRY_MAX" env-default:"1h"`    // RetryMax max delay between two attempts
+}
+
 type TLSConfig struct {
 	Enabled        bool   `yaml:"enabled" env:"ENABLED"`
 	ServerCertFile string `yaml:"server-cert-file" env:"SERVER_CERT_FILE"`
@@ -21,7 +21,9 @@ func Run(cfg *Config) {
 	bucketRepo := repo.NewBucketRepo()
 	metaRepo := repo.NewMetadataRepo()
 	metaService := service.NewMetaService(metaRepo, versionRepo)
-	objService := service.NewObjectService(metaService, bucketRepo, pool.Etcd)
+	objService := service.NewObjectService(metaService, bucketRepo, repo.NewHashRefRepo())
+	tieringService := service.NewTieringService(objService, metaService)
+	replicationService := service.NewReplicationService(objService, metaService, pool.Etcd)
 
 	// lifecycle
 	lifecycle := registry.NewLifecycle(pool.Etcd, cfg.Registry.Interval)
@@ -40,6 +42,11 @@ func Run(cfg *Config) {
 	})
 	defer syncer.StartAutoSave()()
 
+	// transcode cold objects
+	defer tieringService.StartAutoTiering()()
+	// replicate buckets to remote clusters
+	defer replicationService.StartAutoReplication()()
+
 	// start lifecycle
 	go lifecycle.DeadLoop()
 
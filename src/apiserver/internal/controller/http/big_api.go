@@ -7,10 +7,12 @@ import (
 	"apiserver/internal/usecase/pool"
 	"apiserver/internal/usecase/repo"
 	"apiserver/internal/usecase/service"
+	"common/consistency"
 	"common/graceful"
 	"common/response"
 	"common/util"
 	"common/util/crypto"
+	"common/util/slices"
 	"fmt"
 
 	"apiserver/internal/usecase/logic"
@@ -55,29 +57,38 @@ func (bc *BigObjectsController) Post(g *gin.Context) {
 		return
 	}
 	// if bucket enforce compress
+	var codec string
 	if bucket.Compress {
 		req.Compress = true
+		codec = bucket.Codec
+	} else if !req.Compress && (req.Auto || bucket.AutoCompress) && !logic.NewCompression().Skip(req.Ext) {
+		// data is not uploaded yet, so only extension is checked
+		req.Compress = true
+		codec = bucket.Codec
 	}
 	// configure by bucket config
 	conf := bucket.MakeConf(&pool.Config.Object, req.Size).ReedSolomon
 	// generate a unique hash as version hash
-	uniqueHash := bc.objectService.UniqueHash(req.Hash, entity.ECReedSolomon, conf.DataShards, conf.ParityShards, req.Compress)
+	uniqueHash := bc.objectService.UniqueHash(req.Hash, entity.ECReedSolomon, conf.DataShards, conf.ParityShards, 0, req.Compress, codec)
 	// filter duplicate
-	locates, ok := bc.objectService.LocateObject(uniqueHash, conf.AllShards())
+	locates, ok := bc.objectService.LocateObject(uniqueHash)
 	if ok {
 		// finish upload
 		object := &entity.Version{
 			Hash:          uniqueHash,
+			Digest:        req.Hash,
 			Size:          req.Size,
 			Compress:      req.Compress,
+			Codec:         codec,
 			Locate:        locates,
 			DataShards:    conf.DataShards,
 			ParityShards:  conf.ParityShards,
 			ShardSize:     conf.ShardSize(req.Size),
 			StoreStrategy: entity.ECReedSolomon,
 		}
-		verNum, err := bc.finishUpload(req.Name, req.Bucket, object, &conf)
+		verNum, err := bc.finishUpload(req.Name, req.Bucket, object)
 		if err != nil {
+			util.LogErrWithPre("dereference object err", bc.objectService.DereferObject(uniqueHash, locates))
 			response.FailErr(err, g)
 			return
 		}
@@ -88,7 +99,7 @@ func (bc *BigObjectsController) Post(g *gin.Context) {
 		}, g)
 		return
 	}
-	ips := logic.NewDiscovery().SelectDataServer(pool.Balancer, conf.AllShards())
+	ips := logic.NewDiscovery().SelectDataServer(pool.Balancer, conf.AllShards(), conf.ParityShards)
 	if len(ips) == 0 {
 		response.ServiceUnavailableMsg("no available servers", g)
 		return
@@ -99,6 +110,7 @@ func (bc *BigObjectsController) Post(g *gin.Context) {
 		Size:     req.Size,
 		Bucket:   req.Bucket,
 		Compress: req.Compress,
+		Codec:    codec,
 		Locates:  ips,
 	}, &conf)
 	if e != nil {
@@ -165,6 +177,11 @@ func (bc *BigObjectsController) Patch(g *gin.Context) {
 	}
 	curSize += n
 	if curSize < stream.Size {
+		// make sure written data has reached servers before responding
+		if err = stream.Close(); err != nil {
+			response.FailErr(err, g)
+			return
+		}
 		// not read enough, see as interrupted
 		response.Exec(g).Status(http.StatusPartialContent).
 			Header(gin.H{"Content-Length": curSize})
@@ -177,21 +194,42 @@ func (bc *BigObjectsController) Patch(g *gin.Context) {
 		return
 	}
 	// if curSize equals expected size
-	verNum, err := bc.finishUpload(stream.Name, stream.Bucket, &entity.Version{
+	ver := &entity.Version{
 		Hash:          stream.Hash,
 		Size:          stream.Size,
+		Compress:      stream.Compress,
+		Codec:         stream.Codec,
 		Locate:        stream.Servers,
 		DataShards:    stream.Config.DataShards,
 		ParityShards:  stream.Config.ParityShards,
 		ShardSize:     stream.Config.ShardSize(stream.Size),
 		StoreStrategy: entity.ECReedSolomon,
-	}, stream.Config)
+	}
+	if err = bc.validateDigest(ver, stream.Config); err != nil {
+		util.LogErr(stream.Commit(false))
+		response.FailErr(err, g)
+		return
+	}
+	if ver.Compress {
+		// compression is decided before uploading, the ratio is sampled from uploaded data
+		ver.CompressRatio, err = bc.sampleRatio(ver, stream.Name, stream.Config)
+		util.LogErrWithPre("sample compression ratio err", err)
+	}
+	// save reference, locates will be changed if the same object has been stored
+	if ver.Locate, err = bc.objectService.ReferObject(ver.Hash, stream.Servers); err != nil {
+		util.LogErr(stream.Commit(false))
+		response.FailErr(err, g)
+		return
+	}
+	verNum, err := bc.finishUpload(stream.Name, stream.Bucket, ver)
 	if err != nil {
 		util.LogErr(stream.Commit(false))
+		util.LogErrWithPre("dereference object err", bc.objectService.DereferObject(ver.Hash, ver.Locate))
 		response.FailErr(err, g)
 		return
 	}
-	if err = stream.Commit(true); err != nil {
+	// discard uploaded data if it's duplicated
+	if err = stream.Commit(slices.Equal(ver.Locate, stream.Servers)); err != nil {
 		response.FailErr(err, g)
 		return
 	}
@@ -202,20 +240,35 @@ func (bc *BigObjectsController) Patch(g *gin.Context) {
 	}, g)
 }
 
-func (bc *BigObjectsController) finishUpload(metaName, bucketName string, v *entity.Version, conf *config.RsConfig) (verNum int32, err error) {
-	// validate digest
-	if pool.Config.Object.Checksum {
-		getStream := service.NewRSTempStream(&service.StreamOption{
-			Hash:    v.Hash,
-			Size:    v.Size,
-			Locates: v.Locate,
-		}, conf)
-		hash := crypto.SHA256IO(getStream)
-		if hash != v.Hash {
-			err = response.NewError(http.StatusForbidden, "signature authentication failure")
-			return
-		}
+// validateDigest validate digest of uploaded temp data
+func (bc *BigObjectsController) validateDigest(v *entity.Version, conf *config.RsConfig) error {
+	if !pool.Config.Object.Checksum {
+		return nil
 	}
+	getStream := service.NewRSTempStream(&service.StreamOption{
+		Hash:    v.Hash,
+		Size:    v.Size,
+		Locates: v.Locate,
+	}, conf)
+	hash := crypto.SHA256IO(getStream)
+	if hash != v.Hash {
+		return response.NewError(http.StatusForbidden, "signature authentication failure")
+	}
+	return nil
+}
+
+// sampleRatio estimates the compression ratio of uploaded temp data by the head of it
+func (bc *BigObjectsController) sampleRatio(v *entity.Version, name string, conf *config.RsConfig) (float32, error) {
+	getStream := service.NewRSTempStream(&service.StreamOption{
+		Hash:    v.Hash,
+		Size:    v.Size,
+		Locates: v.Locate,
+	}, conf)
+	defer getStream.Close()
+	return logic.NewCompression().Ratio(util.GetFileExtOrDefault(name, false, "bytes"), getStream)
+}
+
+func (bc *BigObjectsController) finishUpload(metaName, bucketName string, v *entity.Version) (verNum int32, err error) {
 	dg := util.NewDoneGroup()
 	defer dg.Close()
 	// get metadata if exist
@@ -224,7 +277,7 @@ func (bc *BigObjectsController) finishUpload(metaName, bucketName string, v *ent
 	go func() {
 		defer dg.Done()
 		var inner error
-		metadata, inner = bc.metaService.GetMetadata(metaName, bucketName, int32(entity.VerModeNot), true)
+		metadata, inner = bc.metaService.GetMetadata(metaName, bucketName, int32(entity.VerModeNot), true, consistency.LevelStrong)
 		if err != nil && !response.CheckErrStatus(404, inner) {
 			dg.Error(inner)
 		}
@@ -254,7 +307,7 @@ func (bc *BigObjectsController) finishUpload(metaName, bucketName string, v *ent
 			go func() {
 				defer graceful.Recover()
 				// if not err, delete first version
-				inner := bc.metaService.RemoveVersion(metadata.Name, metadata.Bucket, int32(metadata.FirstVersion))
+				inner := bc.objectService.RemoveVersion(metadata.Name, metadata.Bucket, int32(metadata.FirstVersion))
 				util.LogErrWithPre("remove first version err", inner)
 			}()
 		}
@@ -32,6 +32,10 @@ func (lc *BucketController) Create(c *gin.Context) {
 		response.BadRequestMsg("name is required", c)
 		return
 	}
+	if err := i.CheckLayout(); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
 	if err := lc.Repo.Create(&i); err != nil {
 		response.FailErr(err, c)
 		return
@@ -46,6 +50,10 @@ func (lc *BucketController) Update(c *gin.Context) {
 		return
 	}
 	i.Name = c.Param("name")
+	if err := i.CheckLayout(); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
 	if err := lc.Repo.Update(&i); err != nil {
 		response.FailErr(err, c)
 		return
@@ -5,6 +5,7 @@ import (
 	"apiserver/internal/usecase"
 	"apiserver/internal/usecase/grpcapi"
 	"apiserver/internal/usecase/logic"
+	"common/consistency"
 	"common/response"
 	"common/util"
 	"fmt"
@@ -35,7 +36,12 @@ func (mc *MetadataController) Get(c *gin.Context) {
 		response.FailErr(err, c)
 		return
 	}
-	data, err := mc.Service.GetMetadata(body.Name, body.Bucket, body.Version, true)
+	level, err := consistency.Parse(c.GetHeader(consistency.Header))
+	if err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	data, err := mc.Service.GetMetadata(body.Name, body.Bucket, body.Version, true, level)
 	if err != nil {
 		response.FailErr(err, c)
 		return
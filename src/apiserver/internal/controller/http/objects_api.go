@@ -3,12 +3,18 @@ package http
 import (
 	"apiserver/internal/entity"
 	"apiserver/internal/usecase"
+	"apiserver/internal/usecase/componet/auth"
+	"apiserver/internal/usecase/pool"
 	"common/datasize"
 	"common/logs"
+	"common/proto/msg"
 	"common/response"
 	"common/util"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"io"
+	"net/http"
+	"strings"
 )
 
 type ObjectsController struct {
@@ -22,7 +28,11 @@ func NewObjectsController(obj usecase.IObjectService, meta usecase.IMetaService)
 
 func (oc *ObjectsController) Register(r gin.IRoutes) {
 	r.PUT("/objects/:name", oc.ValidatePut, oc.Put)
+	r.POST("/objects", oc.Bulk)
+	r.DELETE("/objects", oc.BulkDelete)
+	r.GET("/objects", oc.Query)
 	r.GET("/objects/:name", oc.Get)
+	r.DELETE("/objects/:name", oc.Delete)
 }
 
 func (oc *ObjectsController) Put(c *gin.Context) {
@@ -40,6 +50,10 @@ func (oc *ObjectsController) Put(c *gin.Context) {
 			Hash:          req.Hash,
 			StoreStrategy: req.Store,
 			Compress:      req.Compress,
+			Ts:            req.ReplicaTs,
+			Replication:   util.IfElse(req.ReplicaTs > 0, msg.ReplicaReplica, ""),
+			ContentType:   req.MediaType,
+			Tags:          req.Tags,
 		}},
 	})
 
@@ -55,6 +69,100 @@ func (oc *ObjectsController) Put(c *gin.Context) {
 	}, c)
 }
 
+// Bulk uploads small objects in a multipart form, each file is an object named by its filename.
+// metadata of objects on the same metaserver are saved atomically.
+func (oc *ObjectsController) Bulk(c *gin.Context) {
+	bucket := c.GetHeader("bucket")
+	if bucket == "" {
+		response.BadRequestMsg("header 'bucket' required", c)
+		return
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	var query struct {
+		Store entity.ObjectStrategy `form:"ss"`
+	}
+	if err = c.ShouldBindQuery(&query); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	conf := pool.Config.Object.Bulk
+	var objs []*entity.BulkObject
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			response.BadRequestErr(err, c)
+			return
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		if len(objs) >= conf.MaxCount {
+			response.BadRequestMsg(fmt.Sprintf("at most %d objects in a request", conf.MaxCount), c)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(part, int64(conf.MaxSize)+1))
+		if err != nil {
+			response.BadRequestErr(err, c)
+			return
+		}
+		if len(data) == 0 || datasize.DataSize(len(data)) > conf.MaxSize {
+			response.BadRequestMsg(fmt.Sprintf("size of %s must be in (0, %s]", part.FileName(), conf.MaxSize), c)
+			return
+		}
+		objs = append(objs, &entity.BulkObject{
+			Name:      part.FileName(),
+			Store:     storeOf(query.Store, int64(len(data))),
+			MediaType: part.Header.Get("Content-Type"),
+			Data:      data,
+		})
+	}
+	if len(objs) == 0 {
+		response.BadRequestMsg("no object in form", c)
+		return
+	}
+	results, err := oc.objectService.StoreObjects(bucket, objs)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			c.JSON(http.StatusMultiStatus, results)
+			return
+		}
+	}
+	response.CreatedJson(results, c)
+}
+
+// BulkDelete removes objects named by query 'name' with all their versions,
+// objects on the same metaserver are removed atomically.
+func (oc *ObjectsController) BulkDelete(c *gin.Context) {
+	bucket, names := c.GetHeader("bucket"), c.QueryArray("name")
+	if bucket == "" {
+		response.BadRequestMsg("header 'bucket' required", c)
+		return
+	}
+	if max := pool.Config.Object.Bulk.MaxCount; len(names) == 0 || len(names) > max {
+		response.BadRequestMsg(fmt.Sprintf("number of query 'name' must be in [1, %d]", max), c)
+		return
+	}
+	results := oc.objectService.RemoveObjects(bucket, names)
+	for _, res := range results {
+		if res.Error != "" {
+			c.JSON(http.StatusMultiStatus, results)
+			return
+		}
+	}
+	response.OkJson(results, c)
+}
+
 func (oc *ObjectsController) Get(c *gin.Context) {
 	var req entity.GetReq
 	if e := req.Bind(c); e != nil {
@@ -62,7 +170,7 @@ func (oc *ObjectsController) Get(c *gin.Context) {
 		return
 	}
 	// get metadata
-	metaData, err := oc.metaService.GetMetadata(req.Name, req.Bucket, req.Version, false)
+	metaData, err := oc.metaService.GetMetadata(req.Name, req.Bucket, req.Version, false, req.Consistency)
 	if err != nil {
 		response.FailErr(err, c).Abort()
 		return
@@ -74,6 +182,9 @@ func (oc *ObjectsController) Get(c *gin.Context) {
 		return
 	}
 	defer util.CloseAndLog(stream)
+	if ct := metaData.Versions[0].ContentType; ct != "" {
+		c.Header("Content-Type", ct)
+	}
 	// try seek
 	if tp, ok := req.Range.GetFirstBytes(); ok {
 		if _, err = stream.Seek(tp.First, io.SeekCurrent); err != nil {
@@ -93,6 +204,67 @@ func (oc *ObjectsController) Get(c *gin.Context) {
 	}, c)
 }
 
+// Query finds objects of bucket by size, time, content type and tags. results are sorted and paged by cursor.
+func (oc *ObjectsController) Query(c *gin.Context) {
+	var req entity.QueryReq
+	if err := entity.Bind(c, &req, false); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	for _, tag := range req.Tags {
+		if k, _, ok := strings.Cut(tag, "="); !ok || k == "" {
+			response.BadRequestMsg("tag must be in form of 'key=value'", c)
+			return
+		}
+	}
+	if _, err := msg.ParseQueryCursor(req.Cursor); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	items, cursor, err := oc.metaService.QueryVersions(&msg.Query{
+		Bucket:      req.Bucket,
+		MinSize:     req.MinSize,
+		MaxSize:     req.MaxSize,
+		After:       req.After,
+		Before:      req.Before,
+		ContentType: req.ContentType,
+		Tags:        req.Tags,
+		Sort:        req.Sort,
+		Desc:        req.Desc,
+		Limit:       util.IfElse(req.Limit > 0, req.Limit, 100),
+		Cursor:      req.Cursor,
+	})
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(&entity.QueryResp{Items: items, Cursor: cursor}, c)
+}
+
+// Delete removes versions of object written before query 'before' or at query 'ts' in milliseconds
+func (oc *ObjectsController) Delete(c *gin.Context) {
+	body := struct {
+		Name   string `uri:"name" binding:"required"`
+		Bucket string `header:"bucket" binding:"required"`
+		Before int64  `form:"before"`
+		Ts     int64  `form:"ts"`
+	}{}
+	if err := entity.Bind(c, &body, false); err != nil {
+		response.BadRequestErr(err, c)
+		return
+	}
+	if (body.Before > 0) == (body.Ts > 0) {
+		response.BadRequestMsg("either 'before' or 'ts' is required", c)
+		return
+	}
+	n, err := oc.objectService.RemoveVersionsByTs(body.Name, body.Bucket, util.IfElse(body.Ts > 0, body.Ts, body.Before), body.Ts > 0)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(gin.H{"removed": n}, c)
+}
+
 func (oc *ObjectsController) ValidatePut(g *gin.Context) {
 	var req entity.PutReq
 	if err := req.Bind(g); err != nil {
@@ -103,13 +275,11 @@ func (oc *ObjectsController) ValidatePut(g *gin.Context) {
 		response.BadRequestMsg("empty request", g).Abort()
 		return
 	}
-	if req.Store == 0 {
-		if g.Request.ContentLength > int64(datasize.KB*64) {
-			req.Store = entity.ECReedSolomon
-		} else {
-			req.Store = entity.MultiReplication
-		}
+	if req.ReplicaTs != 0 && !g.GetBool(auth.ReplicaPeerKey) {
+		response.FailErr(response.NewError(http.StatusForbidden, "replica-ts is only accepted from authenticated replication peers"), g).Abort()
+		return
 	}
+	req.Store = storeOf(req.Store, g.Request.ContentLength)
 	if ext, ok := util.GetFileExt(req.Name, false); ok {
 		req.Ext = ext
 	} else {
@@ -117,3 +287,15 @@ func (oc *ObjectsController) ValidatePut(g *gin.Context) {
 	}
 	g.Set("PutReq", &req)
 }
+
+// replicateSize is the size under which objects are replicated by default, larger ones are erasure-coded
+const replicateSize = 64 * datasize.KB
+
+// storeOf returns the strategy of request, or the default one by size if it's not set.
+// the strategy of bucket overrides both of them on making versions.
+func storeOf(store entity.ObjectStrategy, size int64) entity.ObjectStrategy {
+	if store != 0 {
+		return store
+	}
+	return util.IfElse(size > int64(replicateSize), entity.ECReedSolomon, entity.MultiReplication)
+}
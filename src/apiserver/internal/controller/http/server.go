@@ -17,10 +17,12 @@ type Server struct {
 }
 
 func NewHttpServer(port string, o IObjectService, m IMetaService, b repo.IBucketRepo) *Server {
+	passwordValidator := auth.NewPasswordValidator(pool.Etcd, &pool.Config.Auth.Password)
 	authMid := auth.AuthenticationMiddleware(&pool.Config.Auth,
 		auth.NewCallbackValidator(&pool.Config.Auth.Callback),
-		auth.NewPasswordValidator(pool.Etcd, &pool.Config.Auth.Password),
+		passwordValidator,
 	)
+	authMid = append(authMid, auth.ReplicaPeer(passwordValidator))
 
 	eng := gin.New()
 	eng.Use(gin.LoggerWithWriter(logs.Std().Out), gin.RecoveryWithWriter(logs.Std().Out))
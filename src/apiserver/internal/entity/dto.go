@@ -1,12 +1,14 @@
 package entity
 
 import (
+	"common/consistency"
 	"common/request"
 	"common/response"
 
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -20,26 +22,53 @@ type PutResp struct {
 	Version int32  `json:"version"`
 }
 
+// CompressAuto is the value of query 'compress' deciding compression by sampling data
+const CompressAuto = "auto"
+
 type PutReq struct {
-	Store    ObjectStrategy `form:"ss"`
-	Compress bool           `form:"compress"`
-	Name     string         `uri:"name" binding:"required"`
-	Bucket   string         `header:"bucket" binding:"required"`
-	Hash     string         `header:"digest" binding:"required"`
-	Ext      string
-	Locate   []string
-	Body     io.Reader
+	Store     ObjectStrategy `form:"ss"`
+	Mode      string         `form:"compress"` // Mode is the query 'compress': true, false or auto
+	Compress  bool           `form:"-"`
+	Auto      bool           `form:"-"` // Auto decides Compress by sampling data
+	Name      string         `uri:"name" binding:"required"`
+	Bucket    string         `header:"bucket" binding:"required"`
+	Hash      string         `header:"digest" binding:"required"`
+	ReplicaTs int64          `header:"replica-ts"` // ReplicaTs is the time of source version if it is a replica from other cluster
+	MediaType string         `header:"content-type"`
+	Tagging   string         `header:"tagging"` // Tagging is tags of object in form of url query "k1=v1&k2=v2"
+	Tags      map[string]string
+	Ext       string
+	Locate    []string
+	Body      io.Reader
+}
+
+// BulkObject is a small object uploaded with others in a request
+type BulkObject struct {
+	Name      string
+	Store     ObjectStrategy
+	MediaType string
+	Data      []byte
+}
+
+// BulkResult is the result of saving a bulk object, Error is not empty if fails
+type BulkResult struct {
+	Name    string `json:"name"`
+	Version int32  `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
 }
 
 type GetReq struct {
-	Name    string `uri:"name" binding:"required"`
-	Bucket  string `header:"bucket" binding:"required"`
-	Version int32  `form:"version" binding:"min=0"`
-	Range   request.Range
+	Name        string `uri:"name" binding:"required"`
+	Bucket      string `header:"bucket" binding:"required"`
+	Version     int32  `form:"version" binding:"min=0"`
+	Range       request.Range
+	Consistency consistency.Level // Consistency is the read consistency of metadata in header 'Read-Consistency'
 }
 
 type BigPostReq struct {
-	Compress bool   `form:"compress"`
+	Mode     string `form:"compress"` // Mode is the query 'compress': true, false or auto
+	Compress bool   `form:"-"`
+	Auto     bool   `form:"-"` // Auto decides Compress by extension since data is not uploaded yet
 	Name     string `uri:"name" binding:"required"`
 	Bucket   string `header:"bucket" binding:"required"`
 	Hash     string `header:"digest" binding:"required"`
@@ -56,7 +85,23 @@ func (b *BigPostReq) Bind(c *gin.Context) error {
 	if err := BindAll(c, b, binding.Header, binding.Query, binding.Uri); err != nil {
 		return err
 	}
-	return nil
+	var err error
+	b.Compress, b.Auto, err = parseCompress(b.Mode)
+	return err
+}
+
+// parseCompress parses query 'compress'
+func parseCompress(mode string) (compress, auto bool, err error) {
+	if mode == "" {
+		return false, false, nil
+	}
+	if mode == CompressAuto {
+		return false, true, nil
+	}
+	if compress, err = strconv.ParseBool(mode); err != nil {
+		return false, false, response.NewError(http.StatusBadRequest, "query 'compress' must be true, false or auto")
+	}
+	return compress, false, nil
 }
 
 func (bigPut *BigPutReq) Bind(c *gin.Context) error {
@@ -79,9 +124,47 @@ func (p *PutReq) Bind(c *gin.Context) error {
 	if err := BindAll(c, p, binding.Uri, binding.Header, binding.Query); err != nil {
 		return err
 	}
+	var err error
+	if p.Compress, p.Auto, err = parseCompress(p.Mode); err != nil {
+		return err
+	}
+	if p.Tagging == "" {
+		return nil
+	}
+	values, err := url.ParseQuery(p.Tagging)
+	if err != nil {
+		return response.NewError(http.StatusBadRequest, "header 'Tagging' format error")
+	}
+	p.Tags = make(map[string]string, len(values))
+	for k, v := range values {
+		if k == "" || len(v) != 1 {
+			return response.NewError(http.StatusBadRequest, "header 'Tagging' format error")
+		}
+		p.Tags[k] = v[0]
+	}
 	return nil
 }
 
+// QueryReq finds objects of bucket by filters, see msg.Query
+type QueryReq struct {
+	Bucket      string   `header:"bucket" binding:"required"`
+	MinSize     int64    `form:"min_size" binding:"min=0"`
+	MaxSize     int64    `form:"max_size" binding:"min=0"`
+	After       int64    `form:"after" binding:"min=0"`
+	Before      int64    `form:"before" binding:"min=0"`
+	ContentType string   `form:"content_type"`
+	Tags        []string `form:"tag"`
+	Sort        string   `form:"sort" binding:"omitempty,oneof=name size ts"`
+	Desc        bool     `form:"desc"`
+	Limit       int      `form:"limit" binding:"min=0,max=1000"`
+	Cursor      string   `form:"cursor"`
+}
+
+type QueryResp struct {
+	Items  []*Metadata `json:"items"`
+	Cursor string      `json:"cursor"` // Cursor is used to query next page, no more results if it's the same as request
+}
+
 func (g *GetReq) Bind(c *gin.Context) error {
 	g.Version = int32(VerModeLast)
 	if err := BindAll(c, g, binding.Uri, binding.Header, binding.Query); err != nil {
@@ -92,5 +175,9 @@ func (g *GetReq) Bind(c *gin.Context) error {
 			return response.NewError(400, "header 'Range' format error")
 		}
 	}
+	var err error
+	if g.Consistency, err = consistency.Parse(c.GetHeader(consistency.Header)); err != nil {
+		return response.NewError(400, err.Error())
+	}
 	return nil
 }
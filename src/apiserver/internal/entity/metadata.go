@@ -4,6 +4,8 @@ import (
 	"apiserver/config"
 	"common/cst"
 	"common/util/math"
+	"fmt"
+	"time"
 )
 
 type VerMode int32
@@ -22,8 +24,13 @@ type ObjectStrategy int8
 const (
 	ECReedSolomon ObjectStrategy = 1 << iota
 	MultiReplication
+	LocalReconstruction
+	Inline
 )
 
+// MaxInlineLimit is the hard limit of inlineLimit, larger data in versions bloats raft logs and migrations of metadata
+const MaxInlineLimit int64 = 64 << 10
+
 type Extra struct {
 	Total        int `json:"total"`
 	FirstVersion int `json:"firstVersion"`
@@ -48,43 +55,111 @@ func (m *Metadata) LastVersion() *Version {
 
 type Version struct {
 	Compress      bool           `json:"compress"`
+	Codec         string         `json:"codec,omitempty"` // Codec compresses shards if Compress is true, s2 if empty
+	CompressRatio float32        `json:"compressRatio,omitempty"` // CompressRatio is the estimated compressed size divided by size, 0 if not sampled
 	Hash          string         `json:"hash"`
+	Digest        string         `json:"digest,omitempty"` // Digest is the sha256 of content which Hash is generated from, empty for versions saved before it
 	StoreStrategy ObjectStrategy `json:"storeStrategy"`
 	Sequence      int32          `json:"sequence"`
 	Size          int64          `json:"size"`
 	Ts            int64          `json:"ts"`
+	AccessTs      int64          `json:"accessTs"`
 	DataShards    int            `json:"dataShards"`
 	ParityShards  int            `json:"parityShards"`
+	LocalGroups   int            `json:"localGroups,omitempty"` // LocalGroups is the number of local parity shards of LocalReconstruction
 	ShardSize     int            `json:"shardSize"`
 	Locate        []string       `json:"locate"`
+	Replication   string         `json:"replication,omitempty"` // Replication is the status of replicating to remote cluster
+	ContentType   string            `json:"contentType,omitempty"` // ContentType is the media type given on uploading
+	Tags          map[string]string `json:"tags,omitempty"`        // Tags are user defined labels given by header 'Tagging'
+	Inline        []byte            `json:"-"`                     // Inline is data of object stored in version if StoreStrategy is Inline
+}
+
+// Tolerance returns the max number of shards allowed to lose
+func (v *Version) Tolerance() int {
+	if v.StoreStrategy == MultiReplication {
+		return v.DataShards - 1
+	}
+	// LocalReconstruction repairs a local group losing only one shard in it, which is not counted here
+	return v.ParityShards
+}
+
+// AllShards returns the number of shards stored
+func (v *Version) AllShards() int {
+	return v.DataShards + v.ParityShards + v.LocalGroups
 }
 
 type Bucket struct {
-	Versioning     bool           `json:"versioning"`     // Versioning marks bucket can store multi versions of object. if true, VersionRemains will be used
-	Readonly       bool           `json:"readonly"`       // Readonly marks objects in bucket only allowed to read
-	Compress       bool           `json:"compress"`       // Compress marks objects in bucket should be compressed before store
-	StoreStrategy  ObjectStrategy `json:"storeStrategy"`  // StoreStrategy if not zero, it will apply to ever objects under this bucket
-	DataShards     int            `json:"dataShards"`     // DataShards used when StoreStrategy is not zero
-	ParityShards   int            `json:"parityShards"`   // ParityShards used when StoreStrategy is not zero
-	VersionRemains int            `json:"versionRemains"` // VersionRemains is maximum number of remained versions
-	CreateTime     int64          `json:"createTime"`     // CreateTime is bucket created time
-	UpdateTime     int64          `json:"updateTime"`     // UpdateTime is last updating time
-	Name           string         `json:"name"`           // Name is the bucket's name
-	Policies       []string       `json:"policies"`       // Policies is the iam polices for this bucket (No support yet)
+	Versioning     bool           `json:"versioning"`             // Versioning marks bucket can store multi versions of object. if true, VersionRemains will be used
+	Readonly       bool           `json:"readonly"`               // Readonly marks objects in bucket only allowed to read
+	Compress       bool           `json:"compress"`               // Compress marks objects in bucket should be compressed before store
+	Codec          string         `json:"codec,omitempty" binding:"omitempty,oneof=s2 zstd"` // Codec is used to compress objects, s2 or zstd. s2 if empty
+	AutoCompress   bool           `json:"autoCompress"`           // AutoCompress compresses objects only if they are sampled compressible, ignored if Compress is true
+	StoreStrategy  ObjectStrategy `json:"storeStrategy"`          // StoreStrategy if not zero, it will apply to ever objects under this bucket
+	DataShards     int            `json:"dataShards"`             // DataShards used when StoreStrategy is not zero
+	ParityShards   int            `json:"parityShards"`           // ParityShards used when StoreStrategy is not zero, global parity shards of LocalReconstruction
+	LocalGroups    int            `json:"localGroups,omitempty"`  // LocalGroups used when StoreStrategy is LocalReconstruction, DataShards must be divisible by it
+	InlineLimit    int64          `json:"inlineLimit,omitempty"`  // InlineLimit is the size under which objects are stored Inline. default of config if zero, negative to disable
+	VersionRemains int            `json:"versionRemains"`         // VersionRemains is maximum number of remained versions
+	CreateTime     int64          `json:"createTime"`             // CreateTime is bucket created time
+	UpdateTime     int64          `json:"updateTime"`             // UpdateTime is last updating time
+	Name           string         `json:"name"`                   // Name is the bucket's name
+	Policies       []string       `json:"policies"`               // Policies is the iam polices for this bucket (No support yet)
+	Notification   *Notification  `json:"notification,omitempty"` // Notification publishes events of objects in bucket if not nil
+	Tiering        *Tiering       `json:"tiering,omitempty"`      // Tiering overrides the tiering config for objects in bucket if not nil
+}
+
+// Tiering is the policy of transcoding cold objects in bucket
+type Tiering struct {
+	Disabled     bool  `json:"disabled"`               // Disabled marks objects in bucket are never transcoded
+	ColdAfter    int64 `json:"coldAfter,omitempty"`    // ColdAfter is seconds of objects not read or written before they are cold. default of config if zero, shorter than config is not supported
+	DataShards   int32 `json:"dataShards,omitempty"`   // DataShards of cold layout, default of config if zero
+	ParityShards int32 `json:"parityShards,omitempty"` // ParityShards of cold layout, default of config if zero
+}
+
+// Notification configures events of bucket published by metadata servers
+type Notification struct {
+	Events  []string `json:"events"`  // Events are types to publish, all types if empty
+	Webhook string   `json:"webhook"` // Webhook is the url receiving POST of events
+	Secret  string   `json:"secret"`  // Secret signs webhook requests
+}
+
+// inlineLimit returns the size under which objects are stored Inline, no more than MaxInlineLimit
+func (b *Bucket) inlineLimit(conf *config.ObjectConfig) int64 {
+	if b.InlineLimit != 0 {
+		return math.MinNumber(b.InlineLimit, MaxInlineLimit)
+	}
+	return math.MinNumber(int64(conf.InlineLimit), MaxInlineLimit)
 }
 
 func (b *Bucket) MakeVersion(ver *Version, conf *config.ObjectConfig) {
+	// tiny objects are stored in version without shards or compression
+	if ver.Size < b.inlineLimit(conf) {
+		ver.StoreStrategy = Inline
+		ver.Compress, ver.Codec = false, ""
+		ver.DataShards, ver.ParityShards, ver.LocalGroups = 0, 0, 0
+		ver.ShardSize = int(ver.Size)
+		return
+	}
 	if b.Compress {
 		ver.Compress = true
+		ver.Codec = b.Codec
 	}
 	// copy of config
-	rsConf, rpConf := conf.ReedSolomon, conf.Replication
+	rsConf, rpConf, lrcConf := conf.ReedSolomon, conf.Replication, conf.LocalReconstruction
 
 	if b.StoreStrategy > 0 {
 		ver.StoreStrategy = b.StoreStrategy
 		rsConf.DataShards = b.DataShards
 		rsConf.ParityShards = b.ParityShards
 		rpConf.CopiesCount = b.DataShards
+		lrcConf.DataShards = b.DataShards
+		lrcConf.GlobalParity = b.ParityShards
+		lrcConf.LocalGroups = b.LocalGroups
+	}
+	// larger objects are never stored Inline
+	if ver.StoreStrategy == Inline {
+		ver.StoreStrategy = ECReedSolomon
 	}
 
 	switch ver.StoreStrategy {
@@ -97,9 +172,55 @@ func (b *Bucket) MakeVersion(ver *Version, conf *config.ObjectConfig) {
 	case MultiReplication:
 		ver.DataShards = rpConf.CopiesCount
 		ver.ShardSize = int(ver.Size)
+	case LocalReconstruction:
+		ver.DataShards = lrcConf.DataShards
+		ver.ParityShards = lrcConf.GlobalParity
+		ver.LocalGroups = lrcConf.LocalGroups
+		ver.ShardSize = lrcConf.ShardSize(ver.Size)
 	}
 }
 
+// CheckLayout returns an error if shards of StoreStrategy or tiering are invalid
+func (b *Bucket) CheckLayout() error {
+	if b.StoreStrategy == Inline {
+		return fmt.Errorf("objects are stored inline by inlineLimit instead of storeStrategy")
+	}
+	if b.InlineLimit > MaxInlineLimit {
+		return fmt.Errorf("inlineLimit must not be larger than %d", MaxInlineLimit)
+	}
+	if t := b.Tiering; t != nil && (t.ColdAfter < 0 || t.DataShards < 0 || t.ParityShards < 0) {
+		return fmt.Errorf("tiering coldAfter, dataShards and parityShards must not be negative")
+	}
+	if b.StoreStrategy != LocalReconstruction {
+		return nil
+	}
+	if b.LocalGroups <= 0 || b.DataShards%b.LocalGroups != 0 {
+		return fmt.Errorf("dataShards %d must be divisible by localGroups %d", b.DataShards, b.LocalGroups)
+	}
+	return nil
+}
+
+// TieringConf returns the tiering config applied to objects in bucket
+func (b *Bucket) TieringConf(conf *config.TieringConfig) (cfg config.TieringConfig) {
+	cfg = *conf
+	t := b.Tiering
+	if t == nil {
+		return
+	}
+	cfg.Enabled = cfg.Enabled && !t.Disabled
+	// objects are scanned by the config, a shorter one makes no difference
+	if d := time.Duration(t.ColdAfter) * time.Second; d > cfg.ColdAfter {
+		cfg.ColdAfter = d
+	}
+	if t.DataShards > 0 {
+		cfg.DataShards = int(t.DataShards)
+	}
+	if t.ParityShards > 0 {
+		cfg.ParityShards = int(t.ParityShards)
+	}
+	return
+}
+
 func (b *Bucket) MakeConf(conf *config.ObjectConfig, objectSize int64) (cfg config.ObjectConfig) {
 	// copy of config
 	cfg = *conf
@@ -113,6 +234,10 @@ func (b *Bucket) MakeConf(conf *config.ObjectConfig, objectSize int64) (cfg conf
 		}
 	case MultiReplication:
 		cfg.Replication.CopiesCount = b.DataShards
+	case LocalReconstruction:
+		cfg.LocalReconstruction.DataShards = b.DataShards
+		cfg.LocalReconstruction.GlobalParity = b.ParityShards
+		cfg.LocalReconstruction.LocalGroups = b.LocalGroups
 	}
 	return
 }
@@ -10,6 +10,7 @@ import (
 
 const MiddleKey = "IsAuthenticated"
 const MiddleErr = "AuthenticatedErr"
+const ReplicaPeerKey = "IsReplicaPeer"
 
 func PreAuthenticate(cfg *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -60,3 +61,18 @@ func AuthenticationMiddleware(cfg *Config, authenticators ...Verification) gin.H
 	}
 	return append(chain, AfterAuthenticate)
 }
+
+// ReplicaPeer marks requests with Replica-Ts header as from replication peers of other clusters if they
+// carry valid password credentials. only peers are trusted to write replicas at the time of source versions.
+func ReplicaPeer(validator *PasswordValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Replica-Ts") == "" {
+			return
+		}
+		ok, err := validator.Middleware(c)
+		if err != nil {
+			logs.Std().Infof("authenticate replication peer fail: %s", err)
+		}
+		c.Set(ReplicaPeerKey, ok)
+	}
+}
@@ -0,0 +1,96 @@
+package selector
+
+import (
+	"apiserver/internal/usecase/webapi"
+	"common/balance"
+	"common/logs"
+	"common/util"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	entropyNodeMap   = map[string]balance.Node{}
+	entropyLock      = sync.RWMutex{}
+	entropyUpdatedAt time.Time
+	entropyFetching  atomic.Bool
+)
+
+// entropyDelta is the load increment assumed for a selection
+const entropyDelta = 0.01
+
+// StorageEntropy selects the server which makes the loads of all servers the most balanced,
+// i.e. the max storage entropy. load is combined by disk usage and weighted io.
+type StorageEntropy struct {
+}
+
+const EntropyFirst SelectStrategy = "entropy"
+
+// fetchStat requests states of servers without holding the lock, only one fetching runs at the same time
+func (s *StorageEntropy) fetchStat(ds []string) {
+	if !entropyFetching.CompareAndSwap(false, true) {
+		return
+	}
+	defer entropyFetching.Store(false)
+	stats := make(map[string]balance.Node, len(ds))
+	for _, ip := range ds {
+		hd, err := webapi.StatObject(ip)
+		if err != nil {
+			logs.Std().Errorf("update storage info of %s err: %s", ip, err)
+			continue
+		}
+		stats[ip] = balance.Node{
+			Used:   util.ToInt64(hd.Get("Capacity")),
+			Total:  util.ToInt64(hd.Get("Total-Space")),
+			Free:   util.ToInt64(hd.Get("Free-Space")),
+			IOLoad: float64(util.ToInt32(hd.Get("Weighted-IO"))),
+		}
+	}
+	entropyLock.Lock()
+	defer entropyLock.Unlock()
+	for _, ip := range ds {
+		if n, ok := stats[ip]; ok {
+			entropyNodeMap[ip] = n
+		} else {
+			delete(entropyNodeMap, ip)
+		}
+	}
+	entropyUpdatedAt = time.Now()
+}
+
+// selectIndex returns the index of server in ds making the max storage entropy
+func (s *StorageEntropy) selectIndex(ds []string) int {
+	entropyLock.RLock()
+	updatedAt := entropyUpdatedAt
+	entropyLock.RUnlock()
+	if time.Since(updatedAt) > 1*time.Minute {
+		s.fetchStat(ds)
+	}
+	nodes := make([]balance.Node, len(ds))
+	entropyLock.RLock()
+	for i, ip := range ds {
+		n, ok := entropyNodeMap[ip]
+		// unreachable server has the max load
+		n.Unknown = !ok
+		nodes[i] = n
+	}
+	entropyLock.RUnlock()
+	return balance.SelectIndex(balance.Loads(nodes, balance.DefaultWeights), entropyDelta)
+}
+
+func (s *StorageEntropy) Pop(ds []string) ([]string, string) {
+	idx := s.selectIndex(ds)
+	res := ds[idx]
+	ds[0], ds[idx] = ds[idx], ds[0]
+	return ds[1:], res
+}
+
+// Select returns the selected server, ds is not changed
+func (s *StorageEntropy) Select(ds []string) string {
+	return ds[s.selectIndex(ds)]
+}
+
+func (s *StorageEntropy) Strategy() SelectStrategy {
+	return EntropyFirst
+}
@@ -27,6 +27,8 @@ func NewSelector(str string) Selector {
 		sec = &FreeSpaceFirst{}
 	case IOFirst:
 		sec = &WeightedIOFirst{}
+	case EntropyFirst:
+		sec = &StorageEntropy{}
 	default:
 		log.Panicf("Not allowed selector strategy: %v", str)
 	}
@@ -0,0 +1,60 @@
+package selector
+
+import (
+	"common/registry"
+)
+
+var domainLevels = []registry.DomainLevel{registry.LevelZone, registry.LevelRack, registry.LevelHost}
+
+// SpreadSelect selects size servers from ips and spreads them over failure domains as far as possible.
+// a server will be reused only when there are fewer servers than size.
+// servers are preferred in the order given by selector when they have the same spread.
+func SpreadSelect(sel Selector, ips []string, topo map[string]registry.Topology, size int) []string {
+	if len(ips) == 0 {
+		return []string{}
+	}
+	// order servers by preference of selector
+	order := make([]string, 0, len(ips))
+	remains := append(make([]string, 0, len(ips)), ips...)
+	for len(remains) > 0 {
+		var ip string
+		remains, ip = sel.Pop(remains)
+		order = append(order, ip)
+	}
+	// counts[i] is the shards number of each domain at domainLevels[i], the last one is of each server
+	counts := make([]map[string]int, len(domainLevels)+1)
+	for i := range counts {
+		counts[i] = make(map[string]int)
+	}
+	domainsOf := func(ip string) []string {
+		t := topo[ip]
+		ds := make([]string, 0, len(counts))
+		for _, lv := range domainLevels {
+			ds = append(ds, t.Domain(lv))
+		}
+		return append(ds, ip)
+	}
+	// less compares the counts of domains from the top level
+	less := func(a, b []string) bool {
+		for i := range counts {
+			if ca, cb := counts[i][a[i]], counts[i][b[i]]; ca != cb {
+				return ca < cb
+			}
+		}
+		return false
+	}
+	res := make([]string, 0, size)
+	for len(res) < size {
+		best, bestDomains := order[0], domainsOf(order[0])
+		for _, ip := range order[1:] {
+			if ds := domainsOf(ip); less(ds, bestDomains) {
+				best, bestDomains = ip, ds
+			}
+		}
+		for i, d := range bestDomains {
+			counts[i][d]++
+		}
+		res = append(res, best)
+	}
+	return res
+}
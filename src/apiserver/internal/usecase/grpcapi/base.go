@@ -3,8 +3,12 @@ package grpcapi
 import (
 	"common/performance"
 	"common/util"
+	"context"
 	"errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
 	"sync"
 	"time"
 )
@@ -26,7 +30,7 @@ func getConn(addr string) (*grpc.ClientConn, error) {
 		return conn, nil
 	}
 	var err error
-	conn, err = grpc.Dial(addr, grpc.WithInsecure())
+	conn, err = grpc.Dial(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(followMovedUnary))
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +38,28 @@ func getConn(addr string) (*grpc.ClientConn, error) {
 	return conn, nil
 }
 
+type movedKey struct{}
+
+// followMovedUnary calls again on the server which the key has moved to. metadata server responses Aborted with
+// address of the owner if the key is not in its slots, e.g. slot-info of caller is stale after migration.
+// a call is redirected at most once.
+func followMovedUnary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Aborted || ctx.Value(movedKey{}) != nil {
+		return err
+	}
+	addr := st.Message()
+	if _, _, inner := net.SplitHostPort(addr); inner != nil || addr == cc.Target() {
+		return err
+	}
+	conn, inner := getConn(addr)
+	if inner != nil {
+		return err
+	}
+	return conn.Invoke(context.WithValue(ctx, movedKey{}, addr), method, req, reply, opts...)
+}
+
 func Close() error {
 	var errs []error
 	for _, v := range connPool {
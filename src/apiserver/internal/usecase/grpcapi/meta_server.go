@@ -2,20 +2,23 @@ package grpcapi
 
 import (
 	"apiserver/internal/entity"
+	"common/consistency"
 	"common/proto"
 	"common/proto/msg"
 	"common/proto/pb"
 	"common/util"
 	"context"
+	"fmt"
+	"strings"
 )
 
-func GetMetadata(ip, id string, withExtra bool) (*entity.Metadata, error) {
+func GetMetadata(ip, id string, withExtra bool, level consistency.Level) (*entity.Metadata, error) {
 	defer perform(false)()
 	conn, err := getConn(ip)
 	if err != nil {
 		return nil, err
 	}
-	ctx := context.Background()
+	ctx := consistency.OutgoingContext(context.Background(), level)
 	cli := pb.NewMetadataApiClient(conn)
 	resp, err := cli.GetMetadata(ctx, &pb.MetaReq{Id: id, WithExtra: withExtra})
 	if err = proto.ResolveErr(err); err != nil {
@@ -41,13 +44,13 @@ func GetMetadata(ip, id string, withExtra bool) (*entity.Metadata, error) {
 	return res, nil
 }
 
-func GetVersion(ip, id string, verNum int32) (*entity.Version, error) {
+func GetVersion(ip, id string, verNum int32, level consistency.Level) (*entity.Version, error) {
 	defer perform(false)()
 	conn, err := getConn(ip)
 	if err != nil {
 		return nil, err
 	}
-	ctx := context.Background()
+	ctx := consistency.OutgoingContext(context.Background(), level)
 	cli := pb.NewMetadataApiClient(conn)
 	resp, err := cli.GetVersion(ctx, &pb.MetaReq{Id: id, Version: verNum})
 	if err = proto.ResolveErr(err); err != nil {
@@ -57,18 +60,31 @@ func GetVersion(ip, id string, verNum int32) (*entity.Version, error) {
 	if err = util.DecodeMsgp(&v, resp.Data); err != nil {
 		return nil, err
 	}
+	return toVersion(&v), nil
+}
+
+func toVersion(v *msg.Version) *entity.Version {
 	return &entity.Version{
 		Compress:      v.Compress,
+		Codec:         v.Codec,
+		CompressRatio: v.CompressRatio,
 		Hash:          v.Hash,
+		Digest:        v.Digest,
 		StoreStrategy: entity.ObjectStrategy(v.StoreStrategy),
 		Sequence:      int32(v.Sequence),
 		Size:          v.Size,
 		Ts:            v.Ts,
+		AccessTs:      v.AccessTs,
 		DataShards:    int(v.DataShards),
 		ParityShards:  int(v.ParityShards),
+		LocalGroups:   int(v.LocalGroups),
+		Inline:        v.Inline,
 		ShardSize:     int(v.ShardSize),
 		Locate:        v.Locate,
-	}, nil
+		Replication:   v.Replication,
+		ContentType:   v.ContentType,
+		Tags:          v.Tags,
+	}
 }
 
 func GetBucket(ip, name string) (*entity.Bucket, error) {
@@ -91,14 +107,20 @@ func GetBucket(ip, name string) (*entity.Bucket, error) {
 		Versioning:     b.Versioning,
 		Readonly:       b.Readonly,
 		Compress:       b.Compress,
+		Codec:          b.Codec,
+		AutoCompress:   b.AutoCompress,
 		StoreStrategy:  entity.ObjectStrategy(b.StoreStrategy),
 		DataShards:     int(b.DataShards),
 		ParityShards:   int(b.ParityShards),
+		LocalGroups:    int(b.LocalGroups),
+		InlineLimit:    b.InlineLimit,
 		VersionRemains: int(b.VersionRemains),
 		CreateTime:     b.CreateTime,
 		UpdateTime:     b.UpdateTime,
 		Name:           b.Name,
 		Policies:       b.Policies,
+		Notification:   (*entity.Notification)(b.Notification),
+		Tiering:        (*entity.Tiering)(b.Tiering),
 	}, nil
 }
 
@@ -125,14 +147,22 @@ func UpdateVersion(ip, id string, body *entity.Version) error {
 	}
 	bt, err := util.EncodeMsgp(&msg.Version{
 		Compress:      body.Compress,
+		Codec:         body.Codec,
+		CompressRatio: body.CompressRatio,
 		StoreStrategy: int8(body.StoreStrategy),
 		DataShards:    int32(body.DataShards),
 		ParityShards:  int32(body.ParityShards),
+		LocalGroups:   int32(body.LocalGroups),
+		Inline:        body.Inline,
 		ShardSize:     int64(body.ShardSize),
 		Sequence:      uint64(body.Sequence),
 		Size:          body.Size,
 		Hash:          body.Hash,
+		Digest:        body.Digest,
 		Locate:        body.Locate,
+		Replication:   body.Replication,
+		ContentType:   body.ContentType,
+		Tags:          body.Tags,
 	})
 	_, err = pb.NewMetadataApiClient(conn).UpdateVersion(context.Background(), &pb.Metadata{
 		Id:      id,
@@ -147,25 +177,104 @@ func SaveVersion(ip, id string, body *entity.Version) (int32, error) {
 	if err != nil {
 		return 0, err
 	}
-	bt, err := util.EncodeMsgp(&msg.Version{
+	bt, err := util.EncodeMsgp(fromVersion(body))
+	res, err := pb.NewMetadataApiClient(conn).SaveVersion(context.Background(), &pb.Metadata{
+		Id:      id,
+		Version: body.Sequence,
+		Msgpack: bt,
+	})
+	if err != nil {
+		return 0, proto.ResolveErr(err)
+	}
+	return res.Data, nil
+}
+
+// fromVersion returns the version to save
+func fromVersion(body *entity.Version) *msg.Version {
+	return &msg.Version{
 		Compress:      body.Compress,
+		Codec:         body.Codec,
+		CompressRatio: body.CompressRatio,
 		StoreStrategy: int8(body.StoreStrategy),
 		DataShards:    int32(body.DataShards),
 		ParityShards:  int32(body.ParityShards),
+		LocalGroups:   int32(body.LocalGroups),
+		Inline:        body.Inline,
 		ShardSize:     int64(body.ShardSize),
 		Size:          body.Size,
+		Ts:            body.Ts,
 		Hash:          body.Hash,
+		Digest:        body.Digest,
 		Locate:        body.Locate,
-	})
-	res, err := pb.NewMetadataApiClient(conn).SaveVersion(context.Background(), &pb.Metadata{
-		Id:      id,
-		Version: body.Sequence,
-		Msgpack: bt,
-	})
+		Replication:   body.Replication,
+		ContentType:   body.ContentType,
+		Tags:          body.Tags,
+	}
+}
+
+// BatchSave saves metadata and their first versions atomically. metadata will be created if not exists.
+// all keys must be on the metaserver. returns sequences of versions.
+func BatchSave(ip string, mds []*entity.Metadata) ([]int32, error) {
+	changes := make([]*msg.Change, 0, 2*len(mds))
+	for _, md := range mds {
+		id := fmt.Sprint(md.Bucket, "/", md.Name)
+		changes = append(changes,
+			&msg.Change{Op: msg.ChangePutMetadata, Id: id, Metadata: &msg.Metadata{Name: md.Name, Bucket: md.Bucket}},
+			&msg.Change{Op: msg.ChangePutVersion, Id: id, Version: fromVersion(md.Versions[0])})
+	}
+	resp, err := batch(ip, changes)
 	if err != nil {
-		return 0, proto.ResolveErr(err)
+		return nil, err
 	}
-	return res.Data, nil
+	if len(resp.Versions) != len(changes) {
+		return nil, fmt.Errorf("batch responses %d versions of %d ops", len(resp.Versions), len(changes))
+	}
+	res := make([]int32, len(mds))
+	for i := range mds {
+		res[i] = resp.Versions[2*i+1]
+	}
+	return res, nil
+}
+
+// BatchRemove removes metadata with all their versions atomically. all keys must be on the metaserver.
+// returns removed versions whose objects should be dereferenced.
+func BatchRemove(ip string, ids []string) ([]*entity.Version, error) {
+	changes := make([]*msg.Change, len(ids))
+	for i, id := range ids {
+		changes[i] = &msg.Change{Op: msg.ChangeRemoveMetadata, Id: id}
+	}
+	resp, err := batch(ip, changes)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*entity.Version, len(resp.Removed))
+	for i, bt := range resp.Removed {
+		var v msg.Version
+		if err = util.DecodeMsgp(&v, bt); err != nil {
+			return nil, err
+		}
+		res[i] = toVersion(&v)
+	}
+	return res, nil
+}
+
+// batch applies changes in a transaction of the metaserver
+func batch(ip string, changes []*msg.Change) (*pb.BatchResp, error) {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.BatchReq{Ops: make([]*pb.BatchOp, len(changes))}
+	for i, c := range changes {
+		bt, err := util.EncodeMsgp(c)
+		if err != nil {
+			return nil, err
+		}
+		req.Ops[i] = &pb.BatchOp{Id: c.Id, Msgpack: bt}
+	}
+	resp, err := pb.NewMetadataApiClient(conn).Batch(context.Background(), req)
+	return resp, proto.ResolveErr(err)
 }
 
 func SaveMetadata(ip, id string, body *entity.Metadata) error {
@@ -195,12 +304,18 @@ func SaveBucket(ip string, body *entity.Bucket) error {
 		Versioning:     body.Versioning,
 		Readonly:       body.Readonly,
 		Compress:       body.Compress,
+		Codec:          body.Codec,
+		AutoCompress:   body.AutoCompress,
 		StoreStrategy:  int8(body.StoreStrategy),
 		DataShards:     int32(body.DataShards),
 		ParityShards:   int32(body.ParityShards),
+		LocalGroups:    int32(body.LocalGroups),
+		InlineLimit:    body.InlineLimit,
 		VersionRemains: int32(body.VersionRemains),
 		Name:           body.Name,
 		Policies:       body.Policies,
+		Notification:   (*msg.Notification)(body.Notification),
+		Tiering:        (*msg.Tiering)(body.Tiering),
 	})
 	_, err = pb.NewMetadataApiClient(conn).SaveBucket(context.Background(), &pb.Metadata{
 		Id:      body.Name,
@@ -231,6 +346,19 @@ func ListVersion(ip, id string, page, pageSize int) (arr []*msg.Version, total i
 	return
 }
 
+// ListVersions lists versions by page like ListVersion
+func ListVersions(ip, id string, page, pageSize int) ([]*entity.Version, int64, error) {
+	arr, total, err := ListVersion(ip, id, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := make([]*entity.Version, 0, len(arr))
+	for _, v := range arr {
+		res = append(res, toVersion(v))
+	}
+	return res, total, nil
+}
+
 func RemoveVersion(ip, id string, version int32) error {
 	defer perform(true)()
 	conn, err := getConn(ip)
@@ -240,3 +368,224 @@ func RemoveVersion(ip, id string, version int32) error {
 	_, err = pb.NewMetadataApiClient(conn).RemoveVersion(context.Background(), &pb.MetaReq{Id: id, Version: version})
 	return err
 }
+
+func LocateHash(ip, hash string) ([]string, error) {
+	defer perform(false)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).LocateHash(context.Background(), &pb.MetaReq{Hash: hash})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, err
+	}
+	return resp.Locate, nil
+}
+
+// ReferHash increase the reference of hash, returns locates of it.
+// if hash not exists and locates is empty, a 404 error will be returned,
+// otherwise it's created with count which is 1 if not positive.
+func ReferHash(ip, hash string, locates []string, count int64) ([]string, error) {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).ReferHash(context.Background(), &pb.HashRef{Hash: hash, Locate: locates, Count: count})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, err
+	}
+	return resp.Locate, nil
+}
+
+// DereferHash decrease the reference of hash, returns remaining count and locates of it.
+func DereferHash(ip, hash string) (int64, []string, error) {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).DereferHash(context.Background(), &pb.MetaReq{Hash: hash})
+	if err = proto.ResolveErr(err); err != nil {
+		return 0, nil, err
+	}
+	return resp.Count, resp.Locate, nil
+}
+
+// VersionsByHash finds versions of hash stored on the metadata server of ip
+func VersionsByHash(ip, hash string) ([]*entity.Version, error) {
+	defer perform(false)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).GetVersionsByHash(context.Background(), &pb.MetaReq{Hash: hash})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, err
+	}
+	arr, err := util.DecodeArrayMsgp(resp.Data, func() *msg.Version { return new(msg.Version) })
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*entity.Version, 0, len(arr))
+	for _, v := range arr {
+		res = append(res, toVersion(v))
+	}
+	return res, nil
+}
+
+// TouchVersion update the accessing time of version to now
+func TouchVersion(ip, id string, version int32) error {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return err
+	}
+	_, err = pb.NewMetadataApiClient(conn).TouchVersion(context.Background(), &pb.MetaReq{Id: id, Version: version})
+	return proto.ResolveErr(err)
+}
+
+// SwapVersion replace the storage layout of version. body.Ts must be the same as the saved one.
+func SwapVersion(ip, id string, body *entity.Version) error {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return err
+	}
+	bt, err := util.EncodeMsgp(&msg.Version{
+		Compress:      body.Compress,
+		Codec:         body.Codec,
+		CompressRatio: body.CompressRatio,
+		StoreStrategy: int8(body.StoreStrategy),
+		DataShards:    int32(body.DataShards),
+		ParityShards:  int32(body.ParityShards),
+		LocalGroups:   int32(body.LocalGroups),
+		Inline:        body.Inline,
+		ShardSize:     int64(body.ShardSize),
+		Sequence:      uint64(body.Sequence),
+		Size:          body.Size,
+		Ts:            body.Ts,
+		Hash:          body.Hash,
+		Digest:        body.Digest,
+		Locate:        body.Locate,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = pb.NewMetadataApiClient(conn).SwapVersion(context.Background(), &pb.Metadata{
+		Id:      id,
+		Version: body.Sequence,
+		Msgpack: bt,
+	})
+	return proto.ResolveErr(err)
+}
+
+// ListColdVersion lists versions not written or read since 'before'. returns metadata ids, versions and next cursor.
+func ListColdVersion(ip string, before int64, cursor string, limit int) ([]string, []*entity.Version, string, error) {
+	defer perform(false)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).ListColdVersion(context.Background(), &pb.ColdReq{
+		Before: before,
+		Cursor: cursor,
+		Limit:  int32(limit),
+	})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, nil, "", err
+	}
+	ids := make([]string, 0, len(resp.Items))
+	vers := make([]*entity.Version, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		var v msg.Version
+		if err = util.DecodeMsgp(&v, item.Msgpack); err != nil {
+			return nil, nil, "", err
+		}
+		ids = append(ids, item.Id)
+		vers = append(vers, toVersion(&v))
+	}
+	return ids, vers, resp.Cursor, nil
+}
+
+// QueryVersions finds versions by secondary indexes. each metadata contains a single version,
+// cursors are the positions of versions in results.
+func QueryVersions(ip string, q *msg.Query) ([]*entity.Metadata, []msg.QueryCursor, error) {
+	defer perform(false)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).QueryVersions(context.Background(), &pb.QueryReq{
+		Bucket:      q.Bucket,
+		MinSize:     q.MinSize,
+		MaxSize:     q.MaxSize,
+		After:       q.After,
+		Before:      q.Before,
+		ContentType: q.ContentType,
+		Tags:        q.Tags,
+		Sort:        q.Sort,
+		Desc:        q.Desc,
+		Limit:       int32(q.Limit),
+		Cursor:      q.Cursor,
+	})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, nil, err
+	}
+	res := make([]*entity.Metadata, 0, len(resp.Items))
+	cursors := make([]msg.QueryCursor, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		var v msg.Version
+		if err = util.DecodeMsgp(&v, item.Msgpack); err != nil {
+			return nil, nil, err
+		}
+		bucket, name, _ := strings.Cut(item.Id, "/")
+		res = append(res, &entity.Metadata{Name: name, Bucket: bucket, Versions: []*entity.Version{toVersion(&v)}})
+		cursors = append(cursors, q.CursorOf(msg.QueryKey(item.Id, v.Sequence), &v))
+	}
+	return res, cursors, nil
+}
+
+// MarkVersion sets the replication status of version
+func MarkVersion(ip, id string, version int32, status string) error {
+	defer perform(true)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return err
+	}
+	bt, err := util.EncodeMsgp(&msg.Version{Replication: status})
+	if err != nil {
+		return err
+	}
+	_, err = pb.NewMetadataApiClient(conn).MarkVersion(context.Background(), &pb.Metadata{
+		Id:      id,
+		Version: version,
+		Msgpack: bt,
+	})
+	return proto.ResolveErr(err)
+}
+
+// ListChanges lists changes of change feed after the sequence. returns changes, the latest sequence and the group of feed.
+func ListChanges(ip string, after uint64, limit int) ([]*msg.Change, uint64, string, error) {
+	defer perform(false)()
+	conn, err := getConn(ip)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	resp, err := pb.NewMetadataApiClient(conn).ListChanges(context.Background(), &pb.ChangeReq{
+		After: after,
+		Limit: int32(limit),
+	})
+	if err = proto.ResolveErr(err); err != nil {
+		return nil, 0, "", err
+	}
+	res := make([]*msg.Change, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		var c msg.Change
+		if err = util.DecodeMsgp(&c, item); err != nil {
+			return nil, 0, "", err
+		}
+		res = append(res, &c)
+	}
+	return res, resp.Head, resp.Group, nil
+}
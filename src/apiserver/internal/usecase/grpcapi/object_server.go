@@ -0,0 +1,72 @@
+package grpcapi
+
+import (
+	"common/proto/pb"
+	"context"
+	"io"
+)
+
+// WriteShard opens a stream writing a shard to the object server
+func WriteShard(ip string) (pb.ObjectStream_WriteShardClient, context.CancelFunc, error) {
+	cc, err := getConn(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewObjectStreamClient(cc).WriteShard(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return stream, cancel, nil
+}
+
+// ReadShard opens a stream reading a shard from the object server.
+// the first message is received before returning so that errors of server are reported at once.
+func ReadShard(ip string, req *pb.ShardReadReq) (io.ReadCloser, error) {
+	defer perform(false)()
+	cc, err := getConn(ip)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewObjectStreamClient(cc).ReadShard(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		cancel()
+		return nil, err
+	}
+	return &shardReader{stream: stream, cancel: cancel, buf: first.GetData(), eof: err == io.EOF}, nil
+}
+
+type shardReader struct {
+	stream pb.ObjectStream_ReadShardClient
+	cancel context.CancelFunc
+	buf    []byte
+	eof    bool
+}
+
+func (r *shardReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		data, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = data.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *shardReader) Close() error {
+	r.cancel()
+	return nil
+}
@@ -2,22 +2,39 @@ package usecase
 
 import (
 	"apiserver/internal/entity"
+	"common/consistency"
+	"common/proto/msg"
 	"io"
 )
 
 type (
 	IMetaService interface {
 		SaveMetadata(data *entity.Metadata) (int32, error)
+		SaveMetadataBatch(mds []*entity.Metadata) ([]int32, []error)
+		RemoveMetadataBatch(bucket string, names []string) ([]*entity.Version, []error)
 		AddVersion(name, bucket string, version *entity.Version) (int32, error)
 		UpdateVersion(name, bucket string, data *entity.Version) error
-		GetVersion(name, bucket string, verMode int32) (*entity.Version, error)
-		GetMetadata(name, bucket string, verMode int32, withExtra bool) (*entity.Metadata, error)
+		GetVersion(name, bucket string, verMode int32, level consistency.Level) (*entity.Version, error)
+		GetMetadata(name, bucket string, verMode int32, withExtra bool, level consistency.Level) (*entity.Metadata, error)
 		RemoveVersion(name, bucket string, version int32) error
+		TouchVersion(name, bucket string, version int32) error
+		SwapVersion(name, bucket string, version *entity.Version) error
+		ListColdVersions(masterId string, before int64, cursor string, limit int) ([]*entity.Metadata, string, error)
+		ListVersions(name, bucket string, page, size int) ([]*entity.Version, int, error)
+		MarkVersion(name, bucket string, version int32, status string) error
+		ListChanges(masterId string, after uint64, limit int) ([]*msg.Change, uint64, string, error)
+		QueryVersions(q *msg.Query) ([]*entity.Metadata, string, error)
 	}
 	IObjectService interface {
-		UniqueHash(digest string, ss entity.ObjectStrategy, ds, ps int, compress bool) string
-		LocateObject(hash string, shardNum int) ([]string, bool)
+		UniqueHash(digest string, ss entity.ObjectStrategy, ds, ps, lg int, compress bool, codec string) string
+		LocateObject(hash string) ([]string, bool)
+		ReferObject(hash string, locates []string) ([]string, error)
+		DereferObject(hash string, locates []string) error
+		RemoveVersion(name, bucket string, version int32) error
 		StoreObject(req *entity.PutReq, md *entity.Metadata) (int32, error)
+		StoreObjects(bucket string, objs []*entity.BulkObject) ([]*entity.BulkResult, error)
+		RemoveObjects(bucket string, names []string) []*entity.BulkResult
 		GetObject(meta *entity.Metadata, ver *entity.Version) (io.ReadSeekCloser, error)
+		RemoveVersionsByTs(name, bucket string, ts int64, exact bool) (int, error)
 	}
 )
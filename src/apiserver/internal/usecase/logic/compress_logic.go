@@ -0,0 +1,83 @@
+package logic
+
+import (
+	"apiserver/internal/usecase/pool"
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+)
+
+// Compression decides whether to compress objects in auto mode
+type Compression struct{}
+
+func NewCompression() Compression { return Compression{} }
+
+// Skip returns true if files with extension ext are compressed already
+func (Compression) Skip(ext string) bool {
+	for _, e := range pool.Config.Object.AutoCompress.SkipExts {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Estimate returns compressed size of sample divided by its size.
+// deflate in the fastest level is used as it's close to s2 and costs little.
+func (Compression) Estimate(sample []byte) float32 {
+	if len(sample) == 0 {
+		return 1
+	}
+	cnt := &countWriter{}
+	fw, _ := flate.NewWriter(cnt, flate.BestSpeed)
+	_, _ = fw.Write(sample)
+	_ = fw.Close()
+	return float32(cnt.n) / float32(len(sample))
+}
+
+// Sample reads the head of body to estimate the compression ratio, returns a reader of whole body.
+// ratio is 1 without sampling if ext is in the skipped list.
+func (c Compression) Sample(ext string, body io.Reader) (io.Reader, float32, error) {
+	if c.Skip(ext) {
+		return body, 1, nil
+	}
+	sample, err := readSample(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.MultiReader(bytes.NewReader(sample), body), c.Estimate(sample), nil
+}
+
+// Ratio estimates the compression ratio by the head of r like Sample, but consumes r
+func (c Compression) Ratio(ext string, r io.Reader) (float32, error) {
+	if c.Skip(ext) {
+		return 1, nil
+	}
+	sample, err := readSample(r)
+	if err != nil {
+		return 0, err
+	}
+	return c.Estimate(sample), nil
+}
+
+func readSample(r io.Reader) ([]byte, error) {
+	sample := make([]byte, pool.Config.Object.AutoCompress.SampleSize.Int64())
+	n, err := io.ReadFull(r, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return sample[:n], nil
+}
+
+// Compressible returns true if ratio estimated is low enough
+func (Compression) Compressible(ratio float32) bool {
+	return ratio <= pool.Config.Object.AutoCompress.MaxRatio
+}
+
+type countWriter struct{ n int }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
@@ -6,6 +6,7 @@ import (
 	"apiserver/internal/usecase/grpcapi"
 	"apiserver/internal/usecase/pool"
 	"common/logs"
+	"common/registry"
 )
 
 type Discovery struct{}
@@ -16,6 +17,24 @@ func (Discovery) GetDataServers() []string {
 	return pool.Discovery.GetServices(pool.Config.Discovery.DataServName)
 }
 
+// GetColdDataServers returns object servers for cold data. returns nil if not configured
+func (Discovery) GetColdDataServers() []string {
+	if pool.Config.Discovery.ColdServName == "" {
+		return nil
+	}
+	return pool.Discovery.GetServices(pool.Config.Discovery.ColdServName)
+}
+
+// GetMetaServerIDs returns ids of master metadata servers, one for each group
+func (Discovery) GetMetaServerIDs() []string {
+	mapping := pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true)
+	ids := make([]string, 0, len(mapping))
+	for id := range mapping {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (Discovery) GetMetaServerHTTP(id string) string {
 	ip, ok := pool.Discovery.GetService(pool.Config.Discovery.MetaServName, id)
 	if !ok {
@@ -32,15 +51,38 @@ func (Discovery) GetMetaServerGRPC(id string) string {
 	return ip
 }
 
-func (d Discovery) SelectDataServer(sel selector.Selector, size int) []string {
-	ds := d.GetDataServers()
+// SelectDataServer select servers for a stripe of size shards which tolerates losing 'tolerance' shards.
+// servers are spread over failure domains. returns empty if placement constraint is violated in strict mode.
+func (d Discovery) SelectDataServer(sel selector.Selector, size, tolerance int) []string {
+	return d.selectSpread(pool.Config.Discovery.DataServName, sel, size, tolerance)
+}
+
+// SelectColdDataServer select from cold object servers. fallbacks to SelectDataServer if there is no cold servers
+func (d Discovery) SelectColdDataServer(sel selector.Selector, size, tolerance int) []string {
+	if len(d.GetColdDataServers()) == 0 {
+		return d.SelectDataServer(sel, size, tolerance)
+	}
+	return d.selectSpread(pool.Config.Discovery.ColdServName, sel, size, tolerance)
+}
+
+func (Discovery) selectSpread(servName string, sel selector.Selector, size, tolerance int) []string {
+	ds := pool.Discovery.GetServices(servName)
 	if len(ds) == 0 {
 		return []string{}
 	}
-	serv := make([]string, size)
-	lb := selector.IPSelector{Selector: sel, IPs: ds}
-	for i := 0; i < size; i++ {
-		serv[i] = lb.Select()
+	topo := pool.Discovery.GetTopology(servName)
+	serv := selector.SpreadSelect(sel, ds, topo, size)
+	if tolerance <= 0 {
+		return serv
+	}
+	conf := &pool.Config.Object.Placement
+	if vs := registry.CheckPlacement(serv, topo, conf.Level, tolerance); len(vs) > 0 {
+		for _, v := range vs {
+			logs.Std().Warnf("placement violation: %d shards in %s '%s' (max %d)", v.Shards, conf.Level, v.Domain, v.Max)
+		}
+		if conf.Strict {
+			return []string{}
+		}
 	}
 	return serv
 }
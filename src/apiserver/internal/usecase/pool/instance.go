@@ -5,6 +5,7 @@ import (
 	"apiserver/internal/usecase/componet/selector"
 	"apiserver/internal/usecase/grpcapi"
 	"apiserver/internal/usecase/webapi"
+	"common/cache"
 	"common/logs"
 	"common/performance"
 	"common/registry"
@@ -22,6 +23,8 @@ var (
 	Balancer  selector.Selector
 	Discovery *registry.EtcdDiscovery
 	Perform   performance.Collector
+	// ObjectCache caches decoded objects by version hash, nil if disabled
+	ObjectCache *cache.Tiered
 )
 
 func InitPool(cfg *config.Config) {
@@ -31,9 +34,13 @@ func InitPool(cfg *config.Config) {
 	initDiscovery(Etcd, cfg)
 	initBalancer(cfg)
 	initPerform(&cfg.Performance, &cfg.Log, &cfg.Registry, Etcd)
+	initObjectCache(&cfg.Object.Cache)
 }
 
 func Close() {
+	if ObjectCache != nil {
+		util.LogErr(ObjectCache.Close())
+	}
 	util.LogErr(Perform.Close())
 	util.LogErr(Etcd.Close())
 	util.LogErr(grpcapi.Close())
@@ -57,6 +64,9 @@ func initEtcd(cfg *config.Config) {
 
 func initDiscovery(etcd *clientv3.Client, cfg *config.Config) {
 	cfg.Registry.Services = []string{cfg.Discovery.DataServName, cfg.Discovery.MetaServName}
+	if cfg.Discovery.ColdServName != "" {
+		cfg.Registry.Services = append(cfg.Registry.Services, cfg.Discovery.ColdServName)
+	}
 	Discovery = registry.NewEtcdDiscovery(etcd, &cfg.Registry)
 }
 
@@ -91,3 +101,19 @@ func initPerform(cfg *performance.Config, logCfg *logs.Config, regCfg *registry.
 	webapi.SetPerformanceCollector(Perform)
 	grpcapi.SetPerformanceCollector(Perform)
 }
+
+func initObjectCache(cfg *config.ObjectCacheConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	var err error
+	ObjectCache, err = cache.NewTiered(cache.TieredConfig{
+		MaxSize:     int64(cfg.MaxSize.Byte()),
+		MaxItemSize: int64(cfg.MaxItemSize.Byte()),
+		DiskPath:    cfg.DiskPath,
+		DiskSize:    int64(cfg.DiskSize.Byte()),
+	})
+	if err != nil {
+		panic("init object cache fail: " + err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"apiserver/internal/entity"
+	"apiserver/internal/usecase/grpcapi"
+	"apiserver/internal/usecase/logic"
+)
+
+type HashRefRepo struct {
+}
+
+func NewHashRefRepo() *HashRefRepo {
+	return &HashRefRepo{}
+}
+
+// Locate find locates of hash from the server where the slot of hash belongs to
+func (h *HashRefRepo) Locate(hash string) ([]string, error) {
+	masterId, err := logic.NewHashSlot().KeySlotLocation(hash)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := logic.NewDiscovery().SelectMetaServerGRPC(masterId)
+	if err != nil {
+		return nil, err
+	}
+	return grpcapi.LocateHash(ip, hash)
+}
+
+// Refer increase reference of hash. locates can be empty if only refer to an existing one
+func (h *HashRefRepo) Refer(hash string, locates []string) ([]string, error) {
+	masterId, err := logic.NewHashSlot().KeySlotLocation(hash)
+	if err != nil {
+		return nil, err
+	}
+	return grpcapi.ReferHash(logic.NewDiscovery().GetMetaServerGRPC(masterId), hash, locates, 0)
+}
+
+// Backfill creates reference of hash with count for objects stored before reference counting.
+// it increases the reference like Refer if it has been created.
+func (h *HashRefRepo) Backfill(hash string, locates []string, count int64) ([]string, error) {
+	masterId, err := logic.NewHashSlot().KeySlotLocation(hash)
+	if err != nil {
+		return nil, err
+	}
+	return grpcapi.ReferHash(logic.NewDiscovery().GetMetaServerGRPC(masterId), hash, locates, count)
+}
+
+// Derefer decrease reference of hash. returns remaining count and locates of it
+func (h *HashRefRepo) Derefer(hash string) (int64, []string, error) {
+	masterId, err := logic.NewHashSlot().KeySlotLocation(hash)
+	if err != nil {
+		return 0, nil, err
+	}
+	return grpcapi.DereferHash(logic.NewDiscovery().GetMetaServerGRPC(masterId), hash)
+}
+
+// Holders finds versions of hash on all metadata servers
+func (h *HashRefRepo) Holders(hash string) ([]*entity.Version, error) {
+	var res []*entity.Version
+	for _, masterId := range logic.NewDiscovery().GetMetaServerIDs() {
+		ip, err := logic.NewDiscovery().SelectMetaServerGRPC(masterId)
+		if err != nil {
+			return nil, err
+		}
+		vers, err := grpcapi.VersionsByHash(ip, hash)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, vers...)
+	}
+	return res, nil
+}
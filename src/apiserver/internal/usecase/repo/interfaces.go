@@ -2,18 +2,29 @@ package repo
 
 import (
 	"apiserver/internal/entity"
+	"common/consistency"
+	"common/proto/msg"
 )
 
 type IMetadataRepo interface {
-	FindByName(name string, bucket string, withExtra bool) (*entity.Metadata, error)
+	FindByName(name string, bucket string, withExtra bool, level consistency.Level) (*entity.Metadata, error)
 	Insert(data *entity.Metadata) error
+	BatchInsert(mds []*entity.Metadata) ([]int32, []error)
+	BatchRemove(ids []string) ([]*entity.Version, []error)
 }
 
 type IVersionRepo interface {
-	Find(name, bucket string, i int32) (*entity.Version, error)
+	Find(name, bucket string, i int32, level consistency.Level) (*entity.Version, error)
 	Update(name, bucket string, ver *entity.Version) error
 	Add(name, bucket string, ver *entity.Version) (int32, error)
 	Delete(name, bucket string, ver int32) error
+	Touch(name, bucket string, ver int32) error
+	Swap(name, bucket string, ver *entity.Version) error
+	FindCold(masterId string, before int64, cursor string, limit int) ([]*entity.Metadata, string, error)
+	List(name, bucket string, page, size int) ([]*entity.Version, int, error)
+	Mark(name, bucket string, ver int32, status string) error
+	Changes(masterId string, after uint64, limit int) ([]*msg.Change, uint64, string, error)
+	Query(q *msg.Query) ([]*entity.Metadata, string, error)
 }
 
 type IBucketRepo interface {
@@ -22,3 +33,11 @@ type IBucketRepo interface {
 	Create(*entity.Bucket) error
 	Delete(string) error
 }
+
+type IHashRefRepo interface {
+	Locate(hash string) ([]string, error)
+	Refer(hash string, locates []string) ([]string, error)
+	Backfill(hash string, locates []string, count int64) ([]string, error)
+	Derefer(hash string) (int64, []string, error)
+	Holders(hash string) ([]*entity.Version, error)
+}
@@ -5,8 +5,11 @@ import (
 	"apiserver/internal/usecase"
 	"apiserver/internal/usecase/grpcapi"
 	"apiserver/internal/usecase/logic"
+	"common/consistency"
+	"common/graceful"
 	"common/response"
 	"fmt"
+	"sync"
 )
 
 type MetadataRepo struct{}
@@ -16,7 +19,7 @@ func NewMetadataRepo() *MetadataRepo {
 }
 
 // FindByName 根据文件名查找元数据 不查询版本
-func (m *MetadataRepo) FindByName(name, bucket string, withExtra bool) (*entity.Metadata, error) {
+func (m *MetadataRepo) FindByName(name, bucket string, withExtra bool, level consistency.Level) (*entity.Metadata, error) {
 	name = fmt.Sprint(bucket, "/", name)
 	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
 	if err != nil {
@@ -26,7 +29,7 @@ func (m *MetadataRepo) FindByName(name, bucket string, withExtra bool) (*entity.
 	if err != nil {
 		return nil, err
 	}
-	return grpcapi.GetMetadata(ip, name, withExtra)
+	return grpcapi.GetMetadata(ip, name, withExtra, level)
 }
 
 func (m *MetadataRepo) Insert(data *entity.Metadata) error {
@@ -46,3 +49,77 @@ func (m *MetadataRepo) Insert(data *entity.Metadata) error {
 	}
 	return err
 }
+
+// BatchInsert saves metadata and their first versions, metadata on the same metaserver are saved atomically.
+// returns sequences of versions and errors of metadata at the same index.
+func (m *MetadataRepo) BatchInsert(mds []*entity.Metadata) ([]int32, []error) {
+	versions := make([]int32, len(mds))
+	ids := make([]string, len(mds))
+	for i, md := range mds {
+		ids[i] = fmt.Sprint(md.Bucket, "/", md.Name)
+	}
+	errs := byMetaServer(ids, func(ip string, idx []int) error {
+		group := make([]*entity.Metadata, len(idx))
+		for i, j := range idx {
+			group[i] = mds[j]
+		}
+		vers, err := grpcapi.BatchSave(ip, group)
+		if err != nil {
+			return err
+		}
+		for i, j := range idx {
+			versions[j] = vers[i]
+		}
+		return nil
+	})
+	return versions, errs
+}
+
+// BatchRemove removes metadata of ids with all their versions, metadata on the same metaserver are removed atomically.
+// returns removed versions and errors of metadata at the same index.
+func (m *MetadataRepo) BatchRemove(ids []string) ([]*entity.Version, []error) {
+	var mu sync.Mutex
+	var removed []*entity.Version
+	errs := byMetaServer(ids, func(ip string, idx []int) error {
+		group := make([]string, len(idx))
+		for i, j := range idx {
+			group[i] = ids[j]
+		}
+		vers, err := grpcapi.BatchRemove(ip, group)
+		mu.Lock()
+		defer mu.Unlock()
+		removed = append(removed, vers...)
+		return err
+	})
+	return removed, errs
+}
+
+// byMetaServer groups ids by metaserver and runs fn for groups concurrently with indexes of ids in the group.
+// returns errors of ids at the same index.
+func byMetaServer(ids []string, fn func(ip string, idx []int) error) []error {
+	errs := make([]error, len(ids))
+	groups := make(map[string][]int)
+	for i, id := range ids {
+		masterId, err := logic.NewHashSlot().KeySlotLocation(id)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		groups[masterId] = append(groups[masterId], i)
+	}
+	var wg sync.WaitGroup
+	for masterId, idx := range groups {
+		wg.Add(1)
+		go func(masterId string, idx []int) {
+			defer graceful.Recover()
+			defer wg.Done()
+			if err := fn(logic.NewDiscovery().GetMetaServerGRPC(masterId), idx); err != nil {
+				for _, j := range idx {
+					errs[j] = err
+				}
+			}
+		}(masterId, idx)
+	}
+	wg.Wait()
+	return errs
+}
@@ -4,7 +4,12 @@ import (
 	"apiserver/internal/entity"
 	"apiserver/internal/usecase/grpcapi"
 	"apiserver/internal/usecase/logic"
+	"common/consistency"
+	"common/graceful"
+	"common/proto/msg"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 const (
@@ -19,7 +24,7 @@ func NewVersionRepo() *VersionRepo {
 }
 
 // Find return the metadata of specified version
-func (v *VersionRepo) Find(name, bucket string, version int32) (*entity.Version, error) {
+func (v *VersionRepo) Find(name, bucket string, version int32, level consistency.Level) (*entity.Version, error) {
 	name = fmt.Sprint(bucket, "/", name)
 	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
 	if err != nil {
@@ -29,7 +34,7 @@ func (v *VersionRepo) Find(name, bucket string, version int32) (*entity.Version,
 	if err != nil {
 		return nil, err
 	}
-	return grpcapi.GetVersion(ip, name, version)
+	return grpcapi.GetVersion(ip, name, version, level)
 }
 
 // Update updating locate and setting ts to now
@@ -56,6 +61,133 @@ func (v *VersionRepo) Add(name, bucket string, ver *entity.Version) (int32, erro
 	return ver.Sequence, nil
 }
 
+// Touch updating accessing time of version to now
+func (v *VersionRepo) Touch(name, bucket string, ver int32) error {
+	name = fmt.Sprint(bucket, "/", name)
+	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
+	if err != nil {
+		return err
+	}
+	return grpcapi.TouchVersion(logic.NewDiscovery().GetMetaServerGRPC(masterId), name, ver)
+}
+
+// Swap replace storage layout of version. ver.Ts must be the same as the saved one
+func (v *VersionRepo) Swap(name, bucket string, ver *entity.Version) error {
+	name = fmt.Sprint(bucket, "/", name)
+	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
+	if err != nil {
+		return err
+	}
+	return grpcapi.SwapVersion(logic.NewDiscovery().GetMetaServerGRPC(masterId), name, ver)
+}
+
+// FindCold find versions not written or read since 'before' on the metadata server of masterId.
+// each metadata contains a single version. returns the cursor for next finding.
+func (v *VersionRepo) FindCold(masterId string, before int64, cursor string, limit int) ([]*entity.Metadata, string, error) {
+	ip, err := logic.NewDiscovery().SelectMetaServerGRPC(masterId)
+	if err != nil {
+		return nil, "", err
+	}
+	ids, vers, next, err := grpcapi.ListColdVersion(ip, before, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]*entity.Metadata, 0, len(ids))
+	for i, id := range ids {
+		bucket, name, _ := strings.Cut(id, "/")
+		res = append(res, &entity.Metadata{Name: name, Bucket: bucket, Versions: []*entity.Version{vers[i]}})
+	}
+	return res, next, nil
+}
+
+// List returns versions of page and the total number of versions
+func (v *VersionRepo) List(name, bucket string, page, size int) ([]*entity.Version, int, error) {
+	name = fmt.Sprint(bucket, "/", name)
+	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	ip, err := logic.NewDiscovery().SelectMetaServerGRPC(masterId)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, total, err := grpcapi.ListVersions(ip, name, page, size)
+	return res, int(total), err
+}
+
+// Mark sets the replication status of version
+func (v *VersionRepo) Mark(name, bucket string, ver int32, status string) error {
+	name = fmt.Sprint(bucket, "/", name)
+	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
+	if err != nil {
+		return err
+	}
+	return grpcapi.MarkVersion(logic.NewDiscovery().GetMetaServerGRPC(masterId), name, ver, status)
+}
+
+// Changes lists changes of the metadata server of masterId after the sequence. returns the latest sequence
+// and the group of change feed.
+func (v *VersionRepo) Changes(masterId string, after uint64, limit int) ([]*msg.Change, uint64, string, error) {
+	return grpcapi.ListChanges(logic.NewDiscovery().GetMetaServerGRPC(masterId), after, limit)
+}
+
+// Query finds versions on all metadata servers and merges results in the order of query.
+// returns at most q.Limit versions and the cursor for next page.
+func (v *VersionRepo) Query(q *msg.Query) ([]*entity.Metadata, string, error) {
+	type result struct {
+		items   []*entity.Metadata
+		cursors []msg.QueryCursor
+		err     error
+	}
+	masters := logic.NewDiscovery().GetMetaServerIDs()
+	results := make(chan *result, len(masters))
+	for _, masterId := range masters {
+		go func(id string) {
+			defer graceful.Recover()
+			var r result
+			defer func() { results <- &r }()
+			ip, err := logic.NewDiscovery().SelectMetaServerGRPC(id)
+			if err != nil {
+				r.err = err
+				return
+			}
+			r.items, r.cursors, r.err = grpcapi.QueryVersions(ip, q)
+		}(masterId)
+	}
+	var items []*entity.Metadata
+	var cursors []msg.QueryCursor
+	for range masters {
+		r := <-results
+		if r.err != nil {
+			return nil, "", r.err
+		}
+		items = append(items, r.items...)
+		cursors = append(cursors, r.cursors...)
+	}
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		if q.Desc {
+			return cursors[idx[j]].Less(cursors[idx[i]])
+		}
+		return cursors[idx[i]].Less(cursors[idx[j]])
+	})
+	if len(idx) > q.Limit {
+		idx = idx[:q.Limit]
+	}
+	res := make([]*entity.Metadata, 0, len(idx))
+	for _, i := range idx {
+		res = append(res, items[i])
+	}
+	next := q.Cursor
+	if len(idx) > 0 {
+		next = cursors[idx[len(idx)-1]].String()
+	}
+	return res, next, nil
+}
+
 func (v *VersionRepo) Delete(name, bucket string, ver int32) error {
 	name = fmt.Sprint(bucket, "/", name)
 	masterId, err := logic.NewHashSlot().KeySlotLocation(name)
@@ -15,7 +15,7 @@ import (
 type CopyFixStream struct {
 	fileNames []string
 	locates   []string
-	compress  bool
+	codec     string
 	buffer    *bytes.Buffer
 	rpConfig  *config.ReplicationConfig
 	Updater   LocatesUpdater
@@ -26,7 +26,7 @@ func NewCopyFixStream(lostNames []string, newLocates []string, opt *StreamOption
 		fileNames: lostNames,
 		locates:   newLocates,
 		rpConfig:  cfg,
-		compress:  opt.Compress,
+		codec:     opt.ShardCodec(),
 		buffer:    bytes.NewBuffer(make([]byte, 0, opt.Size)),
 		Updater:   opt.Updater,
 	}, nil
@@ -60,8 +60,8 @@ func (c *CopyFixStream) startFix() func() error {
 			wg.Todo()
 			go func(i int, key string) {
 				defer wg.Done()
-				if err := webapi.PutObject(c.locates[i], key, c.compress, bytes.NewBuffer(data)); err != nil {
-					errs.Add(fmt.Sprintf("fix %s put-api err: %w", key, err))
+				if err := webapi.PutObject(c.locates[i], key, c.codec, bytes.NewBuffer(data)); err != nil {
+					errs.Add(fmt.Sprintf("fix %s put-api err: %s", key, err))
 				}
 			}(idx, name)
 		}
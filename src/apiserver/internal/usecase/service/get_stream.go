@@ -2,27 +2,32 @@ package service
 
 import (
 	"apiserver/internal/usecase/webapi"
+	"common/proto/pb"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 )
 
 type GetStream struct {
-	reader   io.ReadCloser
-	Locate   string
-	name     string
-	size     int64
-	compress bool
+	mu     sync.Mutex // guards reader and closed, Close may be called while reading by hedged readers
+	reader io.ReadCloser
+	closed bool
+	Locate string
+	name   string
+	size   int64
+	codec  string
 }
 
 // NewGetStream IO: Head object
-func NewGetStream(ip, name string, size int64, compress bool) (*GetStream, error) {
+func NewGetStream(ip, name string, size int64, codec string) (*GetStream, error) {
 	stream := &GetStream{
-		reader:   nil,
-		Locate:   ip,
-		name:     name,
-		size:     size,
-		compress: compress,
+		reader: nil,
+		Locate: ip,
+		name:   name,
+		size:   size,
+		codec:  codec,
 	}
 	return stream, stream.CheckStat()
 }
@@ -32,17 +37,38 @@ func (g *GetStream) CheckStat() error {
 }
 
 func (g *GetStream) request(offset int) error {
-	resp, err := webapi.GetObject(g.Locate, g.name, offset, g.size, g.compress)
+	reader, err := g.open(offset)
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("get object from dataServer return http code %v", resp.StatusCode)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		_ = reader.Close()
+		return errors.New("get stream closed")
 	}
-	g.reader = resp.Body
+	g.reader = reader
 	return nil
 }
 
+func (g *GetStream) open(offset int) (io.ReadCloser, error) {
+	if !isLegacyServer(g.Locate) {
+		reader, err := openShardReader(g.Locate, &pb.ShardReadReq{Name: g.name, Offset: int64(offset), Size: g.size, Compress: g.codec != "", Codec: g.codec})
+		if !errors.Is(err, errLegacyServer) {
+			return reader, err
+		}
+	}
+	resp, err := webapi.GetObject(g.Locate, g.name, offset, g.size, g.codec)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("get object from dataServer return http code %v", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
 func (g *GetStream) Seek(offset int64, whence int) (int64, error) {
 	if offset < 0 {
 		return 0, fmt.Errorf("get stream only supports forward seek offest")
@@ -50,14 +76,15 @@ func (g *GetStream) Seek(offset int64, whence int) (int64, error) {
 	if whence == io.SeekEnd {
 		return 0, fmt.Errorf("get stream only supports SeekStart and SeekCurrent")
 	}
-	if g.reader == nil {
+	reader := g.current()
+	if reader == nil {
 		if err := g.request(int(offset)); err != nil {
 			return 0, err
 		}
 		return offset, nil
 	}
 	if offset > 0 {
-		n, err := io.ReadFull(g.reader, make([]byte, offset))
+		n, err := io.ReadFull(reader, make([]byte, offset))
 		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			err = nil
 		}
@@ -67,15 +94,41 @@ func (g *GetStream) Seek(offset int64, whence int) (int64, error) {
 }
 
 func (g *GetStream) Read(bt []byte) (int, error) {
-	if g.reader == nil {
+	reader := g.current()
+	if reader == nil {
 		if err := g.request(0); err != nil {
 			return 0, err
 		}
+		reader = g.current()
+	}
+	return reader.Read(bt)
+}
+
+func (g *GetStream) current() io.ReadCloser {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.reader
+}
+
+// Reset closes the reader in progress, so that the stream is reopened from the offset of next Seek or Read
+func (g *GetStream) Reset() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.reader == nil {
+		return nil
 	}
-	return g.reader.Read(bt)
+	err := g.reader.Close()
+	g.reader = nil
+	return err
 }
 
 func (g *GetStream) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
 	if g.reader == nil {
 		return nil
 	}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"apiserver/config"
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := &latencyTracker{servers: map[string]*latencySamples{}}
+	// only the latest latencyWindow latencies are kept
+	for i := 1; i <= 100; i++ {
+		tracker.Observe("a", time.Duration(i)*time.Millisecond)
+	}
+	p, ok := tracker.Percentile("a", 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, 68*time.Millisecond, p)
+	p, _ = tracker.Percentile("a", 1)
+	assert.Equal(t, 100*time.Millisecond, p)
+	_, ok = tracker.Percentile("b", 0.5)
+	assert.False(t, ok)
+
+	tracker.Observe("c", 10*time.Millisecond)
+	tracker.Observe("d", 10*time.Millisecond)
+	// servers without latencies come first to be learned, lower indexes are preferred on ties
+	assert.Equal(t, []int{1, 2, 3, 0}, tracker.Rank([]string{"a", "b", "c", "d"}))
+}
+
+// fakeShard serves data of a shard, reading blocks in slow is blocked until it's reset
+type fakeShard struct {
+	mu    sync.Mutex
+	data  []byte
+	pos   int
+	block int
+	slow  map[int]bool
+	reset chan struct{}
+	seeks []int64
+}
+
+func newFakeShard(data []byte, block int, slow ...int) *fakeShard {
+	f := &fakeShard{data: data, block: block, slow: map[int]bool{}, reset: make(chan struct{})}
+	for _, b := range slow {
+		f.slow[b] = true
+	}
+	return f
+}
+
+func (f *fakeShard) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	pos, reset := f.pos, f.reset
+	f.mu.Unlock()
+	if f.slow[pos/f.block] {
+		select {
+		case <-time.After(time.Second):
+		case <-reset:
+			return 0, errors.New("reset")
+		}
+	}
+	if pos >= len(f.data) {
+		return 0, io.EOF
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := copy(p, f.data[pos:])
+	f.pos += n
+	return n, nil
+}
+
+func (f *fakeShard) Seek(offset int64, _ int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pos = int(offset)
+	f.seeks = append(f.seeks, offset)
+	return offset, nil
+}
+
+func (f *fakeShard) Reset() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.reset)
+	f.reset = make(chan struct{})
+	return nil
+}
+
+func TestHedgedDecoder(t *testing.T) {
+	cfg := &config.RsConfig{DataShards: 2, ParityShards: 1, BlockPerShard: 4}
+	data := []byte("hedged reading!!")
+	enc, err := reedsolomon.New(cfg.DataShards, cfg.ParityShards)
+	assert.NoError(t, err)
+	// every block is split into data shards and encoded
+	shards := make([][]byte, cfg.AllShards())
+	for i := 0; i < len(data); i += cfg.BlockSize() {
+		blk, err := enc.Split(data[i : i+cfg.BlockSize()])
+		assert.NoError(t, err)
+		assert.NoError(t, enc.Encode(blk))
+		for j := range shards {
+			shards[j] = append(shards[j], blk[j]...)
+		}
+	}
+
+	// shard 0 is slow on the first block and shard 1 on the second one
+	fakes := []*fakeShard{newFakeShard(shards[0], 4, 0), newFakeShard(shards[1], 4, 1), newFakeShard(shards[2], 4)}
+	readers := []io.Reader{fakes[0], fakes[1], fakes[2]}
+	locates := []string{"hedge-0", "hedge-1", "hedge-2"}
+	shardLatency.mu.Lock()
+	for _, ip := range locates {
+		delete(shardLatency.servers, ip)
+	}
+	shardLatency.mu.Unlock()
+	dec := NewDecoder(readers, make([]io.Writer, 3), int64(len(data)), cfg)
+	dec.enableHedge(locates, &config.HedgeConfig{Percentile: 0.9, MinDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond})
+	assert.Equal(t, []int{2}, dec.standby)
+
+	start := time.Now()
+	got, err := io.ReadAll(dec)
+	assert.NoError(t, err)
+	assert.Equal(t, data, got)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	// shard 0 was put back on standby after the first block, and reopened from the second block when shard 1 was slow
+	assert.Equal(t, []int64{4}, fakes[0].seeks)
+	assert.Equal(t, shardActive, dec.states[0])
+	assert.Equal(t, []int{1}, dec.standby)
+	for _, st := range dec.states {
+		assert.NotEqual(t, shardDropped, st)
+	}
+}
+
+// closeRecorder records whether it's closed
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDrainGetStream(t *testing.T) {
+	results := make(chan *provideStream, 3)
+	late := &closeRecorder{}
+	results <- &provideStream{stream: late, index: 0}
+	results <- &provideStream{index: 1, err: errors.New("lost")}
+	results <- &provideStream{index: 2, err: errors.New("lost")}
+	close(results)
+	lost := make(chan []int, 1)
+	drainGetStream(results, lost)
+	assert.Equal(t, []int{1, 2}, <-lost)
+	assert.True(t, late.closed)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"apiserver/config"
+	"common/util/math"
+	"io"
+)
+
+// NewLRCDecoder decodes shards encoded by local reconstruction codes. only data shards are read unless some of them
+// are lost or fail, then the local parity shard of its group is read to repair it if it's the only one lost in group,
+// global parity shards are read otherwise. lost parity shards are repaired from data shards without reading more.
+func NewLRCDecoder(readers []io.Reader, writers []io.Writer, size int64, cfg *config.LrcConfig) (*rsDecoder, error) {
+	codec, err := newLRCCodec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(codec, readers, writers, size, cfg.RsConfig())
+	for i := cfg.DataShards; i < cfg.AllShards(); i++ {
+		if readers[i] != nil {
+			d.states[i] = shardIdle
+		}
+	}
+	d.plan = lrcPlan(d, cfg)
+	for _, idx := range d.plan() {
+		d.states[idx] = shardActive
+	}
+	return d, nil
+}
+
+// lrcPlan returns shards should be read: data shards not lost, local parity shards of groups losing one data shard,
+// and global parity shards as many as data shards lost in other groups.
+func lrcPlan(d *rsDecoder, cfg *config.LrcConfig) func() []int {
+	lost := func(idx int) bool {
+		return d.readers[idx] == nil || d.states[idx] == shardDropped
+	}
+	return func() []int {
+		plan := make([]int, 0, cfg.AllShards())
+		var globals int
+		gs := cfg.GroupSize()
+		for g := 0; g < cfg.LocalGroups; g++ {
+			var lostData int
+			for i := g * gs; i < (g+1)*gs; i++ {
+				if lost(i) {
+					lostData++
+				} else {
+					plan = append(plan, i)
+				}
+			}
+			if p := cfg.LocalParity(g); lostData == 1 && !lost(p) {
+				plan = append(plan, p)
+			} else {
+				globals += lostData
+			}
+		}
+		for i := cfg.DataShards + cfg.LocalGroups; i < cfg.AllShards() && globals > 0; i++ {
+			if !lost(i) {
+				plan = append(plan, i)
+				globals--
+			}
+		}
+		return plan
+	}
+}
+
+// localRepair returns local groups of lost shards to rewrite if each of them loses only one shard,
+// which are repaired by reading only the other shards of their groups. returns false if any global parity
+// shard is to rewrite or any group loses more than one shard.
+func (c *lrcCodec) localRepair(readers []io.Reader, writers []io.Writer) ([]int, bool) {
+	var groups []int
+	for i, w := range writers {
+		if w == nil {
+			continue
+		}
+		g := c.cfg.Group(i)
+		if g < 0 {
+			return nil, false
+		}
+		var lost int
+		for j := range readers {
+			if readers[j] == nil && c.cfg.Group(j) == g {
+				lost++
+			}
+		}
+		if lost > 1 {
+			return nil, false
+		}
+		groups = append(groups, g)
+	}
+	return groups, len(groups) > 0
+}
+
+// repairGroups reads shards of groups block by block and rewrites the lost one of each group
+func (d *rsDecoder) repairGroups(c *lrcCodec, groups []int) error {
+	shardSize := int64(c.cfg.ShardSize(d.size))
+	for done := int64(0); done < shardSize; {
+		n := math.MinNumber(int64(d.rsCfg.BlockPerShard), shardSize-done)
+		shards := make([][]byte, len(d.readers))
+		for _, g := range groups {
+			for i, r := range d.readers {
+				if r == nil || c.cfg.Group(i) != g {
+					continue
+				}
+				shards[i] = d.buffers[i][:n]
+				if _, err := io.ReadFull(r, shards[i]); err != nil {
+					return err
+				}
+			}
+			if err := c.reconstructGroup(shards, g); err != nil {
+				return err
+			}
+		}
+		if err := d.writeLost(shards); err != nil {
+			return err
+		}
+		done += n
+	}
+	return nil
+}
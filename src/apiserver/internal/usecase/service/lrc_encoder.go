@@ -0,0 +1,150 @@
+package service
+
+import (
+	"apiserver/config"
+	"errors"
+	"io"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// lrcCodec local reconstruction codes. each local group of data shards has a parity shard encoded by RS of
+// one parity, so a lost shard is reconstructed by reading only its group. global parity shards are encoded
+// from all data shards by RS, which are used if a group loses more than one shard.
+type lrcCodec struct {
+	cfg    config.LrcConfig
+	split  reedsolomon.Encoder // split splits blocks into data shards and allocates all parity shards
+	local  reedsolomon.Encoder
+	global reedsolomon.Encoder
+}
+
+func newLRCCodec(cfg *config.LrcConfig) (*lrcCodec, error) {
+	split, err := reedsolomon.New(cfg.DataShards, cfg.LocalGroups+cfg.GlobalParity)
+	if err != nil {
+		return nil, err
+	}
+	local, err := reedsolomon.New(cfg.GroupSize(), 1)
+	if err != nil {
+		return nil, err
+	}
+	global, err := reedsolomon.New(cfg.DataShards, cfg.GlobalParity, reedsolomon.WithAutoGoroutines(cfg.BlockPerShard))
+	if err != nil {
+		return nil, err
+	}
+	return &lrcCodec{cfg: *cfg, split: split, local: local, global: global}, nil
+}
+
+func (c *lrcCodec) Split(data []byte) ([][]byte, error) {
+	return c.split.Split(data)
+}
+
+func (c *lrcCodec) Encode(shards [][]byte) error {
+	for g := 0; g < c.cfg.LocalGroups; g++ {
+		if err := c.local.Encode(c.groupShards(shards, g)); err != nil {
+			return err
+		}
+	}
+	return c.global.Encode(c.globalShards(shards))
+}
+
+// ReconstructData reconstructs lost data shards
+func (c *lrcCodec) ReconstructData(shards [][]byte) error {
+	return c.reconstruct(shards, false)
+}
+
+// Reconstruct reconstructs all lost shards
+func (c *lrcCodec) Reconstruct(shards [][]byte) error {
+	return c.reconstruct(shards, true)
+}
+
+func (c *lrcCodec) reconstruct(shards [][]byte, all bool) error {
+	// groups losing only one shard are repaired locally, lost local parity is ignored unless all is true
+	for g := 0; g < c.cfg.LocalGroups; g++ {
+		if c.lostInGroup(shards, g) == 1 && (all || len(shards[c.cfg.LocalParity(g)]) > 0) {
+			if err := c.reconstructGroup(shards, g); err != nil {
+				return err
+			}
+		}
+	}
+	lostGlobal := false
+	for i, shard := range shards {
+		if len(shard) == 0 && (i < c.cfg.DataShards || all && c.cfg.Group(i) < 0) {
+			lostGlobal = true
+			break
+		}
+	}
+	if lostGlobal {
+		sub := c.globalShards(shards)
+		var err error
+		if all {
+			err = c.global.Reconstruct(sub)
+		} else {
+			err = c.global.ReconstructData(sub)
+		}
+		if err != nil {
+			return err
+		}
+		copy(shards, sub[:c.cfg.DataShards])
+		copy(shards[c.cfg.DataShards+c.cfg.LocalGroups:], sub[c.cfg.DataShards:])
+	}
+	if !all {
+		return nil
+	}
+	// local parity shards of groups losing more than one shard, whose data shards are complete now
+	for g := 0; g < c.cfg.LocalGroups; g++ {
+		if c.lostInGroup(shards, g) > 0 {
+			if err := c.reconstructGroup(shards, g); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *lrcCodec) reconstructGroup(shards [][]byte, g int) error {
+	sub := c.groupShards(shards, g)
+	if err := c.local.Reconstruct(sub); err != nil {
+		return err
+	}
+	gs := c.cfg.GroupSize()
+	copy(shards[g*gs:], sub[:gs])
+	shards[c.cfg.LocalParity(g)] = sub[gs]
+	return nil
+}
+
+func (c *lrcCodec) lostInGroup(shards [][]byte, g int) int {
+	var lost int
+	for _, shard := range c.groupShards(shards, g) {
+		if len(shard) == 0 {
+			lost++
+		}
+	}
+	return lost
+}
+
+// groupShards returns data shards of group g followed by its local parity shard
+func (c *lrcCodec) groupShards(shards [][]byte, g int) [][]byte {
+	gs := c.cfg.GroupSize()
+	sub := make([][]byte, 0, gs+1)
+	sub = append(sub, shards[g*gs:(g+1)*gs]...)
+	return append(sub, shards[c.cfg.LocalParity(g)])
+}
+
+// globalShards returns data shards followed by global parity shards
+func (c *lrcCodec) globalShards(shards [][]byte) [][]byte {
+	sub := make([][]byte, 0, c.cfg.DataShards+c.cfg.GlobalParity)
+	sub = append(sub, shards[:c.cfg.DataShards]...)
+	return append(sub, shards[c.cfg.DataShards+c.cfg.LocalGroups:]...)
+}
+
+// NewLRCEncoder encodes data into shards by local reconstruction codes
+func NewLRCEncoder(wrs []io.WriteCloser, cfg *config.LrcConfig) (*rsEncoder, error) {
+	if len(wrs) != cfg.AllShards() {
+		return nil, errors.New("writers number mismatch shards number")
+	}
+	codec, err := newLRCCodec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newEncoder(codec, wrs, cfg.RsConfig()), nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"apiserver/config"
+)
+
+// NewLRCPutStream encodes object by local reconstruction codes and writes shards to option.Locates
+func NewLRCPutStream(opt *StreamOption, cfg *config.LrcConfig) (*RSPutStream, error) {
+	writers, err := openShardWriters(opt, cfg.AllShards(), cfg.ShardSize(opt.Size))
+	if err != nil {
+		return nil, err
+	}
+	enc, err := NewLRCEncoder(writers, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RSPutStream{enc}, nil
+}
+
+// NewLRCGetStream reads object encoded by local reconstruction codes. lost shards are rewritten to new servers
+// while reading, which is the same as RSGetStream.
+func NewLRCGetStream(opt *StreamOption, cfg *config.LrcConfig) (*RSGetStream, error) {
+	readers, writers, _, err := openShards(opt, cfg.AllShards(), cfg.ShardSize(opt.Size), cfg.DataShards, nil)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := NewLRCDecoder(readers, writers, opt.Size, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RSGetStream{rsDecoder: dec, StreamOption: opt}, nil
+}
@@ -4,6 +4,9 @@ import (
 	"apiserver/internal/entity"
 	"apiserver/internal/usecase"
 	"apiserver/internal/usecase/repo"
+	"common/consistency"
+	"common/proto/msg"
+	"fmt"
 )
 
 type MetaService struct {
@@ -29,17 +32,61 @@ func (m *MetaService) SaveMetadata(md *entity.Metadata) (int32, error) {
 	return 0, nil
 }
 
+// SaveMetadataBatch saves metadata with their first versions in a transaction per metaserver.
+// returns sequences of versions and errors of metadata at the same index.
+func (m *MetaService) SaveMetadataBatch(mds []*entity.Metadata) ([]int32, []error) {
+	return m.repo.BatchInsert(mds)
+}
+
+// RemoveMetadataBatch removes metadata of objects with all their versions in a transaction per metaserver.
+// returns removed versions and errors of objects at the same index.
+func (m *MetaService) RemoveMetadataBatch(bucket string, names []string) ([]*entity.Version, []error) {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = fmt.Sprint(bucket, "/", name)
+	}
+	return m.repo.BatchRemove(ids)
+}
+
 func (m *MetaService) UpdateVersion(name, bucket string, version *entity.Version) (err error) {
 	err = m.versionRepo.Update(name, bucket, version)
 	return
 }
 
+func (m *MetaService) TouchVersion(name, bucket string, version int32) error {
+	return m.versionRepo.Touch(name, bucket, version)
+}
+
+func (m *MetaService) SwapVersion(name, bucket string, version *entity.Version) error {
+	return m.versionRepo.Swap(name, bucket, version)
+}
+
+func (m *MetaService) ListColdVersions(masterId string, before int64, cursor string, limit int) ([]*entity.Metadata, string, error) {
+	return m.versionRepo.FindCold(masterId, before, cursor, limit)
+}
+
+func (m *MetaService) ListVersions(name, bucket string, page, size int) ([]*entity.Version, int, error) {
+	return m.versionRepo.List(name, bucket, page, size)
+}
+
+func (m *MetaService) MarkVersion(name, bucket string, version int32, status string) error {
+	return m.versionRepo.Mark(name, bucket, version, status)
+}
+
+func (m *MetaService) ListChanges(masterId string, after uint64, limit int) ([]*msg.Change, uint64, string, error) {
+	return m.versionRepo.Changes(masterId, after, limit)
+}
+
+func (m *MetaService) QueryVersions(q *msg.Query) ([]*entity.Metadata, string, error) {
+	return m.versionRepo.Query(q)
+}
+
 func (m *MetaService) RemoveVersion(name, bucket string, version int32) error {
 	return m.versionRepo.Delete(name, bucket, version)
 }
 
-func (m *MetaService) GetVersion(name, bucket string, version int32) (*entity.Version, error) {
-	res, err := m.versionRepo.Find(name, bucket, version)
+func (m *MetaService) GetVersion(name, bucket string, version int32, level consistency.Level) (*entity.Version, error) {
+	res, err := m.versionRepo.Find(name, bucket, version, level)
 	if err != nil {
 		return nil, err
 	}
@@ -49,9 +96,9 @@ func (m *MetaService) GetVersion(name, bucket string, version int32) (*entity.Ve
 	return res, nil
 }
 
-func (m *MetaService) GetMetadata(name, bucket string, ver int32, withExtra bool) (*entity.Metadata, error) {
+func (m *MetaService) GetMetadata(name, bucket string, ver int32, withExtra bool, level consistency.Level) (*entity.Metadata, error) {
 	verMode := entity.VerMode(ver)
-	res, err := m.repo.FindByName(name, bucket, withExtra)
+	res, err := m.repo.FindByName(name, bucket, withExtra, level)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +106,7 @@ func (m *MetaService) GetMetadata(name, bucket string, ver int32, withExtra bool
 		return nil, usecase.ErrNotFound
 	}
 	if verMode != entity.VerModeNot {
-		v, err := m.versionRepo.Find(name, bucket, ver)
+		v, err := m.versionRepo.Find(name, bucket, ver, level)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"apiserver/internal/entity"
+	"apiserver/internal/usecase/pool"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// objectLoad is an in-flight read of an object into cache, concurrent misses of the same object wait for it
+type objectLoad struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+var (
+	loadMux sync.Mutex
+	loads   = map[string]*objectLoad{}
+)
+
+// cachedObject returns decoded data of version from pool.ObjectCache, loads it if it's admitted.
+// returns false if the object should be streamed from data servers.
+func (o *ObjectService) cachedObject(meta *entity.Metadata, ver *entity.Version) ([]byte, bool, error) {
+	if pool.ObjectCache == nil || ver.StoreStrategy == entity.Inline {
+		return nil, false, nil
+	}
+	if data, ok := pool.ObjectCache.Get(ver.Hash); ok {
+		return data, true, nil
+	}
+	loadMux.Lock()
+	if load, ok := loads[ver.Hash]; ok {
+		loadMux.Unlock()
+		load.wg.Wait()
+		return load.data, load.err == nil, load.err
+	}
+	if !pool.ObjectCache.Admit(ver.Hash, ver.Size) {
+		loadMux.Unlock()
+		return nil, false, nil
+	}
+	load := &objectLoad{}
+	load.wg.Add(1)
+	loads[ver.Hash] = load
+	loadMux.Unlock()
+
+	load.data, load.err = o.loadObject(meta, ver)
+	if load.err == nil {
+		pool.ObjectCache.Set(ver.Hash, load.data)
+	}
+	loadMux.Lock()
+	delete(loads, ver.Hash)
+	loadMux.Unlock()
+	load.wg.Done()
+	return load.data, load.err == nil, load.err
+}
+
+// loadObject reads whole data of version
+func (o *ObjectService) loadObject(meta *entity.Metadata, ver *entity.Version) ([]byte, error) {
+	stream, err := o.getObject(meta, ver)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	data := make([]byte, ver.Size)
+	if _, err = io.ReadFull(stream, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// memoryStream reads data in memory, e.g. inline or cached objects
+type memoryStream struct {
+	*bytes.Reader
+}
+
+func newMemoryStream(data []byte) memoryStream {
+	return memoryStream{bytes.NewReader(data)}
+}
+
+func (memoryStream) Close() error {
+	return nil
+}
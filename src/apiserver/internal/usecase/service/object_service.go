@@ -6,106 +6,189 @@ import (
 	"apiserver/internal/usecase/logic"
 	"apiserver/internal/usecase/pool"
 	"apiserver/internal/usecase/repo"
+	"apiserver/internal/usecase/webapi"
 	"bufio"
+	"bytes"
+	"common/consistency"
 	"common/cst"
 	"common/datasize"
 	"common/graceful"
 	"common/logs"
+	"common/proto/msg"
 	"common/response"
 	"common/util"
 	"common/util/crypto"
 	"common/util/math"
-	"context"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
-
-	"github.com/google/uuid"
-	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
-// getLocateResp raw must like "ip#idx"
-func getLocateResp(raw string) (ip string, idx int) {
-	var err error
-	strs := strings.Split(raw, "#")
-	if len(strs) != 2 {
-		panic("err format locating resp: " + raw)
-	}
-	idx, err = strconv.Atoi(strs[1])
-	if err != nil {
-		panic(err)
-	}
-	ip = strs[0]
-	return
-}
-
 type ObjectService struct {
 	metaService IMetaService
 	bucketRepo  repo.IBucketRepo
-	etcd        *clientv3.Client
+	hashRefRepo repo.IHashRefRepo
+	touches     *touchThrottle
 }
 
-func NewObjectService(s IMetaService, b repo.IBucketRepo, etcd *clientv3.Client) *ObjectService {
-	return &ObjectService{s, b, etcd}
+func NewObjectService(s IMetaService, b repo.IBucketRepo, h repo.IHashRefRepo) *ObjectService {
+	return &ObjectService{s, b, h, newTouchThrottle(s)}
 }
 
 // UniqueHash generate unique identify for an object
-func (o *ObjectService) UniqueHash(digest string, ss entity.ObjectStrategy, ds, ps int, compress bool) string {
+func (o *ObjectService) UniqueHash(digest string, ss entity.ObjectStrategy, ds, ps, lg int, compress bool, codec string) string {
 	if ss == entity.MultiReplication {
 		// MultiReplication doesn't care about shards number
 		ds, ps = 0, 0
 	}
-	return crypto.SHA256(util.StrToBytes(fmt.Sprint(digest, ss, ds, ps, compress)))
+	str := fmt.Sprint(digest, ss, ds, ps, compress)
+	// local groups make different shards of LocalReconstruction
+	if ss == entity.LocalReconstruction {
+		str += fmt.Sprint(lg)
+	}
+	// s2 is the codec of objects before codec could be chosen, keep their hash unchanged
+	if c := shardCodec(compress, codec); c != "" && c != msg.CodecS2 {
+		str += c
+	}
+	return crypto.SHA256(util.StrToBytes(str))
 }
 
-// LocateObject locate object shards by hash. send "hash.idx#key" expect "ip#idx"
-func (o *ObjectService) LocateObject(hash string, shardNum int) ([]string, bool) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	// generate a unique id as key for receive locates
-	tempId := uuid.NewString()
-	// remove this key after all
-	defer o.etcd.Delete(ctx, tempId)
-	wt := o.etcd.Watch(ctx, tempId)
-	locates := make([]string, shardNum)
-	for i := 0; i < shardNum; i++ {
-		val := fmt.Sprintf("%s.%d#%s", hash, i, tempId)
-		_, err := o.etcd.Put(ctx, cst.EtcdPrefix.LocationSubKey, val)
-		if err != nil {
-			logs.Std().Errorf("put '%s' to location-sub-key err: %s", val, err)
+// LocateObject locate object shards by hash and refer to it if exists.
+// a located object must be saved as a version, otherwise DereferObject should be called.
+func (o *ObjectService) LocateObject(hash string) ([]string, bool) {
+	locates, err := o.hashRefRepo.Refer(hash, nil)
+	if response.CheckErrStatus(404, err) {
+		// objects stored before reference counting have versions but no reference
+		locates, err = o.backfillRef(hash, 1)
+	}
+	if err != nil {
+		if !response.CheckErrStatus(404, err) {
+			logs.Std().Errorf("locate object %s err: %s", hash, err)
 		}
+		return nil, false
 	}
-	// to receive locates
-	tt := time.NewTicker(pool.Config.Object.DistinctTimeout)
-	defer tt.Stop()
+	return locates, true
+}
 
-	var cnt int
-	for cnt < len(locates) {
-		select {
-		case resp, ok := <-wt:
-			if !ok {
-				logs.Std().Warn("etcd watching locate-key stopped")
-				return nil, false
-			}
-			if resp.Canceled {
-				logs.Std().Errorf("etcd watching locate-key abort: %s", resp.Err())
-				return nil, false
-			}
-			for _, event := range resp.Events {
-				ip, idx := getLocateResp(string(event.Kv.Value))
-				logs.Std().Debugf("located success for index %d of %s at %s", idx, hash, ip)
-				locates[idx] = ip
-				cnt++
+// backfillRef creates the reference of hash counting versions holding it plus extra.
+// returns 404 error if no version holds it.
+func (o *ObjectService) backfillRef(hash string, extra int64) ([]string, error) {
+	holders, err := o.hashRefRepo.Holders(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(holders) == 0 {
+		return nil, response.NewError(404, "object not found")
+	}
+	return o.hashRefRepo.Backfill(hash, holders[0].Locate, int64(len(holders))+extra)
+}
+
+// ReferObject save the reference of object stored at locates.
+// returns locates of the first stored one if the same object has been stored.
+// uploaded shards are removed if it fails, unless they are referred anyway.
+func (o *ObjectService) ReferObject(hash string, locates []string) ([]string, error) {
+	res, err := o.hashRefRepo.Refer(hash, locates)
+	if err != nil {
+		go func() {
+			defer graceful.Recover()
+			o.discardShards(hash, locates)
+		}()
+	}
+	return res, err
+}
+
+// discardShards removes shards of hash at locates which are not referred.
+// the reference may be saved even if the response of referring is lost,
+// and objects stored before reference counting are held by versions without reference.
+func (o *ObjectService) discardShards(hash string, locates []string) {
+	cur, err := o.hashRefRepo.Locate(hash)
+	if response.CheckErrStatus(404, err) {
+		var holders []*entity.Version
+		if holders, err = o.hashRefRepo.Holders(hash); err == nil && len(holders) > 0 {
+			cur = holders[0].Locate
+		}
+	}
+	if err != nil {
+		logs.Std().Errorf("shards of %s may be orphaned, locate err: %s", hash, err)
+		return
+	}
+	removeShards(hash, locates, cur)
+}
+
+// DereferObject decrease the reference of object and remove data if there is no more reference.
+// objects stored before reference counting have no reference, their shards at locates are removed
+// if no other version holds them, otherwise the reference is created for the remaining versions.
+func (o *ObjectService) DereferObject(hash string, locates []string) error {
+	cnt, refLocates, err := o.hashRefRepo.Derefer(hash)
+	if response.CheckErrStatus(404, err) {
+		if _, err = o.backfillRef(hash, 0); !response.CheckErrStatus(404, err) {
+			return err
+		}
+		cnt, refLocates, err = 0, locates, nil
+	}
+	if err != nil || cnt > 0 {
+		return err
+	}
+	removeShards(hash, refLocates, nil)
+	return nil
+}
+
+// RemoveVersion remove a version and dereference its object
+func (o *ObjectService) RemoveVersion(name, bucket string, version int32) error {
+	ver, err := o.metaService.GetVersion(name, bucket, version, consistency.LevelStrong)
+	if err != nil {
+		return err
+	}
+	if err = o.metaService.RemoveVersion(name, bucket, version); err != nil {
+		return err
+	}
+	// data of inline version is removed with it
+	if ver.StoreStrategy == entity.Inline {
+		return nil
+	}
+	return o.DereferObject(ver.Hash, ver.Locate)
+}
+
+// RemoveVersionsByTs removes versions whose Ts is not later than ts, or equals to ts if exact.
+// returns the number of removed versions.
+func (o *ObjectService) RemoveVersionsByTs(name, bucket string, ts int64, exact bool) (int, error) {
+	const pageSize = 100
+	var matched []int32
+	for page := 1; ; page++ {
+		vers, total, err := o.metaService.ListVersions(name, bucket, page, pageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, v := range vers {
+			if v.Ts == ts || !exact && v.Ts < ts {
+				matched = append(matched, v.Sequence)
 			}
-		case <-tt.C:
-			logs.Std().Warnf("locate object %s timeout!", hash)
-			return nil, false
+		}
+		if len(vers) < pageSize || page*pageSize >= total {
+			break
+		}
+	}
+	for i, seq := range matched {
+		if err := o.RemoveVersion(name, bucket, seq); err != nil && !response.CheckErrStatus(404, err) {
+			return i, err
+		}
+	}
+	return len(matched), nil
+}
+
+// removeShards remove shards of hash at locates except the ones at same index of excepts.
+func removeShards(hash string, locates []string, excepts []string) {
+	for i, loc := range locates {
+		if i < len(excepts) && excepts[i] == loc {
+			continue
+		}
+		shard := fmt.Sprint(hash, ".", i)
+		if err := webapi.DeleteObject(loc, shard); err != nil {
+			logs.Std().Errorf("remove shard %s at %s err: %s", shard, loc, err)
 		}
 	}
-	return locates, true
 }
 
 // StoreObject store object to data server
@@ -118,7 +201,8 @@ func (o *ObjectService) StoreObject(req *entity.PutReq, md *entity.Metadata) (vn
 	go func() {
 		defer dg.Done()
 		var inner error
-		metadata, inner = o.metaService.GetMetadata(md.Name, md.Bucket, int32(entity.VerModeNot), true)
+		// read strongly to see the latest version written by others
+		metadata, inner = o.metaService.GetMetadata(md.Name, md.Bucket, int32(entity.VerModeNot), true, consistency.LevelStrong)
 		if err != nil && !response.CheckErrStatus(404, inner) {
 			dg.Error(inner)
 		}
@@ -142,31 +226,68 @@ func (o *ObjectService) StoreObject(req *entity.PutReq, md *entity.Metadata) (vn
 	if bucket.Readonly {
 		return 0, response.NewError(400, "bucket is readonly")
 	}
+	// the latest writer wins, a replica older than the latest version is a conflict
+	if req.ReplicaTs > 0 && metadata != nil && metadata.Total > 0 {
+		latest, inner := o.metaService.GetVersion(md.Name, md.Bucket, int32(metadata.Extra.LastVersion), consistency.LevelStrong)
+		if inner != nil && !response.CheckErrStatus(404, inner) {
+			return 0, inner
+		}
+		if latest != nil && latest.Ts >= req.ReplicaTs {
+			return 0, response.NewError(409, "a newer version exists")
+		}
+	}
 
 	// pre-processing the version info
 	ver := md.Versions[0]
 	// check bucket configuration and change version info
 	bucket.MakeVersion(ver, &pool.Config.Object)
+	// decide compression by sampling if it's not forced
+	if req.Body, err = autoCompress(bucket, ver, req.Auto, req.Ext, req.Body); err != nil {
+		return
+	}
 	// generate unique hash as this version hash
-	ver.Hash = o.UniqueHash(ver.Hash, ver.StoreStrategy, ver.DataShards, ver.ParityShards, ver.Compress)
+	ver.Digest = ver.Hash
+	ver.Hash = o.UniqueHash(ver.Hash, ver.StoreStrategy, ver.DataShards, ver.ParityShards, ver.LocalGroups, ver.Compress, ver.Codec)
 	// filter duplicate
 	var ok bool
-	if datasize.DataSize(ver.Size) >= pool.Config.Object.DistinctSize {
-		ver.Locate, ok = o.LocateObject(ver.Hash, ver.DataShards+ver.ParityShards)
+	if ver.StoreStrategy == entity.Inline {
+		// tiny object is saved with version, nothing is uploaded to data servers
+		if ver.Inline, err = readInline(req, ver.Size); err != nil {
+			return
+		}
+		ok = true
+	} else if datasize.DataSize(ver.Size) >= pool.Config.Object.DistinctSize {
+		ver.Locate, ok = o.LocateObject(ver.Hash)
 	}
 
 	// if object not exists, upload to data server
 	if !ok {
-		if ver.Locate, err = streamToDataServer(req, ver, NewStreamProvider(&StreamOption{
+		var locates []string
+		if locates, err = streamToDataServer(req, ver, NewStreamProvider(&StreamOption{
 			Bucket:   bucket.Name,
 			Hash:     ver.Hash, // store to data-server with version hash
 			Name:     md.Name,
 			Size:     ver.Size,
 			Compress: ver.Compress,
+			Codec:    ver.Codec,
 		}, ver)); err != nil {
 			return -1, fmt.Errorf("stream to data server err: %w", err)
 		}
+		if ver.Locate, err = o.ReferObject(ver.Hash, locates); err != nil {
+			return -1, fmt.Errorf("refer object err: %w", err)
+		}
+		// the same object has been stored concurrently, remove the duplicated shards
+		go func() {
+			defer graceful.Recover()
+			removeShards(ver.Hash, locates, ver.Locate)
+		}()
 	}
+	// release reference if fails to save version
+	defer func() {
+		if err != nil && ver.StoreStrategy != entity.Inline {
+			util.LogErrWithPre("dereference object err", o.DereferObject(ver.Hash, ver.Locate))
+		}
+	}()
 
 	// save metadata
 	if metadata == nil {
@@ -174,7 +295,7 @@ func (o *ObjectService) StoreObject(req *entity.PutReq, md *entity.Metadata) (vn
 		if vn, err = o.metaService.SaveMetadata(md); !errors.Is(err, ErrMetadataExists) {
 			return
 		}
-		metadata, err = o.metaService.GetMetadata(md.Name, md.Bucket, int32(entity.VerModeNot), true)
+		metadata, err = o.metaService.GetMetadata(md.Name, md.Bucket, int32(entity.VerModeNot), true, consistency.LevelStrong)
 		if err != nil {
 			return
 		}
@@ -186,13 +307,195 @@ func (o *ObjectService) StoreObject(req *entity.PutReq, md *entity.Metadata) (vn
 		go func() {
 			defer graceful.Recover()
 			// if not err, delete first version
-			inner := o.metaService.RemoveVersion(md.Name, md.Bucket, int32(metadata.FirstVersion))
+			inner := o.RemoveVersion(md.Name, md.Bucket, int32(metadata.FirstVersion))
 			util.LogErrWithPre("remove first version err", inner)
 		}()
 	}
 	return
 }
 
+// bulkConcurrency is the max number of bulk objects uploaded to data servers at the same time
+const bulkConcurrency = 16
+
+// StoreObjects stores small objects to data servers, then saves their metadata in a batch per metaserver.
+// objects failed to save metadata are dereferenced. results are at the same index of objects.
+func (o *ObjectService) StoreObjects(bucketName string, objs []*entity.BulkObject) ([]*entity.BulkResult, error) {
+	bucket, err := o.bucketRepo.Get(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if bucket.Readonly {
+		return nil, response.NewError(400, "bucket is readonly")
+	}
+	results := make([]*entity.BulkResult, len(objs))
+	stored := make([]*entity.Metadata, len(objs))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, bulkConcurrency)
+	for i, obj := range objs {
+		results[i] = &entity.BulkResult{Name: obj.Name}
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, obj *entity.BulkObject) {
+			defer graceful.Recover()
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			md, inner := o.storeBulkObject(bucket, obj)
+			if inner != nil {
+				results[i].Error = inner.Error()
+				return
+			}
+			stored[i] = md
+		}(i, obj)
+	}
+	wg.Wait()
+	// save metadata of stored objects
+	mds := make([]*entity.Metadata, 0, len(objs))
+	idx := make([]int, 0, len(objs))
+	for i, md := range stored {
+		if md != nil {
+			mds = append(mds, md)
+			idx = append(idx, i)
+		}
+	}
+	if len(mds) == 0 {
+		return results, nil
+	}
+	vers, errs := o.metaService.SaveMetadataBatch(mds)
+	for j, md := range mds {
+		res := results[idx[j]]
+		if errs[j] != nil {
+			res.Error = errs[j].Error()
+			if md.Versions[0].StoreStrategy == entity.Inline {
+				continue
+			}
+			util.LogErrWithPre("dereference object err", o.DereferObject(md.Versions[0].Hash, md.Versions[0].Locate))
+			continue
+		}
+		res.Version = vers[j]
+		if vers[j] > 1 {
+			go func(name string) {
+				defer graceful.Recover()
+				o.trimVersions(name, bucket)
+			}(md.Name)
+		}
+	}
+	return results, nil
+}
+
+// RemoveObjects removes objects with all their versions in a transaction per metaserver,
+// then dereferences objects of removed versions. results are at the same index of names.
+func (o *ObjectService) RemoveObjects(bucket string, names []string) []*entity.BulkResult {
+	removed, errs := o.metaService.RemoveMetadataBatch(bucket, names)
+	results := make([]*entity.BulkResult, len(names))
+	for i, name := range names {
+		results[i] = &entity.BulkResult{Name: name}
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+		}
+	}
+	for _, ver := range removed {
+		// data of inline version is removed with it
+		if ver.StoreStrategy == entity.Inline {
+			continue
+		}
+		util.LogErrWithPre("dereference object err", o.DereferObject(ver.Hash, ver.Locate))
+	}
+	return results
+}
+
+// storeBulkObject stores data of object and returns its metadata to save
+func (o *ObjectService) storeBulkObject(bucket *entity.Bucket, obj *entity.BulkObject) (*entity.Metadata, error) {
+	digest := crypto.SHA256(obj.Data)
+	ver := &entity.Version{
+		Size:          int64(len(obj.Data)),
+		Hash:          digest,
+		StoreStrategy: obj.Store,
+		ContentType:   obj.MediaType,
+	}
+	bucket.MakeVersion(ver, &pool.Config.Object)
+	body, err := autoCompress(bucket, ver, false, util.GetFileExtOrDefault(obj.Name, false, "bytes"), bytes.NewReader(obj.Data))
+	if err != nil {
+		return nil, err
+	}
+	ver.Digest = ver.Hash
+	ver.Hash = o.UniqueHash(ver.Hash, ver.StoreStrategy, ver.DataShards, ver.ParityShards, ver.LocalGroups, ver.Compress, ver.Codec)
+	var ok bool
+	if ver.StoreStrategy == entity.Inline {
+		ver.Inline, ok = obj.Data, true
+	} else if datasize.DataSize(ver.Size) >= pool.Config.Object.DistinctSize {
+		ver.Locate, ok = o.LocateObject(ver.Hash)
+	}
+	if !ok {
+		req := &entity.PutReq{Name: obj.Name, Bucket: bucket.Name, Hash: digest, Body: body}
+		locates, err := streamToDataServer(req, ver, NewStreamProvider(&StreamOption{
+			Bucket:   bucket.Name,
+			Hash:     ver.Hash,
+			Name:     obj.Name,
+			Size:     ver.Size,
+			Compress: ver.Compress,
+			Codec:    ver.Codec,
+		}, ver))
+		if err != nil {
+			return nil, fmt.Errorf("stream to data server err: %w", err)
+		}
+		if ver.Locate, err = o.ReferObject(ver.Hash, locates); err != nil {
+			return nil, fmt.Errorf("refer object err: %w", err)
+		}
+		go func() {
+			defer graceful.Recover()
+			removeShards(ver.Hash, locates, ver.Locate)
+		}()
+	}
+	return &entity.Metadata{Name: obj.Name, Bucket: bucket.Name, Versions: []*entity.Version{ver}}, nil
+}
+
+// trimVersions removes the first version of object if it has more versions than the bucket keeps
+func (o *ObjectService) trimVersions(name string, bucket *entity.Bucket) {
+	md, err := o.metaService.GetMetadata(name, bucket.Name, int32(entity.VerModeNot), true, consistency.LevelStrong)
+	if err != nil {
+		logs.Std().Errorf("get metadata of %s/%s err: %s", bucket.Name, name, err)
+		return
+	}
+	if md.Total > 1 && !bucket.Versioning || md.Total > bucket.VersionRemains {
+		util.LogErrWithPre("remove first version err", o.RemoveVersion(name, bucket.Name, int32(md.FirstVersion)))
+	}
+}
+
+// autoCompress samples body to decide whether to compress the version in auto mode,
+// which is asked by the request or configured by bucket. versions compressed anyway are sampled as well,
+// so that every compressed version has the ratio for estimating stored size. returns a reader of the whole body.
+func autoCompress(bucket *entity.Bucket, ver *entity.Version, auto bool, ext string, body io.Reader) (io.Reader, error) {
+	if ver.StoreStrategy == entity.Inline || !ver.Compress && !auto && !bucket.AutoCompress {
+		return body, nil
+	}
+	comp := logic.NewCompression()
+	body, ratio, err := comp.Sample(ext, body)
+	if err != nil {
+		return nil, err
+	}
+	ver.CompressRatio = ratio
+	if !ver.Compress && comp.Compressible(ratio) {
+		ver.Compress = true
+		ver.Codec = bucket.Codec
+	}
+	return body, nil
+}
+
+// readInline reads the whole object stored Inline and validates its digest if required
+func readInline(req *entity.PutReq, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(req.Body, data); err != nil {
+		return nil, response.NewError(400, fmt.Sprintf("read object err: %s", err))
+	}
+	if pool.Config.Object.Checksum && crypto.SHA256(data) != req.Hash {
+		logs.Std().Infof("Digest of %v validation failure\n", req.Name)
+		return nil, ErrInvalidFile
+	}
+	return data, nil
+}
+
 func streamToDataServer(req *entity.PutReq, meta *entity.Version, provider StreamProvider) ([]string, error) {
 	//stream to store
 	stream, locates, err := dataServerStream(meta, provider)
@@ -232,6 +535,23 @@ func streamToDataServer(req *entity.PutReq, meta *entity.Version, provider Strea
 }
 
 func (o *ObjectService) GetObject(meta *entity.Metadata, ver *entity.Version) (io.ReadSeekCloser, error) {
+	if pool.Config.Tiering.Enabled && time.Since(time.UnixMilli(ver.AccessTs)) >= pool.Config.Tiering.AccessInterval {
+		o.touches.Add(meta.Name, meta.Bucket, ver.Sequence)
+	}
+	if data, ok, err := o.cachedObject(meta, ver); err != nil {
+		return nil, err
+	} else if ok {
+		return newMemoryStream(data), nil
+	}
+	return o.getObject(meta, ver)
+}
+
+// getObject get object stream without updating access time
+func (o *ObjectService) getObject(meta *entity.Metadata, ver *entity.Version) (io.ReadSeekCloser, error) {
+	// inline object is read from version without data servers
+	if ver.StoreStrategy == entity.Inline {
+		return newMemoryStream(ver.Inline), nil
+	}
 	up := func(locates []string) error {
 		ver.Locate = locates
 		return o.metaService.UpdateVersion(meta.Name, meta.Bucket, ver)
@@ -242,6 +562,7 @@ func (o *ObjectService) GetObject(meta *entity.Metadata, ver *entity.Version) (i
 		Name:     meta.Name,
 		Bucket:   meta.Bucket,
 		Compress: ver.Compress,
+		Codec:    ver.Codec,
 		Updater:  up,
 	}
 	return NewStreamProvider(opt, ver).GetStream(ver.Locate)
@@ -265,11 +586,22 @@ func NewStreamProvider(opt *StreamOption, ver *entity.Version) StreamProvider {
 		cfg := pool.Config.Object.Replication
 		cfg.CopiesCount = ver.DataShards
 		return CpStreamProvider(opt, &cfg)
+	case entity.LocalReconstruction:
+		cfg := pool.Config.Object.LocalReconstruction
+		cfg.DataShards = ver.DataShards
+		cfg.LocalGroups = ver.LocalGroups
+		cfg.GlobalParity = ver.ParityShards
+		// aligned block size
+		if i := cfg.BlockSize() % cst.OS.NetPkgSize; i > 0 {
+			newSize := math.MinInt(cfg.BlockSize()-i+cst.OS.NetPkgSize, int(opt.Size))
+			cfg.BlockPerShard = newSize / cfg.DataShards
+		}
+		return LrcStreamProvider(opt, &cfg)
 	}
 }
 
 func dataServerStream(meta *entity.Version, provider StreamProvider) (WriteCommitCloser, []string, error) {
-	ds := logic.NewDiscovery().SelectDataServer(pool.Balancer, meta.DataShards+meta.ParityShards)
+	ds := logic.NewDiscovery().SelectDataServer(pool.Balancer, meta.AllShards(), meta.Tolerance())
 	if len(ds) == 0 {
 		return nil, nil, ErrServiceUnavailable
 	}
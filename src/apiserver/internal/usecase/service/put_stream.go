@@ -6,36 +6,54 @@ import (
 	"bytes"
 	"common/graceful"
 	"common/logs"
+	"common/proto/pb"
+	"errors"
 	"sync/atomic"
 )
 
-// PutStream transaction put stream
+// PutStream transaction put stream.
+// data is written through a grpc stream if server supports it, otherwise by http requests.
 type PutStream struct {
 	Locate    string
 	name      string
 	tmpId     string
-	compress  bool
+	codec     string
 	committed *atomic.Bool
+	stream    *shardStream
 }
 
-// NewPutStream IO: sending POST request to server
-func NewPutStream(ip, name string, size int64, compress bool) (*PutStream, error) {
+// NewPutStream IO: open a stream to server or sending POST request for old servers
+func NewPutStream(ip, name string, size int64, codec string) (*PutStream, error) {
+	res := &PutStream{Locate: ip, name: name, committed: &atomic.Bool{}, codec: codec}
+	if !isLegacyServer(ip) {
+		stream, id, err := openShardStream(ip, &pb.ShardWrite{Name: name, Size: size})
+		if err == nil {
+			res.stream, res.tmpId = stream, id
+			return res, nil
+		}
+		if !errors.Is(err, errLegacyServer) {
+			return nil, err
+		}
+	}
 	id, e := webapi.PostTmpObject(ip, name, size)
 	if e != nil {
 		return nil, e
 	}
-	res := &PutStream{Locate: ip, name: name, tmpId: id, committed: &atomic.Bool{}, compress: compress}
+	res.tmpId = id
 	return res, nil
 }
 
-// newExistedPutStream skip POST request to continue a transfer
-func newExistedPutStream(ip, name, id string, compress bool) *PutStream {
-	res := &PutStream{Locate: ip, name: name, tmpId: id, committed: &atomic.Bool{}, compress: compress}
+// newExistedPutStream skip POST request to continue a transfer. stream is opened on first writing.
+func newExistedPutStream(ip, name, id string, codec string) *PutStream {
+	res := &PutStream{Locate: ip, name: name, tmpId: id, committed: &atomic.Bool{}, codec: codec}
 	return res
 }
 
+// Close IO: wait until written data reaches server without committing
 func (p *PutStream) Close() error {
-	p.committed.CompareAndSwap(false, true)
+	if p.committed.CompareAndSwap(false, true) && p.stream != nil {
+		return p.stream.finish(nil)
+	}
 	return nil
 }
 
@@ -43,6 +61,18 @@ func (p *PutStream) Write(b []byte) (n int, err error) {
 	if p.committed.Load() {
 		return 0, usecase.ErrStreamClosed
 	}
+	if p.stream == nil && !isLegacyServer(p.Locate) {
+		p.stream, _, err = openShardStream(p.Locate, &pb.ShardWrite{TmpId: p.tmpId})
+		if err != nil && !errors.Is(err, errLegacyServer) {
+			return 0, err
+		}
+	}
+	if p.stream != nil {
+		if err = p.stream.Write(b); err != nil {
+			return
+		}
+		return len(b), nil
+	}
 	if err = webapi.PatchTmpObject(p.Locate, p.tmpId, bytes.NewBuffer(b)); err != nil {
 		return
 	}
@@ -55,14 +85,22 @@ func (p *PutStream) Commit(ok bool) error {
 		if !ok {
 			go func() {
 				defer graceful.Recover()
-				if err := webapi.DeleteTmpObject(p.Locate, p.tmpId); err != nil {
+				var err error
+				if p.stream != nil {
+					err = p.stream.finish(&pb.ShardWrite{Abort: true})
+				} else {
+					err = webapi.DeleteTmpObject(p.Locate, p.tmpId)
+				}
+				if err != nil {
 					logs.Std().Error(err)
 				}
 			}()
 			return nil
 		}
-
-		return webapi.PutTmpObject(p.Locate, p.tmpId, p.compress)
+		if p.stream != nil {
+			return p.stream.finish(&pb.ShardWrite{Commit: true, Compress: p.codec != "", Codec: p.codec})
+		}
+		return webapi.PutTmpObject(p.Locate, p.tmpId, p.codec)
 	}
 	return nil
 }
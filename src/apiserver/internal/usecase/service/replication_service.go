@@ -0,0 +1,403 @@
+package service
+
+import (
+	"apiserver/internal/entity"
+	. "apiserver/internal/usecase"
+	"apiserver/internal/usecase/pool"
+	"apiserver/internal/usecase/webapi"
+	"common/consistency"
+	"common/cst"
+	"common/graceful"
+	"common/logs"
+	"common/proto/msg"
+	"common/replication"
+	"common/response"
+	"common/util"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var replLog = logs.New("replication-service")
+
+// errConflict means a newer version exists in remote cluster
+var errConflict = errors.New("newer version exists in remote cluster")
+
+// ReplicationService copies changes of buckets with replication rules to remote clusters asynchronously.
+// changes are read from the change feed of every metadata server, progresses and failed changes are saved in etcd.
+type ReplicationService struct {
+	objectService *ObjectService
+	metaService   IMetaService
+	kv            clientv3.KV
+}
+
+func NewReplicationService(o *ObjectService, m IMetaService, kv clientv3.KV) *ReplicationService {
+	return &ReplicationService{objectService: o, metaService: m, kv: kv}
+}
+
+func replicationKey(kind, id string) string {
+	return cst.EtcdPrefix.FmtReplication(pool.Config.Registry.Group, kind, id)
+}
+
+// loadRules returns enabled rules by bucket
+func (r *ReplicationService) loadRules(ctx context.Context) (map[string]*replication.Rule, error) {
+	resp, err := r.kv.Get(ctx, replicationKey(replication.KindRule, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	rules := make(map[string]*replication.Rule, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var rule replication.Rule
+		if err = json.Unmarshal(kv.Value, &rule); err != nil {
+			replLog.Errorf("invalid replication rule %s: %s", kv.Key, err)
+			continue
+		}
+		if !rule.Disabled {
+			rules[rule.Bucket] = &rule
+		}
+	}
+	return rules, nil
+}
+
+func (r *ReplicationService) loadStatus(ctx context.Context, group string) (*replication.Status, error) {
+	st := &replication.Status{Group: group}
+	resp, err := r.kv.Get(ctx, replicationKey(replication.KindStatus, group))
+	if err != nil || len(resp.Kvs) == 0 {
+		return st, err
+	}
+	return st, json.Unmarshal(resp.Kvs[0].Value, st)
+}
+
+func (r *ReplicationService) saveStatus(ctx context.Context, st *replication.Status) error {
+	st.UpdatedAt = time.Now()
+	bt, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, err = r.kv.Put(ctx, replicationKey(replication.KindStatus, st.Group), string(bt))
+	return err
+}
+
+// migrateStatus moves the progress and retries saved by masterId in previous versions to the group
+func (r *ReplicationService) migrateStatus(ctx context.Context, masterId string, st *replication.Status) error {
+	legacy, err := r.loadStatus(ctx, masterId)
+	if err != nil || legacy.UpdatedAt.IsZero() {
+		return err
+	}
+	replLog.Infof("migrate replication status of %s to group %s", masterId, st.Group)
+	group := st.Group
+	*st = *legacy
+	st.Group = group
+	resp, err := r.kv.Get(ctx, replicationKey(replication.KindRetry, masterId+"-"), clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	for _, kv := range resp.Kvs {
+		seq := strings.TrimPrefix(string(kv.Key), replicationKey(replication.KindRetry, masterId+"-"))
+		if _, err = r.kv.Put(ctx, replicationKey(replication.KindRetry, st.Group+"-"+seq), string(kv.Value)); err != nil {
+			return err
+		}
+		if _, err = r.kv.Delete(ctx, string(kv.Key)); err != nil {
+			return err
+		}
+	}
+	if err = r.saveStatus(ctx, st); err != nil {
+		return err
+	}
+	_, err = r.kv.Delete(ctx, replicationKey(replication.KindStatus, masterId))
+	return err
+}
+
+// RunOnce replicates new changes and retries failed ones of all metadata servers
+func (r *ReplicationService) RunOnce(ctx context.Context) {
+	masters := pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true)
+	masterIds := make([]string, 0, len(masters))
+	for masterId := range masters {
+		masterIds = append(masterIds, masterId)
+	}
+	r.ReplicateFrom(ctx, masterIds...)
+}
+
+// ReplicateFrom replicates new changes and retries failed ones of the metadata servers of masterIds.
+// progresses are saved by the group of change feed which stays the same after master of the group changed.
+func (r *ReplicationService) ReplicateFrom(ctx context.Context, masterIds ...string) {
+	rules, err := r.loadRules(ctx)
+	if err != nil {
+		replLog.Errorf("load replication rules err: %s", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	for _, masterId := range masterIds {
+		_, _, group, err := r.metaService.ListChanges(masterId, 0, 1)
+		if err != nil {
+			replLog.Errorf("list changes from %s err: %s", masterId, err)
+			continue
+		}
+		st, err := r.loadStatus(ctx, group)
+		if err == nil && st.UpdatedAt.IsZero() && group != masterId {
+			err = r.migrateStatus(ctx, masterId, st)
+		}
+		if err != nil {
+			replLog.Errorf("load replication status of %s err: %s", group, err)
+			continue
+		}
+		r.retry(ctx, st, rules)
+		r.replicate(ctx, masterId, st, rules)
+		util.LogErrWithPre("save replication status err", r.saveStatus(ctx, st))
+	}
+}
+
+// replicate handles changes from masterId after the checkpoint. failed changes are put into the retry queue.
+func (r *ReplicationService) replicate(ctx context.Context, masterId string, st *replication.Status, rules map[string]*replication.Rule) {
+	conf := &pool.Config.BucketReplication
+	for ctx.Err() == nil {
+		changes, head, group, err := r.metaService.ListChanges(masterId, st.Checkpoint, conf.BatchSize)
+		if err != nil {
+			replLog.Errorf("list changes from %s err: %s", masterId, err)
+			break
+		}
+		if group != st.Group {
+			// another group has been served by the server since the progress was loaded
+			replLog.Warnf("change feed of %s is group %s now, expect %s", masterId, group, st.Group)
+			break
+		}
+		st.Head = head
+		if len(changes) > 0 && st.Checkpoint > 0 && changes[0].Seq > st.Checkpoint+1 {
+			replLog.Warnf("changes of %s from %d to %d have been trimmed before replicated", st.Group, st.Checkpoint+1, changes[0].Seq-1)
+		}
+		for _, c := range changes {
+			if err = r.handle(c, rules, st); err != nil {
+				replLog.Warnf("replicate change %d of %s err: %s", c.Seq, st.Group, err)
+				if r.enqueue(ctx, st, &replication.Retry{Change: c}, err) {
+					st.Retrying++
+				}
+			}
+			st.Checkpoint = c.Seq
+		}
+		if len(changes) < conf.BatchSize {
+			break
+		}
+	}
+	st.Lag, st.LagMillis = 0, 0
+	if st.Head > st.Checkpoint {
+		st.Lag = st.Head - st.Checkpoint
+		if next, _, _, err := r.metaService.ListChanges(masterId, st.Checkpoint, 1); err == nil && len(next) > 0 {
+			st.LagMillis = time.Since(time.UnixMilli(next[0].Ts)).Milliseconds()
+		}
+	}
+}
+
+// handle replicates a change if its bucket has a rule
+func (r *ReplicationService) handle(c *msg.Change, rules map[string]*replication.Rule, st *replication.Status) error {
+	bucket, name, _ := strings.Cut(c.Id, "/")
+	rule, ok := rules[bucket]
+	if !ok {
+		return nil
+	}
+	switch c.Op {
+	case msg.ChangePutVersion:
+		// replicas from other clusters are not replicated again to avoid loops
+		if c.Version == nil || c.Version.Replication == msg.ReplicaReplica {
+			return nil
+		}
+		err := r.copyVersion(rule, name, bucket, c)
+		if errors.Is(err, errConflict) {
+			st.Conflicts++
+			return r.mark(name, bucket, c.Sequence, msg.ReplicaConflict)
+		}
+		if err != nil {
+			return err
+		}
+		st.Replicated++
+		return r.mark(name, bucket, c.Sequence, msg.ReplicaCompleted)
+	case msg.ChangeRemoveVersion:
+		if !rule.Deletes || c.Version == nil {
+			return nil
+		}
+		if err := webapi.DeleteReplica(rule, name, 0, c.Version.Ts); err != nil && !response.CheckErrStatus(404, err) {
+			return err
+		}
+		st.Deleted++
+	case msg.ChangeRemoveAllVersion, msg.ChangeRemoveMetadata:
+		if !rule.Deletes {
+			return nil
+		}
+		if err := webapi.DeleteReplica(rule, name, c.Ts, 0); err != nil && !response.CheckErrStatus(404, err) {
+			return err
+		}
+		st.Deleted++
+	}
+	return nil
+}
+
+func (r *ReplicationService) mark(name, bucket string, ver uint64, status string) error {
+	err := r.metaService.MarkVersion(name, bucket, int32(ver), status)
+	if response.CheckErrStatus(404, err) {
+		// removed after replicated
+		return nil
+	}
+	return err
+}
+
+// copyVersion uploads the version to remote cluster with the time of source version, which decides the winner of conflicts
+func (r *ReplicationService) copyVersion(rule *replication.Rule, name, bucket string, c *msg.Change) error {
+	ver, err := r.metaService.GetVersion(name, bucket, int32(c.Sequence), consistency.LevelStrong)
+	if response.CheckErrStatus(404, err) || errors.Is(err, ErrNotFound) {
+		// removed before replicated
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reader, err := r.objectService.getObject(&entity.Metadata{Name: name, Bucket: bucket}, ver)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	// digest of object is required by remote, spool object to a temp file to calculate it before uploading
+	file, err := os.CreateTemp("", "replica-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		util.LogErr(file.Close())
+		util.LogErr(os.Remove(file.Name()))
+	}()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), reader)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	err = webapi.PutReplica(rule, name, hex.EncodeToString(hash.Sum(nil)), c.Version.Ts, file, size)
+	if response.CheckErrStatus(409, err) {
+		return errConflict
+	}
+	return err
+}
+
+// enqueue saves a failed change to the retry queue, or gives up if retried too many times.
+// returns false if the change is not in queue.
+func (r *ReplicationService) enqueue(ctx context.Context, st *replication.Status, rt *replication.Retry, cause error) bool {
+	conf := &pool.Config.BucketReplication
+	key := replicationKey(replication.KindRetry, fmt.Sprintf("%s-%020d", st.Group, rt.Change.Seq))
+	bucket, name, _ := strings.Cut(rt.Change.Id, "/")
+	rt.Attempts++
+	rt.Error = cause.Error()
+	if rt.Attempts >= conf.MaxRetry {
+		replLog.Errorf("give up replicating change %d of %s after %d attempts: %s", rt.Change.Seq, st.Group, rt.Attempts, cause)
+		st.Failed++
+		if rt.Change.Op == msg.ChangePutVersion {
+			util.LogErrWithPre("mark version err", r.mark(name, bucket, rt.Change.Sequence, msg.ReplicaFailed))
+		}
+		_, err := r.kv.Delete(ctx, key)
+		util.LogErrWithPre("remove replication retry err", err)
+		return false
+	}
+	rt.NextAt = time.Now().Add(replication.Backoff(rt.Attempts, conf.RetryBase, conf.RetryMax))
+	bt, err := json.Marshal(rt)
+	if err != nil {
+		replLog.Errorf("encode replication retry err: %s", err)
+		return false
+	}
+	if _, err = r.kv.Put(ctx, key, string(bt)); err != nil {
+		replLog.Errorf("save replication retry err: %s", err)
+		return false
+	}
+	if rt.Attempts == 1 && rt.Change.Op == msg.ChangePutVersion {
+		util.LogErrWithPre("mark version err", r.mark(name, bucket, rt.Change.Sequence, msg.ReplicaPending))
+	}
+	return true
+}
+
+// retry handles changes in the retry queue whose time has come
+func (r *ReplicationService) retry(ctx context.Context, st *replication.Status, rules map[string]*replication.Rule) {
+	resp, err := r.kv.Get(ctx, replicationKey(replication.KindRetry, st.Group+"-"), clientv3.WithPrefix())
+	if err != nil {
+		replLog.Errorf("list replication retries of %s err: %s", st.Group, err)
+		return
+	}
+	st.Retrying = len(resp.Kvs)
+	now := time.Now()
+	for _, kv := range resp.Kvs {
+		var rt replication.Retry
+		if err = json.Unmarshal(kv.Value, &rt); err != nil || rt.Change == nil {
+			replLog.Errorf("invalid replication retry %s, remove it", kv.Key)
+			_, _ = r.kv.Delete(ctx, string(kv.Key))
+			st.Retrying--
+			continue
+		}
+		if rt.NextAt.After(now) {
+			continue
+		}
+		if err = r.handle(rt.Change, rules, st); err != nil {
+			if !r.enqueue(ctx, st, &rt, err) {
+				st.Retrying--
+			}
+			continue
+		}
+		if _, err = r.kv.Delete(ctx, string(kv.Key)); err != nil {
+			replLog.Errorf("remove replication retry err: %s", err)
+			continue
+		}
+		st.Retrying--
+	}
+}
+
+// StartAutoReplication replicates changes periodically. only one api server will do replicating at the same time.
+func (r *ReplicationService) StartAutoReplication() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !pool.Config.BucketReplication.Enabled {
+		return cancel
+	}
+	tk := time.NewTicker(pool.Config.BucketReplication.Interval)
+	go func() {
+		defer graceful.Recover()
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				replLog.Info("stop auto replication")
+				return
+			case <-tk.C:
+				if err := r.runWithLock(ctx); err != nil {
+					replLog.Warnf("auto replication err: %s", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+func (r *ReplicationService) runWithLock(ctx context.Context) error {
+	sess, err := concurrency.NewSession(pool.Etcd, concurrency.WithTTL(15))
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	mux := concurrency.NewMutex(sess, cst.EtcdPrefix.FmtLock(pool.Config.Registry.Group, "replication"))
+	if err = mux.TryLock(ctx); err != nil {
+		if errors.Is(err, concurrency.ErrLocked) {
+			replLog.Debug("replication is running on other server, skip")
+			return nil
+		}
+		return err
+	}
+	defer func() { util.LogErr(mux.Unlock(context.Background())) }()
+	r.RunOnce(ctx)
+	return nil
+}
@@ -5,35 +5,106 @@ import (
 	"apiserver/internal/usecase"
 	"common/graceful"
 	"common/logs"
+	"errors"
 	"io"
-	"sync"
+	"time"
 
 	"github.com/klauspost/reedsolomon"
 )
 
+// states of shards in decoder
+const (
+	shardIdle    = iota // shardIdle is not read or was too slow, it's a standby of hedging
+	shardActive         // shardActive is read for every block
+	shardDropped        // shardDropped failed, it will never be read again
+)
+
+// shardResetter is a shard reader which can abandon reading in progress, then it's reopened from the offset of next Seek
+type shardResetter interface {
+	Reset() error
+}
+
 type rsDecoder struct {
-	enc          reedsolomon.Encoder
-	rsCfg        config.RsConfig
-	readers      []io.Reader
-	writers      []io.Writer
-	cache        []byte
-	shardsBuffer []byte
-	cursor       int
-	total        int64
-	size         int64
+	enc     erasureCodec
+	rsCfg   config.RsConfig
+	readers []io.Reader
+	writers []io.Writer
+	buffers [][]byte // buffers of reading blocks, one for each shard
+	cache   []byte
+	cursor  int
+	total   int64
+	size    int64
+	blocks  int64 // blocks number of shard have been read
+	// rewriting lost shards in background
+	rewrites    chan [][]byte
+	rewriteDone chan struct{}
+	rewriteErr  error
+	// hedging
+	hedge   *config.HedgeConfig
+	locates []string
+	states  []int
+	standby []int           // idle shards in the order of activating
+	reading []chan struct{} // reading[i] is closed when the last read of shard i finishes
+	// abandoned[i] is closed when the read of shard i in progress when it was put back on standby is canceled
+	abandoned []chan struct{}
+	// plan returns shards should be read after some failed, a standby one is read instead of each failed if nil
+	plan func() []int
+}
+
+// rewriteQueueSize blocks waiting to be rewritten before reading is blocked
+const rewriteQueueSize = 8
+
+type shardBlock struct {
+	index int
+	data  []byte
+	err   error
 }
 
 func NewDecoder(readers []io.Reader, writes []io.Writer, size int64, rsCfg *config.RsConfig) *rsDecoder {
 	enc, _ := reedsolomon.New(rsCfg.DataShards, rsCfg.ParityShards, reedsolomon.WithAutoGoroutines(rsCfg.BlockPerShard))
+	return newDecoder(enc, readers, writes, size, rsCfg)
+}
+
+func newDecoder(enc erasureCodec, readers []io.Reader, writes []io.Writer, size int64, rsCfg *config.RsConfig) *rsDecoder {
 	buf := make([]byte, rsCfg.FullSize())
+	buffers := make([][]byte, rsCfg.AllShards())
+	states := make([]int, rsCfg.AllShards())
+	for i := range buffers {
+		buffers[i] = buf[i*rsCfg.BlockPerShard : (i+1)*rsCfg.BlockPerShard : (i+1)*rsCfg.BlockPerShard]
+		if readers[i] != nil {
+			states[i] = shardActive
+		}
+	}
 	return &rsDecoder{
-		readers:      readers,
-		writers:      writes,
-		enc:          enc,
-		size:         size,
-		cache:        buf[:0], // let cache and shardsBuffer using same address to reduce memory allocate and usage
-		shardsBuffer: buf,
-		rsCfg:        *rsCfg,
+		readers: readers,
+		writers: writes,
+		enc:     enc,
+		size:    size,
+		buffers: buffers,
+		cache:   make([]byte, 0, rsCfg.BlockSize()),
+		states:  states,
+		rsCfg:   *rsCfg,
+		// channels are only set by hedging
+		reading:   make([]chan struct{}, rsCfg.AllShards()),
+		abandoned: make([]chan struct{}, rsCfg.AllShards()),
+	}
+}
+
+// enableHedge reads only the fastest DataShards shards of readers. locates are servers of shards to rank them.
+func (d *rsDecoder) enableHedge(locates []string, conf *config.HedgeConfig) {
+	d.hedge = conf
+	d.locates = locates
+	var active int
+	for _, idx := range shardLatency.Rank(locates) {
+		if d.readers[idx] == nil {
+			continue
+		}
+		if active < d.rsCfg.DataShards {
+			active++
+			continue
+		}
+		d.states[idx] = shardIdle
+		d.standby = append(d.standby, idx)
 	}
 }
 
@@ -69,53 +140,32 @@ func (d *rsDecoder) getData() error {
 	}
 
 	// read shards
-	var wg sync.WaitGroup
-	shards := make([][]byte, d.rsCfg.AllShards())
-	for i := range shards {
-		if d.readers[i] == nil {
-			continue
-		}
-		// split shardsBuffer to avoid new memory alloc
-		shards[i] = d.shardsBuffer[i*d.rsCfg.BlockPerShard : (i+1)*d.rsCfg.BlockPerShard]
-		wg.Add(1)
-		go func(idx int) {
-			defer graceful.Recover()
-			defer wg.Done()
-			n, err := io.ReadFull(d.readers[idx], shards[idx])
-			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-				logs.Std().Debugf("read shard %d err: %s", idx, err)
-				shards[idx] = nil
-			}
-			shards[idx] = shards[idx][:n]
-		}(i)
+	shards, err := d.readShards()
+	if err != nil {
+		return err
 	}
-	wg.Wait()
+	d.blocks++
 
-	// reconstruct data
-	if err := d.enc.Reconstruct(shards); err != nil {
+	// reconstruct data, parity shards are required only if they are lost and will be rewritten
+	hasWriter := false
+	for _, w := range d.writers {
+		hasWriter = hasWriter || w != nil
+	}
+	if hasWriter {
+		err = d.enc.Reconstruct(shards)
+	} else {
+		err = d.enc.ReconstructData(shards)
+	}
+	if err != nil {
 		return err
 	}
 
 	// save lost shards
-	var err error
-	go func() {
-		defer graceful.Recover()
-		for i, w := range d.writers {
-			if w == nil {
-				continue
-			}
-			wg.Add(1)
-			go func(idx int, wt io.Writer) {
-				defer graceful.Recover()
-				defer wg.Done()
-				if _, inner := wt.Write(shards[idx]); inner != nil {
-					err = inner
-					logs.Std().Errorf("rewrite lost shards fail: %s", inner)
-					return
-				}
-			}(i, w)
+	if hasWriter {
+		if err = d.rewrite(shards); err != nil {
+			return err
 		}
-	}()
+	}
 
 	// combine data shards
 	for i := range shards[:d.rsCfg.DataShards] {
@@ -128,10 +178,261 @@ func (d *rsDecoder) getData() error {
 		d.total += shardSize
 	}
 
+	return nil
+}
+
+// Repair rewrites lost shards to writers without returning data, it must be called before reading.
+// shards of local reconstruction codes are repaired by reading only their local groups if possible,
+// the whole object is decoded otherwise.
+func (d *rsDecoder) Repair() error {
+	if c, ok := d.enc.(*lrcCodec); ok {
+		if groups, ok := c.localRepair(d.readers, d.writers); ok {
+			return d.repairGroups(c, groups)
+		}
+	}
+	_, err := io.Copy(io.Discard, d)
+	return errors.Join(err, d.waitRewrite())
+}
+
+// rewrite writes reconstructed blocks to writers of lost shards, in background if RewriteAsync
+func (d *rsDecoder) rewrite(shards [][]byte) error {
 	if !d.rsCfg.RewriteAsync {
-		wg.Wait()
-		return err
+		return d.writeLost(shards)
+	}
+	if d.rewrites == nil {
+		d.rewrites = make(chan [][]byte, rewriteQueueSize)
+		d.rewriteDone = make(chan struct{})
+		go func() {
+			defer graceful.Recover()
+			defer close(d.rewriteDone)
+			// blocks are written in order
+			for blk := range d.rewrites {
+				if d.rewriteErr == nil {
+					d.rewriteErr = d.writeLost(blk)
+				}
+			}
+		}()
 	}
+	d.rewrites <- shards
+	return nil
+}
 
+func (d *rsDecoder) writeLost(shards [][]byte) error {
+	for i, w := range d.writers {
+		if w == nil {
+			continue
+		}
+		if _, err := w.Write(shards[i]); err != nil {
+			logs.Std().Errorf("rewrite lost shards fail: %s", err)
+			return err
+		}
+	}
 	return nil
 }
+
+// waitRewrite waits until all reconstructed blocks are written to writers of lost shards
+func (d *rsDecoder) waitRewrite() error {
+	if d.rewrites == nil {
+		return nil
+	}
+	close(d.rewrites)
+	<-d.rewriteDone
+	d.rewrites = nil
+	return d.rewriteErr
+}
+
+// readShards reads a block of every active shard, and replaces it by standby shards if any active one fails.
+// if hedging is enabled, it returns as soon as DataShards blocks are read,
+// and activates a standby shard if active ones are slower than the percentile of their servers' latency.
+// shards still pending are put back on standby, so that a server slow once is read again if others are slower later.
+func (d *rsDecoder) readShards() ([][]byte, error) {
+	shards := make([][]byte, d.rsCfg.AllShards())
+	results := make(chan *shardBlock, d.rsCfg.AllShards())
+	pending := map[int]bool{}
+	for i, st := range d.states {
+		if st == shardActive {
+			d.readBlock(i, results)
+			pending[i] = true
+		}
+	}
+	need := len(pending)
+	var hedgeC <-chan time.Time
+	if d.hedge != nil {
+		need = d.rsCfg.DataShards
+		timer := time.NewTimer(d.hedgeDelay(pending))
+		defer timer.Stop()
+		hedgeC = timer.C
+	}
+	var got int
+	for got < need {
+		if len(pending) == 0 {
+			return nil, errors.New("too few shards to reconstruct data")
+		}
+		select {
+		case r := <-results:
+			delete(pending, r.index)
+			if r.err != nil {
+				logs.Std().Debugf("read shard %d err: %s", r.index, r.err)
+				d.drop(r.index)
+				started := d.replace(results)
+				for _, idx := range started {
+					pending[idx] = true
+				}
+				if d.hedge == nil {
+					need += len(started) - 1
+				}
+				continue
+			}
+			shards[r.index] = r.data
+			got++
+		case <-hedgeC:
+			if idx, ok := d.activate(results); ok {
+				pending[idx] = true
+				hedgeC = time.After(d.hedgeDelay(pending))
+			}
+		}
+	}
+	// cancel the slow ones
+	for idx := range pending {
+		d.standBy(idx)
+	}
+	return shards, nil
+}
+
+// readBlock reads the next block of shard idx in background, opening it from current offset if it's idle
+func (d *rsDecoder) readBlock(idx int, results chan<- *shardBlock) {
+	reader, buf := d.readers[idx], d.buffers[idx]
+	offset := d.blocks * int64(d.rsCfg.BlockPerShard)
+	seek := d.states[idx] == shardIdle && offset > 0
+	d.states[idx] = shardActive
+	abandoned := d.abandoned[idx]
+	d.abandoned[idx] = nil
+	var done chan struct{}
+	if d.hedge != nil {
+		done = make(chan struct{})
+		d.reading[idx] = done
+	}
+	go func() {
+		defer graceful.Recover()
+		if done != nil {
+			defer close(done)
+		}
+		var err error
+		var n int
+		if abandoned != nil {
+			// the abandoned read shares the buffer and the position of reader, which may have reopened it
+			<-abandoned
+			err = reader.(shardResetter).Reset()
+		}
+		start := time.Now()
+		if err == nil && seek {
+			if sk, ok := reader.(io.Seeker); ok {
+				_, err = sk.Seek(offset, io.SeekStart)
+			} else {
+				err = errors.New("shard reader is not seekable")
+			}
+		}
+		if err == nil {
+			n, err = io.ReadFull(reader, buf)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+		}
+		if d.locates != nil {
+			// failures are recorded as the slowest so that the server is avoided next time
+			shardLatency.Observe(d.locates[idx], func() time.Duration {
+				if err != nil {
+					return d.hedge.MaxDelay
+				}
+				return time.Since(start)
+			}())
+		}
+		results <- &shardBlock{index: idx, data: buf[:n], err: err}
+	}()
+}
+
+// activate starts reading the next standby shard, returns false if there is none
+func (d *rsDecoder) activate(results chan<- *shardBlock) (int, bool) {
+	if len(d.standby) == 0 {
+		return 0, false
+	}
+	idx := d.standby[0]
+	d.standby = d.standby[1:]
+	d.readBlock(idx, results)
+	return idx, true
+}
+
+// replace starts reading shards instead of a failed one, returns indexes of them
+func (d *rsDecoder) replace(results chan<- *shardBlock) []int {
+	if d.plan == nil {
+		if idx, ok := d.activate(results); ok {
+			return []int{idx}
+		}
+		return nil
+	}
+	var started []int
+	for _, idx := range d.plan() {
+		if d.states[idx] == shardIdle {
+			d.readBlock(idx, results)
+			started = append(started, idx)
+		}
+	}
+	return started
+}
+
+// standBy puts slow shard idx back on standby. reading in progress is canceled, and the shard is reopened
+// from the offset of block when it's activated again. it's dropped if its reader can't be reopened.
+func (d *rsDecoder) standBy(idx int) {
+	rs, ok := d.readers[idx].(shardResetter)
+	if !ok {
+		d.drop(idx)
+		return
+	}
+	d.states[idx] = shardIdle
+	d.standby = append(d.standby, idx)
+	reading, abandoned := d.reading[idx], make(chan struct{})
+	d.abandoned[idx] = abandoned
+	go func() {
+		defer graceful.Recover()
+		defer close(abandoned)
+		_ = rs.Reset()
+		if reading != nil {
+			<-reading
+		}
+	}()
+}
+
+// drop stops reading shard idx. reading in progress is canceled and its buffer is abandoned.
+func (d *rsDecoder) drop(idx int) {
+	d.states[idx] = shardDropped
+	d.buffers[idx] = nil
+	if c, ok := d.readers[idx].(io.Closer); ok {
+		go func() {
+			defer graceful.Recover()
+			_ = c.Close()
+		}()
+	}
+}
+
+// hedgeDelay returns the time to wait for pending shards before activating a standby one
+func (d *rsDecoder) hedgeDelay(pending map[int]bool) time.Duration {
+	delay := d.hedge.MinDelay
+	for idx := range pending {
+		if p, ok := shardLatency.Percentile(d.locates[idx], d.hedge.Percentile); ok && p > delay {
+			delay = p
+		}
+	}
+	if delay > d.hedge.MaxDelay {
+		delay = d.hedge.MaxDelay
+	}
+	return delay
+}
+
+// closeReaders closes readers of all shards
+func (d *rsDecoder) closeReaders() {
+	for i, r := range d.readers {
+		if c, ok := r.(io.Closer); ok && d.states[i] != shardDropped {
+			_ = c.Close()
+		}
+	}
+}
@@ -9,15 +9,27 @@ import (
 	"sync/atomic"
 )
 
+// erasureCodec splits blocks into shards and reconstructs lost ones, reedsolomon.Encoder is one of them
+type erasureCodec interface {
+	Split(data []byte) ([][]byte, error)
+	Encode(shards [][]byte) error
+	Reconstruct(shards [][]byte) error
+	ReconstructData(shards [][]byte) error
+}
+
 type rsEncoder struct {
 	writers  []io.WriteCloser
-	enc      reedsolomon.Encoder
+	enc      erasureCodec
 	cache    []byte
 	rsConfig config.RsConfig
 }
 
 func NewEncoder(wrs []io.WriteCloser, rsCfg *config.RsConfig) *rsEncoder {
 	enc, _ := reedsolomon.New(rsCfg.DataShards, rsCfg.ParityShards, reedsolomon.WithAutoGoroutines(rsCfg.BlockPerShard))
+	return newEncoder(enc, wrs, rsCfg)
+}
+
+func newEncoder(enc erasureCodec, wrs []io.WriteCloser, rsCfg *config.RsConfig) *rsEncoder {
 	return &rsEncoder{
 		writers:  wrs,
 		enc:      enc,
@@ -3,6 +3,7 @@ package service
 import (
 	"apiserver/config"
 	"apiserver/internal/usecase/logic"
+	"apiserver/internal/usecase/pool"
 	"bytes"
 	"common/graceful"
 	"common/logs"
@@ -11,11 +12,13 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
 type RSGetStream struct {
 	*rsDecoder
 	*StreamOption
+	late <-chan []int // late sends shards found lost after reading started, nil without hedging
 }
 
 type provideStream struct {
@@ -24,7 +27,7 @@ type provideStream struct {
 	err    error
 }
 
-func provideGetStream(hash string, locates []string, shardSize int, compress bool) <-chan *provideStream {
+func provideGetStream(hash string, locates []string, shardSize int, codec string) <-chan *provideStream {
 	respChan := make(chan *provideStream, 1)
 	go func() {
 		defer graceful.Recover()
@@ -36,7 +39,7 @@ func provideGetStream(hash string, locates []string, shardSize int, compress boo
 				defer graceful.Recover()
 				defer wg.Done()
 				if len(ip) > 0 {
-					reader, e := NewGetStream(ip, fmt.Sprintf("%s.%d", hash, idx), int64(shardSize), compress)
+					reader, e := NewGetStream(ip, fmt.Sprintf("%s.%d", hash, idx), int64(shardSize), codec)
 					respChan <- &provideStream{reader, idx, e}
 				} else {
 					respChan <- &provideStream{nil, idx, fmt.Errorf("shard %s.%d lost", hash, idx)}
@@ -49,26 +52,125 @@ func provideGetStream(hash string, locates []string, shardSize int, compress boo
 }
 
 func NewRSGetStream(option *StreamOption, rsCfg *config.RsConfig) (*RSGetStream, error) {
-	readers := make([]io.Reader, rsCfg.AllShards())
-	writers := make([]io.Writer, rsCfg.AllShards())
-	perSize := rsCfg.ShardSize(option.Size)
+	hedge := &pool.Config.Object.Hedge
+	if !hedge.Enabled {
+		hedge = nil
+	}
+	readers, writers, late, err := openShards(option, rsCfg.AllShards(), rsCfg.ShardSize(option.Size), rsCfg.DataShards, hedge)
+	if err != nil {
+		return nil, err
+	}
+	locates := append([]string(nil), option.Locates...)
+	dec := NewDecoder(readers, writers, option.Size, rsCfg)
+	if hedge != nil {
+		dec.enableHedge(locates, hedge)
+	}
+	return &RSGetStream{dec, option, late}, nil
+}
+
+// openShards opens readers of shards, and writers to new servers for lost ones which are set to option.Locates.
+// with hedging, shards responding later than the fastest 'need' ones are left as slow ones instead of waiting for them,
+// indexes of lost ones among them are sent to the returned channel after all servers respond, which is nil without hedging.
+func openShards(option *StreamOption, all, perSize, need int, hedge *config.HedgeConfig) ([]io.Reader, []io.Writer, <-chan []int, error) {
+	readers := make([]io.Reader, all)
+	writers := make([]io.Writer, all)
 	lb := logic.NewDiscovery().NewDataServSelector()
-	for r := range provideGetStream(option.Hash, option.Locates, perSize, option.Compress) {
-		if r.err != nil {
-			logs.Std().Error(r.err)
-			ip := lb.Select()
-			writers[r.index], r.err = NewPutStream(ip, fmt.Sprintf("%s.%d", option.Hash, r.index), int64(perSize), option.Compress)
+	results := provideGetStream(option.Hash, option.Locates, perSize, option.ShardCodec())
+	var ready int
+	var wait <-chan time.Time
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				return readers, writers, nil, nil
+			}
 			if r.err != nil {
-				return nil, r.err
+				logs.Std().Error(r.err)
+				ip := lb.Select()
+				writers[r.index], r.err = NewPutStream(ip, fmt.Sprintf("%s.%d", option.Hash, r.index), int64(perSize), option.ShardCodec())
+				if r.err != nil {
+					drainGetStream(results, nil)
+					closeAll(readers)
+					return nil, nil, nil, r.err
+				}
+				// metadata update required
+				option.Locates[r.index] = ip
+				continue
 			}
-			// metadata update required
-			option.Locates[r.index] = ip
-		} else {
 			readers[r.index] = r.stream
+			if ready++; hedge != nil && ready == need {
+				wait = time.After(hedge.MinDelay)
+			}
+		case <-wait:
+			late := make(chan []int, 1)
+			drainGetStream(results, late)
+			return readers, writers, late, nil
+		}
+	}
+}
+
+// drainGetStream discards responses not waited for and closes their streams.
+// indexes of lost shards among them are sent to lost if it's not nil.
+func drainGetStream(results <-chan *provideStream, lost chan<- []int) {
+	go func() {
+		defer graceful.Recover()
+		var idx []int
+		for r := range results {
+			if r.err != nil {
+				idx = append(idx, r.index)
+				continue
+			}
+			closeAll([]io.Reader{r.stream})
+		}
+		if lost != nil {
+			lost <- idx
+		}
+	}()
+}
+
+// closeAll closes readers which are closers
+func closeAll(readers []io.Reader) {
+	for _, r := range readers {
+		if c, ok := r.(io.Closer); ok {
+			util.LogErr(c.Close())
 		}
 	}
-	dec := NewDecoder(readers, writers, option.Size, rsCfg)
-	return &RSGetStream{dec, option}, nil
+}
+
+// repairLate rewrites shards found lost after reading started by reading the object again in background,
+// since blocks read before can't be rewritten by this stream. it starts after shards rewritten by this stream are updated.
+func (g *RSGetStream) repairLate() {
+	if g.late == nil {
+		return
+	}
+	late, opt, cfg, enc := g.late, *g.StreamOption, g.rsCfg, g.enc
+	g.late = nil
+	opt.Locates = append([]string(nil), g.Locates...)
+	go func() {
+		defer graceful.Recover()
+		lost := <-late
+		if len(lost) == 0 {
+			return
+		}
+		logs.Std().Infof("repair shards %v of %s found lost late", lost, opt.Hash)
+		readers, writers, _, err := openShards(&opt, cfg.AllShards(), cfg.ShardSize(opt.Size), cfg.DataShards, nil)
+		if err != nil {
+			logs.Std().Errorf("repair shards of %s err: %s", opt.Hash, err)
+			return
+		}
+		dec := NewDecoder(readers, writers, opt.Size, &cfg)
+		if lrc, ok := enc.(*lrcCodec); ok {
+			// shards of local reconstruction codes are decoded by their own codec
+			if dec, err = NewLRCDecoder(readers, writers, opt.Size, &lrc.cfg); err != nil {
+				closeAll(readers)
+				logs.Std().Errorf("repair shards of %s err: %s", opt.Hash, err)
+				return
+			}
+		}
+		stream := &RSGetStream{rsDecoder: dec, StreamOption: &opt}
+		err = stream.Repair()
+		util.LogErrWithPre(fmt.Sprintf("repair shards of %s", opt.Hash), errors.Join(err, stream.Close()))
+	}()
 }
 
 func NewRSTempStream(option *StreamOption, rsCfg *config.RsConfig) *RSGetStream {
@@ -80,7 +182,7 @@ func NewRSTempStream(option *StreamOption, rsCfg *config.RsConfig) *RSGetStream
 		readers[idx] = NewTempStream(loc, fmt.Sprintf("%s.%d", option.Hash, idx), perSize)
 	}
 	dec := NewDecoder(readers, writers, option.Size, rsCfg)
-	return &RSGetStream{dec, option}
+	return &RSGetStream{rsDecoder: dec, StreamOption: option}
 }
 
 func (g *RSGetStream) Seek(offset int64, whence int) (int64, error) {
@@ -111,6 +213,11 @@ func (g *RSGetStream) Seek(offset int64, whence int) (int64, error) {
 }
 
 func (g *RSGetStream) Close() error {
+	defer g.repairLate()
+	g.closeReaders()
+	if err := g.waitRewrite(); err != nil {
+		return err
+	}
 	wg := util.NewDoneGroup()
 	defer wg.Close()
 	var needUpdate bool
@@ -12,18 +12,26 @@ type RSPutStream struct {
 }
 
 func NewRSPutStream(opt *StreamOption, rsCfg *config.RsConfig) (*RSPutStream, error) {
-	if len(opt.Locates) < rsCfg.AllShards() {
-		return nil, fmt.Errorf("dataServers ip number mismatch %v", rsCfg.AllShards())
+	writers, err := openShardWriters(opt, rsCfg.AllShards(), rsCfg.ShardSize(opt.Size))
+	if err != nil {
+		return nil, err
 	}
-	perShard := rsCfg.ShardSize(opt.Size)
-	writers := make([]io.WriteCloser, rsCfg.AllShards())
+	return &RSPutStream{NewEncoder(writers, rsCfg)}, nil
+}
+
+// openShardWriters opens put streams of all shards to opt.Locates
+func openShardWriters(opt *StreamOption, all, perShard int) ([]io.WriteCloser, error) {
+	if len(opt.Locates) < all {
+		return nil, fmt.Errorf("dataServers ip number mismatch %v", all)
+	}
+	writers := make([]io.WriteCloser, all)
 	wg := util.NewDoneGroup()
 	defer wg.Close()
 	for i := range writers {
 		wg.Todo()
 		go func(idx int) {
 			defer wg.Done()
-			stream, err := NewPutStream(opt.Locates[idx], fmt.Sprintf("%s.%d", opt.Hash, idx), int64(perShard), opt.Compress)
+			stream, err := NewPutStream(opt.Locates[idx], fmt.Sprintf("%s.%d", opt.Hash, idx), int64(perShard), opt.ShardCodec())
 			if err != nil {
 				wg.Error(err)
 				return
@@ -34,13 +42,13 @@ func NewRSPutStream(opt *StreamOption, rsCfg *config.RsConfig) (*RSPutStream, er
 	if e := wg.WaitUntilError(); e != nil {
 		return nil, e
 	}
-	return &RSPutStream{NewEncoder(writers, rsCfg)}, nil
+	return writers, nil
 }
 
-func newExistedRSPutStream(ips, ids []string, hash string, compress bool, rsCfg *config.RsConfig) *RSPutStream {
+func newExistedRSPutStream(ips, ids []string, hash string, codec string, rsCfg *config.RsConfig) *RSPutStream {
 	writers := make([]io.WriteCloser, len(ids))
 	for i := range writers {
-		writers[i] = newExistedPutStream(ips[i], fmt.Sprintf("%s.%d", hash, i), ids[i], compress)
+		writers[i] = newExistedPutStream(ips[i], fmt.Sprintf("%s.%d", hash, i), ids[i], codec)
 	}
 	return &RSPutStream{NewEncoder(writers, rsCfg)}
 }
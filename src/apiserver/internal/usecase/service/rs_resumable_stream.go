@@ -10,6 +10,7 @@ import (
 
 type resumeToken struct {
 	Compress bool             `json:"compress"`
+	Codec    string           `json:"codec"`
 	Size     int64            `json:"size"`
 	Name     string           `json:"name"`
 	Hash     string           `json:"hash"`
@@ -33,7 +34,7 @@ func NewRSResumablePutStreamFromToken(token string) (*RSResumablePutStream, erro
 	}
 	var tk resumeToken
 	if ok := util.GobDecode(bt, &tk); ok {
-		return &RSResumablePutStream{newExistedRSPutStream(tk.Servers, tk.Ids, tk.Hash, tk.Compress, tk.Config), &tk}, nil
+		return &RSResumablePutStream{newExistedRSPutStream(tk.Servers, tk.Ids, tk.Hash, shardCodec(tk.Compress, tk.Codec), tk.Config), &tk}, nil
 	}
 	return nil, fmt.Errorf("invalid token")
 }
@@ -55,6 +56,7 @@ func NewRSResumablePutStream(opt *StreamOption, rsCfg *config.RsConfig) (*RSResu
 		Servers:  opt.Locates,
 		Size:     opt.Size,
 		Compress: opt.Compress,
+		Codec:    opt.Codec,
 		Ids:      ids,
 		Config:   rsCfg,
 	}
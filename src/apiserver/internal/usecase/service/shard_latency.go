@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow number of recent latencies kept for each server
+const latencyWindow = 64
+
+// shardLatency latencies of reading shard blocks from object servers, used to choose and hedge shards
+var shardLatency = &latencyTracker{servers: map[string]*latencySamples{}}
+
+type latencySamples struct {
+	values [latencyWindow]time.Duration
+	count  int
+	next   int
+}
+
+type latencyTracker struct {
+	mu      sync.RWMutex
+	servers map[string]*latencySamples
+}
+
+// Observe records a latency of server
+func (t *latencyTracker) Observe(ip string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.servers[ip]
+	if !ok {
+		s = &latencySamples{}
+		t.servers[ip] = s
+	}
+	s.values[s.next] = d
+	s.next = (s.next + 1) % latencyWindow
+	if s.count < latencyWindow {
+		s.count++
+	}
+}
+
+// Percentile returns the p-th (0-1) percentile of recent latencies of server, false if none recorded
+func (t *latencyTracker) Percentile(ip string, p float64) (time.Duration, bool) {
+	t.mu.RLock()
+	s, ok := t.servers[ip]
+	if !ok || s.count == 0 {
+		t.mu.RUnlock()
+		return 0, false
+	}
+	values := make([]time.Duration, s.count)
+	copy(values, s.values[:s.count])
+	t.mu.RUnlock()
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	idx := int(p * float64(len(values)-1))
+	return values[idx], true
+}
+
+// Rank returns indexes of servers sorted by median latency. servers without latencies are considered fastest
+// so that they are learned, and lower indexes are preferred on ties because data shards need no decoding.
+func (t *latencyTracker) Rank(ips []string) []int {
+	medians := make([]time.Duration, len(ips))
+	idx := make([]int, len(ips))
+	for i, ip := range ips {
+		idx[i] = i
+		medians[i], _ = t.Percentile(ip, 0.5)
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return medians[idx[a]] < medians[idx[b]] })
+	return idx
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"apiserver/internal/usecase/grpcapi"
+	"bytes"
+	"common/datasize"
+	"common/graceful"
+	"common/proto"
+	"common/proto/pb"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// shardChunkSize max size of data in one message
+	shardChunkSize = datasize.MB
+	// shardQueueSize messages waiting to be sent for a shard before Write blocks
+	shardQueueSize = 16
+	// legacyServerTTL how long a server is known as legacy, streaming is tried again after that in case it's upgraded
+	legacyServerTTL = 5 * time.Minute
+)
+
+var (
+	// errLegacyServer object server doesn't support streaming transport, http is required
+	errLegacyServer = errors.New("streaming shard transport is not supported")
+	// legacyServers addresses of servers returning errLegacyServer to the time found
+	legacyServers sync.Map
+)
+
+func isLegacyServer(ip string) bool {
+	v, ok := legacyServers.Load(ip)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) < legacyServerTTL {
+		return true
+	}
+	legacyServers.CompareAndDelete(ip, v)
+	return false
+}
+
+func resolveStreamErr(ip string, err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		legacyServers.Store(ip, time.Now())
+		return errLegacyServer
+	}
+	return proto.ResolveErr(err)
+}
+
+// openShardReader IO: open a stream reading a shard
+func openShardReader(ip string, req *pb.ShardReadReq) (io.ReadCloser, error) {
+	reader, err := grpcapi.ReadShard(ip, req)
+	if err != nil {
+		return nil, resolveStreamErr(ip, err)
+	}
+	return reader, nil
+}
+
+// shardStream writes a shard to a temp object through a grpc stream.
+// data is queued and sent by a background goroutine, so writes of different shards are pipelined.
+type shardStream struct {
+	ip     string
+	client pb.ObjectStream_WriteShardClient
+	cancel context.CancelFunc
+	queue  chan *pb.ShardWrite
+	failed chan struct{} // closed when sending fails
+	done   chan struct{} // closed when all queued messages are handled
+	err    error
+}
+
+// openShardStream IO: open a stream to a new temp object or an existing one if head.TmpId is provided.
+// returns id of the temp object.
+func openShardStream(ip string, head *pb.ShardWrite) (*shardStream, string, error) {
+	client, cancel, err := grpcapi.WriteShard(ip)
+	if err != nil {
+		return nil, "", resolveStreamErr(ip, err)
+	}
+	resp, err := func() (*pb.ShardWriteResp, error) {
+		if err := client.Send(head); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return client.Recv()
+	}()
+	if err != nil {
+		cancel()
+		return nil, "", resolveStreamErr(ip, err)
+	}
+	s := &shardStream{
+		ip:     ip,
+		client: client,
+		cancel: cancel,
+		queue:  make(chan *pb.ShardWrite, shardQueueSize),
+		failed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.sendLoop()
+	return s, resp.TmpId, nil
+}
+
+func (s *shardStream) sendLoop() {
+	defer graceful.Recover()
+	defer close(s.done)
+	for req := range s.queue {
+		if s.err != nil {
+			continue
+		}
+		if err := s.client.Send(req); err != nil {
+			// the real error is returned from Recv if server closes the stream
+			if err == io.EOF {
+				_, err = s.client.Recv()
+			}
+			s.err = resolveStreamErr(s.ip, err)
+			close(s.failed)
+		}
+	}
+}
+
+// Write copies b to messages in queue
+func (s *shardStream) Write(b []byte) error {
+	for len(b) > 0 {
+		n := len(b)
+		if n > shardChunkSize.Int() {
+			n = shardChunkSize.Int()
+		}
+		if err := s.send(&pb.ShardWrite{Data: bytes.Clone(b[:n])}); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func (s *shardStream) send(req *pb.ShardWrite) error {
+	select {
+	case s.queue <- req:
+		return nil
+	case <-s.failed:
+		return s.err
+	}
+}
+
+// finish IO: send the last message if it is not nil and wait until server has handled all of data
+func (s *shardStream) finish(last *pb.ShardWrite) error {
+	defer s.cancel()
+	var err error
+	if last != nil {
+		err = s.send(last)
+	}
+	close(s.queue)
+	<-s.done
+	if err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+	if err = s.client.CloseSend(); err != nil {
+		return resolveStreamErr(s.ip, err)
+	}
+	if _, err = s.client.Recv(); err != nil {
+		return resolveStreamErr(s.ip, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"apiserver/config"
+	"common/proto/msg"
 )
 
 type StreamOption struct {
@@ -11,9 +12,25 @@ type StreamOption struct {
 	Name     string
 	Size     int64
 	Compress bool
+	Codec    string
 	Updater  LocatesUpdater
 }
 
+// ShardCodec returns the codec compressing shards, empty if shards are not compressed
+func (opt *StreamOption) ShardCodec() string {
+	return shardCodec(opt.Compress, opt.Codec)
+}
+
+func shardCodec(compress bool, codec string) string {
+	if !compress {
+		return ""
+	}
+	if codec == "" {
+		return msg.CodecS2
+	}
+	return codec
+}
+
 func RsStreamProvider(opt *StreamOption, cfg *config.RsConfig) StreamProvider {
 	return &streamProvider{
 		getStream: func(s []string) (ReadSeekCloser, error) {
@@ -27,6 +44,19 @@ func RsStreamProvider(opt *StreamOption, cfg *config.RsConfig) StreamProvider {
 	}
 }
 
+func LrcStreamProvider(opt *StreamOption, cfg *config.LrcConfig) StreamProvider {
+	return &streamProvider{
+		getStream: func(s []string) (ReadSeekCloser, error) {
+			opt.Locates = s
+			return NewLRCGetStream(opt, cfg)
+		},
+		puStream: func(s []string) (WriteCommitCloser, error) {
+			opt.Locates = s
+			return NewLRCPutStream(opt, cfg)
+		},
+	}
+}
+
 func CpStreamProvider(opt *StreamOption, cfg *config.ReplicationConfig) StreamProvider {
 	return &streamProvider{
 		getStream: func(s []string) (ReadSeekCloser, error) {
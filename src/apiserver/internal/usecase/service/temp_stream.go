@@ -2,6 +2,7 @@ package service
 
 import (
 	"apiserver/internal/usecase/webapi"
+	"common/proto/pb"
 	"errors"
 	"fmt"
 	"io"
@@ -31,6 +32,13 @@ func (ts *tempStream) CheckStat() error {
 }
 
 func (ts *tempStream) request() error {
+	if !isLegacyServer(ts.Locate) {
+		reader, err := openShardReader(ts.Locate, &pb.ShardReadReq{Name: ts.name, Size: ts.size, Temp: true})
+		if !errors.Is(err, errLegacyServer) {
+			ts.reader = reader
+			return err
+		}
+	}
 	resp, err := webapi.GetTmpObject(ts.Locate, ts.name, ts.size)
 	if err != nil {
 		return err
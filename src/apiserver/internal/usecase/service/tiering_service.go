@@ -0,0 +1,258 @@
+package service
+
+import (
+	"apiserver/config"
+	"apiserver/internal/entity"
+	. "apiserver/internal/usecase"
+	"apiserver/internal/usecase/logic"
+	"apiserver/internal/usecase/pool"
+	"common/collection/set"
+	"common/cst"
+	"common/datasize"
+	"common/graceful"
+	"common/logs"
+	"common/util"
+	"common/util/crypto"
+	xmath "common/util/math"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var tierLog = logs.New("tiering-service")
+
+// TieringService transcodes cold objects to the cold layout (Reed-Solomon with wider shards) and cold object servers,
+// objects of local reconstruction codes keep their layout and are only moved to cold servers.
+type TieringService struct {
+	objectService *ObjectService
+	metaService   IMetaService
+}
+
+func NewTieringService(o *ObjectService, m IMetaService) *TieringService {
+	return &TieringService{objectService: o, metaService: m}
+}
+
+// coldVersion returns the target version in cold layout without hash. returns nil if no need to transcode.
+func (t *TieringService) coldVersion(ver *entity.Version, conf *config.TieringConfig) *entity.Version {
+	if !conf.Enabled || time.Since(time.UnixMilli(xmath.MaxNumber(ver.Ts, ver.AccessTs))) < conf.ColdAfter {
+		return nil
+	}
+	if ver.StoreStrategy == entity.Inline || datasize.DataSize(ver.Size) < conf.MinSize {
+		return nil
+	}
+	layout := entity.Bucket{StoreStrategy: entity.ECReedSolomon, DataShards: conf.DataShards, ParityShards: conf.ParityShards, InlineLimit: -1}
+	if ver.StoreStrategy == entity.LocalReconstruction {
+		// local reconstruction codes are kept to repair lost shards by local groups
+		layout = entity.Bucket{StoreStrategy: entity.LocalReconstruction, DataShards: ver.DataShards, ParityShards: ver.ParityShards, LocalGroups: ver.LocalGroups, InlineLimit: -1}
+	}
+	if ver.StoreStrategy == layout.StoreStrategy && ver.DataShards == layout.DataShards &&
+		ver.ParityShards == layout.ParityShards && ver.LocalGroups == layout.LocalGroups {
+		// already in cold layout, only moving to cold servers is required
+		cold := logic.NewDiscovery().GetColdDataServers()
+		if len(cold) == 0 || isSubset(ver.Locate, set.OfString(cold)) {
+			return nil
+		}
+	}
+	target := &entity.Version{
+		Compress: ver.Compress,
+		Codec:    ver.Codec,
+		Sequence: ver.Sequence,
+		Size:     ver.Size,
+		Ts:       ver.Ts,
+		Digest:   ver.Digest,
+	}
+	layout.MakeVersion(target, &pool.Config.Object)
+	return target
+}
+
+// digest reads the whole object to compute the sha256 of content
+func (t *TieringService) digest(md *entity.Metadata, ver *entity.Version) (string, error) {
+	reader, err := t.objectService.getObject(md, ver)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	res := crypto.SHA256IO(reader)
+	if res == "" {
+		return "", errors.New("read object fail")
+	}
+	return res, nil
+}
+
+func isSubset(locates []string, servers set.Set) bool {
+	for _, loc := range locates {
+		if !servers.Contains(loc) {
+			return false
+		}
+	}
+	return true
+}
+
+// Transcode re-encode the only version of md to cold layout by the tiering policy of bucket,
+// swap it in metadata and then remove the old shards.
+func (t *TieringService) Transcode(md *entity.Metadata, bucket *entity.Bucket) (err error) {
+	ver := md.LastVersion()
+	if ver == nil {
+		return nil
+	}
+	conf := bucket.TieringConf(&pool.Config.Tiering)
+	target := t.coldVersion(ver, &conf)
+	if target == nil {
+		return nil
+	}
+	// hash of shards is generated from digest of content, which is unknown for versions saved before it was recorded
+	if target.Digest == "" {
+		if target.Digest, err = t.digest(md, ver); err != nil {
+			return fmt.Errorf("compute digest err: %w", err)
+		}
+	}
+	target.Hash = t.objectService.UniqueHash(target.Digest, target.StoreStrategy, target.DataShards, target.ParityShards, target.LocalGroups, target.Compress, target.Codec)
+	var ok bool
+	if target.Locate, ok = t.objectService.LocateObject(target.Hash); !ok {
+		var locates []string
+		if locates, err = t.writeColdObject(md, ver, target); err != nil {
+			return fmt.Errorf("write cold object err: %w", err)
+		}
+		if target.Locate, err = t.objectService.ReferObject(target.Hash, locates); err != nil {
+			return fmt.Errorf("refer object err: %w", err)
+		}
+		// the same object has been stored concurrently, remove the duplicated shards
+		go func() {
+			defer graceful.Recover()
+			removeShards(target.Hash, locates, target.Locate)
+		}()
+	}
+	// release reference of new layout if fails to swap
+	defer func() {
+		if err != nil {
+			util.LogErrWithPre("dereference object err", t.objectService.DereferObject(target.Hash, target.Locate))
+		}
+	}()
+	if err = t.metaService.SwapVersion(md.Name, md.Bucket, target); err != nil {
+		return fmt.Errorf("swap version err: %w", err)
+	}
+	util.LogErrWithPre("dereference object err", t.objectService.DereferObject(ver.Hash, ver.Locate))
+	tierLog.Debugf("transcode %s/%s version %d from %s to %s", md.Bucket, md.Name, ver.Sequence, ver.Hash, target.Hash)
+	return nil
+}
+
+func (t *TieringService) writeColdObject(md *entity.Metadata, ver, target *entity.Version) ([]string, error) {
+	reader, err := t.objectService.getObject(md, ver)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	ds := logic.NewDiscovery().SelectColdDataServer(pool.Balancer, target.AllShards(), target.Tolerance())
+	if len(ds) == 0 {
+		return nil, ErrServiceUnavailable
+	}
+	stream, err := NewStreamProvider(&StreamOption{
+		Bucket:   md.Bucket,
+		Hash:     target.Hash,
+		Name:     md.Name,
+		Size:     target.Size,
+		Compress: target.Compress,
+		Codec:    target.Codec,
+	}, target).PutStream(ds)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	if _, err = io.CopyBuffer(stream, reader, make([]byte, 8*cst.OS.PageSize)); err != nil {
+		util.LogErr(stream.Commit(false))
+		return nil, err
+	}
+	if err = stream.Commit(true); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// RunOnce scans cold objects on all metadata servers and transcodes them
+func (t *TieringService) RunOnce() {
+	conf := &pool.Config.Tiering
+	before := time.Now().Add(-conf.ColdAfter).UnixMilli()
+	var success, fails int
+	// buckets are read once in a scanning
+	buckets := make(map[string]*entity.Bucket)
+	for masterId := range pool.Discovery.GetServiceMappingWith(pool.Config.Discovery.MetaServName, true) {
+		var cursor string
+		for {
+			lst, next, err := t.metaService.ListColdVersions(masterId, before, cursor, conf.BatchSize)
+			if err != nil {
+				tierLog.Errorf("list cold versions from %s err: %s", masterId, err)
+				break
+			}
+			for _, md := range lst {
+				bucket, ok := buckets[md.Bucket]
+				if !ok {
+					if bucket, err = t.objectService.bucketRepo.Get(md.Bucket); err != nil {
+						tierLog.Errorf("get bucket %s err: %s", md.Bucket, err)
+						fails++
+						continue
+					}
+					buckets[md.Bucket] = bucket
+				}
+				if err = t.Transcode(md, bucket); err != nil {
+					tierLog.Errorf("transcode %s/%s err: %s", md.Bucket, md.Name, err)
+					fails++
+					continue
+				}
+				success++
+			}
+			if len(lst) < conf.BatchSize {
+				break
+			}
+			cursor = next
+		}
+	}
+	tierLog.Infof("tiering finished: %d checked, %d failed", success+fails, fails)
+}
+
+// StartAutoTiering scans cold objects periodically. only one api server will do scanning at the same time.
+func (t *TieringService) StartAutoTiering() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !pool.Config.Tiering.Enabled {
+		return cancel
+	}
+	tk := time.NewTicker(pool.Config.Tiering.ScanInterval)
+	go func() {
+		defer graceful.Recover()
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				tierLog.Info("stop auto tiering")
+				return
+			case <-tk.C:
+				if err := t.runWithLock(ctx); err != nil {
+					tierLog.Warnf("auto tiering err: %s", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+func (t *TieringService) runWithLock(ctx context.Context) error {
+	sess, err := concurrency.NewSession(pool.Etcd, concurrency.WithTTL(15))
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	mux := concurrency.NewMutex(sess, cst.EtcdPrefix.FmtLock(pool.Config.Registry.Group, "tiering"))
+	if err = mux.TryLock(ctx); err != nil {
+		if errors.Is(err, concurrency.ErrLocked) {
+			tierLog.Debug("tiering is running on other server, skip")
+			return nil
+		}
+		return err
+	}
+	defer func() { util.LogErr(mux.Unlock(context.Background())) }()
+	t.RunOnce()
+	return nil
+}
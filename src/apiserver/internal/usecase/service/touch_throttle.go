@@ -0,0 +1,76 @@
+package service
+
+import (
+	. "apiserver/internal/usecase"
+	"apiserver/internal/usecase/pool"
+	"common/graceful"
+	"sync"
+	"time"
+)
+
+// maxPendingTouch limits versions waiting to be touched, later ones are dropped until next flushing
+const maxPendingTouch = 10000
+
+type touchKey struct {
+	name, bucket string
+	version      int32
+}
+
+// touchThrottle collects versions read by GetObject and updates their accessing time in background.
+// a version is touched at most once in Tiering.AccessInterval by this server, no matter how many times it's read.
+type touchThrottle struct {
+	mux       sync.Mutex
+	meta      IMetaService
+	pending   map[touchKey]struct{}
+	touched   map[touchKey]time.Time
+	scheduled bool
+}
+
+func newTouchThrottle(meta IMetaService) *touchThrottle {
+	return &touchThrottle{meta: meta, pending: map[touchKey]struct{}{}, touched: map[touchKey]time.Time{}}
+}
+
+// Add marks version to be touched, a flushing is scheduled after Tiering.TouchDelay if not yet.
+func (t *touchThrottle) Add(name, bucket string, version int32) {
+	key := touchKey{name, bucket, version}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if last, ok := t.touched[key]; ok && time.Since(last) < pool.Config.Tiering.AccessInterval {
+		return
+	}
+	if len(t.pending) >= maxPendingTouch {
+		return
+	}
+	t.pending[key] = struct{}{}
+	if !t.scheduled {
+		t.scheduled = true
+		time.AfterFunc(pool.Config.Tiering.TouchDelay, t.flush)
+	}
+}
+
+func (t *touchThrottle) flush() {
+	defer graceful.Recover()
+	t.mux.Lock()
+	keys := t.pending
+	t.pending = map[touchKey]struct{}{}
+	t.scheduled = false
+	now := time.Now()
+	for k, last := range t.touched {
+		if now.Sub(last) >= pool.Config.Tiering.AccessInterval {
+			delete(t.touched, k)
+		}
+	}
+	for k := range keys {
+		t.touched[k] = now
+	}
+	t.mux.Unlock()
+	var fails int
+	for k := range keys {
+		if err := t.meta.TouchVersion(k.name, k.bucket, k.version); err != nil {
+			fails++
+		}
+	}
+	if fails > 0 {
+		tierLog.Warnf("touch %d versions, %d failed", len(keys), fails)
+	}
+}
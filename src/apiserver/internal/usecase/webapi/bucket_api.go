@@ -79,7 +79,7 @@ func DeleteBucket(ip, name string) error {
 
 func ListBucket(ip, name, prefix string, size int) error {
 	defer perform(false)()
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/bucket/%s?page_size=%d&prefix=%s", ip, name, prefix, size), nil)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/bucket/%s?page_size=%d&prefix=%s", ip, name, size, prefix), nil)
 	if err != nil {
 		return err
 	}
@@ -70,10 +70,10 @@ func PatchTmpObject(ip, id string, body io.Reader) error {
 	return nil
 }
 
-func PutTmpObject(ip, id string, compress bool) error {
+func PutTmpObject(ip, id string, codec string) error {
 	defer perform(true)()
 	form := make(url.Values)
-	form.Set("compress", fmt.Sprintf("%t", compress))
+	setCodec(form, codec)
 	req, err := http.NewRequest(http.MethodPut, fmt.Sprint(tempRest(ip, id), "?", form.Encode()), nil)
 	if err != nil {
 		return err
@@ -139,10 +139,10 @@ func GetTmpObject(ip, name string, size int64) (*http.Response, error) {
 	return httpClient.Do(req)
 }
 
-func GetObject(ip, name string, offset int, size int64, compress bool) (*http.Response, error) {
+func GetObject(ip, name string, offset int, size int64, codec string) (*http.Response, error) {
 	defer perform(false)()
 	form := url.Values{}
-	form.Set("compress", fmt.Sprintf("%t", compress))
+	setCodec(form, codec)
 	req, err := request.UrlValuesEncode(objectRest(ip, name), &form)
 	if err != nil {
 		return nil, err
@@ -172,10 +172,10 @@ func HeadObject(ip, id string) error {
 	return fmt.Errorf("requset %s: %s", resp.Request.URL, resp.Status)
 }
 
-func PutObject(ip, id string, compress bool, body io.Reader) error {
+func PutObject(ip, id string, codec string, body io.Reader) error {
 	defer perform(true)()
 	form := url.Values{}
-	form.Set("compress", fmt.Sprint(compress))
+	setCodec(form, codec)
 	req, err := http.NewRequest(http.MethodPut, fmt.Sprint(objectRest(ip, id), "?", form.Encode()), body)
 	if err != nil {
 		return err
@@ -192,6 +192,23 @@ func PutObject(ip, id string, compress bool, body io.Reader) error {
 	return nil
 }
 
+func DeleteObject(ip, id string) error {
+	defer perform(true)()
+	req, err := http.NewRequest(http.MethodDelete, objectRest(ip, id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return response.NewError(resp.StatusCode, response.MessageFromJSONBody(resp.Body))
+	}
+	return nil
+}
+
 func PingObject(ip string) error {
 	defer perform(false)()
 	resp, err := httpClient.Get(fmt.Sprint("http://", ip, "/ping"))
@@ -220,6 +237,14 @@ func StatObject(ip string) (hd http.Header, err error) {
 	return
 }
 
+// setCodec sets query of compression. codec is empty if object is not compressed
+func setCodec(form url.Values, codec string) {
+	form.Set("compress", fmt.Sprint(codec != ""))
+	if codec != "" {
+		form.Set("codec", codec)
+	}
+}
+
 func objectRest(ip, id string) string {
 	return fmt.Sprintf("http://%s/objects/%s", ip, id)
 }
@@ -0,0 +1,63 @@
+package webapi
+
+import (
+	"common/replication"
+	"common/response"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// replicaClient requests api servers of remote clusters which may not support h2c
+var replicaClient = &http.Client{Timeout: 30 * time.Minute}
+
+func replicaRest(rule *replication.Rule, name string) string {
+	return fmt.Sprintf("%s/v1/objects/%s", rule.Target, url.PathEscape(name))
+}
+
+func doReplica(rule *replication.Rule, req *http.Request, expect int) error {
+	req.Header.Set("Bucket", rule.RemoteBucket())
+	if rule.Username != "" {
+		req.SetBasicAuth(rule.Username, rule.Password)
+	}
+	resp, err := replicaClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expect {
+		return response.NewError(resp.StatusCode, response.MessageFromJSONBody(resp.Body))
+	}
+	return nil
+}
+
+// PutReplica uploads object to the remote cluster of rule as a replica written at ts
+func PutReplica(rule *replication.Rule, name, digest string, ts int64, body io.Reader, size int64) error {
+	defer perform(true)()
+	req, err := http.NewRequest(http.MethodPut, replicaRest(rule, name), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Digest", digest)
+	req.Header.Set("Replica-Ts", fmt.Sprint(ts))
+	return doReplica(rule, req, http.StatusCreated)
+}
+
+// DeleteReplica removes versions of object in the remote cluster of rule written before 'before' or exactly at 'ts'
+func DeleteReplica(rule *replication.Rule, name string, before, ts int64) error {
+	defer perform(true)()
+	qry := url.Values{}
+	if ts > 0 {
+		qry.Set("ts", fmt.Sprint(ts))
+	} else {
+		qry.Set("before", fmt.Sprint(before))
+	}
+	req, err := http.NewRequest(http.MethodDelete, replicaRest(rule, name)+"?"+qry.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return doReplica(rule, req, http.StatusOK)
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"apiserver/config"
+	controller "apiserver/internal/controller/http"
+	"apiserver/internal/entity"
+	"apiserver/internal/usecase"
+	"apiserver/internal/usecase/pool"
+	"apiserver/internal/usecase/repo"
+	"apiserver/internal/usecase/service"
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// bulkMetaService removes metadata of objects, the ones named "fail" fail
+type bulkMetaService struct {
+	usecase.IMetaService
+	removed []*entity.Version
+}
+
+func (b *bulkMetaService) RemoveMetadataBatch(_ string, names []string) ([]*entity.Version, []error) {
+	errs := make([]error, len(names))
+	for i, name := range names {
+		if name == "fail" {
+			errs[i] = errors.New("fail")
+		}
+	}
+	return b.removed, errs
+}
+
+// countingRefRepo records dereferenced hashes, objects are still referred by others
+type countingRefRepo struct {
+	repo.IHashRefRepo
+	derefered []string
+}
+
+func (c *countingRefRepo) Derefer(hash string) (int64, []string, error) {
+	c.derefered = append(c.derefered, hash)
+	return 1, nil, nil
+}
+
+func TestRemoveObjectsDerefers(t *testing.T) {
+	meta := &bulkMetaService{removed: []*entity.Version{
+		{Hash: "inline", StoreStrategy: entity.Inline},
+		{Hash: "rs", StoreStrategy: entity.ECReedSolomon},
+		{Hash: "rp", StoreStrategy: entity.MultiReplication},
+	}}
+	refs := &countingRefRepo{}
+	svc := service.NewObjectService(meta, nil, refs)
+	results := svc.RemoveObjects("b1", []string{"a", "fail"})
+	assert.Equal(t, []*entity.BulkResult{{Name: "a"}, {Name: "fail", Error: "fail"}}, results)
+	// data of inline versions are removed with them
+	assert.Equal(t, []string{"rs", "rp"}, refs.derefered)
+}
+
+// bulkObjectService records objects to store
+type bulkObjectService struct {
+	usecase.IObjectService
+	objs []*entity.BulkObject
+}
+
+func (b *bulkObjectService) StoreObjects(_ string, objs []*entity.BulkObject) ([]*entity.BulkResult, error) {
+	b.objs = objs
+	return []*entity.BulkResult{}, nil
+}
+
+func TestStoreStrategyOfRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pool.Config = &config.Config{}
+	pool.Config.Object.Bulk = config.BulkConfig{MaxCount: 10, MaxSize: 1 << 20}
+	objs := &bulkObjectService{}
+	oc := controller.NewObjectsController(objs, nil)
+	eng := gin.New()
+	var store entity.ObjectStrategy
+	eng.PUT("/objects/:name", oc.ValidatePut, func(c *gin.Context) { store = c.Value("PutReq").(*entity.PutReq).Store })
+	eng.POST("/objects", oc.Bulk)
+
+	put := func(query string, size int) entity.ObjectStrategy {
+		req := httptest.NewRequest(http.MethodPut, "/objects/a.txt"+query, bytes.NewReader(make([]byte, size)))
+		req.Header.Set("Bucket", "b1")
+		req.Header.Set("Digest", "digest")
+		eng.ServeHTTP(httptest.NewRecorder(), req)
+		return store
+	}
+	assert.Equal(t, entity.MultiReplication, put("", 100))
+	assert.Equal(t, entity.ECReedSolomon, put("", 100<<10))
+	assert.Equal(t, entity.LocalReconstruction, put("?ss=4", 100))
+
+	bulk := func(query string, size int) entity.ObjectStrategy {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		w, _ := form.CreateFormFile("file", "a.txt")
+		_, _ = w.Write(bytes.Repeat([]byte("a"), size))
+		_ = form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/objects"+query, strings.NewReader(body.String()))
+		req.Header.Set("Bucket", "b1")
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		eng.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		return objs.objs[0].Store
+	}
+	assert.Equal(t, entity.MultiReplication, bulk("", 100))
+	assert.Equal(t, entity.ECReedSolomon, bulk("", 100<<10))
+	assert.Equal(t, entity.LocalReconstruction, bulk("?ss=4", 100<<10))
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"apiserver/config"
+	controller "apiserver/internal/controller/http"
+	"apiserver/internal/entity"
+	"apiserver/internal/usecase/logic"
+	"apiserver/internal/usecase/pool"
+	"bytes"
+	"common/datasize"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func initCompressConfig() {
+	pool.Config = &config.Config{}
+	pool.Config.Object.AutoCompress = config.AutoCompressConfig{SampleSize: 4 * datasize.KB, MaxRatio: 0.9, SkipExts: []string{"jpg"}}
+}
+
+func TestParseCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng := gin.New()
+	var req *entity.PutReq
+	eng.PUT("/objects/:name", controller.NewObjectsController(nil, nil).ValidatePut, func(c *gin.Context) {
+		req = c.Value("PutReq").(*entity.PutReq)
+	})
+	put := func(mode string) int {
+		req = nil
+		r := httptest.NewRequest(http.MethodPut, "/objects/a.txt?compress="+mode, bytes.NewReader([]byte("a")))
+		r.Header.Set("Bucket", "b1")
+		r.Header.Set("Digest", "digest")
+		rec := httptest.NewRecorder()
+		eng.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	cases := []struct {
+		mode           string
+		compress, auto bool
+	}{{"", false, false}, {"true", true, false}, {"false", false, false}, {"auto", false, true}}
+	for _, c := range cases {
+		put(c.mode)
+		if assert.NotNil(t, req, c.mode) {
+			assert.Equal(t, c.compress, req.Compress, c.mode)
+			assert.Equal(t, c.auto, req.Auto, c.mode)
+		}
+	}
+	assert.Equal(t, http.StatusBadRequest, put("yes"))
+	assert.Nil(t, req)
+}
+
+func TestEstimateCompression(t *testing.T) {
+	initCompressConfig()
+	comp := logic.NewCompression()
+	random := make([]byte, 8<<10)
+	_, _ = rand.Read(random)
+	text := bytes.Repeat([]byte("goodfs compresses text well. "), 300)
+	assert.EqualValues(t, 1, comp.Estimate(nil))
+	assert.True(t, comp.Compressible(comp.Estimate(text)))
+	assert.False(t, comp.Compressible(comp.Estimate(random)))
+
+	// the whole body can be read after sampling
+	body, ratio, err := comp.Sample("txt", bytes.NewReader(text))
+	assert.NoError(t, err)
+	assert.Equal(t, comp.Estimate(text[:4<<10]), ratio)
+	all, _ := io.ReadAll(body)
+	assert.Equal(t, text, all)
+	// skipped files are not sampled
+	body, ratio, err = comp.Sample("JPG", bytes.NewReader(text))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, ratio)
+	all, _ = io.ReadAll(body)
+	assert.Equal(t, text, all)
+
+	ratio, err = comp.Ratio("txt", bytes.NewReader(text))
+	assert.NoError(t, err)
+	assert.Equal(t, comp.Estimate(text[:4<<10]), ratio)
+}
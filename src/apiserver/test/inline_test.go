@@ -0,0 +1,41 @@
+package test
+
+import (
+	"apiserver/config"
+	"apiserver/internal/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlineVersion(t *testing.T) {
+	conf := &config.ObjectConfig{
+		InlineLimit: 512,
+		ReedSolomon: config.RsConfig{DataShards: 4, ParityShards: 2, BlockPerShard: 1024},
+	}
+	bucket := &entity.Bucket{Compress: true}
+	// tiny object is stored in version without compression
+	ver := &entity.Version{Size: 100, StoreStrategy: entity.MultiReplication}
+	bucket.MakeVersion(ver, conf)
+	assert.Equal(t, entity.Inline, ver.StoreStrategy)
+	assert.False(t, ver.Compress)
+	assert.Zero(t, ver.AllShards())
+	// overwritten with larger content, it's stored in shards
+	ver = &entity.Version{Size: 4096, StoreStrategy: entity.Inline}
+	bucket.MakeVersion(ver, conf)
+	assert.Equal(t, entity.ECReedSolomon, ver.StoreStrategy)
+	assert.Equal(t, 6, ver.AllShards())
+	// limit of bucket overrides config
+	bucket.InlineLimit = -1
+	ver = &entity.Version{Size: 100, StoreStrategy: entity.MultiReplication}
+	bucket.MakeVersion(ver, conf)
+	assert.Equal(t, entity.MultiReplication, ver.StoreStrategy)
+	assert.Error(t, (&entity.Bucket{StoreStrategy: entity.Inline}).CheckLayout())
+	// limits are capped by the hard limit
+	bucket.InlineLimit = entity.MaxInlineLimit + 1
+	assert.Error(t, bucket.CheckLayout())
+	conf.InlineLimit = 1 << 20
+	ver = &entity.Version{Size: entity.MaxInlineLimit, StoreStrategy: entity.MultiReplication}
+	(&entity.Bucket{}).MakeVersion(ver, conf)
+	assert.Equal(t, entity.MultiReplication, ver.StoreStrategy)
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"apiserver/config"
+	. "apiserver/internal/usecase/service"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// countReader counts bytes read from a shard
+type countReader struct {
+	io.Reader
+	n int
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += n
+	return n, err
+}
+
+// encodeLRC encodes random data of size into shards
+func encodeLRC(t *testing.T, cfg *config.LrcConfig, size int) ([]byte, []*bytes.Buffer) {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	shards := make([]*bytes.Buffer, cfg.AllShards())
+	writers := make([]io.WriteCloser, cfg.AllShards())
+	for i := range shards {
+		shards[i] = new(bytes.Buffer)
+		writers[i] = nopWriteCloser{shards[i]}
+	}
+	enc, err := NewLRCEncoder(writers, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = enc.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return data, shards
+}
+
+func TestLocalReconstruction(t *testing.T) {
+	cfg := &config.LrcConfig{DataShards: 6, LocalGroups: 2, GlobalParity: 2, BlockPerShard: 1024}
+	data, shards := encodeLRC(t, cfg, 20000)
+	// lost shards and parity shards expected to be read
+	cases := []struct {
+		lost, read []int
+	}{
+		{nil, nil},
+		{[]int{1}, []int{6}},
+		{[]int{1, 4}, []int{6, 7}},
+		{[]int{6, 9}, nil},
+		{[]int{0, 1}, []int{8, 9}},
+		{[]int{0, 6}, []int{8}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.lost), func(t *testing.T) {
+			readers := make([]io.Reader, cfg.AllShards())
+			counters := make([]*countReader, cfg.AllShards())
+			rewrites := make([]io.Writer, cfg.AllShards())
+			for i := range readers {
+				counters[i] = &countReader{Reader: bytes.NewReader(shards[i].Bytes())}
+				readers[i] = counters[i]
+			}
+			for _, i := range c.lost {
+				readers[i] = nil
+				rewrites[i] = new(bytes.Buffer)
+			}
+			dec, err := NewLRCDecoder(readers, rewrites, int64(len(data)), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := io.ReadAll(dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(res, data) {
+				t.Fatal("decoded data mismatch")
+			}
+			for _, i := range c.lost {
+				if !bytes.Equal(rewrites[i].(*bytes.Buffer).Bytes(), shards[i].Bytes()) {
+					t.Errorf("rewritten shard %d mismatch", i)
+				}
+			}
+			read := map[int]bool{}
+			for _, i := range c.read {
+				read[i] = true
+			}
+			for i := cfg.DataShards; i < cfg.AllShards(); i++ {
+				if (counters[i].n > 0) != read[i] {
+					t.Errorf("parity shard %d read %d bytes", i, counters[i].n)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalRepair(t *testing.T) {
+	cfg := &config.LrcConfig{DataShards: 6, LocalGroups: 2, GlobalParity: 2, BlockPerShard: 1024}
+	_, shards := encodeLRC(t, cfg, 20000)
+	// lost shards and all shards expected to be read
+	cases := []struct {
+		lost, read []int
+	}{
+		{[]int{1}, []int{0, 2, 6}},
+		{[]int{1, 4}, []int{0, 2, 3, 5, 6, 7}},
+		{[]int{6}, []int{0, 1, 2}},
+		// groups losing more than one shard and global parity shards are repaired by decoding the whole object
+		{[]int{0, 1}, []int{2, 3, 4, 5, 8, 9}},
+		{[]int{8}, []int{0, 1, 2, 3, 4, 5}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.lost), func(t *testing.T) {
+			readers := make([]io.Reader, cfg.AllShards())
+			counters := make([]*countReader, cfg.AllShards())
+			rewrites := make([]io.Writer, cfg.AllShards())
+			for i := range readers {
+				counters[i] = &countReader{Reader: bytes.NewReader(shards[i].Bytes())}
+				readers[i] = counters[i]
+			}
+			for _, i := range c.lost {
+				readers[i] = nil
+				rewrites[i] = new(bytes.Buffer)
+			}
+			dec, err := NewLRCDecoder(readers, rewrites, 20000, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = dec.Repair(); err != nil {
+				t.Fatal(err)
+			}
+			for _, i := range c.lost {
+				if !bytes.Equal(rewrites[i].(*bytes.Buffer).Bytes(), shards[i].Bytes()) {
+					t.Errorf("rewritten shard %d mismatch", i)
+				}
+			}
+			read := map[int]bool{}
+			for _, i := range c.read {
+				read[i] = true
+			}
+			for i, counter := range counters {
+				if (counter.n > 0) != read[i] {
+					t.Errorf("shard %d read %d bytes", i, counter.n)
+				}
+			}
+		})
+	}
+}
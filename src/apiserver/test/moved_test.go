@@ -0,0 +1,67 @@
+package test
+
+import (
+	"apiserver/internal/usecase/grpcapi"
+	"common/consistency"
+	"common/proto/msg"
+	"common/proto/pb"
+	"common/util"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// movedMetaServer responses Aborted with address of 'owner' if it's not empty
+type movedMetaServer struct {
+	pb.UnimplementedMetadataApiServer
+	owner string
+	calls int
+}
+
+func (m *movedMetaServer) GetMetadata(_ context.Context, req *pb.MetaReq) (*pb.Msgpack, error) {
+	m.calls++
+	if m.owner != "" {
+		return nil, status.Error(codes.Aborted, m.owner)
+	}
+	bt, err := util.EncodeMsgp(&msg.Metadata{Name: req.Id})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Msgpack{Data: bt}, nil
+}
+
+func serveMeta(t *testing.T, serv *movedMetaServer) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterMetadataApiServer(s, serv)
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestFollowMovedKey(t *testing.T) {
+	target := &movedMetaServer{}
+	targetAddr := serveMeta(t, target)
+	source := &movedMetaServer{owner: targetAddr}
+	sourceAddr := serveMeta(t, source)
+	// request to the stale owner is redirected to the new one
+	m, err := grpcapi.GetMetadata(sourceAddr, "a/b", false, consistency.LevelDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, "a/b", m.Name)
+	assert.Equal(t, 1, source.calls)
+	assert.Equal(t, 1, target.calls)
+	// redirect at most once
+	target.owner = sourceAddr
+	_, err = grpcapi.GetMetadata(sourceAddr, "a/b", false, consistency.LevelDefault)
+	assert.Error(t, err)
+	assert.Equal(t, 2, source.calls)
+	assert.Equal(t, 2, target.calls)
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"apiserver/config"
+	controller "apiserver/internal/controller/http"
+	"apiserver/internal/usecase"
+	"apiserver/internal/usecase/componet/auth"
+	"apiserver/internal/usecase/pool"
+	"apiserver/internal/usecase/service"
+	"common/cst"
+	"common/proto/msg"
+	"common/replication"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// memKV is an in-memory etcd kv supporting get, put and delete of keys or prefixes
+type memKV struct {
+	clientv3.KV
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemKV() *memKV {
+	return &memKV{data: map[string]string{}}
+}
+
+func (m *memKV) match(key string, opts []clientv3.OpOption) []string {
+	prefix := len(clientv3.OpGet(key, opts...).RangeBytes()) > 0
+	var keys []string
+	for k := range m.data {
+		if k == key || (prefix && strings.HasPrefix(k, key)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *memKV) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resp := &clientv3.GetResponse{}
+	for _, k := range m.match(key, opts) {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(m.data[k])})
+	}
+	return resp, nil
+}
+
+func (m *memKV) Put(_ context.Context, key, val string, _ ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (m *memKV) Delete(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.match(key, opts) {
+		delete(m.data, k)
+	}
+	return &clientv3.DeleteResponse{}, nil
+}
+
+// feedMetaService serves change feeds of groups by master
+type feedMetaService struct {
+	usecase.IMetaService
+	groups map[string]string // groups by master id
+	feeds  map[string][]*msg.Change
+	listed []string
+}
+
+func (f *feedMetaService) ListChanges(masterId string, after uint64, limit int) ([]*msg.Change, uint64, string, error) {
+	group := f.groups[masterId]
+	feed := f.feeds[group]
+	f.listed = append(f.listed, fmt.Sprint(masterId, ":", after))
+	var res []*msg.Change
+	for _, c := range feed {
+		if c.Seq > after && len(res) < limit {
+			res = append(res, c)
+		}
+	}
+	return res, uint64(len(feed)), group, nil
+}
+
+func initReplicationConfig() {
+	pool.Config = &config.Config{}
+	pool.Config.Registry.Group = "test"
+	pool.Config.BucketReplication = config.BucketReplicationConfig{BatchSize: 2, MaxRetry: 3, RetryBase: time.Hour, RetryMax: time.Hour}
+}
+
+func replicationStatus(t *testing.T, kv *memKV, group string) *replication.Status {
+	var st replication.Status
+	val, ok := kv.data[cst.EtcdPrefix.FmtReplication("test", replication.KindStatus, group)]
+	assert.True(t, ok, "status of %s not found", group)
+	assert.NoError(t, json.Unmarshal([]byte(val), &st))
+	return &st
+}
+
+func TestReplicateByGroup(t *testing.T) {
+	initReplicationConfig()
+	// remote cluster fails to delete the version at ts 3
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ts") == "3" {
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer remote.Close()
+	kv := newMemKV()
+	rule, _ := json.Marshal(&replication.Rule{Bucket: "b1", Target: remote.URL, Deletes: true})
+	_, _ = kv.Put(context.Background(), cst.EtcdPrefix.FmtReplication("test", replication.KindRule, "b1"), string(rule))
+	// progress saved by master id in previous versions
+	legacy, _ := json.Marshal(&replication.Status{Group: "m1", Checkpoint: 1, Deleted: 1, UpdatedAt: time.Now()})
+	_, _ = kv.Put(context.Background(), cst.EtcdPrefix.FmtReplication("test", replication.KindStatus, "m1"), string(legacy))
+	feed := make([]*msg.Change, 0, 5)
+	for i := 1; i <= 5; i++ {
+		feed = append(feed, &msg.Change{Seq: uint64(i), Ts: time.Now().UnixMilli(), Op: msg.ChangeRemoveVersion,
+			Id: fmt.Sprint("b1/obj", i), Version: &msg.Version{Ts: int64(i)}})
+	}
+	meta := &feedMetaService{groups: map[string]string{"m1": "g1"}, feeds: map[string][]*msg.Change{"g1": feed[:4]}}
+	svc := service.NewReplicationService(nil, meta, kv)
+
+	svc.ReplicateFrom(context.Background(), "m1")
+	st := replicationStatus(t, kv, "g1")
+	assert.Equal(t, uint64(4), st.Checkpoint)
+	assert.Equal(t, int64(3), st.Deleted)
+	assert.Equal(t, 1, st.Retrying)
+	assert.NotContains(t, kv.data, cst.EtcdPrefix.FmtReplication("test", replication.KindStatus, "m1"))
+	// the failed change is retried by group
+	retries, _ := kv.Get(context.Background(), cst.EtcdPrefix.FmtReplication("test", replication.KindRetry, "g1-"), clientv3.WithPrefix())
+	assert.Len(t, retries.Kvs, 1)
+	assert.True(t, strings.HasSuffix(string(retries.Kvs[0].Key), fmt.Sprintf("g1-%020d", 3)))
+
+	// master of g1 changed, replicating continues from the checkpoint
+	meta.groups = map[string]string{"m2": "g1"}
+	meta.feeds["g1"] = feed
+	meta.listed = nil
+	svc.ReplicateFrom(context.Background(), "m2")
+	assert.Equal(t, []string{"m2:0", "m2:4"}, meta.listed)
+	st = replicationStatus(t, kv, "g1")
+	assert.Equal(t, uint64(5), st.Checkpoint)
+	assert.Equal(t, int64(4), st.Deleted)
+	assert.Equal(t, uint64(0), st.Lag)
+}
+
+func TestReplicaTsFromPeers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	validator := auth.NewPasswordValidator(newMemKV(), &auth.PasswordConfig{Enable: true, Username: "peer", Password: "secret"})
+	eng := gin.New()
+	oc := controller.NewObjectsController(nil, nil)
+	eng.PUT("/objects/:name", auth.ReplicaPeer(validator), oc.ValidatePut, func(c *gin.Context) { c.Status(http.StatusCreated) })
+	put := func(replicaTs, user, pwd string) int {
+		req := httptest.NewRequest(http.MethodPut, "/objects/a.txt", strings.NewReader("data"))
+		req.Header.Set("Bucket", "b1")
+		req.Header.Set("Digest", "digest")
+		if replicaTs != "" {
+			req.Header.Set("Replica-Ts", replicaTs)
+		}
+		if user != "" {
+			req.SetBasicAuth(user, pwd)
+		}
+		w := httptest.NewRecorder()
+		eng.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusCreated, put("", "", ""))
+	assert.Equal(t, http.StatusForbidden, put("100", "", ""))
+	assert.Equal(t, http.StatusForbidden, put("100", "peer", "wrong"))
+	assert.Equal(t, http.StatusCreated, put("100", "peer", "secret"))
+}
@@ -0,0 +1,19 @@
+package test
+
+import (
+	"apiserver/internal/usecase/componet/selector"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntropySelectKeepsServers(t *testing.T) {
+	sel := &selector.StorageEntropy{}
+	ds := []string{"127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3"}
+	ip := sel.Select(ds)
+	assert.Contains(t, ds, ip)
+	assert.Equal(t, []string{"127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3"}, ds)
+	remains, ip := sel.Pop(append([]string{}, ds...))
+	assert.Len(t, remains, 2)
+	assert.NotContains(t, remains, ip)
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"apiserver/config"
+	"apiserver/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketTieringConf(t *testing.T) {
+	conf := &config.TieringConfig{Enabled: true, ColdAfter: time.Hour, DataShards: 10, ParityShards: 4}
+	// no policy
+	res := (&entity.Bucket{}).TieringConf(conf)
+	assert.Equal(t, *conf, res)
+	// policy overrides config
+	bucket := &entity.Bucket{Tiering: &entity.Tiering{ColdAfter: 7200, DataShards: 6}}
+	res = bucket.TieringConf(conf)
+	assert.True(t, res.Enabled)
+	assert.Equal(t, 2*time.Hour, res.ColdAfter)
+	assert.Equal(t, 6, res.DataShards)
+	assert.Equal(t, 4, res.ParityShards)
+	// a shorter cold-after than scanning makes no difference
+	bucket.Tiering.ColdAfter = 60
+	assert.Equal(t, time.Hour, bucket.TieringConf(conf).ColdAfter)
+	bucket.Tiering.Disabled = true
+	assert.False(t, bucket.TieringConf(conf).Enabled)
+	// config is not changed
+	assert.Equal(t, 10, conf.DataShards)
+	bucket.Tiering.ParityShards = -1
+	assert.Error(t, bucket.CheckLayout())
+}
@@ -0,0 +1,123 @@
+// Package balance implements the storage-entropy based load balancing.
+// The load of a server combines its disk usage and I/O load. The system is the most
+// balanced when the distribution of loads reaches the maximum entropy.
+package balance
+
+import "math"
+
+// Node is the storage state of a server
+type Node struct {
+	Used    int64   // Used is the size of objects stored on server
+	Total   int64   // Total is the total space of disks
+	Free    int64   // Free is the free space of disks
+	IOLoad  float64 // IOLoad is the weighted io of disks
+	Unknown bool    // Unknown marks the state is not available
+}
+
+type Weights struct {
+	Space float64
+	IO    float64
+}
+
+var DefaultWeights = Weights{Space: 0.7, IO: 0.3}
+
+// Loads returns the load of each node in range [0,1]. nodes with unknown state have the max load.
+// space load is the usage of disks, or the size of stored objects if it's larger e.g. the disks are shared by others.
+// if total space is unknown, space load is the size of stored objects compared with the largest one.
+func Loads(nodes []Node, w Weights) []float64 {
+	var maxIO float64
+	var maxUsed int64
+	for _, n := range nodes {
+		maxIO = math.Max(maxIO, n.IOLoad)
+		if !n.Unknown && n.Used > maxUsed {
+			maxUsed = n.Used
+		}
+	}
+	res := make([]float64, len(nodes))
+	for i, n := range nodes {
+		if n.Unknown {
+			res[i] = 1
+			continue
+		}
+		var space, io float64
+		switch {
+		case n.Total > 0:
+			space = math.Min(math.Max(1-float64(n.Free)/float64(n.Total), float64(n.Used)/float64(n.Total)), 1)
+		case maxUsed > 0:
+			space = float64(n.Used) / float64(maxUsed)
+		}
+		if maxIO > 0 {
+			io = n.IOLoad / maxIO
+		}
+		res[i] = (w.Space*space + w.IO*io) / (w.Space + w.IO)
+	}
+	return res
+}
+
+// Entropy returns the normalized entropy of loads in range [0,1]. 1 means loads are perfectly balanced.
+func Entropy(loads []float64) float64 {
+	if len(loads) <= 1 {
+		return 1
+	}
+	var sum float64
+	for _, l := range loads {
+		sum += l
+	}
+	if sum <= 0 {
+		return 1
+	}
+	var h float64
+	for _, l := range loads {
+		if l <= 0 {
+			continue
+		}
+		p := l / sum
+		h -= p * math.Log(p)
+	}
+	return h / math.Log(float64(len(loads)))
+}
+
+// SelectIndex returns the index of load which makes the max entropy after it increases delta.
+// the first one is preferred if several ones have the same result.
+func SelectIndex(loads []float64, delta float64) int {
+	if len(loads) == 0 {
+		return -1
+	}
+	tmp := append(make([]float64, 0, len(loads)), loads...)
+	idx, maxH := 0, -1.0
+	for i := range tmp {
+		tmp[i] += delta
+		if h := Entropy(tmp); h > maxH {
+			idx, maxH = i, h
+		}
+		tmp[i] -= delta
+	}
+	return idx
+}
+
+// Targets returns the size of objects each node should store, which distributes 'sum' in proportion to
+// the total space of nodes so that all nodes have the same usage, i.e. the max storage entropy.
+// nodes are weighted equally if any total space is unknown.
+func Targets(nodes []Node, sum int64) []int64 {
+	res := make([]int64, len(nodes))
+	if len(nodes) == 0 {
+		return res
+	}
+	var totals float64
+	weighted := true
+	for _, n := range nodes {
+		if n.Unknown || n.Total <= 0 {
+			weighted = false
+			break
+		}
+		totals += float64(n.Total)
+	}
+	for i, n := range nodes {
+		if weighted {
+			res[i] = int64(math.Ceil(float64(sum) * float64(n.Total) / totals))
+		} else {
+			res[i] = int64(math.Ceil(float64(sum) / float64(len(nodes))))
+		}
+	}
+	return res
+}
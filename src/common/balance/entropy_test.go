@@ -0,0 +1,48 @@
+package balance
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEntropy(t *testing.T) {
+	if h := Entropy([]float64{0.5, 0.5, 0.5}); math.Abs(h-1) > 1e-9 {
+		t.Fatalf("balanced loads expect 1, got %f", h)
+	}
+	if h := Entropy([]float64{0.9, 0.1, 0.1}); h >= 1 {
+		t.Fatalf("unbalanced loads expect less than 1, got %f", h)
+	}
+}
+
+func TestSelectIndex(t *testing.T) {
+	if i := SelectIndex([]float64{0.6, 0.2, 0.4}, 0.05); i != 1 {
+		t.Fatalf("expect the least loaded one, got %d", i)
+	}
+}
+
+func TestLoads(t *testing.T) {
+	loads := Loads([]Node{
+		{Total: 100, Free: 50, IOLoad: 10},
+		{Total: 100, Free: 90, IOLoad: 0},
+		{Unknown: true},
+	}, DefaultWeights)
+	if !(loads[1] < loads[0] && loads[0] < loads[2]) {
+		t.Fatalf("unexpected loads %v", loads)
+	}
+	// stored objects count if total space is unknown or they are more than disk usage
+	loads = Loads([]Node{{Used: 80}, {Used: 20}, {Used: 60, Total: 100, Free: 90}}, DefaultWeights)
+	if !(loads[1] < loads[2] && loads[2] < loads[0]) {
+		t.Fatalf("unexpected loads %v", loads)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	res := Targets([]Node{{Total: 100}, {Total: 300}}, 80)
+	if res[0] != 20 || res[1] != 60 {
+		t.Fatalf("expect [20 60], got %v", res)
+	}
+	res = Targets([]Node{{Total: 100}, {Unknown: true}}, 80)
+	if res[0] != 40 || res[1] != 40 {
+		t.Fatalf("expect [40 40], got %v", res)
+	}
+}
@@ -0,0 +1,118 @@
+package balance
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type MoveState string
+
+const (
+	MovePending MoveState = "pending"
+	MoveRunning MoveState = "running"
+	MoveDone    MoveState = "done"
+	MoveFailed  MoveState = "failed"
+)
+
+// Move is a step of plan which sends Size bytes of shards from server From to server To
+type Move struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Size  int64     `json:"size"`
+	State MoveState `json:"state"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Plan is the moves to balance servers computed by the rebalance coordinator
+type Plan struct {
+	Leader    string    `json:"leader"`
+	Imbalance float64   `json:"imbalance"`
+	Paused    bool      `json:"paused"`
+	Moves     []*Move   `json:"moves"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Finished reports whether all moves are done or failed
+func (p *Plan) Finished() bool {
+	for _, m := range p.Moves {
+		if m.State == MovePending || m.State == MoveRunning {
+			return false
+		}
+	}
+	return true
+}
+
+// Imbalance returns the max ratio of the deviation to the target among nodes.
+// 0 means all nodes store exactly as much as their targets.
+func Imbalance(nodes []Node) float64 {
+	var sum int64
+	for _, n := range nodes {
+		sum += n.Used
+	}
+	if sum == 0 {
+		return 0
+	}
+	var res float64
+	for i, t := range Targets(nodes, sum) {
+		dev := math.Abs(float64(nodes[i].Used - t))
+		res = math.Max(res, dev/math.Max(float64(t), 1))
+	}
+	return res
+}
+
+// PlanMoves returns moves from nodes storing more than their targets to those storing less.
+// addrs[i] is the address of nodes[i]. nodes with unknown state are ignored.
+// no moves will be planned if the imbalance is not above threshold, and moves are at most maxSize bytes in total.
+func PlanMoves(addrs []string, nodes []Node, threshold float64, maxSize int64) []*Move {
+	known := make([]Node, 0, len(nodes))
+	knownAddrs := make([]string, 0, len(addrs))
+	for i, n := range nodes {
+		if !n.Unknown {
+			known = append(known, n)
+			knownAddrs = append(knownAddrs, addrs[i])
+		}
+	}
+	if len(known) <= 1 || Imbalance(known) <= threshold {
+		return nil
+	}
+	var sum int64
+	for _, n := range known {
+		sum += n.Used
+	}
+	type diff struct {
+		addr string
+		size int64
+	}
+	var sources, dests []*diff
+	for i, t := range Targets(known, sum) {
+		if d := known[i].Used - t; d > 0 {
+			sources = append(sources, &diff{knownAddrs[i], d})
+		} else if d < 0 {
+			dests = append(dests, &diff{knownAddrs[i], -d})
+		}
+	}
+	// the most unbalanced ones come first
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].size > sources[j].size })
+	sort.SliceStable(dests, func(i, j int) bool { return dests[i].size > dests[j].size })
+	var res []*Move
+	for i, j := 0, 0; i < len(sources) && j < len(dests) && maxSize > 0; {
+		size := sources[i].size
+		if dests[j].size < size {
+			size = dests[j].size
+		}
+		if maxSize < size {
+			size = maxSize
+		}
+		res = append(res, &Move{From: sources[i].addr, To: dests[j].addr, Size: size, State: MovePending})
+		maxSize -= size
+		if sources[i].size -= size; sources[i].size == 0 {
+			i++
+		}
+		if dests[j].size -= size; dests[j].size == 0 {
+			j++
+		}
+	}
+	return res
+}
@@ -0,0 +1,26 @@
+package balance
+
+import "testing"
+
+func TestPlanMoves(t *testing.T) {
+	addrs := []string{"a", "b", "c", "d"}
+	nodes := []Node{
+		{Used: 90, Total: 100},
+		{Used: 10, Total: 100},
+		{Used: 50, Total: 100},
+		{Unknown: true},
+	}
+	moves := PlanMoves(addrs, nodes, 0.1, 1000)
+	if len(moves) != 1 {
+		t.Fatalf("expect 1 move, got %d", len(moves))
+	}
+	if m := moves[0]; m.From != "a" || m.To != "b" || m.Size != 40 {
+		t.Fatalf("unexpected move %+v", m)
+	}
+	if moves = PlanMoves(addrs, nodes, 0.1, 30); moves[0].Size != 30 {
+		t.Fatalf("expect move limited to 30, got %d", moves[0].Size)
+	}
+	if moves = PlanMoves(addrs, nodes, 2, 1000); len(moves) != 0 {
+		t.Fatalf("expect no moves under threshold, got %d", len(moves))
+	}
+}
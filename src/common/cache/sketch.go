@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"hash/fnv"
+)
+
+const sketchDepth = 4
+
+// sketchSeeds mix hash of key for each row
+var sketchSeeds = [sketchDepth]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// frequencySketch is a count-min sketch estimating access frequency of keys with 4-bit counters.
+// all counters are halved after sampling enough accesses, so the frequency of old keys decays (TinyLFU).
+type frequencySketch struct {
+	table   [sketchDepth][]uint8
+	mask    uint64
+	samples int
+	limit   int
+}
+
+func newFrequencySketch(width int) *frequencySketch {
+	size := 64
+	for size < width {
+		size <<= 1
+	}
+	s := &frequencySketch{mask: uint64(size - 1), limit: 10 * size}
+	for i := range s.table {
+		s.table[i] = make([]uint8, size)
+	}
+	return s
+}
+
+func (s *frequencySketch) hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func (s *frequencySketch) index(h uint64, row int) uint64 {
+	h ^= sketchSeeds[row]
+	h *= 0x9e3779b97f4a7c15
+	return (h >> 32) & s.mask
+}
+
+// Increment records an access of key
+func (s *frequencySketch) Increment(key string) {
+	h := s.hash(key)
+	for i := range s.table {
+		if idx := s.index(h, i); s.table[i][idx] < 15 {
+			s.table[i][idx]++
+		}
+	}
+	if s.samples++; s.samples >= s.limit {
+		s.reset()
+	}
+}
+
+// Estimate returns the estimated frequency of key
+func (s *frequencySketch) Estimate(key string) uint8 {
+	h := s.hash(key)
+	res := uint8(15)
+	for i := range s.table {
+		if v := s.table[i][s.index(h, i)]; v < res {
+			res = v
+		}
+	}
+	return res
+}
+
+func (s *frequencySketch) reset() {
+	for i := range s.table {
+		for j := range s.table[i] {
+			s.table[i][j] >>= 1
+		}
+	}
+	s.samples /= 2
+}
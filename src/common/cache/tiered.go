@@ -0,0 +1,341 @@
+package cache
+
+import (
+	"common/cst"
+	"common/util"
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const spillFileExt = ".cache"
+
+// TieredConfig configures a Tiered cache. sizes are in bytes.
+type TieredConfig struct {
+	MaxSize     int64  // MaxSize is the max size of memory tier
+	MaxItemSize int64  // MaxItemSize is the max size of an entry, larger ones are never cached
+	DiskPath    string // DiskPath is the directory of disk tier which entries evicted from memory spill to, disabled if empty
+	DiskSize    int64  // DiskSize is the max size of disk tier
+}
+
+// TieredStats are counters and sizes of a Tiered cache
+type TieredStats struct {
+	MemSize   int64 `json:"memSize"`   // MemSize is the bytes of keys and values in memory
+	MemItems  int   `json:"memItems"`  // MemItems is the number of entries in memory
+	DiskSize  int64 `json:"diskSize"`  // DiskSize is the bytes of keys and values in disk
+	DiskItems int   `json:"diskItems"` // DiskItems is the number of entries in disk
+	Hits      int64 `json:"hits"`      // Hits is the number of reads found in memory
+	DiskHits  int64 `json:"diskHits"`  // DiskHits is the number of reads found in disk
+	Misses    int64 `json:"misses"`    // Misses is the number of reads not found
+	Rejects   int64 `json:"rejects"`   // Rejects is the number of entries not admitted or too large
+}
+
+type tieredEntry struct {
+	key   string
+	value []byte // value of entry in disk tier is nil unless it's being written
+	path  string // path is the file of entry in disk tier
+	size  int64
+}
+
+// lruTier is a list of entries ordered by recent accesses with their total size
+type lruTier struct {
+	ll    *list.List
+	items map[string]*list.Element
+	size  int64
+	max   int64
+}
+
+func newLruTier(max int64) *lruTier {
+	return &lruTier{ll: list.New(), items: make(map[string]*list.Element), max: max}
+}
+
+func (t *lruTier) get(key string) (*tieredEntry, bool) {
+	if el, ok := t.items[key]; ok {
+		t.ll.MoveToFront(el)
+		return el.Value.(*tieredEntry), true
+	}
+	return nil, false
+}
+
+func (t *lruTier) push(e *tieredEntry) {
+	t.items[e.key] = t.ll.PushFront(e)
+	t.size += e.size
+}
+
+func (t *lruTier) remove(key string) *tieredEntry {
+	el, ok := t.items[key]
+	if !ok {
+		return nil
+	}
+	delete(t.items, key)
+	t.ll.Remove(el)
+	e := el.Value.(*tieredEntry)
+	t.size -= e.size
+	return e
+}
+
+// victims returns the least recently used entries to remove for adding size
+func (t *lruTier) victims(size int64) []*tieredEntry {
+	var res []*tieredEntry
+	need := t.size + size - t.max
+	for el := t.ll.Back(); el != nil && need > 0; el = el.Prev() {
+		e := el.Value.(*tieredEntry)
+		res = append(res, e)
+		need -= e.size
+	}
+	return res
+}
+
+// Tiered is a LRU cache of a memory tier and an optional disk tier.
+// an entry is admitted only if it's accessed more frequently than the ones evicted for it (TinyLFU),
+// entries evicted from memory are moved to disk, and moved back to memory once they are read.
+// the index of disk tier is in memory, spilled files are removed on creating and closing.
+type Tiered struct {
+	mux    sync.Mutex
+	conf   TieredConfig
+	mem    *lruTier
+	disk   *lruTier
+	sketch *frequencySketch
+	seq    uint64
+	stats  TieredStats
+}
+
+func NewTiered(conf TieredConfig) (*Tiered, error) {
+	if conf.DiskPath != "" {
+		if err := os.MkdirAll(conf.DiskPath, cst.OS.ModeUser); err != nil {
+			return nil, err
+		}
+		if err := removeSpills(conf.DiskPath); err != nil {
+			return nil, err
+		}
+	} else {
+		conf.DiskSize = 0
+	}
+	// assume entries are 16KB in average
+	width := (conf.MaxSize + conf.DiskSize) / (16 << 10)
+	return &Tiered{
+		conf:   conf,
+		mem:    newLruTier(conf.MaxSize),
+		disk:   newLruTier(conf.DiskSize),
+		sketch: newFrequencySketch(int(min64(width, 1<<20))),
+	}, nil
+}
+
+func removeSpills(dir string) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+spillFileExt))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if err = os.Remove(m); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (c *Tiered) entrySize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+// Get returns value of key and records an access of it
+func (c *Tiered) Get(key string) ([]byte, bool) {
+	c.mux.Lock()
+	c.sketch.Increment(key)
+	if e, ok := c.mem.get(key); ok {
+		c.stats.Hits++
+		c.mux.Unlock()
+		return e.value, true
+	}
+	e := c.disk.remove(key)
+	if e == nil {
+		c.stats.Misses++
+	} else {
+		c.stats.DiskHits++
+	}
+	c.mux.Unlock()
+	if e == nil {
+		return nil, false
+	}
+	value := e.value
+	// the file is being written if value is not nil, which will be removed by writer
+	if value == nil {
+		var err error
+		value, err = os.ReadFile(e.path)
+		util.LogErrWithPre("remove cache spill", os.Remove(e.path))
+		if err != nil {
+			return nil, false
+		}
+	}
+	// move back to memory
+	c.set(&tieredEntry{key: key, value: value, size: e.size}, false)
+	return value, true
+}
+
+// Admit returns true if an entry of key in size would be added by Set
+func (c *Tiered) Admit(key string, size int64) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.admit(key, size+int64(len(key)))
+}
+
+func (c *Tiered) admit(key string, size int64) bool {
+	if size > c.conf.MaxItemSize || size > c.mem.max {
+		return false
+	}
+	freq := c.sketch.Estimate(key)
+	for _, v := range c.mem.victims(size) {
+		if v.key != key && c.sketch.Estimate(v.key) >= freq {
+			return false
+		}
+	}
+	return true
+}
+
+// Set adds or replaces the entry of key, returns false if it's not admitted
+func (c *Tiered) Set(key string, value []byte) bool {
+	return c.set(&tieredEntry{key: key, value: value, size: c.entrySize(key, value)}, true)
+}
+
+// Warm adds or replaces the entry of key bypassing admission, e.g. preloading entries known to be hot.
+// returns false if it's too large.
+func (c *Tiered) Warm(key string, value []byte) bool {
+	return c.set(&tieredEntry{key: key, value: value, size: c.entrySize(key, value)}, false)
+}
+
+func (c *Tiered) set(e *tieredEntry, admission bool) bool {
+	c.mux.Lock()
+	if e.size > c.conf.MaxItemSize || e.size > c.mem.max || admission && !c.admit(e.key, e.size) {
+		c.stats.Rejects++
+		c.mux.Unlock()
+		return false
+	}
+	c.mem.remove(e.key)
+	removes := c.removeDisk(nil, e.key)
+	var spills []*tieredEntry
+	for _, v := range c.mem.victims(e.size) {
+		c.mem.remove(v.key)
+		if v.size > c.disk.max {
+			continue
+		}
+		// spill to disk
+		for _, dv := range c.disk.victims(v.size) {
+			removes = c.removeDisk(removes, dv.key)
+		}
+		c.seq++
+		v.path = filepath.Join(c.conf.DiskPath, fmt.Sprint(c.seq, spillFileExt))
+		c.disk.push(v)
+		spills = append(spills, v)
+	}
+	c.mem.push(e)
+	c.mux.Unlock()
+	for _, path := range removes {
+		util.LogErrWithPre("remove cache spill", os.Remove(path))
+	}
+	for _, v := range spills {
+		c.writeSpill(v)
+	}
+	return true
+}
+
+// removeDisk removes entry of key from disk tier, appends its file to removes if it has been written
+func (c *Tiered) removeDisk(removes []string, key string) []string {
+	if e := c.disk.remove(key); e != nil && e.value == nil {
+		removes = append(removes, e.path)
+	}
+	return removes
+}
+
+func (c *Tiered) writeSpill(e *tieredEntry) {
+	err := os.WriteFile(e.path, e.value, cst.OS.ModeUser)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	el, ok := c.disk.items[e.key]
+	owned := ok && el.Value == e
+	if owned && err == nil {
+		e.value = nil
+		return
+	}
+	// failed or removed during writing
+	util.LogErrWithPre("write cache spill", err)
+	if owned {
+		c.disk.remove(e.key)
+	}
+	if err = os.Remove(e.path); !os.IsNotExist(err) {
+		util.LogErrWithPre("remove cache spill", err)
+	}
+}
+
+// Delete removes entry of key from all tiers
+func (c *Tiered) Delete(key string) {
+	c.mux.Lock()
+	c.mem.remove(key)
+	removes := c.removeDisk(nil, key)
+	c.mux.Unlock()
+	for _, path := range removes {
+		util.LogErrWithPre("remove cache spill", os.Remove(path))
+	}
+}
+
+// DeletePrefix removes entries whose keys start with prefix from all tiers, returns the number of removed entries
+func (c *Tiered) DeletePrefix(prefix string) int {
+	c.mux.Lock()
+	var removes []string
+	n := 0
+	for key := range c.mem.items {
+		if strings.HasPrefix(key, prefix) {
+			c.mem.remove(key)
+			n++
+		}
+	}
+	for key := range c.disk.items {
+		if strings.HasPrefix(key, prefix) {
+			removes = c.removeDisk(removes, key)
+			n++
+		}
+	}
+	c.mux.Unlock()
+	for _, path := range removes {
+		util.LogErrWithPre("remove cache spill", os.Remove(path))
+	}
+	return n
+}
+
+// Size returns the size of entries in memory and disk
+func (c *Tiered) Size() (mem int64, disk int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.mem.size, c.disk.size
+}
+
+// Stats returns a snapshot of counters and sizes
+func (c *Tiered) Stats() TieredStats {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	res := c.stats
+	res.MemSize, res.MemItems = c.mem.size, len(c.mem.items)
+	res.DiskSize, res.DiskItems = c.disk.size, len(c.disk.items)
+	return res
+}
+
+// Close removes all entries and spilled files
+func (c *Tiered) Close() error {
+	c.mux.Lock()
+	c.mem = newLruTier(c.conf.MaxSize)
+	c.disk = newLruTier(c.conf.DiskSize)
+	c.mux.Unlock()
+	if c.conf.DiskPath == "" {
+		return nil
+	}
+	return removeSpills(c.conf.DiskPath)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestTieredAdmission(t *testing.T) {
+	c, err := NewTiered(TieredConfig{MaxSize: 100, MaxItemSize: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Set("big", make([]byte, 60)) {
+		t.Fatal("entry larger than MaxItemSize should not be cached")
+	}
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprint("k", i)
+		c.Get(key)
+		c.Get(key)
+		if !c.Set(key, make([]byte, 22)) {
+			t.Fatalf("set %s fail", key)
+		}
+	}
+	// a one-hit key cannot evict frequently accessed ones
+	if c.Set("scan", make([]byte, 22)) {
+		t.Fatal("cold entry should not be admitted")
+	}
+	if !c.Warm("scan", make([]byte, 22)) {
+		t.Fatal("warming should bypass admission")
+	}
+	for i := 0; i < 5; i++ {
+		c.Get("hot")
+	}
+	if !c.Set("hot", make([]byte, 22)) {
+		t.Fatal("hot entry should be admitted")
+	}
+	if _, ok := c.Get("k0"); ok {
+		t.Fatal("the least recently used entry should be evicted")
+	}
+	if mem, _ := c.Size(); mem > 100 {
+		t.Fatalf("memory size %d exceeds", mem)
+	}
+}
+
+func TestTieredSpill(t *testing.T) {
+	c, err := NewTiered(TieredConfig{MaxSize: 64, MaxItemSize: 64, DiskPath: t.TempDir(), DiskSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprint("k", i)
+		for j := 0; j <= i; j++ {
+			c.Get(key)
+		}
+		if !c.Set(key, bytes.Repeat([]byte{byte(i)}, 30)) {
+			t.Fatalf("set %s fail", key)
+		}
+	}
+	mem, disk := c.Size()
+	if mem > 64 || disk == 0 {
+		t.Fatalf("expect evicted entries spill to disk, got mem=%d disk=%d", mem, disk)
+	}
+	val, ok := c.Get("k0")
+	if !ok || !bytes.Equal(val, bytes.Repeat([]byte{0}, 30)) {
+		t.Fatal("read spilled entry fail")
+	}
+	c.Delete("k0")
+	if _, ok = c.Get("k0"); ok {
+		t.Fatal("deleted entry should not be found")
+	}
+	if n := c.DeletePrefix("k"); n != 3 {
+		t.Fatalf("expect 3 entries removed by prefix, got %d", n)
+	}
+	st := c.Stats()
+	if st.MemItems+st.DiskItems != 0 || st.MemSize+st.DiskSize != 0 {
+		t.Fatalf("expect empty cache, got %+v", st)
+	}
+	if st.DiskHits != 1 || st.Misses == 0 {
+		t.Fatalf("unexpected counters %+v", st)
+	}
+}
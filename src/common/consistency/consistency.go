@@ -0,0 +1,99 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header is the http header to select read consistency
+	Header = "Read-Consistency"
+	// MetadataKey is the grpc metadata key to select read consistency
+	MetadataKey = "read-consistency"
+)
+
+type Mode int8
+
+const (
+	// Default uses the default read consistency of server
+	Default Mode = iota
+	// Any reads from any replica, data may be stale
+	Any
+	// BoundedStaleness reads from a follower which has contacted leader within Staleness, otherwise reads strongly
+	BoundedStaleness
+	// Strong reads data committed before the read started
+	Strong
+)
+
+// Level is the read consistency of a request
+type Level struct {
+	Mode      Mode
+	Staleness time.Duration
+}
+
+var (
+	LevelDefault = Level{}
+	LevelAny     = Level{Mode: Any}
+	LevelStrong  = Level{Mode: Strong}
+)
+
+// Parse parses 'strong', 'any' or 'bounded-staleness=<ms>'. empty string means Default.
+func Parse(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return LevelDefault, nil
+	case "any":
+		return LevelAny, nil
+	case "strong":
+		return LevelStrong, nil
+	}
+	if ms, ok := strings.CutPrefix(s, "bounded-staleness="); ok {
+		n, err := strconv.ParseInt(ms, 10, 64)
+		if err != nil || n < 0 {
+			return LevelDefault, fmt.Errorf("invalid staleness '%s'", ms)
+		}
+		return Level{Mode: BoundedStaleness, Staleness: time.Duration(n) * time.Millisecond}, nil
+	}
+	return LevelDefault, fmt.Errorf("unknown read consistency '%s'", s)
+}
+
+func (l Level) String() string {
+	switch l.Mode {
+	case Strong:
+		return "strong"
+	case BoundedStaleness:
+		return fmt.Sprint("bounded-staleness=", l.Staleness.Milliseconds())
+	case Any:
+		return "any"
+	default:
+		return ""
+	}
+}
+
+// OutgoingContext attaches level to grpc metadata of ctx. nothing is attached for Default.
+func OutgoingContext(ctx context.Context, l Level) context.Context {
+	if l.Mode == Default {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, l.String())
+}
+
+// FromIncomingContext returns the level in grpc metadata of ctx. returns false if absent.
+func FromIncomingContext(ctx context.Context) (Level, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return LevelDefault, false, nil
+	}
+	values := md.Get(MetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return LevelDefault, false, nil
+	}
+	l, err := Parse(values[0])
+	return l, true, err
+}
@@ -0,0 +1,44 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Level{
+		"":                       LevelDefault,
+		"any":                    LevelAny,
+		"strong":                 LevelStrong,
+		"bounded-staleness=1500": {Mode: BoundedStaleness, Staleness: 1500 * time.Millisecond},
+	}
+	for s, want := range cases {
+		got, err := Parse(s)
+		if err != nil {
+			t.Fatalf("parse '%s': %s", s, err)
+		}
+		if got != want {
+			t.Fatalf("parse '%s': want %v, got %v", s, want, got)
+		}
+	}
+	for _, s := range []string{"linear", "bounded-staleness=", "bounded-staleness=-1"} {
+		if _, err := Parse(s); err == nil {
+			t.Fatalf("parse '%s' should fail", s)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	l := Level{Mode: BoundedStaleness, Staleness: time.Second}
+	md, _ := metadata.FromOutgoingContext(OutgoingContext(context.Background(), l))
+	got, ok, err := FromIncomingContext(metadata.NewIncomingContext(context.Background(), md))
+	if err != nil || !ok || got != l {
+		t.Fatalf("want %v, got %v %v %v", l, got, ok, err)
+	}
+	if _, ok, _ = FromIncomingContext(context.Background()); ok {
+		t.Fatal("level should be absent")
+	}
+}
@@ -3,31 +3,45 @@ package cst
 import "fmt"
 
 type etcdPrefix struct {
-	Sep            []byte
-	HashSlot       string
-	Registry       string
-	ObjectCap      string
-	ApiCredential  string
-	SystemInfo     string
-	Configure      string
-	LocationSubKey string
+	Sep           []byte
+	HashSlot      string
+	Registry      string
+	ObjectCap     string
+	ApiCredential string
+	SystemInfo    string
+	Configure     string
+	Lock          string
+	Topology      string
+	Rebalance     string
+	Migration     string
+	Backup        string
+	Replication   string
 }
 
 var EtcdPrefix = etcdPrefix{
-	Sep:            []byte("/"),
-	HashSlot:       "hash_slot",
-	Registry:       "registry",
-	ObjectCap:      "object_cap",
-	ApiCredential:  "api_credential",
-	SystemInfo:     "sys_info",
-	Configure:      "configure",
-	LocationSubKey: "good.fs.location",
+	Sep:           []byte("/"),
+	HashSlot:      "hash_slot",
+	Registry:      "registry",
+	ObjectCap:     "object_cap",
+	ApiCredential: "api_credential",
+	SystemInfo:    "sys_info",
+	Configure:     "configure",
+	Lock:          "lock",
+	Topology:      "topology",
+	Rebalance:     "rebalance",
+	Migration:     "migration",
+	Backup:        "backup",
+	Replication:   "replication",
 }
 
 func (e *etcdPrefix) FmtRegistry(groupName, serviceName string) string {
 	return fmt.Sprintf("%s/%s/%s", groupName, e.Registry, serviceName)
 }
 
+func (e *etcdPrefix) FmtTopology(groupName, serviceName string) string {
+	return fmt.Sprintf("%s/%s/%s", groupName, e.Topology, serviceName)
+}
+
 func (e *etcdPrefix) FmtHashSlot(groupName, id string) string {
 	return fmt.Sprintf("%s/%s/%s", groupName, e.HashSlot, id)
 }
@@ -39,3 +53,23 @@ func (e *etcdPrefix) FmtSystemInfo(groupName, serviceName, id string) string {
 func (e *etcdPrefix) FmtConfigure(groupName, id string) string {
 	return fmt.Sprintf("%s/%s/%s", groupName, e.Configure, id)
 }
+
+func (e *etcdPrefix) FmtLock(groupName, name string) string {
+	return fmt.Sprintf("%s/%s/%s", groupName, e.Lock, name)
+}
+
+func (e *etcdPrefix) FmtRebalance(groupName, name string) string {
+	return fmt.Sprintf("%s/%s/%s", groupName, e.Rebalance, name)
+}
+
+func (e *etcdPrefix) FmtMigration(groupName, id string) string {
+	return fmt.Sprintf("%s/%s/%s", groupName, e.Migration, id)
+}
+
+func (e *etcdPrefix) FmtBackup(groupName, storeID, id string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", groupName, e.Backup, storeID, id)
+}
+
+func (e *etcdPrefix) FmtReplication(groupName, kind, id string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", groupName, e.Replication, kind, id)
+}
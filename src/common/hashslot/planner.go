@@ -0,0 +1,179 @@
+package hashslot
+
+import (
+	"common/balance"
+	"errors"
+	"math"
+	"sort"
+)
+
+// GroupLoad is the slots and load of a group
+type GroupLoad struct {
+	ID    string   `json:"id"`
+	Slots []string `json:"slots"`
+	Keys  int64    `json:"keys"`  // Keys is the number of keys stored in group
+	Bytes int64    `json:"bytes"` // Bytes is the size of data stored in group
+}
+
+// Move migrates Slots from group From to group To
+type Move struct {
+	From  string            `json:"from"`
+	To    string            `json:"to"`
+	Slots []string          `json:"slots"`
+	Keys  int64             `json:"keys"`  // Keys is the estimated number of keys in Slots
+	Bytes int64             `json:"bytes"` // Bytes is the estimated size of data in Slots
+	State balance.MoveState `json:"state"`
+	Error string            `json:"error,omitempty"`
+}
+
+type planGroup struct {
+	id      string
+	edges   EdgeList
+	load    float64
+	target  float64
+	density float64 // density is the load of a slot
+	keys    float64 // keys is the number of keys of a slot
+	bytes   float64 // bytes is the size of data of a slot
+}
+
+// planTolerance is the ratio of load exceeding target allowed
+const planTolerance = 0.05
+
+// tolerance is the load exceeding target allowed, at least one slot
+func (g *planGroup) tolerance() float64 {
+	return math.Max(g.density, g.target*planTolerance)
+}
+
+func (g *planGroup) slots() int {
+	var n int
+	for _, e := range g.edges {
+		n += e.End - e.Start
+	}
+	return n
+}
+
+// take removes n slots from tail of edges
+func (g *planGroup) take(n int) EdgeList {
+	var res EdgeList
+	for n > 0 && len(g.edges) > 0 {
+		last := g.edges[len(g.edges)-1]
+		if size := last.End - last.Start; size <= n {
+			res = append(res, last)
+			g.edges = g.edges[:len(g.edges)-1]
+			n -= size
+			continue
+		}
+		res = append(res, &Edge{Start: last.End - n, End: last.End, Value: last.Value})
+		last.End -= n
+		n = 0
+	}
+	sort.Sort(res)
+	return res
+}
+
+// share returns the ratio of n to total, 0 if total is 0
+func share(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// PlanRebalance computes moves which make groups have the same load. the load of a group is the sum of its shares
+// of keys and bytes, or the number of slots if there is no data at all. groups in drain will move out all slots.
+func PlanRebalance(groups []*GroupLoad, drain []string) ([]*Move, error) {
+	drained := make(map[string]bool, len(drain))
+	for _, id := range drain {
+		drained[id] = true
+	}
+	var totalKeys, totalBytes int64
+	for _, g := range groups {
+		totalKeys += g.Keys
+		totalBytes += g.Bytes
+	}
+	pgs := make([]*planGroup, 0, len(groups))
+	var totalLoad float64
+	var actives int
+	for _, g := range groups {
+		edges, err := WrapSlotsToEdges(g.Slots, g.ID)
+		if err != nil {
+			return nil, err
+		}
+		pg := &planGroup{id: g.ID, edges: edges}
+		n := pg.slots()
+		pg.load = float64(n)
+		if totalKeys > 0 || totalBytes > 0 {
+			pg.load = share(g.Keys, totalKeys) + share(g.Bytes, totalBytes)
+		}
+		if n > 0 {
+			pg.density = pg.load / float64(n)
+			pg.keys = float64(g.Keys) / float64(n)
+			pg.bytes = float64(g.Bytes) / float64(n)
+		}
+		totalLoad += pg.load
+		if !drained[g.ID] {
+			actives++
+		}
+		pgs = append(pgs, pg)
+	}
+	if actives == 0 {
+		return nil, errors.New("no group to receive slots")
+	}
+	var sources, dests []*planGroup
+	for _, pg := range pgs {
+		if !drained[pg.id] {
+			pg.target = totalLoad / float64(actives)
+			dests = append(dests, pg)
+		}
+		// drained groups are moved out firstly
+		if drained[pg.id] || pg.load-pg.target > pg.tolerance() {
+			sources = append(sources, pg)
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		if drained[sources[i].id] != drained[sources[j].id] {
+			return drained[sources[i].id]
+		}
+		return sources[i].load-sources[i].target > sources[j].load-sources[j].target
+	})
+	var res []*Move
+	for _, src := range sources {
+		for src.slots() > 0 {
+			// the group lacking the most load receives
+			sort.SliceStable(dests, func(i, j int) bool {
+				return dests[i].target-dests[i].load > dests[j].target-dests[j].load
+			})
+			dst := dests[0]
+			if dst == src {
+				break
+			}
+			n := src.slots()
+			if !drained[src.id] {
+				amount := math.Min(src.load-src.target, dst.target-dst.load)
+				if src.load-src.target <= src.tolerance() || amount < src.density {
+					break
+				}
+				n = int(math.Min(math.Round(amount/src.density), float64(n)))
+			} else if deficit := dst.target - dst.load; len(dests) > 1 && deficit > 0 && src.density > 0 {
+				n = int(math.Min(math.Ceil(deficit/src.density), float64(n)))
+			}
+			moved := src.take(n)
+			load := float64(n) * src.density
+			src.load -= load
+			dst.load += load
+			res = append(res, &Move{
+				From:  src.id,
+				To:    dst.id,
+				Slots: moved.Strings(),
+				Keys:  int64(float64(n) * src.keys),
+				Bytes: int64(float64(n) * src.bytes),
+				State: balance.MovePending,
+			})
+			for _, e := range moved {
+				e.Value = dst.id
+			}
+			dst.edges = CombineEdges(dst.edges, moved)
+		}
+	}
+	return res, nil
+}
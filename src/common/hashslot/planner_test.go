@@ -0,0 +1,77 @@
+package hashslot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRebalanceNewGroup(t *testing.T) {
+	moves, err := PlanRebalance([]*GroupLoad{
+		{ID: "A", Slots: []string{"0-16384"}, Keys: 1000},
+		{ID: "B", Slots: []string{}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := assert.New(t)
+	as.Len(moves, 1)
+	as.Equal("A", moves[0].From)
+	as.Equal("B", moves[0].To)
+	as.Equal([]string{"8192-16384"}, moves[0].Slots)
+}
+
+func TestPlanRebalanceDrain(t *testing.T) {
+	moves, err := PlanRebalance([]*GroupLoad{
+		{ID: "A", Slots: []string{"0-8192"}, Keys: 100},
+		{ID: "B", Slots: []string{"8192-12288"}, Keys: 100},
+		{ID: "C", Slots: []string{"12288-16384"}, Keys: 100},
+	}, []string{"A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := assert.New(t)
+	var slots int
+	for _, m := range moves {
+		as.Equal("A", m.From)
+		edges, _ := WrapSlotsToEdges(m.Slots, "")
+		for _, e := range edges {
+			slots += e.End - e.Start
+		}
+	}
+	as.Equal(8192, slots)
+	_, err = PlanRebalance([]*GroupLoad{{ID: "A", Slots: []string{"0-16384"}}}, []string{"A"})
+	as.Error(err)
+}
+
+func TestPlanRebalanceBalanced(t *testing.T) {
+	moves, err := PlanRebalance([]*GroupLoad{
+		{ID: "A", Slots: []string{"0-8192"}, Keys: 100},
+		{ID: "B", Slots: []string{"8192-16384"}, Keys: 102},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.New(t).Empty(moves)
+}
+
+func TestPlanRebalanceBytes(t *testing.T) {
+	// the same number of keys, but A stores 3 times the bytes of B
+	moves, err := PlanRebalance([]*GroupLoad{
+		{ID: "A", Slots: []string{"0-8192"}, Keys: 100, Bytes: 3000},
+		{ID: "B", Slots: []string{"8192-16384"}, Keys: 100, Bytes: 1000},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := assert.New(t)
+	as.Len(moves, 1)
+	as.Equal("A", moves[0].From)
+	as.Equal("B", moves[0].To)
+	// a fifth of slots of A moves to B
+	edges, _ := WrapSlotsToEdges(moves[0].Slots, "")
+	as.Len(edges, 1)
+	as.InDelta(8192/5, edges[0].End-edges[0].Start, 1)
+	as.InDelta(20, moves[0].Keys, 1)
+	as.InDelta(600, moves[0].Bytes, 1)
+}
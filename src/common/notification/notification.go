@@ -0,0 +1,62 @@
+// Package notification signs webhook requests of bucket events.
+// the signature is HMAC-SHA256 of "<timestamp>.<body>" with the secret of bucket,
+// receivers should verify it and reject requests with old timestamps to avoid replaying.
+package notification
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Goodfs-Signature" // HeaderSignature is like 't=<unix seconds>,v1=<hex of hmac>'
+	HeaderEvent     = "X-Goodfs-Event"     // HeaderEvent is the type of event
+	HeaderDelivery  = "X-Goodfs-Delivery"  // HeaderDelivery is the unique id of event, the same for retries
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+func mac(secret string, ts int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(ts, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sign returns the value of HeaderSignature
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, mac(secret, ts, body))
+}
+
+// Verify checks signature of body and its timestamp is within tolerance
+func Verify(secret, signature string, body []byte, tolerance time.Duration) error {
+	var ts int64
+	var sig string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == 0 || sig == "" {
+		return ErrInvalidSignature
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp out of tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"object:created"}`)
+	sig := Sign("secret", time.Now(), body)
+	if err := Verify("secret", sig, body, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify("other", sig, body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong secret should fail, got %v", err)
+	}
+	if err := Verify("secret", sig, []byte(`{}`), time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("modified body should fail, got %v", err)
+	}
+	old := Sign("secret", time.Now().Add(-time.Hour), body)
+	if err := Verify("secret", old, body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("old timestamp should fail, got %v", err)
+	}
+}
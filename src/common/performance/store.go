@@ -9,13 +9,16 @@ import (
 const (
 	ActionWrite = "write"
 	ActionRead  = "read"
+	ActionHit   = "hit"  // ActionHit is a read served by cache
+	ActionMiss  = "miss" // ActionMiss is a read not found in cache
 )
 
 const (
-	KindOfHTTP = "http"
-	KindOfGRPC = "grpc"
-	KindOfBolt = "bolt"
-	KindOfDisk = "disk"
+	KindOfHTTP  = "http"
+	KindOfGRPC  = "grpc"
+	KindOfBolt  = "bolt"
+	KindOfDisk  = "disk"
+	KindOfCache = "cache"
 )
 
 type Perform struct {
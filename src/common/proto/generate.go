@@ -16,6 +16,7 @@ import (
 //go:generate protoc -I=. --go_out=. --go-grpc_out=. hashslot.proto
 //go:generate protoc -I=. --go_out=. --go-grpc_out=. metadata.proto
 //go:generate protoc -I=. --go_out=. --go-grpc_out=. object_migration.proto
+//go:generate protoc -I=. --go_out=. --go-grpc_out=. object_stream.proto
 //go:generate protoc -I=. --go_out=. --go-grpc_out=. config_service.proto
 
 func ResolveErr(err error) error {
@@ -0,0 +1,45 @@
+package msg
+
+//go:generate msgp -tests=false #msg
+
+// types of events
+const (
+	EventObjectCreated  = "object:created"  // EventObjectCreated a new version of object is put
+	EventObjectRemoved  = "object:removed"  // EventObjectRemoved object is removed with all versions
+	EventVersionTrimmed = "version:trimmed" // EventVersionTrimmed a single version is removed, such as exceeding version remains
+)
+
+// Notification is the configuration of events of a bucket
+type Notification struct {
+	Events  []string `json:"events" msg:"events"`   // Events are types to publish, all types if empty
+	Webhook string   `json:"webhook" msg:"webhook"` // Webhook is the url receiving POST of events, no webhook if empty
+	Secret  string   `json:"secret" msg:"secret"`   // Secret signs webhook requests
+}
+
+// Accepts returns true if events of the type should be published
+func (n *Notification) Accepts(typ string) bool {
+	if n == nil {
+		return false
+	}
+	if len(n.Events) == 0 {
+		return true
+	}
+	for _, e := range n.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a mutation of object published to subscribers and webhooks of bucket
+type Event struct {
+	Seq     uint64 `json:"seq" msg:"seq"` // Seq is the increasing position in event queue
+	Type    string `json:"type" msg:"type"`
+	Bucket  string `json:"bucket" msg:"bucket"`
+	Name    string `json:"name" msg:"name"`
+	Version uint64 `json:"version,omitempty" msg:"version"`
+	Size    int64  `json:"size,omitempty" msg:"size"`
+	Hash    string `json:"hash,omitempty" msg:"hash"`
+	Ts      int64  `json:"ts" msg:"ts"` // Ts is the time of mutation in milliseconds
+}
@@ -26,37 +26,111 @@ func (z *Metadata) ID() string {
 }
 
 type Version struct {
-	Compress      bool     `json:"compress" msg:"compress"`
-	StoreStrategy int8     `json:"storeStrategy" msg:"store_strategy" binding:"required"`
-	DataShards    int32    `json:"dataShards" msg:"data_shards" binding:"required"`
-	ParityShards  int32    `json:"parityShards" msg:"parity_shards"`
-	ShardSize     int64    `json:"shardSize" msg:"shard_size" binding:"required"`
-	Size          int64    `json:"size" msg:"size" binding:"required"`
-	Ts            int64    `json:"ts" msg:"ts"`
-	Sequence      uint64   `json:"sequence" msg:"sequence"` // Sequence version number auto generated on saving
-	Hash          string   `json:"hash" msg:"hash" binding:"required"`
-	UniqueId      string   `json:"uniqueId" msg:"uniqueId"`
-	Locate        []string `json:"locate" msg:"locate" binding:"min=1"`
+	Compress      bool              `json:"compress" msg:"compress"`
+	Codec         string            `json:"codec,omitempty" msg:"codec"`                  // Codec compresses shards if Compress is true, s2 if empty
+	CompressRatio float32           `json:"compressRatio,omitempty" msg:"compress_ratio"` // CompressRatio is the estimated compressed size divided by size, 0 if not sampled
+	StoreStrategy int8              `json:"storeStrategy" msg:"store_strategy" binding:"required"`
+	DataShards    int32             `json:"dataShards" msg:"data_shards" binding:"required_unless=StoreStrategy 8"`
+	ParityShards  int32             `json:"parityShards" msg:"parity_shards"`
+	LocalGroups   int32             `json:"localGroups,omitempty" msg:"local_groups"` // LocalGroups is the number of local parity shards of local reconstruction codes
+	ShardSize     int64             `json:"shardSize" msg:"shard_size" binding:"required"`
+	Size          int64             `json:"size" msg:"size" binding:"required"`
+	Ts            int64             `json:"ts" msg:"ts"`
+	AccessTs      int64             `json:"accessTs" msg:"access_ts"` // AccessTs is the last reading time, updated by TouchVersion
+	Sequence      uint64            `json:"sequence" msg:"sequence"`  // Sequence version number auto generated on saving
+	Hash          string            `json:"hash" msg:"hash" binding:"required"`
+	Digest        string            `json:"digest,omitempty" msg:"digest"` // Digest is the sha256 of content which Hash is generated from, empty for versions saved before it
+	UniqueId      string            `json:"uniqueId" msg:"uniqueId"`
+	Locate        []string          `json:"locate" msg:"locate" binding:"required_unless=StoreStrategy 8"`
+	Replication   string            `json:"replication,omitempty" msg:"replication"`  // Replication is the status of replicating to remote cluster
+	ContentType   string            `json:"contentType,omitempty" msg:"content_type"` // ContentType is the media type given on uploading
+	Tags          map[string]string `json:"tags,omitempty" msg:"tags"`                // Tags are user defined labels of version
+	Inline        []byte            `json:"inline,omitempty" msg:"inline"`            // Inline is data of tiny object stored in version without shards, StoreStrategy is 8 then
 }
 
 func (z *Version) ID() string {
 	return util.UIntString(z.Sequence)
 }
 
+// replication status of version
+const (
+	ReplicaPending   = "pending"   // ReplicaPending waits to be retried after failure
+	ReplicaCompleted = "completed" // ReplicaCompleted has been copied to remote cluster
+	ReplicaFailed    = "failed"    // ReplicaFailed is given up after retries
+	ReplicaConflict  = "conflict"  // ReplicaConflict is skipped because remote one is newer
+	ReplicaReplica   = "replica"   // ReplicaReplica is copied from other cluster and will not be replicated again
+)
+
+// codecs of compressed objects
+const (
+	CodecS2   = "s2"   // CodecS2 is fast and the default one
+	CodecZstd = "zstd" // CodecZstd has better ratio and supports dictionaries
+)
+
+// HashRef is the reference counter of a unique hash, stored on the server owning the hash's slot
+type HashRef struct {
+	Count  int64    `json:"count" msg:"count"`   // Count is the number of versions referring to this hash
+	Hash   string   `json:"hash" msg:"hash"`     // Hash is the unique hash of version
+	Locate []string `json:"locate" msg:"locate"` // Locate is the locations of shards
+}
+
+func (z *HashRef) ID() string {
+	return z.Hash
+}
+
 type Bucket struct {
-	Versioning     bool     `json:"versioning" msg:"versioning"`          // Versioning marks bucket can store multi versions of object. if true, VersionRemains will be used
-	Readonly       bool     `json:"readonly" msg:"readonly"`              // Readonly marks objects in bucket only allowed to read
-	Compress       bool     `json:"compress" msg:"compress"`              // Compress marks objects in bucket should be compressed before store
-	StoreStrategy  int8     `json:"storeStrategy" msg:"store_strategy"`   // StoreStrategy if not zero, it will apply to ever objects under this bucket
-	DataShards     int32    `json:"dataShards" msg:"data_shards"`         // DataShards used when StoreStrategy is not zero
-	ParityShards   int32    `json:"parityShards" msg:"parity_shards"`     // ParityShards used when StoreStrategy is not zero
-	VersionRemains int32    `json:"versionRemains" msg:"version_remains"` // VersionRemains is maximum number of remained versions
-	CreateTime     int64    `json:"createTime" msg:"create_time"`         // CreateTime is bucket created time
-	UpdateTime     int64    `json:"updateTime" msg:"update_time"`         // UpdateTime is last updating time
-	Name           string   `json:"name" msg:"name"`                      // Name is the bucket's name
-	Policies       []string `json:"policies" msg:"policies"`              // Policies is the iam polices for this bucket (No support yet)
+	Versioning     bool          `json:"versioning" msg:"versioning"`               // Versioning marks bucket can store multi versions of object. if true, VersionRemains will be used
+	Readonly       bool          `json:"readonly" msg:"readonly"`                   // Readonly marks objects in bucket only allowed to read
+	Compress       bool          `json:"compress" msg:"compress"`                   // Compress marks objects in bucket should be compressed before store
+	Codec          string        `json:"codec,omitempty" msg:"codec"`               // Codec is used to compress objects, s2 if empty
+	AutoCompress   bool          `json:"autoCompress" msg:"auto_compress"`          // AutoCompress compresses objects only if they are sampled compressible, ignored if Compress is true
+	StoreStrategy  int8          `json:"storeStrategy" msg:"store_strategy"`        // StoreStrategy if not zero, it will apply to ever objects under this bucket
+	DataShards     int32         `json:"dataShards" msg:"data_shards"`              // DataShards used when StoreStrategy is not zero
+	ParityShards   int32         `json:"parityShards" msg:"parity_shards"`          // ParityShards used when StoreStrategy is not zero
+	LocalGroups    int32         `json:"localGroups,omitempty" msg:"local_groups"`  // LocalGroups used when StoreStrategy is local reconstruction codes
+	InlineLimit    int64         `json:"inlineLimit,omitempty" msg:"inline_limit"`  // InlineLimit is the size under which objects are stored in versions, default of config if zero, negative to disable
+	VersionRemains int32         `json:"versionRemains" msg:"version_remains"`      // VersionRemains is maximum number of remained versions
+	CreateTime     int64         `json:"createTime" msg:"create_time"`              // CreateTime is bucket created time
+	UpdateTime     int64         `json:"updateTime" msg:"update_time"`              // UpdateTime is last updating time
+	Name           string        `json:"name" msg:"name"`                           // Name is the bucket's name
+	Policies       []string      `json:"policies" msg:"policies"`                   // Policies is the iam polices for this bucket (No support yet)
+	Notification   *Notification `json:"notification,omitempty" msg:"notification"` // Notification publishes events of objects if not nil
+	Tiering        *Tiering      `json:"tiering,omitempty" msg:"tiering"`           // Tiering overrides the tiering config of api servers if not nil
+}
+
+// Tiering is the policy of transcoding cold objects in a bucket
+type Tiering struct {
+	Disabled     bool  `json:"disabled" msg:"disabled"`                    // Disabled marks objects in bucket are never transcoded
+	ColdAfter    int64 `json:"coldAfter,omitempty" msg:"cold_after"`       // ColdAfter is seconds of objects not read or written before they are cold, default of config if zero
+	DataShards   int32 `json:"dataShards,omitempty" msg:"data_shards"`     // DataShards of cold layout, default of config if zero
+	ParityShards int32 `json:"parityShards,omitempty" msg:"parity_shards"` // ParityShards of cold layout, default of config if zero
 }
 
 func (z *Bucket) ID() string {
 	return z.Name
 }
+
+type ChangeOp int8
+
+const (
+	ChangePutMetadata ChangeOp = iota + 1
+	ChangeRemoveMetadata
+	ChangePutVersion
+	ChangeUpdateVersion // ChangeUpdateVersion changes locations or layout of version, data is the same
+	ChangeRemoveVersion
+	ChangeRemoveAllVersion
+	ChangePutBucket
+	ChangeRemoveBucket
+)
+
+// Change is an entry of the change feed of metadata server
+type Change struct {
+	Seq      uint64    `json:"seq" msg:"seq"` // Seq is the increasing position in change feed
+	Ts       int64     `json:"ts" msg:"ts"`   // Ts is the time of change in milliseconds
+	Op       ChangeOp  `json:"op" msg:"op"`
+	Id       string    `json:"id" msg:"id"`                       // Id is the metadata id 'bucket/name' or the bucket name
+	Sequence uint64    `json:"sequence,omitempty" msg:"sequence"` // Sequence is the version number
+	Version  *Version  `json:"version,omitempty" msg:"version"`   // Version is the put one, or the removed one if exists
+	Metadata *Metadata `json:"metadata,omitempty" msg:"metadata"`
+	Bucket   *Bucket   `json:"bucket,omitempty" msg:"bucket"`
+}
@@ -42,6 +42,18 @@ func (z *Bucket) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "Compress")
 				return
 			}
+		case "codec":
+			z.Codec, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Codec")
+				return
+			}
+		case "auto_compress":
+			z.AutoCompress, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "AutoCompress")
+				return
+			}
 		case "store_strategy":
 			z.StoreStrategy, err = dc.ReadInt8()
 			if err != nil {
@@ -60,6 +72,18 @@ func (z *Bucket) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "ParityShards")
 				return
 			}
+		case "local_groups":
+			z.LocalGroups, err = dc.ReadInt32()
+			if err != nil {
+				err = msgp.WrapError(err, "LocalGroups")
+				return
+			}
+		case "inline_limit":
+			z.InlineLimit, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "InlineLimit")
+				return
+			}
 		case "version_remains":
 			z.VersionRemains, err = dc.ReadInt32()
 			if err != nil {
@@ -103,6 +127,42 @@ func (z *Bucket) DecodeMsg(dc *msgp.Reader) (err error) {
 					return
 				}
 			}
+		case "notification":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Notification")
+					return
+				}
+				z.Notification = nil
+			} else {
+				if z.Notification == nil {
+					z.Notification = new(Notification)
+				}
+				err = z.Notification.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Notification")
+					return
+				}
+			}
+		case "tiering":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Tiering")
+					return
+				}
+				z.Tiering = nil
+			} else {
+				if z.Tiering == nil {
+					z.Tiering = new(Tiering)
+				}
+				err = z.Tiering.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Tiering")
+					return
+				}
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -116,9 +176,9 @@ func (z *Bucket) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *Bucket) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 11
+	// map header, size 17
 	// write "versioning"
-	err = en.Append(0x8b, 0xaa, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67)
+	err = en.Append(0xde, 0x0, 0x11, 0xaa, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67)
 	if err != nil {
 		return
 	}
@@ -147,6 +207,26 @@ func (z *Bucket) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "Compress")
 		return
 	}
+	// write "codec"
+	err = en.Append(0xa5, 0x63, 0x6f, 0x64, 0x65, 0x63)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Codec)
+	if err != nil {
+		err = msgp.WrapError(err, "Codec")
+		return
+	}
+	// write "auto_compress"
+	err = en.Append(0xad, 0x61, 0x75, 0x74, 0x6f, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.AutoCompress)
+	if err != nil {
+		err = msgp.WrapError(err, "AutoCompress")
+		return
+	}
 	// write "store_strategy"
 	err = en.Append(0xae, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79)
 	if err != nil {
@@ -177,6 +257,26 @@ func (z *Bucket) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "ParityShards")
 		return
 	}
+	// write "local_groups"
+	err = en.Append(0xac, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt32(z.LocalGroups)
+	if err != nil {
+		err = msgp.WrapError(err, "LocalGroups")
+		return
+	}
+	// write "inline_limit"
+	err = en.Append(0xac, 0x69, 0x6e, 0x6c, 0x69, 0x6e, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.InlineLimit)
+	if err != nil {
+		err = msgp.WrapError(err, "InlineLimit")
+		return
+	}
 	// write "version_remains"
 	err = en.Append(0xaf, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x73)
 	if err != nil {
@@ -234,15 +334,49 @@ func (z *Bucket) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
+	// write "notification"
+	err = en.Append(0xac, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	if z.Notification == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Notification.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Notification")
+			return
+		}
+	}
+	// write "tiering"
+	err = en.Append(0xa7, 0x74, 0x69, 0x65, 0x72, 0x69, 0x6e, 0x67)
+	if err != nil {
+		return
+	}
+	if z.Tiering == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Tiering.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Tiering")
+			return
+		}
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *Bucket) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 11
+	// map header, size 17
 	// string "versioning"
-	o = append(o, 0x8b, 0xaa, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67)
+	o = append(o, 0xde, 0x0, 0x11, 0xaa, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67)
 	o = msgp.AppendBool(o, z.Versioning)
 	// string "readonly"
 	o = append(o, 0xa8, 0x72, 0x65, 0x61, 0x64, 0x6f, 0x6e, 0x6c, 0x79)
@@ -250,6 +384,12 @@ func (z *Bucket) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "compress"
 	o = append(o, 0xa8, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
 	o = msgp.AppendBool(o, z.Compress)
+	// string "codec"
+	o = append(o, 0xa5, 0x63, 0x6f, 0x64, 0x65, 0x63)
+	o = msgp.AppendString(o, z.Codec)
+	// string "auto_compress"
+	o = append(o, 0xad, 0x61, 0x75, 0x74, 0x6f, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
+	o = msgp.AppendBool(o, z.AutoCompress)
 	// string "store_strategy"
 	o = append(o, 0xae, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79)
 	o = msgp.AppendInt8(o, z.StoreStrategy)
@@ -259,6 +399,12 @@ func (z *Bucket) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "parity_shards"
 	o = append(o, 0xad, 0x70, 0x61, 0x72, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
 	o = msgp.AppendInt32(o, z.ParityShards)
+	// string "local_groups"
+	o = append(o, 0xac, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73)
+	o = msgp.AppendInt32(o, z.LocalGroups)
+	// string "inline_limit"
+	o = append(o, 0xac, 0x69, 0x6e, 0x6c, 0x69, 0x6e, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	o = msgp.AppendInt64(o, z.InlineLimit)
 	// string "version_remains"
 	o = append(o, 0xaf, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x73)
 	o = msgp.AppendInt32(o, z.VersionRemains)
@@ -277,6 +423,28 @@ func (z *Bucket) MarshalMsg(b []byte) (o []byte, err error) {
 	for za0001 := range z.Policies {
 		o = msgp.AppendString(o, z.Policies[za0001])
 	}
+	// string "notification"
+	o = append(o, 0xac, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e)
+	if z.Notification == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Notification.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Notification")
+			return
+		}
+	}
+	// string "tiering"
+	o = append(o, 0xa7, 0x74, 0x69, 0x65, 0x72, 0x69, 0x6e, 0x67)
+	if z.Tiering == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Tiering.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Tiering")
+			return
+		}
+	}
 	return
 }
 
@@ -316,6 +484,18 @@ func (z *Bucket) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "Compress")
 				return
 			}
+		case "codec":
+			z.Codec, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Codec")
+				return
+			}
+		case "auto_compress":
+			z.AutoCompress, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AutoCompress")
+				return
+			}
 		case "store_strategy":
 			z.StoreStrategy, bts, err = msgp.ReadInt8Bytes(bts)
 			if err != nil {
@@ -334,6 +514,18 @@ func (z *Bucket) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "ParityShards")
 				return
 			}
+		case "local_groups":
+			z.LocalGroups, bts, err = msgp.ReadInt32Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "LocalGroups")
+				return
+			}
+		case "inline_limit":
+			z.InlineLimit, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "InlineLimit")
+				return
+			}
 		case "version_remains":
 			z.VersionRemains, bts, err = msgp.ReadInt32Bytes(bts)
 			if err != nil {
@@ -377,6 +569,40 @@ func (z *Bucket) UnmarshalMsg(bts []byte) (o []byte, err error) {
 					return
 				}
 			}
+		case "notification":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Notification = nil
+			} else {
+				if z.Notification == nil {
+					z.Notification = new(Notification)
+				}
+				bts, err = z.Notification.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Notification")
+					return
+				}
+			}
+		case "tiering":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Tiering = nil
+			} else {
+				if z.Tiering == nil {
+					z.Tiering = new(Tiering)
+				}
+				bts, err = z.Tiering.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Tiering")
+					return
+				}
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -391,15 +617,27 @@ func (z *Bucket) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *Bucket) Msgsize() (s int) {
-	s = 1 + 11 + msgp.BoolSize + 9 + msgp.BoolSize + 9 + msgp.BoolSize + 15 + msgp.Int8Size + 12 + msgp.Int32Size + 14 + msgp.Int32Size + 16 + msgp.Int32Size + 12 + msgp.Int64Size + 12 + msgp.Int64Size + 5 + msgp.StringPrefixSize + len(z.Name) + 9 + msgp.ArrayHeaderSize
+	s = 3 + 11 + msgp.BoolSize + 9 + msgp.BoolSize + 9 + msgp.BoolSize + 6 + msgp.StringPrefixSize + len(z.Codec) + 14 + msgp.BoolSize + 15 + msgp.Int8Size + 12 + msgp.Int32Size + 14 + msgp.Int32Size + 13 + msgp.Int32Size + 13 + msgp.Int64Size + 16 + msgp.Int32Size + 12 + msgp.Int64Size + 12 + msgp.Int64Size + 5 + msgp.StringPrefixSize + len(z.Name) + 9 + msgp.ArrayHeaderSize
 	for za0001 := range z.Policies {
 		s += msgp.StringPrefixSize + len(z.Policies[za0001])
 	}
+	s += 13
+	if z.Notification == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Notification.Msgsize()
+	}
+	s += 8
+	if z.Tiering == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Tiering.Msgsize()
+	}
 	return
 }
 
 // DecodeMsg implements msgp.Decodable
-func (z *Extra) DecodeMsg(dc *msgp.Reader) (err error) {
+func (z *Change) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
 	_ = field
 	var zb0001 uint32
@@ -416,24 +654,94 @@ func (z *Extra) DecodeMsg(dc *msgp.Reader) (err error) {
 			return
 		}
 		switch msgp.UnsafeString(field) {
-		case "total":
-			z.Total, err = dc.ReadInt()
+		case "seq":
+			z.Seq, err = dc.ReadUint64()
 			if err != nil {
-				err = msgp.WrapError(err, "Total")
+				err = msgp.WrapError(err, "Seq")
 				return
 			}
-		case "first_version":
-			z.FirstVersion, err = dc.ReadInt()
+		case "ts":
+			z.Ts, err = dc.ReadInt64()
 			if err != nil {
-				err = msgp.WrapError(err, "FirstVersion")
+				err = msgp.WrapError(err, "Ts")
 				return
 			}
-		case "last_version":
-			z.LastVersion, err = dc.ReadInt()
+		case "op":
+			{
+				var zb0002 int8
+				zb0002, err = dc.ReadInt8()
+				if err != nil {
+					err = msgp.WrapError(err, "Op")
+					return
+				}
+				z.Op = ChangeOp(zb0002)
+			}
+		case "id":
+			z.Id, err = dc.ReadString()
 			if err != nil {
-				err = msgp.WrapError(err, "LastVersion")
+				err = msgp.WrapError(err, "Id")
+				return
+			}
+		case "sequence":
+			z.Sequence, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "Sequence")
 				return
 			}
+		case "version":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Version")
+					return
+				}
+				z.Version = nil
+			} else {
+				if z.Version == nil {
+					z.Version = new(Version)
+				}
+				err = z.Version.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Version")
+					return
+				}
+			}
+		case "metadata":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Metadata")
+					return
+				}
+				z.Metadata = nil
+			} else {
+				if z.Metadata == nil {
+					z.Metadata = new(Metadata)
+				}
+				err = z.Metadata.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Metadata")
+					return
+				}
+			}
+		case "bucket":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Bucket")
+					return
+				}
+				z.Bucket = nil
+			} else {
+				if z.Bucket == nil {
+					z.Bucket = new(Bucket)
+				}
+				err = z.Bucket.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Bucket")
+					return
+				}
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -446,72 +754,471 @@ func (z *Extra) DecodeMsg(dc *msgp.Reader) (err error) {
 }
 
 // EncodeMsg implements msgp.Encodable
-func (z Extra) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 3
-	// write "total"
-	err = en.Append(0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
+func (z *Change) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 8
+	// write "seq"
+	err = en.Append(0x88, 0xa3, 0x73, 0x65, 0x71)
 	if err != nil {
 		return
 	}
-	err = en.WriteInt(z.Total)
+	err = en.WriteUint64(z.Seq)
 	if err != nil {
-		err = msgp.WrapError(err, "Total")
+		err = msgp.WrapError(err, "Seq")
 		return
 	}
-	// write "first_version"
-	err = en.Append(0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	// write "ts"
+	err = en.Append(0xa2, 0x74, 0x73)
 	if err != nil {
 		return
 	}
-	err = en.WriteInt(z.FirstVersion)
+	err = en.WriteInt64(z.Ts)
 	if err != nil {
-		err = msgp.WrapError(err, "FirstVersion")
+		err = msgp.WrapError(err, "Ts")
 		return
 	}
-	// write "last_version"
-	err = en.Append(0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	// write "op"
+	err = en.Append(0xa2, 0x6f, 0x70)
 	if err != nil {
 		return
 	}
-	err = en.WriteInt(z.LastVersion)
+	err = en.WriteInt8(int8(z.Op))
 	if err != nil {
-		err = msgp.WrapError(err, "LastVersion")
+		err = msgp.WrapError(err, "Op")
 		return
 	}
-	return
-}
-
-// MarshalMsg implements msgp.Marshaler
-func (z Extra) MarshalMsg(b []byte) (o []byte, err error) {
-	o = msgp.Require(b, z.Msgsize())
-	// map header, size 3
-	// string "total"
-	o = append(o, 0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
-	o = msgp.AppendInt(o, z.Total)
-	// string "first_version"
-	o = append(o, 0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
-	o = msgp.AppendInt(o, z.FirstVersion)
-	// string "last_version"
-	o = append(o, 0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
-	o = msgp.AppendInt(o, z.LastVersion)
-	return
-}
-
-// UnmarshalMsg implements msgp.Unmarshaler
-func (z *Extra) UnmarshalMsg(bts []byte) (o []byte, err error) {
-	var field []byte
-	_ = field
-	var zb0001 uint32
-	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	// write "id"
+	err = en.Append(0xa2, 0x69, 0x64)
 	if err != nil {
-		err = msgp.WrapError(err)
 		return
 	}
-	for zb0001 > 0 {
-		zb0001--
-		field, bts, err = msgp.ReadMapKeyZC(bts)
+	err = en.WriteString(z.Id)
+	if err != nil {
+		err = msgp.WrapError(err, "Id")
+		return
+	}
+	// write "sequence"
+	err = en.Append(0xa8, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.Sequence)
+	if err != nil {
+		err = msgp.WrapError(err, "Sequence")
+		return
+	}
+	// write "version"
+	err = en.Append(0xa7, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	if z.Version == nil {
+		err = en.WriteNil()
 		if err != nil {
-			err = msgp.WrapError(err)
+			return
+		}
+	} else {
+		err = z.Version.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Version")
+			return
+		}
+	}
+	// write "metadata"
+	err = en.Append(0xa8, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61)
+	if err != nil {
+		return
+	}
+	if z.Metadata == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Metadata.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Metadata")
+			return
+		}
+	}
+	// write "bucket"
+	err = en.Append(0xa6, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74)
+	if err != nil {
+		return
+	}
+	if z.Bucket == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Bucket.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Bucket")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *Change) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 8
+	// string "seq"
+	o = append(o, 0x88, 0xa3, 0x73, 0x65, 0x71)
+	o = msgp.AppendUint64(o, z.Seq)
+	// string "ts"
+	o = append(o, 0xa2, 0x74, 0x73)
+	o = msgp.AppendInt64(o, z.Ts)
+	// string "op"
+	o = append(o, 0xa2, 0x6f, 0x70)
+	o = msgp.AppendInt8(o, int8(z.Op))
+	// string "id"
+	o = append(o, 0xa2, 0x69, 0x64)
+	o = msgp.AppendString(o, z.Id)
+	// string "sequence"
+	o = append(o, 0xa8, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65)
+	o = msgp.AppendUint64(o, z.Sequence)
+	// string "version"
+	o = append(o, 0xa7, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	if z.Version == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Version.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Version")
+			return
+		}
+	}
+	// string "metadata"
+	o = append(o, 0xa8, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61)
+	if z.Metadata == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Metadata.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Metadata")
+			return
+		}
+	}
+	// string "bucket"
+	o = append(o, 0xa6, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74)
+	if z.Bucket == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Bucket.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Bucket")
+			return
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Change) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "seq":
+			z.Seq, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Seq")
+				return
+			}
+		case "ts":
+			z.Ts, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Ts")
+				return
+			}
+		case "op":
+			{
+				var zb0002 int8
+				zb0002, bts, err = msgp.ReadInt8Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Op")
+					return
+				}
+				z.Op = ChangeOp(zb0002)
+			}
+		case "id":
+			z.Id, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Id")
+				return
+			}
+		case "sequence":
+			z.Sequence, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Sequence")
+				return
+			}
+		case "version":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Version = nil
+			} else {
+				if z.Version == nil {
+					z.Version = new(Version)
+				}
+				bts, err = z.Version.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Version")
+					return
+				}
+			}
+		case "metadata":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Metadata = nil
+			} else {
+				if z.Metadata == nil {
+					z.Metadata = new(Metadata)
+				}
+				bts, err = z.Metadata.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Metadata")
+					return
+				}
+			}
+		case "bucket":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Bucket = nil
+			} else {
+				if z.Bucket == nil {
+					z.Bucket = new(Bucket)
+				}
+				bts, err = z.Bucket.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Bucket")
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *Change) Msgsize() (s int) {
+	s = 1 + 4 + msgp.Uint64Size + 3 + msgp.Int64Size + 3 + msgp.Int8Size + 3 + msgp.StringPrefixSize + len(z.Id) + 9 + msgp.Uint64Size + 8
+	if z.Version == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Version.Msgsize()
+	}
+	s += 9
+	if z.Metadata == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Metadata.Msgsize()
+	}
+	s += 7
+	if z.Bucket == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Bucket.Msgsize()
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *ChangeOp) DecodeMsg(dc *msgp.Reader) (err error) {
+	{
+		var zb0001 int8
+		zb0001, err = dc.ReadInt8()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		(*z) = ChangeOp(zb0001)
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z ChangeOp) EncodeMsg(en *msgp.Writer) (err error) {
+	err = en.WriteInt8(int8(z))
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z ChangeOp) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	o = msgp.AppendInt8(o, int8(z))
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *ChangeOp) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	{
+		var zb0001 int8
+		zb0001, bts, err = msgp.ReadInt8Bytes(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		(*z) = ChangeOp(zb0001)
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z ChangeOp) Msgsize() (s int) {
+	s = msgp.Int8Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *Extra) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "total":
+			z.Total, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Total")
+				return
+			}
+		case "first_version":
+			z.FirstVersion, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "FirstVersion")
+				return
+			}
+		case "last_version":
+			z.LastVersion, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "LastVersion")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z Extra) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "total"
+	err = en.Append(0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Total)
+	if err != nil {
+		err = msgp.WrapError(err, "Total")
+		return
+	}
+	// write "first_version"
+	err = en.Append(0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.FirstVersion)
+	if err != nil {
+		err = msgp.WrapError(err, "FirstVersion")
+		return
+	}
+	// write "last_version"
+	err = en.Append(0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.LastVersion)
+	if err != nil {
+		err = msgp.WrapError(err, "LastVersion")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z Extra) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "total"
+	o = append(o, 0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
+	o = msgp.AppendInt(o, z.Total)
+	// string "first_version"
+	o = append(o, 0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	o = msgp.AppendInt(o, z.FirstVersion)
+	// string "last_version"
+	o = append(o, 0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+	o = msgp.AppendInt(o, z.LastVersion)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Extra) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
 			return
 		}
 		switch msgp.UnsafeString(field) {
@@ -524,14 +1231,203 @@ func (z *Extra) UnmarshalMsg(bts []byte) (o []byte, err error) {
 		case "first_version":
 			z.FirstVersion, bts, err = msgp.ReadIntBytes(bts)
 			if err != nil {
-				err = msgp.WrapError(err, "FirstVersion")
+				err = msgp.WrapError(err, "FirstVersion")
+				return
+			}
+		case "last_version":
+			z.LastVersion, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "LastVersion")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z Extra) Msgsize() (s int) {
+	s = 1 + 6 + msgp.IntSize + 14 + msgp.IntSize + 13 + msgp.IntSize
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *HashRef) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "count":
+			z.Count, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Count")
+				return
+			}
+		case "hash":
+			z.Hash, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Hash")
+				return
+			}
+		case "locate":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Locate")
+				return
+			}
+			if cap(z.Locate) >= int(zb0002) {
+				z.Locate = (z.Locate)[:zb0002]
+			} else {
+				z.Locate = make([]string, zb0002)
+			}
+			for za0001 := range z.Locate {
+				z.Locate[za0001], err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Locate", za0001)
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *HashRef) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "count"
+	err = en.Append(0x83, 0xa5, 0x63, 0x6f, 0x75, 0x6e, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Count)
+	if err != nil {
+		err = msgp.WrapError(err, "Count")
+		return
+	}
+	// write "hash"
+	err = en.Append(0xa4, 0x68, 0x61, 0x73, 0x68)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Hash)
+	if err != nil {
+		err = msgp.WrapError(err, "Hash")
+		return
+	}
+	// write "locate"
+	err = en.Append(0xa6, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Locate)))
+	if err != nil {
+		err = msgp.WrapError(err, "Locate")
+		return
+	}
+	for za0001 := range z.Locate {
+		err = en.WriteString(z.Locate[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "Locate", za0001)
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *HashRef) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "count"
+	o = append(o, 0x83, 0xa5, 0x63, 0x6f, 0x75, 0x6e, 0x74)
+	o = msgp.AppendInt64(o, z.Count)
+	// string "hash"
+	o = append(o, 0xa4, 0x68, 0x61, 0x73, 0x68)
+	o = msgp.AppendString(o, z.Hash)
+	// string "locate"
+	o = append(o, 0xa6, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x65)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Locate)))
+	for za0001 := range z.Locate {
+		o = msgp.AppendString(o, z.Locate[za0001])
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *HashRef) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "count":
+			z.Count, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Count")
+				return
+			}
+		case "hash":
+			z.Hash, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Hash")
+				return
+			}
+		case "locate":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Locate")
 				return
 			}
-		case "last_version":
-			z.LastVersion, bts, err = msgp.ReadIntBytes(bts)
-			if err != nil {
-				err = msgp.WrapError(err, "LastVersion")
-				return
+			if cap(z.Locate) >= int(zb0002) {
+				z.Locate = (z.Locate)[:zb0002]
+			} else {
+				z.Locate = make([]string, zb0002)
+			}
+			for za0001 := range z.Locate {
+				z.Locate[za0001], bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Locate", za0001)
+					return
+				}
 			}
 		default:
 			bts, err = msgp.Skip(bts)
@@ -546,8 +1442,11 @@ func (z *Extra) UnmarshalMsg(bts []byte) (o []byte, err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z Extra) Msgsize() (s int) {
-	s = 1 + 6 + msgp.IntSize + 14 + msgp.IntSize + 13 + msgp.IntSize
+func (z *HashRef) Msgsize() (s int) {
+	s = 1 + 6 + msgp.Int64Size + 5 + msgp.StringPrefixSize + len(z.Hash) + 7 + msgp.ArrayHeaderSize
+	for za0001 := range z.Locate {
+		s += msgp.StringPrefixSize + len(z.Locate[za0001])
+	}
 	return
 }
 
@@ -738,51 +1637,287 @@ func (z *Metadata) EncodeMsg(en *msgp.Writer) (err error) {
 	if err != nil {
 		return
 	}
-	err = en.WriteInt64(z.UpdateTime)
+	err = en.WriteInt64(z.UpdateTime)
+	if err != nil {
+		err = msgp.WrapError(err, "UpdateTime")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *Metadata) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 5
+	// string "Extra"
+	o = append(o, 0x85, 0xa5, 0x45, 0x78, 0x74, 0x72, 0x61)
+	if z.Extra == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		// map header, size 3
+		// string "total"
+		o = append(o, 0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
+		o = msgp.AppendInt(o, z.Extra.Total)
+		// string "first_version"
+		o = append(o, 0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+		o = msgp.AppendInt(o, z.Extra.FirstVersion)
+		// string "last_version"
+		o = append(o, 0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
+		o = msgp.AppendInt(o, z.Extra.LastVersion)
+	}
+	// string "name"
+	o = append(o, 0xa4, 0x6e, 0x61, 0x6d, 0x65)
+	o = msgp.AppendString(o, z.Name)
+	// string "bucket"
+	o = append(o, 0xa6, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74)
+	o = msgp.AppendString(o, z.Bucket)
+	// string "create_time"
+	o = append(o, 0xab, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65)
+	o = msgp.AppendInt64(o, z.CreateTime)
+	// string "update_time"
+	o = append(o, 0xab, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65)
+	o = msgp.AppendInt64(o, z.UpdateTime)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Metadata) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "Extra":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Extra = nil
+			} else {
+				if z.Extra == nil {
+					z.Extra = new(Extra)
+				}
+				var zb0002 uint32
+				zb0002, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Extra")
+					return
+				}
+				for zb0002 > 0 {
+					zb0002--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Extra")
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "total":
+						z.Extra.Total, bts, err = msgp.ReadIntBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Extra", "Total")
+							return
+						}
+					case "first_version":
+						z.Extra.FirstVersion, bts, err = msgp.ReadIntBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Extra", "FirstVersion")
+							return
+						}
+					case "last_version":
+						z.Extra.LastVersion, bts, err = msgp.ReadIntBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Extra", "LastVersion")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Extra")
+							return
+						}
+					}
+				}
+			}
+		case "name":
+			z.Name, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Name")
+				return
+			}
+		case "bucket":
+			z.Bucket, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Bucket")
+				return
+			}
+		case "create_time":
+			z.CreateTime, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "CreateTime")
+				return
+			}
+		case "update_time":
+			z.UpdateTime, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "UpdateTime")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *Metadata) Msgsize() (s int) {
+	s = 1 + 6
+	if z.Extra == nil {
+		s += msgp.NilSize
+	} else {
+		s += 1 + 6 + msgp.IntSize + 14 + msgp.IntSize + 13 + msgp.IntSize
+	}
+	s += 5 + msgp.StringPrefixSize + len(z.Name) + 7 + msgp.StringPrefixSize + len(z.Bucket) + 12 + msgp.Int64Size + 12 + msgp.Int64Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *Tiering) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "disabled":
+			z.Disabled, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "Disabled")
+				return
+			}
+		case "cold_after":
+			z.ColdAfter, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "ColdAfter")
+				return
+			}
+		case "data_shards":
+			z.DataShards, err = dc.ReadInt32()
+			if err != nil {
+				err = msgp.WrapError(err, "DataShards")
+				return
+			}
+		case "parity_shards":
+			z.ParityShards, err = dc.ReadInt32()
+			if err != nil {
+				err = msgp.WrapError(err, "ParityShards")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *Tiering) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 4
+	// write "disabled"
+	err = en.Append(0x84, 0xa8, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.Disabled)
+	if err != nil {
+		err = msgp.WrapError(err, "Disabled")
+		return
+	}
+	// write "cold_after"
+	err = en.Append(0xaa, 0x63, 0x6f, 0x6c, 0x64, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.ColdAfter)
+	if err != nil {
+		err = msgp.WrapError(err, "ColdAfter")
+		return
+	}
+	// write "data_shards"
+	err = en.Append(0xab, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt32(z.DataShards)
+	if err != nil {
+		err = msgp.WrapError(err, "DataShards")
+		return
+	}
+	// write "parity_shards"
+	err = en.Append(0xad, 0x70, 0x61, 0x72, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt32(z.ParityShards)
 	if err != nil {
-		err = msgp.WrapError(err, "UpdateTime")
+		err = msgp.WrapError(err, "ParityShards")
 		return
 	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
-func (z *Metadata) MarshalMsg(b []byte) (o []byte, err error) {
+func (z *Tiering) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 5
-	// string "Extra"
-	o = append(o, 0x85, 0xa5, 0x45, 0x78, 0x74, 0x72, 0x61)
-	if z.Extra == nil {
-		o = msgp.AppendNil(o)
-	} else {
-		// map header, size 3
-		// string "total"
-		o = append(o, 0x83, 0xa5, 0x74, 0x6f, 0x74, 0x61, 0x6c)
-		o = msgp.AppendInt(o, z.Extra.Total)
-		// string "first_version"
-		o = append(o, 0xad, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
-		o = msgp.AppendInt(o, z.Extra.FirstVersion)
-		// string "last_version"
-		o = append(o, 0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e)
-		o = msgp.AppendInt(o, z.Extra.LastVersion)
-	}
-	// string "name"
-	o = append(o, 0xa4, 0x6e, 0x61, 0x6d, 0x65)
-	o = msgp.AppendString(o, z.Name)
-	// string "bucket"
-	o = append(o, 0xa6, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74)
-	o = msgp.AppendString(o, z.Bucket)
-	// string "create_time"
-	o = append(o, 0xab, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65)
-	o = msgp.AppendInt64(o, z.CreateTime)
-	// string "update_time"
-	o = append(o, 0xab, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65)
-	o = msgp.AppendInt64(o, z.UpdateTime)
+	// map header, size 4
+	// string "disabled"
+	o = append(o, 0x84, 0xa8, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64)
+	o = msgp.AppendBool(o, z.Disabled)
+	// string "cold_after"
+	o = append(o, 0xaa, 0x63, 0x6f, 0x6c, 0x64, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72)
+	o = msgp.AppendInt64(o, z.ColdAfter)
+	// string "data_shards"
+	o = append(o, 0xab, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
+	o = msgp.AppendInt32(o, z.DataShards)
+	// string "parity_shards"
+	o = append(o, 0xad, 0x70, 0x61, 0x72, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
+	o = msgp.AppendInt32(o, z.ParityShards)
 	return
 }
 
 // UnmarshalMsg implements msgp.Unmarshaler
-func (z *Metadata) UnmarshalMsg(bts []byte) (o []byte, err error) {
+func (z *Tiering) UnmarshalMsg(bts []byte) (o []byte, err error) {
 	var field []byte
 	_ = field
 	var zb0001 uint32
@@ -799,80 +1934,28 @@ func (z *Metadata) UnmarshalMsg(bts []byte) (o []byte, err error) {
 			return
 		}
 		switch msgp.UnsafeString(field) {
-		case "Extra":
-			if msgp.IsNil(bts) {
-				bts, err = msgp.ReadNilBytes(bts)
-				if err != nil {
-					return
-				}
-				z.Extra = nil
-			} else {
-				if z.Extra == nil {
-					z.Extra = new(Extra)
-				}
-				var zb0002 uint32
-				zb0002, bts, err = msgp.ReadMapHeaderBytes(bts)
-				if err != nil {
-					err = msgp.WrapError(err, "Extra")
-					return
-				}
-				for zb0002 > 0 {
-					zb0002--
-					field, bts, err = msgp.ReadMapKeyZC(bts)
-					if err != nil {
-						err = msgp.WrapError(err, "Extra")
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "total":
-						z.Extra.Total, bts, err = msgp.ReadIntBytes(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "Extra", "Total")
-							return
-						}
-					case "first_version":
-						z.Extra.FirstVersion, bts, err = msgp.ReadIntBytes(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "Extra", "FirstVersion")
-							return
-						}
-					case "last_version":
-						z.Extra.LastVersion, bts, err = msgp.ReadIntBytes(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "Extra", "LastVersion")
-							return
-						}
-					default:
-						bts, err = msgp.Skip(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "Extra")
-							return
-						}
-					}
-				}
-			}
-		case "name":
-			z.Name, bts, err = msgp.ReadStringBytes(bts)
+		case "disabled":
+			z.Disabled, bts, err = msgp.ReadBoolBytes(bts)
 			if err != nil {
-				err = msgp.WrapError(err, "Name")
+				err = msgp.WrapError(err, "Disabled")
 				return
 			}
-		case "bucket":
-			z.Bucket, bts, err = msgp.ReadStringBytes(bts)
+		case "cold_after":
+			z.ColdAfter, bts, err = msgp.ReadInt64Bytes(bts)
 			if err != nil {
-				err = msgp.WrapError(err, "Bucket")
+				err = msgp.WrapError(err, "ColdAfter")
 				return
 			}
-		case "create_time":
-			z.CreateTime, bts, err = msgp.ReadInt64Bytes(bts)
+		case "data_shards":
+			z.DataShards, bts, err = msgp.ReadInt32Bytes(bts)
 			if err != nil {
-				err = msgp.WrapError(err, "CreateTime")
+				err = msgp.WrapError(err, "DataShards")
 				return
 			}
-		case "update_time":
-			z.UpdateTime, bts, err = msgp.ReadInt64Bytes(bts)
+		case "parity_shards":
+			z.ParityShards, bts, err = msgp.ReadInt32Bytes(bts)
 			if err != nil {
-				err = msgp.WrapError(err, "UpdateTime")
+				err = msgp.WrapError(err, "ParityShards")
 				return
 			}
 		default:
@@ -888,14 +1971,8 @@ func (z *Metadata) UnmarshalMsg(bts []byte) (o []byte, err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z *Metadata) Msgsize() (s int) {
-	s = 1 + 6
-	if z.Extra == nil {
-		s += msgp.NilSize
-	} else {
-		s += 1 + 6 + msgp.IntSize + 14 + msgp.IntSize + 13 + msgp.IntSize
-	}
-	s += 5 + msgp.StringPrefixSize + len(z.Name) + 7 + msgp.StringPrefixSize + len(z.Bucket) + 12 + msgp.Int64Size + 12 + msgp.Int64Size
+func (z *Tiering) Msgsize() (s int) {
+	s = 1 + 9 + msgp.BoolSize + 11 + msgp.Int64Size + 12 + msgp.Int32Size + 14 + msgp.Int32Size
 	return
 }
 
@@ -923,6 +2000,18 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "Compress")
 				return
 			}
+		case "codec":
+			z.Codec, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Codec")
+				return
+			}
+		case "compress_ratio":
+			z.CompressRatio, err = dc.ReadFloat32()
+			if err != nil {
+				err = msgp.WrapError(err, "CompressRatio")
+				return
+			}
 		case "store_strategy":
 			z.StoreStrategy, err = dc.ReadInt8()
 			if err != nil {
@@ -941,6 +2030,12 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "ParityShards")
 				return
 			}
+		case "local_groups":
+			z.LocalGroups, err = dc.ReadInt32()
+			if err != nil {
+				err = msgp.WrapError(err, "LocalGroups")
+				return
+			}
 		case "shard_size":
 			z.ShardSize, err = dc.ReadInt64()
 			if err != nil {
@@ -959,6 +2054,12 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "Ts")
 				return
 			}
+		case "access_ts":
+			z.AccessTs, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "AccessTs")
+				return
+			}
 		case "sequence":
 			z.Sequence, err = dc.ReadUint64()
 			if err != nil {
@@ -971,6 +2072,12 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "Hash")
 				return
 			}
+		case "digest":
+			z.Digest, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Digest")
+				return
+			}
 		case "uniqueId":
 			z.UniqueId, err = dc.ReadString()
 			if err != nil {
@@ -996,6 +2103,54 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 					return
 				}
 			}
+		case "replication":
+			z.Replication, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Replication")
+				return
+			}
+		case "content_type":
+			z.ContentType, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "ContentType")
+				return
+			}
+		case "tags":
+			var zb0003 uint32
+			zb0003, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if z.Tags == nil {
+				z.Tags = make(map[string]string, zb0003)
+			} else if len(z.Tags) > 0 {
+				for key := range z.Tags {
+					delete(z.Tags, key)
+				}
+			}
+			for zb0003 > 0 {
+				zb0003--
+				var za0002 string
+				var za0003 string
+				za0002, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Tags")
+					return
+				}
+				za0003, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Tags", za0002)
+					return
+				}
+				z.Tags[za0002] = za0003
+			}
+		case "inline":
+			z.Inline, err = dc.ReadBytes(z.Inline)
+			if err != nil {
+				err = msgp.WrapError(err, "Inline")
+				return
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -1009,9 +2164,9 @@ func (z *Version) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 11
+	// map header, size 20
 	// write "compress"
-	err = en.Append(0x8b, 0xa8, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
+	err = en.Append(0xde, 0x0, 0x14, 0xa8, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
 	if err != nil {
 		return
 	}
@@ -1020,6 +2175,26 @@ func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "Compress")
 		return
 	}
+	// write "codec"
+	err = en.Append(0xa5, 0x63, 0x6f, 0x64, 0x65, 0x63)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Codec)
+	if err != nil {
+		err = msgp.WrapError(err, "Codec")
+		return
+	}
+	// write "compress_ratio"
+	err = en.Append(0xae, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f)
+	if err != nil {
+		return
+	}
+	err = en.WriteFloat32(z.CompressRatio)
+	if err != nil {
+		err = msgp.WrapError(err, "CompressRatio")
+		return
+	}
 	// write "store_strategy"
 	err = en.Append(0xae, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79)
 	if err != nil {
@@ -1050,6 +2225,16 @@ func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "ParityShards")
 		return
 	}
+	// write "local_groups"
+	err = en.Append(0xac, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt32(z.LocalGroups)
+	if err != nil {
+		err = msgp.WrapError(err, "LocalGroups")
+		return
+	}
 	// write "shard_size"
 	err = en.Append(0xaa, 0x73, 0x68, 0x61, 0x72, 0x64, 0x5f, 0x73, 0x69, 0x7a, 0x65)
 	if err != nil {
@@ -1080,6 +2265,16 @@ func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "Ts")
 		return
 	}
+	// write "access_ts"
+	err = en.Append(0xa9, 0x61, 0x63, 0x63, 0x65, 0x73, 0x73, 0x5f, 0x74, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.AccessTs)
+	if err != nil {
+		err = msgp.WrapError(err, "AccessTs")
+		return
+	}
 	// write "sequence"
 	err = en.Append(0xa8, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65)
 	if err != nil {
@@ -1100,6 +2295,16 @@ func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "Hash")
 		return
 	}
+	// write "digest"
+	err = en.Append(0xa6, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Digest)
+	if err != nil {
+		err = msgp.WrapError(err, "Digest")
+		return
+	}
 	// write "uniqueId"
 	err = en.Append(0xa8, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x49, 0x64)
 	if err != nil {
@@ -1127,16 +2332,74 @@ func (z *Version) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
+	// write "replication"
+	err = en.Append(0xab, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Replication)
+	if err != nil {
+		err = msgp.WrapError(err, "Replication")
+		return
+	}
+	// write "content_type"
+	err = en.Append(0xac, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.ContentType)
+	if err != nil {
+		err = msgp.WrapError(err, "ContentType")
+		return
+	}
+	// write "tags"
+	err = en.Append(0xa4, 0x74, 0x61, 0x67, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteMapHeader(uint32(len(z.Tags)))
+	if err != nil {
+		err = msgp.WrapError(err, "Tags")
+		return
+	}
+	for za0002, za0003 := range z.Tags {
+		err = en.WriteString(za0002)
+		if err != nil {
+			err = msgp.WrapError(err, "Tags")
+			return
+		}
+		err = en.WriteString(za0003)
+		if err != nil {
+			err = msgp.WrapError(err, "Tags", za0002)
+			return
+		}
+	}
+	// write "inline"
+	err = en.Append(0xa6, 0x69, 0x6e, 0x6c, 0x69, 0x6e, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.Inline)
+	if err != nil {
+		err = msgp.WrapError(err, "Inline")
+		return
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *Version) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 11
+	// map header, size 20
 	// string "compress"
-	o = append(o, 0x8b, 0xa8, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
+	o = append(o, 0xde, 0x0, 0x14, 0xa8, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73)
 	o = msgp.AppendBool(o, z.Compress)
+	// string "codec"
+	o = append(o, 0xa5, 0x63, 0x6f, 0x64, 0x65, 0x63)
+	o = msgp.AppendString(o, z.Codec)
+	// string "compress_ratio"
+	o = append(o, 0xae, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f)
+	o = msgp.AppendFloat32(o, z.CompressRatio)
 	// string "store_strategy"
 	o = append(o, 0xae, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79)
 	o = msgp.AppendInt8(o, z.StoreStrategy)
@@ -1146,6 +2409,9 @@ func (z *Version) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "parity_shards"
 	o = append(o, 0xad, 0x70, 0x61, 0x72, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x64, 0x73)
 	o = msgp.AppendInt32(o, z.ParityShards)
+	// string "local_groups"
+	o = append(o, 0xac, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73)
+	o = msgp.AppendInt32(o, z.LocalGroups)
 	// string "shard_size"
 	o = append(o, 0xaa, 0x73, 0x68, 0x61, 0x72, 0x64, 0x5f, 0x73, 0x69, 0x7a, 0x65)
 	o = msgp.AppendInt64(o, z.ShardSize)
@@ -1155,12 +2421,18 @@ func (z *Version) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "ts"
 	o = append(o, 0xa2, 0x74, 0x73)
 	o = msgp.AppendInt64(o, z.Ts)
+	// string "access_ts"
+	o = append(o, 0xa9, 0x61, 0x63, 0x63, 0x65, 0x73, 0x73, 0x5f, 0x74, 0x73)
+	o = msgp.AppendInt64(o, z.AccessTs)
 	// string "sequence"
 	o = append(o, 0xa8, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65)
 	o = msgp.AppendUint64(o, z.Sequence)
 	// string "hash"
 	o = append(o, 0xa4, 0x68, 0x61, 0x73, 0x68)
 	o = msgp.AppendString(o, z.Hash)
+	// string "digest"
+	o = append(o, 0xa6, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74)
+	o = msgp.AppendString(o, z.Digest)
 	// string "uniqueId"
 	o = append(o, 0xa8, 0x75, 0x6e, 0x69, 0x71, 0x75, 0x65, 0x49, 0x64)
 	o = msgp.AppendString(o, z.UniqueId)
@@ -1170,6 +2442,22 @@ func (z *Version) MarshalMsg(b []byte) (o []byte, err error) {
 	for za0001 := range z.Locate {
 		o = msgp.AppendString(o, z.Locate[za0001])
 	}
+	// string "replication"
+	o = append(o, 0xab, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e)
+	o = msgp.AppendString(o, z.Replication)
+	// string "content_type"
+	o = append(o, 0xac, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	o = msgp.AppendString(o, z.ContentType)
+	// string "tags"
+	o = append(o, 0xa4, 0x74, 0x61, 0x67, 0x73)
+	o = msgp.AppendMapHeader(o, uint32(len(z.Tags)))
+	for za0002, za0003 := range z.Tags {
+		o = msgp.AppendString(o, za0002)
+		o = msgp.AppendString(o, za0003)
+	}
+	// string "inline"
+	o = append(o, 0xa6, 0x69, 0x6e, 0x6c, 0x69, 0x6e, 0x65)
+	o = msgp.AppendBytes(o, z.Inline)
 	return
 }
 
@@ -1197,6 +2485,18 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "Compress")
 				return
 			}
+		case "codec":
+			z.Codec, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Codec")
+				return
+			}
+		case "compress_ratio":
+			z.CompressRatio, bts, err = msgp.ReadFloat32Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "CompressRatio")
+				return
+			}
 		case "store_strategy":
 			z.StoreStrategy, bts, err = msgp.ReadInt8Bytes(bts)
 			if err != nil {
@@ -1215,6 +2515,12 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "ParityShards")
 				return
 			}
+		case "local_groups":
+			z.LocalGroups, bts, err = msgp.ReadInt32Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "LocalGroups")
+				return
+			}
 		case "shard_size":
 			z.ShardSize, bts, err = msgp.ReadInt64Bytes(bts)
 			if err != nil {
@@ -1233,6 +2539,12 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "Ts")
 				return
 			}
+		case "access_ts":
+			z.AccessTs, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AccessTs")
+				return
+			}
 		case "sequence":
 			z.Sequence, bts, err = msgp.ReadUint64Bytes(bts)
 			if err != nil {
@@ -1245,6 +2557,12 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "Hash")
 				return
 			}
+		case "digest":
+			z.Digest, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Digest")
+				return
+			}
 		case "uniqueId":
 			z.UniqueId, bts, err = msgp.ReadStringBytes(bts)
 			if err != nil {
@@ -1270,6 +2588,54 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 					return
 				}
 			}
+		case "replication":
+			z.Replication, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Replication")
+				return
+			}
+		case "content_type":
+			z.ContentType, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ContentType")
+				return
+			}
+		case "tags":
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if z.Tags == nil {
+				z.Tags = make(map[string]string, zb0003)
+			} else if len(z.Tags) > 0 {
+				for key := range z.Tags {
+					delete(z.Tags, key)
+				}
+			}
+			for zb0003 > 0 {
+				var za0002 string
+				var za0003 string
+				zb0003--
+				za0002, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Tags")
+					return
+				}
+				za0003, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Tags", za0002)
+					return
+				}
+				z.Tags[za0002] = za0003
+			}
+		case "inline":
+			z.Inline, bts, err = msgp.ReadBytesBytes(bts, z.Inline)
+			if err != nil {
+				err = msgp.WrapError(err, "Inline")
+				return
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -1284,9 +2650,17 @@ func (z *Version) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *Version) Msgsize() (s int) {
-	s = 1 + 9 + msgp.BoolSize + 15 + msgp.Int8Size + 12 + msgp.Int32Size + 14 + msgp.Int32Size + 11 + msgp.Int64Size + 5 + msgp.Int64Size + 3 + msgp.Int64Size + 9 + msgp.Uint64Size + 5 + msgp.StringPrefixSize + len(z.Hash) + 9 + msgp.StringPrefixSize + len(z.UniqueId) + 7 + msgp.ArrayHeaderSize
+	s = 3 + 9 + msgp.BoolSize + 6 + msgp.StringPrefixSize + len(z.Codec) + 15 + msgp.Float32Size + 15 + msgp.Int8Size + 12 + msgp.Int32Size + 14 + msgp.Int32Size + 13 + msgp.Int32Size + 11 + msgp.Int64Size + 5 + msgp.Int64Size + 3 + msgp.Int64Size + 10 + msgp.Int64Size + 9 + msgp.Uint64Size + 5 + msgp.StringPrefixSize + len(z.Hash) + 7 + msgp.StringPrefixSize + len(z.Digest) + 9 + msgp.StringPrefixSize + len(z.UniqueId) + 7 + msgp.ArrayHeaderSize
 	for za0001 := range z.Locate {
 		s += msgp.StringPrefixSize + len(z.Locate[za0001])
 	}
+	s += 12 + msgp.StringPrefixSize + len(z.Replication) + 13 + msgp.StringPrefixSize + len(z.ContentType) + 5 + msgp.MapHeaderSize
+	if z.Tags != nil {
+		for za0002, za0003 := range z.Tags {
+			_ = za0003
+			s += msgp.StringPrefixSize + len(za0002) + msgp.StringPrefixSize + len(za0003)
+		}
+	}
+	s += 7 + msgp.BytesPrefixSize + len(z.Inline)
 	return
 }
@@ -0,0 +1,169 @@
+package msg
+
+import (
+	"common/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sort fields of query
+const (
+	SortByName = "name"
+	SortBySize = "size"
+	SortByTs   = "ts"
+)
+
+var ErrInvalidCursor = errors.New("invalid query cursor")
+
+// Query filters versions of a bucket. zero values of filters are ignored.
+type Query struct {
+	Bucket      string
+	MinSize     int64    // MinSize is the inclusive lower bound of size
+	MaxSize     int64    // MaxSize is the inclusive upper bound of size
+	After       int64    // After is the inclusive lower bound of ts in milliseconds
+	Before      int64    // Before is the exclusive upper bound of ts in milliseconds
+	ContentType string   // ContentType must be equal to the one of version
+	Tags        []string // Tags are "key=value" pairs all of which version must have
+	Sort        string   // Sort is one of SortByName (default), SortBySize and SortByTs
+	Desc        bool
+	Limit       int
+	Cursor      string // Cursor is the position of the last version of previous page, see QueryCursor
+}
+
+// Match returns true if version satisfies all filters of query
+func (q *Query) Match(v *Version) bool {
+	if v.Size < q.MinSize || (q.MaxSize > 0 && v.Size > q.MaxSize) {
+		return false
+	}
+	if v.Ts < q.After || (q.Before > 0 && v.Ts >= q.Before) {
+		return false
+	}
+	if q.ContentType != "" && v.ContentType != q.ContentType {
+		return false
+	}
+	for _, tag := range q.Tags {
+		k, val, _ := strings.Cut(tag, "=")
+		if tv, ok := v.Tags[k]; !ok || tv != val {
+			return false
+		}
+	}
+	return true
+}
+
+// CursorOf returns the position of version in results of query. key is the version key "bucket/name.sequence".
+func (q *Query) CursorOf(key string, v *Version) QueryCursor {
+	switch q.Sort {
+	case SortBySize:
+		return QueryCursor{Value: v.Size, Key: key}
+	case SortByTs:
+		return QueryCursor{Value: v.Ts, Key: key}
+	default:
+		return QueryCursor{Key: key}
+	}
+}
+
+// QueryKey returns the key "bucket/name.sequence" of version in query results. the sequence is zero-padded,
+// so that versions of an object are ordered by sequence in keys.
+func QueryKey(id string, seq uint64) string {
+	return fmt.Sprintf("%s.%020d", id, seq)
+}
+
+// QueryCursor is the position of a version in sorted results. versions are ordered by value of sort field, then by key.
+type QueryCursor struct {
+	Value int64
+	Key   string
+}
+
+func ParseQueryCursor(s string) (c QueryCursor, err error) {
+	if s == "" {
+		return
+	}
+	val, key, ok := strings.Cut(s, ":")
+	if !ok || key == "" {
+		return c, ErrInvalidCursor
+	}
+	c.Key = key
+	if val != "" {
+		if c.Value, err = strconv.ParseInt(val, 10, 64); err != nil || c.Value < 0 {
+			return c, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+func (c QueryCursor) IsZero() bool {
+	return c.Key == ""
+}
+
+func (c QueryCursor) Less(o QueryCursor) bool {
+	if c.Value != o.Value {
+		return c.Value < o.Value
+	}
+	return c.Key < o.Key
+}
+
+func (c QueryCursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return util.IntString(c.Value) + ":" + c.Key
+}
+
+// BucketStat aggregates sizes of versions in a bucket
+type BucketStat struct {
+	Bucket         string `json:"bucket"`
+	Versions       int64  `json:"versions"`       // Versions is the number of versions
+	Size           int64  `json:"size"`           // Size is the total size of versions
+	Compressed     int64  `json:"compressed"`     // Compressed is the number of compressed versions
+	CompressedSize int64  `json:"compressedSize"` // CompressedSize is the total size of compressed versions
+	SampledSize    int64  `json:"sampledSize"`    // SampledSize is the total size of compressed versions with sampled ratio
+	SampledStored  int64  `json:"sampledStored"`  // SampledStored is the estimated total size of sampled versions after compression
+	// StoredSize is the estimated total size after compression. compressed versions without ratio, which are stored
+	// before sampling is supported, are estimated by the average ratio of sampled ones, or counted as uncompressed if none is sampled.
+	StoredSize int64 `json:"storedSize"`
+}
+
+// Add counts version v
+func (s *BucketStat) Add(v *Version) {
+	s.Versions++
+	s.Size += v.Size
+	if v.Compress {
+		s.Compressed++
+		s.CompressedSize += v.Size
+		if v.CompressRatio > 0 {
+			s.SampledSize += v.Size
+			s.SampledStored += int64(float64(v.Size) * float64(v.CompressRatio))
+		}
+	}
+	s.estimate()
+}
+
+// Merge adds counts of other
+func (s *BucketStat) Merge(other *BucketStat) {
+	s.Versions += other.Versions
+	s.Size += other.Size
+	s.Compressed += other.Compressed
+	s.CompressedSize += other.CompressedSize
+	s.SampledSize += other.SampledSize
+	s.SampledStored += other.SampledStored
+	s.estimate()
+}
+
+// estimate updates StoredSize by the counts
+func (s *BucketStat) estimate() {
+	unsampled := float64(s.CompressedSize - s.SampledSize)
+	if s.SampledSize > 0 {
+		unsampled *= float64(s.SampledStored) / float64(s.SampledSize)
+	}
+	s.StoredSize = s.Size - s.CompressedSize + s.SampledStored + int64(unsampled)
+}
+
+// Ratio returns StoredSize divided by Size, 1 if bucket is empty
+func (s *BucketStat) Ratio() float64 {
+	if s.Size == 0 {
+		return 1
+	}
+	return float64(s.StoredSize) / float64(s.Size)
+}
@@ -0,0 +1,33 @@
+package msg
+
+import "testing"
+
+func TestBucketStat(t *testing.T) {
+	var a, b BucketStat
+	a.Add(&Version{Size: 100})
+	a.Add(&Version{Size: 1000, Compress: true, CompressRatio: 0.5})
+	if a.StoredSize != 600 || a.Compressed != 1 {
+		t.Fatalf("stat %+v", a)
+	}
+	// compressed versions without ratio are counted as uncompressed if no version is sampled
+	b.Add(&Version{Size: 400, Compress: true})
+	if b.StoredSize != 400 {
+		t.Fatalf("stat %+v", b)
+	}
+	// otherwise they are estimated by the average ratio of sampled versions
+	b.Add(&Version{Size: 1000, Compress: true, CompressRatio: 0.3})
+	if b.StoredSize != 300+120 {
+		t.Fatalf("stat %+v", b)
+	}
+	a.Merge(&b)
+	// sampled versions of 2000 bytes are stored in 800 bytes, so unsampled 400 bytes are estimated in ratio 0.4
+	if a.Versions != 4 || a.Size != 2500 || a.CompressedSize != 2400 || a.StoredSize != 100+800+160 {
+		t.Fatalf("merged %+v", a)
+	}
+	if r := a.Ratio(); r != float64(1060)/2500 {
+		t.Fatalf("ratio %v", r)
+	}
+	if r := (&BucketStat{}).Ratio(); r != 1 {
+		t.Fatalf("ratio of empty bucket %v", r)
+	}
+}
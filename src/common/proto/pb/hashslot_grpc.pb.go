@@ -25,6 +25,7 @@ type HashSlotClient interface {
 	PrepareMigration(ctx context.Context, in *PrepareReq, opts ...grpc.CallOption) (*Response, error)
 	StartMigration(ctx context.Context, in *MigrationReq, opts ...grpc.CallOption) (*Response, error)
 	StreamingReceive(ctx context.Context, opts ...grpc.CallOption) (HashSlot_StreamingReceiveClient, error)
+	FinishMigration(ctx context.Context, in *FinishReq, opts ...grpc.CallOption) (*Response, error)
 	GetCurrentSlots(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 }
 
@@ -85,6 +86,15 @@ func (x *hashSlotStreamingReceiveClient) Recv() (*Response, error) {
 	return m, nil
 }
 
+func (c *hashSlotClient) FinishMigration(ctx context.Context, in *FinishReq, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/proto.HashSlot/FinishMigration", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *hashSlotClient) GetCurrentSlots(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
 	err := c.cc.Invoke(ctx, "/proto.HashSlot/GetCurrentSlots", in, out, opts...)
@@ -101,6 +111,7 @@ type HashSlotServer interface {
 	PrepareMigration(context.Context, *PrepareReq) (*Response, error)
 	StartMigration(context.Context, *MigrationReq) (*Response, error)
 	StreamingReceive(HashSlot_StreamingReceiveServer) error
+	FinishMigration(context.Context, *FinishReq) (*Response, error)
 	GetCurrentSlots(context.Context, *EmptyReq) (*Response, error)
 	mustEmbedUnimplementedHashSlotServer()
 }
@@ -118,6 +129,9 @@ func (UnimplementedHashSlotServer) StartMigration(context.Context, *MigrationReq
 func (UnimplementedHashSlotServer) StreamingReceive(HashSlot_StreamingReceiveServer) error {
 	return status.Errorf(codes.Unimplemented, "method StreamingReceive not implemented")
 }
+func (UnimplementedHashSlotServer) FinishMigration(context.Context, *FinishReq) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishMigration not implemented")
+}
 func (UnimplementedHashSlotServer) GetCurrentSlots(context.Context, *EmptyReq) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetCurrentSlots not implemented")
 }
@@ -196,6 +210,24 @@ func (x *hashSlotStreamingReceiveServer) Recv() (*MigrationItem, error) {
 	return m, nil
 }
 
+func _HashSlot_FinishMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashSlotServer).FinishMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.HashSlot/FinishMigration",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashSlotServer).FinishMigration(ctx, req.(*FinishReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _HashSlot_GetCurrentSlots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(EmptyReq)
 	if err := dec(in); err != nil {
@@ -229,6 +261,10 @@ var HashSlot_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StartMigration",
 			Handler:    _HashSlot_StartMigration_Handler,
 		},
+		{
+			MethodName: "FinishMigration",
+			Handler:    _HashSlot_FinishMigration_Handler,
+		},
 		{
 			MethodName: "GetCurrentSlots",
 			Handler:    _HashSlot_GetCurrentSlots_Handler,
@@ -33,6 +33,18 @@ type MetadataApiClient interface {
 	UpdateVersion(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error)
 	SaveBucket(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error)
 	RemoveVersion(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*Empty, error)
+	LocateHash(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*HashRef, error)
+	ReferHash(ctx context.Context, in *HashRef, opts ...grpc.CallOption) (*HashRef, error)
+	DereferHash(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*HashRef, error)
+	TouchVersion(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*Empty, error)
+	SwapVersion(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error)
+	ListColdVersion(ctx context.Context, in *ColdReq, opts ...grpc.CallOption) (*ColdResp, error)
+	ScanVersions(ctx context.Context, in *ScanReq, opts ...grpc.CallOption) (*ColdResp, error)
+	ListChanges(ctx context.Context, in *ChangeReq, opts ...grpc.CallOption) (*ChangeResp, error)
+	MarkVersion(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error)
+	Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (MetadataApi_SubscribeClient, error)
+	QueryVersions(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*QueryResp, error)
+	Batch(ctx context.Context, in *BatchReq, opts ...grpc.CallOption) (*BatchResp, error)
 }
 
 type metadataApiClient struct {
@@ -142,6 +154,137 @@ func (c *metadataApiClient) RemoveVersion(ctx context.Context, in *MetaReq, opts
 	return out, nil
 }
 
+func (c *metadataApiClient) LocateHash(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*HashRef, error) {
+	out := new(HashRef)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/LocateHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) ReferHash(ctx context.Context, in *HashRef, opts ...grpc.CallOption) (*HashRef, error) {
+	out := new(HashRef)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/ReferHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) DereferHash(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*HashRef, error) {
+	out := new(HashRef)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/DereferHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) TouchVersion(ctx context.Context, in *MetaReq, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/TouchVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) SwapVersion(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/SwapVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) ListColdVersion(ctx context.Context, in *ColdReq, opts ...grpc.CallOption) (*ColdResp, error) {
+	out := new(ColdResp)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/ListColdVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) ScanVersions(ctx context.Context, in *ScanReq, opts ...grpc.CallOption) (*ColdResp, error) {
+	out := new(ColdResp)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/ScanVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) ListChanges(ctx context.Context, in *ChangeReq, opts ...grpc.CallOption) (*ChangeResp, error) {
+	out := new(ChangeResp)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/ListChanges", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) MarkVersion(ctx context.Context, in *Metadata, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/MarkVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) Subscribe(ctx context.Context, in *SubscribeReq, opts ...grpc.CallOption) (MetadataApi_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MetadataApi_ServiceDesc.Streams[0], "/proto.MetadataApi/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metadataApiSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MetadataApi_SubscribeClient interface {
+	Recv() (*EventBatch, error)
+	grpc.ClientStream
+}
+
+type metadataApiSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *metadataApiSubscribeClient) Recv() (*EventBatch, error) {
+	m := new(EventBatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metadataApiClient) QueryVersions(ctx context.Context, in *QueryReq, opts ...grpc.CallOption) (*QueryResp, error) {
+	out := new(QueryResp)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/QueryVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataApiClient) Batch(ctx context.Context, in *BatchReq, opts ...grpc.CallOption) (*BatchResp, error) {
+	out := new(BatchResp)
+	err := c.cc.Invoke(ctx, "/proto.MetadataApi/Batch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetadataApiServer is the server API for MetadataApi service.
 // All implementations must embed UnimplementedMetadataApiServer
 // for forward compatibility
@@ -157,6 +300,18 @@ type MetadataApiServer interface {
 	UpdateVersion(context.Context, *Metadata) (*Empty, error)
 	SaveBucket(context.Context, *Metadata) (*Empty, error)
 	RemoveVersion(context.Context, *MetaReq) (*Empty, error)
+	LocateHash(context.Context, *MetaReq) (*HashRef, error)
+	ReferHash(context.Context, *HashRef) (*HashRef, error)
+	DereferHash(context.Context, *MetaReq) (*HashRef, error)
+	TouchVersion(context.Context, *MetaReq) (*Empty, error)
+	SwapVersion(context.Context, *Metadata) (*Empty, error)
+	ListColdVersion(context.Context, *ColdReq) (*ColdResp, error)
+	ScanVersions(context.Context, *ScanReq) (*ColdResp, error)
+	ListChanges(context.Context, *ChangeReq) (*ChangeResp, error)
+	MarkVersion(context.Context, *Metadata) (*Empty, error)
+	Subscribe(*SubscribeReq, MetadataApi_SubscribeServer) error
+	QueryVersions(context.Context, *QueryReq) (*QueryResp, error)
+	Batch(context.Context, *BatchReq) (*BatchResp, error)
 	mustEmbedUnimplementedMetadataApiServer()
 }
 
@@ -197,6 +352,42 @@ func (UnimplementedMetadataApiServer) SaveBucket(context.Context, *Metadata) (*E
 func (UnimplementedMetadataApiServer) RemoveVersion(context.Context, *MetaReq) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveVersion not implemented")
 }
+func (UnimplementedMetadataApiServer) LocateHash(context.Context, *MetaReq) (*HashRef, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LocateHash not implemented")
+}
+func (UnimplementedMetadataApiServer) ReferHash(context.Context, *HashRef) (*HashRef, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReferHash not implemented")
+}
+func (UnimplementedMetadataApiServer) DereferHash(context.Context, *MetaReq) (*HashRef, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DereferHash not implemented")
+}
+func (UnimplementedMetadataApiServer) TouchVersion(context.Context, *MetaReq) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TouchVersion not implemented")
+}
+func (UnimplementedMetadataApiServer) SwapVersion(context.Context, *Metadata) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SwapVersion not implemented")
+}
+func (UnimplementedMetadataApiServer) ListColdVersion(context.Context, *ColdReq) (*ColdResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListColdVersion not implemented")
+}
+func (UnimplementedMetadataApiServer) ScanVersions(context.Context, *ScanReq) (*ColdResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScanVersions not implemented")
+}
+func (UnimplementedMetadataApiServer) ListChanges(context.Context, *ChangeReq) (*ChangeResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChanges not implemented")
+}
+func (UnimplementedMetadataApiServer) MarkVersion(context.Context, *Metadata) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkVersion not implemented")
+}
+func (UnimplementedMetadataApiServer) Subscribe(*SubscribeReq, MetadataApi_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedMetadataApiServer) QueryVersions(context.Context, *QueryReq) (*QueryResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryVersions not implemented")
+}
+func (UnimplementedMetadataApiServer) Batch(context.Context, *BatchReq) (*BatchResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Batch not implemented")
+}
 func (UnimplementedMetadataApiServer) mustEmbedUnimplementedMetadataApiServer() {}
 
 // UnsafeMetadataApiServer may be embedded to opt out of forward compatibility for this service.
@@ -408,6 +599,225 @@ func _MetadataApi_RemoveVersion_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataApi_LocateHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).LocateHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/LocateHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).LocateHash(ctx, req.(*MetaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_ReferHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).ReferHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/ReferHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).ReferHash(ctx, req.(*HashRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_DereferHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).DereferHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/DereferHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).DereferHash(ctx, req.(*MetaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_TouchVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).TouchVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/TouchVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).TouchVersion(ctx, req.(*MetaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_SwapVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Metadata)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).SwapVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/SwapVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).SwapVersion(ctx, req.(*Metadata))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_ListColdVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ColdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).ListColdVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/ListColdVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).ListColdVersion(ctx, req.(*ColdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_ScanVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).ScanVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/ScanVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).ScanVersions(ctx, req.(*ScanReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_ListChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).ListChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/ListChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).ListChanges(ctx, req.(*ChangeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_MarkVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Metadata)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).MarkVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/MarkVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).MarkVersion(ctx, req.(*Metadata))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetadataApiServer).Subscribe(m, &metadataApiSubscribeServer{stream})
+}
+
+type MetadataApi_SubscribeServer interface {
+	Send(*EventBatch) error
+	grpc.ServerStream
+}
+
+type metadataApiSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *metadataApiSubscribeServer) Send(m *EventBatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MetadataApi_QueryVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).QueryVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/QueryVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).QueryVersions(ctx, req.(*QueryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataApi_Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataApiServer).Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.MetadataApi/Batch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataApiServer).Batch(ctx, req.(*BatchReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetadataApi_ServiceDesc is the grpc.ServiceDesc for MetadataApi service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -459,7 +869,57 @@ var MetadataApi_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveVersion",
 			Handler:    _MetadataApi_RemoveVersion_Handler,
 		},
+		{
+			MethodName: "LocateHash",
+			Handler:    _MetadataApi_LocateHash_Handler,
+		},
+		{
+			MethodName: "ReferHash",
+			Handler:    _MetadataApi_ReferHash_Handler,
+		},
+		{
+			MethodName: "DereferHash",
+			Handler:    _MetadataApi_DereferHash_Handler,
+		},
+		{
+			MethodName: "TouchVersion",
+			Handler:    _MetadataApi_TouchVersion_Handler,
+		},
+		{
+			MethodName: "SwapVersion",
+			Handler:    _MetadataApi_SwapVersion_Handler,
+		},
+		{
+			MethodName: "ListColdVersion",
+			Handler:    _MetadataApi_ListColdVersion_Handler,
+		},
+		{
+			MethodName: "ScanVersions",
+			Handler:    _MetadataApi_ScanVersions_Handler,
+		},
+		{
+			MethodName: "ListChanges",
+			Handler:    _MetadataApi_ListChanges_Handler,
+		},
+		{
+			MethodName: "MarkVersion",
+			Handler:    _MetadataApi_MarkVersion_Handler,
+		},
+		{
+			MethodName: "QueryVersions",
+			Handler:    _MetadataApi_QueryVersions_Handler,
+		},
+		{
+			MethodName: "Batch",
+			Handler:    _MetadataApi_Batch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _MetadataApi_Subscribe_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "metadata.proto",
 }
@@ -25,6 +25,7 @@ type ObjectMigrationClient interface {
 	ReceiveData(ctx context.Context, opts ...grpc.CallOption) (ObjectMigration_ReceiveDataClient, error)
 	FinishReceive(ctx context.Context, in *ObjectInfo, opts ...grpc.CallOption) (*Response, error)
 	RequireSend(ctx context.Context, in *RequiredInfo, opts ...grpc.CallOption) (*Response, error)
+	AcceptShard(ctx context.Context, in *ObjectInfo, opts ...grpc.CallOption) (*Response, error)
 	LeaveCommand(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 	JoinCommand(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 }
@@ -89,6 +90,15 @@ func (c *objectMigrationClient) RequireSend(ctx context.Context, in *RequiredInf
 	return out, nil
 }
 
+func (c *objectMigrationClient) AcceptShard(ctx context.Context, in *ObjectInfo, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/proto.ObjectMigration/AcceptShard", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *objectMigrationClient) LeaveCommand(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
 	err := c.cc.Invoke(ctx, "/proto.ObjectMigration/LeaveCommand", in, out, opts...)
@@ -114,6 +124,7 @@ type ObjectMigrationServer interface {
 	ReceiveData(ObjectMigration_ReceiveDataServer) error
 	FinishReceive(context.Context, *ObjectInfo) (*Response, error)
 	RequireSend(context.Context, *RequiredInfo) (*Response, error)
+	AcceptShard(context.Context, *ObjectInfo) (*Response, error)
 	LeaveCommand(context.Context, *EmptyReq) (*Response, error)
 	JoinCommand(context.Context, *EmptyReq) (*Response, error)
 	mustEmbedUnimplementedObjectMigrationServer()
@@ -132,6 +143,9 @@ func (UnimplementedObjectMigrationServer) FinishReceive(context.Context, *Object
 func (UnimplementedObjectMigrationServer) RequireSend(context.Context, *RequiredInfo) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequireSend not implemented")
 }
+func (UnimplementedObjectMigrationServer) AcceptShard(context.Context, *ObjectInfo) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptShard not implemented")
+}
 func (UnimplementedObjectMigrationServer) LeaveCommand(context.Context, *EmptyReq) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LeaveCommand not implemented")
 }
@@ -213,6 +227,24 @@ func _ObjectMigration_RequireSend_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ObjectMigration_AcceptShard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ObjectInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObjectMigrationServer).AcceptShard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ObjectMigration/AcceptShard",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObjectMigrationServer).AcceptShard(ctx, req.(*ObjectInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ObjectMigration_LeaveCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(EmptyReq)
 	if err := dec(in); err != nil {
@@ -264,6 +296,10 @@ var ObjectMigration_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RequireSend",
 			Handler:    _ObjectMigration_RequireSend_Handler,
 		},
+		{
+			MethodName: "AcceptShard",
+			Handler:    _ObjectMigration_AcceptShard_Handler,
+		},
 		{
 			MethodName: "LeaveCommand",
 			Handler:    _ObjectMigration_LeaveCommand_Handler,
@@ -0,0 +1,465 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.21.5
+// source: object_stream.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ShardWrite the first message opens a temp object, creating a new one if tmpId is empty.
+// following messages carry data and the last one may commit or abort the temp object.
+type ShardWrite struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size     int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	TmpId    string `protobuf:"bytes,3,opt,name=tmpId,proto3" json:"tmpId,omitempty"`
+	Data     []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Commit   bool   `protobuf:"varint,5,opt,name=commit,proto3" json:"commit,omitempty"`
+	Abort    bool   `protobuf:"varint,6,opt,name=abort,proto3" json:"abort,omitempty"`
+	Compress bool   `protobuf:"varint,7,opt,name=compress,proto3" json:"compress,omitempty"`
+	Codec    string `protobuf:"bytes,8,opt,name=codec,proto3" json:"codec,omitempty"` // codec of compression, s2 if empty
+}
+
+func (x *ShardWrite) Reset() {
+	*x = ShardWrite{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_object_stream_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShardWrite) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardWrite) ProtoMessage() {}
+
+func (x *ShardWrite) ProtoReflect() protoreflect.Message {
+	mi := &file_object_stream_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardWrite.ProtoReflect.Descriptor instead.
+func (*ShardWrite) Descriptor() ([]byte, []int) {
+	return file_object_stream_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShardWrite) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ShardWrite) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ShardWrite) GetTmpId() string {
+	if x != nil {
+		return x.TmpId
+	}
+	return ""
+}
+
+func (x *ShardWrite) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ShardWrite) GetCommit() bool {
+	if x != nil {
+		return x.Commit
+	}
+	return false
+}
+
+func (x *ShardWrite) GetAbort() bool {
+	if x != nil {
+		return x.Abort
+	}
+	return false
+}
+
+func (x *ShardWrite) GetCompress() bool {
+	if x != nil {
+		return x.Compress
+	}
+	return false
+}
+
+func (x *ShardWrite) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+type ShardWriteResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TmpId   string `protobuf:"bytes,1,opt,name=tmpId,proto3" json:"tmpId,omitempty"`
+	Written int64  `protobuf:"varint,2,opt,name=written,proto3" json:"written,omitempty"`
+}
+
+func (x *ShardWriteResp) Reset() {
+	*x = ShardWriteResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_object_stream_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShardWriteResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardWriteResp) ProtoMessage() {}
+
+func (x *ShardWriteResp) ProtoReflect() protoreflect.Message {
+	mi := &file_object_stream_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardWriteResp.ProtoReflect.Descriptor instead.
+func (*ShardWriteResp) Descriptor() ([]byte, []int) {
+	return file_object_stream_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ShardWriteResp) GetTmpId() string {
+	if x != nil {
+		return x.TmpId
+	}
+	return ""
+}
+
+func (x *ShardWriteResp) GetWritten() int64 {
+	if x != nil {
+		return x.Written
+	}
+	return 0
+}
+
+type ShardReadReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Offset   int64  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Size     int64  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Compress bool   `protobuf:"varint,4,opt,name=compress,proto3" json:"compress,omitempty"`
+	Temp     bool   `protobuf:"varint,5,opt,name=temp,proto3" json:"temp,omitempty"` // read a temp object which name is tmpId
+	Codec    string `protobuf:"bytes,6,opt,name=codec,proto3" json:"codec,omitempty"`
+}
+
+func (x *ShardReadReq) Reset() {
+	*x = ShardReadReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_object_stream_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShardReadReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardReadReq) ProtoMessage() {}
+
+func (x *ShardReadReq) ProtoReflect() protoreflect.Message {
+	mi := &file_object_stream_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardReadReq.ProtoReflect.Descriptor instead.
+func (*ShardReadReq) Descriptor() ([]byte, []int) {
+	return file_object_stream_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ShardReadReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ShardReadReq) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ShardReadReq) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ShardReadReq) GetCompress() bool {
+	if x != nil {
+		return x.Compress
+	}
+	return false
+}
+
+func (x *ShardReadReq) GetTemp() bool {
+	if x != nil {
+		return x.Temp
+	}
+	return false
+}
+
+func (x *ShardReadReq) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+type ShardData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *ShardData) Reset() {
+	*x = ShardData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_object_stream_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShardData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardData) ProtoMessage() {}
+
+func (x *ShardData) ProtoReflect() protoreflect.Message {
+	mi := &file_object_stream_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardData.ProtoReflect.Descriptor instead.
+func (*ShardData) Descriptor() ([]byte, []int) {
+	return file_object_stream_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ShardData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_object_stream_proto protoreflect.FileDescriptor
+
+var file_object_stream_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xbe, 0x01, 0x0a,
+	0x0a, 0x53, 0x68, 0x61, 0x72, 0x64, 0x57, 0x72, 0x69, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73,
+	0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6d, 0x70, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x74, 0x6d, 0x70, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x62, 0x6f, 0x72, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x61, 0x62, 0x6f, 0x72, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x63,
+	0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63,
+	0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x22, 0x40, 0x0a,
+	0x0e, 0x53, 0x68, 0x61, 0x72, 0x64, 0x57, 0x72, 0x69, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12,
+	0x14, 0x0a, 0x05, 0x74, 0x6d, 0x70, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x74, 0x6d, 0x70, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x22,
+	0x94, 0x01, 0x0a, 0x0c, 0x53, 0x68, 0x61, 0x72, 0x64, 0x52, 0x65, 0x61, 0x64, 0x52, 0x65, 0x71,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x65, 0x6d, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x74, 0x65, 0x6d, 0x70,
+	0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x22, 0x1f, 0x0a, 0x09, 0x53, 0x68, 0x61, 0x72, 0x64, 0x44,
+	0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0x80, 0x01, 0x0a, 0x0c, 0x4f, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x3a, 0x0a, 0x0a, 0x57, 0x72, 0x69, 0x74,
+	0x65, 0x53, 0x68, 0x61, 0x72, 0x64, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53,
+	0x68, 0x61, 0x72, 0x64, 0x57, 0x72, 0x69, 0x74, 0x65, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x68, 0x61, 0x72, 0x64, 0x57, 0x72, 0x69, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x28, 0x01, 0x30, 0x01, 0x12, 0x34, 0x0a, 0x09, 0x52, 0x65, 0x61, 0x64, 0x53, 0x68, 0x61, 0x72,
+	0x64, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x68, 0x61, 0x72, 0x64, 0x52,
+	0x65, 0x61, 0x64, 0x52, 0x65, 0x71, 0x1a, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53,
+	0x68, 0x61, 0x72, 0x64, 0x44, 0x61, 0x74, 0x61, 0x30, 0x01, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f,
+	0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_object_stream_proto_rawDescOnce sync.Once
+	file_object_stream_proto_rawDescData = file_object_stream_proto_rawDesc
+)
+
+func file_object_stream_proto_rawDescGZIP() []byte {
+	file_object_stream_proto_rawDescOnce.Do(func() {
+		file_object_stream_proto_rawDescData = protoimpl.X.CompressGZIP(file_object_stream_proto_rawDescData)
+	})
+	return file_object_stream_proto_rawDescData
+}
+
+var file_object_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_object_stream_proto_goTypes = []interface{}{
+	(*ShardWrite)(nil),     // 0: proto.ShardWrite
+	(*ShardWriteResp)(nil), // 1: proto.ShardWriteResp
+	(*ShardReadReq)(nil),   // 2: proto.ShardReadReq
+	(*ShardData)(nil),      // 3: proto.ShardData
+}
+var file_object_stream_proto_depIdxs = []int32{
+	0, // 0: proto.ObjectStream.WriteShard:input_type -> proto.ShardWrite
+	2, // 1: proto.ObjectStream.ReadShard:input_type -> proto.ShardReadReq
+	1, // 2: proto.ObjectStream.WriteShard:output_type -> proto.ShardWriteResp
+	3, // 3: proto.ObjectStream.ReadShard:output_type -> proto.ShardData
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_object_stream_proto_init() }
+func file_object_stream_proto_init() {
+	if File_object_stream_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_object_stream_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShardWrite); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_object_stream_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShardWriteResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_object_stream_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShardReadReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_object_stream_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShardData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_object_stream_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_object_stream_proto_goTypes,
+		DependencyIndexes: file_object_stream_proto_depIdxs,
+		MessageInfos:      file_object_stream_proto_msgTypes,
+	}.Build()
+	File_object_stream_proto = out.File
+	file_object_stream_proto_rawDesc = nil
+	file_object_stream_proto_goTypes = nil
+	file_object_stream_proto_depIdxs = nil
+}
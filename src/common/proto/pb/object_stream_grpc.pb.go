@@ -0,0 +1,200 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.5
+// source: object_stream.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ObjectStreamClient is the client API for ObjectStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ObjectStreamClient interface {
+	WriteShard(ctx context.Context, opts ...grpc.CallOption) (ObjectStream_WriteShardClient, error)
+	ReadShard(ctx context.Context, in *ShardReadReq, opts ...grpc.CallOption) (ObjectStream_ReadShardClient, error)
+}
+
+type objectStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewObjectStreamClient(cc grpc.ClientConnInterface) ObjectStreamClient {
+	return &objectStreamClient{cc}
+}
+
+func (c *objectStreamClient) WriteShard(ctx context.Context, opts ...grpc.CallOption) (ObjectStream_WriteShardClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObjectStream_ServiceDesc.Streams[0], "/proto.ObjectStream/WriteShard", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &objectStreamWriteShardClient{stream}
+	return x, nil
+}
+
+type ObjectStream_WriteShardClient interface {
+	Send(*ShardWrite) error
+	Recv() (*ShardWriteResp, error)
+	grpc.ClientStream
+}
+
+type objectStreamWriteShardClient struct {
+	grpc.ClientStream
+}
+
+func (x *objectStreamWriteShardClient) Send(m *ShardWrite) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *objectStreamWriteShardClient) Recv() (*ShardWriteResp, error) {
+	m := new(ShardWriteResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *objectStreamClient) ReadShard(ctx context.Context, in *ShardReadReq, opts ...grpc.CallOption) (ObjectStream_ReadShardClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObjectStream_ServiceDesc.Streams[1], "/proto.ObjectStream/ReadShard", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &objectStreamReadShardClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObjectStream_ReadShardClient interface {
+	Recv() (*ShardData, error)
+	grpc.ClientStream
+}
+
+type objectStreamReadShardClient struct {
+	grpc.ClientStream
+}
+
+func (x *objectStreamReadShardClient) Recv() (*ShardData, error) {
+	m := new(ShardData)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ObjectStreamServer is the server API for ObjectStream service.
+// All implementations must embed UnimplementedObjectStreamServer
+// for forward compatibility
+type ObjectStreamServer interface {
+	WriteShard(ObjectStream_WriteShardServer) error
+	ReadShard(*ShardReadReq, ObjectStream_ReadShardServer) error
+	mustEmbedUnimplementedObjectStreamServer()
+}
+
+// UnimplementedObjectStreamServer must be embedded to have forward compatible implementations.
+type UnimplementedObjectStreamServer struct {
+}
+
+func (UnimplementedObjectStreamServer) WriteShard(ObjectStream_WriteShardServer) error {
+	return status.Errorf(codes.Unimplemented, "method WriteShard not implemented")
+}
+func (UnimplementedObjectStreamServer) ReadShard(*ShardReadReq, ObjectStream_ReadShardServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReadShard not implemented")
+}
+func (UnimplementedObjectStreamServer) mustEmbedUnimplementedObjectStreamServer() {}
+
+// UnsafeObjectStreamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ObjectStreamServer will
+// result in compilation errors.
+type UnsafeObjectStreamServer interface {
+	mustEmbedUnimplementedObjectStreamServer()
+}
+
+func RegisterObjectStreamServer(s grpc.ServiceRegistrar, srv ObjectStreamServer) {
+	s.RegisterService(&ObjectStream_ServiceDesc, srv)
+}
+
+func _ObjectStream_WriteShard_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ObjectStreamServer).WriteShard(&objectStreamWriteShardServer{stream})
+}
+
+type ObjectStream_WriteShardServer interface {
+	Send(*ShardWriteResp) error
+	Recv() (*ShardWrite, error)
+	grpc.ServerStream
+}
+
+type objectStreamWriteShardServer struct {
+	grpc.ServerStream
+}
+
+func (x *objectStreamWriteShardServer) Send(m *ShardWriteResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *objectStreamWriteShardServer) Recv() (*ShardWrite, error) {
+	m := new(ShardWrite)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ObjectStream_ReadShard_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ShardReadReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObjectStreamServer).ReadShard(m, &objectStreamReadShardServer{stream})
+}
+
+type ObjectStream_ReadShardServer interface {
+	Send(*ShardData) error
+	grpc.ServerStream
+}
+
+type objectStreamReadShardServer struct {
+	grpc.ServerStream
+}
+
+func (x *objectStreamReadShardServer) Send(m *ShardData) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ObjectStream_ServiceDesc is the grpc.ServiceDesc for ObjectStream service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ObjectStream_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.ObjectStream",
+	HandlerType: (*ObjectStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WriteShard",
+			Handler:       _ObjectStream_WriteShard_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ReadShard",
+			Handler:       _ObjectStream_ReadShard_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "object_stream.proto",
+}
@@ -363,7 +363,7 @@ var file_raft_cmd_proto_rawDesc = []byte{
 	0x0a, 0x66, 0x6f, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x0a, 0x66, 0x6f, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a,
 	0x09, 0x70, 0x72, 0x65, 0x76, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
-	0x52, 0x09, 0x70, 0x72, 0x65, 0x76, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x32, 0xaa, 0x03, 0x0a, 0x07,
+	0x52, 0x09, 0x70, 0x72, 0x65, 0x76, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x32, 0x93, 0x04, 0x0a, 0x07,
 	0x52, 0x61, 0x66, 0x74, 0x43, 0x6d, 0x64, 0x12, 0x33, 0x0a, 0x09, 0x42, 0x6f, 0x6f, 0x74, 0x73,
 	0x74, 0x72, 0x61, 0x70, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x42, 0x6f, 0x6f,
 	0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74,
@@ -390,8 +390,15 @@ var file_raft_cmd_proto_rawDesc = []byte{
 	0x73, 0x65, 0x22, 0x00, 0x12, 0x32, 0x0a, 0x0c, 0x4c, 0x65, 0x61, 0x76, 0x65, 0x43, 0x6c, 0x75,
 	0x73, 0x74, 0x65, 0x72, 0x12, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70,
 	0x74, 0x79, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x2f, 0x0a, 0x09, 0x52, 0x65, 0x61, 0x64,
+	0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x36, 0x0a, 0x10, 0x53, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x0f, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x52, 0x65, 0x71, 0x1a, 0x0f,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -428,16 +435,20 @@ var file_raft_cmd_proto_depIdxs = []int32{
 	6,  // 7: proto.RaftCmd.Peers:input_type -> proto.EmptyReq
 	6,  // 8: proto.RaftCmd.Config:input_type -> proto.EmptyReq
 	6,  // 9: proto.RaftCmd.LeaveCluster:input_type -> proto.EmptyReq
-	7,  // 10: proto.RaftCmd.Bootstrap:output_type -> proto.Response
-	7,  // 11: proto.RaftCmd.AddVoter:output_type -> proto.Response
-	7,  // 12: proto.RaftCmd.JoinLeader:output_type -> proto.Response
-	7,  // 13: proto.RaftCmd.RemoveFollower:output_type -> proto.Response
-	7,  // 14: proto.RaftCmd.AppliedIndex:output_type -> proto.Response
-	7,  // 15: proto.RaftCmd.Peers:output_type -> proto.Response
-	7,  // 16: proto.RaftCmd.Config:output_type -> proto.Response
-	7,  // 17: proto.RaftCmd.LeaveCluster:output_type -> proto.Response
-	10, // [10:18] is the sub-list for method output_type
-	2,  // [2:10] is the sub-list for method input_type
+	6,  // 10: proto.RaftCmd.ReadIndex:input_type -> proto.EmptyReq
+	6,  // 11: proto.RaftCmd.SnapshotProgress:input_type -> proto.EmptyReq
+	7,  // 12: proto.RaftCmd.Bootstrap:output_type -> proto.Response
+	7,  // 13: proto.RaftCmd.AddVoter:output_type -> proto.Response
+	7,  // 14: proto.RaftCmd.JoinLeader:output_type -> proto.Response
+	7,  // 15: proto.RaftCmd.RemoveFollower:output_type -> proto.Response
+	7,  // 16: proto.RaftCmd.AppliedIndex:output_type -> proto.Response
+	7,  // 17: proto.RaftCmd.Peers:output_type -> proto.Response
+	7,  // 18: proto.RaftCmd.Config:output_type -> proto.Response
+	7,  // 19: proto.RaftCmd.LeaveCluster:output_type -> proto.Response
+	7,  // 20: proto.RaftCmd.ReadIndex:output_type -> proto.Response
+	7,  // 21: proto.RaftCmd.SnapshotProgress:output_type -> proto.Response
+	12, // [12:22] is the sub-list for method output_type
+	2,  // [2:12] is the sub-list for method input_type
 	2,  // [2:2] is the sub-list for extension type_name
 	2,  // [2:2] is the sub-list for extension extendee
 	0,  // [0:2] is the sub-list for field type_name
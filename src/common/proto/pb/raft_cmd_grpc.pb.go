@@ -30,6 +30,10 @@ type RaftCmdClient interface {
 	Peers(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 	Config(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 	LeaveCluster(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
+	// ReadIndex returns the log index confirmed by leader, followers read after applied it
+	ReadIndex(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
+	// SnapshotProgress returns the progress of the latest persisting or restoring snapshot in json
+	SnapshotProgress(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error)
 }
 
 type raftCmdClient struct {
@@ -112,6 +116,24 @@ func (c *raftCmdClient) LeaveCluster(ctx context.Context, in *EmptyReq, opts ...
 	return out, nil
 }
 
+func (c *raftCmdClient) ReadIndex(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/proto.RaftCmd/ReadIndex", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftCmdClient) SnapshotProgress(ctx context.Context, in *EmptyReq, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/proto.RaftCmd/SnapshotProgress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RaftCmdServer is the server API for RaftCmd service.
 // All implementations must embed UnimplementedRaftCmdServer
 // for forward compatibility
@@ -124,6 +146,10 @@ type RaftCmdServer interface {
 	Peers(context.Context, *EmptyReq) (*Response, error)
 	Config(context.Context, *EmptyReq) (*Response, error)
 	LeaveCluster(context.Context, *EmptyReq) (*Response, error)
+	// ReadIndex returns the log index confirmed by leader, followers read after applied it
+	ReadIndex(context.Context, *EmptyReq) (*Response, error)
+	// SnapshotProgress returns the progress of the latest persisting or restoring snapshot in json
+	SnapshotProgress(context.Context, *EmptyReq) (*Response, error)
 	mustEmbedUnimplementedRaftCmdServer()
 }
 
@@ -155,6 +181,12 @@ func (UnimplementedRaftCmdServer) Config(context.Context, *EmptyReq) (*Response,
 func (UnimplementedRaftCmdServer) LeaveCluster(context.Context, *EmptyReq) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LeaveCluster not implemented")
 }
+func (UnimplementedRaftCmdServer) ReadIndex(context.Context, *EmptyReq) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadIndex not implemented")
+}
+func (UnimplementedRaftCmdServer) SnapshotProgress(context.Context, *EmptyReq) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SnapshotProgress not implemented")
+}
 func (UnimplementedRaftCmdServer) mustEmbedUnimplementedRaftCmdServer() {}
 
 // UnsafeRaftCmdServer may be embedded to opt out of forward compatibility for this service.
@@ -312,6 +344,42 @@ func _RaftCmd_LeaveCluster_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RaftCmd_ReadIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftCmdServer).ReadIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RaftCmd/ReadIndex",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftCmdServer).ReadIndex(ctx, req.(*EmptyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RaftCmd_SnapshotProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmptyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftCmdServer).SnapshotProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.RaftCmd/SnapshotProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftCmdServer).SnapshotProgress(ctx, req.(*EmptyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RaftCmd_ServiceDesc is the grpc.ServiceDesc for RaftCmd service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -351,6 +419,14 @@ var RaftCmd_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "LeaveCluster",
 			Handler:    _RaftCmd_LeaveCluster_Handler,
 		},
+		{
+			MethodName: "ReadIndex",
+			Handler:    _RaftCmd_ReadIndex_Handler,
+		},
+		{
+			MethodName: "SnapshotProgress",
+			Handler:    _RaftCmd_SnapshotProgress_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "raft_cmd.proto",
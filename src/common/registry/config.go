@@ -16,6 +16,7 @@ type Config struct {
 	Interval   time.Duration `yaml:"interval" env:"INTERVAL" env-default:"5s"`
 	Timeout    time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"3s"`
 	Services   []string      `yaml:"services,omitempty" env:"SERVICES" env-separator:","`
+	Topology   Topology      `yaml:"topology" env-prefix:"TOPOLOGY"`
 	ServerPort string        `yaml:"-" env:"-"`
 }
 
@@ -13,26 +13,30 @@ import (
 )
 
 type EtcdDiscovery struct {
-	cli      *clientv3.Client
-	group    string
-	services map[string]*serviceList
-	context  context.Context
-	Close    func()
+	cli        *clientv3.Client
+	group      string
+	services   map[string]*serviceList
+	topologies map[string]*serviceList
+	context    context.Context
+	Close      func()
 }
 
 func NewEtcdDiscovery(cli *clientv3.Client, cfg *Config) *EtcdDiscovery {
 	hs := make(map[string]*serviceList)
 	ctx, cancel := context.WithCancel(context.Background())
 	d := &EtcdDiscovery{
-		cli:      cli,
-		group:    cfg.Group,
-		services: hs,
-		context:  ctx,
-		Close:    cancel,
+		cli:        cli,
+		group:      cfg.Group,
+		services:   hs,
+		topologies: make(map[string]*serviceList),
+		context:    ctx,
+		Close:      cancel,
 	}
 	for _, s := range cfg.Services {
 		d.services[s] = newServiceList()
-		d.initService(s)
+		d.topologies[s] = newServiceList()
+		d.initService(s, d.services[s], cst.EtcdPrefix.FmtRegistry(d.group, s))
+		d.initService(s, d.topologies[s], cst.EtcdPrefix.FmtTopology(d.group, s))
 	}
 	// save self
 	addr, _ := cfg.RegisterAddr()
@@ -40,11 +44,10 @@ func NewEtcdDiscovery(cli *clientv3.Client, cfg *Config) *EtcdDiscovery {
 	return d
 }
 
-func (e *EtcdDiscovery) initService(serv string) {
+func (e *EtcdDiscovery) initService(serv string, sl *serviceList, prefix string) {
 	go func() {
 		defer graceful.Recover()
 		// fetch kvs
-		prefix := cst.EtcdPrefix.FmtRegistry(e.group, serv)
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		res, err := e.cli.Get(ctx, prefix, clientv3.WithPrefix())
@@ -58,13 +61,13 @@ func (e *EtcdDiscovery) initService(serv string) {
 			mp[util.BytesToStr(kv.Key)] = util.BytesToStr(kv.Value)
 		}
 		// init serv
-		e.services[serv].combine(mp)
+		sl.combine(mp)
 		// start watch change
-		e.asyncWatch(serv, prefix)
+		e.asyncWatch(serv, sl, prefix)
 	}()
 }
 
-func (e *EtcdDiscovery) asyncWatch(serv, prefix string) {
+func (e *EtcdDiscovery) asyncWatch(serv string, sl *serviceList, prefix string) {
 	go func() {
 		defer graceful.Recover()
 		for {
@@ -83,10 +86,10 @@ func (e *EtcdDiscovery) asyncWatch(serv, prefix string) {
 					switch event.Type {
 					case mvccpb.PUT:
 						registryLog.Tracef("%s:%s added", serv, addr)
-						e.addService(serv, addr, key)
+						sl.add(addr, key)
 					case mvccpb.DELETE:
 						registryLog.Tracef("%s:%s removed", serv, key)
-						e.removeService(serv, key)
+						sl.remove(key)
 					}
 				}
 			}
@@ -172,6 +175,32 @@ func (e *EtcdDiscovery) GetServicesWith(name string, master bool) []string {
 	return arr
 }
 
+// GetTopology returns topology labels of servers. key=address. servers without labels only have Host.
+func (e *EtcdDiscovery) GetTopology(name string) map[string]Topology {
+	labels := make(map[string]string)
+	if sl, ok := e.topologies[name]; ok {
+		for k, v := range sl.copy() {
+			if idx := strings.LastIndexByte(k, '/'); idx >= 0 {
+				labels[k[idx+1:]] = v
+			}
+		}
+	}
+	mapping := e.GetServiceMapping(name)
+	res := make(map[string]Topology, len(mapping))
+	for sid, addr := range mapping {
+		var topo Topology
+		if v, ok := labels[sid]; ok {
+			t, err := UnmarshalTopology(v)
+			if err != nil {
+				registryLog.Warnf("invalid topology of %s: %s", sid, err)
+			}
+			topo = t
+		}
+		res[addr] = topo.withDefault(addr)
+	}
+	return res
+}
+
 func (e *EtcdDiscovery) addService(name string, value string, key string) {
 	if e.services[name] == nil {
 		e.services[name] = newServiceList()
@@ -40,6 +40,11 @@ func (e *EtcdRegistry) Key() string {
 	return EtcdPrefix.FmtRegistry(e.cfg.Group, e.name)
 }
 
+// TopologyKey is the key of topology labels of this server
+func (e *EtcdRegistry) TopologyKey() string {
+	return fmt.Sprint(EtcdPrefix.FmtTopology(e.cfg.Group, e.cfg.Name), "/", e.cfg.SID())
+}
+
 func (e *EtcdRegistry) AsMaster() *EtcdRegistry {
 	e.name = fmt.Sprint(e.stdName, "_", "master")
 	return e
@@ -95,10 +100,17 @@ func (e *EtcdRegistry) Register(id clientv3.LeaseID) {
 		registryLog.Errorf("register %s fails: %s", e.addr, err)
 		return
 	}
+	topo := e.cfg.Topology.withDefault(e.addr)
+	if _, err := e.cli.Put(context.Background(), e.TopologyKey(), topo.Marshal(), clientv3.WithLease(id)); err != nil {
+		registryLog.Errorf("register topology of %s fails: %s", e.addr, err)
+	}
 	registryLog.Infof("register %s success", e.Key())
 }
 
+// Unregister deletes the registry key and the topology key in one transaction
 func (e *EtcdRegistry) Unregister() error {
-	_, err := e.cli.Delete(context.Background(), e.Key())
+	_, err := e.cli.Txn(context.Background()).
+		Then(clientv3.OpDelete(e.Key()), clientv3.OpDelete(e.TopologyKey())).
+		Commit()
 	return err
 }
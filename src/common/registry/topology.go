@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DomainLevel is the level of failure domain
+type DomainLevel string
+
+const (
+	LevelZone DomainLevel = "zone"
+	LevelRack DomainLevel = "rack"
+	LevelHost DomainLevel = "host"
+)
+
+// Topology labels where a server is deployed. servers in the same zone/rack/host are considered to fail together.
+type Topology struct {
+	Zone string `yaml:"zone" env:"ZONE" json:"zone,omitempty"`
+	Rack string `yaml:"rack" env:"RACK" json:"rack,omitempty"`
+	Host string `yaml:"host" env:"HOST" json:"host,omitempty"` // Host defaults to the ip of server
+}
+
+// Domain returns the failure domain at level. the domain of a lower level includes the upper ones.
+// returns empty if not labeled at level.
+func (t Topology) Domain(level DomainLevel) string {
+	switch level {
+	case LevelZone:
+		return t.Zone
+	case LevelRack:
+		if t.Rack == "" {
+			return ""
+		}
+		return fmt.Sprint(t.Zone, "/", t.Rack)
+	case LevelHost:
+		if t.Host == "" {
+			return ""
+		}
+		return fmt.Sprint(t.Zone, "/", t.Rack, "/", t.Host)
+	default:
+		return ""
+	}
+}
+
+// withDefault fills Host with the ip of addr if not labeled
+func (t Topology) withDefault(addr string) Topology {
+	if t.Host == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			t.Host = host
+		} else {
+			t.Host = addr
+		}
+	}
+	return t
+}
+
+func (t Topology) Marshal() string {
+	bt, _ := json.Marshal(t)
+	return string(bt)
+}
+
+func UnmarshalTopology(s string) (Topology, error) {
+	var t Topology
+	err := json.Unmarshal([]byte(s), &t)
+	return t, err
+}
+
+// DomainViolation describes a failure domain holding more shards of a stripe than allowed
+type DomainViolation struct {
+	Domain string `json:"domain"`
+	Shards int    `json:"shards"`
+	Max    int    `json:"max"`
+}
+
+// CheckPlacement returns failure domains at level which hold more than max shards of locates.
+// servers not labeled at level are ignored.
+func CheckPlacement(locates []string, topo map[string]Topology, level DomainLevel, max int) []*DomainViolation {
+	return CheckRecoverable(locates, topo, level, max, func(lost []int) bool { return len(lost) <= max })
+}
+
+// CheckRecoverable returns failure domains at level whose shards of locates can't be recovered if they are lost together.
+// recoverable tells whether shards of the indexes are allowed to be lost, max is the number of any shards allowed to be lost.
+// servers not labeled at level are ignored.
+func CheckRecoverable(locates []string, topo map[string]Topology, level DomainLevel, max int, recoverable func(lost []int) bool) []*DomainViolation {
+	domains := make(map[string][]int, len(locates))
+	for i, loc := range locates {
+		t, ok := topo[loc]
+		if !ok {
+			t = Topology{}.withDefault(loc)
+		}
+		if d := t.Domain(level); d != "" {
+			domains[d] = append(domains[d], i)
+		}
+	}
+	var res []*DomainViolation
+	for d, idx := range domains {
+		if !recoverable(idx) {
+			res = append(res, &DomainViolation{Domain: strings.TrimLeft(d, "/"), Shards: len(idx), Max: max})
+		}
+	}
+	return res
+}
+
+// LRCRecoverable tells whether shards of the indexes encoded by local reconstruction codes can be lost together.
+// shards are ordered as data shards, a local parity shard of each group and global parity shards.
+// a group losing only one shard is repaired locally, data shards lost in other groups are repaired by global parity shards.
+func LRCRecoverable(dataShards, localGroups, globalParity int, lost []int) bool {
+	if localGroups <= 0 || dataShards%localGroups != 0 {
+		return len(lost) <= globalParity
+	}
+	gs := dataShards / localGroups
+	lostInGroup, lostData := make([]int, localGroups), make([]int, localGroups)
+	var unrepaired int
+	for _, i := range lost {
+		switch {
+		case i < dataShards:
+			lostInGroup[i/gs]++
+			lostData[i/gs]++
+		case i < dataShards+localGroups:
+			lostInGroup[i-dataShards]++
+		default:
+			unrepaired++
+		}
+	}
+	for g := range lostInGroup {
+		if lostInGroup[g] > 1 {
+			unrepaired += lostData[g]
+		}
+	}
+	return unrepaired <= globalParity
+}
@@ -0,0 +1,62 @@
+package registry
+
+import "testing"
+
+func TestCheckPlacement(t *testing.T) {
+	topo := map[string]Topology{
+		"10.0.0.1:80": {Zone: "a", Rack: "r1"},
+		"10.0.0.2:80": {Zone: "a", Rack: "r2"},
+		"10.0.0.3:80": {Zone: "b", Rack: "r1"},
+	}
+	locates := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.1:80"}
+	if res := CheckPlacement(locates, topo, LevelZone, 2); len(res) != 1 || res[0].Domain != "a" || res[0].Shards != 3 {
+		t.Fatalf("unexpected zone violations %+v", res)
+	}
+	if res := CheckPlacement(locates, topo, LevelRack, 2); len(res) != 0 {
+		t.Fatalf("unexpected rack violations %+v", res)
+	}
+	// unlabeled servers fallback to host level only
+	if res := CheckPlacement([]string{"10.0.0.4:80", "10.0.0.4:81"}, topo, LevelHost, 1); len(res) != 1 {
+		t.Fatalf("unexpected host violations %+v", res)
+	}
+	if res := CheckPlacement([]string{"10.0.0.4:80", "10.0.0.4:81"}, topo, LevelZone, 1); len(res) != 0 {
+		t.Fatalf("unlabeled zone should be ignored, got %+v", res)
+	}
+}
+
+func TestCheckRecoverable(t *testing.T) {
+	topo := map[string]Topology{
+		"10.0.0.1:80": {Zone: "a"},
+		"10.0.0.2:80": {Zone: "b"},
+		"10.0.0.3:80": {Zone: "c"},
+	}
+	// LRC of 4 data shards in 2 groups and 1 global parity shard: 0 1 | 2 3 | local 4 5 | global 6
+	recoverable := func(lost []int) bool { return LRCRecoverable(4, 2, 1, lost) }
+	// zone a holds a shard of each group and the global parity shard, which are repaired locally and globally
+	locates := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.3:80", "10.0.0.1:80"}
+	if res := CheckRecoverable(locates, topo, LevelZone, 1, recoverable); len(res) != 0 {
+		t.Fatalf("unexpected zone violations %+v", res)
+	}
+	// zone a holds a whole group and the global parity shard
+	locates = []string{"10.0.0.1:80", "10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.1:80"}
+	if res := CheckRecoverable(locates, topo, LevelZone, 1, recoverable); len(res) != 1 || res[0].Domain != "a" || res[0].Max != 1 {
+		t.Fatalf("unexpected zone violations %+v", res)
+	}
+	cases := []struct {
+		lost []int
+		ok   bool
+	}{
+		{[]int{0, 2, 6}, true},
+		{[]int{0, 4}, true},
+		{[]int{0, 1}, false},
+		{[]int{0, 1, 6}, false},
+		{[]int{0, 1, 2, 3}, false},
+		{[]int{0, 4, 2}, true},
+		{[]int{4, 5, 6}, true},
+	}
+	for _, c := range cases {
+		if recoverable(c.lost) != c.ok {
+			t.Errorf("lost %v expected recoverable %v", c.lost, c.ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package replication defines the rules and progress of replicating buckets to a remote cluster.
+// changes of objects are read from the change feed of metadata servers and copied to the api server of remote cluster.
+package replication
+
+import (
+	"common/proto/msg"
+	"errors"
+	"strings"
+	"time"
+)
+
+// kinds of replication keys in etcd
+const (
+	KindRule   = "rule"   // KindRule keys are rules by bucket
+	KindStatus = "status" // KindStatus keys are progresses by metadata group
+	KindRetry  = "retry"  // KindRetry keys are changes waiting to be retried by group and seq
+)
+
+// Rule replicates objects of Bucket to TargetBucket of the remote cluster
+type Rule struct {
+	Bucket       string `json:"bucket"`
+	Target       string `json:"target"`       // Target is the address of remote api server, like http://remote:8080
+	TargetBucket string `json:"targetBucket"` // TargetBucket is the bucket in remote cluster, the same as Bucket if empty
+	Username     string `json:"username"`     // Username is for basic auth of remote api server
+	Password     string `json:"password"`
+	Deletes      bool   `json:"deletes"` // Deletes replicates removing of objects
+	Disabled     bool   `json:"disabled"`
+}
+
+func (r *Rule) Validate() error {
+	if r.Bucket == "" {
+		return errors.New("bucket is required")
+	}
+	if !strings.HasPrefix(r.Target, "http://") && !strings.HasPrefix(r.Target, "https://") {
+		return errors.New("target must be an url starts with http:// or https://")
+	}
+	return nil
+}
+
+// RemoteBucket returns the bucket in remote cluster
+func (r *Rule) RemoteBucket() string {
+	if r.TargetBucket == "" {
+		return r.Bucket
+	}
+	return r.TargetBucket
+}
+
+// Status is the progress of replicating the change feed of a metadata group
+type Status struct {
+	Group      string    `json:"group"`
+	Checkpoint uint64    `json:"checkpoint"` // Checkpoint is the seq of change feed which all changes until it have been handled
+	Head       uint64    `json:"head"`       // Head is the latest seq of change feed
+	Lag        uint64    `json:"lag"`        // Lag is the number of changes not handled
+	LagMillis  int64     `json:"lagMillis"`  // LagMillis is the age of the oldest change not handled
+	Replicated int64     `json:"replicated"` // Replicated is the total number of versions copied
+	Deleted    int64     `json:"deleted"`    // Deleted is the total number of removes applied
+	Conflicts  int64     `json:"conflicts"`  // Conflicts is the total number of changes skipped because remote ones are newer
+	Failed     int64     `json:"failed"`     // Failed is the total number of changes given up after retries
+	Retrying   int       `json:"retrying"`   // Retrying is the number of changes in retry queue
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Retry is a change failed to replicate and waiting to be retried
+type Retry struct {
+	Change   *msg.Change `json:"change"`
+	Attempts int         `json:"attempts"`
+	NextAt   time.Time   `json:"nextAt"`
+	Error    string      `json:"error"`
+}
+
+// Backoff returns the delay before next attempt, doubled for every failed attempt but no more than max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
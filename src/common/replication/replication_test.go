@@ -0,0 +1,40 @@
+package replication
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0: time.Second,
+		1: time.Second,
+		2: 2 * time.Second,
+		4: 8 * time.Second,
+		9: 10 * time.Second,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts, time.Second, 10*time.Second); got != want {
+			t.Fatalf("backoff of %d attempts: want %s, got %s", attempts, want, got)
+		}
+	}
+}
+
+func TestRule(t *testing.T) {
+	r := Rule{Bucket: "photos", Target: "http://remote:8080"}
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if r.RemoteBucket() != "photos" {
+		t.Fatalf("remote bucket should be the same as bucket, got %s", r.RemoteBucket())
+	}
+	r.TargetBucket = "photos-dr"
+	if r.RemoteBucket() != "photos-dr" {
+		t.Fatalf("remote bucket should be target bucket, got %s", r.RemoteBucket())
+	}
+	for _, bad := range []Rule{{Target: "http://remote"}, {Bucket: "photos", Target: "remote:8080"}} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("rule %+v should be invalid", bad)
+		}
+	}
+}
@@ -35,6 +35,9 @@ func (mr *multiReader) Read(p []byte) (n int, err error) {
 		var nr int
 		nr, err = mr.readers[0].Read(p[n:])
 		n += nr
+		if err != nil && err != io.EOF {
+			return
+		}
 		if err == io.EOF {
 			// Use eofReader instead of nil to avoid nil panic
 			// after performing flatten (Issue 18232).
@@ -87,6 +87,19 @@ func Search[T comparable](arr []T, target T) int {
 	return -1
 }
 
+// Equal reports whether two slices have same elements in same order
+func Equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func RemoveLast[T any](arr *[]T) {
 	*arr = (*arr)[:len(*arr)-1]
 }
@@ -23,3 +23,11 @@ func TestClear(t *testing.T) {
 	Clear(&arr)
 	assert.New(t).Equal(0, len(arr))
 }
+
+func TestEqual(t *testing.T) {
+	at := assert.New(t)
+	at.True(Equal([]string{"a", "b"}, []string{"a", "b"}))
+	at.False(Equal([]string{"a", "b"}, []string{"b", "a"}))
+	at.False(Equal([]string{"a"}, []string{"a", "b"}))
+	at.True(Equal[string](nil, []string{}))
+}
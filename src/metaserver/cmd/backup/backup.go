@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"common/cache"
+	"common/cmd"
+	"context"
+	"fmt"
+	"metaserver/config"
+	"metaserver/internal/usecase/backup"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/raftimpl"
+	"metaserver/internal/usecase/repo"
+	"metaserver/internal/usecase/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/hashicorp/raft"
+)
+
+func init() {
+	cmd.Register("backup", func(args []string) {
+		if len(args) < 2 {
+			fmt.Println("should input command: backup config-file [list/restore] [..]")
+			return
+		}
+		cfg := config.ReadConfigFrom(args[0])
+		dest, err := backup.NewDestination(&cfg.Backup)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		switch args[1] {
+		case "list":
+			group := cfg.HashSlot.StoreID
+			if len(args) > 2 {
+				group = args[2]
+			}
+			list(dest, group)
+		case "restore":
+			if len(args) < 4 {
+				fmt.Println("restore require 'group output-db-file [index=N/time=RFC3339]'")
+				return
+			}
+			var target backup.Target
+			if len(args) > 4 {
+				if target, err = parseTarget(args[4]); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+			restore(dest, args[2], cfg.StorageEngine, args[3], target)
+		default:
+			fmt.Printf("no such command %s\n", args[1])
+		}
+	})
+}
+
+func parseTarget(s string) (backup.Target, error) {
+	var target backup.Target
+	var err error
+	switch {
+	case strings.HasPrefix(s, "index="):
+		target.Index, err = strconv.ParseUint(strings.TrimPrefix(s, "index="), 10, 64)
+	case strings.HasPrefix(s, "time="):
+		target.Time, err = time.Parse(time.RFC3339, strings.TrimPrefix(s, "time="))
+	default:
+		err = fmt.Errorf("target '%s' format error, require index=N or time=RFC3339", s)
+	}
+	return target, err
+}
+
+func list(dest backup.Destination, group string) {
+	manifests, err := backup.ListManifests(context.Background(), dest, group)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, m := range manifests {
+		fmt.Printf("%s\t%s\tindex %d-%d\t%d bytes\t%s\n", m.ID, m.Type, m.FromIndex, m.ToIndex, m.Size, m.LastTime.Format(time.RFC3339))
+	}
+}
+
+// restore rebuilds a db of the configured engine from backups. replace the db of a stopped server with it and
+// bootstrap a new raft cluster (without old raft logs and snapshots) to bring the group back.
+func restore(dest backup.Destination, group, engine, output string, target backup.Target) {
+	index, err := service.RestoreBackup(context.Background(), dest, group, target, engine, output, newFSM)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("restored group %s to index %d at %s\n", group, index, output)
+}
+
+func newFSM(storage *db.Storage) raft.BatchingFSM {
+	c := cache.NewCache(bigcache.DefaultConfig(time.Minute))
+	metaCache, bucketCache := repo.NewMetadataCacheRepo(c), repo.NewBucketCacheRepo(c)
+	metaRepo := repo.NewMetadataRepo(storage, metaCache)
+	bucketRepo := repo.NewBucketRepo(storage, bucketCache)
+	opsRepo := repo.NewOpsRepo(storage, metaCache, bucketCache)
+	fsm := raftimpl.NewFSM(metaRepo, repo.NewBatchRepo(storage), bucketRepo, repo.NewBatchBucketRepo(storage), repo.NewHashIndexRepo(storage), metaRepo, repo.NewChangeFeedRepo(storage, false), repo.NewEventRepo(storage, false), opsRepo)
+	return fsm.(raft.BatchingFSM)
+}
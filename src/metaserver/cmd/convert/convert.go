@@ -0,0 +1,23 @@
+package convert
+
+import (
+	"common/cmd"
+	"fmt"
+	"metaserver/internal/usecase/db"
+	"time"
+)
+
+func init() {
+	cmd.Register("convert", func(args []string) {
+		if len(args) < 3 {
+			fmt.Println("should input command: convert source-db [bolt/badger] output-db")
+			return
+		}
+		start := time.Now()
+		if err := db.ConvertFile(args[0], args[1], args[2]); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("converted %s to %s %s in %s\n", args[0], args[1], args[2], time.Since(start))
+	})
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"common/consistency"
 	"common/cst"
 	"common/datasize"
 	"common/etcd"
@@ -21,18 +22,22 @@ const (
 )
 
 type Config struct {
-	Port                 string          `yaml:"port" env:"PORT" env-default:"8090"`
-	DataDir              string          `yaml:"data-dir" env:"DATA_DIR"`
-	MaxConcurrentStreams uint32          `yaml:"max-concurrent-streams" env:"MAX_CONCURRENT_STREAMS" env-default:"100"`
-	Log                  logs.Config     `yaml:"log" env-prefix:"LOG"`
-	Cluster              ClusterConfig   `yaml:"cluster" env-prefix:"CLUSTER"`
-	Registry             registry.Config `yaml:"registry" env-prefix:"REGISTRY"`
-	Etcd                 etcd.Config     `yaml:"etcd" env-prefix:"ETCD"`
-	HashSlot             HashSlotConfig  `yaml:"hash-slot" env-prefix:"HASH_SLOT"`
-	Cache                CacheConfig     `yaml:"cache" env-prefix:"CACHE"`
-	DataPath             string          `yaml:"-" env:"-"`
-	filePath             string          `yaml:"-" env:"-"`
-	persistLock          sync.Locker     `yaml:"-" env:"-"`
+	Port                 string             `yaml:"port" env:"PORT" env-default:"8090"`
+	DataDir              string             `yaml:"data-dir" env:"DATA_DIR"`
+	MaxConcurrentStreams uint32             `yaml:"max-concurrent-streams" env:"MAX_CONCURRENT_STREAMS" env-default:"100"`
+	StorageEngine        string             `yaml:"storage-engine" env:"STORAGE_ENGINE" env-default:"bolt"` // StorageEngine is bolt (B+tree) or badger (LSM)
+	Log                  logs.Config        `yaml:"log" env-prefix:"LOG"`
+	Cluster              ClusterConfig      `yaml:"cluster" env-prefix:"CLUSTER"`
+	Registry             registry.Config    `yaml:"registry" env-prefix:"REGISTRY"`
+	Etcd                 etcd.Config        `yaml:"etcd" env-prefix:"ETCD"`
+	HashSlot             HashSlotConfig     `yaml:"hash-slot" env-prefix:"HASH_SLOT"`
+	Cache                CacheConfig        `yaml:"cache" env-prefix:"CACHE"`
+	Backup               BackupConfig       `yaml:"backup" env-prefix:"BACKUP"`
+	ChangeFeed           ChangeFeedConfig   `yaml:"change-feed" env-prefix:"CHANGE_FEED"`
+	Notification         NotificationConfig `yaml:"notification" env-prefix:"NOTIFICATION"`
+	DataPath             string             `yaml:"-" env:"-"`
+	filePath             string             `yaml:"-" env:"-"`
+	persistLock          sync.Locker        `yaml:"-" env:"-"`
 }
 
 func (c *Config) initialize(filePath string) {
@@ -45,6 +50,11 @@ func (c *Config) initialize(filePath string) {
 	c.DataPath = filepath.Join(c.DataDir, c.Registry.SID())
 	c.Cluster.StoreDir = c.DataPath
 	c.Cluster.LogLevel = string(c.Log.Level)
+	var err error
+	if c.Cluster.readLevel, err = consistency.Parse(c.Cluster.ReadConsistency); err != nil {
+		logs.Std().Warnf("invalid cluster.read-consistency, use 'any': %s", err)
+		c.Cluster.readLevel = consistency.LevelAny
+	}
 	if c.Cluster.Enable {
 		c.Cluster.ID = c.Registry.SID()
 		c.HashSlot.StoreID = c.Cluster.GroupID
@@ -81,18 +91,77 @@ type HashSlotConfig struct {
 	StoreID        string        `yaml:"-" env:"-"` //StoreID could be Cluster.GroupID or Registry.ServerId
 	Slots          []string      `yaml:"slots" env-separator:"," env-default:"0-16384"`
 	PrepareTimeout time.Duration `yaml:"prepare-timeout" env-default:"10s"`
+	MigrateWorkers int           `yaml:"migrate-workers" env-default:"4"` // MigrateWorkers is the number of streams sending keys in parallel
 }
 
 type ClusterConfig struct {
-	Enable           bool          `yaml:"enable" env:"ENABLE" env-default:"false"`
-	Bootstrap        bool          `yaml:"bootstrap" env:"BOOTSTRAP" env-default:"false"`
-	GroupID          string        `yaml:"group-id" env:"GROUP_ID" env-default:"raft"`
-	ElectionTimeout  time.Duration `yaml:"election-timeout" env:"ELECTION_TIMEOUT" env-default:"900ms"`
-	HeartbeatTimeout time.Duration `yaml:"heartbeat-timeout" env:"HEARTBEAT_TIMEOUT" env-default:"800ms"`
-	ID               string        `yaml:"-" env:"-"` //ID equals to Registry.ServerId
-	LogLevel         string        `yaml:"-" env:"-"`
-	StoreDir         string        `yaml:"-" env:"-"`
-	Nodes            []string      `yaml:"nodes" env:"NODES" env-separator:","`
+	Enable           bool           `yaml:"enable" env:"ENABLE" env-default:"false"`
+	Bootstrap        bool           `yaml:"bootstrap" env:"BOOTSTRAP" env-default:"false"`
+	GroupID          string         `yaml:"group-id" env:"GROUP_ID" env-default:"raft"`
+	ElectionTimeout  time.Duration  `yaml:"election-timeout" env:"ELECTION_TIMEOUT" env-default:"900ms"`
+	HeartbeatTimeout time.Duration  `yaml:"heartbeat-timeout" env:"HEARTBEAT_TIMEOUT" env-default:"800ms"`
+	ID               string         `yaml:"-" env:"-"` //ID equals to Registry.ServerId
+	LogLevel         string         `yaml:"-" env:"-"`
+	StoreDir         string         `yaml:"-" env:"-"`
+	Nodes            []string       `yaml:"nodes" env:"NODES" env-separator:","`
+	ReadConsistency  string         `yaml:"read-consistency" env:"READ_CONSISTENCY" env-default:"any"` // ReadConsistency is used by requests without one: any, strong or bounded-staleness=<ms>
+	ReadTimeout      time.Duration  `yaml:"read-timeout" env:"READ_TIMEOUT" env-default:"3s"`          // ReadTimeout limits waiting for catching up with leader
+	Snapshot         SnapshotConfig `yaml:"snapshot" env-prefix:"SNAPSHOT"`
+	readLevel        consistency.Level
+}
+
+// DefaultReadConsistency returns the parsed ReadConsistency
+func (c *ClusterConfig) DefaultReadConsistency() consistency.Level {
+	return c.readLevel
+}
+
+type SnapshotConfig struct {
+	Threshold    uint64            `yaml:"threshold" env:"THRESHOLD" env-default:"8192"`          // Threshold is the number of logs since last snapshot to take a new one
+	Interval     time.Duration     `yaml:"interval" env:"INTERVAL" env-default:"2m"`              // Interval is how often to check whether to take a snapshot
+	TrailingLogs uint64            `yaml:"trailing-logs" env:"TRAILING_LOGS" env-default:"10240"` // TrailingLogs is the number of logs kept after snapshot for lagging followers
+	Retain       int               `yaml:"retain" env:"RETAIN" env-default:"2"`                   // Retain is the number of full snapshots kept with their incremental ones
+	FullEvery    int               `yaml:"full-every" env:"FULL_EVERY" env-default:"8"`           // FullEvery is the number of incremental snapshots between two full ones, 0 to disable incremental snapshots
+	ChunkSize    datasize.DataSize `yaml:"chunk-size" env:"CHUNK_SIZE" env-default:"4MB"`         // ChunkSize is the max size of a checksummed chunk of snapshot
+}
+
+type BackupConfig struct {
+	Enable    bool          `yaml:"enable" env:"ENABLE"`
+	Interval  time.Duration `yaml:"interval" env:"INTERVAL" env-default:"1h"`     // Interval of taking backups
+	FullEvery int           `yaml:"full-every" env:"FULL_EVERY" env-default:"24"` // FullEvery is the number of incremental backups between two full backups
+	Retain    int           `yaml:"retain" env:"RETAIN" env-default:"7"`          // Retain is the number of full backups kept with their incremental ones
+	Dest      string        `yaml:"dest" env:"DEST" env-default:"local"`          // Dest is where to store backups: local or s3
+	Dir       string        `yaml:"dir" env:"DIR" env-default:"backup"`           // Dir is the directory of local destination
+	S3        S3Config      `yaml:"s3" env-prefix:"S3"`
+}
+
+type ChangeFeedConfig struct {
+	Enable       bool          `yaml:"enable" env:"ENABLE"`                                 // Enable records changes of objects and buckets, required by bucket replication
+	Retention    time.Duration `yaml:"retention" env:"RETENTION" env-default:"72h"`         // Retention is how long changes are kept
+	TrimInterval time.Duration `yaml:"trim-interval" env:"TRIM_INTERVAL" env-default:"10m"` // TrimInterval is how often to remove expired changes
+	ListLimit    int           `yaml:"list-limit" env:"LIST_LIMIT" env-default:"1000"`      // ListLimit is the max number of changes returned by a request
+}
+
+type NotificationConfig struct {
+	Enable         bool          `yaml:"enable" env:"ENABLE"`                                     // Enable publishes events of buckets with notification
+	Retention      time.Duration `yaml:"retention" env:"RETENTION" env-default:"72h"`             // Retention is how long events are kept
+	TrimInterval   time.Duration `yaml:"trim-interval" env:"TRIM_INTERVAL" env-default:"10m"`     // TrimInterval is how often to remove expired events
+	PollInterval   time.Duration `yaml:"poll-interval" env:"POLL_INTERVAL" env-default:"1s"`      // PollInterval is how often subscribers and webhooks check new events
+	BatchSize      int           `yaml:"batch-size" env:"BATCH_SIZE" env-default:"100"`           // BatchSize is the max number of events read at once
+	WebhookTimeout time.Duration `yaml:"webhook-timeout" env:"WEBHOOK_TIMEOUT" env-default:"10s"` // WebhookTimeout limits a webhook request
+	MaxRetry       int           `yaml:"max-retry" env:"MAX_RETRY" env-default:"8"`               // MaxRetry attempts of an event before skipping it
+	RetryBase      time.Duration `yaml:"retry-base" env:"RETRY_BASE" env-default:"1s"`            // RetryBase delay before the first retry, doubled for every attempt
+	RetryMax       time.Duration `yaml:"retry-max" env:"RETRY_MAX" env-default:"5m"`              // RetryMax max delay between two attempts
+}
+
+type S3Config struct {
+	Endpoint  string            `yaml:"endpoint" env:"ENDPOINT"` // Endpoint is like https://s3.amazonaws.com
+	Region    string            `yaml:"region" env:"REGION" env-default:"us-east-1"`
+	Bucket    string            `yaml:"bucket" env:"BUCKET"`
+	Prefix    string            `yaml:"prefix" env:"PREFIX"`
+	AccessKey string            `yaml:"access-key" env:"ACCESS_KEY"`
+	SecretKey string            `yaml:"secret-key" env:"SECRET_KEY"`
+	PathStyle bool              `yaml:"path-style" env:"PATH_STYLE" env-default:"true"` // PathStyle puts bucket in path instead of host, required by most S3-compatible services
+	PartSize  datasize.DataSize `yaml:"part-size" env:"PART_SIZE" env-default:"64MB"`   // PartSize is the size of parts uploading larger backups by multipart, at least 5MB
 }
 
 func ReadConfig() *Config {
@@ -3,6 +3,7 @@ package app
 import (
 	"common/cst"
 	"common/graceful"
+	"common/logs"
 	"common/system"
 	"common/util"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -23,23 +24,36 @@ func Run(cfg *config.Config) {
 	pool.InitPool(cfg)
 	defer pool.Close()
 	// init repos
-	metaRepo := repo.NewMetadataRepo(pool.Storage, repo.NewMetadataCacheRepo(pool.Cache))
-	bucketRepo := repo.NewBucketRepo(pool.Storage, repo.NewBucketCacheRepo(pool.Cache))
+	metaCache, bucketCache := repo.NewMetadataCacheRepo(pool.Cache), repo.NewBucketCacheRepo(pool.Cache)
+	metaRepo := repo.NewMetadataRepo(pool.Storage, metaCache)
+	bucketRepo := repo.NewBucketRepo(pool.Storage, bucketCache)
+	opsRepo := repo.NewOpsRepo(pool.Storage, metaCache, bucketCache)
+	hashIndexRepo := repo.NewHashIndexRepo(pool.Storage)
+	feedRepo := repo.NewChangeFeedRepo(pool.Storage, cfg.ChangeFeed.Enable)
+	eventRepo := repo.NewEventRepo(pool.Storage, cfg.Notification.Enable)
+	if err := metaRepo.BuildIndexes(); err != nil {
+		logs.Std().Errorf("build secondary indexes err: %s", err)
+	}
 	// init raft
-	fsm := raftimpl.NewFSM(metaRepo, repo.NewBatchRepo(pool.Storage), bucketRepo, repo.NewBatchBucketRepo(pool.Storage), metaRepo)
+	fsm := raftimpl.NewFSM(metaRepo, repo.NewBatchRepo(pool.Storage), bucketRepo, repo.NewBatchBucketRepo(pool.Storage), hashIndexRepo, metaRepo, feedRepo, eventRepo, opsRepo)
 	raftWrapper := raftimpl.NewRaft(util.ServerAddress(cfg.Port), cfg.Cluster, fsm)
 	pool.RaftWrapper = raftWrapper
 	// init services
-	bucketServ := service.NewBucketService(bucketRepo, raftWrapper)
+	bucketServ := service.NewBucketService(bucketRepo, opsRepo, feedRepo, eventRepo, raftWrapper)
 	metaService := service.NewMetadataService(
 		metaRepo,
 		repo.NewBatchRepo(pool.Storage),
-		repo.NewHashIndexRepo(pool.Storage),
+		hashIndexRepo,
+		opsRepo,
+		feedRepo,
+		eventRepo,
 		raftWrapper,
 	)
+	feedService := service.NewChangeFeedService(feedRepo, &cfg.ChangeFeed)
+	notificationService := service.NewNotificationService(eventRepo, bucketRepo, raftWrapper, cfg)
 	hsService := service.NewHashSlotService(pool.HashSlot, metaService, bucketServ, &cfg.HashSlot)
 	// init server
-	grpcServer := grpc.NewRpcServer(cfg.MaxConcurrentStreams, raftWrapper, metaService, hsService, bucketServ)
+	grpcServer := grpc.NewRpcServer(cfg.MaxConcurrentStreams, raftWrapper, metaService, hsService, bucketServ, feedService, notificationService)
 	httpServer := http.NewHttpServer(cfg.Port, grpcServer, metaService, bucketServ)
 	// auto sync sys-info
 	syncer := system.Syncer(pool.Etcd, cst.EtcdPrefix.FmtSystemInfo(cfg.Registry.Group, cfg.Registry.Name, cfg.Registry.SID()))
@@ -48,6 +62,17 @@ func Run(cfg *config.Config) {
 		_ = syncer.Sync()
 	})
 	defer syncer.StartAutoSave()()
+	// auto backup
+	if backupService, err := service.NewBackupService(pool.Storage, raftWrapper, cfg); err != nil {
+		logs.Std().Errorf("init backup service err: %s", err)
+	} else {
+		defer backupService.StartAutoBackup()()
+	}
+	// auto trim change feed
+	defer feedService.StartAutoTrim()()
+	// bucket notifications
+	defer notificationService.StartDispatch()()
+	defer notificationService.StartAutoTrim()()
 	// registry
 	if raftWrapper.Enabled {
 		pool.Registry.AsSlave()
@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc"
 	"metaserver/internal/usecase"
 	"metaserver/internal/usecase/raftimpl"
+	"metaserver/internal/usecase/service"
 )
 
 var log = logs.New("grpc-server")
@@ -18,7 +19,7 @@ type Server struct {
 }
 
 // NewRpcServer init a grpc raft server. if no available nodes return empty object
-func NewRpcServer(maxStreams uint32, rw *raftimpl.RaftWrapper, serv1 usecase.IMetadataService, serv2 usecase.IHashSlotService, serv3 usecase.BucketService) *Server {
+func NewRpcServer(maxStreams uint32, rw *raftimpl.RaftWrapper, serv1 usecase.IMetadataService, serv2 usecase.IHashSlotService, serv3 usecase.BucketService, serv4 *service.ChangeFeedService, serv5 *service.NotificationService) *Server {
 	server := grpc.NewServer(
 		util.CommonUnaryInterceptors(),
 		util.CommonStreamInterceptors(),
@@ -29,6 +30,7 @@ func NewRpcServer(maxStreams uint32, rw *raftimpl.RaftWrapper, serv1 usecase.IMe
 			CheckRaftEnabledUnary,
 			CheckRaftLeaderUnary,
 			CheckRaftNonLeaderUnary,
+			CheckReadConsistencyUnary,
 		),
 		grpc.ChainStreamInterceptor(
 			CheckWritableStreaming,
@@ -43,7 +45,7 @@ func NewRpcServer(maxStreams uint32, rw *raftimpl.RaftWrapper, serv1 usecase.IMe
 	// register services
 	// grpc_health_v1.RegisterHealthServer(server, health.NewServer())
 	pb.RegisterHashSlotServer(server, NewHashSlotServer(serv2))
-	pb.RegisterMetadataApiServer(server, NewMetadataApiServer(serv1, serv3))
+	pb.RegisterMetadataApiServer(server, NewMetadataApiServer(serv1, serv3, serv4, serv5))
 	pb.RegisterConfigServiceServer(server, &ConfigServiceServer{})
 	return &Server{server}
 }
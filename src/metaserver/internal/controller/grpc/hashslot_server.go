@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"metaserver/internal/entity"
 	"metaserver/internal/usecase"
 )
 
@@ -20,9 +21,12 @@ func NewHashSlotServer(serv usecase.IHashSlotService) *HashSlotServer {
 }
 
 func (h *HashSlotServer) PrepareMigration(_ context.Context, req *pb.PrepareReq) (*pb.Response, error) {
-	if err := h.Service.PrepareMigrationFrom(req.GetLocation(), req.GetSlots()); err != nil {
+	if err := h.Service.PrepareMigrationFrom(req.GetLocation(), req.GetSlots(), req.GetFinishByRpc()); err != nil {
 		return &pb.Response{Success: false, Message: err.Error()}, nil
 	}
+	if req.GetFinishByRpc() {
+		return &pb.Response{Success: true, Message: entity.AckFinishByRpc}, nil
+	}
 	return okResp, nil
 }
 
@@ -36,17 +40,21 @@ func (h *HashSlotServer) StartMigration(_ context.Context, req *pb.MigrationReq)
 	return okResp, nil
 }
 
+// StreamingReceive receives items from one of the streams of source. migration is finished by FinishMigration,
+// or when the stream closed if source is of old version.
 func (h *HashSlotServer) StreamingReceive(stream pb.HashSlot_StreamingReceiveServer) (err error) {
-	defer func() {
-		if err != nil {
-			log.Errorf("stream receive abort, migrate failed: %s", err)
-			if err2 := h.Service.FinishReceiveItem(false); err2 != nil {
-				err = fmt.Errorf("%w: %s", err2, err)
+	if h.Service.FinishOnClose() {
+		defer func() {
+			if err != nil {
+				log.Errorf("stream receive abort, migrate failed: %s", err)
+				if err2 := h.Service.FinishReceiveItem(false); err2 != nil {
+					err = fmt.Errorf("%w: %s", err2, err)
+				}
+			} else if err = h.Service.FinishReceiveItem(true); err == nil {
+				log.Info("stream closed, migrate success")
 			}
-		} else if err = h.Service.FinishReceiveItem(true); err == nil {
-			log.Info("stream closed, migrate success")
-		}
-	}()
+		}()
+	}
 	var resp pb.Response
 	var item *pb.MigrationItem
 	for {
@@ -58,6 +66,7 @@ func (h *HashSlotServer) StreamingReceive(stream pb.HashSlot_StreamingReceiveSer
 		}
 		// if client side abort
 		if err != nil {
+			log.Errorf("stream receive abort: %s", err)
 			return
 		}
 		if err = h.Service.ReceiveItem(item); err != nil {
@@ -68,6 +77,14 @@ func (h *HashSlotServer) StreamingReceive(stream pb.HashSlot_StreamingReceiveSer
 	}
 }
 
+func (h *HashSlotServer) FinishMigration(_ context.Context, req *pb.FinishReq) (*pb.Response, error) {
+	if err := h.Service.FinishReceiveItem(req.GetSuccess()); err != nil {
+		return &pb.Response{Success: false, Message: err.Error()}, nil
+	}
+	log.Infof("migration finished, success=%t", req.GetSuccess())
+	return okResp, nil
+}
+
 func (h *HashSlotServer) GetCurrentSlots(_ context.Context, _ *pb.EmptyReq) (*pb.Response, error) {
 	mp, err := h.Service.GetCurrentSlots(true)
 	if err != nil {
@@ -6,24 +6,30 @@ import (
 	"common/response"
 	"common/util"
 	"context"
+	"errors"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/tinylib/msgp/msgp"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"metaserver/internal/usecase"
 	"metaserver/internal/usecase/logic"
+	"metaserver/internal/usecase/pool"
+	"metaserver/internal/usecase/service"
+	"strings"
 )
 
 type MetadataApiServer struct {
 	pb.UnimplementedMetadataApiServer
 	Service       usecase.IMetadataService
 	BucketService usecase.BucketService
+	ChangeFeed    *service.ChangeFeedService
+	Notification  *service.NotificationService
 }
 
 var emp = new(pb.Empty)
 
-func NewMetadataApiServer(s usecase.IMetadataService, b usecase.BucketService) *MetadataApiServer {
-	return &MetadataApiServer{Service: s, BucketService: b}
+func NewMetadataApiServer(s usecase.IMetadataService, b usecase.BucketService, cf *service.ChangeFeedService, nt *service.NotificationService) *MetadataApiServer {
+	return &MetadataApiServer{Service: s, BucketService: b, ChangeFeed: cf, Notification: nt}
 }
 
 func (m *MetadataApiServer) GetVersionsByHash(_ context.Context, req *pb.MetaReq) (*pb.Msgpack, error) {
@@ -166,6 +172,242 @@ func (m *MetadataApiServer) RemoveVersion(_ context.Context, req *pb.MetaReq) (*
 	return emp, nil
 }
 
+func (m *MetadataApiServer) TouchVersion(_ context.Context, req *pb.MetaReq) (*pb.Empty, error) {
+	if req.Id == "" || req.Version <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "metadata id and version required")
+	}
+	if err := m.Service.TouchVersion(req.Id, int(req.Version)); err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return emp, nil
+}
+
+// MarkVersion sets the replication status of version which is the 'replication' field of msgpack
+func (m *MetadataApiServer) MarkVersion(_ context.Context, req *pb.Metadata) (*pb.Empty, error) {
+	if req.Id == "" || req.Version <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "metadata id and version required")
+	}
+	var md msg.Version
+	if err := ShouldBindMsgpack(&md, req.Msgpack); err != nil {
+		return nil, response.GRPCError(err)
+	}
+	if err := m.Service.MarkVersion(req.Id, int(req.Version), md.Replication); err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return emp, nil
+}
+
+// ListChanges returns changes of change feed after the sequence
+func (m *MetadataApiServer) ListChanges(_ context.Context, req *pb.ChangeReq) (*pb.ChangeResp, error) {
+	changes, head, err := m.ChangeFeed.List(req.After, int(req.Limit))
+	if errors.Is(err, service.ErrChangeFeedDisabled) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	resp := &pb.ChangeResp{Head: head, Group: pool.Config.HashSlot.StoreID, Items: make([][]byte, 0, len(changes))}
+	for _, c := range changes {
+		bt, err := util.EncodeMsgp(c)
+		if err != nil {
+			return nil, response.GRPCError(err)
+		}
+		resp.Items = append(resp.Items, bt)
+	}
+	return resp, nil
+}
+
+// Subscribe streams events of bucket until client cancels
+func (m *MetadataApiServer) Subscribe(req *pb.SubscribeReq, stream pb.MetadataApi_SubscribeServer) error {
+	err := m.Notification.Subscribe(stream.Context(), req.Consumer, req.Bucket, req.After, func(evs []*msg.Event) error {
+		items := make([][]byte, 0, len(evs))
+		for _, ev := range evs {
+			bt, err := util.EncodeMsgp(ev)
+			if err != nil {
+				return err
+			}
+			items = append(items, bt)
+		}
+		return stream.Send(&pb.EventBatch{Items: items})
+	})
+	if errors.Is(err, service.ErrNotificationDisabled) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return response.GRPCError(err)
+	}
+	return nil
+}
+
+func (m *MetadataApiServer) SwapVersion(_ context.Context, req *pb.Metadata) (*pb.Empty, error) {
+	if req.Id == "" || req.Version <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "metadata id and version required")
+	}
+	var md msg.Version
+	if err := ShouldBindMsgpack(&md, req.Msgpack); err != nil {
+		return nil, response.GRPCError(err)
+	}
+	md.Sequence = uint64(req.Version)
+	if err := m.Service.SwapVersion(req.Id, &md); err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return emp, nil
+}
+
+func (m *MetadataApiServer) ListColdVersion(_ context.Context, req *pb.ColdReq) (*pb.ColdResp, error) {
+	if req.Before <= 0 || req.Limit <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "before and limit must gt 0")
+	}
+	keys, vers, err := m.Service.ListColdVersions(req.Before, req.Cursor, int(req.Limit))
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return toColdResp(req.Cursor, keys, vers)
+}
+
+// ScanVersions lists all versions page by page
+func (m *MetadataApiServer) ScanVersions(_ context.Context, req *pb.ScanReq) (*pb.ColdResp, error) {
+	if req.Limit <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must gt 0")
+	}
+	keys, vers, err := m.Service.ScanVersions(req.Cursor, int(req.Limit))
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return toColdResp(req.Cursor, keys, vers)
+}
+
+// toColdResp encodes versions without inline data and their keys "id.sequence", cursor is the last key
+func toColdResp(cursor string, keys []string, vers []*msg.Version) (*pb.ColdResp, error) {
+	resp := &pb.ColdResp{Cursor: cursor, Items: make([]*pb.Metadata, 0, len(vers))}
+	for i, v := range vers {
+		v.Inline = nil
+		bt, err := util.EncodeMsgp(v)
+		if err != nil {
+			return nil, response.GRPCError(err)
+		}
+		idx := strings.LastIndexByte(keys[i], '.')
+		resp.Items = append(resp.Items, &pb.Metadata{Id: keys[i][:idx], Version: int32(v.Sequence), Msgpack: bt})
+		resp.Cursor = keys[i]
+	}
+	return resp, nil
+}
+
+// maxQueryLimit is the max number of versions returned by a query
+const maxQueryLimit = 1000
+
+// QueryVersions finds versions of bucket by secondary indexes
+func (m *MetadataApiServer) QueryVersions(_ context.Context, req *pb.QueryReq) (*pb.QueryResp, error) {
+	if req.Bucket == "" || req.Limit <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "bucket required and limit must gt 0")
+	}
+	switch req.Sort {
+	case "", msg.SortByName, msg.SortBySize, msg.SortByTs:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort field %s", req.Sort)
+	}
+	if _, err := msg.ParseQueryCursor(req.Cursor); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	q := &msg.Query{
+		Bucket:      req.Bucket,
+		MinSize:     req.MinSize,
+		MaxSize:     req.MaxSize,
+		After:       req.After,
+		Before:      req.Before,
+		ContentType: req.ContentType,
+		Tags:        req.Tags,
+		Sort:        req.Sort,
+		Desc:        req.Desc,
+		Limit:       util.IfElse(req.Limit > maxQueryLimit, maxQueryLimit, int(req.Limit)),
+		Cursor:      req.Cursor,
+	}
+	keys, vers, err := m.Service.QueryVersions(q)
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	resp := &pb.QueryResp{Cursor: req.Cursor, Items: make([]*pb.Metadata, 0, len(vers))}
+	for i, v := range vers {
+		// inline data is read by GetVersion only
+		v.Inline = nil
+		bt, err := util.EncodeMsgp(v)
+		if err != nil {
+			return nil, response.GRPCError(err)
+		}
+		idx := strings.LastIndexByte(keys[i], '.')
+		resp.Items = append(resp.Items, &pb.Metadata{Id: keys[i][:idx], Version: int32(v.Sequence), Msgpack: bt})
+		resp.Cursor = q.CursorOf(keys[i], v).String()
+	}
+	return resp, nil
+}
+
+// maxBatchOps is the max number of ops in a batch
+const maxBatchOps = 10000
+
+// Batch applies mutations of ops in a raft entry and a transaction. ops are msgpack of changes whose keys are on this server.
+func (m *MetadataApiServer) Batch(_ context.Context, req *pb.BatchReq) (*pb.BatchResp, error) {
+	if len(req.Ops) == 0 || len(req.Ops) > maxBatchOps {
+		return nil, status.Errorf(codes.InvalidArgument, "number of ops must be in [1, %d]", maxBatchOps)
+	}
+	changes := make([]*msg.Change, len(req.Ops))
+	for i, op := range req.Ops {
+		var c msg.Change
+		if err := ShouldBindMsgpack(&c, op.Msgpack); err != nil {
+			return nil, response.GRPCError(err)
+		}
+		// keys are checked by id of op
+		c.Id = op.Id
+		changes[i] = &c
+	}
+	res, err := m.Service.Batch(changes)
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	resp := &pb.BatchResp{Versions: make([]int32, len(res.Versions)), Removed: make([][]byte, len(res.Removed))}
+	for i, seq := range res.Versions {
+		resp.Versions[i] = int32(seq)
+	}
+	for i, ver := range res.Removed {
+		if resp.Removed[i], err = util.EncodeMsgp(ver); err != nil {
+			return nil, response.GRPCError(err)
+		}
+	}
+	return resp, nil
+}
+
+func (m *MetadataApiServer) LocateHash(_ context.Context, req *pb.MetaReq) (*pb.HashRef, error) {
+	if req.Hash == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash value required")
+	}
+	ref, err := m.Service.LocateHash(req.Hash)
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return &pb.HashRef{Hash: ref.Hash, Count: ref.Count, Locate: ref.Locate}, nil
+}
+
+func (m *MetadataApiServer) ReferHash(_ context.Context, req *pb.HashRef) (*pb.HashRef, error) {
+	if req.Hash == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash value required")
+	}
+	ref, err := m.Service.ReferHash(&msg.HashRef{Hash: req.Hash, Locate: req.Locate, Count: req.Count})
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return &pb.HashRef{Hash: ref.Hash, Count: ref.Count, Locate: ref.Locate}, nil
+}
+
+func (m *MetadataApiServer) DereferHash(_ context.Context, req *pb.MetaReq) (*pb.HashRef, error) {
+	if req.Hash == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash value required")
+	}
+	ref, err := m.Service.DereferHash(req.Hash)
+	if err != nil {
+		return nil, response.GRPCError(err)
+	}
+	return &pb.HashRef{Hash: ref.Hash, Count: ref.Count, Locate: ref.Locate}, nil
+}
+
 func (m *MetadataApiServer) GetPeers(context.Context, *pb.Empty) (*pb.Strings, error) {
 	peers, err := logic.NewPeers().GetPeers()
 	if err != nil {
@@ -2,10 +2,14 @@ package grpc
 
 import (
 	"common/collection/set"
+	"common/consistency"
 	"common/proto/pb"
+	"common/util"
 	"context"
+	"metaserver/internal/entity"
 	"metaserver/internal/usecase/logic"
 	"metaserver/internal/usecase/pool"
+	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -17,11 +21,13 @@ var checkRaftEnabledMethods = set.OfString([]string{
 	"/proto.RaftCmd/AddVoter",
 	"/proto.RaftCmd/RemoveFollower",
 	"/proto.RaftCmd/LeaveCluster",
+	"/proto.RaftCmd/ReadIndex",
 })
 
 var checkRaftLeaderMethods = set.OfString([]string{
 	"/proto.RaftCmd/AddVoter",
 	"/proto.RaftCmd/RemoveFollower",
+	"/proto.RaftCmd/ReadIndex",
 })
 
 var checkRaftNonLeaderMethods = set.OfString([]string{
@@ -32,11 +38,36 @@ var checkWritableMethods = set.OfString([]string{
 	"/proto.HashSlot/StartMigration",
 	"/proto.HashSlot/PrepareMigration",
 	"/proto.HashSlot/StreamingReceive",
+	"/proto.HashSlot/FinishMigration",
 	"/proto.MetadataApi/SaveVersion",
 	"/proto.MetadataApi/SaveMetadata",
 	"/proto.MetadataApi/UpdateMetadata",
 	"/proto.MetadataApi/SaveBucket",
 	"/proto.MetadataApi/RemoveVersion",
+	"/proto.MetadataApi/ReferHash",
+	"/proto.MetadataApi/DereferHash",
+	"/proto.MetadataApi/TouchVersion",
+	"/proto.MetadataApi/MarkVersion",
+	"/proto.MetadataApi/SwapVersion",
+	"/proto.MetadataApi/Subscribe",
+	"/proto.MetadataApi/Batch",
+})
+
+// checkReadConsistencyMethods are reads served according to the read consistency of request
+var checkReadConsistencyMethods = set.OfString([]string{
+	"/proto.MetadataApi/GetMetadata",
+	"/proto.MetadataApi/GetVersion",
+	"/proto.MetadataApi/ListVersion",
+	"/proto.MetadataApi/GetBucket",
+	"/proto.MetadataApi/GetVersionsByHash",
+	"/proto.MetadataApi/LocateHash",
+})
+
+// checkHashSlotMethods are methods whose key slot is calculated by hash instead of id
+var checkHashSlotMethods = set.OfString([]string{
+	"/proto.MetadataApi/LocateHash",
+	"/proto.MetadataApi/ReferHash",
+	"/proto.MetadataApi/DereferHash",
 })
 
 func CheckRaftEnabledUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
@@ -57,20 +88,29 @@ func CheckRaftLeaderUnary(ctx context.Context, req interface{}, info *grpc.Unary
 	return handler(ctx, req)
 }
 
+// CheckWritableUnary rejects writes to slots frozen by migration and tracks writes to migrating slots.
+// keys in other slots are always writable while migrating.
 func CheckWritableUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-	if checkWritableMethods.Contains(info.FullMethod) {
-		if pool.RaftWrapper.Enabled && !pool.RaftWrapper.IsLeader() {
-			return nil, status.Error(codes.Unavailable, "server is not writable")
-		}
-		if info.FullMethod == "/proto.HashSlot/StreamingReceive" {
-			goto RETURNS
-		}
-		if !pool.HashSlot.IsNormal() {
-			return nil, status.Error(codes.Unavailable, "server in migration")
-		}
+	if !checkWritableMethods.Contains(info.FullMethod) {
+		return handler(ctx, req)
 	}
-RETURNS:
-	return handler(ctx, req)
+	if pool.RaftWrapper.Enabled && !pool.RaftWrapper.IsLeader() {
+		return nil, status.Error(codes.Unavailable, "server is not writable")
+	}
+	var release func(bool)
+	var err error
+	if r, ok := req.(*pb.BatchReq); ok {
+		release, err = pool.HashSlot.AcquireWrites(batchKeys(r))
+	} else {
+		key, dest := writingKey(info.FullMethod, req)
+		release, err = pool.HashSlot.AcquireWrite(key, dest)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp, err := handler(ctx, req)
+	release(err == nil)
+	return resp, err
 }
 
 func CheckWritableStreaming(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
@@ -78,15 +118,57 @@ func CheckWritableStreaming(srv interface{}, ss grpc.ServerStream, info *grpc.St
 		if pool.RaftWrapper.Enabled && !pool.RaftWrapper.IsLeader() {
 			return status.Error(codes.Unavailable, "server is not writable")
 		}
-		if info.FullMethod == "/proto.HashSlot/StreamingReceive" {
-			goto RETURNS
+	}
+	return handler(srv, ss)
+}
+
+// writingKey returns the key and its type written by method
+func writingKey(method string, req interface{}) (string, entity.Dest) {
+	switch r := req.(type) {
+	case *pb.HashRef:
+		return r.Hash, entity.DestHashRef
+	case *pb.MetaReq:
+		if checkHashSlotMethods.Contains(method) {
+			return r.Hash, entity.DestHashRef
 		}
-		if !pool.HashSlot.IsNormal() {
-			return status.Error(codes.Unavailable, "server in migration")
+		return r.Id, entity.DestMetadata
+	case *pb.Metadata:
+		if method == "/proto.MetadataApi/SaveBucket" {
+			return r.Id, entity.DestBucket
 		}
+		return r.Id, entity.DestMetadata
 	}
-RETURNS:
-	return handler(srv, ss)
+	return "", 0
+}
+
+// batchKeys returns keys written by ops of batch. ids of metadata are "bucket/name", others are names of buckets.
+func batchKeys(req *pb.BatchReq) map[string]entity.Dest {
+	keys := make(map[string]entity.Dest, len(req.Ops))
+	for _, op := range req.Ops {
+		keys[op.Id] |= util.IfElse(strings.Contains(op.Id, "/"), entity.DestMetadata, entity.DestBucket)
+	}
+	return keys
+}
+
+// CheckReadConsistencyUnary waits until this server catches up with the read consistency of request.
+// requests without read consistency use the default one of config.
+func CheckReadConsistencyUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !checkReadConsistencyMethods.Contains(info.FullMethod) || !pool.RaftWrapper.Enabled {
+		return handler(ctx, req)
+	}
+	level, ok, err := consistency.FromIncomingContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !ok {
+		level = pool.Config.Cluster.DefaultReadConsistency()
+	}
+	rctx, cancel := context.WithTimeout(ctx, pool.Config.Cluster.ReadTimeout)
+	defer cancel()
+	if err = pool.RaftWrapper.ConsistentRead(rctx, level); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return handler(ctx, req)
 }
 
 func CheckRaftNonLeaderUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
@@ -98,10 +180,22 @@ func CheckRaftNonLeaderUnary(ctx context.Context, req interface{}, info *grpc.Un
 	return handler(ctx, req)
 }
 
-func CheckKeySlot(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+func CheckKeySlot(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	if req == nil {
 		return nil, status.Error(codes.InvalidArgument, "request value can not be nil")
 	}
+	if checkHashSlotMethods.Contains(info.FullMethod) {
+		if err := checkHashSlot(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	if r, ok := req.(*pb.BatchReq); ok {
+		if err := checkKeySlotBatch(r); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
 	r, ok := req.(*pb.MetaReq)
 	if !ok {
 		if err := checkKeySlotMetadata(req); err != nil {
@@ -133,3 +227,34 @@ func checkKeySlotMetadata(req interface{}) error {
 	}
 	return status.Error(codes.Aborted, logic.NewDiscovery().PeerIp(other))
 }
+
+// checkKeySlotBatch requires all keys of batch on this server
+func checkKeySlotBatch(req *pb.BatchReq) error {
+	for _, op := range req.Ops {
+		if op.Id == "" {
+			continue
+		}
+		if ok, other := logic.NewHashSlot().IsKeyOnThisServer(op.Id); !ok {
+			return status.Error(codes.Aborted, logic.NewDiscovery().PeerIp(other))
+		}
+	}
+	return nil
+}
+
+func checkHashSlot(req interface{}) error {
+	var hash string
+	switch r := req.(type) {
+	case *pb.MetaReq:
+		hash = r.Hash
+	case *pb.HashRef:
+		hash = r.Hash
+	}
+	if hash == "" {
+		return nil
+	}
+	ok, other := logic.NewHashSlot().IsKeyOnThisServer(hash)
+	if ok {
+		return nil
+	}
+	return status.Error(codes.Aborted, logic.NewDiscovery().PeerIp(other))
+}
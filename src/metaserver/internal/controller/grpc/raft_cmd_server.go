@@ -98,7 +98,7 @@ func (rcs *RaftCmdServerImpl) AddVoter(_ context.Context, req *pb.AddVoterReq) (
 		if nodes.Contains(node) {
 			continue
 		}
-		pool.Config.Cluster.Nodes = append(pool.Config.Cluster.Nodes)
+		pool.Config.Cluster.Nodes = append(pool.Config.Cluster.Nodes, node)
 	}
 	// persist config async
 	go func() {
@@ -211,6 +211,28 @@ func (rcs *RaftCmdServerImpl) AppliedIndex(_ context.Context, _ *pb.EmptyReq) (*
 	return &pb.Response{Success: true, Message: fmt.Sprint(rcs.rf.Raft.AppliedIndex())}, nil
 }
 
+func (rcs *RaftCmdServerImpl) ReadIndex(ctx context.Context, _ *pb.EmptyReq) (*pb.Response, error) {
+	idx, err := rcs.rf.ReadIndex(ctx)
+	if err != nil {
+		return &pb.Response{Success: false, Message: err.Error()}, nil
+	}
+	return &pb.Response{Success: true, Message: fmt.Sprint(idx)}, nil
+}
+
+func (rcs *RaftCmdServerImpl) SnapshotProgress(context.Context, *pb.EmptyReq) (*pb.Response, error) {
+	stats := rcs.rf.Raft.Stats()
+	bt, err := json.Marshal(map[string]any{
+		"progress":          pool.Storage.SnapshotProgress(),
+		"lastSnapshotIndex": stats["last_snapshot_index"],
+		"lastSnapshotTerm":  stats["last_snapshot_term"],
+		"appliedIndex":      stats["applied_index"],
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Response{Success: true, Message: util.BytesToStr(bt)}, nil
+}
+
 func (rcs *RaftCmdServerImpl) Peers(_ context.Context, _ *pb.EmptyReq) (*pb.Response, error) {
 	servers := rcs.rf.Raft.GetConfiguration().Configuration().Servers
 	res := make([]string, 0, len(servers)-1)
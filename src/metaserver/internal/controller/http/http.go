@@ -24,6 +24,7 @@ func NewHttpServer(port string, grpcServer *grpc.Server, service IMetadataServic
 		CheckInNormal,
 		CheckLeaderInRaftMode,
 		CheckKeySlot,
+		CheckReadConsistency,
 	)
 	engine.UseRawPath = true
 	engine.UnescapePathValues = true
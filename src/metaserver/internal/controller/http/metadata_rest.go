@@ -25,6 +25,8 @@ func (m *MetadataController) RegisterRoute(engine gin.IRouter) {
 	engine.GET("/metadata/:name", m.Get)
 	engine.DELETE("/metadata/:name", m.Delete)
 	engine.GET("/metadata/list", m.List)
+	engine.GET("/metadata/stat", m.Stat)
+	engine.GET("/metadata/load", m.Load)
 }
 
 func (m *MetadataController) Post(g *gin.Context) {
@@ -122,3 +124,28 @@ func (m *MetadataController) List(c *gin.Context) {
 		Header(gin.H{"X-Total-Count": total}).
 		JSON(res)
 }
+
+// Stat aggregates sizes and compression of versions in a bucket on this server
+// Load responses the load of data stored on this server
+func (m *MetadataController) Load(c *gin.Context) {
+	load, err := m.service.Load()
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(load, c)
+}
+
+func (m *MetadataController) Stat(c *gin.Context) {
+	bucket := c.Query("bucket")
+	if bucket == "" {
+		response.BadRequestMsg("query 'bucket' required", c)
+		return
+	}
+	stat, err := m.service.StatVersions(bucket)
+	if err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	response.OkJson(stat, c)
+}
@@ -1,11 +1,16 @@
 package http
 
 import (
+	"common/collection/set"
+	"common/consistency"
 	"common/response"
+	"context"
 	"github.com/gin-gonic/gin"
+	"metaserver/internal/entity"
 	"metaserver/internal/usecase/logic"
 	"metaserver/internal/usecase/pool"
 	"net/http"
+	"strings"
 )
 
 func isWriteMethod(method string) bool {
@@ -25,13 +30,58 @@ func CheckLeaderInRaftMode(c *gin.Context) {
 	c.Next()
 }
 
+// checkReadConsistencyRoutes are reads served according to the read consistency of request,
+// the same as the ones of gRPC. scans and stats are always served locally.
+var checkReadConsistencyRoutes = set.OfString([]string{
+	"/metadata/:name",
+	"/metadata_version/:name",
+	"/metadata_version/:name/list",
+	"/bucket/:name",
+})
+
+// CheckReadConsistency waits until this server catches up with the read consistency in header before reading
+func CheckReadConsistency(c *gin.Context) {
+	if c.Request.Method != http.MethodGet || !pool.RaftWrapper.Enabled || !checkReadConsistencyRoutes.Contains(c.FullPath()) {
+		c.Next()
+		return
+	}
+	level := pool.Config.Cluster.DefaultReadConsistency()
+	if h := c.GetHeader(consistency.Header); h != "" {
+		var err error
+		if level, err = consistency.Parse(h); err != nil {
+			response.Exec(c).Fail(http.StatusBadRequest, err.Error())
+			c.Abort()
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pool.Config.Cluster.ReadTimeout)
+	defer cancel()
+	if err := pool.RaftWrapper.ConsistentRead(ctx, level); err != nil {
+		response.Exec(c).Fail(http.StatusServiceUnavailable, err.Error())
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// CheckInNormal rejects writes to slots frozen by migration and tracks writes to migrating slots
 func CheckInNormal(c *gin.Context) {
-	if isWriteMethod(c.Request.Method) && !pool.HashSlot.IsNormal() {
-		response.Exec(c).Fail(http.StatusConflict, "server in migration")
+	if !isWriteMethod(c.Request.Method) {
+		c.Next()
+		return
+	}
+	dest := entity.DestMetadata
+	if strings.HasPrefix(c.FullPath(), "/bucket") {
+		dest = entity.DestBucket
+	}
+	release, err := pool.HashSlot.AcquireWrite(c.Param("name"), dest)
+	if err != nil {
+		response.Exec(c).Fail(http.StatusConflict, err.Error())
 		c.Abort()
 		return
 	}
 	c.Next()
+	release(c.Writer.Status() < http.StatusBadRequest)
 }
 
 func CheckKeySlot(c *gin.Context) {
@@ -0,0 +1,7 @@
+package entity
+
+// StoreLoad is the load of data stored on this server, it's used to balance slots between groups.
+type StoreLoad struct {
+	Keys  int64 `json:"keys"`  // Keys is the number of metadata
+	Bytes int64 `json:"bytes"` // Bytes is the size of database files
+}
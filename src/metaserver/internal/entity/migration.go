@@ -0,0 +1,27 @@
+package entity
+
+// AckFinishByRpc is the message of response to a prepare request which finishes migration by rpc.
+// target of old version responses "ok" and finishes migration when stream closed.
+const AckFinishByRpc = "finish-by-rpc"
+
+// MigrationCheckpoint is the progress of migrating slots to other group. a broken migration continues from it.
+type MigrationCheckpoint struct {
+	Host    string          `json:"host"`
+	Port    string          `json:"port"`
+	Slots   []string        `json:"slots"`
+	Cursors map[Dest]string `json:"cursors"` // Cursors is the max key of each dest which all keys before it have been migrated
+	Dirty   map[string]Dest `json:"dirty"`   // Dirty is saved by old versions, dirty keys are persisted before writing now
+}
+
+// IsSame reports whether the checkpoint belongs to the migration of slots to host:port
+func (c *MigrationCheckpoint) IsSame(host, port string, slots []string) bool {
+	if c.Host != host || c.Port != port || len(c.Slots) != len(slots) {
+		return false
+	}
+	for i := range slots {
+		if c.Slots[i] != slots[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,9 @@
 package entity
 
-import "common/proto/msg"
+import (
+	"common/proto/msg"
+	"fmt"
+)
 
 //go:generate msgp -tests=false #metaserver/entity
 
@@ -12,6 +15,9 @@ const (
 	LogRemove
 	LogUpdate
 	LogMigrate
+	LogTouch
+	LogSwap
+	LogMark
 )
 
 const (
@@ -19,15 +25,94 @@ const (
 	DestVersionAll
 	DestMetadata
 	DestBucket
+	DestHashRef
+	DestEventCursor // DestEventCursor is the acknowledged seq of an event consumer, or the seq events are trimmed until
+	DestBatch       // DestBatch applies all Ops in a transaction
 )
 
 type RaftData struct {
-	Type     LogType       `msg:"type" json:"type"`
-	Dest     Dest          `msg:"dest" json:"dest"`
-	Name     string        `msg:"name" json:"name"`
-	Sequence uint64        `msg:"sequence" json:"sequence,omitempty"`
-	Version  *msg.Version  `msg:"version" json:"version,omitempty"`
-	Metadata *msg.Metadata `msg:"metadata" json:"metadata,omitempty"`
-	Bucket   *msg.Bucket   `msg:"bucket" json:"bucket,omitempty"`
-	Batch    bool          `msg:"-" json:"-"`
+	Type     LogType        `msg:"type" json:"type"`
+	Dest     Dest           `msg:"dest" json:"dest"`
+	Name     string         `msg:"name" json:"name"`
+	Sequence uint64         `msg:"sequence" json:"sequence,omitempty"`
+	Version  *msg.Version   `msg:"version" json:"version,omitempty"`
+	Metadata *msg.Metadata  `msg:"metadata" json:"metadata,omitempty"`
+	Bucket   *msg.Bucket    `msg:"bucket" json:"bucket,omitempty"`
+	HashRef  *msg.HashRef   `msg:"hash_ref" json:"hashRef,omitempty"`
+	Expect   int64          `msg:"expect" json:"expect,omitempty"` // Expect is the timestamp the target must have for LogSwap
+	Ops      []*RaftData    `msg:"ops" json:"ops,omitempty"`       // Ops are mutations of DestBatch
+	Batch    bool           `msg:"-" json:"-"`
+	Removed  []*msg.Version `msg:"-" json:"-"` // Removed are versions removed by the op in DestBatch, set on applying
+}
+
+// BatchResult is the result of applying ops of DestBatch
+type BatchResult struct {
+	Versions []int          // Versions are sequences of versions inserted by ops at the same index, zero for other ops
+	Removed  []*msg.Version // Removed are versions removed by ops, objects of them should be dereferenced
+}
+
+// BatchResultOf returns the result of applied ops
+func BatchResultOf(ops []*RaftData) *BatchResult {
+	res := &BatchResult{Versions: make([]int, len(ops))}
+	for i, op := range ops {
+		if op.Dest == DestVersion && op.Type == LogInsert && op.Version != nil {
+			res.Versions[i] = int(op.Version.Sequence)
+		}
+		res.Removed = append(res.Removed, op.Removed...)
+	}
+	return res
+}
+
+// Change returns the entry of change feed for data. returns nil if it's not a change of objects or buckets,
+// such as touching, marking or migrating versions.
+func (d *RaftData) Change() *msg.Change {
+	c := &msg.Change{Id: d.Name, Sequence: d.Sequence}
+	switch {
+	case d.Dest == DestMetadata && (d.Type == LogInsert || d.Type == LogUpdate):
+		c.Op, c.Metadata = msg.ChangePutMetadata, d.Metadata
+	case d.Dest == DestMetadata && d.Type == LogRemove:
+		c.Op = msg.ChangeRemoveMetadata
+	case d.Dest == DestVersion && d.Type == LogInsert:
+		c.Op, c.Version, c.Sequence = msg.ChangePutVersion, d.Version, d.Version.Sequence
+	case d.Dest == DestVersion && (d.Type == LogUpdate || d.Type == LogSwap):
+		c.Op, c.Version = msg.ChangeUpdateVersion, d.Version
+	case d.Dest == DestVersion && d.Type == LogRemove:
+		c.Op = msg.ChangeRemoveVersion
+	case d.Dest == DestVersionAll && d.Type == LogRemove:
+		c.Op = msg.ChangeRemoveAllVersion
+	case d.Dest == DestBucket && (d.Type == LogInsert || d.Type == LogUpdate):
+		c.Op, c.Bucket = msg.ChangePutBucket, d.Bucket
+	case d.Dest == DestBucket && d.Type == LogRemove:
+		c.Op = msg.ChangeRemoveBucket
+	default:
+		return nil
+	}
+	return c
+}
+
+// FromChange returns the data applying change, which is the reverse of Change.
+// putting metadata or buckets creates them if not exist, otherwise updates them.
+func FromChange(c *msg.Change) (*RaftData, error) {
+	d := &RaftData{Name: c.Id, Sequence: c.Sequence}
+	switch c.Op {
+	case msg.ChangePutMetadata:
+		d.Type, d.Dest, d.Metadata = LogInsert, DestMetadata, c.Metadata
+	case msg.ChangeRemoveMetadata:
+		d.Type, d.Dest = LogRemove, DestMetadata
+	case msg.ChangePutVersion:
+		d.Type, d.Dest, d.Version = LogInsert, DestVersion, c.Version
+	case msg.ChangeUpdateVersion:
+		d.Type, d.Dest, d.Version = LogUpdate, DestVersion, c.Version
+	case msg.ChangeRemoveVersion:
+		d.Type, d.Dest = LogRemove, DestVersion
+	case msg.ChangeRemoveAllVersion:
+		d.Type, d.Dest = LogRemove, DestVersionAll
+	case msg.ChangePutBucket:
+		d.Type, d.Dest, d.Bucket = LogInsert, DestBucket, c.Bucket
+	case msg.ChangeRemoveBucket:
+		d.Type, d.Dest = LogRemove, DestBucket
+	default:
+		return nil, fmt.Errorf("unknown change op %d", c.Op)
+	}
+	return d, nil
 }
@@ -216,6 +216,61 @@ func (z *RaftData) DecodeMsg(dc *msgp.Reader) (err error) {
 					return
 				}
 			}
+		case "hash_ref":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "HashRef")
+					return
+				}
+				z.HashRef = nil
+			} else {
+				if z.HashRef == nil {
+					z.HashRef = new(msg.HashRef)
+				}
+				err = z.HashRef.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "HashRef")
+					return
+				}
+			}
+		case "expect":
+			z.Expect, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Expect")
+				return
+			}
+		case "ops":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Ops")
+				return
+			}
+			if cap(z.Ops) >= int(zb0004) {
+				z.Ops = (z.Ops)[:zb0004]
+			} else {
+				z.Ops = make([]*RaftData, zb0004)
+			}
+			for za0001 := range z.Ops {
+				if dc.IsNil() {
+					err = dc.ReadNil()
+					if err != nil {
+						err = msgp.WrapError(err, "Ops", za0001)
+						return
+					}
+					z.Ops[za0001] = nil
+				} else {
+					if z.Ops[za0001] == nil {
+						z.Ops[za0001] = new(RaftData)
+					}
+					err = z.Ops[za0001].DecodeMsg(dc)
+					if err != nil {
+						err = msgp.WrapError(err, "Ops", za0001)
+						return
+					}
+				}
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -229,9 +284,9 @@ func (z *RaftData) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *RaftData) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 7
+	// map header, size 10
 	// write "type"
-	err = en.Append(0x87, 0xa4, 0x74, 0x79, 0x70, 0x65)
+	err = en.Append(0x8a, 0xa4, 0x74, 0x79, 0x70, 0x65)
 	if err != nil {
 		return
 	}
@@ -321,15 +376,66 @@ func (z *RaftData) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
+	// write "hash_ref"
+	err = en.Append(0xa8, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x72, 0x65, 0x66)
+	if err != nil {
+		return
+	}
+	if z.HashRef == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.HashRef.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "HashRef")
+			return
+		}
+	}
+	// write "expect"
+	err = en.Append(0xa6, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Expect)
+	if err != nil {
+		err = msgp.WrapError(err, "Expect")
+		return
+	}
+	// write "ops"
+	err = en.Append(0xa3, 0x6f, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Ops)))
+	if err != nil {
+		err = msgp.WrapError(err, "Ops")
+		return
+	}
+	for za0001 := range z.Ops {
+		if z.Ops[za0001] == nil {
+			err = en.WriteNil()
+			if err != nil {
+				return
+			}
+		} else {
+			err = z.Ops[za0001].EncodeMsg(en)
+			if err != nil {
+				err = msgp.WrapError(err, "Ops", za0001)
+				return
+			}
+		}
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *RaftData) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 7
+	// map header, size 10
 	// string "type"
-	o = append(o, 0x87, 0xa4, 0x74, 0x79, 0x70, 0x65)
+	o = append(o, 0x8a, 0xa4, 0x74, 0x79, 0x70, 0x65)
 	o = msgp.AppendInt8(o, int8(z.Type))
 	// string "dest"
 	o = append(o, 0xa4, 0x64, 0x65, 0x73, 0x74)
@@ -373,6 +479,34 @@ func (z *RaftData) MarshalMsg(b []byte) (o []byte, err error) {
 			return
 		}
 	}
+	// string "hash_ref"
+	o = append(o, 0xa8, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x72, 0x65, 0x66)
+	if z.HashRef == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.HashRef.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "HashRef")
+			return
+		}
+	}
+	// string "expect"
+	o = append(o, 0xa6, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74)
+	o = msgp.AppendInt64(o, z.Expect)
+	// string "ops"
+	o = append(o, 0xa3, 0x6f, 0x70, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Ops)))
+	for za0001 := range z.Ops {
+		if z.Ops[za0001] == nil {
+			o = msgp.AppendNil(o)
+		} else {
+			o, err = z.Ops[za0001].MarshalMsg(o)
+			if err != nil {
+				err = msgp.WrapError(err, "Ops", za0001)
+				return
+			}
+		}
+	}
 	return
 }
 
@@ -477,6 +611,59 @@ func (z *RaftData) UnmarshalMsg(bts []byte) (o []byte, err error) {
 					return
 				}
 			}
+		case "hash_ref":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.HashRef = nil
+			} else {
+				if z.HashRef == nil {
+					z.HashRef = new(msg.HashRef)
+				}
+				bts, err = z.HashRef.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "HashRef")
+					return
+				}
+			}
+		case "expect":
+			z.Expect, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Expect")
+				return
+			}
+		case "ops":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Ops")
+				return
+			}
+			if cap(z.Ops) >= int(zb0004) {
+				z.Ops = (z.Ops)[:zb0004]
+			} else {
+				z.Ops = make([]*RaftData, zb0004)
+			}
+			for za0001 := range z.Ops {
+				if msgp.IsNil(bts) {
+					bts, err = msgp.ReadNilBytes(bts)
+					if err != nil {
+						return
+					}
+					z.Ops[za0001] = nil
+				} else {
+					if z.Ops[za0001] == nil {
+						z.Ops[za0001] = new(RaftData)
+					}
+					bts, err = z.Ops[za0001].UnmarshalMsg(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Ops", za0001)
+						return
+					}
+				}
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -509,5 +696,19 @@ func (z *RaftData) Msgsize() (s int) {
 	} else {
 		s += z.Bucket.Msgsize()
 	}
+	s += 9
+	if z.HashRef == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.HashRef.Msgsize()
+	}
+	s += 7 + msgp.Int64Size + 4 + msgp.ArrayHeaderSize
+	for za0001 := range z.Ops {
+		if z.Ops[za0001] == nil {
+			s += msgp.NilSize
+		} else {
+			s += z.Ops[za0001].Msgsize()
+		}
+	}
 	return
 }
@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/config"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoBackup = errors.New("no available backup")
+
+type Type string
+
+const (
+	// Full is the whole data of a group at ToIndex
+	Full Type = "full"
+	// Incremental is the raft logs from FromIndex to ToIndex applied after its parent
+	Incremental Type = "incremental"
+)
+
+// Manifest describes a backup of a metadata group
+type Manifest struct {
+	ID        string    `json:"id"`
+	Group     string    `json:"group"`
+	Server    string    `json:"server"`
+	Type      Type      `json:"type"`
+	Parent    string    `json:"parent,omitempty"` // Parent is the previous backup which an incremental one applies on
+	Reason    string    `json:"reason,omitempty"` // Reason is why a full backup is taken instead of an incremental one
+	FromIndex uint64    `json:"fromIndex"`
+	ToIndex   uint64    `json:"toIndex"`  // ToIndex is the raft applied index that data restored from backup is at
+	LastTime  time.Time `json:"lastTime"` // LastTime is the time of the last change in backup
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewManifest(group, server string, typ Type, from, to uint64) *Manifest {
+	now := time.Now()
+	return &Manifest{
+		ID:        fmt.Sprintf("%020d-%d-%s", to, now.Unix(), typ),
+		Group:     group,
+		Server:    server,
+		Type:      typ,
+		FromIndex: from,
+		ToIndex:   to,
+		LastTime:  now,
+		CreatedAt: now,
+	}
+}
+
+// DataName is the name of the data file in destination
+func (m *Manifest) DataName() string {
+	return path.Join(m.Group, m.ID+".data")
+}
+
+// Name is the name of the manifest file in destination
+func (m *Manifest) Name() string {
+	return path.Join(m.Group, m.ID+".json")
+}
+
+// Destination stores backup files
+type Destination interface {
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// NewDestination returns the destination of config. 'local' and 's3' are supported.
+func NewDestination(cfg *config.BackupConfig) (Destination, error) {
+	switch cfg.Dest {
+	case "", "local":
+		return NewLocalDestination(cfg.Dir)
+	case "s3":
+		return NewS3Destination(&cfg.S3), nil
+	default:
+		return nil, fmt.Errorf("unknown backup destination '%s'", cfg.Dest)
+	}
+}
+
+// SaveManifest writes manifest after its data has been written
+func SaveManifest(ctx context.Context, dest Destination, m *Manifest) error {
+	bt, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return dest.Put(ctx, m.Name(), strings.NewReader(string(bt)), int64(len(bt)))
+}
+
+// ListManifests returns manifests of group ordered by ToIndex
+func ListManifests(ctx context.Context, dest Destination, group string) ([]*Manifest, error) {
+	names, err := dest.List(ctx, group+"/")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Manifest, 0, len(names)/2)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		rc, err := dest.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		err = json.NewDecoder(rc).Decode(&m)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode manifest %s: %w", name, err)
+		}
+		res = append(res, &m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// Target is the point in time to restore. zero Index or Time means no limit.
+type Target struct {
+	Index uint64
+	Time  time.Time
+}
+
+func (t Target) before(m *Manifest) bool {
+	return (t.Index > 0 && m.ToIndex > t.Index) || (!t.Time.IsZero() && m.LastTime.After(t.Time))
+}
+
+// Chain returns the latest full backup not after target and the incremental ones following it.
+// the last incremental may contain changes after target which should be skipped on restoring.
+func Chain(manifests []*Manifest, target Target) ([]*Manifest, error) {
+	var full *Manifest
+	for _, m := range manifests {
+		if m.Type == Full && !target.before(m) {
+			full = m
+		}
+	}
+	if full == nil {
+		return nil, ErrNoBackup
+	}
+	// backups may fork if leader changed while backing up, logs of every fork are the same committed ones
+	children := make(map[string][]*Manifest, len(manifests))
+	for _, m := range manifests {
+		if m.Type == Incremental {
+			children[m.Parent] = append(children[m.Parent], m)
+		}
+	}
+	return append([]*Manifest{full}, longestChain(children, full, target)...), nil
+}
+
+// longestChain returns the incremental backups after cur which restore to the latest index not after target.
+// the one with greater ID wins if two chains reach the same index.
+func longestChain(children map[string][]*Manifest, cur *Manifest, target Target) []*Manifest {
+	if target.before(cur) {
+		return nil
+	}
+	var best []*Manifest
+	for _, next := range children[cur.ID] {
+		chain := append([]*Manifest{next}, longestChain(children, next, target)...)
+		if best == nil {
+			best = chain
+			continue
+		}
+		a, b := chain[len(chain)-1], best[len(best)-1]
+		if a.ToIndex > b.ToIndex || (a.ToIndex == b.ToIndex && a.ID > b.ID) {
+			best = chain
+		}
+	}
+	return best
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"common/cst"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalDestination stores backups in a directory
+type LocalDestination struct {
+	Dir string
+}
+
+func NewLocalDestination(dir string) (*LocalDestination, error) {
+	if err := os.MkdirAll(dir, cst.OS.ModeUser); err != nil {
+		return nil, err
+	}
+	return &LocalDestination{Dir: dir}, nil
+}
+
+func (l *LocalDestination) Put(_ context.Context, name string, r io.Reader, _ int64) error {
+	fullPath := filepath.Join(l.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), cst.OS.ModeUser); err != nil {
+		return err
+	}
+	// write to a temp file firstly to avoid partial files
+	tmp := fullPath + ".tmp"
+	file, err := os.OpenFile(tmp, cst.OS.WriteFlag, cst.OS.ModeUser)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fullPath)
+}
+
+func (l *LocalDestination) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.Dir, filepath.FromSlash(name)))
+}
+
+func (l *LocalDestination) List(_ context.Context, prefix string) ([]string, error) {
+	var res []string
+	err := filepath.WalkDir(l.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return err
+		}
+		rel, err := filepath.Rel(l.Dir, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			res = append(res, name)
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (l *LocalDestination) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(l.Dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
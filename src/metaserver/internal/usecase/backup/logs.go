@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+var ErrLogCompacted = errors.New("raft logs have been compacted")
+
+// logHeaderSize is [index uint64][term uint64][appended at int64][length uint32][crc32 uint32]
+const logHeaderSize = 32
+
+// WriteLogs writes command logs in (from, to] of store to w. returns the appended time of the last log.
+// returns ErrLogCompacted if some logs have been removed from store.
+func WriteLogs(w io.Writer, store raft.LogStore, from, to uint64) (time.Time, error) {
+	var last time.Time
+	first, err := store.FirstIndex()
+	if err != nil {
+		return last, err
+	}
+	if first == 0 || first > from+1 {
+		return last, ErrLogCompacted
+	}
+	var lg raft.Log
+	var header [logHeaderSize]byte
+	for i := from + 1; i <= to; i++ {
+		if err = store.GetLog(i, &lg); err != nil {
+			if errors.Is(err, raft.ErrLogNotFound) {
+				return last, ErrLogCompacted
+			}
+			return last, err
+		}
+		if !lg.AppendedAt.IsZero() {
+			last = lg.AppendedAt
+		}
+		if lg.Type != raft.LogCommand {
+			continue
+		}
+		binary.BigEndian.PutUint64(header[0:], lg.Index)
+		binary.BigEndian.PutUint64(header[8:], lg.Term)
+		binary.BigEndian.PutUint64(header[16:], uint64(lg.AppendedAt.UnixNano()))
+		binary.BigEndian.PutUint32(header[24:], uint32(len(lg.Data)))
+		binary.BigEndian.PutUint32(header[28:], crc32.ChecksumIEEE(lg.Data))
+		if _, err = w.Write(header[:]); err != nil {
+			return last, err
+		}
+		if _, err = w.Write(lg.Data); err != nil {
+			return last, err
+		}
+	}
+	// an empty header ends logs
+	_, err = w.Write(make([]byte, logHeaderSize))
+	return last, err
+}
+
+// ReadLogs reads command logs written by WriteLogs and calls fn for each one until fn returns false
+func ReadLogs(r io.Reader, fn func(*raft.Log) bool) error {
+	var header [logHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return fmt.Errorf("read log header: %w", err)
+		}
+		lg := &raft.Log{
+			Index:      binary.BigEndian.Uint64(header[0:]),
+			Term:       binary.BigEndian.Uint64(header[8:]),
+			Type:       raft.LogCommand,
+			AppendedAt: time.Unix(0, int64(binary.BigEndian.Uint64(header[16:]))),
+		}
+		if lg.Index == 0 {
+			return nil
+		}
+		lg.Data = make([]byte, binary.BigEndian.Uint32(header[24:]))
+		if _, err := io.ReadFull(r, lg.Data); err != nil {
+			return fmt.Errorf("read log %d: %w", lg.Index, err)
+		}
+		if crc32.ChecksumIEEE(lg.Data) != binary.BigEndian.Uint32(header[28:]) {
+			return fmt.Errorf("log %d checksum mismatch", lg.Index)
+		}
+		if !fn(lg) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"metaserver/config"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	minPartSize     = 5 << 20 // minPartSize is the min size of parts except the last one
+	maxParts        = 10000   // maxParts is the max number of parts of an upload
+)
+
+// S3Destination stores backups in a bucket of an S3-compatible service, requests are signed by AWS signature v4
+type S3Destination struct {
+	cfg    *config.S3Config
+	client *http.Client
+}
+
+func NewS3Destination(cfg *config.S3Config) *S3Destination {
+	return &S3Destination{cfg: cfg, client: &http.Client{}}
+}
+
+func (s *S3Destination) objectKey(name string) string {
+	return strings.TrimPrefix(path.Join(s.cfg.Prefix, name), "/")
+}
+
+// Put uploads by a single request, or by multipart if it's larger than PartSize since a single one is limited to 5GB
+func (s *S3Destination) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	partSize := int64(s.cfg.PartSize.Byte())
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if size > partSize {
+		// parts are enlarged to not exceed maxParts
+		if n := (size + maxParts - 1) / maxParts; n > partSize {
+			partSize = n
+		}
+		return s.putMultipart(ctx, s.objectKey(name), r, size, partSize)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectKey(name), nil, r, size)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putMultipart uploads size bytes of r in parts of partSize, the upload is aborted on failure
+func (s *S3Destination) putMultipart(ctx context.Context, key string, r io.Reader, size, partSize int64) (err error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0)
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	uploadId := url.Values{"uploadId": {initiated.UploadId}}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// parts uploaded are charged until aborted, it's done even if ctx is cancelled
+		if resp, inner := s.do(context.Background(), http.MethodDelete, key, uploadId, nil, 0); inner != nil {
+			err = fmt.Errorf("%w, abort upload: %s", err, inner)
+		} else {
+			_ = resp.Body.Close()
+		}
+	}()
+	var parts []completedPart
+	for num, left := 1, size; left > 0; num++ {
+		n := partSize
+		if left < n {
+			n = left
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(num)}, "uploadId": uploadId["uploadId"]}
+		if resp, err = s.do(ctx, http.MethodPut, key, query, io.LimitReader(r, n), n); err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: num, ETag: resp.Header.Get("ETag")})
+		left -= n
+	}
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	if resp, err = s.do(ctx, http.MethodPost, key, uploadId, bytes.NewReader(body), int64(len(body))); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// completing may fail with status 200 and an error in body
+	msg, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(msg, []byte("<Error>")) {
+		return fmt.Errorf("s3 complete upload %s: %s", key, msg)
+	}
+	return nil
+}
+
+func (s *S3Destination) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectKey(name), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Destination) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectKey(name), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Destination) List(ctx context.Context, prefix string) ([]string, error) {
+	var res []string
+	keyPrefix := s.objectKey(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		keyPrefix += "/"
+	}
+	trim := strings.TrimSuffix(s.objectKey(""), "/")
+	query := url.Values{"list-type": {"2"}, "prefix": {keyPrefix}}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			res = append(res, strings.TrimPrefix(strings.TrimPrefix(c.Key, trim), "/"))
+		}
+		if !result.IsTruncated {
+			return res, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// do sends a signed request. key is empty for requests to the bucket.
+func (s *S3Destination) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	endpoint, err := url.Parse(s.cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u := *endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s %s", method, key, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+func (s *S3Destination) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	scope := strings.Join([]string{date, s.cfg.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSha256(canonicalRequest)}, "\n")
+	key := hmacSha256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSha256(key, s.cfg.Region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSha256(key, stringToSign))))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSha256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// escape encodes s as RFC 3986 required by signature v4
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"bufio"
+	"common/graceful"
+	"common/util"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/internal/usecase/db/kv"
+	"os"
+)
+
+// Convert copies all buckets and keys of src into dst. data is streamed as a chunked snapshot,
+// so that every chunk is written in a transaction of bounded size.
+func Convert(src, dst kv.Engine) error {
+	tx, err := src.Begin()
+	if err != nil {
+		return err
+	}
+	snap := &chunkedSnapshot{tx: tx, chunkSize: defaultSnapshotChunkSize, progress: &progressTracker{}}
+	pr, pw := io.Pipe()
+	go func() {
+		defer graceful.Recover()
+		_, err := snap.WriteTo(pw)
+		util.LogErr(snap.Rollback())
+		util.LogErr(pw.CloseWithError(err))
+	}()
+	rd := bufio.NewReaderSize(pr, 64*1024)
+	if _, err = isChunkedSnapshot(rd); err == nil {
+		err = restoreChunks(rd, dst, &progressTracker{})
+	}
+	// unblock writer if restoring fails
+	util.LogErr(pr.CloseWithError(err))
+	return err
+}
+
+// ConvertFile converts the database at srcPath to a new one of engine at dstPath. the engine of source is detected by path.
+func ConvertFile(srcPath, engine, dstPath string) (err error) {
+	srcEngine, err := kv.Detect(srcPath)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(dstPath); err == nil {
+		return fmt.Errorf("%s already exists", dstPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	src, err := kv.Open(srcEngine, srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() { util.LogErr(src.Close()) }()
+	dst, err := kv.Open(engine, dstPath)
+	if err != nil {
+		return err
+	}
+	if err = Convert(src, dst); err != nil {
+		util.LogErr(dst.Close())
+		util.LogErrWithPre("remove converting db", os.RemoveAll(dstPath))
+		return err
+	}
+	return dst.Close()
+}
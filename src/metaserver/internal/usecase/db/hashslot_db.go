@@ -5,8 +5,13 @@ import (
 	"common/logs"
 	"common/util"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"metaserver/internal/entity"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -40,6 +45,8 @@ func StatusDesc(status int32) (string, error) {
 	return desc, nil
 }
 
+var ErrSlotFrozen = errors.New("slot in migration")
+
 type HashSlotDB struct {
 	kv             clientv3.KV
 	status         *atomic.Int32
@@ -47,18 +54,234 @@ type HashSlotDB struct {
 	updatedAt      int64
 	migratingSlots []string
 	migratingHost  string
-	KeyPrefix      string
+	// trackingEdges are slots migrating to other group. writes to keys in them are tracked as dirty.
+	trackingEdges *atomic.Pointer[hashslot.EdgeList]
+	// writeLock is held by writes to tracking slots, so that freezing waits for writes in-flight.
+	writeLock sync.RWMutex
+	frozen    bool
+	dirtyLock sync.Mutex
+	dirty     map[string]entity.Dest
+	// trackingId is the store id of tracking migration, persisted dirty marks are saved under it.
+	trackingId string
+	// persisted are the dirty marks which have been saved to etcd, they are saved only once in a migration.
+	persisted        map[string]entity.Dest
+	KeyPrefix        string
+	CheckpointPrefix string
 }
 
-func NewHashSlotDB(keyPrefix string, kv clientv3.KV) *HashSlotDB {
+func NewHashSlotDB(keyPrefix, checkpointPrefix string, kv clientv3.KV) *HashSlotDB {
 	at := &atomic.Int32{}
 	at.Store(StatusNormal)
 	return &HashSlotDB{
-		KeyPrefix: keyPrefix,
-		kv:        kv,
-		provider:  &atomic.Pointer[hashslot.IEdgeProvider]{},
-		status:    at,
+		KeyPrefix:        keyPrefix,
+		CheckpointPrefix: checkpointPrefix,
+		kv:               kv,
+		provider:         &atomic.Pointer[hashslot.IEdgeProvider]{},
+		trackingEdges:    &atomic.Pointer[hashslot.EdgeList]{},
+		dirty:            map[string]entity.Dest{},
+		persisted:        map[string]entity.Dest{},
+		status:           at,
+	}
+}
+
+// TrackMigrating starts tracking writes to keys in slots which are migrating to other group.
+// the 'id' is the store id which defined in configuration, dirty marks are persisted under it.
+func (h *HashSlotDB) TrackMigrating(id string, slots []string) error {
+	edges, err := hashslot.WrapSlotsToEdges(slots, "")
+	if err != nil {
+		return err
+	}
+	h.dirtyLock.Lock()
+	h.trackingId = id
+	h.dirtyLock.Unlock()
+	h.Unfreeze()
+	h.trackingEdges.Store(&edges)
+	return nil
+}
+
+// StopTracking stops tracking writes and clears dirty keys
+func (h *HashSlotDB) StopTracking() {
+	h.trackingEdges.Store(nil)
+	h.dirtyLock.Lock()
+	defer h.dirtyLock.Unlock()
+	h.dirty = map[string]entity.Dest{}
+	h.persisted = map[string]entity.Dest{}
+}
+
+// IsKeyMigrating reports whether the slot of key is migrating to other group
+func (h *HashSlotDB) IsKeyMigrating(key string) bool {
+	edges := h.trackingEdges.Load()
+	if edges == nil || key == "" {
+		return false
+	}
+	return hashslot.IsSlotInEdges(hashslot.CalcBytesSlot(util.StrToBytes(key)), *edges)
+}
+
+// AcquireWrite must be called before writing key. returns ErrSlotFrozen if the slot of key is frozen.
+// release must be called after writing, the key will be marked as dirty if success.
+func (h *HashSlotDB) AcquireWrite(key string, dest entity.Dest) (release func(success bool), err error) {
+	if !h.IsKeyMigrating(key) {
+		return func(bool) {}, nil
+	}
+	h.writeLock.RLock()
+	if h.frozen {
+		h.writeLock.RUnlock()
+		return nil, ErrSlotFrozen
+	}
+	if err = h.persistDirty(key, dest); err != nil {
+		h.writeLock.RUnlock()
+		return nil, err
+	}
+	return func(success bool) {
+		defer h.writeLock.RUnlock()
+		if success {
+			h.MarkDirty(key, dest)
+		}
+	}, nil
+}
+
+// AcquireWrites is AcquireWrite of keys written in a transaction. all the migrating ones are marked as dirty if success.
+func (h *HashSlotDB) AcquireWrites(keys map[string]entity.Dest) (release func(success bool), err error) {
+	migrating := make(map[string]entity.Dest)
+	for k, dest := range keys {
+		if h.IsKeyMigrating(k) {
+			migrating[k] = dest
+		}
+	}
+	if len(migrating) == 0 {
+		return func(bool) {}, nil
+	}
+	h.writeLock.RLock()
+	if h.frozen {
+		h.writeLock.RUnlock()
+		return nil, ErrSlotFrozen
+	}
+	for k, dest := range migrating {
+		if err = h.persistDirty(k, dest); err != nil {
+			h.writeLock.RUnlock()
+			return nil, err
+		}
+	}
+	return func(success bool) {
+		defer h.writeLock.RUnlock()
+		if success {
+			for k, dest := range migrating {
+				h.MarkDirty(k, dest)
+			}
+		}
+	}, nil
+}
+
+// persistDirty saves the dirty mark of key to etcd before writing it, so that a broken migration resends the key
+// even if it's written after the last checkpoint. a mark of failed writing only causes a needless resending.
+func (h *HashSlotDB) persistDirty(key string, dest entity.Dest) error {
+	h.dirtyLock.Lock()
+	id, mark := h.trackingId, h.persisted[key]
+	h.dirtyLock.Unlock()
+	if mark&dest == dest {
+		return nil
+	}
+	if _, err := h.kv.Put(context.Background(), h.dirtyKey(id, dest, key), ""); err != nil {
+		return fmt.Errorf("persist dirty mark of %s err: %w", key, err)
+	}
+	h.dirtyLock.Lock()
+	defer h.dirtyLock.Unlock()
+	h.persisted[key] |= dest
+	return nil
+}
+
+func (h *HashSlotDB) dirtyPrefix(id string) string {
+	return fmt.Sprint(h.CheckpointPrefix, id, "/dirty/")
+}
+
+// dirtyKey is the etcd key of dirty mark, each dest of key has its own mark so that concurrent writes never overwrite others.
+func (h *HashSlotDB) dirtyKey(id string, dest entity.Dest, key string) string {
+	return fmt.Sprint(h.dirtyPrefix(id), int(dest), "/", key)
+}
+
+// LoadDirty returns the persisted dirty marks of migration of store 'id', they are kept as persisted.
+func (h *HashSlotDB) LoadDirty(id string) (map[string]entity.Dest, error) {
+	resp, err := h.kv.Get(context.Background(), h.dirtyPrefix(id), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
 	}
+	res := make(map[string]entity.Dest, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		parts := strings.SplitN(strings.TrimPrefix(string(kv.Key), h.dirtyPrefix(id)), "/", 2)
+		dest, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("decode dirty mark %s fails", kv.Key)
+		}
+		res[parts[1]] |= entity.Dest(dest)
+	}
+	h.dirtyLock.Lock()
+	defer h.dirtyLock.Unlock()
+	for k, v := range res {
+		h.persisted[k] |= v
+	}
+	return res, nil
+}
+
+// Freeze rejects writes to tracking slots after writes in-flight finished
+func (h *HashSlotDB) Freeze() {
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	h.frozen = true
+}
+
+func (h *HashSlotDB) Unfreeze() {
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	h.frozen = false
+}
+
+func (h *HashSlotDB) MarkDirty(key string, dest entity.Dest) {
+	h.dirtyLock.Lock()
+	defer h.dirtyLock.Unlock()
+	h.dirty[key] |= dest
+}
+
+// TakeDirty returns and clears dirty keys
+func (h *HashSlotDB) TakeDirty() map[string]entity.Dest {
+	h.dirtyLock.Lock()
+	defer h.dirtyLock.Unlock()
+	res := h.dirty
+	h.dirty = map[string]entity.Dest{}
+	return res
+}
+
+// GetCheckpoint The 'id' is the store id which defined in configuration
+func (h *HashSlotDB) GetCheckpoint(id string) (*entity.MigrationCheckpoint, bool, error) {
+	resp, err := h.kv.Get(context.Background(), fmt.Sprint(h.CheckpointPrefix, id))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, nil
+	}
+	var cp entity.MigrationCheckpoint
+	if err = json.Unmarshal(resp.Kvs[0].Value, &cp); err != nil {
+		return nil, false, err
+	}
+	return &cp, true, nil
+}
+
+func (h *HashSlotDB) SaveCheckpoint(id string, cp *entity.MigrationCheckpoint) error {
+	bt, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = h.kv.Put(context.Background(), fmt.Sprint(h.CheckpointPrefix, id), string(bt))
+	return err
+}
+
+// RemoveCheckpoint removes checkpoint and persisted dirty marks of migration
+func (h *HashSlotDB) RemoveCheckpoint(id string) error {
+	if _, err := h.kv.Delete(context.Background(), h.dirtyPrefix(id), clientv3.WithPrefix()); err != nil {
+		return err
+	}
+	_, err := h.kv.Delete(context.Background(), fmt.Sprint(h.CheckpointPrefix, id))
+	return err
 }
 
 func (h *HashSlotDB) IsNormal() bool {
@@ -0,0 +1,472 @@
+package kv
+
+import (
+	"bytes"
+	"common/logs"
+	"encoding/binary"
+	"errors"
+	"math"
+	"runtime"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/options"
+	"github.com/sirupsen/logrus"
+)
+
+// keys of badger engine:
+//   - entry of bucket: [bucket id 8 bytes][key] => [tagValue][value] or [tagBucket][sub-bucket id 8 bytes]
+//   - sequence of bucket: [metaID][bucket id] => [sequence 8 bytes]
+//   - last allocated bucket id: [metaID] => [id 8 bytes]
+//
+// top level buckets are entries of the bucket with id 0.
+const (
+	tagValue byte = iota
+	tagBucket
+)
+
+const (
+	rootID uint64 = 0
+	metaID uint64 = math.MaxUint64
+	// maxConflictRetry is the max attempts of a read-write tx aborted by conflicts
+	maxConflictRetry = 16
+)
+
+type badgerEngine struct {
+	db   *badger.DB
+	path string
+}
+
+func OpenBadger(path string) (Engine, error) {
+	db, err := badger.Open(
+		badger.DefaultOptions(path).
+			WithNumGoroutines(runtime.NumCPU()).
+			WithCompression(options.Snappy).
+			WithMetricsEnabled(false).
+			WithLogger(badgerLogger{logs.New("badger")}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &badgerEngine{db: db, path: path}, nil
+}
+
+// badgerLogger drops info and debug logs of badger which are too verbose
+type badgerLogger struct {
+	*logrus.Entry
+}
+
+func (badgerLogger) Infof(string, ...interface{}) {}
+
+func (badgerLogger) Debugf(string, ...interface{}) {}
+
+func (e *badgerEngine) View(fn func(Tx) error) error {
+	return e.db.View(func(txn *badger.Txn) error {
+		tx := &badgerTx{txn: txn}
+		defer tx.closeIterators()
+		return fn(tx)
+	})
+}
+
+// Update retries fn if tx conflicts with concurrent ones, fn must reset its outputs on every call
+func (e *badgerEngine) Update(fn func(Tx) error) (err error) {
+	for i := 0; i < maxConflictRetry; i++ {
+		err = e.db.Update(func(txn *badger.Txn) error {
+			tx := &badgerTx{txn: txn, writable: true}
+			defer tx.closeIterators()
+			return fn(tx)
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+// Batch is the same as Update. writes of badger are already batched and concurrent.
+func (e *badgerEngine) Batch(fn func(Tx) error) error {
+	return e.Update(fn)
+}
+
+func (e *badgerEngine) Begin() (ReadTx, error) {
+	return &badgerTx{txn: e.db.NewTransaction(false)}, nil
+}
+
+func (e *badgerEngine) Name() string { return EngineBadger }
+
+func (e *badgerEngine) Path() string { return e.path }
+
+func (e *badgerEngine) Sync() error { return e.db.Sync() }
+
+func (e *badgerEngine) Close() error { return e.db.Close() }
+
+type badgerTx struct {
+	txn       *badger.Txn
+	writable  bool
+	iterators []*badger.Iterator
+}
+
+func (t *badgerTx) root() *badgerBucket {
+	return &badgerBucket{tx: t, id: rootID}
+}
+
+func (t *badgerTx) Writable() bool { return t.writable }
+
+func (t *badgerTx) Rollback() error {
+	t.closeIterators()
+	t.txn.Discard()
+	return nil
+}
+
+// closeIterators closes iterators of cursors, badger requires all iterators closed before the end of tx
+func (t *badgerTx) closeIterators() {
+	for _, it := range t.iterators {
+		it.Close()
+	}
+	t.iterators = nil
+}
+
+func (t *badgerTx) newIterator(prefix []byte, reverse bool) *badger.Iterator {
+	opt := badger.DefaultIteratorOptions
+	opt.Prefix, opt.Reverse = prefix, reverse
+	it := t.txn.NewIterator(opt)
+	t.iterators = append(t.iterators, it)
+	return it
+}
+
+func (t *badgerTx) Bucket(name []byte) Bucket {
+	return t.root().Bucket(name)
+}
+
+func (t *badgerTx) CreateBucket(name []byte) (Bucket, error) {
+	return t.root().CreateBucket(name)
+}
+
+func (t *badgerTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	return t.root().CreateBucketIfNotExists(name)
+}
+
+func (t *badgerTx) DeleteBucket(name []byte) error {
+	return t.root().DeleteBucket(name)
+}
+
+func (t *badgerTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	root := t.root()
+	return root.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return fn(k, root.Bucket(k))
+	})
+}
+
+// get returns the raw value of key, nil if not exists
+func (t *badgerTx) get(key []byte) ([]byte, error) {
+	item, err := t.txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (t *badgerTx) allocID() (uint64, error) {
+	raw, err := t.get(idKey(metaID))
+	if err != nil {
+		return 0, err
+	}
+	id := rootID
+	if len(raw) == 8 {
+		id = binary.BigEndian.Uint64(raw)
+	}
+	id++
+	return id, t.txn.Set(idKey(metaID), idKey(id))
+}
+
+func idKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 0, 8), id)
+}
+
+type badgerBucket struct {
+	tx *badgerTx
+	id uint64
+}
+
+func (b *badgerBucket) key(k []byte) []byte {
+	return append(idKey(b.id), k...)
+}
+
+func (b *badgerBucket) seqKey() []byte {
+	return append(idKey(metaID), idKey(b.id)...)
+}
+
+func (b *badgerBucket) raw(k []byte) []byte {
+	v, err := b.tx.get(b.key(k))
+	if err != nil {
+		logs.Std().Errorf("badger get err: %s", err)
+		return nil
+	}
+	return v
+}
+
+func (b *badgerBucket) Writable() bool { return b.tx.writable }
+
+func (b *badgerBucket) Get(key []byte) []byte {
+	if v := b.raw(key); len(v) > 0 && v[0] == tagValue {
+		return v[1:]
+	}
+	return nil
+}
+
+func (b *badgerBucket) Put(key, value []byte) error {
+	switch {
+	case !b.tx.writable:
+		return ErrTxNotWritable
+	case len(key) == 0:
+		return ErrKeyRequired
+	}
+	if v := b.raw(key); len(v) > 0 && v[0] == tagBucket {
+		return ErrIncompatibleValue
+	}
+	return b.tx.txn.Set(b.key(key), append([]byte{tagValue}, value...))
+}
+
+func (b *badgerBucket) Delete(key []byte) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	if v := b.raw(key); len(v) > 0 && v[0] == tagBucket {
+		return ErrIncompatibleValue
+	}
+	return b.tx.txn.Delete(b.key(key))
+}
+
+func (b *badgerBucket) ForEach(fn func(k, v []byte) error) error {
+	prefix := idKey(b.id)
+	opt := badger.DefaultIteratorOptions
+	opt.Prefix = prefix
+	it := b.tx.txn.NewIterator(opt)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		k, v, err := entryOf(it.Item(), len(prefix))
+		if err != nil {
+			return err
+		}
+		if err = fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryOf returns the key without bucket prefix and the value of item, the value is nil if it's a sub-bucket
+func entryOf(item *badger.Item, prefixLen int) ([]byte, []byte, error) {
+	k := item.KeyCopy(nil)[prefixLen:]
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(v) == 0 || v[0] == tagBucket {
+		return k, nil, nil
+	}
+	return k, v[1:], nil
+}
+
+func (b *badgerBucket) Cursor() Cursor {
+	return &badgerCursor{b: b, prefix: idKey(b.id)}
+}
+
+func (b *badgerBucket) Bucket(name []byte) Bucket {
+	if v := b.raw(name); len(v) == 9 && v[0] == tagBucket {
+		return &badgerBucket{tx: b.tx, id: binary.BigEndian.Uint64(v[1:])}
+	}
+	return nil
+}
+
+func (b *badgerBucket) CreateBucket(name []byte) (Bucket, error) {
+	switch {
+	case !b.tx.writable:
+		return nil, ErrTxNotWritable
+	case len(name) == 0:
+		return nil, ErrBucketNameRequired
+	}
+	if v := b.raw(name); len(v) > 0 {
+		if v[0] == tagBucket {
+			return nil, ErrBucketExists
+		}
+		return nil, ErrIncompatibleValue
+	}
+	id, err := b.tx.allocID()
+	if err != nil {
+		return nil, err
+	}
+	if err = b.tx.txn.Set(b.key(name), append([]byte{tagBucket}, idKey(id)...)); err != nil {
+		return nil, err
+	}
+	return &badgerBucket{tx: b.tx, id: id}, nil
+}
+
+func (b *badgerBucket) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	if sub := b.Bucket(name); sub != nil {
+		return sub, nil
+	}
+	return b.CreateBucket(name)
+}
+
+func (b *badgerBucket) DeleteBucket(name []byte) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	v := b.raw(name)
+	if len(v) == 0 {
+		return ErrBucketNotFound
+	}
+	if v[0] != tagBucket {
+		return ErrIncompatibleValue
+	}
+	sub := &badgerBucket{tx: b.tx, id: binary.BigEndian.Uint64(v[1:])}
+	if err := sub.drop(); err != nil {
+		return err
+	}
+	return b.tx.txn.Delete(b.key(name))
+}
+
+// drop removes all keys and sub-buckets
+func (b *badgerBucket) drop() error {
+	var keys [][]byte
+	var subs []*badgerBucket
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			subs = append(subs, b.Bucket(k).(*badgerBucket))
+		}
+		keys = append(keys, b.key(k))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if err = sub.drop(); err != nil {
+			return err
+		}
+	}
+	for _, k := range keys {
+		if err = b.tx.txn.Delete(k); err != nil {
+			return err
+		}
+	}
+	return b.tx.txn.Delete(b.seqKey())
+}
+
+func (b *badgerBucket) Sequence() uint64 {
+	v, err := b.tx.get(b.seqKey())
+	if err != nil {
+		logs.Std().Errorf("badger get sequence err: %s", err)
+	}
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+func (b *badgerBucket) SetSequence(v uint64) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	return b.tx.txn.Set(b.seqKey(), idKey(v))
+}
+
+func (b *badgerBucket) NextSequence() (uint64, error) {
+	seq := b.Sequence() + 1
+	return seq, b.SetSequence(seq)
+}
+
+// KeyN iterates keys without prefetching or copying values, only the tag of value is read
+func (b *badgerBucket) KeyN() (n int) {
+	prefix := idKey(b.id)
+	opt := badger.DefaultIteratorOptions
+	opt.Prefix, opt.PrefetchValues = prefix, false
+	it := b.tx.txn.NewIterator(opt)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		err := it.Item().Value(func(v []byte) error {
+			if len(v) > 0 && v[0] == tagValue {
+				n++
+			}
+			return nil
+		})
+		if err != nil {
+			logs.Std().Errorf("badger read value err: %s", err)
+		}
+	}
+	return
+}
+
+// badgerCursor positions by an iterator, which is recreated on changing direction
+type badgerCursor struct {
+	b       *badgerBucket
+	prefix  []byte
+	it      *badger.Iterator
+	reverse bool
+	cur     []byte // cur is the full key of current position, nil if out of range
+}
+
+func (c *badgerCursor) seek(key []byte, reverse bool) ([]byte, []byte) {
+	if c.it != nil {
+		c.it.Close()
+	}
+	c.it, c.reverse = c.b.tx.newIterator(c.prefix, reverse), reverse
+	c.it.Seek(key)
+	return c.item()
+}
+
+func (c *badgerCursor) item() ([]byte, []byte) {
+	if !c.it.ValidForPrefix(c.prefix) {
+		c.cur = nil
+		return nil, nil
+	}
+	k, v, err := entryOf(c.it.Item(), len(c.prefix))
+	if err != nil {
+		logs.Std().Errorf("badger read value err: %s", err)
+		c.cur = nil
+		return nil, nil
+	}
+	c.cur = append(c.prefix[:len(c.prefix):len(c.prefix)], k...)
+	return k, v
+}
+
+func (c *badgerCursor) First() ([]byte, []byte) {
+	return c.seek(c.prefix, false)
+}
+
+func (c *badgerCursor) Last() ([]byte, []byte) {
+	// the largest key not greater than prefix of next bucket id
+	return c.seek(idKey(c.b.id+1), true)
+}
+
+func (c *badgerCursor) Seek(seek []byte) ([]byte, []byte) {
+	return c.seek(append(c.prefix[:len(c.prefix):len(c.prefix)], seek...), false)
+}
+
+func (c *badgerCursor) move(reverse bool) ([]byte, []byte) {
+	if c.cur == nil {
+		return nil, nil
+	}
+	if c.reverse != reverse {
+		cur := c.cur
+		if k, v := c.seek(cur, reverse); k == nil || !bytes.Equal(c.cur, cur) {
+			return k, v
+		}
+	}
+	c.it.Next()
+	return c.item()
+}
+
+func (c *badgerCursor) Next() ([]byte, []byte) {
+	return c.move(false)
+}
+
+func (c *badgerCursor) Prev() ([]byte, []byte) {
+	return c.move(true)
+}
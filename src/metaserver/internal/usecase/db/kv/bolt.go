@@ -0,0 +1,177 @@
+package kv
+
+import (
+	"common/cst"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltFillPercent is used by buckets in writable tx. keys are mostly appended, so pages are filled more than default.
+const boltFillPercent = 0.9
+
+type boltEngine struct {
+	db *bolt.DB
+}
+
+func OpenBolt(path string) (Engine, error) {
+	db, err := bolt.Open(path, cst.OS.ModeUser, &bolt.Options{
+		Timeout:      12 * time.Second,
+		NoGrowSync:   false,
+		FreelistType: bolt.FreelistMapType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltEngine{db: db}, nil
+}
+
+func (e *boltEngine) View(fn func(Tx) error) error {
+	return e.db.View(func(tx *bolt.Tx) error { return fn(boltTx{tx}) })
+}
+
+func (e *boltEngine) Update(fn func(Tx) error) error {
+	return e.db.Update(func(tx *bolt.Tx) error { return fn(boltTx{tx}) })
+}
+
+func (e *boltEngine) Batch(fn func(Tx) error) error {
+	return e.db.Batch(func(tx *bolt.Tx) error { return fn(boltTx{tx}) })
+}
+
+func (e *boltEngine) Begin() (ReadTx, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return boltTx{tx}, nil
+}
+
+func (e *boltEngine) Name() string { return EngineBolt }
+
+func (e *boltEngine) Path() string { return e.db.Path() }
+
+func (e *boltEngine) Sync() error { return e.db.Sync() }
+
+func (e *boltEngine) Close() error { return e.db.Close() }
+
+// SetNoSync skips fsync after commits, used on bulk loading
+func (e *boltEngine) SetNoSync(noSync bool) { e.db.NoSync = noSync }
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) Writable() bool { return t.tx.Writable() }
+
+func (t boltTx) Rollback() error { return t.tx.Rollback() }
+
+func (t boltTx) Bucket(name []byte) Bucket {
+	return wrapBoltBucket(t.tx.Bucket(name))
+}
+
+func (t boltTx) CreateBucket(name []byte) (Bucket, error) {
+	b, err := t.tx.CreateBucket(name)
+	return wrapBoltBucket(b), boltErr(err)
+}
+
+func (t boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	return wrapBoltBucket(b), boltErr(err)
+}
+
+func (t boltTx) DeleteBucket(name []byte) error {
+	return boltErr(t.tx.DeleteBucket(name))
+}
+
+func (t boltTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	return t.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		return fn(name, wrapBoltBucket(b))
+	})
+}
+
+type boltBucket struct {
+	b *bolt.Bucket
+}
+
+// wrapBoltBucket returns nil interface for nil bucket
+func wrapBoltBucket(b *bolt.Bucket) Bucket {
+	if b == nil {
+		return nil
+	}
+	if b.Writable() {
+		b.FillPercent = boltFillPercent
+	}
+	return boltBucket{b}
+}
+
+func (b boltBucket) Writable() bool { return b.b.Writable() }
+
+func (b boltBucket) Get(key []byte) []byte { return b.b.Get(key) }
+
+func (b boltBucket) Put(key, value []byte) error { return boltErr(b.b.Put(key, value)) }
+
+func (b boltBucket) Delete(key []byte) error { return boltErr(b.b.Delete(key)) }
+
+func (b boltBucket) ForEach(fn func(k, v []byte) error) error { return b.b.ForEach(fn) }
+
+func (b boltBucket) Cursor() Cursor { return b.b.Cursor() }
+
+func (b boltBucket) Bucket(name []byte) Bucket { return wrapBoltBucket(b.b.Bucket(name)) }
+
+func (b boltBucket) CreateBucket(name []byte) (Bucket, error) {
+	sub, err := b.b.CreateBucket(name)
+	return wrapBoltBucket(sub), boltErr(err)
+}
+
+func (b boltBucket) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	sub, err := b.b.CreateBucketIfNotExists(name)
+	return wrapBoltBucket(sub), boltErr(err)
+}
+
+func (b boltBucket) DeleteBucket(name []byte) error { return boltErr(b.b.DeleteBucket(name)) }
+
+func (b boltBucket) Sequence() uint64 { return b.b.Sequence() }
+
+func (b boltBucket) SetSequence(v uint64) error { return boltErr(b.b.SetSequence(v)) }
+
+func (b boltBucket) NextSequence() (uint64, error) {
+	seq, err := b.b.NextSequence()
+	return seq, boltErr(err)
+}
+
+// KeyN counts keys by page stats, which include keys of sub-buckets, so buckets with sub-buckets are counted by iterating
+func (b boltBucket) KeyN() (n int) {
+	if st := b.b.Stats(); st.BucketN <= 1 {
+		return st.KeyN
+	}
+	_ = b.b.ForEach(func(_, v []byte) error {
+		if v != nil {
+			n++
+		}
+		return nil
+	})
+	return
+}
+
+// boltErr converts errors of bbolt to the ones of kv
+func boltErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bolt.ErrBucketNotFound):
+		return ErrBucketNotFound
+	case errors.Is(err, bolt.ErrBucketExists):
+		return ErrBucketExists
+	case errors.Is(err, bolt.ErrIncompatibleValue):
+		return ErrIncompatibleValue
+	case errors.Is(err, bolt.ErrTxNotWritable):
+		return ErrTxNotWritable
+	case errors.Is(err, bolt.ErrKeyRequired):
+		return ErrKeyRequired
+	case errors.Is(err, bolt.ErrBucketNameRequired):
+		return ErrBucketNameRequired
+	default:
+		return err
+	}
+}
@@ -0,0 +1,128 @@
+// Package kv abstracts the storage engine of metadata as transactional, nested and ordered buckets.
+// bbolt is the default engine, badger is an LSM engine for write heavy workloads.
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	EngineBolt   = "bolt"
+	EngineBadger = "badger"
+)
+
+var (
+	ErrBucketNotFound     = errors.New("bucket not found")
+	ErrBucketExists       = errors.New("bucket already exists")
+	ErrIncompatibleValue  = errors.New("incompatible value")
+	ErrUnknownEngine      = errors.New("unknown storage engine")
+	ErrTxNotWritable      = errors.New("tx not writable")
+	ErrKeyRequired        = errors.New("key required")
+	ErrBucketNameRequired = errors.New("bucket name required")
+)
+
+type (
+	// Engine is a key-value database organized as nested buckets
+	Engine interface {
+		// View runs fn in a read-only transaction
+		View(fn func(Tx) error) error
+		// Update runs fn in a read-write transaction. fn may be called more than once if the transaction conflicts
+		// with others, so it must reset anything it writes outside the transaction.
+		Update(fn func(Tx) error) error
+		// Batch runs fn in a read-write transaction which may be combined with others. fn may be called more than once.
+		Batch(fn func(Tx) error) error
+		// Begin starts a read-only transaction which must be rolled back after using
+		Begin() (ReadTx, error)
+		// Name returns the engine name
+		Name() string
+		// Path returns the file or directory of database
+		Path() string
+		Sync() error
+		Close() error
+	}
+
+	// Tx accesses top level buckets
+	Tx interface {
+		Writable() bool
+		// Bucket returns nil if not exists
+		Bucket(name []byte) Bucket
+		CreateBucket(name []byte) (Bucket, error)
+		CreateBucketIfNotExists(name []byte) (Bucket, error)
+		DeleteBucket(name []byte) error
+		// ForEach iterates over top level buckets in order of name
+		ForEach(fn func(name []byte, b Bucket) error) error
+	}
+
+	// ReadTx is a read-only Tx held by caller
+	ReadTx interface {
+		Tx
+		Rollback() error
+	}
+
+	// Bucket is an ordered collection of keys and sub-buckets. keys and names of sub-buckets share the namespace.
+	Bucket interface {
+		Writable() bool
+		// Get returns nil if key not exists or it's a sub-bucket
+		Get(key []byte) []byte
+		Put(key, value []byte) error
+		Delete(key []byte) error
+		// ForEach iterates over keys in order, values of sub-buckets are nil
+		ForEach(fn func(k, v []byte) error) error
+		Cursor() Cursor
+		// Bucket returns nil if not exists
+		Bucket(name []byte) Bucket
+		CreateBucket(name []byte) (Bucket, error)
+		CreateBucketIfNotExists(name []byte) (Bucket, error)
+		DeleteBucket(name []byte) error
+		Sequence() uint64
+		SetSequence(v uint64) error
+		NextSequence() (uint64, error)
+		// KeyN returns the number of keys, sub-buckets excluded. it walks over the whole bucket.
+		KeyN() int
+	}
+
+	// Cursor iterates keys of a bucket in both directions. values of sub-buckets are nil.
+	// moves after a nil key are undefined, seek or go to the first or last key again.
+	Cursor interface {
+		First() (key, value []byte)
+		Last() (key, value []byte)
+		// Seek moves to the first key which is greater than or equal to seek
+		Seek(seek []byte) (key, value []byte)
+		Next() (key, value []byte)
+		Prev() (key, value []byte)
+	}
+)
+
+// Open opens database of engine at path
+func Open(engine, path string) (Engine, error) {
+	switch engine {
+	case EngineBolt, "":
+		return OpenBolt(path)
+	case EngineBadger:
+		return OpenBadger(path)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEngine, engine)
+	}
+}
+
+// Ext returns the extension of database path of engine
+func Ext(engine string) string {
+	if engine == EngineBadger {
+		return ".badger"
+	}
+	return ".db"
+}
+
+// Detect returns the engine of an existing database at path
+func Detect(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return EngineBadger, nil
+	}
+	return EngineBolt, nil
+}
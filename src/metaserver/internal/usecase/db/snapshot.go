@@ -0,0 +1,330 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"metaserver/internal/usecase/db/kv"
+	"time"
+)
+
+// SnapshotMagic heads a chunked snapshot. snapshots without it are the whole bbolt file written by older versions.
+// chunked snapshots are independent of storage engine.
+var SnapshotMagic = []byte("GFSNAP01")
+
+var ErrSnapshotChecksum = errors.New("snapshot chunk checksum mismatch")
+
+const (
+	// maxSnapshotChunkSize bounds a chunk, the size in chunk header is untrusted until its checksum verified
+	maxSnapshotChunkSize = 64 << 20
+	// RestoreBufferSize is the buffer size of reading a snapshot. restoring with a bufio.Reader of this size
+	// leaves data after the snapshot in the reader.
+	RestoreBufferSize = 64 * 1024
+)
+
+const (
+	recordBucket byte = iota
+	recordValue
+)
+
+type SnapshotState string
+
+const (
+	SnapshotIdle      SnapshotState = "idle"
+	SnapshotPersist   SnapshotState = "persisting"
+	SnapshotRestore   SnapshotState = "restoring"
+	SnapshotSucceeded SnapshotState = "succeeded"
+	SnapshotFailed    SnapshotState = "failed"
+)
+
+// SnapshotProgress is the progress of the latest persisting or restoring snapshot
+type SnapshotProgress struct {
+	State      SnapshotState `json:"state"`
+	Chunks     int64         `json:"chunks"`
+	Records    int64         `json:"records"`
+	Bytes      int64         `json:"bytes"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type progressTracker struct {
+	mux sync.Mutex
+	cur SnapshotProgress
+}
+
+func (p *progressTracker) start(state SnapshotState) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.cur = SnapshotProgress{State: state, StartedAt: time.Now()}
+}
+
+func (p *progressTracker) chunk(records, bytes int64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.cur.Chunks++
+	p.cur.Records += records
+	p.cur.Bytes += bytes
+}
+
+func (p *progressTracker) finish(err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.cur.FinishedAt = time.Now()
+	p.cur.State = SnapshotSucceeded
+	if err != nil {
+		p.cur.State, p.cur.Error = SnapshotFailed, err.Error()
+	}
+}
+
+func (p *progressTracker) get() SnapshotProgress {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.cur.State == "" {
+		return SnapshotProgress{State: SnapshotIdle}
+	}
+	return p.cur
+}
+
+// chunkedSnapshot writes all buckets and keys of a read-only tx as a stream of chunks.
+// a chunk is [length uint32][crc32 uint32][records], and a zero length chunk ends the stream.
+// a record is [type byte][path depth uvarint][(len uvarint, name)...][len uvarint, key][len uvarint, value or sequence].
+type chunkedSnapshot struct {
+	tx        kv.ReadTx
+	chunkSize int
+	progress  *progressTracker
+}
+
+func (s *chunkedSnapshot) Rollback() error {
+	return s.tx.Rollback()
+}
+
+func (s *chunkedSnapshot) WriteTo(w io.Writer) (n int64, err error) {
+	s.progress.start(SnapshotPersist)
+	defer func() { s.progress.finish(err) }()
+	cw := &chunkWriter{w: w, size: s.chunkSize, progress: s.progress}
+	if err = cw.writeRaw(SnapshotMagic); err != nil {
+		return cw.written, err
+	}
+	err = s.tx.ForEach(func(name []byte, b kv.Bucket) error {
+		return cw.writeBucket([][]byte{name}, b)
+	})
+	if err == nil {
+		err = cw.close()
+	}
+	return cw.written, err
+}
+
+type chunkWriter struct {
+	w        io.Writer
+	size     int
+	buf      bytes.Buffer
+	records  int64
+	written  int64
+	progress *progressTracker
+}
+
+func (cw *chunkWriter) writeRaw(p []byte) error {
+	n, err := cw.w.Write(p)
+	cw.written += int64(n)
+	return err
+}
+
+func (cw *chunkWriter) writeBucket(path [][]byte, b kv.Bucket) error {
+	if err := cw.writeRecord(recordBucket, path, nil, binary.AppendUvarint(nil, b.Sequence())); err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return cw.writeBucket(append(path[:len(path):len(path)], k), b.Bucket(k))
+		}
+		return cw.writeRecord(recordValue, path, k, v)
+	})
+}
+
+func (cw *chunkWriter) writeRecord(typ byte, path [][]byte, key, value []byte) error {
+	cw.buf.WriteByte(typ)
+	cw.appendBytes(nil, uint64(len(path)))
+	for _, p := range path {
+		cw.appendBytes(p, uint64(len(p)))
+	}
+	cw.appendBytes(key, uint64(len(key)))
+	cw.appendBytes(value, uint64(len(value)))
+	cw.records++
+	if cw.buf.Len() >= cw.size {
+		return cw.flush()
+	}
+	return nil
+}
+
+func (cw *chunkWriter) appendBytes(p []byte, n uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	cw.buf.Write(tmp[:binary.PutUvarint(tmp[:], n)])
+	cw.buf.Write(p)
+}
+
+func (cw *chunkWriter) flush() error {
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	if cw.buf.Len() > maxSnapshotChunkSize {
+		return fmt.Errorf("snapshot chunk of %d bytes exceeds %d bytes", cw.buf.Len(), maxSnapshotChunkSize)
+	}
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(cw.buf.Len()))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(cw.buf.Bytes()))
+	if err := cw.writeRaw(header[:]); err != nil {
+		return err
+	}
+	if err := cw.writeRaw(cw.buf.Bytes()); err != nil {
+		return err
+	}
+	cw.progress.chunk(cw.records, int64(cw.buf.Len()))
+	cw.buf.Reset()
+	cw.records = 0
+	return nil
+}
+
+func (cw *chunkWriter) close() error {
+	if err := cw.flush(); err != nil {
+		return err
+	}
+	return cw.writeRaw(make([]byte, 8))
+}
+
+// isChunkedSnapshot reports whether rd begins with the magic of chunked snapshot and skips it if so
+func isChunkedSnapshot(rd *bufio.Reader) (bool, error) {
+	head, err := rd.Peek(len(SnapshotMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	if !bytes.Equal(head, SnapshotMagic) {
+		return false, nil
+	}
+	_, err = rd.Discard(len(SnapshotMagic))
+	return true, err
+}
+
+// restoreChunks writes records of chunks into db. every chunk is verified and written in a transaction.
+func restoreChunks(rd io.Reader, db kv.Engine, progress *progressTracker) error {
+	// skip syncing every chunk and sync once at the end
+	if ns, ok := db.(interface{ SetNoSync(bool) }); ok {
+		ns.SetNoSync(true)
+		defer ns.SetNoSync(false)
+	}
+	var header [8]byte
+	var buf []byte
+	for {
+		if _, err := io.ReadFull(rd, header[:]); err != nil {
+			return fmt.Errorf("read chunk header: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[:4])
+		if size == 0 {
+			return db.Sync()
+		}
+		if size > maxSnapshotChunkSize {
+			return fmt.Errorf("snapshot chunk of %d bytes exceeds %d bytes", size, maxSnapshotChunkSize)
+		}
+		if cap(buf) < int(size) {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return fmt.Errorf("read chunk: %w", err)
+		}
+		if crc32.ChecksumIEEE(buf) != binary.BigEndian.Uint32(header[4:]) {
+			return ErrSnapshotChecksum
+		}
+		var records int64
+		if err := db.Update(func(tx kv.Tx) error {
+			var err error
+			records, err = applyRecords(tx, buf)
+			return err
+		}); err != nil {
+			return err
+		}
+		progress.chunk(records, int64(size))
+	}
+}
+
+func applyRecords(tx kv.Tx, data []byte) (int64, error) {
+	var records int64
+	rd := bytes.NewReader(data)
+	for rd.Len() > 0 {
+		typ, err := rd.ReadByte()
+		if err != nil {
+			return records, err
+		}
+		depth, err := binary.ReadUvarint(rd)
+		if err != nil {
+			return records, err
+		}
+		if depth > uint64(rd.Len()) {
+			return records, io.ErrUnexpectedEOF
+		}
+		path := make([][]byte, depth)
+		for i := range path {
+			if path[i], err = readBytes(rd); err != nil {
+				return records, err
+			}
+		}
+		key, err := readBytes(rd)
+		if err != nil {
+			return records, err
+		}
+		value, err := readBytes(rd)
+		if err != nil {
+			return records, err
+		}
+		b, err := createBuckets(tx, path)
+		if err != nil {
+			return records, err
+		}
+		switch typ {
+		case recordBucket:
+			seq, _ := binary.Uvarint(value)
+			err = b.SetSequence(seq)
+		case recordValue:
+			err = b.Put(key, value)
+		default:
+			err = fmt.Errorf("unknown snapshot record type %d", typ)
+		}
+		if err != nil {
+			return records, err
+		}
+		records++
+	}
+	return records, nil
+}
+
+func readBytes(rd *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(rd)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(rd.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	p := make([]byte, n)
+	_, err = io.ReadFull(rd, p)
+	return p, err
+}
+
+func createBuckets(tx kv.Tx, path [][]byte) (kv.Bucket, error) {
+	if len(path) == 0 {
+		return nil, errors.New("snapshot record without bucket")
+	}
+	b, err := tx.CreateBucketIfNotExists(path[0])
+	for _, name := range path[1:] {
+		if err != nil {
+			return nil, err
+		}
+		b, err = b.CreateBucketIfNotExists(name)
+	}
+	return b, err
+}
@@ -1,29 +1,36 @@
 package db
 
 import (
+	"bufio"
 	"common/cst"
 	"common/graceful"
 	"common/logs"
 	"common/util"
 	"fmt"
+	"io"
 	"io/fs"
 	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
 	"os"
 	"path/filepath"
 	"sync/atomic"
 	"time"
-
-	bolt "go.etcd.io/bbolt"
 )
 
 var (
 	dbLog = logs.New("storage")
 )
 
+// defaultSnapshotChunkSize is used if SnapshotChunkSize is not set
+const defaultSnapshotChunkSize = 4 << 20
+
 type Storage struct {
-	originalPath string
-	current      atomic.Value
-	rdOnly       atomic.Value
+	SnapshotChunkSize int    // SnapshotChunkSize is the max bytes of records in a snapshot chunk
+	Engine            string // Engine is the storage engine to open, see kv.EngineBolt and kv.EngineBadger
+	originalPath      string
+	current           atomic.Value
+	rdOnly            atomic.Value
+	progress          progressTracker
 }
 
 func NewStorage() *Storage {
@@ -32,8 +39,8 @@ func NewStorage() *Storage {
 	return &Storage{rdOnly: at}
 }
 
-func (s *Storage) DB() *bolt.DB {
-	return s.current.Load().(*bolt.DB)
+func (s *Storage) DB() kv.Engine {
+	return s.current.Load().(kv.Engine)
 }
 
 func (s *Storage) View(fn usecase.TxFunc) error {
@@ -90,6 +97,23 @@ func (s *Storage) FileInfo() (fs.FileInfo, error) {
 	return os.Stat(s.DB().Path())
 }
 
+// Size returns total size of database files, the database of some engines is a directory
+func (s *Storage) Size() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(s.DB().Path(), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
 func (s *Storage) checkPath(path string) error {
 	dir := filepath.Dir(path)
 	_, err := os.Stat(dir)
@@ -103,11 +127,7 @@ func (s *Storage) Open(path string) error {
 	if err := s.checkPath(path); err != nil {
 		return err
 	}
-	cur, err := bolt.Open(path, cst.OS.ModeUser, &bolt.Options{
-		Timeout:      12 * time.Second,
-		NoGrowSync:   false,
-		FreelistType: bolt.FreelistMapType,
-	})
+	cur, err := kv.Open(s.Engine, path)
 	if err != nil {
 		return err
 	}
@@ -117,18 +137,20 @@ func (s *Storage) Open(path string) error {
 }
 
 func (s *Storage) Replace(replacePath string) (err error) {
+	var newDB kv.Engine
+	if newDB, err = kv.Open(s.Engine, replacePath); err != nil {
+		return err
+	}
+	return s.swap(newDB)
+}
+
+// swap changes current db to newDB. storage is read-only only while swapping.
+func (s *Storage) swap(newDB kv.Engine) (err error) {
 	if !s.rdOnly.CompareAndSwap(false, true) {
+		util.LogErr(newDB.Close())
 		return fmt.Errorf("replace failed: storage is in readonly mode")
 	}
 	defer s.rdOnly.Store(false)
-
-	var newDB *bolt.DB
-	if newDB, err = bolt.Open(replacePath, cst.OS.ModeUser, &bolt.Options{
-		Timeout:    12 * time.Second,
-		NoGrowSync: false,
-	}); err != nil {
-		return err
-	}
 	// record current db
 	old := s.DB()
 	// change current db to new one
@@ -142,3 +164,112 @@ func (s *Storage) Replace(replacePath string) (err error) {
 	}()
 	return
 }
+
+// Snapshot begins a read-only transaction which writes all data as chunks with checksum
+func (s *Storage) Snapshot() (usecase.SnapshotTx, error) {
+	tx, err := s.DB().Begin()
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := s.SnapshotChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultSnapshotChunkSize
+	}
+	// leave room for the last record of a chunk
+	chunkSize = util.IfElse(chunkSize > maxSnapshotChunkSize/2, maxSnapshotChunkSize/2, chunkSize)
+	return &chunkedSnapshot{tx: tx, chunkSize: chunkSize, progress: &s.progress}, nil
+}
+
+// Restore rebuilds data from snapshot into a new db file chunk by chunk, then replaces current db with it.
+// current db keeps serving until all chunks are restored. snapshots of the whole db file are also supported.
+func (s *Storage) Restore(r io.Reader) (err error) {
+	s.progress.start(SnapshotRestore)
+	defer func() { s.progress.finish(err) }()
+	newDB, err := restoreFile(s.DB().Name(), s.DB().Path()+"_replace", r, &s.progress)
+	if err != nil {
+		return err
+	}
+	return s.swap(newDB)
+}
+
+// RestoreFile rebuilds a database of engine at path from snapshot
+func RestoreFile(engine, path string, r io.Reader) error {
+	newDB, err := restoreFile(engine, path, r, &progressTracker{})
+	if err != nil {
+		return err
+	}
+	return newDB.Close()
+}
+
+func restoreFile(engine, path string, r io.Reader, progress *progressTracker) (newDB kv.Engine, err error) {
+	if err = os.RemoveAll(path); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			util.LogErrWithPre("remove restoring db file", os.RemoveAll(path))
+		}
+	}()
+	rd := bufio.NewReaderSize(r, RestoreBufferSize)
+	chunked, err := isChunkedSnapshot(rd)
+	if err != nil {
+		return nil, err
+	}
+	if !chunked {
+		return restoreBoltFile(engine, path, rd)
+	}
+	if newDB, err = kv.Open(engine, path); err != nil {
+		return nil, err
+	}
+	if err = restoreChunks(rd, newDB, progress); err != nil {
+		util.LogErr(newDB.Close())
+		return nil, err
+	}
+	return newDB, nil
+}
+
+// restoreBoltFile restores a snapshot of the whole bbolt file, which is converted if engine is not bbolt
+func restoreBoltFile(engine, path string, rd io.Reader) (kv.Engine, error) {
+	if engine == kv.EngineBolt || engine == "" {
+		if err := copyToFile(path, rd); err != nil {
+			return nil, err
+		}
+		return kv.OpenBolt(path)
+	}
+	boltPath := path + "_bolt"
+	defer func() { util.LogErrWithPre("remove converted bolt file", os.RemoveAll(boltPath)) }()
+	if err := copyToFile(boltPath, rd); err != nil {
+		return nil, err
+	}
+	src, err := kv.OpenBolt(boltPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { util.LogErr(src.Close()) }()
+	dst, err := kv.Open(engine, path)
+	if err != nil {
+		return nil, err
+	}
+	if err = Convert(src, dst); err != nil {
+		util.LogErr(dst.Close())
+		return nil, err
+	}
+	return dst, nil
+}
+
+func copyToFile(path string, rd io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, cst.OS.ModeUser)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, rd); err != nil {
+		util.LogErr(file.Close())
+		return err
+	}
+	return file.Close()
+}
+
+// SnapshotProgress returns the progress of the latest persisting or restoring snapshot
+func (s *Storage) SnapshotProgress() SnapshotProgress {
+	return s.progress.get()
+}
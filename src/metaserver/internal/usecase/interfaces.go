@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/raft"
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 )
 
 type (
@@ -18,6 +18,8 @@ type (
 		FilterKeys(fn func(string) bool) []string
 		FindByHash(hash string) (res []*msg.Version, err error)
 		UpdateLocates(hash string, locateIndex int, locate string) error
+		ForeachHashRef(fn func(k, v []byte) error) error
+		GetHashRefBytes(hash string) ([]byte, error)
 	}
 
 	IMetadataService interface {
@@ -33,6 +35,20 @@ type (
 		GetVersion(string, int) (*msg.Version, error)
 		ListVersions(string, int, int) ([]*msg.Version, int, error)
 		ListMetadata(prefix string, size int) (lst []*msg.Metadata, total int, err error)
+		LocateHash(hash string) (*msg.HashRef, error)
+		ReferHash(ref *msg.HashRef) (*msg.HashRef, error)
+		DereferHash(hash string) (*msg.HashRef, error)
+		ReceiveHashRef(ref *msg.HashRef) error
+		RemoveHashRef(hash string) error
+		TouchVersion(name string, ver int) error
+		MarkVersion(name string, ver int, status string) error
+		SwapVersion(name string, data *msg.Version) error
+		ListColdVersions(before int64, cursor string, limit int) ([]string, []*msg.Version, error)
+		ScanVersions(cursor string, limit int) ([]string, []*msg.Version, error)
+		QueryVersions(q *msg.Query) ([]string, []*msg.Version, error)
+		StatVersions(bucket string) (*msg.BucketStat, error)
+		Load() (*entity.StoreLoad, error)
+		Batch(changes []*msg.Change) (*entity.BatchResult, error)
 	}
 
 	WritableRepo interface {
@@ -44,6 +60,9 @@ type (
 		RemoveVersion(string, uint64) error
 		AddVersionFromRaft(string, *msg.Version) error
 		RemoveAllVersion(string) error
+		TouchVersion(string, uint64, int64) error
+		MarkVersion(string, uint64, string) error
+		SwapVersion(string, *msg.Version, int64) error
 	}
 
 	ReadableRepo interface {
@@ -57,6 +76,19 @@ type (
 		Remove(hash, key string) error
 		FindAll(hash string) ([]string, error)
 		Sync() error
+		GetRef(hash string) (*msg.HashRef, error)
+		GetRefBytes(hash string) ([]byte, error)
+		Refer(ref *msg.HashRef) error
+		Derefer(hash string) (*msg.HashRef, error)
+		CreateRef(ref *msg.HashRef) error
+		RemoveRef(hash string) error
+		ForeachRef(fn func(k, v []byte) error) error
+	}
+
+	// OpsRepo applies mutations of metadata, versions and buckets atomically, with records of them written by record
+	OpsRepo interface {
+		ApplyOps(ops []*entity.RaftData, record TxFunc) error
+		Apply(data *entity.RaftData, record TxFunc) error
 	}
 
 	IBatchMetaRepo interface {
@@ -77,6 +109,25 @@ type (
 		ApplyIndex(i uint64) error
 	}
 
+	ChangeFeedRepo interface {
+		Enabled() bool
+		Prepare(data *entity.RaftData, ts time.Time) *msg.Change
+		Append(tx kv.Tx, changes ...*msg.Change) error
+		List(after uint64, limit int) ([]*msg.Change, uint64, error)
+		Trim(before time.Time) (int, error)
+	}
+
+	EventRepo interface {
+		Enabled() bool
+		Publish(tx kv.Tx, changes ...*msg.Change) error
+		List(after uint64, limit int) ([]*msg.Event, uint64, error)
+		GetCursor(consumer string) (uint64, error)
+		SetCursor(consumer string, seq uint64) error
+		SetCursors(cursors map[string]uint64) error
+		Expired(before time.Time) (uint64, error)
+		Trim(until uint64) (int, error)
+	}
+
 	RaftApply interface {
 		ApplyRaft(*entity.RaftData) (bool, any, error)
 	}
@@ -90,14 +141,20 @@ type (
 		ForeachVersionBytes(string, func([]byte) bool)
 		GetMetadataBytes(string) ([]byte, error)
 		GetExtra(id string) (*msg.Extra, error)
+		ListColdVersions(before int64, cursor string, limit int) ([]string, []*msg.Version, error)
+		ScanVersions(cursor string, limit int) ([]string, []*msg.Version, error)
+		QueryVersions(q *msg.Query) ([]string, []*msg.Version, error)
+		StatVersions(bucket string) (*msg.BucketStat, error)
+		Load() (*entity.StoreLoad, error)
+		BuildIndexes() error
 	}
 
-	TxFunc func(*bolt.Tx) error
+	TxFunc func(kv.Tx) error
 
 	ITransaction interface {
-		Update(func(*bolt.Tx) error) error
-		Batch(func(*bolt.Tx) error) error
-		View(func(*bolt.Tx) error) error
+		Update(func(kv.Tx) error) error
+		Batch(func(kv.Tx) error) error
+		View(func(kv.Tx) error) error
 	}
 
 	IRaft interface {
@@ -111,10 +168,11 @@ type (
 
 	IHashSlotService interface {
 		AutoMigrate(toLoc *pb.LocationInfo, slots []string) error
-		PrepareMigrationFrom(loc *pb.LocationInfo, slots []string) error
+		PrepareMigrationFrom(loc *pb.LocationInfo, slots []string, finishByRpc bool) error
 		PrepareMigrationTo(loc *pb.LocationInfo, slots []string) error
 		ReceiveItem(*pb.MigrationItem) error
 		FinishReceiveItem(bool) error
+		FinishOnClose() bool
 		GetCurrentSlots(bool) (map[string][]string, error)
 	}
 
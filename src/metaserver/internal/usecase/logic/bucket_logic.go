@@ -5,7 +5,7 @@ import (
 	"common/proto/msg"
 	"common/util"
 	"errors"
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 	"metaserver/internal/usecase"
 )
 
@@ -20,7 +20,7 @@ func NewBucketCrud() *BucketCrud {
 	return &BucketCrud{}
 }
 
-func (b *BucketCrud) getBucketBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+func (b *BucketCrud) getBucketBucket(tx kv.Tx) (kv.Bucket, error) {
 	if tx.Writable() {
 		return tx.CreateBucketIfNotExists(util.StrToBytes(BucketBucketRoot))
 	}
@@ -28,12 +28,11 @@ func (b *BucketCrud) getBucketBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
 	if bk == nil {
 		return nil, usecase.ErrNotFound
 	}
-	bk.FillPercent = 0.9
 	return bk, nil
 }
 
 func (b *BucketCrud) Get(name string, data *msg.Bucket) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root, err := b.getBucketBucket(tx)
 		if err != nil {
 			return err
@@ -47,7 +46,7 @@ func (b *BucketCrud) Get(name string, data *msg.Bucket) usecase.TxFunc {
 }
 
 func (b *BucketCrud) GetBytes(name string, bt *[]byte) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root, err := b.getBucketBucket(tx)
 		if err != nil {
 			return err
@@ -61,7 +60,7 @@ func (b *BucketCrud) GetBytes(name string, bt *[]byte) usecase.TxFunc {
 }
 
 func (b *BucketCrud) Create(data *msg.Bucket) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if data.Name == "" {
 			return errors.New("empty primary key 'Name'")
 		}
@@ -82,7 +81,7 @@ func (b *BucketCrud) Create(data *msg.Bucket) usecase.TxFunc {
 }
 
 func (b *BucketCrud) Delete(name string) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root, err := b.getBucketBucket(tx)
 		if err != nil {
 			return err
@@ -92,7 +91,7 @@ func (b *BucketCrud) Delete(name string) usecase.TxFunc {
 }
 
 func (b *BucketCrud) Update(data *msg.Bucket) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if data.Name == "" {
 			return errors.New("empty primary key 'Name'")
 		}
@@ -116,7 +115,7 @@ func (b *BucketCrud) Update(data *msg.Bucket) usecase.TxFunc {
 
 func (b *BucketCrud) List(prefix string, limit int, res *[]*msg.Bucket, total *int) usecase.TxFunc {
 	prefixBt := util.StrToBytes(prefix)
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root, err := b.getBucketBucket(tx)
 		if err != nil {
 			return err
@@ -127,7 +126,7 @@ func (b *BucketCrud) List(prefix string, limit int, res *[]*msg.Bucket, total *i
 			k, v = cur.Seek(prefixBt)
 			defer func() { *total = len(*res) }()
 		} else {
-			*total = root.Stats().KeyN
+			*total = root.KeyN()
 			k, v = cur.First()
 		}
 		for k != nil && len(*res) < limit {
@@ -149,7 +148,7 @@ func (b *BucketCrud) List(prefix string, limit int, res *[]*msg.Bucket, total *i
 }
 
 func (b *BucketCrud) Foreach(fn func(k, v []byte) error) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root, err := b.getBucketBucket(tx)
 		if err != nil {
 			return err
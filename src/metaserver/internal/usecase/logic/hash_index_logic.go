@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"metaserver/internal/usecase"
 
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 )
 
 const (
@@ -18,7 +18,7 @@ type HashIndexLogic struct{}
 func NewHashIndexLogic() HashIndexLogic { return HashIndexLogic{} }
 
 func (HashIndexLogic) AddIndex(hash, key string) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, HashIndexName)
 		hashBuk, err := buk.CreateBucketIfNotExists(util.StrToBytes(hash))
 		if err != nil {
@@ -29,7 +29,7 @@ func (HashIndexLogic) AddIndex(hash, key string) usecase.TxFunc {
 }
 
 func (HashIndexLogic) RemoveIndex(hash, key string) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, HashIndexName)
 		if hashBuk := buk.Bucket(util.StrToBytes(hash)); hashBuk != nil {
 			return hashBuk.Delete(util.StrToBytes(key))
@@ -40,7 +40,7 @@ func (HashIndexLogic) RemoveIndex(hash, key string) usecase.TxFunc {
 
 func (HashIndexLogic) GetIndex(hash string, res *[]string) usecase.TxFunc {
 	*res = []string{}
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, HashIndexName)
 		if buk == nil {
 			return nil
@@ -59,7 +59,7 @@ func (HashIndexLogic) GetIndex(hash string, res *[]string) usecase.TxFunc {
 
 func (HashIndexLogic) GetIndexPrefix(hash string, prefix []byte, res *[][]byte) usecase.TxFunc {
 	*res = [][]byte{}
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, HashIndexName)
 		if buk == nil {
 			return nil
@@ -70,22 +70,17 @@ func (HashIndexLogic) GetIndexPrefix(hash string, prefix []byte, res *[][]byte)
 		}
 		cur := hashBuk.Cursor()
 		for k, v := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cur.Next() {
-			*res= append(*res, v)
+			*res = append(*res, v)
 		}
 		return nil
 	}
 }
 
-func GetIndexBucket(tx *bolt.Tx, indexName string) *bolt.Bucket {
+func GetIndexBucket(tx kv.Tx, indexName string) kv.Bucket {
 	bt := util.StrToBytes(fmt.Sprint("go.dfs.index.", indexName))
 	if tx.Writable() {
 		res, _ := tx.CreateBucketIfNotExists(bt)
 		return res
 	}
-	b := tx.Bucket(bt)
-	if b == nil {
-		return nil
-	}
-	b.FillPercent = 0.9
-	return b
+	return tx.Bucket(bt)
 }
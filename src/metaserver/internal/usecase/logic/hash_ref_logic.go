@@ -0,0 +1,149 @@
+package logic
+
+import (
+	"common/proto/msg"
+	"common/util"
+	"errors"
+	"metaserver/internal/usecase"
+
+	"metaserver/internal/usecase/db/kv"
+)
+
+const (
+	HashRefIndexName = "hashRef"
+)
+
+// HashRefLogic maintains reference counter of unique hash.
+// a record is located by the slot of its hash instead of the name of object.
+type HashRefLogic struct{}
+
+func NewHashRefLogic() HashRefLogic { return HashRefLogic{} }
+
+func (HashRefLogic) Get(hash string, ref *msg.HashRef) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		buk := GetIndexBucket(tx, HashRefIndexName)
+		if buk == nil {
+			return usecase.ErrNotFound
+		}
+		v := buk.Get(util.StrToBytes(hash))
+		if v == nil {
+			return usecase.ErrNotFound
+		}
+		return util.DecodeMsgp(ref, v)
+	}
+}
+
+func (HashRefLogic) GetBytes(hash string, bt *[]byte) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		buk := GetIndexBucket(tx, HashRefIndexName)
+		if buk == nil {
+			return usecase.ErrNotFound
+		}
+		*bt = buk.Get(util.StrToBytes(hash))
+		if *bt == nil {
+			return usecase.ErrNotFound
+		}
+		return nil
+	}
+}
+
+// Refer increases counter of ref.Hash. if not exists, creates it with ref.Locate and ref.Count (1 if not positive),
+// or returns ErrNotFound when ref.Locate is empty. ref will be filled with the saved one.
+func (h HashRefLogic) Refer(ref *msg.HashRef) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		if ref.Hash == "" {
+			return errors.New("empty primary key 'Hash'")
+		}
+		var origin msg.HashRef
+		err := h.Get(ref.Hash, &origin)(tx)
+		if usecase.IsNotFound(err) {
+			if len(ref.Locate) == 0 {
+				return err
+			}
+			if ref.Count <= 0 {
+				ref.Count = 1
+			}
+			return h.put(tx, ref)
+		}
+		if err != nil {
+			return err
+		}
+		origin.Count++
+		if err = h.put(tx, &origin); err != nil {
+			return err
+		}
+		*ref = origin
+		return nil
+	}
+}
+
+// Derefer decreases counter of hash and removes it if counter reaches zero.
+// ref will be filled with the remaining one.
+func (h HashRefLogic) Derefer(hash string, ref *msg.HashRef) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		if err := h.Get(hash, ref)(tx); err != nil {
+			return err
+		}
+		ref.Count--
+		if ref.Count <= 0 {
+			ref.Count = 0
+			return GetIndexBucket(tx, HashRefIndexName).Delete(util.StrToBytes(hash))
+		}
+		return h.put(tx, ref)
+	}
+}
+
+// Create saves a ref as it is. returns ErrExists if exists
+func (h HashRefLogic) Create(ref *msg.HashRef) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		if ref.Hash == "" {
+			return errors.New("empty primary key 'Hash'")
+		}
+		if GetIndexBucket(tx, HashRefIndexName).Get(util.StrToBytes(ref.Hash)) != nil {
+			return usecase.ErrExists
+		}
+		return h.put(tx, ref)
+	}
+}
+
+func (HashRefLogic) Remove(hash string) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		return GetIndexBucket(tx, HashRefIndexName).Delete(util.StrToBytes(hash))
+	}
+}
+
+// UpdateLocate update the locate at index. it will do nothing if hash not exists.
+func (h HashRefLogic) UpdateLocate(hash string, index int, value string) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		var ref msg.HashRef
+		if err := h.Get(hash, &ref)(tx); err != nil {
+			if usecase.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if index < 0 || index >= len(ref.Locate) {
+			return nil
+		}
+		ref.Locate[index] = value
+		return h.put(tx, &ref)
+	}
+}
+
+func (HashRefLogic) Foreach(fn func(k, v []byte) error) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		buk := GetIndexBucket(tx, HashRefIndexName)
+		if buk == nil {
+			return nil
+		}
+		return buk.ForEach(fn)
+	}
+}
+
+func (HashRefLogic) put(tx kv.Tx, ref *msg.HashRef) error {
+	bt, err := util.EncodeMsgp(ref)
+	if err != nil {
+		return err
+	}
+	return GetIndexBucket(tx, HashRefIndexName).Put(util.StrToBytes(ref.Hash), bt)
+}
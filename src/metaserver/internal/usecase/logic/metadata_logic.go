@@ -5,12 +5,14 @@ import (
 	"common/logs"
 	"common/proto/msg"
 	"common/util"
+	xmath "common/util/math"
 	"errors"
 	"fmt"
 	. "metaserver/internal/usecase"
+	"strings"
 
 	"github.com/google/uuid"
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 )
 
 const (
@@ -20,7 +22,7 @@ const (
 )
 
 func ForeachKeys(fn func(string) bool) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root := GetMetadataBucket(tx)
 		return root.ForEach(func(k, v []byte) error {
 			if !fn(string(k)) {
@@ -32,7 +34,7 @@ func ForeachKeys(fn func(string) bool) TxFunc {
 }
 
 func AddMeta(id string, data *msg.Metadata) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root := GetMetadataBucket(tx)
 		key := util.StrToBytes(id)
 		// check duplicate
@@ -56,7 +58,7 @@ func AddMeta(id string, data *msg.Metadata) TxFunc {
 }
 
 func RemoveMeta(name string) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		key := util.StrToBytes(name)
 		root := GetMetadataBucket(tx)
 		if root.Get(key) == nil {
@@ -67,7 +69,7 @@ func RemoveMeta(name string) TxFunc {
 		}
 		err := RemoveVersionBucket(tx, name)
 		// ignore err of bucket not found
-		if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		if err != nil && !errors.Is(err, kv.ErrBucketNotFound) {
 			return err
 		}
 		return nil
@@ -75,7 +77,7 @@ func RemoveMeta(name string) TxFunc {
 }
 
 func UpdateMeta(id string, data *msg.Metadata) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		root := GetMetadataBucket(tx)
 		var origin msg.Metadata
 		if err := getMeta(root, id, &origin); err != nil {
@@ -97,18 +99,18 @@ func UpdateMeta(id string, data *msg.Metadata) TxFunc {
 }
 
 func GetMeta(name string, data *msg.Metadata) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		return getMeta(GetMetadataBucket(tx), name, data)
 	}
 }
 
 func GetExtra(id string, extra *msg.Extra) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		b := GetVersionBucket(tx, id)
 		if b == nil {
 			return ErrNotFound
 		}
-		extra.Total = b.Stats().KeyN
+		extra.Total = b.KeyN()
 		cur := b.Cursor()
 		// first key
 		k, _ := cur.First()
@@ -124,7 +126,7 @@ func GetExtra(id string, extra *msg.Extra) TxFunc {
 	}
 }
 
-func ExistsByUniqueId(tx *bolt.Tx, uniqueId string) error {
+func ExistsByUniqueId(tx kv.Tx, uniqueId string) error {
 	var byUniqueId []string
 	if err := NewUniqueIdIndex().GetIndex(uniqueId, &byUniqueId)(tx); err != nil {
 		return err
@@ -136,7 +138,7 @@ func ExistsByUniqueId(tx *bolt.Tx, uniqueId string) error {
 }
 
 func AddVerWithSequence(name string, data *msg.Version) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if bucket := GetVersionBucket(tx, name); bucket != nil {
 			if err := ExistsByUniqueId(tx, data.UniqueId); err != nil {
 				return err
@@ -162,14 +164,17 @@ func AddVerWithSequence(name string, data *msg.Version) TxFunc {
 			if err = NewUniqueIdIndex().AddIndex(data.Hash, keyStr)(tx); err != nil {
 				return err
 			}
-			return NewHashIndexLogic().AddIndex(data.Hash, keyStr)(tx)
+			if err = NewHashIndexLogic().AddIndex(data.Hash, keyStr)(tx); err != nil {
+				return err
+			}
+			return IndexVer(tx, name, data)
 		}
 		return ErrNotFound
 	}
 }
 
 func AddVer(name string, data *msg.Version) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if bucket := GetVersionBucket(tx, name); bucket != nil {
 			if err := ExistsByUniqueId(tx, data.UniqueId); err != nil {
 				return err
@@ -190,14 +195,17 @@ func AddVer(name string, data *msg.Version) TxFunc {
 			if err = NewHashIndexLogic().AddIndex(data.Hash, keyStr)(tx); err != nil {
 				return err
 			}
-			return NewUniqueIdIndex().AddIndex(data.UniqueId, keyStr)(tx)
+			if err = NewUniqueIdIndex().AddIndex(data.UniqueId, keyStr)(tx); err != nil {
+				return err
+			}
+			return IndexVer(tx, name, data)
 		}
 		return ErrNotFound
 	}
 }
 
 func RemoveVer(name string, ver uint64) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		key := util.StrToBytes(fmt.Sprint(name, Sep, ver))
 		b := GetVersionBucket(tx, name)
 		if b == nil {
@@ -215,12 +223,15 @@ func RemoveVer(name string, ver uint64) TxFunc {
 		if err := NewUniqueIdIndex().RemoveIndex(data.Hash, keyStr)(tx); err != nil {
 			return fmt.Errorf("remove uniqueId-index err: %w", err)
 		}
+		if err := UnindexVer(tx, name, &data); err != nil {
+			return fmt.Errorf("remove secondary-index err: %w", err)
+		}
 		return b.Delete(key)
 	}
 }
 
 func UpdateVer(id string, data *msg.Version) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if b := GetVersionBucket(tx, id); b != nil {
 			key := util.StrToBytes(fmt.Sprint(id, Sep, data.Sequence))
 			// get old one
@@ -236,11 +247,20 @@ func UpdateVer(id string, data *msg.Version) TxFunc {
 			data.UniqueId = origin.UniqueId
 			data.Sequence = origin.Sequence
 			data.Hash = origin.Hash
+			data.Digest = origin.Digest
+			data.AccessTs = origin.AccessTs
+			data.Inline = origin.Inline
 			// encode to bytes
 			bt, err := util.EncodeMsgp(data)
 			if err != nil {
 				return err
 			}
+			if err = UnindexVer(tx, id, &origin); err != nil {
+				return fmt.Errorf("remove secondary-index err: %w", err)
+			}
+			if err = IndexVer(tx, id, data); err != nil {
+				return fmt.Errorf("add secondary-index err: %w", err)
+			}
 			return b.Put(key, bt)
 		}
 		return ErrNotFound
@@ -248,7 +268,7 @@ func UpdateVer(id string, data *msg.Version) TxFunc {
 }
 
 func GetVer(id string, ver uint64, dest *msg.Version) TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		if bucket := GetVersionBucket(tx, id); bucket != nil {
 			return getVer(bucket, id, ver, dest)
 		}
@@ -256,27 +276,168 @@ func GetVer(id string, ver uint64, dest *msg.Version) TxFunc {
 	}
 }
 
+// TouchVer update the accessing time of version. an earlier ts will be ignored.
+func TouchVer(id string, ver uint64, ts int64) TxFunc {
+	return func(tx kv.Tx) error {
+		b := GetVersionBucket(tx, id)
+		var origin msg.Version
+		if err := getVer(b, id, ver, &origin); err != nil {
+			return err
+		}
+		if ts <= origin.AccessTs {
+			return nil
+		}
+		origin.AccessTs = ts
+		bt, err := util.EncodeMsgp(&origin)
+		if err != nil {
+			return err
+		}
+		return b.Put(util.StrToBytes(fmt.Sprint(id, Sep, ver)), bt)
+	}
+}
+
+// MarkVer sets the replication status of version without changing its ts
+func MarkVer(id string, ver uint64, status string) TxFunc {
+	return func(tx kv.Tx) error {
+		b := GetVersionBucket(tx, id)
+		var origin msg.Version
+		if err := getVer(b, id, ver, &origin); err != nil {
+			return err
+		}
+		if origin.Replication == status {
+			return nil
+		}
+		origin.Replication = status
+		bt, err := util.EncodeMsgp(&origin)
+		if err != nil {
+			return err
+		}
+		return b.Put(util.StrToBytes(fmt.Sprint(id, Sep, ver)), bt)
+	}
+}
+
+// SwapVer replace the storage layout (hash, strategy, shards and locations) of version, Ts of version is unchanged.
+// it fails with ErrOldData if version has been modified after the time of 'expect' or the layout has been swapped.
+func SwapVer(id string, data *msg.Version, expect int64) TxFunc {
+	return func(tx kv.Tx) error {
+		b := GetVersionBucket(tx, id)
+		var origin msg.Version
+		if err := getVer(b, id, data.Sequence, &origin); err != nil {
+			return err
+		}
+		if origin.Ts != expect || origin.Hash == data.Hash {
+			return ErrOldData
+		}
+		keyStr := fmt.Sprint(id, Sep, data.Sequence)
+		if origin.Hash != data.Hash {
+			if err := NewHashIndexLogic().RemoveIndex(origin.Hash, keyStr)(tx); err != nil {
+				return fmt.Errorf("remove hash-index err: %w", err)
+			}
+			if err := NewHashIndexLogic().AddIndex(data.Hash, keyStr)(tx); err != nil {
+				return fmt.Errorf("add hash-index err: %w", err)
+			}
+		}
+		if err := UnindexVer(tx, id, &origin); err != nil {
+			return fmt.Errorf("remove secondary-index err: %w", err)
+		}
+		origin.Hash = data.Hash
+		origin.Compress = data.Compress
+		origin.Codec = data.Codec
+		origin.CompressRatio = data.CompressRatio
+		origin.StoreStrategy = data.StoreStrategy
+		origin.DataShards = data.DataShards
+		origin.ParityShards = data.ParityShards
+		origin.LocalGroups = data.LocalGroups
+		origin.Inline = data.Inline
+		origin.ShardSize = data.ShardSize
+		origin.Locate = data.Locate
+		if origin.Digest == "" {
+			origin.Digest = data.Digest
+		}
+		if err := IndexVer(tx, id, &origin); err != nil {
+			return fmt.Errorf("add secondary-index err: %w", err)
+		}
+		bt, err := util.EncodeMsgp(&origin)
+		if err != nil {
+			return err
+		}
+		*data = origin
+		return b.Put(util.StrToBytes(keyStr), bt)
+	}
+}
+
+// ListColdVer lists at most 'limit' versions which have not been written or read since 'before'.
+// iteration starts after the version key 'cursor' ("name.sequence"), keys of results are written to 'keys'.
+func ListColdVer(before int64, cursor string, limit int, keys *[]string, res *[]*msg.Version) TxFunc {
+	return ScanVer(cursor, limit, func(ver *msg.Version) bool {
+		return xmath.MaxNumber(ver.Ts, ver.AccessTs) < before
+	}, keys, res)
+}
+
+// ScanVer lists at most 'limit' versions accepted by filter (all if nil) in the order of version keys.
+// iteration starts after the version key 'cursor' ("name.sequence"), keys of results are written to 'keys'.
+func ScanVer(cursor string, limit int, filter func(*msg.Version) bool, keys *[]string, res *[]*msg.Version) TxFunc {
+	return func(tx kv.Tx) error {
+		root := getVersionRoot(tx)
+		if root == nil {
+			return nil
+		}
+		var name, last []byte
+		if idx := strings.LastIndexByte(cursor, Sep[0]); idx > 0 {
+			name, last = util.StrToBytes(cursor[:idx]), util.StrToBytes(cursor)
+		}
+		rc := root.Cursor()
+		rk, rv := rc.First()
+		if name != nil {
+			rk, rv = rc.Seek(name)
+		}
+		for ; rk != nil && len(*res) < limit; rk, rv = rc.Next() {
+			// sub-buckets have nil values
+			if rv != nil {
+				continue
+			}
+			b := root.Bucket(rk)
+			if b == nil {
+				continue
+			}
+			c := b.Cursor()
+			k, v := c.First()
+			if last != nil && bytes.Equal(rk, name) {
+				if k, v = c.Seek(last); bytes.Equal(k, last) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil && len(*res) < limit; k, v = c.Next() {
+				var ver msg.Version
+				if err := util.DecodeMsgp(&ver, v); err != nil {
+					return err
+				}
+				if filter != nil && !filter(&ver) {
+					continue
+				}
+				*keys = append(*keys, string(k))
+				*res = append(*res, &ver)
+			}
+		}
+		return nil
+	}
+}
+
 // GetMetadataBucket get or create metadata root bucket
-func GetMetadataBucket(tx *bolt.Tx) *bolt.Bucket {
+func GetMetadataBucket(tx kv.Tx) kv.Bucket {
 	if tx.Writable() {
 		root, err := tx.CreateBucketIfNotExists(util.StrToBytes(MetadataBucketRoot))
 		if err != nil {
 			logs.Std().Error(err)
 			return nil
 		}
-		root.FillPercent = 0.9
 		return root
 	} else {
-		b := tx.Bucket(util.StrToBytes(MetadataBucketRoot))
-		if b == nil {
-			return b
-		}
-		b.FillPercent = 0.9
-		return b
+		return tx.Bucket(util.StrToBytes(MetadataBucketRoot))
 	}
 }
 
-func CreateVersionBucket(tx *bolt.Tx, name string) error {
+func CreateVersionBucket(tx kv.Tx, name string) error {
 	root := getVersionRoot(tx)
 	if root == nil {
 		return errors.New("version root is nil")
@@ -287,12 +448,62 @@ func CreateVersionBucket(tx *bolt.Tx, name string) error {
 	return nil
 }
 
-func RemoveVersionBucket(tx *bolt.Tx, name string) error {
-	return getVersionRoot(tx).DeleteBucket(util.StrToBytes(name))
+func RemoveVersionBucket(tx kv.Tx, name string) error {
+	root := getVersionRoot(tx)
+	if b := root.Bucket(util.StrToBytes(name)); b != nil {
+		// drop versions from secondary indexes
+		err := b.ForEach(func(_, v []byte) error {
+			var ver msg.Version
+			if err := util.DecodeMsgp(&ver, v); err != nil {
+				return err
+			}
+			return UnindexVer(tx, name, &ver)
+		})
+		if err != nil {
+			return fmt.Errorf("remove secondary-index err: %w", err)
+		}
+	}
+	return root.DeleteBucket(util.StrToBytes(name))
+}
+
+// TrimVersions removes the first 'limit' versions of object with their secondary indexes if it has more than 'limit' versions,
+// otherwise nothing is removed. removed versions are written to 'removed'.
+// it bounds transactions of removing the version bucket of a large object, the bucket and its sequence are kept.
+func TrimVersions(name string, limit int, removed *[]*msg.Version) TxFunc {
+	return func(tx kv.Tx) error {
+		*removed = nil
+		b := GetVersionBucket(tx, name)
+		if b == nil {
+			return nil
+		}
+		var keys [][]byte
+		var vers []*msg.Version
+		c := b.Cursor()
+		for k, v := c.First(); k != nil && len(keys) <= limit; k, v = c.Next() {
+			var ver msg.Version
+			if err := util.DecodeMsgp(&ver, v); err != nil {
+				return err
+			}
+			keys, vers = append(keys, k), append(vers, &ver)
+		}
+		if len(keys) <= limit {
+			return nil
+		}
+		for i, k := range keys[:limit] {
+			if err := UnindexVer(tx, name, vers[i]); err != nil {
+				return fmt.Errorf("remove secondary-index err: %w", err)
+			}
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		*removed = vers[:limit]
+		return nil
+	}
 }
 
 // getVersionRoot get or create version root bucket
-func getVersionRoot(tx *bolt.Tx) *bolt.Bucket {
+func getVersionRoot(tx kv.Tx) kv.Bucket {
 	if tx.Writable() {
 		root, err := tx.CreateBucketIfNotExists(util.StrToBytes(VersionBucketRoot))
 		if err != nil {
@@ -306,17 +517,14 @@ func getVersionRoot(tx *bolt.Tx) *bolt.Bucket {
 }
 
 // GetVersionBucket get version bucket for given name
-func GetVersionBucket(tx *bolt.Tx, name string) *bolt.Bucket {
+func GetVersionBucket(tx kv.Tx, name string) kv.Bucket {
 	if root := getVersionRoot(tx); root != nil {
-		if b := root.Bucket(util.StrToBytes(name)); b != nil {
-			b.FillPercent = 0.9
-			return b
-		}
+		return root.Bucket(util.StrToBytes(name))
 	}
 	return nil
 }
 
-func getVer(bucket *bolt.Bucket, id string, ver uint64, dest *msg.Version) error {
+func getVer(bucket kv.Bucket, id string, ver uint64, dest *msg.Version) error {
 	if bucket == nil {
 		return ErrNotFound
 	}
@@ -327,7 +535,7 @@ func getVer(bucket *bolt.Bucket, id string, ver uint64, dest *msg.Version) error
 	return util.DecodeMsgp(dest, bt)
 }
 
-func getMeta(b *bolt.Bucket, name string, dest *msg.Metadata) error {
+func getMeta(b kv.Bucket, name string, dest *msg.Metadata) error {
 	if b == nil {
 		return ErrNotFound
 	}
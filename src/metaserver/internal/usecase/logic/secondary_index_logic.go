@@ -0,0 +1,377 @@
+package logic
+
+import (
+	"bytes"
+	"common/proto/msg"
+	"common/util"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"metaserver/internal/usecase"
+	"strconv"
+	"strings"
+
+	"metaserver/internal/usecase/db/kv"
+)
+
+const (
+	IndexBucket      = "sec.bucket"
+	IndexSize        = "sec.size"
+	IndexTs          = "sec.ts"
+	IndexContentType = "sec.content_type"
+	IndexTag         = "sec.tag"
+
+	// SecondaryIndexVersion must be increased when SecondaryIndexes changes, so that indexes are rebuilt on starting
+	SecondaryIndexVersion = 2
+	secondaryIndexMeta    = "sec.meta"
+)
+
+// SecondaryIndex declares an index of versions. keys of index are "bucket \0 value versionKey",
+// so versions in a bucket are ordered by value and then by key "bucket/name.sequence", see msg.QueryKey.
+type SecondaryIndex struct {
+	Name    string
+	Numeric bool                          // Numeric value is a 8 bytes big-endian number, otherwise it's a string ends with \0
+	Values  func(v *msg.Version) []string // Values returns the values to index, a version may have none or many
+}
+
+// SecondaryIndexes are indexes maintained on every writing of versions
+var SecondaryIndexes = []*SecondaryIndex{
+	{Name: IndexBucket, Values: func(*msg.Version) []string { return []string{""} }},
+	{Name: IndexSize, Numeric: true, Values: func(v *msg.Version) []string { return []string{numValue(v.Size)} }},
+	{Name: IndexTs, Numeric: true, Values: func(v *msg.Version) []string { return []string{numValue(v.Ts)} }},
+	{Name: IndexContentType, Values: func(v *msg.Version) []string {
+		if v.ContentType == "" {
+			return nil
+		}
+		return []string{v.ContentType}
+	}},
+	{Name: IndexTag, Values: func(v *msg.Version) []string {
+		res := make([]string, 0, len(v.Tags))
+		for k, val := range v.Tags {
+			res = append(res, k+"="+val)
+		}
+		return res
+	}},
+}
+
+func numValue(n int64) string {
+	var bt [8]byte
+	binary.BigEndian.PutUint64(bt[:], uint64(n))
+	return string(bt[:])
+}
+
+// value returns the encoded value in keys of index
+func (si *SecondaryIndex) value(v string) []byte {
+	if si.Numeric || v == "" {
+		return util.StrToBytes(v)
+	}
+	return append([]byte(v), 0)
+}
+
+func (si *SecondaryIndex) key(bucket string, value []byte, verKey string) []byte {
+	key := make([]byte, 0, len(bucket)+1+len(value)+len(verKey))
+	key = append(key, bucket...)
+	key = append(key, 0)
+	key = append(key, value...)
+	return append(key, verKey...)
+}
+
+// update adds or removes index entries of version
+func (si *SecondaryIndex) update(tx kv.Tx, bucket, verKey string, v *msg.Version, add bool) error {
+	b := GetIndexBucket(tx, si.Name)
+	for _, val := range si.Values(v) {
+		key := si.key(bucket, si.value(val), verKey)
+		var err error
+		if add {
+			err = b.Put(key, []byte{})
+		} else {
+			err = b.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexVer adds version to all secondary indexes. id is "bucket/name".
+func IndexVer(tx kv.Tx, id string, v *msg.Version) error {
+	return updateSecondaryIndexes(tx, id, v, true)
+}
+
+// UnindexVer removes version from all secondary indexes. id is "bucket/name".
+func UnindexVer(tx kv.Tx, id string, v *msg.Version) error {
+	return updateSecondaryIndexes(tx, id, v, false)
+}
+
+func updateSecondaryIndexes(tx kv.Tx, id string, v *msg.Version, add bool) error {
+	bucket, _, ok := strings.Cut(id, "/")
+	if !ok {
+		return nil
+	}
+	verKey := msg.QueryKey(id, v.Sequence)
+	for _, si := range SecondaryIndexes {
+		if err := si.update(tx, bucket, verKey, v, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SecondaryIndexBuilt returns true if indexes have been built with current SecondaryIndexVersion
+func SecondaryIndexBuilt(built *bool) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		b := GetIndexBucket(tx, secondaryIndexMeta)
+		if b == nil {
+			*built = false
+			return nil
+		}
+		v := b.Get([]byte("version"))
+		*built = len(v) == 8 && binary.BigEndian.Uint64(v) == SecondaryIndexVersion
+		return nil
+	}
+}
+
+// DropSecondaryIndexes removes all secondary indexes and the mark of built
+func DropSecondaryIndexes() usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		for _, si := range SecondaryIndexes {
+			err := tx.DeleteBucket(util.StrToBytes("go.dfs.index." + si.Name))
+			if err != nil && !errors.Is(err, kv.ErrBucketNotFound) {
+				return err
+			}
+		}
+		err := tx.DeleteBucket(util.StrToBytes("go.dfs.index." + secondaryIndexMeta))
+		if errors.Is(err, kv.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	}
+}
+
+// IndexCursor is the position of rebuilding indexes, the last version indexed
+type IndexCursor struct {
+	Id   []byte // Id is the version bucket "bucket/name"
+	Key  []byte // Key is the version key in bucket
+	Done bool
+}
+
+// IndexVersions adds at most limit versions after cursor to secondary indexes, and moves cursor to the last one.
+// cursor is done if all versions have been indexed.
+func IndexVersions(cursor *IndexCursor, limit int) usecase.TxFunc {
+	// the tx may be retried, so it always starts from the position before it
+	fromId, fromKey := cursor.Id, cursor.Key
+	return func(tx kv.Tx) error {
+		cursor.Id, cursor.Key, cursor.Done = fromId, fromKey, false
+		root := getVersionRoot(tx)
+		n := 0
+		c := root.Cursor()
+		name, v := c.First()
+		if fromId != nil {
+			name, v = c.Seek(fromId)
+		}
+		for ; name != nil; name, v = c.Next() {
+			// sub-buckets have nil values
+			if v != nil {
+				continue
+			}
+			id := string(name)
+			vc := root.Bucket(name).Cursor()
+			k, bt := vc.First()
+			if bytes.Equal(name, fromId) {
+				if k, bt = vc.Seek(fromKey); bytes.Equal(k, fromKey) {
+					k, bt = vc.Next()
+				}
+			}
+			for ; k != nil; k, bt = vc.Next() {
+				if n >= limit {
+					return nil
+				}
+				var ver msg.Version
+				if err := util.DecodeMsgp(&ver, bt); err != nil {
+					return err
+				}
+				if err := IndexVer(tx, id, &ver); err != nil {
+					return err
+				}
+				cursor.Id, cursor.Key = append([]byte(nil), name...), append([]byte(nil), k...)
+				n++
+			}
+		}
+		cursor.Done = true
+		return nil
+	}
+}
+
+// MarkSecondaryIndexBuilt marks indexes have been built with current SecondaryIndexVersion
+func MarkSecondaryIndexBuilt() usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		return GetIndexBucket(tx, secondaryIndexMeta).Put([]byte("version"), util.StrToBytes(numValue(SecondaryIndexVersion)))
+	}
+}
+
+// QueryVer finds at most q.Limit versions matching query in the order of q.Sort, starting after q.Cursor.
+// an index is chosen to drive the scan by sort field and filters, other filters are checked on every version.
+// keys "bucket/name.sequence" of results are written to 'keys'.
+func QueryVer(q *msg.Query, keys *[]string, res *[]*msg.Version) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		cursor, err := msg.ParseQueryCursor(q.Cursor)
+		if err != nil {
+			return err
+		}
+		si, lo, hi, value := scanRange(q)
+		b := GetIndexBucket(tx, si.Name)
+		verRoot := getVersionRoot(tx)
+		if b == nil || verRoot == nil {
+			return nil
+		}
+		var start []byte
+		if !cursor.IsZero() {
+			if si.Numeric {
+				value = util.StrToBytes(numValue(cursor.Value))
+			}
+			start = si.key(q.Bucket, value, cursor.Key)
+		}
+		prefixLen := len(q.Bucket) + 1 + util.IfElse(si.Numeric, 8, len(value))
+		visit := func(k []byte) (bool, error) {
+			if start != nil && bytes.Equal(k, start) {
+				return true, nil
+			}
+			verKey := k[prefixLen:]
+			ver, err := indexedVer(verRoot, verKey)
+			if err != nil {
+				return false, err
+			}
+			if ver != nil && q.Match(ver) {
+				*keys = append(*keys, string(verKey))
+				*res = append(*res, ver)
+			}
+			return len(*res) < q.Limit, nil
+		}
+		c := b.Cursor()
+		if q.Desc {
+			from := hi
+			if start != nil && bytes.Compare(start, hi) < 0 {
+				from = start
+			}
+			k, _ := c.Seek(from)
+			if k == nil {
+				k, _ = c.Last()
+			} else {
+				k, _ = c.Prev()
+			}
+			for ; k != nil && bytes.Compare(k, lo) >= 0; k, _ = c.Prev() {
+				if ok, err := visit(k); !ok || err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		from := lo
+		if start != nil && bytes.Compare(start, lo) > 0 {
+			from = start
+		}
+		for k, _ := c.Seek(from); k != nil && bytes.Compare(k, hi) < 0; k, _ = c.Next() {
+			if ok, err := visit(k); !ok || err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StatVer aggregates all versions of bucket on this server by the bucket index
+func StatVer(bucket string, stat *msg.BucketStat) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		stat.Bucket = bucket
+		b := GetIndexBucket(tx, IndexBucket)
+		verRoot := getVersionRoot(tx)
+		if b == nil || verRoot == nil {
+			return nil
+		}
+		lo := secondaryIndex(IndexBucket).key(bucket, []byte{}, "")
+		hi := append(lo[:len(lo):len(lo)], 0xff)
+		c := b.Cursor()
+		for k, _ := c.Seek(lo); k != nil && bytes.Compare(k, hi) < 0; k, _ = c.Next() {
+			ver, err := indexedVer(verRoot, k[len(lo):])
+			if err != nil {
+				return err
+			}
+			if ver != nil {
+				stat.Add(ver)
+			}
+		}
+		return nil
+	}
+}
+
+// indexedVer returns the version of key "bucket/name.sequence" in index, nil if it doesn't exist
+func indexedVer(verRoot kv.Bucket, verKey []byte) (*msg.Version, error) {
+	idx := bytes.LastIndexByte(verKey, Sep[0])
+	if idx < 0 {
+		return nil, nil
+	}
+	vb := verRoot.Bucket(verKey[:idx])
+	if vb == nil {
+		return nil, nil
+	}
+	seq, err := strconv.ParseUint(string(verKey[idx+1:]), 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	bt := vb.Get(util.StrToBytes(fmt.Sprint(string(verKey[:idx]), Sep, seq)))
+	if bt == nil {
+		return nil, nil
+	}
+	var ver msg.Version
+	if err := util.DecodeMsgp(&ver, bt); err != nil {
+		return nil, err
+	}
+	return &ver, nil
+}
+
+// scanRange chooses the index for query and returns the range [lo, hi) of keys to scan.
+// value is the fixed value of keys in range, nil if the index is numeric.
+func scanRange(q *msg.Query) (si *SecondaryIndex, lo, hi, value []byte) {
+	end := append([]byte(q.Bucket), 1)
+	switch q.Sort {
+	case msg.SortBySize:
+		si = secondaryIndex(IndexSize)
+		lo = si.key(q.Bucket, util.StrToBytes(numValue(q.MinSize)), "")
+		if hi = end; q.MaxSize > 0 {
+			hi = si.key(q.Bucket, util.StrToBytes(numValue(q.MaxSize+1)), "")
+		}
+		return
+	case msg.SortByTs:
+		si = secondaryIndex(IndexTs)
+		lo = si.key(q.Bucket, util.StrToBytes(numValue(q.After)), "")
+		if hi = end; q.Before > 0 {
+			hi = si.key(q.Bucket, util.StrToBytes(numValue(q.Before)), "")
+		}
+		return
+	}
+	switch {
+	case q.ContentType != "":
+		si = secondaryIndex(IndexContentType)
+		value = si.value(q.ContentType)
+	case len(q.Tags) > 0:
+		si = secondaryIndex(IndexTag)
+		value = si.value(q.Tags[0])
+	default:
+		si = secondaryIndex(IndexBucket)
+		value = []byte{}
+	}
+	lo = si.key(q.Bucket, value, "")
+	// version keys are printable, so all keys with the prefix are less than it
+	hi = append(lo[:len(lo):len(lo)], 0xff)
+	return
+}
+
+func secondaryIndex(name string) *SecondaryIndex {
+	for _, si := range SecondaryIndexes {
+		if si.Name == name {
+			return si
+		}
+	}
+	panic("unknown secondary index " + name)
+}
@@ -4,7 +4,7 @@ import (
 	"common/util"
 	"metaserver/internal/usecase"
 
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 )
 
 const (
@@ -18,7 +18,7 @@ func NewUniqueIdIndex() UniqueIdIndex {
 }
 
 func (UniqueIdIndex) AddIndex(uniqueId, key string) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, UniqueIdIndexName)
 		uniqueIdBuk, err := buk.CreateBucketIfNotExists(util.StrToBytes(uniqueId))
 		if err != nil {
@@ -29,7 +29,7 @@ func (UniqueIdIndex) AddIndex(uniqueId, key string) usecase.TxFunc {
 }
 
 func (UniqueIdIndex) RemoveIndex(uniqueId, key string) usecase.TxFunc {
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, UniqueIdIndexName)
 		if uniqueIdBuk := buk.Bucket(util.StrToBytes(uniqueId)); uniqueIdBuk != nil {
 			return uniqueIdBuk.Delete(util.StrToBytes(key))
@@ -40,7 +40,7 @@ func (UniqueIdIndex) RemoveIndex(uniqueId, key string) usecase.TxFunc {
 
 func (UniqueIdIndex) GetIndex(uniqueId string, res *[]string) usecase.TxFunc {
 	*res = []string{}
-	return func(tx *bolt.Tx) error {
+	return func(tx kv.Tx) error {
 		buk := GetIndexBucket(tx, UniqueIdIndexName)
 		if buk == nil {
 			return nil
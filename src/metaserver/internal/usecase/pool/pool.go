@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"metaserver/config"
 	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/db/kv"
 	"metaserver/internal/usecase/raftimpl"
 	"path/filepath"
 	"time"
@@ -72,7 +73,9 @@ func initEtcd(cfg *etcd.Config) {
 func initStorage(cfg *config.Config) {
 	// open db file
 	Storage = db.NewStorage()
-	if err := Storage.Open(filepath.Join(cfg.DataPath, cfg.Registry.SID()+".db")); err != nil {
+	Storage.SnapshotChunkSize = int(cfg.Cluster.Snapshot.ChunkSize)
+	Storage.Engine = cfg.StorageEngine
+	if err := Storage.Open(filepath.Join(cfg.DataPath, cfg.Registry.SID()+kv.Ext(cfg.StorageEngine))); err != nil {
 		panic(fmt.Errorf("open db err: %v", err))
 	}
 }
@@ -82,7 +85,7 @@ func initRegistry(cfg *config.Config, etcd *clientv3.Client) {
 }
 
 func initHashSlot(cfg *registry.Config, etcd *clientv3.Client) {
-	HashSlot = db.NewHashSlotDB(cst.EtcdPrefix.FmtHashSlot(cfg.Group, ""), etcd)
+	HashSlot = db.NewHashSlotDB(cst.EtcdPrefix.FmtHashSlot(cfg.Group, ""), cst.EtcdPrefix.FmtMigration(cfg.Group, ""), etcd)
 }
 
 func initCache(cfg config.CacheConfig) {
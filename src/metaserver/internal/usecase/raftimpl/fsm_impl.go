@@ -1,7 +1,9 @@
 package raftimpl
 
 import (
+	"bufio"
 	"common/logs"
+	"common/proto/msg"
 	"common/response"
 	"common/util"
 	"compress/gzip"
@@ -10,6 +12,9 @@ import (
 	"io"
 	"metaserver/internal/entity"
 	. "metaserver/internal/usecase"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/db/kv"
+	"time"
 
 	"github.com/hashicorp/raft"
 )
@@ -23,16 +28,68 @@ type FSMImpl struct {
 	metaBatch   IBatchMetaRepo
 	bucketRepo  BucketRepo
 	bucketBatch BatchBucketRepo
+	hashIndex   IHashIndexRepo
 	snapshot    SnapshotManager
+	feed        ChangeFeedRepo
+	events      EventRepo
+	ops         OpsRepo
 }
 
-func NewFSM(m IMetadataRepo, mb IBatchMetaRepo, b BucketRepo, bb BatchBucketRepo, sm SnapshotManager) raft.FSM {
+func NewFSM(m IMetadataRepo, mb IBatchMetaRepo, b BucketRepo, bb BatchBucketRepo, h IHashIndexRepo, sm SnapshotManager, cf ChangeFeedRepo, ev EventRepo, ops OpsRepo) raft.FSM {
 	return &FSMImpl{
 		metaRepo:    m,
 		metaBatch:   mb,
 		bucketRepo:  b,
 		bucketBatch: bb,
+		hashIndex:   h,
 		snapshot:    sm,
+		feed:        cf,
+		events:      ev,
+		ops:         ops,
+	}
+}
+
+// applyHashRef LogInsert to refer, LogUpdate to derefer, LogRemove to delete whole ref.
+func (f *FSMImpl) applyHashRef(data *entity.RaftData) *FSMResponse {
+	switch data.Type {
+	case entity.LogMigrate:
+		return FSMResult(f.hashIndex.CreateRef(data.HashRef))
+	case entity.LogInsert:
+		resp := FSMResult(f.hashIndex.Refer(data.HashRef))
+		resp.Data = data.HashRef
+		return resp
+	case entity.LogUpdate:
+		ref, err := f.hashIndex.Derefer(data.Name)
+		resp := FSMResult(err)
+		resp.Data = ref
+		return resp
+	case entity.LogRemove:
+		return FSMResult(f.hashIndex.RemoveRef(data.Name))
+	default:
+		return FSMResult(ErrUnknownRaftLog)
+	}
+}
+
+// applyEventCursor LogUpdate to save the acknowledged seq of consumer, or seqs of consumers in Ops.
+// LogRemove to trim events until the sequence.
+func (f *FSMImpl) applyEventCursor(data *entity.RaftData) *FSMResponse {
+	switch data.Type {
+	case entity.LogUpdate:
+		if len(data.Ops) == 0 {
+			return FSMResult(f.events.SetCursor(data.Name, data.Sequence))
+		}
+		cursors := make(map[string]uint64, len(data.Ops))
+		for _, op := range data.Ops {
+			cursors[op.Name] = op.Sequence
+		}
+		return FSMResult(f.events.SetCursors(cursors))
+	case entity.LogRemove:
+		n, err := f.events.Trim(data.Sequence)
+		resp := FSMResult(err)
+		resp.Data = n
+		return resp
+	default:
+		return FSMResult(ErrUnknownRaftLog)
 	}
 }
 
@@ -79,6 +136,13 @@ func (f *FSMImpl) applyVersion(data *entity.RaftData) *FSMResponse {
 	case entity.LogUpdate:
 		data.Version.Sequence = data.Sequence
 		return FSMResult(repo.UpdateVersion(data.Name, data.Version))
+	case entity.LogTouch:
+		return FSMResult(repo.TouchVersion(data.Name, data.Sequence, data.Version.AccessTs))
+	case entity.LogMark:
+		return FSMResult(repo.MarkVersion(data.Name, data.Sequence, data.Version.Replication))
+	case entity.LogSwap:
+		data.Version.Sequence = data.Sequence
+		return FSMResult(repo.SwapVersion(data.Name, data.Version, data.Expect))
 	default:
 		return FSMResult(ErrUnknownRaftLog)
 	}
@@ -94,6 +158,16 @@ func (f *FSMImpl) applyVersionAll(data *entity.RaftData) *FSMResponse {
 	}
 }
 
+// applyBatch applies all ops in a transaction, responses the entity.BatchResult
+func (f *FSMImpl) applyBatch(data *entity.RaftData) *FSMResponse {
+	if err := f.ops.ApplyOps(data.Ops, nil); err != nil {
+		return FSMResult(err)
+	}
+	resp := FSMResult(nil)
+	resp.Data = entity.BatchResultOf(data.Ops)
+	return resp
+}
+
 func (f *FSMImpl) Apply(lg *raft.Log) (r any) {
 	if lg == nil || len(lg.Data) == 0 {
 		return FSMResult(ErrNilData)
@@ -107,21 +181,56 @@ func (f *FSMImpl) Apply(lg *raft.Log) (r any) {
 		return err
 	}
 
+	return f.applyRecorded(&data, f.prepare(&data, logTime(lg)))
+}
+
+// applyRecorded applies data, appends its changes to change feed and publishes their events in the same transaction
+func (f *FSMImpl) applyRecorded(data *entity.RaftData, changes []*msg.Change) any {
+	if !hasChange(changes) {
+		return f.apply(data)
+	}
+	record := func(tx kv.Tx) error {
+		if err := f.feed.Append(tx, changes...); err != nil {
+			return err
+		}
+		return f.events.Publish(tx, changes...)
+	}
+	if data.Dest == entity.DestBatch {
+		if err := f.ops.ApplyOps(data.Ops, record); err != nil {
+			return FSMResult(err)
+		}
+		resp := FSMResult(nil)
+		resp.Data = entity.BatchResultOf(data.Ops)
+		return resp
+	}
+	resp := FSMResult(f.ops.Apply(data, record))
+	if resp.Ok() && data.Dest == entity.DestVersion && data.Type == entity.LogInsert {
+		resp.Data = data.Version.Sequence
+	}
+	return resp
+}
+
+func (f *FSMImpl) apply(data *entity.RaftData) any {
 	switch data.Dest {
 	case entity.DestMetadata:
-		return f.applyMetadata(&data)
+		return f.applyMetadata(data)
 	case entity.DestVersion:
-		return f.applyVersion(&data)
+		return f.applyVersion(data)
 	case entity.DestVersionAll:
-		return f.applyVersionAll(&data)
+		return f.applyVersionAll(data)
 	case entity.DestBucket:
-		return f.applyBucket(&data)
+		return f.applyBucket(data)
+	case entity.DestHashRef:
+		return f.applyHashRef(data)
+	case entity.DestEventCursor:
+		return f.applyEventCursor(data)
+	case entity.DestBatch:
+		return f.applyBatch(data)
 	}
 	return ErrUnknownRaftLog
 }
 
 func (f *FSMImpl) ApplyBatch(lgs []*raft.Log) []any {
-	var data entity.RaftData
 	res := make([]any, len(lgs))
 
 	for i, lg := range lgs {
@@ -133,23 +242,13 @@ func (f *FSMImpl) ApplyBatch(lgs []*raft.Log) []any {
 			res[i] = fmt.Errorf("drop recieved fsmLog type %v", lg.Type)
 			continue
 		}
+		var data entity.RaftData
 		if err := util.DecodeMsgp(&data, lg.Data); err != nil {
 			res[i] = err
 			continue
 		}
 		data.Batch = true
-		switch data.Dest {
-		case entity.DestMetadata:
-			res[i] = f.applyMetadata(&data)
-		case entity.DestVersion:
-			res[i] = f.applyVersion(&data)
-		case entity.DestVersionAll:
-			res[i] = f.applyVersionAll(&data)
-		case entity.DestBucket:
-			res[i] = f.applyBucket(&data)
-		default:
-			res[i] = ErrUnknownRaftLog
-		}
+		res[i] = f.applyRecorded(&data, f.prepare(&data, logTime(lg)))
 	}
 	//NOTICE: metaBatch Sync and bucketBatch Sync it's same for now.
 	if err := f.metaBatch.Sync(); err != nil {
@@ -159,10 +258,48 @@ func (f *FSMImpl) ApplyBatch(lgs []*raft.Log) []any {
 				res[i] = err
 			}
 		}
+		return res
 	}
 	return res
 }
 
+// prepare returns changes of data if change feed or events are enabled
+func (f *FSMImpl) prepare(data *entity.RaftData, ts time.Time) []*msg.Change {
+	if !f.feed.Enabled() && !f.events.Enabled() {
+		return nil
+	}
+	if data.Dest != entity.DestBatch {
+		return []*msg.Change{f.feed.Prepare(data, ts)}
+	}
+	changes := make([]*msg.Change, len(data.Ops))
+	for i, op := range data.Ops {
+		changes[i] = f.feed.Prepare(op, ts)
+	}
+	return changes
+}
+
+func hasChange(changes []*msg.Change) bool {
+	for _, change := range changes {
+		if change != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// logTime returns the time of log appended by leader, which is the same on every server.
+func logTime(lg *raft.Log) time.Time {
+	if lg.AppendedAt.IsZero() {
+		return time.Now()
+	}
+	return lg.AppendedAt
+}
+
+func succeeded(res any) bool {
+	r, ok := res.(*FSMResponse)
+	return ok && r.Ok()
+}
+
 func (f *FSMImpl) Snapshot() (raft.FSMSnapshot, error) {
 	snap, err := f.snapshot.Snapshot()
 	if err != nil {
@@ -173,12 +310,17 @@ func (f *FSMImpl) Snapshot() (raft.FSMSnapshot, error) {
 
 func (f *FSMImpl) Restore(snapshot io.ReadCloser) (err error) {
 	defer snapshot.Close()
+	// incremental snapshots after the full one are concatenated gzip members
 	gzipRd, err := gzip.NewReader(snapshot)
 	if err != nil {
 		return err
 	}
 	defer gzipRd.Close()
-	return f.snapshot.Restore(gzipRd)
+	rd := bufio.NewReaderSize(gzipRd, db.RestoreBufferSize)
+	if err = f.snapshot.Restore(rd); err != nil {
+		return err
+	}
+	return ReplayDeltas(rd, f)
 }
 
 type snapshot struct {
@@ -186,6 +328,14 @@ type snapshot struct {
 }
 
 func (s *snapshot) Persist(sink raft.SnapshotSink) error {
+	if is, ok := sink.(*incrementalSink); ok {
+		if done, err := is.PersistDelta(); err != nil {
+			fsmLog.Error(err)
+			return sink.Cancel()
+		} else if done {
+			return sink.Close()
+		}
+	}
 	gzipWt := gzip.NewWriter(sink)
 	if _, err := s.WriteTo(gzipWt); err != nil {
 		fsmLog.Error(err)
@@ -35,7 +35,7 @@ func (r *raftApplier) ApplyRaft(data *entity.RaftData) (bool, any, error) {
 		}
 		if rp, ok := resp.(error); ok {
 			return true, nil, rp
-		}		
+		}
 	}
 	return false, nil, nil
 }
@@ -1,10 +1,15 @@
 package raftimpl
 
 import (
+	"common/consistency"
 	"common/cst"
 	"common/graceful"
 	"common/logs"
+	"common/proto"
+	"common/proto/pb"
 	"common/util"
+	"context"
+	"errors"
 	"fmt"
 	transport "github.com/Jille/raft-grpc-transport"
 	"github.com/hashicorp/go-hclog"
@@ -13,7 +18,11 @@ import (
 	. "metaserver/internal/usecase"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/raft"
 	boltdb "github.com/hashicorp/raft-boltdb/v2"
@@ -23,6 +32,8 @@ var raftLog = logs.New("raft-impl")
 
 type RaftWrapper struct {
 	Raft                *raft.Raft
+	LogStore            raft.LogStore
+	SnapshotStore       raft.SnapshotStore
 	Manager             *transport.Manager
 	ID                  string
 	Address             string
@@ -30,8 +41,14 @@ type RaftWrapper struct {
 	isLeader            bool
 	leaderChangedEvents []IRaftLeaderChanged
 	closeRaftStore      func()
+	leaderConn          *grpc.ClientConn // leaderConn is the cached connection to leader for read index
+	leaderConnLock      sync.Mutex
+	// termCommitted marks an entry of current term has been committed by this leader, the commit index is valid since then
+	termCommitted atomic.Bool
 }
 
+var ErrNoLeader = errors.New("raft has no leader")
+
 func NewDisabledRaft() *RaftWrapper {
 	return &RaftWrapper{Enabled: false}
 }
@@ -45,13 +62,14 @@ func NewRaft(localAddr string, cfg config.ClusterConfig, fsm raft.FSM) *RaftWrap
 
 	c := raft.DefaultConfig()
 	c.LocalID, c.ElectionTimeout, c.HeartbeatTimeout = raft.ServerID(cfg.ID), cfg.ElectionTimeout, cfg.HeartbeatTimeout
+	c.SnapshotThreshold, c.SnapshotInterval, c.TrailingLogs = cfg.Snapshot.Threshold, cfg.Snapshot.Interval, cfg.Snapshot.TrailingLogs
 	c.Logger = hclog.New(&hclog.LoggerOptions{
 		Name:   "raft",
 		Color:  hclog.AutoColor,
 		Level:  hclog.LevelFromString(c.LogLevel),
 		Output: logs.Std().Out,
 	})
-	ldb, sdb, fss, closeFn := newRaftStore(baseDir)
+	ldb, sdb, fss, closeFn := newRaftStore(baseDir, cfg.Snapshot)
 
 	r, err := raft.NewRaft(c, fsm, ldb, sdb, fss, manager.Transport())
 	if err != nil {
@@ -95,6 +113,8 @@ func NewRaft(localAddr string, cfg config.ClusterConfig, fsm raft.FSM) *RaftWrap
 
 	rw := &RaftWrapper{
 		Raft:           r,
+		LogStore:       ldb,
+		SnapshotStore:  fss,
 		Manager:        manager,
 		ID:             cfg.ID,
 		Address:        localAddr,
@@ -107,7 +127,7 @@ func NewRaft(localAddr string, cfg config.ClusterConfig, fsm raft.FSM) *RaftWrap
 }
 
 // newRaftStore init storage
-func newRaftStore(baseDir string) (raft.LogStore, raft.StableStore, raft.SnapshotStore, func()) {
+func newRaftStore(baseDir string, cfg config.SnapshotConfig) (raft.LogStore, raft.StableStore, raft.SnapshotStore, func()) {
 	if err := os.MkdirAll(baseDir, cst.OS.ModeUser); err != nil {
 		panic(err)
 	}
@@ -117,12 +137,13 @@ func newRaftStore(baseDir string) (raft.LogStore, raft.StableStore, raft.Snapsho
 		panic(fmt.Errorf(`boltdb.NewBoltStore(%q): %v`, filepath.Join(baseDir, "logs.dat"), err))
 	}
 
-	fss, err := raft.NewFileSnapshotStore(baseDir, 2, logs.Std().Out)
+	// keep the incremental snapshots of every full one retained
+	fss, err := raft.NewFileSnapshotStore(baseDir, cfg.Retain*(cfg.FullEvery+1), logs.Std().Out)
 	if err != nil {
 		panic(fmt.Errorf(`raft.NewFileSnapshotStore(%q, ...): %v`, baseDir, err))
 	}
 
-	return rdb, rdb, fss, func() { util.LogErr(rdb.Close()) }
+	return rdb, rdb, NewIncrementalStore(fss, rdb, cfg.FullEvery), func() { util.LogErr(rdb.Close()) }
 }
 
 func (rw *RaftWrapper) subscribeLeaderCh() {
@@ -130,6 +151,7 @@ func (rw *RaftWrapper) subscribeLeaderCh() {
 		defer graceful.Recover()
 		for is := range rw.Raft.LeaderCh() {
 			rw.isLeader = is
+			rw.termCommitted.Store(false)
 			rw.OnLeaderChanged(is)
 			raftLog.Infof("server %s leader", util.IfElse(is, "become", "lose"))
 		}
@@ -182,11 +204,118 @@ func (rw *RaftWrapper) LeaderAddress() string {
 	return string(addr)
 }
 
+// ReadIndex returns the commit index confirmed by leader. reads after the index applied are linearizable.
+// leader records its commit index before confirming leadership by a quorum, followers ask leader for the index.
+func (rw *RaftWrapper) ReadIndex(ctx context.Context) (uint64, error) {
+	if rw.IsLeader() {
+		// commit index of a new leader may be behind the entries committed by previous ones until an entry of its term is committed
+		if !rw.termCommitted.Load() {
+			var timeout time.Duration
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = time.Until(deadline)
+			}
+			if err := rw.Raft.Barrier(timeout).Error(); err != nil {
+				return 0, err
+			}
+			rw.termCommitted.Store(true)
+		}
+		idx, err := rw.commitIndex()
+		if err != nil {
+			return 0, err
+		}
+		if err = rw.Raft.VerifyLeader().Error(); err != nil {
+			return 0, err
+		}
+		return idx, nil
+	}
+	cc, err := rw.getLeaderConn()
+	if err != nil {
+		return 0, err
+	}
+	res, err := proto.ResolveResponse(pb.NewRaftCmdClient(cc).ReadIndex(ctx, new(pb.EmptyReq)))
+	if err != nil {
+		return 0, fmt.Errorf("read index from leader: %w", err)
+	}
+	return strconv.ParseUint(res, 10, 64)
+}
+
+func (rw *RaftWrapper) getLeaderConn() (*grpc.ClientConn, error) {
+	addr := rw.LeaderAddress()
+	if addr == "" {
+		return nil, ErrNoLeader
+	}
+	rw.leaderConnLock.Lock()
+	defer rw.leaderConnLock.Unlock()
+	if rw.leaderConn != nil {
+		if rw.leaderConn.Target() == addr {
+			return rw.leaderConn, nil
+		}
+		util.LogErr(rw.leaderConn.Close())
+		rw.leaderConn = nil
+	}
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	rw.leaderConn = cc
+	return cc, nil
+}
+
+// commitIndex returns the commit index known by this server
+func (rw *RaftWrapper) commitIndex() (uint64, error) {
+	return strconv.ParseUint(rw.Raft.Stats()["commit_index"], 10, 64)
+}
+
+// WaitApplied blocks until the applied index reaches idx
+func (rw *RaftWrapper) WaitApplied(ctx context.Context, idx uint64) error {
+	if rw.Raft.AppliedIndex() >= idx {
+		return nil
+	}
+	tk := time.NewTicker(time.Millisecond)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait applied index %d: %w", idx, ctx.Err())
+		case <-tk.C:
+			if rw.Raft.AppliedIndex() >= idx {
+				return nil
+			}
+		}
+	}
+}
+
+// ConsistentRead blocks until this server is able to serve a read with level.
+// a follower which has contacted leader within the staleness knows the commit index of leader no older than it,
+// so it serves bounded-staleness reads after applying the index. otherwise reads strongly.
+func (rw *RaftWrapper) ConsistentRead(ctx context.Context, level consistency.Level) error {
+	if !rw.Enabled || level.Mode == consistency.Default || level.Mode == consistency.Any {
+		return nil
+	}
+	if level.Mode == consistency.BoundedStaleness {
+		if rw.IsLeader() || time.Since(rw.Raft.LastContact()) <= level.Staleness {
+			idx, err := rw.commitIndex()
+			if err != nil {
+				return err
+			}
+			return rw.WaitApplied(ctx, idx)
+		}
+	}
+	idx, err := rw.ReadIndex(ctx)
+	if err != nil {
+		return err
+	}
+	return rw.WaitApplied(ctx, idx)
+}
+
 func (rw *RaftWrapper) Close() error {
 	if !rw.Enabled {
 		return nil
 	}
 	defer rw.closeRaftStore()
+	if rw.leaderConn != nil {
+		util.LogErr(rw.leaderConn.Close())
+	}
 	raftLog.Info("shutdown raft..")
 	return rw.Raft.Shutdown().Error()
 }
@@ -0,0 +1,246 @@
+package raftimpl
+
+import (
+	"bufio"
+	"bytes"
+	"common/util"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/internal/usecase/backup"
+	"metaserver/internal/usecase/db"
+
+	"github.com/hashicorp/raft"
+)
+
+// deltaMagic heads an incremental snapshot, it's followed by the index of base snapshot and the command logs after it
+var deltaMagic = []byte("GFSDELT1")
+
+const deltaHeaderSize = 16
+
+// IncrementalStore is a raft.SnapshotStore whose snapshots are the command logs after the previous one, except that
+// a full one is taken after FullEvery incremental ones. a snapshot is opened together with the snapshots it's based on,
+// concatenated as gzip members, so that followers install and restore it as a self-contained one.
+type IncrementalStore struct {
+	raft.SnapshotStore
+	logs      raft.LogStore
+	fullEvery int
+}
+
+// NewIncrementalStore wraps store which should retain FullEvery+1 times of snapshots, logs is the log store of raft.
+func NewIncrementalStore(store raft.SnapshotStore, logs raft.LogStore, fullEvery int) *IncrementalStore {
+	return &IncrementalStore{SnapshotStore: store, logs: logs, fullEvery: fullEvery}
+}
+
+func (s *IncrementalStore) Create(version raft.SnapshotVersion, index, term uint64, configuration raft.Configuration,
+	configurationIndex uint64, trans raft.Transport) (raft.SnapshotSink, error) {
+	sink, err := s.SnapshotStore.Create(version, index, term, configuration, configurationIndex, trans)
+	if err != nil {
+		return nil, err
+	}
+	return &incrementalSink{SnapshotSink: sink, store: s, index: index}, nil
+}
+
+// Open opens snapshot id with the full snapshot and incremental ones before it
+func (s *IncrementalStore) Open(id string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	chain, err := s.chain(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta := *chain[len(chain)-1]
+	meta.Size = 0
+	rd := &chainReader{}
+	readers := make([]io.Reader, 0, len(chain))
+	for _, m := range chain {
+		_, rc, err := s.SnapshotStore.Open(m.ID)
+		if err != nil {
+			util.LogErr(rd.Close())
+			return nil, nil, err
+		}
+		rd.closers = append(rd.closers, rc)
+		readers = append(readers, rc)
+		meta.Size += m.Size
+	}
+	rd.Reader = io.MultiReader(readers...)
+	return &meta, rd, nil
+}
+
+// chain returns the full snapshot which snapshot id is based on, followed by the incremental ones until id
+func (s *IncrementalStore) chain(id string) ([]*raft.SnapshotMeta, error) {
+	snaps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var chain []*raft.SnapshotMeta
+	next := func(m *raft.SnapshotMeta) bool { return m.ID == id }
+	for _, m := range snaps {
+		if !next(m) {
+			continue
+		}
+		delta, base, err := s.readHeader(m.ID)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]*raft.SnapshotMeta{m}, chain...)
+		if !delta {
+			return chain, nil
+		}
+		next = func(m *raft.SnapshotMeta) bool { return m.Index == base }
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return nil, fmt.Errorf("base snapshot of %s not found", chain[0].ID)
+}
+
+// base returns the latest snapshot and the number of incremental snapshots after the full one it's based on.
+// it returns nil if there is no snapshot which can be a base.
+func (s *IncrementalStore) base() (*raft.SnapshotMeta, int, error) {
+	snaps, err := s.List()
+	if err != nil || len(snaps) == 0 {
+		return nil, 0, err
+	}
+	chain, err := s.chain(snaps[0].ID)
+	if err != nil {
+		// the full one has been reaped
+		raftLog.Warnf("take a full snapshot: %s", err)
+		return nil, 0, nil
+	}
+	// snapshots of the whole bbolt file are read until EOF, so no incremental ones can follow them
+	head, err := s.readHead(chain[0].ID, len(db.SnapshotMagic))
+	if err != nil || !bytes.Equal(head, db.SnapshotMagic) {
+		return nil, 0, err
+	}
+	return snaps[0], len(chain) - 1, nil
+}
+
+// readHeader returns whether snapshot id is incremental and the index of its base if so
+func (s *IncrementalStore) readHeader(id string) (bool, uint64, error) {
+	head, err := s.readHead(id, deltaHeaderSize)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(head) < deltaHeaderSize || !bytes.Equal(head[:len(deltaMagic)], deltaMagic) {
+		return false, 0, nil
+	}
+	return true, binary.BigEndian.Uint64(head[len(deltaMagic):]), nil
+}
+
+// readHead reads at most n bytes at the beginning of snapshot id
+func (s *IncrementalStore) readHead(id string, n int) ([]byte, error) {
+	_, rc, err := s.SnapshotStore.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseAndLog(rc)
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseAndLog(gz)
+	head := make([]byte, n)
+	n, err = io.ReadFull(gz, head)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return head[:n], err
+}
+
+// incrementalSink is the sink of a snapshot at index
+type incrementalSink struct {
+	raft.SnapshotSink
+	store *IncrementalStore
+	index uint64
+}
+
+// PersistDelta writes logs after the latest snapshot as an incremental snapshot. it returns false without writing
+// anything if a full one should be taken.
+func (s *incrementalSink) PersistDelta() (bool, error) {
+	if s.store.fullEvery <= 0 {
+		return false, nil
+	}
+	base, n, err := s.store.base()
+	if err != nil || base == nil || n >= s.store.fullEvery || base.Index >= s.index {
+		return false, err
+	}
+	first, err := s.store.logs.FirstIndex()
+	if err != nil {
+		return false, err
+	}
+	if first == 0 || first > base.Index+1 {
+		raftLog.Warnf("take a full snapshot: logs after %d have been compacted", base.Index)
+		return false, nil
+	}
+	gz := gzip.NewWriter(s)
+	header := make([]byte, deltaHeaderSize)
+	copy(header, deltaMagic)
+	binary.BigEndian.PutUint64(header[len(deltaMagic):], base.Index)
+	if _, err = gz.Write(header); err != nil {
+		return true, err
+	}
+	if _, err = backup.WriteLogs(gz, s.store.logs, base.Index, s.index); err != nil {
+		return true, err
+	}
+	return true, gz.Close()
+}
+
+// chainReader reads snapshots one by one
+type chainReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (c *chainReader) Close() error {
+	var err error
+	for _, rc := range c.closers {
+		if e := rc.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// ReplayDeltas applies logs of incremental snapshots remained in rd after the full snapshot restored
+func ReplayDeltas(rd *bufio.Reader, fsm raft.BatchingFSM) error {
+	header := make([]byte, deltaHeaderSize)
+	for {
+		if _, err := io.ReadFull(rd, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read incremental snapshot: %w", err)
+		}
+		if !bytes.Equal(header[:len(deltaMagic)], deltaMagic) {
+			return errors.New("unknown data after snapshot")
+		}
+		var batch []*raft.Log
+		if err := backup.ReadLogs(rd, func(lg *raft.Log) bool {
+			if batch = append(batch, lg); len(batch) >= 256 {
+				ApplyLogs(fsm, batch)
+				batch = batch[:0]
+			}
+			return true
+		}); err != nil {
+			return fmt.Errorf("read incremental snapshot: %w", err)
+		}
+		ApplyLogs(fsm, batch)
+	}
+}
+
+// ApplyLogs applies logs as they were applied by raft, failures are logged only because the same failures happened before.
+func ApplyLogs(fsm raft.BatchingFSM, logs []*raft.Log) {
+	if len(logs) == 0 {
+		return
+	}
+	for i, res := range fsm.ApplyBatch(logs) {
+		switch r := res.(type) {
+		case error:
+			fsmLog.Warnf("replay log %d: %s", logs[i].Index, r)
+		case *FSMResponse:
+			if err := r.ToError(); err != nil {
+				fsmLog.Warnf("replay log %d: %s", logs[i].Index, err)
+			}
+		}
+	}
+}
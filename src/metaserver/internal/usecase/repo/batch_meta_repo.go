@@ -2,7 +2,7 @@ package repo
 
 import (
 	"common/proto/msg"
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 	"metaserver/internal/usecase"
 	"metaserver/internal/usecase/db"
 	"metaserver/internal/usecase/logic"
@@ -38,6 +38,21 @@ func (br *BatchMetaRepo) UpdateVersion(name string, data *msg.Version) error {
 	return br.Storage.Batch(logic.UpdateVer(name, data))
 }
 
+func (br *BatchMetaRepo) TouchVersion(name string, ver uint64, ts int64) error {
+	return br.Storage.Batch(logic.TouchVer(name, ver, ts))
+}
+
+func (br *BatchMetaRepo) MarkVersion(name string, ver uint64, status string) error {
+	return br.Storage.Batch(logic.MarkVer(name, ver, status))
+}
+
+func (br *BatchMetaRepo) SwapVersion(name string, data *msg.Version, expect int64) error {
+	if data == nil {
+		return usecase.ErrNilData
+	}
+	return br.Storage.Batch(logic.SwapVer(name, data, expect))
+}
+
 func (br *BatchMetaRepo) RemoveVersion(name string, ver uint64) error {
 	return br.Storage.Batch(logic.RemoveVer(name, ver))
 }
@@ -54,6 +69,9 @@ func (br *BatchMetaRepo) UpdateMetadata(name string, data *msg.Metadata) error {
 }
 
 func (br *BatchMetaRepo) RemoveMetadata(name string) error {
+	if _, err := trimVersions(br.Storage, name); err != nil {
+		return err
+	}
 	return br.Storage.Batch(logic.RemoveMeta(name))
 }
 
@@ -65,7 +83,10 @@ func (br *BatchMetaRepo) AddVersionFromRaft(id string, data *msg.Version) error
 }
 
 func (br *BatchMetaRepo) RemoveAllVersion(id string) error {
-	return br.Storage.Batch(func(tx *bolt.Tx) error {
+	if _, err := trimVersions(br.Storage, id); err != nil {
+		return err
+	}
+	return br.Storage.Batch(func(tx kv.Tx) error {
 		// delete bucket
 		if err := logic.RemoveVersionBucket(tx, id); err != nil {
 			return err
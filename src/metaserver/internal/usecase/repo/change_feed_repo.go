@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"common/proto/msg"
+	"common/util"
+	"encoding/binary"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/logic"
+	"time"
+
+	"metaserver/internal/usecase/db/kv"
+)
+
+const changeFeedBucketRoot = "go.dfs.changefeed.root"
+
+// ChangeFeedRepo is an ordered log of changes of objects and buckets. changes are appended in the transactions
+// applying them, so that every server of a raft group has the same feed with the same sequences.
+type ChangeFeedRepo struct {
+	Storage *db.Storage
+	enabled bool
+}
+
+func NewChangeFeedRepo(storage *db.Storage, enabled bool) *ChangeFeedRepo {
+	return &ChangeFeedRepo{Storage: storage, enabled: enabled}
+}
+
+func (c *ChangeFeedRepo) Enabled() bool {
+	return c.enabled
+}
+
+// Prepare returns the change of data which must be called before applying data.
+// returns nil if data is not a change to record.
+func (c *ChangeFeedRepo) Prepare(data *entity.RaftData, ts time.Time) *msg.Change {
+	change := data.Change()
+	if change == nil {
+		return nil
+	}
+	change.Ts = ts.UnixMilli()
+	if change.Op == msg.ChangeRemoveVersion {
+		// keep the removed version to find it in remote cluster
+		var ver msg.Version
+		if err := c.Storage.View(logic.GetVer(data.Name, data.Sequence, &ver)); err == nil {
+			change.Version = &ver
+		}
+	}
+	return change
+}
+
+// Append saves changes with increasing sequences in tx which has applied them. nil changes are ignored.
+func (c *ChangeFeedRepo) Append(tx kv.Tx, changes ...*msg.Change) error {
+	if !c.enabled || !hasChange(changes) {
+		return nil
+	}
+	b, err := tx.CreateBucketIfNotExists(util.StrToBytes(changeFeedBucketRoot))
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		if change.Op == msg.ChangePutVersion {
+			// sequence of new version is generated while applying
+			change.Sequence = change.Version.Sequence
+		}
+		if change.Seq, err = b.NextSequence(); err != nil {
+			return err
+		}
+		bt, err := util.EncodeMsgp(change)
+		if err != nil {
+			return err
+		}
+		if err = b.Put(seqKey(change.Seq), bt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns at most limit changes after the sequence and the latest sequence of feed
+func (c *ChangeFeedRepo) List(after uint64, limit int) (res []*msg.Change, head uint64, err error) {
+	err = c.Storage.View(func(tx kv.Tx) error {
+		b := tx.Bucket(util.StrToBytes(changeFeedBucketRoot))
+		if b == nil {
+			return nil
+		}
+		head = b.Sequence()
+		cur := b.Cursor()
+		for k, v := cur.Seek(seqKey(after + 1)); k != nil && len(res) < limit; k, v = cur.Next() {
+			var change msg.Change
+			if err := util.DecodeMsgp(&change, v); err != nil {
+				return err
+			}
+			res = append(res, &change)
+		}
+		return nil
+	})
+	return
+}
+
+// Trim removes changes earlier than 'before' in transactions of at most trimBatch changes.
+// returns the number of removed changes.
+func (c *ChangeFeedRepo) Trim(before time.Time) (n int, err error) {
+	ts := before.UnixMilli()
+	for removed := trimBatch; removed == trimBatch; n += removed {
+		if err = c.Storage.Update(c.trim(ts, &removed)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// trim removes at most trimBatch changes earlier than ts
+func (c *ChangeFeedRepo) trim(ts int64, removed *int) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		*removed = 0
+		b := tx.Bucket(util.StrToBytes(changeFeedBucketRoot))
+		if b == nil {
+			return nil
+		}
+		var keys [][]byte
+		cur := b.Cursor()
+		for k, v := cur.First(); k != nil && len(keys) < trimBatch; k, v = cur.Next() {
+			var change msg.Change
+			if err := util.DecodeMsgp(&change, v); err != nil {
+				return err
+			}
+			if change.Ts >= ts {
+				break
+			}
+			keys = append(keys, k)
+		}
+		// deleting while iterating makes cursor skip keys
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		*removed = len(keys)
+		return nil
+	}
+}
+
+func hasChange(changes []*msg.Change) bool {
+	for _, change := range changes {
+		if change != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func seqKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"common/proto/msg"
+	"common/util"
+	"encoding/binary"
+	"errors"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/logic"
+	"strings"
+	"time"
+
+	"metaserver/internal/usecase/db/kv"
+)
+
+const (
+	eventBucketRoot  = "go.dfs.event.root"
+	cursorBucketRoot = "go.dfs.event.cursor"
+)
+
+// EventRepo is an ordered queue of events of buckets with notification. events are published in the transactions
+// applying changes, so that every server of a raft group has the same queue. consumers save their acknowledged seq as cursors.
+type EventRepo struct {
+	Storage *db.Storage
+	buckets *logic.BucketCrud
+	enabled bool
+}
+
+func NewEventRepo(storage *db.Storage, enabled bool) *EventRepo {
+	return &EventRepo{Storage: storage, buckets: logic.NewBucketCrud(), enabled: enabled}
+}
+
+func (e *EventRepo) Enabled() bool {
+	return e.enabled
+}
+
+// eventOf returns the event of change without bucket checking. returns nil if change is not about objects.
+func eventOf(change *msg.Change) *msg.Event {
+	bucket, name, ok := strings.Cut(change.Id, "/")
+	if !ok {
+		return nil
+	}
+	ev := &msg.Event{Bucket: bucket, Name: name, Version: change.Sequence, Ts: change.Ts}
+	switch change.Op {
+	case msg.ChangePutVersion:
+		ev.Type = msg.EventObjectCreated
+	case msg.ChangeRemoveVersion:
+		ev.Type = msg.EventVersionTrimmed
+	case msg.ChangeRemoveAllVersion, msg.ChangeRemoveMetadata:
+		ev.Type, ev.Version = msg.EventObjectRemoved, 0
+	default:
+		return nil
+	}
+	if change.Version != nil {
+		ev.Version, ev.Size, ev.Hash = change.Version.Sequence, change.Version.Size, change.Version.Hash
+	}
+	return ev
+}
+
+// Publish converts changes to events and saves the ones accepted by notification of their buckets in tx
+// which has applied the changes. nil changes are ignored.
+func (e *EventRepo) Publish(tx kv.Tx, changes ...*msg.Change) error {
+	if !e.enabled || !hasChange(changes) {
+		return nil
+	}
+	var root kv.Bucket
+	notifications := make(map[string]*msg.Notification)
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		ev := eventOf(change)
+		if ev == nil {
+			continue
+		}
+		nt, ok := notifications[ev.Bucket]
+		if !ok {
+			var b msg.Bucket
+			if err := e.buckets.Get(ev.Bucket, &b)(tx); err != nil && !errors.Is(err, usecase.ErrNotFound) {
+				return err
+			}
+			nt, notifications[ev.Bucket] = b.Notification, b.Notification
+		}
+		if !nt.Accepts(ev.Type) {
+			continue
+		}
+		var err error
+		if root == nil {
+			if root, err = tx.CreateBucketIfNotExists(util.StrToBytes(eventBucketRoot)); err != nil {
+				return err
+			}
+		}
+		if ev.Seq, err = root.NextSequence(); err != nil {
+			return err
+		}
+		bt, err := util.EncodeMsgp(ev)
+		if err != nil {
+			return err
+		}
+		if err = root.Put(seqKey(ev.Seq), bt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns at most limit events after the sequence and the latest sequence of queue
+func (e *EventRepo) List(after uint64, limit int) (res []*msg.Event, head uint64, err error) {
+	err = e.Storage.View(func(tx kv.Tx) error {
+		b := tx.Bucket(util.StrToBytes(eventBucketRoot))
+		if b == nil {
+			return nil
+		}
+		head = b.Sequence()
+		cur := b.Cursor()
+		for k, v := cur.Seek(seqKey(after + 1)); k != nil && len(res) < limit; k, v = cur.Next() {
+			var ev msg.Event
+			if err := util.DecodeMsgp(&ev, v); err != nil {
+				return err
+			}
+			res = append(res, &ev)
+		}
+		return nil
+	})
+	return
+}
+
+// GetCursor returns the acknowledged seq of consumer, zero if not exists
+func (e *EventRepo) GetCursor(consumer string) (seq uint64, err error) {
+	err = e.Storage.View(func(tx kv.Tx) error {
+		b := tx.Bucket(util.StrToBytes(cursorBucketRoot))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get(util.StrToBytes(consumer)); len(v) == 8 {
+			seq = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	return
+}
+
+// SetCursor saves the acknowledged seq of consumer. the cursor never moves backward.
+func (e *EventRepo) SetCursor(consumer string, seq uint64) error {
+	return e.SetCursors(map[string]uint64{consumer: seq})
+}
+
+// SetCursors saves the acknowledged seqs of consumers in a transaction. cursors never move backward.
+func (e *EventRepo) SetCursors(cursors map[string]uint64) error {
+	return e.Storage.Update(func(tx kv.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(util.StrToBytes(cursorBucketRoot))
+		if err != nil {
+			return err
+		}
+		for consumer, seq := range cursors {
+			key := util.StrToBytes(consumer)
+			if v := b.Get(key); len(v) == 8 && binary.BigEndian.Uint64(v) >= seq {
+				continue
+			}
+			if err = b.Put(key, seqKey(seq)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Expired returns the seq of the last event earlier than 'before', zero if there is none
+func (e *EventRepo) Expired(before time.Time) (seq uint64, err error) {
+	ts := before.UnixMilli()
+	err = e.Storage.View(func(tx kv.Tx) error {
+		b := tx.Bucket(util.StrToBytes(eventBucketRoot))
+		if b == nil {
+			return nil
+		}
+		cur := b.Cursor()
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			var ev msg.Event
+			if err := util.DecodeMsgp(&ev, v); err != nil {
+				return err
+			}
+			if ev.Ts >= ts {
+				break
+			}
+			seq = ev.Seq
+		}
+		return nil
+	})
+	return
+}
+
+// Trim removes events until the seq in transactions of at most trimBatch events. returns the number of removed events.
+func (e *EventRepo) Trim(until uint64) (n int, err error) {
+	for removed := trimBatch; removed == trimBatch; n += removed {
+		if err = e.Storage.Update(e.trim(until, &removed)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// trim removes at most trimBatch events until the seq
+func (e *EventRepo) trim(until uint64, removed *int) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		*removed = 0
+		b := tx.Bucket(util.StrToBytes(eventBucketRoot))
+		if b == nil {
+			return nil
+		}
+		var keys [][]byte
+		cur := b.Cursor()
+		for k, _ := cur.First(); k != nil && binary.BigEndian.Uint64(k) <= until && len(keys) < trimBatch; k, _ = cur.Next() {
+			keys = append(keys, k)
+		}
+		// deleting while iterating makes cursor skip keys
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		*removed = len(keys)
+		return nil
+	}
+}
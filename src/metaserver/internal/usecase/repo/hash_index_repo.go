@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"common/proto/msg"
 	"metaserver/internal/usecase/db"
 	"metaserver/internal/usecase/logic"
 )
@@ -25,3 +26,40 @@ func (h *HashIndexRepo) FindAll(hash string) (keys []string, err error) {
 func (h *HashIndexRepo) Sync() error {
 	return h.Storage.DB().Sync()
 }
+
+func (h *HashIndexRepo) GetRef(hash string) (*msg.HashRef, error) {
+	ref := new(msg.HashRef)
+	if err := h.Storage.View(logic.NewHashRefLogic().Get(hash, ref)); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+func (h *HashIndexRepo) GetRefBytes(hash string) (bt []byte, err error) {
+	err = h.Storage.View(logic.NewHashRefLogic().GetBytes(hash, &bt))
+	return
+}
+
+func (h *HashIndexRepo) Refer(ref *msg.HashRef) error {
+	return h.Storage.Update(logic.NewHashRefLogic().Refer(ref))
+}
+
+func (h *HashIndexRepo) Derefer(hash string) (*msg.HashRef, error) {
+	ref := new(msg.HashRef)
+	if err := h.Storage.Update(logic.NewHashRefLogic().Derefer(hash, ref)); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+func (h *HashIndexRepo) CreateRef(ref *msg.HashRef) error {
+	return h.Storage.Update(logic.NewHashRefLogic().Create(ref))
+}
+
+func (h *HashIndexRepo) RemoveRef(hash string) error {
+	return h.Storage.Update(logic.NewHashRefLogic().Remove(hash))
+}
+
+func (h *HashIndexRepo) ForeachRef(fn func(k, v []byte) error) error {
+	return h.Storage.View(logic.NewHashRefLogic().Foreach(fn))
+}
@@ -111,6 +111,18 @@ func (m *MetadataCacheRepo) UpdateVersion(s string, version *msg.Version) error
 	return m.AddVersion(s, version)
 }
 
+func (m *MetadataCacheRepo) TouchVersion(s string, u uint64, _ int64) error {
+	return m.RemoveVersion(s, u)
+}
+
+func (m *MetadataCacheRepo) MarkVersion(s string, u uint64, _ string) error {
+	return m.RemoveVersion(s, u)
+}
+
+func (m *MetadataCacheRepo) SwapVersion(s string, version *msg.Version, _ int64) error {
+	return m.AddVersion(s, version)
+}
+
 func (m *MetadataCacheRepo) RemoveMetadata(s string) error {
 	m.cache.Delete(fmt.Sprint(MetaCachePrefix, s))
 	return nil
@@ -2,7 +2,6 @@ package repo
 
 import (
 	"bytes"
-	"common/cst"
 	"common/graceful"
 	"common/logs"
 	"common/proto/msg"
@@ -10,14 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"metaserver/internal/entity"
 	"metaserver/internal/usecase"
 	"metaserver/internal/usecase/db"
 	"metaserver/internal/usecase/logic"
-	"os"
 	"strings"
 	"time"
 
-	bolt "go.etcd.io/bbolt"
+	"metaserver/internal/usecase/db/kv"
 )
 
 type MetadataRepo struct {
@@ -61,6 +60,9 @@ func (m *MetadataRepo) UpdateMetadata(name string, data *msg.Metadata) error {
 
 func (m *MetadataRepo) RemoveMetadata(name string) error {
 	lastVer := m.GetLastVersionNumber(name)
+	if _, err := trimVersions(m.MainDB, name); err != nil {
+		return err
+	}
 	if err := m.MainDB.Update(logic.RemoveMeta(name)); err != nil {
 		return err
 	}
@@ -146,6 +148,141 @@ func (m *MetadataRepo) UpdateVersion(id string, data *msg.Version) error {
 	return nil
 }
 
+func (m *MetadataRepo) TouchVersion(name string, ver uint64, ts int64) error {
+	if err := m.MainDB.Update(logic.TouchVer(name, ver, ts)); err != nil {
+		return err
+	}
+	go func() {
+		defer graceful.Recover()
+		err := m.Cache.TouchVersion(name, ver, ts)
+		util.LogErrWithPre("metadata cache", err)
+	}()
+	return nil
+}
+
+func (m *MetadataRepo) MarkVersion(name string, ver uint64, status string) error {
+	if err := m.MainDB.Update(logic.MarkVer(name, ver, status)); err != nil {
+		return err
+	}
+	go func() {
+		defer graceful.Recover()
+		err := m.Cache.MarkVersion(name, ver, status)
+		util.LogErrWithPre("metadata cache", err)
+	}()
+	return nil
+}
+
+func (m *MetadataRepo) SwapVersion(name string, data *msg.Version, expect int64) error {
+	if data == nil {
+		return usecase.ErrNilData
+	}
+	if data.Hash == "" {
+		return errors.New("version doesn't contains Hash value")
+	}
+	if err := m.MainDB.Update(logic.SwapVer(name, data, expect)); err != nil {
+		return err
+	}
+	go func() {
+		defer graceful.Recover()
+		err := m.Cache.SwapVersion(name, data, expect)
+		util.LogErrWithPre("metadata cache", err)
+	}()
+	return nil
+}
+
+func (m *MetadataRepo) ScanVersions(cursor string, limit int) (keys []string, res []*msg.Version, err error) {
+	err = m.MainDB.View(logic.ScanVer(cursor, limit, nil, &keys, &res))
+	return
+}
+
+func (m *MetadataRepo) ListColdVersions(before int64, cursor string, limit int) (keys []string, res []*msg.Version, err error) {
+	err = m.MainDB.View(logic.ListColdVer(before, cursor, limit, &keys, &res))
+	return
+}
+
+// QueryVersions finds versions matching query by secondary indexes. returns keys "bucket/name.sequence" and versions.
+func (m *MetadataRepo) QueryVersions(q *msg.Query) (keys []string, res []*msg.Version, err error) {
+	err = m.MainDB.View(logic.QueryVer(q, &keys, &res))
+	return
+}
+
+// StatVersions aggregates versions of bucket on this server
+func (m *MetadataRepo) StatVersions(bucket string) (*msg.BucketStat, error) {
+	var stat msg.BucketStat
+	if err := m.MainDB.View(logic.StatVer(bucket, &stat)); err != nil {
+		return nil, err
+	}
+	return &stat, nil
+}
+
+// Load returns the number of metadata and the size of database
+func (m *MetadataRepo) Load() (*entity.StoreLoad, error) {
+	var load entity.StoreLoad
+	err := m.MainDB.View(func(tx kv.Tx) error {
+		if root := logic.GetMetadataBucket(tx); root != nil {
+			load.Keys = int64(root.KeyN())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if load.Bytes, err = m.MainDB.Size(); err != nil {
+		return nil, err
+	}
+	return &load, nil
+}
+
+// batches bound the size of bulk transactions, badger rejects a transaction larger than a part of its memtable
+const (
+	// rebuildIndexBatch is the max number of versions indexed in a transaction on rebuilding
+	rebuildIndexBatch = 2000
+	// removeVersionBatch is the max number of versions removed in a transaction ahead of removing a version bucket
+	removeVersionBatch = 2000
+	// trimBatch is the max number of changes or events removed in a transaction on trimming
+	trimBatch = 10000
+)
+
+// trimVersions removes versions of a large object in bounded transactions, leaving the last removeVersionBatch ones
+// and the version bucket to the transaction removing them, which stays atomic for most objects. returns removed versions.
+func trimVersions(storage *db.Storage, name string) ([]*msg.Version, error) {
+	var res []*msg.Version
+	for {
+		var removed []*msg.Version
+		if err := storage.Update(logic.TrimVersions(name, removeVersionBatch, &removed)); err != nil {
+			return res, err
+		}
+		if len(removed) == 0 {
+			return res, nil
+		}
+		res = append(res, removed...)
+	}
+}
+
+// BuildIndexes rebuilds secondary indexes if they are absent or outdated
+func (m *MetadataRepo) BuildIndexes() error {
+	var built bool
+	if err := m.MainDB.View(logic.SecondaryIndexBuilt(&built)); err != nil || built {
+		return err
+	}
+	start := time.Now()
+	if err := m.MainDB.Update(logic.DropSecondaryIndexes()); err != nil {
+		return err
+	}
+	// versions are indexed in many transactions to bound the size of them
+	cursor := &logic.IndexCursor{}
+	for !cursor.Done {
+		if err := m.MainDB.Update(logic.IndexVersions(cursor, rebuildIndexBatch)); err != nil {
+			return err
+		}
+	}
+	if err := m.MainDB.Update(logic.MarkSecondaryIndexBuilt()); err != nil {
+		return err
+	}
+	logs.Std().Infof("rebuild secondary indexes in %s", time.Since(start))
+	return nil
+}
+
 func (m *MetadataRepo) RemoveVersion(name string, ver uint64) error {
 	if err := m.MainDB.Update(logic.RemoveVer(name, ver)); err != nil {
 		return err
@@ -160,7 +297,10 @@ func (m *MetadataRepo) RemoveVersion(name string, ver uint64) error {
 
 func (m *MetadataRepo) RemoveAllVersion(name string) error {
 	last := m.GetLastVersionNumber(name)
-	if err := m.MainDB.Update(func(tx *bolt.Tx) error {
+	if _, err := trimVersions(m.MainDB, name); err != nil {
+		return err
+	}
+	if err := m.MainDB.Update(func(tx kv.Tx) error {
 		// delete bucket
 		if err := logic.RemoveVersionBucket(tx, name); err != nil {
 			return err
@@ -182,7 +322,7 @@ func (m *MetadataRepo) RemoveAllVersion(name string) error {
 
 func (m *MetadataRepo) GetFirstVersionNumber(name string) uint64 {
 	var fst uint64 = 1
-	if err := m.MainDB.View(func(tx *bolt.Tx) error {
+	if err := m.MainDB.View(func(tx kv.Tx) error {
 		if buk := logic.GetVersionBucket(tx, name); buk != nil {
 			k, v := buk.Cursor().First()
 			if k == nil || v == nil {
@@ -203,7 +343,7 @@ func (m *MetadataRepo) GetFirstVersionNumber(name string) uint64 {
 
 func (m *MetadataRepo) GetLastVersionNumber(name string) uint64 {
 	var max uint64 = 1
-	if err := m.MainDB.View(func(tx *bolt.Tx) error {
+	if err := m.MainDB.View(func(tx kv.Tx) error {
 		if buk := logic.GetVersionBucket(tx, name); buk != nil {
 			k, v := buk.Cursor().Last()
 			if k == nil || v == nil {
@@ -245,7 +385,7 @@ func (m *MetadataRepo) ListVersions(name string, start int, end int) (lst []*msg
 	} else if err != nil {
 		return
 	}
-	err = m.MainDB.View(func(tx *bolt.Tx) error {
+	err = m.MainDB.View(func(tx kv.Tx) error {
 		buk := logic.GetVersionBucket(tx, name)
 		if buk == nil {
 			return usecase.ErrNotFound
@@ -262,14 +402,14 @@ func (m *MetadataRepo) ListVersions(name string, start int, end int) (lst []*msg
 			lst = append(lst, data)
 		}
 		// record total
-		total = buk.Stats().KeyN
+		total = buk.KeyN()
 		return nil
 	})
 	return
 }
 
 func (m *MetadataRepo) ListMetadata(prefix string, size int) (lst []*msg.Metadata, total int, err error) {
-	err = m.MainDB.View(func(tx *bolt.Tx) error {
+	err = m.MainDB.View(func(tx kv.Tx) error {
 		root := logic.GetMetadataBucket(tx)
 		if root == nil {
 			return usecase.ErrNotFound
@@ -281,7 +421,7 @@ func (m *MetadataRepo) ListMetadata(prefix string, size int) (lst []*msg.Metadat
 			defer func() { total = len(lst) }()
 		} else {
 			k, v = cur.First()
-			total = root.Stats().KeyN
+			total = root.KeyN()
 		}
 		for k != nil && len(lst) < size {
 			if prefix != "" && !strings.HasPrefix(util.BytesToStr(k), prefix) {
@@ -302,33 +442,20 @@ func (m *MetadataRepo) ListMetadata(prefix string, size int) (lst []*msg.Metadat
 }
 
 func (m *MetadataRepo) Snapshot() (usecase.SnapshotTx, error) {
-	return m.MainDB.DB().Begin(false)
+	return m.MainDB.Snapshot()
 }
 
-func (m *MetadataRepo) Restore(r io.Reader) (err error) {
-	dbPath := m.MainDB.DB().Path() + "_replace"
-	// open new db file
-	newFile, err := os.OpenFile(dbPath, os.O_WRONLY|os.O_CREATE, cst.OS.ModeUser)
-	if err != nil {
-		logs.Std().Errorf("restore fail on open new file: %v", err)
-		return err
-	}
-	// save new db data
-	n, err := io.Copy(newFile, r)
-	if err != nil {
-		logs.Std().Errorf("restore fail on copy data to new file: %v, written %d", err, n)
+func (m *MetadataRepo) Restore(r io.Reader) error {
+	if err := m.MainDB.Restore(r); err != nil {
+		logs.Std().Errorf("restore snapshot err: %s", err)
 		return err
 	}
-	if err := newFile.Close(); err != nil {
-		logs.Std().Errorf("close new db file err: %s", err)
-		return err
-	}
-	// reopen db
-	return m.MainDB.Replace(dbPath)
+	// snapshot may be taken before indexes existing
+	return m.BuildIndexes()
 }
 
 func (m *MetadataRepo) ForeachVersionBytes(name string, fn func([]byte) bool) {
-	_ = m.MainDB.View(func(tx *bolt.Tx) error {
+	_ = m.MainDB.View(func(tx kv.Tx) error {
 		_ = logic.GetVersionBucket(tx, name).ForEach(func(k, v []byte) error {
 			if !fn(v) {
 				return usecase.ErrNotFound
@@ -341,7 +468,7 @@ func (m *MetadataRepo) ForeachVersionBytes(name string, fn func([]byte) bool) {
 
 func (m *MetadataRepo) GetMetadataBytes(key string) ([]byte, error) {
 	var res []byte
-	err := m.MainDB.View(func(tx *bolt.Tx) error {
+	err := m.MainDB.View(func(tx kv.Tx) error {
 		v := logic.GetMetadataBucket(tx).Get(util.StrToBytes(key))
 		if v == nil {
 			return usecase.ErrNotFound
@@ -363,7 +490,7 @@ const lastAppliedIndexKey = "go.dfs.metadata.special.lastAppliedIndexKey"
 
 func (m *MetadataRepo) LastAppliedIndex() (uint64, error) {
 	var r uint64
-	err := m.MainDB.View(func(tx *bolt.Tx) error {
+	err := m.MainDB.View(func(tx kv.Tx) error {
 		specBuc := tx.Bucket(util.StrToBytes(lastAppliedIndexKey))
 		if specBuc == nil {
 			return nil
@@ -375,7 +502,7 @@ func (m *MetadataRepo) LastAppliedIndex() (uint64, error) {
 }
 
 func (m *MetadataRepo) ApplyIndex(i uint64) error {
-	return m.MainDB.Update(func(tx *bolt.Tx) error {
+	return m.MainDB.Update(func(tx kv.Tx) error {
 		specBuc, err := tx.CreateBucketIfNotExists(util.StrToBytes(lastAppliedIndexKey))
 		if err != nil {
 			return err
@@ -388,7 +515,7 @@ func (m *MetadataRepo) ApplyIndex(i uint64) error {
 }
 
 func (m *MetadataRepo) UpdateLocateByHash(hash string, index int, value string) error {
-	return m.MainDB.Update(func(tx *bolt.Tx) error {
+	return m.MainDB.Update(func(tx kv.Tx) error {
 		var keys []string
 		if err := logic.NewHashIndexLogic().GetIndex(hash, &keys)(tx); err != nil {
 			return err
@@ -406,7 +533,7 @@ func (m *MetadataRepo) UpdateLocateByHash(hash string, index int, value string)
 				return err
 			}
 		}
-		return nil
+		return logic.NewHashRefLogic().UpdateLocate(hash, index, value)(tx)
 	})
 }
 
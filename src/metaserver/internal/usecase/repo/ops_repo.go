@@ -0,0 +1,292 @@
+package repo
+
+import (
+	"common/graceful"
+	"common/proto/msg"
+	"common/response"
+	"common/util"
+	"errors"
+	"fmt"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+)
+
+// OpsRepo applies mutations of metadata, versions and buckets in a transaction
+type OpsRepo struct {
+	Storage     *db.Storage
+	metaCache   usecase.IMetaCache
+	bucketCache usecase.BucketRepo
+	buckets     *logic.BucketCrud
+}
+
+func NewOpsRepo(storage *db.Storage, metaCache usecase.IMetaCache, bucketCache usecase.BucketRepo) *OpsRepo {
+	return &OpsRepo{Storage: storage, metaCache: metaCache, bucketCache: bucketCache, buckets: logic.NewBucketCrud()}
+}
+
+// ApplyOps applies all ops or none of them, then runs record in the same transaction if it's not nil.
+// sequences of inserted versions are set to the ones of ops. inserting an existing version is skipped like applying it alone.
+// versions of large objects removed by ops are trimmed in bounded transactions ahead, see trimVersions.
+// versions removed by every op are set to its Removed.
+func (o *OpsRepo) ApplyOps(ops []*entity.RaftData, record usecase.TxFunc) error {
+	trimmed := make([][]*msg.Version, len(ops))
+	for i, op := range ops {
+		if removesVersions(op) {
+			vers, err := trimVersions(o.Storage, op.Name)
+			if err != nil {
+				return err
+			}
+			trimmed[i] = vers
+		}
+	}
+	// last version numbers of removed objects to clean cache
+	var removed map[string]uint64
+	err := o.Storage.Update(func(tx kv.Tx) error {
+		// fn may be retried on conflicts
+		removed = make(map[string]uint64)
+		for i, op := range ops {
+			lastRemoved(tx, op, removed)
+			if err := removedVersions(tx, op, trimmed[i]); err != nil {
+				return opError(i, op, err)
+			}
+			if err := o.apply(tx, op); err != nil && !errors.Is(err, usecase.ErrExists) {
+				return opError(i, op, err)
+			}
+		}
+		if record != nil {
+			return record(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer graceful.Recover()
+		o.invalidCache(ops, removed)
+	}()
+	return nil
+}
+
+// Apply applies data as the repository of its dest does, then runs record in the same transaction if it's not nil.
+// data is applied in a batch transaction if data.Batch is set.
+func (o *OpsRepo) Apply(data *entity.RaftData, record usecase.TxFunc) error {
+	if removesVersions(data) {
+		if _, err := trimVersions(o.Storage, data.Name); err != nil {
+			return err
+		}
+	}
+	var removed map[string]uint64
+	write := util.IfElse(data.Batch, o.Storage.Batch, o.Storage.Update)
+	err := write(func(tx kv.Tx) error {
+		removed = make(map[string]uint64)
+		lastRemoved(tx, data, removed)
+		if err := o.applyExactly(tx, data); err != nil {
+			return err
+		}
+		if record != nil {
+			return record(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer graceful.Recover()
+		o.invalidCache([]*entity.RaftData{data}, removed)
+	}()
+	return nil
+}
+
+// removesVersions tells if op removes all versions of an object
+func removesVersions(op *entity.RaftData) bool {
+	return op.Dest == entity.DestMetadata && op.Type == entity.LogRemove || op.Dest == entity.DestVersionAll
+}
+
+// removedVersions sets versions going to be removed by op to its Removed, following the ones trimmed ahead
+func removedVersions(tx kv.Tx, op *entity.RaftData, trimmed []*msg.Version) error {
+	op.Removed = trimmed[:len(trimmed):len(trimmed)]
+	switch {
+	case op.Dest == entity.DestVersion && op.Type == entity.LogRemove:
+		var ver msg.Version
+		// a missing version fails the op
+		if err := logic.GetVer(op.Name, op.Sequence, &ver)(tx); err == nil {
+			op.Removed = append(op.Removed, &ver)
+		}
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogRemove && logic.GetMetadataBucket(tx).Get(util.StrToBytes(op.Name)) == nil:
+		// versions are kept if there is no metadata
+	case removesVersions(op):
+		if b := logic.GetVersionBucket(tx, op.Name); b != nil {
+			return b.ForEach(func(_, v []byte) error {
+				var ver msg.Version
+				if err := util.DecodeMsgp(&ver, v); err != nil {
+					return err
+				}
+				op.Removed = append(op.Removed, &ver)
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+// lastRemoved saves the last version number of object removed by op
+func lastRemoved(tx kv.Tx, op *entity.RaftData, removed map[string]uint64) {
+	if removesVersions(op) {
+		if b := logic.GetVersionBucket(tx, op.Name); b != nil {
+			removed[op.Name] = b.Sequence()
+		}
+	}
+}
+
+// applyExactly applies op like the repository of its dest, which doesn't create or update on conflicts unlike apply
+func (o *OpsRepo) applyExactly(tx kv.Tx, op *entity.RaftData) error {
+	switch {
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogInsert:
+		if op.Metadata == nil {
+			return usecase.ErrNilData
+		}
+		return logic.AddMeta(op.Name, op.Metadata)(tx)
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogUpdate:
+		if op.Metadata == nil {
+			return usecase.ErrNilData
+		}
+		return logic.UpdateMeta(op.Name, op.Metadata)(tx)
+	case op.Dest == entity.DestVersion && op.Type == entity.LogInsert:
+		if err := checkVersion(op, true); err != nil {
+			return err
+		}
+		return logic.AddVer(op.Name, op.Version)(tx)
+	case op.Dest == entity.DestVersion && op.Type == entity.LogSwap:
+		if err := checkVersion(op, false); err != nil {
+			return err
+		}
+		op.Version.Sequence = op.Sequence
+		return logic.SwapVer(op.Name, op.Version, op.Expect)(tx)
+	case op.Dest == entity.DestBucket && op.Type == entity.LogInsert:
+		if op.Bucket == nil {
+			return usecase.ErrNilData
+		}
+		return o.buckets.Create(op.Bucket)(tx)
+	case op.Dest == entity.DestBucket && op.Type == entity.LogUpdate:
+		if op.Bucket == nil {
+			return usecase.ErrNilData
+		}
+		return o.buckets.Update(op.Bucket)(tx)
+	default:
+		return o.apply(tx, op)
+	}
+}
+
+// checkVersion checks version of op as MetadataRepo does, versions are not checked in batch like BatchMetaRepo
+func checkVersion(op *entity.RaftData, unique bool) error {
+	if op.Version == nil {
+		return usecase.ErrNilData
+	}
+	if op.Batch {
+		return nil
+	}
+	if op.Version.Hash == "" {
+		return errors.New("version doesn't contains Hash value")
+	}
+	if unique && op.Version.UniqueId == "" {
+		return errors.New("version doesn't contains UniqueId value")
+	}
+	return nil
+}
+
+func (o *OpsRepo) apply(tx kv.Tx, op *entity.RaftData) error {
+	switch {
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogInsert:
+		if op.Metadata == nil {
+			return usecase.ErrNilData
+		}
+		err := logic.AddMeta(op.Name, op.Metadata)(tx)
+		if errors.Is(err, usecase.ErrExists) {
+			return upsertMeta(tx, op.Name, op.Metadata)
+		}
+		return err
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogRemove:
+		return logic.RemoveMeta(op.Name)(tx)
+	case op.Dest == entity.DestVersion && op.Type == entity.LogInsert:
+		if op.Version == nil {
+			return usecase.ErrNilData
+		}
+		return logic.AddVer(op.Name, op.Version)(tx)
+	case op.Dest == entity.DestVersion && op.Type == entity.LogUpdate:
+		if op.Version == nil {
+			return usecase.ErrNilData
+		}
+		op.Version.Sequence = op.Sequence
+		return logic.UpdateVer(op.Name, op.Version)(tx)
+	case op.Dest == entity.DestVersion && op.Type == entity.LogRemove:
+		return logic.RemoveVer(op.Name, op.Sequence)(tx)
+	case op.Dest == entity.DestVersionAll && op.Type == entity.LogRemove:
+		if err := logic.RemoveVersionBucket(tx, op.Name); err != nil {
+			return err
+		}
+		return logic.CreateVersionBucket(tx, op.Name)
+	case op.Dest == entity.DestBucket && op.Type == entity.LogInsert:
+		if op.Bucket == nil {
+			return usecase.ErrNilData
+		}
+		err := o.buckets.Create(op.Bucket)(tx)
+		if errors.Is(err, usecase.ErrExists) {
+			return o.buckets.Update(op.Bucket)(tx)
+		}
+		return err
+	case op.Dest == entity.DestBucket && op.Type == entity.LogRemove:
+		return o.buckets.Delete(op.Name)(tx)
+	default:
+		return usecase.ErrUnknownRaftLog
+	}
+}
+
+// upsertMeta updates existing metadata as putting data, which keeps the create time
+// and moves the update time forward even if data is not newer, e.g. put twice in a batch.
+func upsertMeta(tx kv.Tx, id string, data *msg.Metadata) error {
+	var origin msg.Metadata
+	if err := logic.GetMeta(id, &origin)(tx); err != nil {
+		return err
+	}
+	data.CreateTime = origin.CreateTime
+	if data.UpdateTime <= origin.UpdateTime {
+		data.UpdateTime = origin.UpdateTime + 1
+	}
+	return logic.UpdateMeta(id, data)(tx)
+}
+
+// opError tells which op fails and keeps the status of error
+func opError(i int, op *entity.RaftData, err error) error {
+	msg := fmt.Sprintf("op %d of %s: %s", i, op.Name, err)
+	if re, ok := err.(response.IErr); ok {
+		return response.NewError(re.GetStatus(), msg)
+	}
+	return errors.New(msg)
+}
+
+// invalidCache removes cache of everything written by ops, so that they are loaded from storage next time
+func (o *OpsRepo) invalidCache(ops []*entity.RaftData, removed map[string]uint64) {
+	for _, op := range ops {
+		switch op.Dest {
+		case entity.DestMetadata:
+			util.LogErrWithPre("metadata cache", o.metaCache.RemoveMetadata(op.Name))
+		case entity.DestVersion:
+			seq := op.Sequence
+			if op.Version != nil && op.Type == entity.LogInsert {
+				seq = op.Version.Sequence
+			}
+			util.LogErrWithPre("metadata cache", o.metaCache.RemoveVersion(op.Name, seq))
+		case entity.DestBucket:
+			util.LogErrWithPre("bucket cache", o.bucketCache.Remove(op.Name))
+		}
+	}
+	for name, last := range removed {
+		for i := uint64(1); i <= last; i++ {
+			util.LogErrWithPre("metadata cache", o.metaCache.RemoveVersion(name, i))
+		}
+	}
+}
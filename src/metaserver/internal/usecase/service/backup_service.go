@@ -0,0 +1,341 @@
+package service
+
+import (
+	"bufio"
+	"common/cst"
+	"common/graceful"
+	"common/logs"
+	"common/util"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/config"
+	"metaserver/internal/usecase/backup"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/pool"
+	"metaserver/internal/usecase/raftimpl"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+var bkLog = logs.New("backup-service")
+
+// BackupService takes full and incremental backups of this group. in raft mode only leader takes backups,
+// full backups are raft snapshots and incremental ones are raft logs after the previous backup.
+type BackupService struct {
+	storage *db.Storage
+	rw      *raftimpl.RaftWrapper
+	dest    backup.Destination
+	cfg     *config.BackupConfig
+	group   string
+	running atomic.Bool
+}
+
+func NewBackupService(storage *db.Storage, rw *raftimpl.RaftWrapper, cfg *config.Config) (*BackupService, error) {
+	dest, err := backup.NewDestination(&cfg.Backup)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupService{
+		storage: storage,
+		rw:      rw,
+		dest:    dest,
+		cfg:     &cfg.Backup,
+		group:   cfg.HashSlot.StoreID,
+	}, nil
+}
+
+// StartAutoBackup takes backups periodically if enabled
+func (b *BackupService) StartAutoBackup() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !b.cfg.Enable {
+		return cancel
+	}
+	go func() {
+		defer graceful.Recover()
+		tk := time.NewTicker(b.cfg.Interval)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				bkLog.Info("stop auto backup")
+				return
+			case <-tk.C:
+				if b.rw.Enabled && !b.rw.IsLeader() {
+					continue
+				}
+				if m, err := b.Backup(ctx); err != nil {
+					bkLog.Errorf("backup err: %s", err)
+				} else if m.Reason != "" {
+					bkLog.Warnf("%s backup %s finished, index %d, because %s", m.Type, m.ID, m.ToIndex, m.Reason)
+				} else {
+					bkLog.Infof("%s backup %s finished, index %d", m.Type, m.ID, m.ToIndex)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// Backup takes an incremental backup after the latest one, or a full backup if there is no base,
+// FullEvery incremental backups have been taken or raft logs have been compacted.
+func (b *BackupService) Backup(ctx context.Context) (*backup.Manifest, error) {
+	if !b.running.CompareAndSwap(false, true) {
+		return nil, errors.New("backup is running")
+	}
+	defer b.running.Store(false)
+	manifests, err := backup.ListManifests(ctx, b.dest, b.group)
+	if err != nil {
+		return nil, err
+	}
+	var m *backup.Manifest
+	var reason string
+	if last, n := latestBackup(manifests); b.rw.Enabled && last != nil && n < b.cfg.FullEvery {
+		if b.rw.Raft.AppliedIndex() <= last.ToIndex {
+			return last, nil
+		}
+		m, err = b.incremental(ctx, last)
+		if errors.Is(err, backup.ErrLogCompacted) {
+			// the chain is broken, the full backup makes a new base
+			reason = fmt.Sprintf("raft logs after %d have been compacted", last.ToIndex)
+			bkLog.Errorf("%s, take a full backup instead. increase cluster.snapshot.trailing-logs or decrease backup.interval "+
+				"to keep incremental backups", reason)
+			m, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m == nil {
+		if m, err = b.full(ctx); err != nil {
+			return nil, err
+		}
+		m.Reason = reason
+	}
+	if err = b.saveManifest(ctx, m); err != nil {
+		return nil, err
+	}
+	b.prune(ctx, append(manifests, m))
+	return m, nil
+}
+
+// latestBackup returns the latest backup and the number of incremental backups after the latest full one
+func latestBackup(manifests []*backup.Manifest) (*backup.Manifest, int) {
+	var n int
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if manifests[i].Type == backup.Full {
+			return manifests[len(manifests)-1], n
+		}
+		n++
+	}
+	return nil, 0
+}
+
+func (b *BackupService) full(ctx context.Context) (*backup.Manifest, error) {
+	if !b.rw.Enabled {
+		tx, err := b.storage.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { util.LogErr(tx.Rollback()) }()
+		m := backup.NewManifest(b.group, pool.Config.Registry.SID(), backup.Full, 0, 0)
+		return m, b.upload(ctx, m, func(w io.Writer) error {
+			gz := gzip.NewWriter(w)
+			if _, err := tx.WriteTo(gz); err != nil {
+				return err
+			}
+			return gz.Close()
+		})
+	}
+	meta, rc, err := b.openSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer util.CloseAndLog(rc)
+	m := backup.NewManifest(b.group, pool.Config.Registry.SID(), backup.Full, 0, meta.Index)
+	// data of raft snapshot has been compressed
+	return m, b.upload(ctx, m, func(w io.Writer) error {
+		_, err := io.Copy(w, rc)
+		return err
+	})
+}
+
+// openSnapshot takes a raft snapshot, or opens the latest one if nothing new to snapshot
+func (b *BackupService) openSnapshot() (*raft.SnapshotMeta, io.ReadCloser, error) {
+	future := b.rw.Raft.Snapshot()
+	if err := future.Error(); err == nil {
+		return future.Open()
+	} else if !errors.Is(err, raft.ErrNothingNewToSnapshot) {
+		return nil, nil, err
+	}
+	snaps, err := b.rw.SnapshotStore.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, nil, errors.New("no raft snapshot")
+	}
+	return b.rw.SnapshotStore.Open(snaps[0].ID)
+}
+
+func (b *BackupService) incremental(ctx context.Context, last *backup.Manifest) (*backup.Manifest, error) {
+	to := b.rw.Raft.AppliedIndex()
+	m := backup.NewManifest(b.group, pool.Config.Registry.SID(), backup.Incremental, last.ToIndex+1, to)
+	m.Parent = last.ID
+	return m, b.upload(ctx, m, func(w io.Writer) error {
+		gz := gzip.NewWriter(w)
+		lastTime, err := backup.WriteLogs(gz, b.rw.LogStore, last.ToIndex, to)
+		if err != nil {
+			return err
+		}
+		if !lastTime.IsZero() {
+			m.LastTime = lastTime
+		}
+		return gz.Close()
+	})
+}
+
+// upload writes data to a temp file firstly to know its size, then puts it to destination
+func (b *BackupService) upload(ctx context.Context, m *backup.Manifest, write func(io.Writer) error) error {
+	file, err := os.CreateTemp("", "backup-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		util.LogErr(file.Close())
+		util.LogErr(os.Remove(file.Name()))
+	}()
+	if err = write(file); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	if m.Size, err = file.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return b.dest.Put(ctx, m.DataName(), file, m.Size)
+}
+
+// saveManifest saves manifest to destination and etcd, so that backups can be listed without destination
+func (b *BackupService) saveManifest(ctx context.Context, m *backup.Manifest) error {
+	if err := backup.SaveManifest(ctx, b.dest, m); err != nil {
+		return err
+	}
+	bt, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Etcd.Put(ctx, cst.EtcdPrefix.FmtBackup(pool.Config.Registry.Group, b.group, m.ID), string(bt))
+	return err
+}
+
+// prune removes backups before the oldest full backup retained
+func (b *BackupService) prune(ctx context.Context, manifests []*backup.Manifest) {
+	var fulls []*backup.Manifest
+	for _, m := range manifests {
+		if m.Type == backup.Full {
+			fulls = append(fulls, m)
+		}
+	}
+	if b.cfg.Retain <= 0 || len(fulls) <= b.cfg.Retain {
+		return
+	}
+	oldest := fulls[len(fulls)-b.cfg.Retain]
+	for _, m := range manifests {
+		if m.ID >= oldest.ID {
+			continue
+		}
+		util.LogErrWithPre("remove backup manifest", b.dest.Delete(ctx, m.Name()))
+		util.LogErrWithPre("remove backup data", b.dest.Delete(ctx, m.DataName()))
+		_, err := pool.Etcd.Delete(ctx, cst.EtcdPrefix.FmtBackup(pool.Config.Registry.Group, b.group, m.ID))
+		util.LogErrWithPre("remove backup manifest in etcd", err)
+	}
+}
+
+// RestoreBackup rebuilds a db of engine at path from backups of group until target, logs in incremental backups and
+// incremental raft snapshots are replayed by fsm opened on the db. returns the raft index which the db is restored to.
+func RestoreBackup(ctx context.Context, dest backup.Destination, group string, target backup.Target, engine, path string,
+	newFSM func(*db.Storage) raft.BatchingFSM) (uint64, error) {
+	manifests, err := backup.ListManifests(ctx, dest, group)
+	if err != nil {
+		return 0, err
+	}
+	chain, err := backup.Chain(manifests, target)
+	if err != nil {
+		return 0, err
+	}
+	storage := db.NewStorage()
+	storage.Engine = engine
+	var fsm raft.BatchingFSM
+	full := chain[0]
+	err = readBackup(ctx, dest, full, func(r io.Reader) error {
+		rd := bufio.NewReaderSize(r, db.RestoreBufferSize)
+		if err := db.RestoreFile(engine, path, rd); err != nil {
+			return err
+		}
+		if err := storage.Open(path); err != nil {
+			return err
+		}
+		fsm = newFSM(storage)
+		// a raft snapshot may be opened with incremental ones
+		return raftimpl.ReplayDeltas(rd, fsm)
+	})
+	if fsm != nil {
+		defer func() { util.LogErr(storage.Stop()) }()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("restore full backup %s: %w", full.ID, err)
+	}
+	index := full.ToIndex
+	for _, m := range chain[1:] {
+		var batch []*raft.Log
+		var reached bool
+		err = readBackup(ctx, dest, m, func(r io.Reader) error {
+			if err := backup.ReadLogs(r, func(lg *raft.Log) bool {
+				if (target.Index > 0 && lg.Index > target.Index) || (!target.Time.IsZero() && lg.AppendedAt.After(target.Time)) {
+					reached = true
+					return false
+				}
+				if batch = append(batch, lg); len(batch) >= 256 {
+					raftimpl.ApplyLogs(fsm, batch)
+					batch = batch[:0]
+				}
+				index = lg.Index
+				return true
+			}); err != nil {
+				return err
+			}
+			raftimpl.ApplyLogs(fsm, batch)
+			return nil
+		})
+		if err != nil {
+			return index, fmt.Errorf("replay incremental backup %s: %w", m.ID, err)
+		}
+		if reached {
+			break
+		}
+		index = m.ToIndex
+	}
+	return index, nil
+}
+
+func readBackup(ctx context.Context, dest backup.Destination, m *backup.Manifest, fn func(io.Reader) error) error {
+	rc, err := dest.Get(ctx, m.DataName())
+	if err != nil {
+		return err
+	}
+	defer util.CloseAndLog(rc)
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return err
+	}
+	defer util.CloseAndLog(gz)
+	return fn(gz)
+}
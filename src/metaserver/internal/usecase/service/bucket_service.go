@@ -11,10 +11,11 @@ import (
 type BucketService struct {
 	usecase.BucketRepo
 	usecase.RaftApply
+	changeRecorder
 }
 
-func NewBucketService(repo usecase.BucketRepo, rw *raftimpl.RaftWrapper) *BucketService {
-	return &BucketService{BucketRepo: repo, RaftApply: raftimpl.RaftApplier(rw)}
+func NewBucketService(repo usecase.BucketRepo, ops usecase.OpsRepo, feed usecase.ChangeFeedRepo, events usecase.EventRepo, rw *raftimpl.RaftWrapper) *BucketService {
+	return &BucketService{BucketRepo: repo, RaftApply: raftimpl.RaftApplier(rw), changeRecorder: changeRecorder{feed, events, ops}}
 }
 
 func (b *BucketService) Create(bucket *msg.Bucket) error {
@@ -23,26 +24,28 @@ func (b *BucketService) Create(bucket *msg.Bucket) error {
 	}
 	bucket.CreateTime = time.Now().UnixMilli()
 	bucket.UpdateTime = bucket.CreateTime
-	if ok, _, err := b.ApplyRaft(&entity.RaftData{
+	data := &entity.RaftData{
 		Type:   entity.LogInsert,
 		Dest:   entity.DestBucket,
 		Name:   bucket.Name,
 		Bucket: bucket,
-	}); ok {
+	}
+	if ok, _, err := b.ApplyRaft(data); ok {
 		return err
 	}
-	return b.BucketRepo.Create(bucket)
+	return b.record(data, func() error { return b.BucketRepo.Create(bucket) })
 }
 
 func (b *BucketService) Remove(name string) error {
-	if ok, _, err := b.ApplyRaft(&entity.RaftData{
+	data := &entity.RaftData{
 		Type: entity.LogRemove,
 		Dest: entity.DestBucket,
 		Name: name,
-	}); ok {
+	}
+	if ok, _, err := b.ApplyRaft(data); ok {
 		return err
 	}
-	return b.BucketRepo.Remove(name)
+	return b.record(data, func() error { return b.BucketRepo.Remove(name) })
 }
 
 func (b *BucketService) Update(bucket *msg.Bucket) error {
@@ -50,13 +53,14 @@ func (b *BucketService) Update(bucket *msg.Bucket) error {
 		return usecase.ErrNilData
 	}
 	bucket.UpdateTime = time.Now().UnixMilli()
-	if ok, _, err := b.ApplyRaft(&entity.RaftData{
+	data := &entity.RaftData{
 		Type:   entity.LogUpdate,
 		Dest:   entity.DestBucket,
 		Name:   bucket.Name,
 		Bucket: bucket,
-	}); ok {
+	}
+	if ok, _, err := b.ApplyRaft(data); ok {
 		return err
 	}
-	return b.BucketRepo.Update(bucket)
+	return b.record(data, func() error { return b.BucketRepo.Update(bucket) })
 }
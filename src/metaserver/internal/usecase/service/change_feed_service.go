@@ -0,0 +1,105 @@
+package service
+
+import (
+	"common/graceful"
+	"common/logs"
+	"common/proto/msg"
+	"context"
+	"errors"
+	"metaserver/config"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"time"
+)
+
+var cfLog = logs.New("change-feed")
+
+var ErrChangeFeedDisabled = errors.New("change feed is disabled")
+
+// changeRecorder records changes written without raft. changes applied by raft are recorded by fsm.
+type changeRecorder struct {
+	feed   usecase.ChangeFeedRepo
+	events usecase.EventRepo
+	ops    usecase.OpsRepo
+}
+
+// record applies data, appends its change to feed and publishes its event in a transaction.
+// data is written by write if there is nothing to record.
+func (r changeRecorder) record(data *entity.RaftData, write func() error) error {
+	if !r.feed.Enabled() && !r.events.Enabled() {
+		return write()
+	}
+	return r.ops.Apply(data, r.appendAll(r.feed.Prepare(data, time.Now())))
+}
+
+// recordAll applies all ops, appends their changes to feed and publishes their events in a transaction
+func (r changeRecorder) recordAll(ops []*entity.RaftData) error {
+	if !r.feed.Enabled() && !r.events.Enabled() {
+		return r.ops.ApplyOps(ops, nil)
+	}
+	now := time.Now()
+	changes := make([]*msg.Change, len(ops))
+	for i, d := range ops {
+		changes[i] = r.feed.Prepare(d, now)
+	}
+	return r.ops.ApplyOps(ops, r.appendAll(changes...))
+}
+
+func (r changeRecorder) appendAll(changes ...*msg.Change) usecase.TxFunc {
+	return func(tx kv.Tx) error {
+		if err := r.feed.Append(tx, changes...); err != nil {
+			return err
+		}
+		return r.events.Publish(tx, changes...)
+	}
+}
+
+// ChangeFeedService reads and trims the change feed of this group
+type ChangeFeedService struct {
+	feed usecase.ChangeFeedRepo
+	cfg  *config.ChangeFeedConfig
+}
+
+func NewChangeFeedService(feed usecase.ChangeFeedRepo, cfg *config.ChangeFeedConfig) *ChangeFeedService {
+	return &ChangeFeedService{feed: feed, cfg: cfg}
+}
+
+// List returns changes after the sequence and the latest sequence of feed
+func (c *ChangeFeedService) List(after uint64, limit int) ([]*msg.Change, uint64, error) {
+	if !c.feed.Enabled() {
+		return nil, 0, ErrChangeFeedDisabled
+	}
+	if limit <= 0 || limit > c.cfg.ListLimit {
+		limit = c.cfg.ListLimit
+	}
+	return c.feed.List(after, limit)
+}
+
+// StartAutoTrim removes changes older than retention periodically if enabled.
+// every server of a group trims its own feed.
+func (c *ChangeFeedService) StartAutoTrim() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !c.feed.Enabled() {
+		return cancel
+	}
+	go func() {
+		defer graceful.Recover()
+		tk := time.NewTicker(c.cfg.TrimInterval)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				cfLog.Info("stop auto trim")
+				return
+			case <-tk.C:
+				if n, err := c.feed.Trim(time.Now().Add(-c.cfg.Retention)); err != nil {
+					cfLog.Errorf("trim change feed err: %s", err)
+				} else if n > 0 {
+					cfLog.Infof("trim %d expired changes", n)
+				}
+			}
+		}
+	}()
+	return cancel
+}
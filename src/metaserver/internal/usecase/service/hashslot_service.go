@@ -11,6 +11,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"metaserver/config"
 	"metaserver/internal/entity"
 	"metaserver/internal/usecase"
@@ -18,6 +19,9 @@ import (
 	"metaserver/internal/usecase/logic"
 	"metaserver/internal/usecase/pool"
 	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -27,12 +31,19 @@ var (
 	hsLog = logs.New("hash-slot-migration")
 )
 
+// checkpointBatch is the number of keys sent between saving checkpoints
+const checkpointBatch = 1000
+
 type HashSlotService struct {
 	Store         *db.HashSlotDB
 	Service       usecase.IMetadataService
 	BucketService usecase.BucketService
 	Cfg           *config.HashSlotConfig
 	startReceive  func()
+	// legacyTarget marks the target of migration finishes when the only stream closed
+	legacyTarget bool
+	// finishOnClose marks the source of migration finishes it by closing the only stream
+	finishOnClose atomic.Bool
 }
 
 func NewHashSlotService(st *db.HashSlotDB, serv usecase.IMetadataService, bucketService usecase.BucketService, cfg *config.HashSlotConfig) *HashSlotService {
@@ -65,7 +76,31 @@ func (h *HashSlotService) OnLeaderChanged(isLeader bool) {
 			logs.Std().Error(err)
 			return
 		}
+		h.restoreTracking()
+		return
 	}
+	h.Store.StopTracking()
+}
+
+// restoreTracking keeps tracking writes to migrating slots if there is a broken migration, so that it could continue later
+func (h *HashSlotService) restoreTracking() {
+	cp, ok, err := h.Store.GetCheckpoint(h.Cfg.StoreID)
+	if err != nil {
+		hsLog.Errorf("get migration checkpoint err: %s", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err = h.Store.TrackMigrating(h.Cfg.StoreID, cp.Slots); err != nil {
+		hsLog.Errorf("restore migration checkpoint err: %s", err)
+		return
+	}
+	if err = h.markDirty(cp); err != nil {
+		hsLog.Errorf("restore dirty keys of migration err: %s", err)
+		return
+	}
+	hsLog.Infof("found broken migration to %s:%s, slots=%s", cp.Host, cp.Port, cp.Slots)
 }
 
 func (h *HashSlotService) GetCurrentSlots(reload bool) (map[string][]string, error) {
@@ -108,7 +143,8 @@ func (h *HashSlotService) PrepareMigrationTo(loc *pb.LocationInfo, slots []strin
 			Host: util.DetectServerIP(),
 			Port: pool.Config.Port,
 		},
-		Slots: slots,
+		Slots:       slots,
+		FinishByRpc: true,
 	})
 	if err != nil {
 		return err
@@ -116,12 +152,31 @@ func (h *HashSlotService) PrepareMigrationTo(loc *pb.LocationInfo, slots []strin
 	if !resp.GetSuccess() {
 		return errors.New(resp.GetMessage())
 	}
+	if h.legacyTarget = resp.GetMessage() != entity.AckFinishByRpc; h.legacyTarget {
+		hsLog.Warnf("target %s finishes migration when stream closed, writes to migrating slots are rejected during migration", loc.GetHost())
+	}
 	// change status to migrate-to
-	return h.Store.ReadyMigrateTo(loc.GetHost(), slots)
+	if err = h.Store.ReadyMigrateTo(loc.GetHost(), slots); err != nil {
+		return err
+	}
+	// discard the broken migration if it's not the same one
+	cp, ok, err := h.Store.GetCheckpoint(h.Cfg.StoreID)
+	if err != nil {
+		return err
+	}
+	if ok && !cp.IsSame(loc.GetHost(), loc.GetPort(), slots) {
+		hsLog.Warnf("discard broken migration to %s:%s, slots=%s", cp.Host, cp.Port, cp.Slots)
+		h.Store.StopTracking()
+		if err = h.Store.RemoveCheckpoint(h.Cfg.StoreID); err != nil {
+			return err
+		}
+	}
+	return h.Store.TrackMigrating(h.Cfg.StoreID, slots)
 }
 
-// PrepareMigrationFrom Change into migrate-from. Status will change back if timeout
-func (h *HashSlotService) PrepareMigrationFrom(loc *pb.LocationInfo, slots []string) error {
+// PrepareMigrationFrom Change into migrate-from. Status will change back if timeout.
+// a source of old version doesn't finish by rpc, the migration finishes when its only stream closed.
+func (h *HashSlotService) PrepareMigrationFrom(loc *pb.LocationInfo, slots []string, finishByRpc bool) error {
 	// validate slots
 	provider, err := h.Store.GetEdgeProvider(false)
 	if err != nil {
@@ -141,10 +196,11 @@ func (h *HashSlotService) PrepareMigrationFrom(loc *pb.LocationInfo, slots []str
 	if err = h.Store.ReadyMigrateFrom(loc.GetHost(), slots); err != nil {
 		return err
 	}
+	h.finishOnClose.Store(!finishByRpc)
 	cancelCh := make(chan struct{})
+	once := &sync.Once{}
 	h.startReceive = func() {
-		close(cancelCh)
-		h.startReceive = func() {}
+		once.Do(func() { close(cancelCh) })
 	}
 	go func() {
 		select {
@@ -158,6 +214,11 @@ func (h *HashSlotService) PrepareMigrationFrom(loc *pb.LocationInfo, slots []str
 	return nil
 }
 
+// FinishOnClose reports whether the migration receiving finishes when the stream closed
+func (h *HashSlotService) FinishOnClose() bool {
+	return h.finishOnClose.Load()
+}
+
 func (h *HashSlotService) FinishReceiveItem(success bool) error {
 	h.startReceive()
 	var (
@@ -186,12 +247,19 @@ func (h *HashSlotService) FinishReceiveItem(success bool) error {
 	if err = logic.NewHashSlot().SaveToEtcd(h.Cfg.StoreID, info); err != nil {
 		return fmt.Errorf("save new slot-info fails after finsih migrateion: %w", err)
 	}
+	// accept keys of received slots at once instead of until slot-info expired
+	if _, err = h.Store.GetEdgeProvider(true); err != nil {
+		hsLog.Errorf("reload slot-info after migration err: %s", err)
+	}
 	logs.Std().Debugf("finish migration from %s success", fromHost)
 	return nil
 }
 
 func (h *HashSlotService) ReceiveItem(item *pb.MigrationItem) error {
 	h.startReceive()
+	if item.Removed {
+		return h.removeItem(entity.Dest(item.Dest), item.Name)
+	}
 	var err error
 	switch entity.Dest(item.Dest) {
 	case entity.DestVersion:
@@ -212,6 +280,12 @@ func (h *HashSlotService) ReceiveItem(item *pb.MigrationItem) error {
 			return err
 		}
 		err = h.BucketService.Create(&i)
+	case entity.DestHashRef:
+		var i msg.HashRef
+		if err = util.DecodeMsgp(&i, item.Data); err != nil {
+			return err
+		}
+		err = h.Service.ReceiveHashRef(&i)
 	default:
 		hsLog.Errorf("unknown migration item: %d", item.Dest)
 	}
@@ -221,173 +295,408 @@ func (h *HashSlotService) ReceiveItem(item *pb.MigrationItem) error {
 	return nil
 }
 
-// AutoMigrate migrate data
-// TODO(perf): multi goroutine
-func (h *HashSlotService) AutoMigrate(toLoc *pb.LocationInfo, slots []string) error {
+func (h *HashSlotService) removeItem(dest entity.Dest, key string) error {
+	var err error
+	switch dest {
+	case entity.DestMetadata:
+		err = h.Service.RemoveMetadata(key)
+	case entity.DestBucket:
+		err = h.BucketService.Remove(key)
+	case entity.DestHashRef:
+		err = h.Service.RemoveHashRef(key)
+	default:
+		hsLog.Errorf("unknown migration item: %d", dest)
+	}
+	if err != nil && !errors.Is(err, usecase.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// migratingDests are the types of data to migrate in order
+var migratingDests = []entity.Dest{entity.DestMetadata, entity.DestBucket, entity.DestHashRef}
+
+// AutoMigrate migrate data of slots to target. keys in other slots are writable during migration.
+// keys are sent by multi streams and the progress is saved as checkpoint. writes to migrated keys are sent again before finishing.
+// a broken migration will continue from checkpoint if it's started again with the same target and slots.
+func (h *HashSlotService) AutoMigrate(toLoc *pb.LocationInfo, slots []string) (err error) {
 	if ok, host, _ := h.Store.GetMigrateTo(); !ok || host != toLoc.GetHost() {
 		return fmt.Errorf("no ready to migrate to %s", toLoc.GetHost())
 	}
 	defer h.Store.FinishMigrateTo() // avoid status stick if PANIC
 	// connect to target
-	cc, err := grpc.Dial(net.JoinHostPort(toLoc.Host, toLoc.Port), grpc.WithInsecure())
+	target, err := dialMigrationTarget(toLoc, h.legacyTarget)
 	if err != nil {
 		return err
 	}
-	defer cc.Close()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	stream, err := pb.NewHashSlotClient(cc).StreamingReceive(ctx)
+	defer target.close()
+	cp, err := h.loadCheckpoint(toLoc, slots)
 	if err != nil {
 		return err
 	}
-	delEdges, _ := hashslot.WrapSlotsToEdges(slots, "")
-	var errs []error
-	errs = append(errs, h.migrateMetadata(stream, delEdges)...)
-	errs = append(errs, h.migrateBuckets(stream, delEdges)...)
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	defer func() {
+		if err != nil {
+			h.Store.Unfreeze()
+		}
+	}()
+	// notify target to abort and save checkpoint for continuing
+	defer func() {
+		if err == nil {
+			return
+		}
+		util.LogErrWithPre("save migration checkpoint", h.Store.SaveCheckpoint(h.Cfg.StoreID, cp))
+		util.LogErrWithPre("abort migration of target", target.finish(false))
+	}()
+	if target.isLegacy() {
+		// target of old version could not remove items to receive again, so all keys are sent while writes are frozen
+		h.Store.Freeze()
+		cp.Cursors = map[entity.Dest]string{}
+		h.Store.TakeDirty()
+	}
+	edges, _ := hashslot.WrapSlotsToEdges(slots, "")
+	keys := h.migratingKeys(edges)
+	for _, dest := range migratingDests {
+		if err = h.streamKeys(target, cp, dest, keys[dest]); err != nil {
+			return err
+		}
+	}
+	// send dirty keys again until few left
+	for i := 0; i < 3; i++ {
+		dirty := h.Store.TakeDirty()
+		if err = h.resendKeys(target, dirty); err != nil {
+			return err
+		}
+		if len(dirty) < 100 {
+			break
+		}
 	}
-	// all migrate success
-	if err = h.Store.FinishMigrateTo(); err != nil {
-		hsLog.Debugf("switch status to normal err: %s", err)
+	// freeze writing to migrating slots and send the rest dirty keys
+	h.Store.Freeze()
+	if err = h.resendKeys(target, h.Store.TakeDirty()); err != nil {
+		return err
+	}
+	if err = target.finish(true); err != nil {
+		return fmt.Errorf("finish migration of target err: %w", err)
 	}
 	// remove slots from current slot-info
 	info, _, err := h.Store.Get(h.Cfg.StoreID)
 	if err != nil {
 		return fmt.Errorf("update slot fails after finish migration: %w", err)
 	}
+	// all migrate success
+	if inner := h.Store.FinishMigrateTo(); inner != nil {
+		hsLog.Debugf("switch status to normal err: %s", inner)
+	}
 	curEdges, _ := hashslot.WrapSlotsToEdges(info.Slots, "")
-	info.Slots = hashslot.RemoveEdges(curEdges, delEdges).Strings()
+	info.Slots = hashslot.RemoveEdges(curEdges, edges).Strings()
 	// save new slot-info
 	if err = logic.NewHashSlot().SaveToEtcd(h.Cfg.StoreID, info); err != nil {
 		return fmt.Errorf("save new slot-info fails after finsih migrateion: %w", err)
 	}
-	// close stream as success
-	if err = stream.CloseSend(); err != nil {
-		hsLog.Error(err)
+	// keys of migrated slots must be rejected by the new slot-info before they are writable again.
+	// if it fails, keep them frozen until the next migration, they are not in this group anyway.
+	if _, inner := h.Store.GetEdgeProvider(true); inner != nil {
+		hsLog.Errorf("reload slot-info after migration err: %s, keep migrated slots frozen", inner)
+	} else {
+		h.Store.StopTracking()
+		h.Store.Unfreeze()
 	}
+	util.LogErrWithPre("remove migration checkpoint", h.Store.RemoveCheckpoint(h.Cfg.StoreID))
+	go func() {
+		defer graceful.Recover()
+		h.removeMigrated(edges)
+	}()
 	hsLog.Infof("finish migration to %s success", toLoc.Host)
 	return nil
 }
 
-func (h *HashSlotService) migrateMetadata(stream pb.HashSlot_StreamingReceiveClient, edges hashslot.EdgeList) (errs []error) {
-	var sucNum int
-	metaKeys := h.Service.FilterKeys(func(s string) bool {
-		return hashslot.IsSlotInEdges(hashslot.CalcBytesSlot(util.StrToBytes(s)), edges)
+// loadCheckpoint returns the checkpoint of the broken migration if it's the same one, otherwise a new one.
+func (h *HashSlotService) loadCheckpoint(toLoc *pb.LocationInfo, slots []string) (*entity.MigrationCheckpoint, error) {
+	cp, ok, err := h.Store.GetCheckpoint(h.Cfg.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	if ok && cp.IsSame(toLoc.GetHost(), toLoc.GetPort(), slots) {
+		if err = h.markDirty(cp); err != nil {
+			return nil, err
+		}
+		hsLog.Infof("continue migration from checkpoint %v", cp.Cursors)
+		return cp, nil
+	}
+	return &entity.MigrationCheckpoint{
+		Host:    toLoc.GetHost(),
+		Port:    toLoc.GetPort(),
+		Slots:   slots,
+		Cursors: map[entity.Dest]string{},
+	}, nil
+}
+
+// markDirty marks keys written after the checkpoint of broken migration as dirty
+func (h *HashSlotService) markDirty(cp *entity.MigrationCheckpoint) error {
+	dirty, err := h.Store.LoadDirty(h.Cfg.StoreID)
+	if err != nil {
+		return err
+	}
+	// checkpoint saved by old version contains dirty keys
+	for k, v := range cp.Dirty {
+		dirty[k] |= v
+	}
+	for k, v := range dirty {
+		h.Store.MarkDirty(k, v)
+	}
+	return nil
+}
+
+// migratingKeys returns sorted keys in slots of each dest
+func (h *HashSlotService) migratingKeys(edges hashslot.EdgeList) map[entity.Dest][]string {
+	inSlots := func(k []byte) bool {
+		return hashslot.IsSlotInEdges(hashslot.CalcBytesSlot(k), edges)
+	}
+	res := make(map[entity.Dest][]string, len(migratingDests))
+	res[entity.DestMetadata] = h.Service.FilterKeys(func(s string) bool {
+		return inSlots(util.StrToBytes(s))
 	})
-	total := len(metaKeys)
-	// send all metadata and versions
-	for len(metaKeys) > 0 {
-		key := metaKeys[0]
-		metaKeys = metaKeys[1:] // for earlier GC
-		data, err := h.Service.GetMetadataBytes(key)
-		if err != nil {
-			errs = append(errs, err)
-			continue
+	util.LogErrWithPre("foreach buckets", h.BucketService.Foreach(func(k []byte, _ []byte) error {
+		if inSlots(k) {
+			res[entity.DestBucket] = append(res[entity.DestBucket], string(k))
 		}
-		// send data
-		if err = stream.Send(&pb.MigrationItem{
-			Name: key,
-			Data: data,
-			Dest: int32(entity.DestMetadata),
-		}); err != nil {
-			hsLog.Debugf("send metadata %s err: %s", key, err)
-			errs = append(errs, err)
-			continue
+		return nil
+	}))
+	util.LogErrWithPre("foreach hash-refs", h.Service.ForeachHashRef(func(k []byte, _ []byte) error {
+		if inSlots(k) {
+			res[entity.DestHashRef] = append(res[entity.DestHashRef], string(k))
 		}
-		// recv response
-		_, err = proto.ResolveResponse(stream.Recv())
+		return nil
+	}))
+	for _, keys := range res {
+		sort.Strings(keys)
+	}
+	return res
+}
+
+// streamKeys sends keys greater than the cursor of checkpoint by multi streams.
+// cursor moves forward when all keys before it have been sent, and checkpoint is saved periodically.
+func (h *HashSlotService) streamKeys(target *migrationTarget, cp *entity.MigrationCheckpoint, dest entity.Dest, keys []string) error {
+	start := sort.SearchStrings(keys, cp.Cursors[dest])
+	if start < len(keys) && keys[start] == cp.Cursors[dest] {
+		start++
+	}
+	keys = keys[start:]
+	var (
+		mux   sync.Mutex
+		done  = make([]bool, len(keys))
+		next  int
+		sent  int
+		errs  []error
+		tasks = make(chan int)
+		wg    sync.WaitGroup
+	)
+	// finished marks keys[i] sent and moves cursor forward
+	finished := func(i int, err error) {
+		mux.Lock()
+		defer mux.Unlock()
 		if err != nil {
-			hsLog.Debugf("recv send-metadata %s response err: %s", key, err)
 			errs = append(errs, err)
-			continue
+			return
 		}
-		// start send versions
-		allVersionSuccess := true
-		h.Service.ForeachVersionBytes(key, func(b []byte) bool {
-			// send version
-			if err = stream.Send(&pb.MigrationItem{
-				Name: key,
-				Data: b,
-				Dest: int32(entity.DestVersion),
-			}); err != nil {
-				errs = append(errs, err)
-				allVersionSuccess = false
-				hsLog.Debugf("send-metadata-version %s err: %s", key, err)
-			}
-			// recv response
-			_, err = proto.ResolveResponse(stream.Recv())
-			if err != nil {
-				errs = append(errs, err)
-				allVersionSuccess = false
-				hsLog.Debugf("send-metadata-version %s recv err: %s", key, err)
-			}
-			return true
-		})
-		if !allVersionSuccess {
-			continue
+		done[i] = true
+		for ; next < len(done) && done[next]; next++ {
+			cp.Cursors[dest] = keys[next]
+		}
+		if sent++; sent%checkpointBatch == 0 {
+			util.LogErrWithPre("save migration checkpoint", h.Store.SaveCheckpoint(h.Cfg.StoreID, cp))
+		}
+	}
+	for w := 0; w < target.workers(h.Cfg.MigrateWorkers); w++ {
+		stream, closeSend, err := target.open()
+		if err != nil {
+			close(tasks)
+			wg.Wait()
+			return err
 		}
-		sucNum++
-		// delete if all success
+		wg.Add(1)
 		go func() {
 			defer graceful.Recover()
-			if inner := h.Service.RemoveMetadata(key); inner != nil {
-				hsLog.Errorf("delete-metadata %s fail: %s", key, inner)
+			defer wg.Done()
+			defer closeSend()
+			for i := range tasks {
+				finished(i, h.sendKey(stream, dest, keys[i], false))
 			}
 		}()
 	}
-	hsLog.Infof("migration totally %d metadata and successed %d verions", total, sucNum)
-	return
+	for i := range keys {
+		tasks <- i
+	}
+	close(tasks)
+	wg.Wait()
+	hsLog.Infof("migration totally %d of dest %d and %d failed", len(keys), dest, len(errs))
+	return errors.Join(errs...)
 }
 
-func (h *HashSlotService) migrateBuckets(stream pb.HashSlot_StreamingReceiveClient, edges hashslot.EdgeList) (errs []error) {
-	var successBuckets int
-	var migKeys [][]byte
-	err := h.BucketService.Foreach(func(k []byte, _ []byte) error {
-		if !hashslot.IsSlotInEdges(hashslot.CalcBytesSlot(k), edges) {
-			return nil
-		}
-		migKeys = append(migKeys, k)
+// resendKeys removes dirty keys from target and sends them again. dirty keys will be marked again if fails.
+func (h *HashSlotService) resendKeys(target *migrationTarget, dirty map[string]entity.Dest) (err error) {
+	if len(dirty) == 0 {
 		return nil
-	})
-	total := len(migKeys)
-	for len(migKeys) > 0 {
-		k := migKeys[0]
-		migKeys = migKeys[1:] // for earlier GC
-		keyStr := util.BytesToStr(k)
-		v, err := h.BucketService.GetBytes(keyStr)
-		if err != nil {
-			errs = append(errs, err)
-			continue
+	}
+	stream, closeSend, err := target.open()
+	if err != nil {
+		return err
+	}
+	defer closeSend()
+	for k, v := range dirty {
+		for _, dest := range migratingDests {
+			if v&dest == 0 {
+				continue
+			}
+			if inner := h.sendKey(stream, dest, k, true); inner != nil {
+				h.Store.MarkDirty(k, dest)
+				err = errors.Join(err, inner)
+			}
 		}
-		// send data
-		if err = stream.Send(&pb.MigrationItem{
-			Name: keyStr,
-			Data: v,
-			Dest: int32(entity.DestBucket),
-		}); err != nil {
-			hsLog.Debugf("send bucket %s err: %s", keyStr, err)
-			errs = append(errs, err)
-			continue
+	}
+	hsLog.Infof("migration resend %d dirty keys", len(dirty))
+	return
+}
+
+// migrationTarget sends items to the target of migration by streams.
+// target of old version receives all items by only one stream and finishes migration when it closed,
+// so the stream is shared and kept until finishing.
+type migrationTarget struct {
+	cc     *grpc.ClientConn
+	client pb.HashSlotClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	legacy pb.HashSlot_StreamingReceiveClient
+}
+
+func dialMigrationTarget(loc *pb.LocationInfo, legacy bool) (*migrationTarget, error) {
+	cc, err := grpc.Dial(net.JoinHostPort(loc.GetHost(), loc.GetPort()), grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	t := &migrationTarget{cc: cc, client: pb.NewHashSlotClient(cc)}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	if legacy {
+		if t.legacy, err = t.client.StreamingReceive(t.ctx); err != nil {
+			t.close()
+			return nil, err
 		}
-		// recv response
-		_, err = proto.ResolveResponse(stream.Recv())
-		if err != nil {
-			hsLog.Debugf("recv send-bucket %s response err: %s", keyStr, err)
-			errs = append(errs, err)
-			continue
+	}
+	return t, nil
+}
+
+func (t *migrationTarget) isLegacy() bool {
+	return t.legacy != nil
+}
+
+// workers returns the number of streams to send keys
+func (t *migrationTarget) workers(n int) int {
+	if t.isLegacy() {
+		return 1
+	}
+	return n
+}
+
+// open returns a stream and the function to close it
+func (t *migrationTarget) open() (pb.HashSlot_StreamingReceiveClient, func(), error) {
+	if t.isLegacy() {
+		return t.legacy, func() {}, nil
+	}
+	stream, err := t.client.StreamingReceive(t.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, func() { util.LogErr(stream.CloseSend()) }, nil
+}
+
+// finish notifies target to finish migration
+func (t *migrationTarget) finish(success bool) error {
+	if !t.isLegacy() {
+		_, err := proto.ResolveResponse(t.client.FinishMigration(context.Background(), &pb.FinishReq{Success: success}))
+		return err
+	}
+	// legacy target aborts if the stream is broken
+	if !success {
+		t.cancel()
+		return nil
+	}
+	if err := t.legacy.CloseSend(); err != nil {
+		return err
+	}
+	// stream ends after target finished
+	if _, err := t.legacy.Recv(); err != io.EOF {
+		return fmt.Errorf("wait for target finishing: %v", err)
+	}
+	return nil
+}
+
+func (t *migrationTarget) close() {
+	t.cancel()
+	util.LogErr(t.cc.Close())
+}
+
+func sendItem(stream pb.HashSlot_StreamingReceiveClient, item *pb.MigrationItem) error {
+	if err := stream.Send(item); err != nil {
+		return fmt.Errorf("send %s err: %w", item.Name, err)
+	}
+	if _, err := proto.ResolveResponse(stream.Recv()); err != nil {
+		return fmt.Errorf("recv send-%s response err: %w", item.Name, err)
+	}
+	return nil
+}
+
+// sendKey sends data of key. if resend, data will be removed from target at first.
+func (h *HashSlotService) sendKey(stream pb.HashSlot_StreamingReceiveClient, dest entity.Dest, key string, resend bool) error {
+	if resend {
+		if err := sendItem(stream, &pb.MigrationItem{Name: key, Dest: int32(dest), Removed: true}); err != nil {
+			return err
 		}
-		successBuckets++
-		go func() {
-			defer graceful.Recover()
-			if err := h.BucketService.Remove(keyStr); err != nil {
-				hsLog.Errorf("delete-bucket %s err: %s", keyStr, err)
-			}
-		}()
+	}
+	var (
+		data []byte
+		err  error
+	)
+	switch dest {
+	case entity.DestMetadata:
+		data, err = h.Service.GetMetadataBytes(key)
+	case entity.DestBucket:
+		data, err = h.BucketService.GetBytes(key)
+	case entity.DestHashRef:
+		data, err = h.Service.GetHashRefBytes(key)
+	}
+	// key has been removed
+	if errors.Is(err, usecase.ErrNotFound) {
+		return nil
 	}
 	if err != nil {
-		errs = append(errs, err)
+		return err
+	}
+	if err = sendItem(stream, &pb.MigrationItem{Name: key, Data: data, Dest: int32(dest)}); err != nil {
+		return err
+	}
+	if dest != entity.DestMetadata {
+		return nil
+	}
+	// send versions of metadata
+	h.Service.ForeachVersionBytes(key, func(b []byte) bool {
+		err = sendItem(stream, &pb.MigrationItem{Name: key, Data: b, Dest: int32(entity.DestVersion)})
+		return err == nil
+	})
+	return err
+}
+
+// removeMigrated removes data in slots which have been migrated to other group
+func (h *HashSlotService) removeMigrated(edges hashslot.EdgeList) {
+	var fails int
+	for dest, keys := range h.migratingKeys(edges) {
+		for _, k := range keys {
+			if err := h.removeItem(dest, k); err != nil {
+				hsLog.Debugf("remove migrated %s err: %s", k, err)
+				fails++
+			}
+		}
+	}
+	if fails > 0 {
+		hsLog.Errorf("remove migrated data fails %d", fails)
 	}
-	hsLog.Infof("migration totally %d buckets and successed %d", total, successBuckets)
-	return
 }
@@ -1,12 +1,14 @@
 package service
 
 import (
-	"metaserver/internal/usecase/logic"
 	"common/proto/msg"
+	"common/response"
 	"common/util"
 	"errors"
+	"fmt"
 	"metaserver/internal/entity"
 	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/logic"
 	"metaserver/internal/usecase/raftimpl"
 	"strings"
 	"time"
@@ -14,46 +16,52 @@ import (
 
 type MetadataService struct {
 	usecase.RaftApply
+	changeRecorder
 	repo      usecase.IMetadataRepo
 	batch     usecase.IBatchMetaRepo
 	hashIndex usecase.IHashIndexRepo
 }
 
-func NewMetadataService(repo usecase.IMetadataRepo, batch usecase.IBatchMetaRepo, hashIndex usecase.IHashIndexRepo, rw *raftimpl.RaftWrapper) *MetadataService {
-	return &MetadataService{raftimpl.RaftApplier(rw), repo, batch, hashIndex}
+func NewMetadataService(repo usecase.IMetadataRepo, batch usecase.IBatchMetaRepo, hashIndex usecase.IHashIndexRepo, ops usecase.OpsRepo, feed usecase.ChangeFeedRepo, events usecase.EventRepo, rw *raftimpl.RaftWrapper) *MetadataService {
+	return &MetadataService{raftimpl.RaftApplier(rw), changeRecorder{feed, events, ops}, repo, batch, hashIndex}
 }
 
 func (m *MetadataService) AddMetadata(id string, data *msg.Metadata) error {
 	data.CreateTime = time.Now().UnixMilli()
 	data.UpdateTime = data.CreateTime
-	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+	rd := &entity.RaftData{
 		Type:     entity.LogInsert,
 		Dest:     entity.DestMetadata,
 		Name:     id,
 		Metadata: data,
-	}); ok {
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
 		return err
 	}
 
-	return m.repo.AddMetadata(id, data)
+	return m.record(rd, func() error { return m.repo.AddMetadata(id, data) })
 }
 
 func (m *MetadataService) AddVersion(name string, data *msg.Version) (int, error) {
 	data.UniqueId = logic.GenerateUniqueId()
-	data.Ts = time.Now().UnixMilli()
-	if ok, resp, err := m.ApplyRaft(&entity.RaftData{
+	// replicas keep the time of source version to resolve conflicts by last writer
+	if data.Replication != msg.ReplicaReplica || data.Ts <= 0 {
+		data.Ts = time.Now().UnixMilli()
+	}
+	rd := &entity.RaftData{
 		Type:    entity.LogInsert,
 		Dest:    entity.DestVersion,
 		Name:    name,
 		Version: data,
-	}); ok {
+	}
+	if ok, resp, err := m.ApplyRaft(rd); ok {
 		if err != nil {
 			return -1, err
 		}
 		return int(resp.(uint64)), nil
 	}
 
-	if err := m.repo.AddVersion(name, data); err != nil {
+	if err := m.record(rd, func() error { return m.repo.AddVersion(name, data) }); err != nil {
 		return -1, err
 	}
 	return int(data.Sequence), nil
@@ -77,61 +85,214 @@ func (m *MetadataService) ReceiveVersion(name string, data *msg.Version) error {
 
 func (m *MetadataService) UpdateMetadata(name string, data *msg.Metadata) error {
 	data.UpdateTime = time.Now().UnixMilli()
-	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+	rd := &entity.RaftData{
 		Type:     entity.LogUpdate,
 		Dest:     entity.DestMetadata,
 		Name:     name,
 		Metadata: data,
-	}); ok {
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
 		return err
 	}
 
-	return m.repo.UpdateMetadata(name, data)
+	return m.record(rd, func() error { return m.repo.UpdateMetadata(name, data) })
 }
 
 func (m *MetadataService) UpdateVersion(name string, ver int, data *msg.Version) error {
 	data.Ts = time.Now().UnixMilli()
 	data.Sequence = uint64(ver)
-	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+	rd := &entity.RaftData{
 		Type:     entity.LogUpdate,
 		Dest:     entity.DestVersion,
 		Name:     name,
 		Sequence: data.Sequence,
 		Version:  data,
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
+		return err
+	}
+
+	return m.record(rd, func() error { return m.repo.UpdateVersion(name, data) })
+}
+
+// TouchVersion marks the version has been read just now
+func (m *MetadataService) TouchVersion(name string, ver int) error {
+	ts := time.Now().UnixMilli()
+	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+		Type:     entity.LogTouch,
+		Dest:     entity.DestVersion,
+		Name:     name,
+		Sequence: uint64(ver),
+		Version:  &msg.Version{AccessTs: ts},
 	}); ok {
 		return err
 	}
 
-	return m.repo.UpdateVersion(name, data)
+	return m.repo.TouchVersion(name, uint64(ver), ts)
 }
 
-func (m *MetadataService) RemoveMetadata(name string) error {
+// MarkVersion sets the replication status of the version
+func (m *MetadataService) MarkVersion(name string, ver int, status string) error {
 	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+		Type:     entity.LogMark,
+		Dest:     entity.DestVersion,
+		Name:     name,
+		Sequence: uint64(ver),
+		Version:  &msg.Version{Replication: status},
+	}); ok {
+		return err
+	}
+
+	return m.repo.MarkVersion(name, uint64(ver), status)
+}
+
+// SwapVersion replaces storage layout of version. data.Ts must be the same as the saved one,
+// or usecase.ErrOldData returns which means version has been changed by others.
+func (m *MetadataService) SwapVersion(name string, data *msg.Version) error {
+	expect := data.Ts
+	rd := &entity.RaftData{
+		Type:     entity.LogSwap,
+		Dest:     entity.DestVersion,
+		Name:     name,
+		Sequence: data.Sequence,
+		Version:  data,
+		Expect:   expect,
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
+		return err
+	}
+
+	return m.record(rd, func() error { return m.repo.SwapVersion(name, data, expect) })
+}
+
+// Batch applies mutations of changes in a raft entry and a transaction, all of them succeed or none.
+// returns sequences of versions put by changes at the same index and versions removed by changes.
+func (m *MetadataService) Batch(changes []*msg.Change) (*entity.BatchResult, error) {
+	now := time.Now().UnixMilli()
+	ops := make([]*entity.RaftData, len(changes))
+	for i, c := range changes {
+		op, err := entity.FromChange(c)
+		if err != nil {
+			return nil, response.NewError(400, err.Error())
+		}
+		if err = prepareOp(op, now); err != nil {
+			return nil, response.NewError(400, fmt.Sprintf("op %d of %s: %s", i, op.Name, err))
+		}
+		ops[i] = op
+	}
+	rd := &entity.RaftData{
+		Type: entity.LogInsert,
+		Dest: entity.DestBatch,
+		Ops:  ops,
+	}
+	if ok, resp, err := m.ApplyRaft(rd); ok {
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*entity.BatchResult), nil
+	}
+
+	if err := m.recordAll(ops); err != nil {
+		return nil, err
+	}
+	return entity.BatchResultOf(ops), nil
+}
+
+// prepareOp checks op and fills the fields set by server like the single writing
+func prepareOp(op *entity.RaftData, now int64) error {
+	if op.Name == "" {
+		return errors.New("id required")
+	}
+	switch {
+	case op.Dest == entity.DestMetadata && op.Type == entity.LogInsert:
+		if op.Metadata == nil {
+			return usecase.ErrNilData
+		}
+		op.Metadata.CreateTime, op.Metadata.UpdateTime = now, now
+	case op.Dest == entity.DestVersion && op.Type == entity.LogInsert:
+		if op.Version == nil {
+			return usecase.ErrNilData
+		}
+		if op.Version.Hash == "" {
+			return errors.New("version hash required")
+		}
+		op.Version.UniqueId = logic.GenerateUniqueId()
+		if op.Version.Replication != msg.ReplicaReplica || op.Version.Ts <= 0 {
+			op.Version.Ts = now
+		}
+	case op.Dest == entity.DestVersion && op.Type == entity.LogUpdate:
+		if op.Version == nil {
+			return usecase.ErrNilData
+		}
+		if op.Sequence == 0 {
+			return errors.New("version sequence required")
+		}
+		op.Version.Ts = now
+	case op.Dest == entity.DestVersion && op.Type == entity.LogRemove:
+		if op.Sequence == 0 {
+			return errors.New("version sequence required")
+		}
+	case op.Dest == entity.DestBucket && op.Type == entity.LogInsert:
+		if op.Bucket == nil {
+			return usecase.ErrNilData
+		}
+		op.Bucket.Name = op.Name
+		op.Bucket.CreateTime, op.Bucket.UpdateTime = now, now
+	}
+	return nil
+}
+
+func (m *MetadataService) QueryVersions(q *msg.Query) ([]string, []*msg.Version, error) {
+	return m.repo.QueryVersions(q)
+}
+
+func (m *MetadataService) StatVersions(bucket string) (*msg.BucketStat, error) {
+	return m.repo.StatVersions(bucket)
+}
+
+func (m *MetadataService) Load() (*entity.StoreLoad, error) {
+	return m.repo.Load()
+}
+
+// ScanVersions lists at most 'limit' versions after the version key 'cursor'
+func (m *MetadataService) ScanVersions(cursor string, limit int) ([]string, []*msg.Version, error) {
+	return m.repo.ScanVersions(cursor, limit)
+}
+
+func (m *MetadataService) ListColdVersions(before int64, cursor string, limit int) ([]string, []*msg.Version, error) {
+	return m.repo.ListColdVersions(before, cursor, limit)
+}
+
+func (m *MetadataService) RemoveMetadata(name string) error {
+	rd := &entity.RaftData{
 		Type: entity.LogRemove,
 		Dest: entity.DestMetadata,
 		Name: name,
-	}); ok {
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
 		return err
 	}
 
-	return m.repo.RemoveMetadata(name)
+	return m.record(rd, func() error { return m.repo.RemoveMetadata(name) })
 }
 
 func (m *MetadataService) RemoveVersion(name string, ver int) error {
-	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+	rd := &entity.RaftData{
 		Type:     entity.LogRemove,
 		Dest:     util.IfElse(ver < 0, entity.DestVersionAll, entity.DestVersion),
 		Name:     name,
 		Sequence: uint64(ver),
-	}); ok {
+	}
+	if ok, _, err := m.ApplyRaft(rd); ok {
 		return err
 	}
 
-	if ver < 0 {
-		return m.repo.RemoveAllVersion(name)
-	} else {
+	return m.record(rd, func() error {
+		if ver < 0 {
+			return m.repo.RemoveAllVersion(name)
+		}
 		return m.repo.RemoveVersion(name, uint64(ver))
-	}
+	})
 }
 
 // GetMetadata 获取metadata及其版本，如果version为-1则不获取任何版本，返回的版本为nil
@@ -231,3 +392,76 @@ func (m *MetadataService) FindByHash(hash string) (res []*msg.Version, err error
 func (m *MetadataService) UpdateLocates(hash string, index int, locate string) error {
 	return m.repo.UpdateLocateByHash(hash, index, locate)
 }
+
+func (m *MetadataService) LocateHash(hash string) (*msg.HashRef, error) {
+	return m.hashIndex.GetRef(hash)
+}
+
+// ReferHash increases reference counter of the hash. returns the saved one whose locates may differ from the given.
+func (m *MetadataService) ReferHash(ref *msg.HashRef) (*msg.HashRef, error) {
+	if ok, resp, err := m.ApplyRaft(&entity.RaftData{
+		Type:    entity.LogInsert,
+		Dest:    entity.DestHashRef,
+		Name:    ref.Hash,
+		HashRef: ref,
+	}); ok {
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*msg.HashRef), nil
+	}
+
+	if err := m.hashIndex.Refer(ref); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// DereferHash decreases reference counter of the hash. the count of returned one will be zero if no more reference.
+func (m *MetadataService) DereferHash(hash string) (*msg.HashRef, error) {
+	if ok, resp, err := m.ApplyRaft(&entity.RaftData{
+		Type: entity.LogUpdate,
+		Dest: entity.DestHashRef,
+		Name: hash,
+	}); ok {
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*msg.HashRef), nil
+	}
+
+	return m.hashIndex.Derefer(hash)
+}
+
+func (m *MetadataService) ReceiveHashRef(ref *msg.HashRef) error {
+	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+		Type:    entity.LogMigrate,
+		Dest:    entity.DestHashRef,
+		Name:    ref.Hash,
+		HashRef: ref,
+	}); ok {
+		return err
+	}
+
+	return m.hashIndex.CreateRef(ref)
+}
+
+func (m *MetadataService) RemoveHashRef(hash string) error {
+	if ok, _, err := m.ApplyRaft(&entity.RaftData{
+		Type: entity.LogRemove,
+		Dest: entity.DestHashRef,
+		Name: hash,
+	}); ok {
+		return err
+	}
+
+	return m.hashIndex.RemoveRef(hash)
+}
+
+func (m *MetadataService) ForeachHashRef(fn func(k, v []byte) error) error {
+	return m.hashIndex.ForeachRef(fn)
+}
+
+func (m *MetadataService) GetHashRefBytes(hash string) ([]byte, error) {
+	return m.hashIndex.GetRefBytes(hash)
+}
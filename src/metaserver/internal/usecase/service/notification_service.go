@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"common/graceful"
+	"common/logs"
+	"common/notification"
+	"common/proto/msg"
+	"common/response"
+	"common/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"metaserver/config"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/raftimpl"
+	"net/http"
+	"time"
+)
+
+var ntLog = logs.New("notification")
+
+var ErrNotificationDisabled = errors.New("notification is disabled")
+
+// webhookConsumerPrefix is the prefix of cursors of webhooks, a cursor for each bucket
+const webhookConsumerPrefix = "webhook/"
+
+// webhookRetry is the failed delivery of a webhook
+type webhookRetry struct {
+	seq      uint64
+	attempts int
+	nextAt   time.Time
+}
+
+// NotificationService delivers events of buckets to subscribers and webhooks.
+// cursors of consumers are saved through raft, so only leader delivers events.
+type NotificationService struct {
+	usecase.RaftApply
+	events  usecase.EventRepo
+	buckets usecase.BucketRepo
+	rw      *raftimpl.RaftWrapper
+	cfg     *config.NotificationConfig
+	group   string
+	client  *http.Client
+	retries map[string]*webhookRetry
+}
+
+func NewNotificationService(events usecase.EventRepo, buckets usecase.BucketRepo, rw *raftimpl.RaftWrapper, cfg *config.Config) *NotificationService {
+	return &NotificationService{
+		RaftApply: raftimpl.RaftApplier(rw),
+		events:    events,
+		buckets:   buckets,
+		rw:        rw,
+		cfg:       &cfg.Notification,
+		group:     cfg.HashSlot.StoreID,
+		client:    &http.Client{Timeout: cfg.Notification.WebhookTimeout},
+		retries:   make(map[string]*webhookRetry),
+	}
+}
+
+func (n *NotificationService) isLeader() bool {
+	return !n.rw.Enabled || n.rw.IsLeader()
+}
+
+// Ack saves the acknowledged seq of consumer
+func (n *NotificationService) Ack(consumer string, seq uint64) error {
+	if !n.isLeader() {
+		return response.NewError(503, "server is not a leader")
+	}
+	if ok, _, err := n.ApplyRaft(&entity.RaftData{
+		Type:     entity.LogUpdate,
+		Dest:     entity.DestEventCursor,
+		Name:     consumer,
+		Sequence: seq,
+	}); ok {
+		return err
+	}
+	return n.events.SetCursor(consumer, seq)
+}
+
+// ackAll saves the acknowledged seqs of consumers in a raft log
+func (n *NotificationService) ackAll(cursors map[string]uint64) error {
+	if len(cursors) == 0 {
+		return nil
+	}
+	data := &entity.RaftData{Type: entity.LogUpdate, Dest: entity.DestEventCursor, Ops: make([]*entity.RaftData, 0, len(cursors))}
+	for consumer, seq := range cursors {
+		data.Ops = append(data.Ops, &entity.RaftData{Name: consumer, Sequence: seq})
+	}
+	if ok, _, err := n.ApplyRaft(data); ok {
+		return err
+	}
+	return n.events.SetCursors(cursors)
+}
+
+// Subscribe sends events of bucket after the seq until ctx is done. events of all buckets are sent if bucket is empty.
+// if consumer is not empty, it starts from the cursor of consumer when after is zero, and moves the cursor after sending.
+func (n *NotificationService) Subscribe(ctx context.Context, consumer, bucket string, after uint64, send func([]*msg.Event) error) (err error) {
+	if !n.events.Enabled() {
+		return ErrNotificationDisabled
+	}
+	if after == 0 && consumer != "" {
+		if after, err = n.events.GetCursor(consumer); err != nil {
+			return err
+		}
+	}
+	tk := time.NewTicker(n.cfg.PollInterval)
+	defer tk.Stop()
+	for {
+		evs, _, err := n.events.List(after, n.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(evs) > 0 {
+			batch := make([]*msg.Event, 0, len(evs))
+			for _, ev := range evs {
+				if bucket == "" || ev.Bucket == bucket {
+					batch = append(batch, ev)
+				}
+			}
+			if len(batch) > 0 {
+				if err = send(batch); err != nil {
+					return err
+				}
+			}
+			after = evs[len(evs)-1].Seq
+			if consumer != "" {
+				if err = n.Ack(consumer, after); err != nil {
+					return err
+				}
+			}
+			if len(evs) == n.cfg.BatchSize {
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tk.C:
+		}
+	}
+}
+
+// StartDispatch posts events to webhooks of buckets periodically if enabled
+func (n *NotificationService) StartDispatch() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !n.events.Enabled() {
+		return cancel
+	}
+	go func() {
+		defer graceful.Recover()
+		tk := time.NewTicker(n.cfg.PollInterval)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				ntLog.Info("stop dispatching webhooks")
+				return
+			case <-tk.C:
+				if !n.isLeader() {
+					continue
+				}
+				if err := n.dispatch(ctx); err != nil {
+					ntLog.Errorf("dispatch webhooks err: %s", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// webhook is the delivering state of a bucket with webhook
+type webhook struct {
+	bucket  string
+	nt      *msg.Notification
+	acked   uint64 // acked is the saved cursor
+	cursor  uint64 // cursor is the seq until which events have been delivered or skipped
+	blocked bool   // blocked is true if an event failed and is waiting to be retried
+}
+
+// dispatch posts new events to webhooks of their buckets in a scan of the queue from the earliest cursor,
+// and saves moved cursors in a raft log. a failed event blocks later ones of its bucket until it succeeds
+// or is skipped after MaxRetry attempts.
+func (n *NotificationService) dispatch(ctx context.Context) error {
+	hooks := make(map[string]*webhook)
+	err := n.buckets.Foreach(func(_, v []byte) error {
+		var b msg.Bucket
+		if err := util.DecodeMsgp(&b, v); err != nil {
+			return err
+		}
+		if b.Notification != nil && b.Notification.Webhook != "" {
+			hooks[b.Name] = &webhook{bucket: b.Name, nt: b.Notification}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var from uint64
+	active := 0
+	for _, h := range hooks {
+		consumer := webhookConsumerPrefix + h.bucket
+		if h.acked, err = n.events.GetCursor(consumer); err != nil {
+			return err
+		}
+		h.cursor = h.acked
+		if rt, ok := n.retries[consumer]; ok && time.Now().Before(rt.nextAt) {
+			h.blocked = true
+			continue
+		}
+		if active == 0 || h.cursor < from {
+			from = h.cursor
+		}
+		active++
+	}
+	if active == 0 {
+		return nil
+	}
+	cursor := from
+	for active > 0 && ctx.Err() == nil {
+		evs, _, err := n.events.List(cursor, n.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, ev := range evs {
+			h, ok := hooks[ev.Bucket]
+			if !ok || h.blocked || ev.Seq <= h.cursor {
+				continue
+			}
+			consumer := webhookConsumerPrefix + h.bucket
+			if err = n.post(ctx, h.nt, ev); err != nil && !n.giveUp(consumer, ev, err) {
+				ntLog.Warnf("deliver event %d of bucket %s err: %s", ev.Seq, ev.Bucket, err)
+				h.blocked, active = true, active-1
+				h.cursor = ev.Seq - 1
+				continue
+			}
+			delete(n.retries, consumer)
+			h.cursor = ev.Seq
+		}
+		if len(evs) > 0 {
+			cursor = evs[len(evs)-1].Seq
+		}
+		if len(evs) < n.cfg.BatchSize {
+			break
+		}
+	}
+	// events scanned have been delivered to webhooks not blocked
+	acked := make(map[string]uint64, len(hooks))
+	for _, h := range hooks {
+		if !h.blocked && cursor > h.cursor {
+			h.cursor = cursor
+		}
+		if h.cursor > h.acked {
+			acked[webhookConsumerPrefix+h.bucket] = h.cursor
+		}
+	}
+	return n.ackAll(acked)
+}
+
+// giveUp records a failed delivery and returns true if the event should be skipped
+func (n *NotificationService) giveUp(consumer string, ev *msg.Event, cause error) bool {
+	rt, ok := n.retries[consumer]
+	if !ok || rt.seq != ev.Seq {
+		rt = &webhookRetry{seq: ev.Seq}
+		n.retries[consumer] = rt
+	}
+	rt.attempts++
+	if rt.attempts >= n.cfg.MaxRetry {
+		ntLog.Errorf("skip event %d of bucket %s after %d attempts: %s", ev.Seq, ev.Bucket, rt.attempts, cause)
+		delete(n.retries, consumer)
+		return true
+	}
+	delay := n.cfg.RetryBase
+	for i := 1; i < rt.attempts && delay < n.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	rt.nextAt = time.Now().Add(util.IfElse(delay > n.cfg.RetryMax, n.cfg.RetryMax, delay))
+	return false
+}
+
+// post sends the event as json body signed by secret of notification
+func (n *NotificationService) post(ctx context.Context, nt *msg.Notification, ev *msg.Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nt.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(notification.HeaderEvent, ev.Type)
+	req.Header.Set(notification.HeaderDelivery, fmt.Sprint(n.group, "-", ev.Seq))
+	if nt.Secret != "" {
+		req.Header.Set(notification.HeaderSignature, notification.Sign(nt.Secret, time.Now(), body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responds status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StartAutoTrim removes events older than retention periodically if enabled.
+// leader decides the expired events and trims them on every server of the group through raft.
+func (n *NotificationService) StartAutoTrim() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !n.events.Enabled() {
+		return cancel
+	}
+	go func() {
+		defer graceful.Recover()
+		tk := time.NewTicker(n.cfg.TrimInterval)
+		defer tk.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				ntLog.Info("stop auto trim")
+				return
+			case <-tk.C:
+				if !n.isLeader() {
+					continue
+				}
+				if cnt, err := n.trim(time.Now().Add(-n.cfg.Retention)); err != nil {
+					ntLog.Errorf("trim events err: %s", err)
+				} else if cnt > 0 {
+					ntLog.Infof("trim %d expired events", cnt)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// trim removes events earlier than 'before'. returns the number of removed events.
+func (n *NotificationService) trim(before time.Time) (int, error) {
+	seq, err := n.events.Expired(before)
+	if err != nil || seq == 0 {
+		return 0, err
+	}
+	ok, resp, err := n.ApplyRaft(&entity.RaftData{Type: entity.LogRemove, Dest: entity.DestEventCursor, Sequence: seq})
+	if !ok {
+		return n.events.Trim(seq)
+	}
+	cnt, _ := resp.(int)
+	return cnt, err
+}
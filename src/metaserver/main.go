@@ -3,6 +3,8 @@ package main
 import (
 	"common/cmd"
 	_ "metaserver/cmd/app"
+	_ "metaserver/cmd/backup"
+	_ "metaserver/cmd/convert"
 	_ "metaserver/cmd/hashslot"
 	_ "metaserver/cmd/raft"
 	"os"
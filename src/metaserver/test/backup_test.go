@@ -0,0 +1,274 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/config"
+	"metaserver/internal/usecase/backup"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+func manifest(id string, typ backup.Type, parent string, to uint64) *backup.Manifest {
+	return &backup.Manifest{ID: id, Type: typ, Parent: parent, ToIndex: to, LastTime: time.Unix(int64(to), 0)}
+}
+
+func chainIDs(t *testing.T, manifests []*backup.Manifest, target backup.Target) string {
+	chain, err := backup.Chain(manifests, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(chain))
+	for i, m := range chain {
+		ids[i] = m.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestBackupChain(t *testing.T) {
+	manifests := []*backup.Manifest{
+		manifest("f1", backup.Full, "", 10),
+		manifest("i1", backup.Incremental, "f1", 20),
+		// i2 and i3 forked from i1 after leader changed, only i3 is continued
+		manifest("i2", backup.Incremental, "i1", 30),
+		manifest("i3", backup.Incremental, "i1", 25),
+		manifest("i4", backup.Incremental, "i3", 40),
+		manifest("f2", backup.Full, "", 50),
+		manifest("i5", backup.Incremental, "f2", 60),
+	}
+	cases := []struct {
+		target backup.Target
+		chain  string
+	}{
+		{backup.Target{}, "f2,i5"},
+		{backup.Target{Index: 45}, "f1,i1,i3,i4"},
+		// the last one may contain changes after target
+		{backup.Target{Index: 22}, "f1,i1,i2"},
+		{backup.Target{Index: 15}, "f1,i1"},
+		{backup.Target{Time: time.Unix(55, 0)}, "f2,i5"},
+	}
+	for _, c := range cases {
+		if got := chainIDs(t, manifests, c.target); got != c.chain {
+			t.Fatalf("target %+v: chain %s, want %s", c.target, got, c.chain)
+		}
+	}
+	// forks reaching the same index are chosen by id
+	forks := append(manifests[:2:2], manifest("i6", backup.Incremental, "i1", 30), manifest("i7", backup.Incremental, "i1", 30))
+	if got := chainIDs(t, forks, backup.Target{}); got != "f1,i1,i7" {
+		t.Fatalf("chain %s", got)
+	}
+	if _, err := backup.Chain(manifests, backup.Target{Index: 5}); !errors.Is(err, backup.ErrNoBackup) {
+		t.Fatalf("expect no backup, got %v", err)
+	}
+}
+
+func TestBackupLogs(t *testing.T) {
+	store := raft.NewInmemStore()
+	for i := uint64(1); i <= 5; i++ {
+		lg := &raft.Log{Index: i, Term: 1, Type: raft.LogCommand, Data: []byte(fmt.Sprint("log", i)), AppendedAt: time.Unix(int64(i), 0)}
+		if i == 3 {
+			lg.Type, lg.Data = raft.LogConfiguration, nil
+		}
+		if err := store.StoreLog(lg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	last, err := backup.WriteLogs(&buf, store, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Equal(time.Unix(5, 0)) {
+		t.Fatalf("last time %s", last)
+	}
+	data := buf.Bytes()
+	// only command logs are written
+	var got []string
+	if err = backup.ReadLogs(bytes.NewReader(data), func(lg *raft.Log) bool {
+		got = append(got, fmt.Sprintf("%d:%s", lg.Index, lg.Data))
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "2:log2,4:log4,5:log5" {
+		t.Fatalf("read logs %v", got)
+	}
+	// reading stops if fn returns false
+	var n int
+	if err = backup.ReadLogs(bytes.NewReader(data), func(*raft.Log) bool { n++; return false }); err != nil || n != 1 {
+		t.Fatalf("read %d logs, err %v", n, err)
+	}
+	// corrupted data
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-40] ^= 0xff
+	if err = backup.ReadLogs(bytes.NewReader(corrupted), func(*raft.Log) bool { return true }); err == nil {
+		t.Fatal("expect checksum error")
+	}
+	// compacted
+	if err = store.DeleteRange(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = backup.WriteLogs(io.Discard, store, 1, 5); !errors.Is(err, backup.ErrLogCompacted) {
+		t.Fatalf("expect compacted, got %v", err)
+	}
+}
+
+// verifySigV4 checks the signature of request by AWS signature version 4
+func verifySigV4(r *http.Request, cfg *config.S3Config) error {
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != 16 {
+		return fmt.Errorf("bad date %q", amzDate)
+	}
+	canonical := strings.Join([]string{r.Method, r.URL.EscapedPath(), r.URL.RawQuery,
+		"host:" + r.Host, "x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256"), "x-amz-date:" + amzDate, "",
+		"host;x-amz-content-sha256;x-amz-date", r.Header.Get("X-Amz-Content-Sha256")}, "\n")
+	scope := amzDate[:8] + "/" + cfg.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := []byte("AWS4" + cfg.SecretKey)
+	for _, s := range []string{amzDate[:8], cfg.Region, "s3", "aws4_request", toSign} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(s))
+		key = h.Sum(nil)
+	}
+	want := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		cfg.AccessKey, scope, hex.EncodeToString(key))
+	if got := r.Header.Get("Authorization"); got != want {
+		return fmt.Errorf("authorization %q, want %q", got, want)
+	}
+	return nil
+}
+
+func TestS3SigV4(t *testing.T) {
+	cfg := &config.S3Config{Bucket: "bk", Region: "us-east-1", AccessKey: "AKID", SecretKey: "secret", PathStyle: true, Prefix: "meta"}
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := verifySigV4(r, cfg); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			_, _ = w.Write([]byte(`<ListBucketResult><IsTruncated>false</IsTruncated><Contents><Key>meta/g1/a b.json</Key></Contents></ListBucketResult>`))
+		}
+	}))
+	defer srv.Close()
+	cfg.Endpoint = srv.URL
+	dest := backup.NewS3Destination(cfg)
+	ctx := context.Background()
+	// keys with characters to escape
+	if err := dest.Put(ctx, "g1/a b+c.json", strings.NewReader("{}"), 2); err != nil {
+		t.Fatal(err)
+	}
+	names, err := dest.List(ctx, "g1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "g1/a b.json" {
+		t.Fatalf("list %v", names)
+	}
+	if err = dest.Delete(ctx, "g1/a b+c.json"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 || requests[0] != "PUT /bk/meta/g1/a%20b%2Bc.json?" {
+		t.Fatalf("requests %v", requests)
+	}
+}
+
+// multipartServer is a fake S3 which assembles multipart uploads, uploading part failPart fails
+type multipartServer struct {
+	cfg      *config.S3Config
+	failPart int
+	parts    map[int][]byte
+	object   []byte
+	aborted  bool
+}
+
+func (m *multipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySigV4(r, m.cfg); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>u1</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && query.Get("uploadId") == "u1":
+		num, _ := strconv.Atoi(query.Get("partNumber"))
+		if num == m.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		m.parts[num], _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", fmt.Sprint(`"etag-`, num, `"`))
+	case r.Method == http.MethodPost && query.Get("uploadId") == "u1":
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for i, p := range complete.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprint(`"etag-`, i+1, `"`) {
+				_, _ = w.Write([]byte(`<Error><Code>InvalidPart</Code></Error>`))
+				return
+			}
+			m.object = append(m.object, m.parts[p.PartNumber]...)
+		}
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "u1":
+		m.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestS3Multipart(t *testing.T) {
+	// parts smaller than 5MB are enlarged
+	cfg := &config.S3Config{Bucket: "bk", Region: "us-east-1", AccessKey: "AKID", SecretKey: "secret", PathStyle: true, PartSize: 1 << 20}
+	data := make([]byte, 11<<20+1)
+	_, _ = rand.Read(data)
+	server := &multipartServer{cfg: cfg, parts: map[int][]byte{}}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	cfg.Endpoint = srv.URL
+	dest := backup.NewS3Destination(cfg)
+	if err := dest.Put(context.Background(), "g1/full.snap", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.parts) != 3 || len(server.parts[1]) != 5<<20 || !bytes.Equal(server.object, data) || server.aborted {
+		t.Fatalf("%d parts uploaded, object of %d bytes, aborted %v", len(server.parts), len(server.object), server.aborted)
+	}
+
+	// a failed upload is aborted
+	server = &multipartServer{cfg: cfg, parts: map[int][]byte{}, failPart: 2}
+	srv2 := httptest.NewServer(server)
+	defer srv2.Close()
+	cfg.Endpoint = srv2.URL
+	if err := dest.Put(context.Background(), "g1/full.snap", bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("upload should fail")
+	}
+	if !server.aborted || server.object != nil {
+		t.Fatalf("failed upload is not aborted")
+	}
+}
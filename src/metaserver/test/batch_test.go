@@ -0,0 +1,91 @@
+package test
+
+import (
+	"common/proto/msg"
+	"errors"
+	"fmt"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"metaserver/internal/usecase/raftimpl"
+	"metaserver/internal/usecase/repo"
+	"metaserver/internal/usecase/service"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func hashes(vers []*msg.Version) []string {
+	res := make([]string, len(vers))
+	for i, v := range vers {
+		res[i] = v.Hash
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestBatch(t *testing.T) {
+	storage := openStorage(t, kv.EngineBolt, filepath.Join(t.TempDir(), "batch.db"))
+	svc := service.NewMetadataService(repo.NewMetadataRepo(storage, nopCache{}), repo.NewBatchRepo(storage),
+		repo.NewHashIndexRepo(storage), repo.NewOpsRepo(storage, nopCache{}, nil), repo.NewChangeFeedRepo(storage, true),
+		repo.NewEventRepo(storage, false), &raftimpl.RaftWrapper{})
+	putMeta := &msg.Change{Op: msg.ChangePutMetadata, Id: "b/a", Metadata: &msg.Metadata{Name: "a", Bucket: "b"}}
+	putVer := func(hash string) *msg.Change {
+		return &msg.Change{Op: msg.ChangePutVersion, Id: "b/a", Version: &msg.Version{Hash: hash}}
+	}
+	res, err := svc.Batch([]*msg.Change{putMeta, putVer("h1"), putVer("h2"), putVer("h3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(res.Versions) != "[0 1 2 3]" || len(res.Removed) != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	var created msg.Metadata
+	if err = storage.View(logic.GetMeta("b/a", &created)); err != nil {
+		t.Fatal(err)
+	}
+
+	// putting existing metadata keeps its create time even if put twice, removed versions are returned to be dereferenced
+	time.Sleep(5 * time.Millisecond)
+	putMeta.Metadata = &msg.Metadata{Name: "a", Bucket: "b"}
+	putAgain := &msg.Change{Op: msg.ChangePutMetadata, Id: "b/a", Metadata: &msg.Metadata{Name: "a", Bucket: "b"}}
+	res, err = svc.Batch([]*msg.Change{putMeta, {Op: msg.ChangeRemoveVersion, Id: "b/a", Sequence: 1}, putAgain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hashes(res.Removed); fmt.Sprint(got) != "[h1]" {
+		t.Fatalf("removed %v", got)
+	}
+	var updated msg.Metadata
+	if err = storage.View(logic.GetMeta("b/a", &updated)); err != nil {
+		t.Fatal(err)
+	}
+	if updated.CreateTime != created.CreateTime || updated.UpdateTime <= created.UpdateTime {
+		t.Fatalf("created at %d, updated %+v", created.CreateTime, updated)
+	}
+	changes, _, err := repo.NewChangeFeedRepo(storage, true).List(4, 3)
+	if err != nil || len(changes) != 3 || changes[2].Metadata.CreateTime != created.CreateTime {
+		t.Fatalf("change of putting metadata %+v, err %v", changes, err)
+	}
+
+	// a failed op rolls back the batch and nothing is removed
+	_, err = svc.Batch([]*msg.Change{{Op: msg.ChangeRemoveVersion, Id: "b/a", Sequence: 2}, {Op: msg.ChangeRemoveVersion, Id: "b/a", Sequence: 1}})
+	if err == nil {
+		t.Fatal("removing a missing version should fail")
+	}
+	if err = storage.View(logic.GetVer("b/a", 2, &msg.Version{})); err != nil {
+		t.Fatalf("version 2 should not be removed: %v", err)
+	}
+
+	res, err = svc.Batch([]*msg.Change{{Op: msg.ChangeRemoveMetadata, Id: "b/a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hashes(res.Removed); fmt.Sprint(got) != "[h2 h3]" {
+		t.Fatalf("removed %v", got)
+	}
+	if err = storage.View(logic.GetMeta("b/a", &msg.Metadata{})); !errors.Is(err, usecase.ErrNotFound) {
+		t.Fatalf("metadata should be removed: %v", err)
+	}
+}
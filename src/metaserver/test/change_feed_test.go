@@ -0,0 +1,59 @@
+package test
+
+import (
+	"common/proto/msg"
+	"errors"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"metaserver/internal/usecase/repo"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nopCache ignores invalidating
+type nopCache struct {
+	usecase.IMetaCache
+}
+
+func (nopCache) RemoveMetadata(string) error        { return nil }
+func (nopCache) RemoveVersion(string, uint64) error { return nil }
+
+func TestChangeFeedAppendedInApplyingTx(t *testing.T) {
+	storage := openStorage(t, kv.EngineBolt, filepath.Join(t.TempDir(), "feed.db"))
+	feed := repo.NewChangeFeedRepo(storage, true)
+	ops := repo.NewOpsRepo(storage, nopCache{}, nil)
+	put := func(name string, record usecase.TxFunc) error {
+		return ops.Apply(&entity.RaftData{Type: entity.LogInsert, Dest: entity.DestMetadata, Name: "b/" + name,
+			Metadata: &msg.Metadata{Name: name, Bucket: "b"}}, record)
+	}
+	appendChange := func(name string) usecase.TxFunc {
+		change := feed.Prepare(&entity.RaftData{Type: entity.LogInsert, Dest: entity.DestMetadata, Name: "b/" + name,
+			Metadata: &msg.Metadata{Name: name, Bucket: "b"}}, time.Now())
+		return func(tx kv.Tx) error { return feed.Append(tx, change) }
+	}
+	if err := put("a", appendChange("a")); err != nil {
+		t.Fatal(err)
+	}
+	// a failed append rolls back the mutation
+	errAppend := errors.New("append failed")
+	if err := put("b", func(tx kv.Tx) error { return errAppend }); !errors.Is(err, errAppend) {
+		t.Fatalf("expect append error, got %v", err)
+	}
+	if err := storage.View(logic.GetMeta("b/b", &msg.Metadata{})); !errors.Is(err, usecase.ErrNotFound) {
+		t.Fatalf("metadata should not be saved, got %v", err)
+	}
+	// a failed mutation appends nothing
+	if err := put("a", appendChange("a")); !errors.Is(err, usecase.ErrExists) {
+		t.Fatalf("expect exists, got %v", err)
+	}
+	changes, head, err := feed.List(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 1 || len(changes) != 1 || changes[0].Id != "b/a" {
+		t.Fatalf("unexpected feed %d %+v", head, changes)
+	}
+}
@@ -0,0 +1,248 @@
+package test
+
+import (
+	"common/proto/msg"
+	"errors"
+	"fmt"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// engineCases runs fn against a new database of every engine
+func engineCases(t *testing.T, fn func(t *testing.T, engine kv.Engine)) {
+	for _, name := range []string{kv.EngineBolt, kv.EngineBadger} {
+		t.Run(name, func(t *testing.T) {
+			engine, err := kv.Open(name, filepath.Join(t.TempDir(), "engine"+kv.Ext(name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer engine.Close()
+			fn(t, engine)
+		})
+	}
+}
+
+// walk returns keys visited by cursor moves, "-" for a nil key, values of sub-buckets are shown as "[]".
+// moves are 'f'irst, 'l'ast, 's'eek, 'n'ext and 'p'rev.
+func walk(c kv.Cursor, seek, moves string) string {
+	var res []string
+	for _, m := range moves {
+		var k, v []byte
+		switch m {
+		case 'f':
+			k, v = c.First()
+		case 'l':
+			k, v = c.Last()
+		case 's':
+			k, v = c.Seek([]byte(seek))
+		case 'n':
+			k, v = c.Next()
+		case 'p':
+			k, v = c.Prev()
+		}
+		switch {
+		case k == nil:
+			res = append(res, "-")
+		case v == nil:
+			res = append(res, string(k)+"[]")
+		default:
+			res = append(res, string(k)+"="+string(v))
+		}
+	}
+	return strings.Join(res, ",")
+}
+
+func TestEngineConformance(t *testing.T) {
+	engineCases(t, func(t *testing.T, engine kv.Engine) {
+		err := engine.Update(func(tx kv.Tx) error {
+			b, err := tx.CreateBucket([]byte("b"))
+			if err != nil {
+				return err
+			}
+			for _, k := range []string{"a", "c", "e"} {
+				if err = b.Put([]byte(k), []byte(strings.ToUpper(k))); err != nil {
+					return err
+				}
+			}
+			sub, err := b.CreateBucket([]byte("d"))
+			if err != nil {
+				return err
+			}
+			return sub.Put([]byte("x"), []byte("X"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = engine.Update(func(tx kv.Tx) error {
+			b := tx.Bucket([]byte("b"))
+			if _, err := tx.CreateBucket([]byte("b")); !errors.Is(err, kv.ErrBucketExists) {
+				return fmt.Errorf("create existing bucket: %v", err)
+			}
+			if err := b.Put([]byte("d"), []byte("D")); !errors.Is(err, kv.ErrIncompatibleValue) {
+				return fmt.Errorf("put to sub-bucket: %v", err)
+			}
+			if _, err := b.CreateBucket([]byte("a")); !errors.Is(err, kv.ErrIncompatibleValue) {
+				return fmt.Errorf("create bucket on key: %v", err)
+			}
+			if err := b.DeleteBucket([]byte("z")); !errors.Is(err, kv.ErrBucketNotFound) {
+				return fmt.Errorf("delete missing bucket: %v", err)
+			}
+			if b.Get([]byte("d")) != nil || b.Get([]byte("z")) != nil || string(b.Get([]byte("c"))) != "C" {
+				return errors.New("unexpected get")
+			}
+			if b.Bucket([]byte("a")) != nil || b.Bucket([]byte("d")) == nil {
+				return errors.New("unexpected sub-bucket")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// cursors iterate in order and change direction at any position
+		cases := []struct{ seek, moves, want string }{
+			{"", "fnnnn", "a=A,c=C,d[],e=E,-"},
+			{"", "lpppp", "e=E,d[],c=C,a=A,-"},
+			{"", "fnnpp", "a=A,c=C,d[],c=C,a=A"},
+			{"", "lppnn", "e=E,d[],c=C,d[],e=E"},
+			{"b", "snpn", "c=C,d[],c=C,d[]"},
+			{"d", "spn", "d[],c=C,d[]"},
+			{"f", "s", "-"},
+		}
+		err = engine.View(func(tx kv.Tx) error {
+			b := tx.Bucket([]byte("b"))
+			for _, c := range cases {
+				if got := walk(b.Cursor(), c.seek, c.moves); got != c.want {
+					return fmt.Errorf("seek %q moves %s: got %s, want %s", c.seek, c.moves, got, c.want)
+				}
+			}
+			if b.KeyN() != 3 {
+				return fmt.Errorf("key number %d", b.KeyN())
+			}
+			if err := b.Put([]byte("f"), nil); !errors.Is(err, kv.ErrTxNotWritable) {
+				return fmt.Errorf("put in read-only tx: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a failed tx is rolled back
+		errAbort := errors.New("abort")
+		err = engine.Update(func(tx kv.Tx) error {
+			if err := tx.Bucket([]byte("b")).Put([]byte("a"), []byte("changed")); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("expect abort, got %v", err)
+		}
+
+		// sub-buckets are dropped with their keys
+		err = engine.Update(func(tx kv.Tx) error {
+			b := tx.Bucket([]byte("b"))
+			if err := b.DeleteBucket([]byte("d")); err != nil {
+				return err
+			}
+			sub, err := b.CreateBucket([]byte("d"))
+			if err != nil {
+				return err
+			}
+			if sub.Get([]byte("x")) != nil || sub.Sequence() != 0 {
+				return errors.New("sub-bucket is not dropped")
+			}
+			return b.Delete([]byte("e"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rtx, err := engine.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := walk(rtx.Bucket([]byte("b")).Cursor(), "", "fnnn"); got != "a=A,c=C,d[],-" {
+			t.Fatalf("after update: %s", got)
+		}
+		_ = rtx.Rollback()
+	})
+}
+
+func TestEngineSequenceConflicts(t *testing.T) {
+	engineCases(t, func(t *testing.T, engine kv.Engine) {
+		if err := engine.Update(func(tx kv.Tx) error {
+			_, err := tx.CreateBucket([]byte("seq"))
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// concurrent txs allocate distinct sequences, outputs of retried txs are reset
+		const n = 10
+		var wg sync.WaitGroup
+		seqs := make([]uint64, n)
+		errs := make([]error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = engine.Update(func(tx kv.Tx) (err error) {
+					seqs[i], err = tx.Bucket([]byte("seq")).NextSequence()
+					return
+				})
+			}(i)
+		}
+		wg.Wait()
+		seen := make(map[uint64]bool)
+		for i, seq := range seqs {
+			if errs[i] != nil {
+				t.Fatal(errs[i])
+			}
+			if seen[seq] || seq == 0 || seq > n {
+				t.Fatalf("duplicated or invalid sequence %d", seq)
+			}
+			seen[seq] = true
+		}
+	})
+}
+
+func TestTrimVersions(t *testing.T) {
+	engineCases(t, func(t *testing.T, engine kv.Engine) {
+		if err := engine.Update(logic.AddMeta("b/x", &msg.Metadata{})); err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 5; i++ {
+			ver := &msg.Version{Hash: fmt.Sprint(i), Size: int64(i), Ts: int64(i), UniqueId: logic.GenerateUniqueId()}
+			if err := engine.Update(logic.AddVer("b/x", ver)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// versions are trimmed by batches until at most a batch is left
+		var trimmed []string
+		for removed := []*msg.Version{nil}; len(removed) > 0; {
+			if err := engine.Update(logic.TrimVersions("b/x", 2, &removed)); err != nil {
+				t.Fatal(err)
+			}
+			trimmed = append(trimmed, fmt.Sprint(hashes(removed)))
+		}
+		if fmt.Sprint(trimmed) != "[[1 2] [3 4] []]" {
+			t.Fatalf("trimmed %v", trimmed)
+		}
+		if got := queryKeys(t, engine, msg.Query{Bucket: "b", Sort: msg.SortBySize, Limit: 10}); got != key("x", 5) {
+			t.Fatalf("indexed versions %s", got)
+		}
+		var seq uint64
+		if err := engine.View(func(tx kv.Tx) error {
+			seq = logic.GetVersionBucket(tx, "b/x").Sequence()
+			return nil
+		}); err != nil || seq != 5 {
+			t.Fatalf("sequence %d, err %v", seq, err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"common/proto/msg"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashRefCounting(t *testing.T) {
+	engine, err := kv.OpenBolt(filepath.Join(t.TempDir(), "ref.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	h := logic.NewHashRefLogic()
+	// refer a missing hash without locates
+	if err = engine.Update(h.Refer(&msg.HashRef{Hash: "a"})); !usecase.IsNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+	// backfill a legacy hash held by 2 versions then refer it by a new one
+	if err = engine.Update(h.Refer(&msg.HashRef{Hash: "a", Locate: []string{"s1"}, Count: 2})); err != nil {
+		t.Fatal(err)
+	}
+	ref := &msg.HashRef{Hash: "a"}
+	if err = engine.Update(h.Refer(ref)); err != nil {
+		t.Fatal(err)
+	}
+	if ref.Count != 3 || len(ref.Locate) != 1 || ref.Locate[0] != "s1" {
+		t.Fatalf("unexpected ref %+v", ref)
+	}
+	for i := 2; i >= 0; i-- {
+		var res msg.HashRef
+		if err = engine.Update(h.Derefer("a", &res)); err != nil {
+			t.Fatal(err)
+		}
+		if res.Count != int64(i) {
+			t.Fatalf("expect count %d, got %d", i, res.Count)
+		}
+	}
+	if err = engine.View(h.Get("a", &msg.HashRef{})); !usecase.IsNotFound(err) {
+		t.Fatalf("ref should be removed at zero, got %v", err)
+	}
+	// a new ref defaults to one
+	ref = &msg.HashRef{Hash: "b", Locate: []string{"s2"}}
+	if err = engine.Update(h.Refer(ref)); err != nil || ref.Count != 1 {
+		t.Fatalf("unexpected ref %+v err %v", ref, err)
+	}
+}
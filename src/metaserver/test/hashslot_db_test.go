@@ -0,0 +1,180 @@
+package test
+
+import (
+	"common/hashslot"
+	"context"
+	"errors"
+	"fmt"
+	"metaserver/internal/entity"
+	"metaserver/internal/usecase/db"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// memKV is an in-memory clientv3.KV supports Put, Get and Delete of a key or a prefix
+type memKV struct {
+	clientv3.KV
+	mux  sync.Mutex
+	data map[string]string
+	fail bool
+}
+
+func newMemKV() *memKV {
+	return &memKV{data: map[string]string{}}
+}
+
+func (m *memKV) match(op clientv3.Op, k string) bool {
+	if op.RangeBytes() == nil {
+		return k == string(op.KeyBytes())
+	}
+	return strings.HasPrefix(k, string(op.KeyBytes()))
+}
+
+func (m *memKV) Put(_ context.Context, key, val string, _ ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.fail {
+		return nil, errors.New("etcd unavailable")
+	}
+	m.data[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (m *memKV) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	op := clientv3.OpGet(key, opts...)
+	resp := &clientv3.GetResponse{}
+	for k, v := range m.data {
+		if m.match(op, k) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+	return resp, nil
+}
+
+func (m *memKV) Delete(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	op := clientv3.OpDelete(key, opts...)
+	for k := range m.data {
+		if m.match(op, k) {
+			delete(m.data, k)
+		}
+	}
+	return &clientv3.DeleteResponse{}, nil
+}
+
+// slotOf returns the slot range contains only the key
+func slotOf(key string) string {
+	slot := hashslot.CalcBytesSlot([]byte(key))
+	return fmt.Sprint(slot, "-", slot+1)
+}
+
+func TestHashSlotDirtyTracking(t *testing.T) {
+	etcd := newMemKV()
+	store := db.NewHashSlotDB("/slots/", "/checkpoint/", etcd)
+	const key, other = "bucket/a", "bucket/b"
+	if hashslot.CalcBytesSlot([]byte(key)) == hashslot.CalcBytesSlot([]byte(other)) {
+		t.Fatal("keys in the same slot")
+	}
+	if err := store.TrackMigrating("s1", []string{slotOf(key)}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsKeyMigrating(key) || store.IsKeyMigrating(other) {
+		t.Fatal("wrong migrating keys")
+	}
+	// dirty mark is persisted before writing
+	release, err := store.AcquireWrite(key, entity.DestMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirty, err := db.NewHashSlotDB("/slots/", "/checkpoint/", etcd).LoadDirty("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirty[key] != entity.DestMetadata {
+		t.Fatalf("dirty mark not persisted before writing: %v", dirty)
+	}
+	release(true)
+	// writes of other dest of the same key are all persisted
+	release, err = store.AcquireWrites(map[string]entity.Dest{key: entity.DestHashRef, other: entity.DestHashRef})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(true)
+	if dirty = store.TakeDirty(); len(dirty) != 1 || dirty[key] != entity.DestMetadata|entity.DestHashRef {
+		t.Fatalf("unexpected dirty keys %v", dirty)
+	}
+	// a restarted leader loads all marks
+	if dirty, err = db.NewHashSlotDB("/slots/", "/checkpoint/", etcd).LoadDirty("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 1 || dirty[key] != entity.DestMetadata|entity.DestHashRef {
+		t.Fatalf("unexpected persisted marks %v", dirty)
+	}
+	// a write fails if its mark could not be persisted
+	etcd.fail = true
+	if _, err = store.AcquireWrite(key, entity.DestBucket); err == nil {
+		t.Fatal("write without persisted mark")
+	}
+	// the mark persisted once is not saved again
+	if release, err = store.AcquireWrite(key, entity.DestMetadata); err != nil {
+		t.Fatal(err)
+	}
+	release(true)
+	etcd.fail = false
+	if err = store.RemoveCheckpoint("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(etcd.data) != 0 {
+		t.Fatalf("persisted marks not removed: %v", etcd.data)
+	}
+}
+
+func TestHashSlotFreeze(t *testing.T) {
+	store := db.NewHashSlotDB("/slots/", "/checkpoint/", newMemKV())
+	const key, other = "bucket/a", "bucket/b"
+	if err := store.TrackMigrating("s1", []string{slotOf(key)}); err != nil {
+		t.Fatal(err)
+	}
+	release, err := store.AcquireWrite(key, entity.DestMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// freezing waits for the write in-flight
+	frozen := make(chan struct{})
+	go func() {
+		store.Freeze()
+		close(frozen)
+	}()
+	release(true)
+	<-frozen
+	if _, err = store.AcquireWrite(key, entity.DestMetadata); !errors.Is(err, db.ErrSlotFrozen) {
+		t.Fatalf("expect frozen, but %v", err)
+	}
+	// keys of other slots are writable
+	if release, err = store.AcquireWrite(other, entity.DestMetadata); err != nil {
+		t.Fatal(err)
+	}
+	release(true)
+	if dirty := store.TakeDirty(); len(dirty) != 1 {
+		t.Fatalf("unexpected dirty keys %v", dirty)
+	}
+	// a new migration starts unfrozen
+	store.StopTracking()
+	if err = store.TrackMigrating("s1", []string{slotOf(key)}); err != nil {
+		t.Fatal(err)
+	}
+	if release, err = store.AcquireWrite(key, entity.DestMetadata); err != nil {
+		t.Fatal(err)
+	}
+	release(false)
+	if dirty := store.TakeDirty(); len(dirty) != 0 {
+		t.Fatalf("failed write marked dirty %v", dirty)
+	}
+}
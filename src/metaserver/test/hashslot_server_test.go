@@ -0,0 +1,85 @@
+package test
+
+import (
+	"common/proto/pb"
+	"errors"
+	"io"
+	"metaserver/internal/controller/grpc"
+	"metaserver/internal/usecase"
+	"testing"
+
+	rpc "google.golang.org/grpc"
+)
+
+type fakeHashSlotService struct {
+	usecase.IHashSlotService
+	finishOnClose bool
+	received      int
+	finished      []bool
+}
+
+func (f *fakeHashSlotService) ReceiveItem(*pb.MigrationItem) error {
+	f.received++
+	return nil
+}
+
+func (f *fakeHashSlotService) FinishReceiveItem(success bool) error {
+	f.finished = append(f.finished, success)
+	return nil
+}
+
+func (f *fakeHashSlotService) FinishOnClose() bool {
+	return f.finishOnClose
+}
+
+// fakeReceiveStream receives items and then ends with err
+type fakeReceiveStream struct {
+	rpc.ServerStream
+	items []*pb.MigrationItem
+	err   error
+}
+
+func (f *fakeReceiveStream) Recv() (*pb.MigrationItem, error) {
+	if len(f.items) == 0 {
+		return nil, f.err
+	}
+	item := f.items[0]
+	f.items = f.items[1:]
+	return item, nil
+}
+
+func (f *fakeReceiveStream) Send(*pb.Response) error {
+	return nil
+}
+
+func TestStreamingReceiveFinish(t *testing.T) {
+	broken := errors.New("broken stream")
+	cases := []struct {
+		finishOnClose bool
+		err           error
+		finished      []bool
+	}{
+		// source of old version finishes migration by closing stream
+		{finishOnClose: true, err: io.EOF, finished: []bool{true}},
+		{finishOnClose: true, err: broken, finished: []bool{false}},
+		// otherwise it's finished by rpc
+		{finishOnClose: false, err: io.EOF},
+		{finishOnClose: false, err: broken},
+	}
+	for i, c := range cases {
+		serv := &fakeHashSlotService{finishOnClose: c.finishOnClose}
+		stream := &fakeReceiveStream{items: []*pb.MigrationItem{{Name: "a"}, {Name: "b"}}, err: c.err}
+		err := grpc.NewHashSlotServer(serv).StreamingReceive(stream)
+		if (err != nil) != (c.err != io.EOF) {
+			t.Fatalf("case %d: unexpected err %v", i, err)
+		}
+		if serv.received != 2 || len(serv.finished) != len(c.finished) {
+			t.Fatalf("case %d: received %d, finished %v", i, serv.received, serv.finished)
+		}
+		for j := range c.finished {
+			if serv.finished[j] != c.finished[j] {
+				t.Fatalf("case %d: finished %v", i, serv.finished)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"common/notification"
+	"common/proto/msg"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"metaserver/config"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"metaserver/internal/usecase/raftimpl"
+	"metaserver/internal/usecase/repo"
+	"metaserver/internal/usecase/service"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func createBuckets(t *testing.T, storage *db.Storage, buckets ...*msg.Bucket) {
+	crud := logic.NewBucketCrud()
+	for _, b := range buckets {
+		if err := storage.Update(crud.Create(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// publish saves events of changes put to objects of bucket
+func publish(t *testing.T, storage *db.Storage, events *repo.EventRepo, ts int64, ids ...string) {
+	changes := make([]*msg.Change, len(ids))
+	for i, id := range ids {
+		changes[i] = &msg.Change{Ts: ts, Op: msg.ChangePutVersion, Id: id, Version: &msg.Version{Sequence: 1}}
+	}
+	if err := storage.Update(func(tx kv.Tx) error { return events.Publish(tx, changes...) }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventIds(t *testing.T, events *repo.EventRepo) string {
+	evs, _, err := events.List(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(evs))
+	for i, ev := range evs {
+		ids[i] = fmt.Sprint(ev.Seq, ":", ev.Bucket, "/", ev.Name)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestEventPublishAndTrim(t *testing.T) {
+	storage := openStorage(t, kv.EngineBolt, filepath.Join(t.TempDir(), "event.db"))
+	events := repo.NewEventRepo(storage, true)
+	createBuckets(t, storage,
+		&msg.Bucket{Name: "all", Notification: &msg.Notification{}},
+		&msg.Bucket{Name: "removed", Notification: &msg.Notification{Events: []string{msg.EventObjectRemoved}}},
+		&msg.Bucket{Name: "none"})
+	publish(t, storage, events, 1, "all/a", "removed/b", "none/c", "all/d")
+	publish(t, storage, events, 2, "all/e")
+	if got := eventIds(t, events); got != "1:all/a,2:all/d,3:all/e" {
+		t.Fatalf("events %s", got)
+	}
+	// events are rolled back with the transaction
+	errAbort := errors.New("abort")
+	err := storage.Update(func(tx kv.Tx) error {
+		if err := events.Publish(tx, &msg.Change{Ts: 3, Op: msg.ChangePutVersion, Id: "all/f"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) || eventIds(t, events) != "1:all/a,2:all/d,3:all/e" {
+		t.Fatalf("events are not rolled back: %v", err)
+	}
+	seq, err := events.Expired(time.UnixMilli(2))
+	if err != nil || seq != 2 {
+		t.Fatalf("expired until %d, err %v", seq, err)
+	}
+	if n, err := events.Trim(seq); err != nil || n != 2 {
+		t.Fatalf("trim %d, err %v", n, err)
+	}
+	if got := eventIds(t, events); got != "3:all/e" {
+		t.Fatalf("events after trim %s", got)
+	}
+	// cursors never move backward
+	if err = events.SetCursors(map[string]uint64{"x": 3, "y": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = events.SetCursors(map[string]uint64{"x": 1, "y": 3}); err != nil {
+		t.Fatal(err)
+	}
+	for consumer, want := range map[string]uint64{"x": 3, "y": 3} {
+		if got, _ := events.GetCursor(consumer); got != want {
+			t.Fatalf("cursor of %s is %d, want %d", consumer, got, want)
+		}
+	}
+}
+
+// hookServer records names of received events, the first 'fails' requests fail
+type hookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	fails    int
+	received []string
+}
+
+func newHookServer(fails int) *hookServer {
+	h := &hookServer{fails: fails}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.fails > 0 {
+			h.fails--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var ev msg.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil || r.Header.Get(notification.HeaderEvent) != ev.Type {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.received = append(h.received, ev.Name)
+		w.WriteHeader(http.StatusOK)
+	}))
+	return h
+}
+
+func (h *hookServer) names() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return strings.Join(h.received, ",")
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	storage := openStorage(t, kv.EngineBolt, filepath.Join(t.TempDir(), "webhook.db"))
+	events := repo.NewEventRepo(storage, true)
+	ok, failing := newHookServer(0), newHookServer(2)
+	defer ok.Close()
+	defer failing.Close()
+	createBuckets(t, storage,
+		&msg.Bucket{Name: "ok", Notification: &msg.Notification{Webhook: ok.URL}},
+		&msg.Bucket{Name: "failing", Notification: &msg.Notification{Webhook: failing.URL}},
+		&msg.Bucket{Name: "quiet", Notification: &msg.Notification{Webhook: ok.URL}})
+	publish(t, storage, events, time.Now().UnixMilli(), "ok/a", "failing/b", "ok/c", "failing/d", "ok/e")
+
+	cfg := &config.Config{}
+	cfg.Notification = config.NotificationConfig{Enable: true, PollInterval: 10 * time.Millisecond, BatchSize: 2,
+		WebhookTimeout: time.Second, MaxRetry: 5, RetryBase: 10 * time.Millisecond, RetryMax: 10 * time.Millisecond}
+	svc := service.NewNotificationService(events, repo.NewBucketRepo(storage, nil), &raftimpl.RaftWrapper{}, cfg)
+	stop := svc.StartDispatch()
+	defer stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for failing.names() != "b,d" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// a failed event delays later ones of its bucket only
+	if ok.names() != "a,c,e" || failing.names() != "b,d" {
+		t.Fatalf("delivered %s and %s", ok.names(), failing.names())
+	}
+	time.Sleep(50 * time.Millisecond)
+	// cursors of all webhooks including the quiet one are moved to the end of queue
+	for _, b := range []string{"ok", "failing", "quiet"} {
+		if seq, _ := events.GetCursor("webhook/" + b); seq != 5 {
+			t.Fatalf("cursor of %s is %d", b, seq)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"common/proto/msg"
+	"common/proto/pb"
+	"common/util"
+	"context"
+	"fmt"
+	"metaserver/internal/controller/grpc"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// queryKeys returns keys of all pages of query, pages are joined by '|'
+func queryKeys(t *testing.T, engine kv.Engine, q msg.Query) string {
+	var pages []string
+	for {
+		var keys []string
+		var vers []*msg.Version
+		if err := engine.View(logic.QueryVer(&q, &keys, &vers)); err != nil {
+			t.Fatal(err)
+		}
+		for i, k := range keys {
+			keys[i] = strings.TrimPrefix(k, q.Bucket+"/")
+		}
+		pages = append(pages, strings.Join(keys, ","))
+		if len(keys) < q.Limit {
+			return strings.Join(pages, "|")
+		}
+		q.Cursor = q.CursorOf(q.Bucket+"/"+keys[len(keys)-1], vers[len(vers)-1]).String()
+	}
+}
+
+// key is the query key of version seq of object in bucket b
+func key(name string, seq uint64) string {
+	return strings.TrimPrefix(msg.QueryKey("b/"+name, seq), "b/")
+}
+
+func TestQueryVersions(t *testing.T) {
+	engine, err := kv.OpenBolt(filepath.Join(t.TempDir(), "query.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	// versions 1..10 of 'x' with size 10*seq and ts seq, and versions of other objects and buckets
+	for _, id := range []string{"b/x", "b/y", "other/x"} {
+		if err = engine.Update(logic.AddMeta(id, &msg.Metadata{})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		ver := &msg.Version{Hash: fmt.Sprint("x", i), Size: int64(i * 10), Ts: int64(i), UniqueId: logic.GenerateUniqueId(),
+			ContentType: "text/plain", Tags: map[string]string{"odd": fmt.Sprint(i%2 == 1)}}
+		if err = engine.Update(logic.AddVer("b/x", ver)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"b/y", "other/x"} {
+		ver := &msg.Version{Hash: id, Size: 55, Ts: 5, UniqueId: logic.GenerateUniqueId(), ContentType: "image/png"}
+		if err = engine.Update(logic.AddVer(id, ver)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	x := func(seqs ...uint64) string {
+		keys := make([]string, len(seqs))
+		for i, s := range seqs {
+			keys[i] = key("x", s)
+		}
+		return strings.Join(keys, ",")
+	}
+	cases := []struct {
+		name string
+		q    msg.Query
+		want string
+	}{
+		// sequence 10 is after 9 by name
+		{"name", msg.Query{Limit: 20}, x(1, 2, 3, 4, 5, 6, 7, 8, 9, 10) + "," + key("y", 1)},
+		{"name pages", msg.Query{Limit: 4}, x(1, 2, 3, 4) + "|" + x(5, 6, 7, 8) + "|" + x(9, 10) + "," + key("y", 1)},
+		{"name desc pages", msg.Query{Limit: 5, Desc: true}, key("y", 1) + "," + x(10, 9, 8, 7) + "|" + x(6, 5, 4, 3, 2) + "|" + x(1)},
+		// size range is inclusive
+		{"size", msg.Query{Sort: msg.SortBySize, MinSize: 30, MaxSize: 60, Limit: 2}, x(3, 4) + "|" + x(5) + "," + key("y", 1) + "|" + x(6)},
+		{"size desc", msg.Query{Sort: msg.SortBySize, MinSize: 30, MaxSize: 60, Limit: 3, Desc: true}, x(6) + "," + key("y", 1) + "," + x(5) + "|" + x(4, 3)},
+		{"size unbounded", msg.Query{Sort: msg.SortBySize, MinSize: 95, Limit: 5}, x(10)},
+		// ts range is [after, before)
+		{"ts", msg.Query{Sort: msg.SortByTs, After: 8, Before: 10, Limit: 5}, x(8, 9)},
+		{"ts desc", msg.Query{Sort: msg.SortByTs, Before: 3, Limit: 1, Desc: true}, x(2) + "|" + x(1) + "|"},
+		{"content type", msg.Query{ContentType: "image/png", Limit: 5}, key("y", 1)},
+		{"tags", msg.Query{Tags: []string{"odd=true"}, Sort: msg.SortBySize, MaxSize: 50, Limit: 5}, x(1, 3, 5)},
+	}
+	for _, c := range cases {
+		c.q.Bucket = "b"
+		if got := queryKeys(t, engine, c.q); got != c.want {
+			t.Fatalf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+
+	// content type and tags can be updated
+	ver := &msg.Version{Sequence: 1, Size: 10, Ts: 100, ContentType: "image/png", Tags: map[string]string{"odd": "no"}}
+	if err = engine.Update(logic.UpdateVer("b/x", ver)); err != nil {
+		t.Fatal(err)
+	}
+	if got := queryKeys(t, engine, msg.Query{Bucket: "b", Tags: []string{"odd=no"}, ContentType: "image/png", Limit: 5}); got != x(1) {
+		t.Fatalf("updated version is not indexed: %s", got)
+	}
+	if got := queryKeys(t, engine, msg.Query{Bucket: "b", Tags: []string{"odd=true"}, Limit: 5}); got != x(3, 5, 7, 9) {
+		t.Fatalf("old tags are not removed: %s", got)
+	}
+
+	// rebuilding in small transactions produces the same indexes
+	before := queryKeys(t, engine, msg.Query{Bucket: "b", Sort: msg.SortByTs, Limit: 20})
+	if err = engine.Update(logic.DropSecondaryIndexes()); err != nil {
+		t.Fatal(err)
+	}
+	var built bool
+	if err = engine.View(logic.SecondaryIndexBuilt(&built)); err != nil || built {
+		t.Fatalf("indexes should be dropped, err %v", err)
+	}
+	cursor, txs := &logic.IndexCursor{}, 0
+	for ; !cursor.Done; txs++ {
+		if err = engine.Update(logic.IndexVersions(cursor, 3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if txs != 4 {
+		t.Fatalf("12 versions indexed in %d transactions", txs)
+	}
+	if err = engine.Update(logic.MarkSecondaryIndexBuilt()); err != nil {
+		t.Fatal(err)
+	}
+	if after := queryKeys(t, engine, msg.Query{Bucket: "b", Sort: msg.SortByTs, Limit: 20}); after != before {
+		t.Fatalf("rebuilt %s, want %s", after, before)
+	}
+	if got := queryKeys(t, engine, msg.Query{Bucket: "other", Limit: 5}); got != key("x", 1) {
+		t.Fatalf("other bucket %s", got)
+	}
+}
+
+// inlineMetaService returns an inline version for listing and querying
+type inlineMetaService struct {
+	usecase.IMetadataService
+}
+
+func (inlineMetaService) versions() ([]string, []*msg.Version, error) {
+	return []string{"b/x.1"}, []*msg.Version{{Sequence: 1, StoreStrategy: 8, Size: 4, Inline: []byte("data")}}, nil
+}
+
+func (s inlineMetaService) ListColdVersions(int64, string, int) ([]string, []*msg.Version, error) {
+	return s.versions()
+}
+
+func (s inlineMetaService) QueryVersions(*msg.Query) ([]string, []*msg.Version, error) {
+	return s.versions()
+}
+
+func TestVersionsWithoutInline(t *testing.T) {
+	server := &grpc.MetadataApiServer{Service: inlineMetaService{}}
+	cold, err := server.ListColdVersion(context.Background(), &pb.ColdReq{Before: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := server.QueryVersions(context.Background(), &pb.QueryReq{Bucket: "b", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range append(cold.Items, query.Items...) {
+		var v msg.Version
+		if err = util.DecodeMsgp(&v, item.Msgpack); err != nil {
+			t.Fatal(err)
+		}
+		if v.Size != 4 || v.Inline != nil {
+			t.Fatalf("inline data of listed version %+v", v)
+		}
+	}
+}
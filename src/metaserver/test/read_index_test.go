@@ -0,0 +1,62 @@
+package test
+
+import (
+	"common/consistency"
+	"context"
+	"io"
+	"metaserver/config"
+	"metaserver/internal/usecase/raftimpl"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+type nopFSM struct{}
+
+func (nopFSM) Apply(*raft.Log) interface{} { return nil }
+
+func (nopFSM) Snapshot() (raft.FSMSnapshot, error) { return nil, io.EOF }
+
+func (nopFSM) Restore(io.ReadCloser) error { return nil }
+
+func TestReadIndexOnLeader(t *testing.T) {
+	rw := raftimpl.NewRaft("127.0.0.1:0", config.ClusterConfig{
+		Enable:           true,
+		Bootstrap:        true,
+		ID:               "n1",
+		StoreDir:         t.TempDir(),
+		ElectionTimeout:  time.Second,
+		HeartbeatTimeout: time.Second,
+		Snapshot:         config.SnapshotConfig{Threshold: 8192, Interval: time.Minute, TrailingLogs: 1024, Retain: 1},
+	}, nopFSM{})
+	defer rw.Close()
+	for i := 0; !rw.IsLeader(); i++ {
+		if i > 100 {
+			t.Fatal("no leader elected")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	f := rw.Raft.Apply([]byte("x"), time.Second)
+	if err := f.Error(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	idx, err := rw.ReadIndex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the read index covers the committed entry, but not the logs only persisted
+	if idx < f.Index() || idx > rw.Raft.LastIndex() {
+		t.Fatalf("read index %d, applied %d, last %d", idx, f.Index(), rw.Raft.LastIndex())
+	}
+	for _, level := range []consistency.Level{consistency.LevelStrong, {Mode: consistency.BoundedStaleness, Staleness: time.Second}} {
+		if err = rw.ConsistentRead(ctx, level); err != nil {
+			t.Fatal(err)
+		}
+		if rw.Raft.AppliedIndex() < f.Index() {
+			t.Fatalf("read before applied %d", f.Index())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"common/proto/msg"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanVersions(t *testing.T) {
+	engine, err := kv.OpenBolt(filepath.Join(t.TempDir(), "scan.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	for _, id := range []string{"b/x", "b/y"} {
+		if err = engine.Update(logic.AddMeta(id, &msg.Metadata{})); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			ver := &msg.Version{Hash: id, Ts: int64(i), UniqueId: logic.GenerateUniqueId()}
+			if err = engine.Update(logic.AddVer(id, ver)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// pages of all versions
+	var all []string
+	var cursor string
+	for {
+		var keys []string
+		var vers []*msg.Version
+		if err = engine.View(logic.ScanVer(cursor, 4, nil, &keys, &vers)); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, keys...)
+		if len(keys) < 4 {
+			break
+		}
+		cursor = keys[len(keys)-1]
+	}
+	if len(all) != 6 || all[0] != "b/x.1" || all[3] != "b/y.1" || all[5] != "b/y.3" {
+		t.Fatalf("unexpected keys %v", all)
+	}
+	// cold versions are filtered by timestamp
+	var keys []string
+	var vers []*msg.Version
+	if err = engine.View(logic.ListColdVer(1, "", 10, &keys, &vers)); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "b/x.1" || keys[1] != "b/y.1" {
+		t.Fatalf("unexpected cold keys %v", keys)
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"metaserver/internal/usecase/db"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/raftimpl"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+// dumpDB returns all buckets, sequences and keys of engine as lines
+func dumpDB(t *testing.T, engine kv.Engine) []string {
+	var lines []string
+	var walk func(path string, b kv.Bucket) error
+	walk = func(path string, b kv.Bucket) error {
+		lines = append(lines, fmt.Sprintf("%s seq=%d", path, b.Sequence()))
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return walk(path+"/"+string(k), b.Bucket(k))
+			}
+			lines = append(lines, fmt.Sprintf("%s/%s=%s", path, k, v))
+			return nil
+		})
+	}
+	if err := engine.View(func(tx kv.Tx) error {
+		return tx.ForEach(func(name []byte, b kv.Bucket) error { return walk(string(name), b) })
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func openStorage(t *testing.T, engine, path string) *db.Storage {
+	s := db.NewStorage()
+	s.Engine = engine
+	if err := s.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+	return s
+}
+
+// writeSnapshot fills a storage of engine and returns its chunked snapshot with the dump of its data
+func writeSnapshot(t *testing.T, engine string) ([]byte, []string) {
+	src := openStorage(t, engine, filepath.Join(t.TempDir(), "src"))
+	src.SnapshotChunkSize = 64
+	if err := src.Update(func(tx kv.Tx) error {
+		for i := 0; i < 3; i++ {
+			root, err := tx.CreateBucketIfNotExists([]byte(fmt.Sprint("root", i)))
+			if err != nil {
+				return err
+			}
+			if err = root.SetSequence(uint64(i * 10)); err != nil {
+				return err
+			}
+			for j := 0; j < 20; j++ {
+				if err = root.Put([]byte(fmt.Sprint("key", j)), []byte(strings.Repeat("v", j))); err != nil {
+					return err
+				}
+				sub, err := root.CreateBucketIfNotExists([]byte(fmt.Sprint("sub", j)))
+				if err != nil {
+					return err
+				}
+				if err = sub.Put([]byte("k"), []byte(fmt.Sprint(i, j))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := src.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Rollback()
+	var buf bytes.Buffer
+	if _, err = snap.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if p := src.SnapshotProgress(); p.State != db.SnapshotSucceeded || p.Chunks < 2 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+	return buf.Bytes(), dumpDB(t, src.DB())
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, engine := range []string{kv.EngineBolt, kv.EngineBadger} {
+		data, want := writeSnapshot(t, engine)
+		path := filepath.Join(t.TempDir(), "dst")
+		if err := db.RestoreFile(engine, path, bytes.NewReader(data)); err != nil {
+			t.Fatal(engine, err)
+		}
+		got := dumpDB(t, openStorage(t, engine, path).DB())
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("%s: restored %v, want %v", engine, got, want)
+		}
+	}
+}
+
+func TestSnapshotCorruptedChunk(t *testing.T) {
+	data, _ := writeSnapshot(t, kv.EngineBolt)
+	// flip a byte in records of the first chunk
+	corrupted := append([]byte{}, data...)
+	corrupted[len(db.SnapshotMagic)+10] ^= 0xff
+	err := db.RestoreFile(kv.EngineBolt, filepath.Join(t.TempDir(), "dst"), bytes.NewReader(corrupted))
+	if !errors.Is(err, db.ErrSnapshotChecksum) {
+		t.Fatalf("expect checksum error, got %v", err)
+	}
+	// a huge size in header is rejected before reading the chunk
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], 0xffffffff)
+	oversize := append(append([]byte{}, db.SnapshotMagic...), header[:]...)
+	err = db.RestoreFile(kv.EngineBolt, filepath.Join(t.TempDir(), "dst"), bytes.NewReader(oversize))
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expect oversize error, got %v", err)
+	}
+}
+
+type recordFSM struct {
+	nopFSM
+	applied []uint64
+}
+
+func (f *recordFSM) ApplyBatch(logs []*raft.Log) []interface{} {
+	for _, lg := range logs {
+		f.applied = append(f.applied, lg.Index)
+	}
+	return make([]interface{}, len(logs))
+}
+
+func TestIncrementalSnapshot(t *testing.T) {
+	fss, err := raft.NewFileSnapshotStore(t.TempDir(), 10, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logStore := raft.NewInmemStore()
+	for i := uint64(1); i <= 9; i++ {
+		if err = logStore.StoreLog(&raft.Log{Index: i, Term: 1, Type: raft.LogCommand, Data: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := raftimpl.NewIncrementalStore(fss, logStore, 2)
+	// persist snapshot at index like fsm, returns whether it's incremental
+	persist := func(index uint64) (string, bool) {
+		sink, err := store.Create(raft.SnapshotVersionMax, index, 1, raft.Configuration{}, 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delta, err := sink.(interface{ PersistDelta() (bool, error) }).PersistDelta()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !delta {
+			// an empty chunked snapshot
+			gz := gzip.NewWriter(sink)
+			_, _ = gz.Write(append(append([]byte{}, db.SnapshotMagic...), make([]byte, 8)...))
+			_ = gz.Close()
+		}
+		if err = sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return sink.ID(), delta
+	}
+	ids := make([]string, 0, 4)
+	for i, c := range []struct {
+		index uint64
+		delta bool
+	}{{3, false}, {5, true}, {8, true}, {9, false}} {
+		id, delta := persist(c.index)
+		if delta != c.delta {
+			t.Fatalf("snapshot %d: incremental %v", i, delta)
+		}
+		ids = append(ids, id)
+	}
+	// the last incremental one is opened with its bases, and logs after the full one are replayed
+	meta, rc, err := store.Open(ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Index != 8 || meta.Size != int64(len(data)) {
+		t.Fatalf("unexpected meta %+v of %d bytes", meta, len(data))
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd := bufio.NewReader(gz)
+	if _, err = rd.Discard(len(db.SnapshotMagic) + 8); err != nil {
+		t.Fatal(err)
+	}
+	fsm := &recordFSM{}
+	if err = raftimpl.ReplayDeltas(rd, fsm); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(fsm.applied) != "[4 5 6 7 8]" {
+		t.Fatalf("replayed %v", fsm.applied)
+	}
+	// a full one is opened alone
+	if meta, _, err = store.Open(ids[3]); err != nil || meta.Index != 9 {
+		t.Fatal(meta, err)
+	}
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"common/proto/msg"
+	"metaserver/internal/usecase"
+	"metaserver/internal/usecase/db/kv"
+	"metaserver/internal/usecase/logic"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwapVersion(t *testing.T) {
+	engine, err := kv.OpenBolt(filepath.Join(t.TempDir(), "swap.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	id := "bucket/obj"
+	if err = engine.Update(logic.AddMeta(id, &msg.Metadata{Name: "obj", Bucket: "bucket"})); err != nil {
+		t.Fatal(err)
+	}
+	ver := &msg.Version{Hash: "hot", Ts: 100, AccessTs: 200, Size: 10, Locate: []string{"a"}, UniqueId: logic.GenerateUniqueId()}
+	if err = engine.Update(logic.AddVer(id, ver)); err != nil {
+		t.Fatal(err)
+	}
+	cold := &msg.Version{Hash: "cold", Sequence: ver.Sequence, Ts: 100, DataShards: 10, ParityShards: 4, Locate: []string{"b"}}
+	if err = engine.Update(logic.SwapVer(id, cold, 99)); err != usecase.ErrOldData {
+		t.Fatalf("expect ErrOldData for modified version, got %v", err)
+	}
+	if err = engine.Update(logic.SwapVer(id, cold, 100)); err != nil {
+		t.Fatal(err)
+	}
+	var res msg.Version
+	if err = engine.View(logic.GetVer(id, ver.Sequence, &res)); err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != "cold" || res.DataShards != 10 || res.Locate[0] != "b" {
+		t.Fatalf("layout is not swapped: %+v", res)
+	}
+	if res.Ts != 100 || res.AccessTs != 200 || res.Size != 10 {
+		t.Fatalf("swapping should not change time and size: %+v", res)
+	}
+	// a retried swap must not be applied twice
+	retry := &msg.Version{Hash: "cold", Sequence: ver.Sequence, Ts: 100, Locate: []string{"c"}}
+	if err = engine.Update(logic.SwapVer(id, retry, 100)); err != usecase.ErrOldData {
+		t.Fatalf("expect ErrOldData for swapped version, got %v", err)
+	}
+}
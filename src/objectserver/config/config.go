@@ -4,6 +4,7 @@ import (
 	"common/datasize"
 	"common/etcd"
 	"common/logs"
+	"common/performance"
 	"common/registry"
 	"os"
 	"path/filepath"
@@ -16,17 +17,42 @@ const (
 	ConfFilePath = "../../conf/object-server.yaml"
 )
 
+// CacheConfig configures the cache of temp infos and locating marks expired by TTL,
+// and the cache of stored objects with a memory tier and an optional disk tier.
 type CacheConfig struct {
 	TTL           time.Duration     `yaml:"ttl" env:"TTL" env-default:"1h"`
 	CleanInterval time.Duration     `yaml:"clean-interval" env:"CLEAN_INTERVAL" env-default:"1h"`
-	MaxItemSize   datasize.DataSize `yaml:"max-item-size" env:"MAX_ITEM_SIZE" env-default:"12MB"`
-	MaxSize       datasize.DataSize `yaml:"max-size" env:"MAX_SIZE" env-default:"128MB"`
+	MaxItemSize   datasize.DataSize `yaml:"max-item-size" env:"MAX_ITEM_SIZE" env-default:"12MB"` // MaxItemSize is the max stored size of cached objects
+	MaxSize       datasize.DataSize `yaml:"max-size" env:"MAX_SIZE" env-default:"128MB"`          // MaxSize is the size of cache of temp infos and marks
+	MemorySize    datasize.DataSize `yaml:"memory-size" env:"MEMORY_SIZE" env-default:"256MB"`    // MemorySize is the size of memory tier of objects, 0 to disable object cache
+	DiskPath      string            `yaml:"disk-path" env:"DISK_PATH"`                            // DiskPath is the directory of disk tier of objects e.g. on a SSD, disabled if empty
+	DiskSize      datasize.DataSize `yaml:"disk-size" env:"DISK_SIZE" env-default:"10GB"`         // DiskSize is the size of disk tier of objects
 }
 
 type StateConfig struct {
 	SyncInterval time.Duration `yaml:"sync-interval" env:"SYNC_INTERVAL" env-default:"1m"`
 }
 
+type RebalanceConfig struct {
+	Enabled     bool              `yaml:"enabled" env:"ENABLED"`
+	Interval    time.Duration     `yaml:"interval" env:"INTERVAL" env-default:"10m"`
+	Threshold   float64           `yaml:"threshold" env:"THRESHOLD" env-default:"0.1"`          // Threshold is the max ratio of deviation to target capacity before rebalancing
+	MaxMoveSize datasize.DataSize `yaml:"max-move-size" env:"MAX_MOVE_SIZE" env-default:"10GB"` // MaxMoveSize limits the size of moves in one round
+	RateLimit   datasize.DataSize `yaml:"rate-limit" env:"RATE_LIMIT" env-default:"32MB"`       // RateLimit is the bytes per second of each move
+}
+
+type CompressionConfig struct {
+	ZstdDicts []string `yaml:"zstd-dicts" env:"ZSTD_DICTS" env-separator:","` // ZstdDicts are paths of zstd dictionaries, the first one is used to compress and others are kept to read old objects
+}
+
+type PackConfig struct {
+	Enabled         bool              `yaml:"enabled" env:"ENABLED" env-default:"false"`
+	MaxObjectSize   datasize.DataSize `yaml:"max-object-size" env:"MAX_OBJECT_SIZE" env-default:"64KB"` // MaxObjectSize is the max size of objects packed into segments
+	SegmentSize     datasize.DataSize `yaml:"segment-size" env:"SEGMENT_SIZE" env-default:"256MB"`      // SegmentSize is the size of a segment before rolling to a new one
+	CompactInterval time.Duration     `yaml:"compact-interval" env:"COMPACT_INTERVAL" env-default:"1h"`
+	GarbageRatio    float64           `yaml:"garbage-ratio" env:"GARBAGE_RATIO" env-default:"0.5"` // GarbageRatio is the min ratio of deleted data in a sealed segment to compact it
+}
+
 type DiscoveryConfig struct {
 	MetaServName string `yaml:"meta-serv-name" env-default:"metaserver"`
 }
@@ -34,27 +60,34 @@ type DiscoveryConfig struct {
 type innerConf struct {
 	PathCachePath string `yaml:"-" env:"-"` // PathCachePath is a path to store path-db-file under BaseMountPoint
 	TempPath      string `yaml:"-" env:"-"` // TempPath is a path to store temporary object file under different mount points
+	SegmentPath   string `yaml:"-" env:"-"` // SegmentPath is a path to store segments of packed objects under different mount points
 }
 
 type Config struct {
 	innerConf          `yaml:"-"`
-	Port               string          `yaml:"port" env-default:"8100"`                                           // Port is port which the http server will listen to
-	BaseMountPoint     string          `yaml:"base-mount-point" env:"BASE_MOUNT_POINT" env-required:"true"`       // BaseMountPoint refers a mount point to store central data also as a fallback choice.
-	StoragePath        string          `yaml:"storage-path" env:"STORAGE_PATH" env-default:"/objects"`            // StoragePath is a path to store object file under different mount points
-	AllowedMountPoints []string        `yaml:"allowed-mount-points" env:"ALLOWED_MOUNT_POINTS" env-separator:","` // AllowedMountPoints limits only these mount points allowed to store object file. Priority over ExcludeMountPoints but not affect BaseMountPoint.
-	ExcludeMountPoints []string        `yaml:"exclude-mount-points" env:"EXCLUDE_MOUNT_POINTS" env-separator:","` // ExcludeMountPoints avoids to store object file under these mount points
-	TempCleaners       int             `yaml:"temp-cleaners" env:"TEMP_CLEANERS" env-default:"3"`
-	Log                logs.Config     `yaml:"log" env-prefix:"LOG"`
-	State              StateConfig     `yaml:"state" env-prefix:"STATE"`
-	Cache              CacheConfig     `yaml:"cache" env-prefix:"CACHE"`
-	Etcd               etcd.Config     `yaml:"etcd" env-prefix:"ETCD"`
-	Registry           registry.Config `yaml:"registry" env-prefix:"REGISTRY"`
-	Discovery          DiscoveryConfig `yaml:"discovery" env-prefix:"DISCOVERY"`
+	Port               string             `yaml:"port" env-default:"8100"`                                           // Port is port which the http server will listen to
+	BaseMountPoint     string             `yaml:"base-mount-point" env:"BASE_MOUNT_POINT" env-required:"true"`       // BaseMountPoint refers a mount point to store central data also as a fallback choice.
+	StoragePath        string             `yaml:"storage-path" env:"STORAGE_PATH" env-default:"/objects"`            // StoragePath is a path to store object file under different mount points
+	AllowedMountPoints []string           `yaml:"allowed-mount-points" env:"ALLOWED_MOUNT_POINTS" env-separator:","` // AllowedMountPoints limits only these mount points allowed to store object file. Priority over ExcludeMountPoints but not affect BaseMountPoint.
+	ExcludeMountPoints []string           `yaml:"exclude-mount-points" env:"EXCLUDE_MOUNT_POINTS" env-separator:","` // ExcludeMountPoints avoids to store object file under these mount points
+	TempCleaners       int                `yaml:"temp-cleaners" env:"TEMP_CLEANERS" env-default:"3"`
+	Log                logs.Config        `yaml:"log" env-prefix:"LOG"`
+	State              StateConfig        `yaml:"state" env-prefix:"STATE"`
+	Cache              CacheConfig        `yaml:"cache" env-prefix:"CACHE"`
+	Etcd               etcd.Config        `yaml:"etcd" env-prefix:"ETCD"`
+	Registry           registry.Config    `yaml:"registry" env-prefix:"REGISTRY"`
+	Discovery          DiscoveryConfig    `yaml:"discovery" env-prefix:"DISCOVERY"`
+	Rebalance          RebalanceConfig    `yaml:"rebalance" env-prefix:"REBALANCE"`
+	Compression        CompressionConfig  `yaml:"compression" env-prefix:"COMPRESSION"`
+	Pack               PackConfig         `yaml:"pack" env-prefix:"PACK"`
+	ZeroCopy           bool               `yaml:"zero-copy" env:"ZERO_COPY" env-default:"true"` // ZeroCopy sends large uncompressed objects by sendfile on plain http/1.x connections
+	Performance        performance.Config `yaml:"performance" env-prefix:"PERFORMANCE"`
 }
 
 func (c *Config) initialize() {
 	c.Registry.ServerPort = c.Port
 	c.PathCachePath = filepath.Join(c.StoragePath, c.Registry.SID()+"_path-cache")
+	c.SegmentPath = filepath.Join(c.StoragePath, c.Registry.SID()+"_segments")
 	c.StoragePath = filepath.Join(c.StoragePath, c.Registry.SID()+"_store")
 	// set to same path to improve writing performance
 	c.TempPath = c.StoragePath
@@ -26,26 +26,29 @@ func Run(cfg *config.Config) {
 		syncer.LeaseID = id
 		_ = syncer.Sync()
 	})
+	migrationService := service.NewMigrationService(pool.ObjectCap)
 	pool.OnOpen(func() {
 		go lifecycle.DeadLoop()
 		pool.OnClose(
 			func() { lifecycle.Close() },
 			// unregister
 			func() { pool.Registry.Unregister() },
-			// locating serv
-			service.NewLocator(pool.Etcd).StartLocate(),
 			// cleaning serv
 			service.StartTempRemovalBackground(pool.Cache, pool.Config.TempCleaners),
 			// auto update driver stat
 			pool.DriverManager.StartAutoUpdate(),
 			// system info sync
 			syncer.StartAutoSave(),
+			// continuous rebalance between servers
+			service.NewRebalanceService(migrationService).StartAutoRebalance(),
+			// compact segments of packed objects
+			service.StartPackCompaction(),
 		)
 	})
 	pool.Open()
 	// warmup serv
 	service.WarmUpLocateCache()
 	// startup server
-	grpcServer := grpc.NewServer(service.NewMigrationService(pool.ObjectCap))
+	grpcServer := grpc.NewServer(migrationService)
 	graceful.ListenAndServe(nil, http.NewHttpServer(cfg.Port, grpcServer))
 }
@@ -24,6 +24,7 @@ func NewServer(service *service.MigrationService) *Server {
 
 	pb.RegisterObjectMigrationServer(serv, NewMigrationServer(service))
 	pb.RegisterConfigServiceServer(serv, &ConfigServiceServer{})
+	pb.RegisterObjectStreamServer(serv, NewStreamServer())
 	return &Server{serv}
 }
 
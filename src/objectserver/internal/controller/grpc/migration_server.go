@@ -77,13 +77,20 @@ func (ms *MigrationServer) RequireSend(_ context.Context, info *pb.RequiredInfo)
 	}
 	if err := ms.Service.SendingTo(curLocate, map[string]int64{
 		info.TargetAddress: info.RequiredSize,
-	}); err != nil {
+	}, info.RateLimit); err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	return &pb.Response{Success: true, Message: "ok"}, nil
 }
 
+func (ms *MigrationServer) AcceptShard(_ context.Context, info *pb.ObjectInfo) (*pb.Response, error) {
+	if err := ms.Service.AcceptShard(info.FileName); err != nil {
+		return &pb.Response{Success: false, Message: err.Error()}, nil
+	}
+	return &pb.Response{Success: true}, nil
+}
+
 func (ms *MigrationServer) LeaveCommand(c context.Context, _ *pb.EmptyReq) (*pb.Response, error) {
 	curLocate, ok := pool.Discovery.GetService(pool.Config.Registry.Name, pool.Config.Registry.SID())
 	if !ok {
@@ -96,7 +103,7 @@ func (ms *MigrationServer) LeaveCommand(c context.Context, _ *pb.EmptyReq) (*pb.
 	if err = pool.CloseGraceful(); err != nil {
 		return &pb.Response{Success: false, Message: "close pool fail: " + err.Error()}, nil
 	}
-	if err = ms.Service.SendingTo(curLocate, sizeMap); err != nil {
+	if err = ms.Service.SendingTo(curLocate, sizeMap, 0); err != nil {
 		return &pb.Response{Success: false, Message: err.Error()}, nil
 	}
 	return &pb.Response{Success: true, Message: "ok"}, nil
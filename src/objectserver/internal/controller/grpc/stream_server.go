@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"common/cst"
+	"common/datasize"
+	"common/proto/pb"
+	"common/response"
+	xmath "common/util/math"
+	"io"
+	"objectserver/internal/entity"
+	"objectserver/internal/usecase/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxShardChunk max size of data in one message of ReadShard
+const maxShardChunk = 256 * datasize.KB
+
+// StreamServer transfers shards through persistent streams instead of one http request per block
+type StreamServer struct {
+	pb.UnimplementedObjectStreamServer
+}
+
+func NewStreamServer() *StreamServer {
+	return &StreamServer{}
+}
+
+// WriteShard appends data of the stream to a temp object.
+// the temp object is committed or removed only if the last message asks for it,
+// otherwise it is kept for resuming just like temp objects uploaded by http.
+func (ss *StreamServer) WriteShard(stream pb.ObjectStream_WriteShardServer) error {
+	head, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	ti, err := openTemp(head)
+	if err != nil {
+		return err
+	}
+	if err = stream.Send(&pb.ShardWriteResp{TmpId: ti.Id}); err != nil {
+		return err
+	}
+	rd := &shardReader{stream: stream}
+	// the whole stream is written in one copy following data written before, so that only the tail of it is padded
+	if _, err = service.AppendTemp(ti, rd, 2*cst.OS.PageSize); err != nil {
+		return response.GRPCError(err)
+	}
+	switch {
+	case rd.last == nil:
+		// client closes the stream without committing
+	case rd.last.Abort:
+		service.RemoveTempInfo(ti.Id)
+	case rd.last.Commit:
+		if ti.Written != ti.Size {
+			return status.Errorf(codes.FailedPrecondition, "written %d bytes of shard in size %d", ti.Written, ti.Size)
+		}
+		if err = service.CommitFile(ti.MountPoint, ti.Id, ti.Name, service.CodecOf(rd.last.Compress, rd.last.Codec)); err != nil {
+			return response.GRPCError(err)
+		}
+		service.RemoveTempInfo(ti.Id)
+	}
+	return stream.Send(&pb.ShardWriteResp{TmpId: ti.Id, Written: rd.n})
+}
+
+// ReadShard sends data of an object or a temp object in chunks
+func (ss *StreamServer) ReadShard(req *pb.ShardReadReq, stream pb.ObjectStream_ReadShardServer) error {
+	if req.Size <= 0 {
+		return status.Error(codes.InvalidArgument, "size must be positive")
+	}
+	wt := &shardWriter{stream: stream}
+	if req.Temp {
+		ti, ok := service.GetTempInfo(req.Name)
+		if !ok {
+			return status.Error(codes.NotFound, "file has been removed")
+		}
+		return response.GRPCError(service.GetFile(ti.FullPath, req.Offset, req.Size, wt))
+	}
+	if err := service.Get(req.Name, req.Offset, req.Size, service.CodecOf(req.Compress, req.Codec), wt); err != nil {
+		return response.GRPCError(err)
+	}
+	return nil
+}
+
+func openTemp(head *pb.ShardWrite) (*entity.TempInfo, error) {
+	if head.TmpId != "" {
+		ti, ok := service.GetTempInfo(head.TmpId)
+		if !ok {
+			return nil, status.Error(codes.NotFound, "file has been removed")
+		}
+		return ti, nil
+	}
+	if head.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name of shard is required")
+	}
+	if head.Size <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "size must be positive")
+	}
+	ti, ok := service.NewTempInfo(head.Name, head.Size)
+	if !ok {
+		return nil, status.Error(codes.Internal, "create temp info fail")
+	}
+	return ti, nil
+}
+
+// shardReader reads data of ShardWrite messages until the stream is closed or the last message arrives
+type shardReader struct {
+	stream pb.ObjectStream_WriteShardServer
+	buf    []byte
+	last   *pb.ShardWrite
+	n      int64
+}
+
+func (r *shardReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.last != nil {
+			return 0, io.EOF
+		}
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.Commit || req.Abort {
+			r.last = req
+		}
+		r.buf = req.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.n += int64(n)
+	return n, nil
+}
+
+type shardWriter struct {
+	stream pb.ObjectStream_ReadShardServer
+}
+
+func (w *shardWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := xmath.MinInt(len(p), maxShardChunk.Int())
+		if err := w.stream.Send(&pb.ShardData{Data: p[:n]}); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
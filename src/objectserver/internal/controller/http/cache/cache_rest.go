@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"common/response"
+	"net/http"
+	"objectserver/internal/usecase/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stat responds counters and sizes of object cache
+func Stat(c *gin.Context) {
+	stats, ok := service.CacheStats()
+	if !ok {
+		response.NotFoundMsg("object cache is disabled", c)
+		return
+	}
+	response.OkJson(stats, c)
+}
+
+// Purge removes cached objects whose names start with query 'prefix', all of them if it's empty
+func Purge(c *gin.Context) {
+	n := service.PurgeCache(c.Query("prefix"))
+	response.OkJson(gin.H{"purged": n}, c)
+}
+
+// Warm loads objects whose names start with query 'prefix' into cache in background
+func Warm(c *gin.Context) {
+	if err := service.WarmCache(c.Query("prefix")); err != nil {
+		response.FailErr(err, c)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
@@ -1,14 +1,11 @@
 package objects
 
 import (
-	"bytes"
-	"common/graceful"
 	"common/request"
 	"common/response"
-	"io"
+	"common/util"
 	"net/http"
 	"objectserver/internal/entity"
-	"objectserver/internal/usecase/pool"
 	"objectserver/internal/usecase/service"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +16,7 @@ func Put(c *gin.Context) {
 	req := &struct {
 		Name     string `uri:"name"`
 		Compress bool   `form:"compress"`
+		Codec    string `form:"codec"`
 	}{}
 	if err := entity.BindAll(c, req, binding.Uri, binding.Query); err != nil {
 		response.FailErr(err, c)
@@ -28,28 +26,15 @@ func Put(c *gin.Context) {
 		response.Ok(c)
 		return
 	}
-	var reader io.Reader = c.Request.Body
-	var cache bytes.Buffer
-	if uint64(c.Request.ContentLength) <= pool.Config.Cache.MaxItemSize.Byte() {
-		cache.Grow(int(c.Request.ContentLength))
-		reader = io.TeeReader(c.Request.Body, &cache)
-	}
-	if err := service.Put(req.Name, reader, req.Compress); err != nil {
+	if err := service.Put(req.Name, c.Request.Body, service.CodecOf(req.Compress, req.Codec)); err != nil {
 		response.FailErr(err, c)
 		return
 	}
-	if cache.Len() > 0 {
-		go func() {
-			defer graceful.Recover()
-			pool.Cache.Set(req.Name, cache.Bytes())
-		}()
-	}
 	response.Ok(c)
 }
 
 func Delete(c *gin.Context) {
 	name := c.Param("name")
-	pool.Cache.Delete(name)
 	if err := service.Delete(name); err != nil {
 		response.FailErr(err, c)
 		return
@@ -63,6 +48,7 @@ func Get(c *gin.Context) {
 		Range    string `header:"range"`
 		Size     int64  `header:"size" binding:"required"`
 		Compress bool   `form:"compress"`
+		Codec    string `form:"codec"`
 	}{}
 	if err := entity.BindAll(c, req, binding.Uri, binding.Query, binding.Header); err != nil {
 		response.FailErr(err, c)
@@ -73,22 +59,23 @@ func Get(c *gin.Context) {
 	if ok := rg.ConvertFrom(req.Range); ok {
 		offset = rg.FirstBytes().First
 	}
-	var writer io.Writer = c.Writer
-	var buf bytes.Buffer
-	if uint64(req.Size) <= pool.Config.Cache.MaxItemSize.Byte() {
-		buf.Grow(int(req.Size))
-		writer = io.MultiWriter(c.Writer, &buf)
+	codec := service.CodecOf(req.Compress, req.Codec)
+	if service.ZeroCopy(codec, req.Size) {
+		sent, err := service.SendObject(req.Name, offset, req.Size, c.Writer, c.Request)
+		if !sent && err != nil {
+			response.FailErr(err, c)
+			return
+		}
+		if sent {
+			// response has been written, error means the connection is broken
+			util.LogErrWithPre("sendfile err", err)
+			return
+		}
 	}
-	if err := service.Get(req.Name, offset, req.Size, req.Compress, writer); err != nil {
+	if err := service.Get(req.Name, offset, req.Size, codec, c.Writer); err != nil {
 		response.FailErr(err, c)
 		return
 	}
-	if buf.Len() > 0 {
-		go func() {
-			defer graceful.Recover()
-			pool.Cache.Set(req.Name, buf.Bytes())
-		}()
-	}
 	c.Status(http.StatusOK)
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"objectserver/internal/controller/grpc"
+	"objectserver/internal/controller/http/cache"
 	"objectserver/internal/controller/http/objects"
 	"objectserver/internal/controller/http/stat"
 	"objectserver/internal/controller/http/temp"
@@ -21,7 +22,7 @@ type Server struct {
 func NewHttpServer(port string, grpcServer *grpc.Server) *Server {
 	r := gin.New()
 	r.Use(gin.LoggerWithWriter(logs.Std().Out), gin.RecoveryWithWriter(logs.Std().Out))
-	r.GET("/objects/:name", objects.GetFromCache, objects.Get)
+	r.GET("/objects/:name", objects.Get)
 	r.HEAD("/objects/:name", objects.Head)
 	r.PUT("/objects/:name", temp.FilterEmptyRequest, objects.Put)
 	r.DELETE("/objects/:name", objects.Delete)
@@ -33,6 +34,10 @@ func NewHttpServer(port string, grpcServer *grpc.Server) *Server {
 	r.GET("/temp/:name", temp.FilterExpired, temp.Get)
 	r.PUT("/temp/:name", temp.FilterExpired, temp.Put)
 
+	r.GET("/cache/stat", cache.Stat)
+	r.DELETE("/cache", cache.Purge)
+	r.POST("/cache/warm", cache.Warm)
+
 	r.GET("/ping", stat.Ping)
 	r.GET("/stat", stat.Info)
 
@@ -14,8 +14,11 @@ func Ping(c *gin.Context) {
 }
 
 func Info(c *gin.Context) {
+	total, free := pool.DriverManager.Space()
 	hd := gin.H{
-		"Capacity": pool.ObjectCap.Capacity(),
+		"Capacity":    pool.ObjectCap.Capacity(),
+		"Total-Space": int64(total),
+		"Free-Space":  int64(free),
 	}
 	if info, err := disk.GetAverageIOStats(); err == nil {
 		hd["Weighted-IO"] = info.WeightedIO
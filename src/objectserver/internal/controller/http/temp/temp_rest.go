@@ -7,10 +7,7 @@ import (
 	xmath "common/util/math"
 	"net/http"
 	"objectserver/internal/entity"
-	"objectserver/internal/usecase/pool"
 	"objectserver/internal/usecase/service"
-	"os"
-	"path/filepath"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -26,7 +23,7 @@ func Patch(g *gin.Context) {
 	// only allow last chuck may not be power of 4KB
 	// for reading from network-io, using too big buffer is not wise.
 	bufSize := xmath.MinInt(int(g.Request.ContentLength), 2*cst.OS.PageSize)
-	if _, err := service.AppendFileAligned(ti.FullPath, g.Request.Body, bufSize); err != nil {
+	if _, err := service.AppendTemp(ti, g.Request.Body, bufSize); err != nil {
 		response.FailErr(err, g)
 		return
 	}
@@ -45,14 +42,8 @@ func Post(g *gin.Context) {
 		response.FailErr(err, g)
 		return
 	}
-	tmpInfo := &entity.TempInfo{
-		Name:       req.Name,
-		Size:       req.Size,
-		Id:         service.GenerateTempID(),
-		MountPoint: pool.DriverManager.SelectMountPointFallback(pool.Config.BaseMountPoint),
-	}
-	tmpInfo.FullPath = filepath.Join(tmpInfo.MountPoint, pool.Config.TempPath, tmpInfo.Id)
-	if !service.SetTempInfo(tmpInfo) {
+	tmpInfo, ok := service.NewTempInfo(req.Name, req.Size)
+	if !ok {
 		g.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -64,6 +55,7 @@ func Put(g *gin.Context) {
 	req := &struct {
 		ID       string `uri:"name"`
 		Compress bool   `form:"compress"`
+		Codec    string `form:"codec"`
 	}{}
 	if err := entity.BindAll(g, req, binding.Uri, binding.Query); err != nil {
 		response.FailErr(err, g)
@@ -74,7 +66,7 @@ func Put(g *gin.Context) {
 		response.BadRequestMsg("file has been removed", g)
 		return
 	}
-	if err := service.CommitFile(ti.MountPoint, req.ID, ti.Name, req.Compress); err != nil {
+	if err := service.CommitFile(ti.MountPoint, req.ID, ti.Name, service.CodecOf(req.Compress, req.Codec)); err != nil {
 		response.FailErr(err, g)
 		return
 	}
@@ -90,19 +82,8 @@ func Head(g *gin.Context) {
 		g.Status(http.StatusNotFound)
 		return
 	}
-	fi, err := os.Stat(ti.FullPath)
-	if os.IsNotExist(err) {
-		response.OkHeader(gin.H{"Size": 0}, g)
-		return
-	}
-	if err != nil {
-		response.FailErr(err, g)
-		return
-	}
-	// fi may have aligned padding if upload has finished
-	response.OkHeader(gin.H{
-		"Size": xmath.MinNumber(fi.Size(), ti.Size),
-	}, g)
+	// file may have aligned padding or data of a failed patch after the written size
+	response.OkHeader(gin.H{"Size": ti.Written}, g)
 }
 
 // Get 获取临时对象分片
@@ -120,6 +101,18 @@ func Get(g *gin.Context) {
 		response.BadRequestMsg("file has been removed", g)
 		return
 	}
+	if service.ZeroCopy("", req.Size) {
+		sent, err := service.SendFile(ti.FullPath, 0, req.Size, g.Writer, g.Request)
+		if !sent && err != nil {
+			response.FailErr(err, g)
+			return
+		}
+		if sent {
+			// response has been written, error means the connection is broken
+			util.LogErrWithPre("sendfile err", err)
+			return
+		}
+	}
 	if err := service.GetFile(ti.FullPath, 0, req.Size, g.Writer); err != nil {
 		response.FailErr(err, g)
 		return
@@ -164,6 +164,10 @@ func (pc *PathCache) IteratorAll(fn func(path string) error) error {
 		itr := txn.NewIterator(badger.DefaultIteratorOptions)
 		defer itr.Close()
 		for itr.Rewind(); itr.Valid(); itr.Next() {
+			// skip entries of PackIndex
+			if bytes.HasPrefix(itr.Item().Key(), PackKeyPrefix) {
+				continue
+			}
 			if err := itr.Item().Value(func(val []byte) error {
 				for _, b := range bytes.Split(val, Sep) {
 					if err := fn(util.BytesToStr(pc.decodeValue(b))); err != nil {
@@ -179,6 +183,28 @@ func (pc *PathCache) IteratorAll(fn func(path string) error) error {
 	})
 }
 
+// RangeNames iterates names of objects starting with prefix. empty prefix means all.
+func (pc *PathCache) RangeNames(prefix string, fn func(name string) error) error {
+	return pc.db.View(func(txn *badger.Txn) error {
+		pre := []byte(prefix)
+		opt := badger.DefaultIteratorOptions
+		opt.Prefix = pre
+		opt.PrefetchValues = false
+		itr := txn.NewIterator(opt)
+		defer itr.Close()
+		for itr.Seek(pre); itr.ValidForPrefix(pre); itr.Next() {
+			// skip entries of PackIndex
+			if bytes.HasPrefix(itr.Item().Key(), PackKeyPrefix) {
+				continue
+			}
+			if err := fn(string(itr.Item().Key())); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (pc *PathCache) Close() error {
 	return pc.db.Close()
 }
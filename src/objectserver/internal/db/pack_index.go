@@ -0,0 +1,140 @@
+package db
+
+import (
+	"common/util"
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	PackKeyPrefix = []byte("pack#")
+)
+
+const packEntryFixedSize = 20
+
+// PackEntry locates data of a packed object in a segment file
+type PackEntry struct {
+	Segment  string // Segment is the full path of segment file
+	Offset   int64  // Offset is the position of data in segment
+	Length   int64  // Length is the size of stored data
+	Checksum uint32 // Checksum is crc32 of stored data
+}
+
+func (e PackEntry) encode() []byte {
+	bt := make([]byte, packEntryFixedSize, packEntryFixedSize+len(e.Segment))
+	binary.BigEndian.PutUint64(bt, uint64(e.Offset))
+	binary.BigEndian.PutUint64(bt[8:], uint64(e.Length))
+	binary.BigEndian.PutUint32(bt[16:], e.Checksum)
+	return append(bt, e.Segment...)
+}
+
+func decodePackEntry(bt []byte) (PackEntry, error) {
+	if len(bt) < packEntryFixedSize {
+		return PackEntry{}, errors.New("broken pack entry")
+	}
+	return PackEntry{
+		Offset:   int64(binary.BigEndian.Uint64(bt)),
+		Length:   int64(binary.BigEndian.Uint64(bt[8:])),
+		Checksum: binary.BigEndian.Uint32(bt[16:]),
+		Segment:  string(bt[packEntryFixedSize:]),
+	}, nil
+}
+
+// PackIndex indexes objects packed into segments by name. it shares the badger of PathCache with prefixed keys.
+type PackIndex struct {
+	db *badger.DB
+}
+
+func NewPackIndex(pc *PathCache) *PackIndex {
+	return &PackIndex{db: pc.db}
+}
+
+func packKey(name string) []byte {
+	return append(append([]byte{}, PackKeyPrefix...), name...)
+}
+
+// Get if not found, return os.ErrNotExist
+func (pi *PackIndex) Get(name string) (PackEntry, error) {
+	var res PackEntry
+	err := pi.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(packKey(name))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) (inner error) {
+			res, inner = decodePackEntry(val)
+			return
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		err = os.ErrNotExist
+	}
+	return res, err
+}
+
+func (pi *PackIndex) Put(name string, entry PackEntry) error {
+	return pi.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(packKey(name), entry.encode())
+	})
+}
+
+// Move updates entry of name to 'to' only if it's still 'from', returns false otherwise
+func (pi *PackIndex) Move(name string, from, to PackEntry) (bool, error) {
+	moved := false
+	err := pi.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(packKey(name))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var cur PackEntry
+		if err = item.Value(func(val []byte) (inner error) {
+			cur, inner = decodePackEntry(val)
+			return
+		}); err != nil {
+			return err
+		}
+		if cur != from {
+			return nil
+		}
+		moved = true
+		return txn.Set(packKey(name), to.encode())
+	})
+	return moved, err
+}
+
+func (pi *PackIndex) Remove(name string) error {
+	return pi.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(packKey(name))
+	})
+}
+
+// Range iterates entries of names starting with prefix. empty prefix means all.
+func (pi *PackIndex) Range(prefix string, fn func(name string, entry PackEntry) error) error {
+	return pi.db.View(func(txn *badger.Txn) error {
+		pre := packKey(prefix)
+		opt := badger.DefaultIteratorOptions
+		opt.Prefix = pre
+		itr := txn.NewIterator(opt)
+		defer itr.Close()
+		for itr.Seek(pre); itr.ValidForPrefix(pre); itr.Next() {
+			item := itr.Item()
+			name := util.BytesToStr(item.KeyCopy(nil)[len(PackKeyPrefix):])
+			if err := item.Value(func(val []byte) error {
+				entry, err := decodePackEntry(val)
+				if err != nil {
+					return err
+				}
+				return fn(name, entry)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
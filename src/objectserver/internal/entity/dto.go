@@ -28,6 +28,7 @@ type TempInfo struct {
 	Size       int64
 	MountPoint string
 	FullPath   string
+	Written    int64 // Written size of data without padding of direct-io
 }
 
 func (t *TempInfo) ShardIndex() int {
@@ -121,3 +121,12 @@ func (dm *DriverManager) GetAllMountPoint() []string {
 	}
 	return res
 }
+
+// Space returns the sum of total and free space of all drivers
+func (dm *DriverManager) Space() (total, free datasize.DataSize) {
+	for _, d := range dm.drivers {
+		total += d.TotalSpace
+		free += d.FreeSpace
+	}
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"common/etcd"
 	"common/graceful"
 	"common/logs"
+	"common/performance"
 	"common/registry"
 	"common/util/slices"
 	"errors"
@@ -29,10 +30,13 @@ var (
 	Etcd          *clientv3.Client
 	ObjectCap     *db.ObjectCapacity
 	PathDB        *db.PathCache
+	PackDB        *db.PackIndex
 	DriverManager *component.DriverManager
 	Cache         cache.ICache
+	ObjectCache   *cache.Tiered // ObjectCache caches stored data of objects by name, nil if disabled
 	Registry      *registry.EtcdRegistry
 	Discovery     registry.Discovery
+	Perform       performance.Collector
 )
 
 var (
@@ -80,8 +84,10 @@ func InitPool(cfg *config.Config) {
 	initDir(cfg, DriverManager)
 	initLog(&cfg.Log)
 	initCache(&cfg.Cache)
+	initObjectCache(cfg)
 	initEtcd(&cfg.Etcd)
 	initRegister(Etcd, cfg)
+	initPerform(&cfg.Performance, &cfg.Log, &cfg.Registry, Etcd)
 	initObjectCap()
 	initPathCache(cfg)
 }
@@ -97,6 +103,9 @@ func initDir(cfg *config.Config, dm *component.DriverManager) {
 		if e := os.MkdirAll(filepath.Join(mp, cfg.StoragePath), cst.OS.ModeUser); e != nil {
 			panic(e)
 		}
+		if e := os.MkdirAll(filepath.Join(mp, cfg.SegmentPath), cst.OS.ModeUser); e != nil {
+			panic(e)
+		}
 	}
 }
 
@@ -113,6 +122,7 @@ func initPathCache(cfg *config.Config) {
 	if err != nil {
 		panic(err)
 	}
+	PackDB = db.NewPackIndex(PathDB)
 }
 
 func initLog(cfg *logs.Config) {
@@ -139,6 +149,43 @@ func initCache(cfg *config.CacheConfig) {
 	Cache = cache.NewCache(cacheConf)
 }
 
+func initObjectCache(cfg *config.Config) {
+	if cfg.Cache.MemorySize == 0 {
+		return
+	}
+	var diskPath string
+	if cfg.Cache.DiskPath != "" {
+		diskPath = filepath.Join(cfg.Cache.DiskPath, cfg.Registry.SID()+"_cache")
+	}
+	var err error
+	ObjectCache, err = cache.NewTiered(cache.TieredConfig{
+		MaxSize:     int64(cfg.Cache.MemorySize.Byte()),
+		MaxItemSize: int64(cfg.Cache.MaxItemSize.Byte()),
+		DiskPath:    diskPath,
+		DiskSize:    int64(cfg.Cache.DiskSize.Byte()),
+	})
+	if err != nil {
+		panic("init object cache: " + err.Error())
+	}
+}
+
+func initPerform(cfg *performance.Config, logCfg *logs.Config, regCfg *registry.Config, etcd *clientv3.Client) {
+	if cfg.Enable && cfg.Store == performance.Local {
+		localPath := logCfg.StoreDir
+		if localPath == "" {
+			localPath = os.TempDir()
+		}
+		performance.SetLocalStore(performance.NewLocalStore(filepath.Join(localPath, regCfg.SID()+".perf")))
+	}
+	if cfg.Enable && cfg.Store == performance.Remote {
+		performance.SetRemoteStore(performance.NewEtcdStore(etcd, []string{
+			performance.ActionHit,
+			performance.ActionMiss,
+		}))
+	}
+	Perform = performance.NewCollector(cfg)
+}
+
 func initEtcd(cfg *etcd.Config) {
 	var e error
 	if Etcd, e = clientv3.New(clientv3.Config{
@@ -160,7 +207,11 @@ func initRegister(et *clientv3.Client, cfg *config.Config) {
 
 func CloseAll() {
 	defer Etcd.Close()
+	defer Perform.Close()
 	defer Cache.Close()
+	if ObjectCache != nil {
+		defer ObjectCache.Close()
+	}
 	defer PathDB.Close()
 	defer Close()
 }
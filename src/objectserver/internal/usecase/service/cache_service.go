@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"common/cache"
+	"common/graceful"
+	"common/logs"
+	"common/performance"
+	"common/response"
+	"common/util"
+	"io"
+	"objectserver/internal/db"
+	global "objectserver/internal/usecase/pool"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	cacheLog = logs.New("object-cache")
+	warming  atomic.Bool // warming is true while WarmCache is loading objects
+)
+
+// writeStored writes at most size bytes of stored data from offset, decompressing it by codec if codec is not empty
+func writeStored(data []byte, offset, size int64, codec string, writer io.Writer) error {
+	if codec != "" {
+		cd, err := GetCodec(codec)
+		if err != nil {
+			return err
+		}
+		return cd.Read(bytes.NewReader(data), offset, size, writer)
+	}
+	if offset >= int64(len(data)) {
+		return nil
+	}
+	data = data[offset:]
+	if size < int64(len(data)) {
+		data = data[:size]
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
+// readStored returns data of an object as it's stored, returns false if it's larger than Cache.MaxItemSize
+func readStored(name string) ([]byte, bool, error) {
+	if data, packed, err := ReadPacked(name); packed {
+		return data, err == nil, err
+	}
+	fullPath, ok := FindRealStoragePath(name)
+	if !ok {
+		return nil, false, os.ErrNotExist
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if uint64(info.Size()) > global.Config.Cache.MaxItemSize.Byte() {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(fullPath)
+	return data, err == nil, err
+}
+
+// getCached reads an object from global.ObjectCache, stored data is loaded into cache if it's admitted.
+// returns false if the object should be read from storage.
+func getCached(name string, offset, size int64, codec string, writer io.Writer) (bool, error) {
+	if global.ObjectCache == nil {
+		return false, nil
+	}
+	start := time.Now()
+	data, ok := global.ObjectCache.Get(name)
+	observeCache(ok, start)
+	if !ok {
+		if !global.ObjectCache.Admit(name, size) {
+			return false, nil
+		}
+		var err error
+		if data, ok, err = readStored(name); !ok {
+			// let storage report the error
+			if err != nil {
+				cacheLog.Debugf("load %s err: %s", name, err)
+			}
+			return false, nil
+		}
+		global.ObjectCache.Set(name, data)
+	}
+	return true, writeStored(data, offset, size, codec, writer)
+}
+
+// observeCache records a hit or miss of object cache in performance collector
+func observeCache(hit bool, start time.Time) {
+	if global.Perform == nil {
+		return
+	}
+	global.Perform.PutAsync(util.IfElse(hit, performance.ActionHit, performance.ActionMiss), performance.KindOfCache, time.Since(start))
+}
+
+// uncache removes an object from global.ObjectCache
+func uncache(name string) {
+	if global.ObjectCache != nil {
+		global.ObjectCache.Delete(name)
+	}
+}
+
+// CacheStats returns counters and sizes of object cache, false if it's disabled
+func CacheStats() (cache.TieredStats, bool) {
+	if global.ObjectCache == nil {
+		return cache.TieredStats{}, false
+	}
+	return global.ObjectCache.Stats(), true
+}
+
+// PurgeCache removes cached objects whose names start with prefix, returns the number of them
+func PurgeCache(prefix string) int {
+	if global.ObjectCache == nil {
+		return 0
+	}
+	return global.ObjectCache.DeletePrefix(prefix)
+}
+
+// WarmCache loads objects whose names start with prefix into cache bypassing admission in background.
+// prefix is required to not read all objects, and only one warming runs at a time.
+// objects larger than Cache.MaxItemSize are skipped.
+func WarmCache(prefix string) error {
+	if global.ObjectCache == nil {
+		return response.NewError(404, "object cache is disabled")
+	}
+	if prefix == "" {
+		return response.NewError(400, "prefix is required")
+	}
+	if !warming.CompareAndSwap(false, true) {
+		return response.NewError(409, "cache is warming")
+	}
+	go func() {
+		defer graceful.Recover()
+		defer warming.Store(false)
+		if err := warmCache(prefix); err != nil {
+			cacheLog.Errorf("warm objects of prefix '%s' err: %s", prefix, err)
+		}
+	}()
+	return nil
+}
+
+func warmCache(prefix string) error {
+	var names []string
+	if err := global.PathDB.RangeNames(prefix, func(name string) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := global.PackDB.Range(prefix, func(name string, _ db.PackEntry) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+	n := 0
+	for _, name := range names {
+		data, ok, err := readStored(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			cacheLog.Warnf("warm %s err: %s", name, err)
+			continue
+		}
+		if ok && global.ObjectCache.Warm(name, data) {
+			n++
+		}
+	}
+	cacheLog.Infof("warm %d objects of prefix '%s'", n, prefix)
+	return nil
+}
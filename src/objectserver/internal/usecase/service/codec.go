@@ -0,0 +1,282 @@
+package service
+
+import (
+	"common/cst"
+	"common/datasize"
+	"common/proto/msg"
+	"common/response"
+	"common/util/math"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	global "objectserver/internal/usecase/pool"
+	"os"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses objects into files which can be read from any offset without decompressing from the beginning
+type Codec interface {
+	// Write compresses data of stream to file with an index for seeking, returns size of data
+	Write(file io.Writer, stream io.Reader) (int64, error)
+	// Read decompresses at most size bytes of data from offset to writer
+	Read(file io.ReadSeeker, offset, size int64, writer io.Writer) error
+}
+
+// CodecOf returns the codec of an object, empty if it's not compressed. s2 is the default codec of compressed objects.
+func CodecOf(compress bool, codec string) string {
+	if !compress {
+		return ""
+	}
+	if codec == "" {
+		return msg.CodecS2
+	}
+	return codec
+}
+
+// GetCodec returns the Codec of name
+func GetCodec(name string) (Codec, error) {
+	switch name {
+	case msg.CodecS2:
+		return s2Codec{}, nil
+	case msg.CodecZstd:
+		return zstdCodec{}, nil
+	default:
+		return nil, response.NewError(400, fmt.Sprintf("unknown codec '%s'", name))
+	}
+}
+
+// WriteFileCodec creates a file compressed by codec
+func WriteFileCodec(fullPath string, fileStream io.Reader, codec string) (int64, error) {
+	cd, err := GetCodec(codec)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(fullPath, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, cst.OS.ModeUser)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return cd.Write(file, fileStream)
+}
+
+// GetFileCodec read file compressed by codec from offset of decompressed data
+func GetFileCodec(fullPath string, offset, size int64, codec string, writer io.Writer) error {
+	cd, err := GetCodec(codec)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		return response.NewError(404, "object not found")
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return cd.Read(file, offset, size, writer)
+}
+
+// s2Codec writes s2 stream with an index at the end of it. streams without index are still readable by forward skipping.
+type s2Codec struct{}
+
+func (s2Codec) Write(file io.Writer, stream io.Reader) (int64, error) {
+	wt := s2.NewWriter(file, s2.WriterBetterCompression(), s2.WriterBlockSize(2*datasize.MB.Int()), s2.WriterAddIndex())
+	n, err := io.CopyBuffer(wt, stream, make([]byte, 4*datasize.MB))
+	if err != nil {
+		return n, err
+	}
+	return n, wt.Close()
+}
+
+func (s2Codec) Read(file io.ReadSeeker, offset, size int64, writer io.Writer) error {
+	rd, err := s2.NewReader(file).ReadSeeker(false, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if _, err = rd.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	bufSize := math.MinNumber(8*cst.OS.PageSize, int(size))
+	_, err = io.CopyBuffer(writer, io.LimitReader(rd, size), make([]byte, bufSize))
+	return err
+}
+
+const (
+	// zstdFrameSize is the size of data in each independent frame of zstd seekable format
+	zstdFrameSize       = datasize.MB
+	zstdSkippableMagic  = 0x184D2A5E
+	zstdSeekableMagic   = 0x8F92EAB1
+	zstdSeekFooterSize  = 9
+	zstdSeekEntrySize   = 8
+	zstdSkippableHeader = 8
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCoders creates encoder and decoder with dictionaries of config.
+// the first dictionary is used to compress, others are kept to read objects compressed before.
+func zstdCoders() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		var dicts [][]byte
+		var paths []string
+		if global.Config != nil {
+			paths = global.Config.Compression.ZstdDicts
+		}
+		for _, path := range paths {
+			bt, err := os.ReadFile(path)
+			if err != nil {
+				zstdErr = fmt.Errorf("read zstd dictionary: %w", err)
+				return
+			}
+			dicts = append(dicts, bt)
+		}
+		var eOpts []zstd.EOption
+		if len(dicts) > 0 {
+			eOpts = append(eOpts, zstd.WithEncoderDict(dicts[0]))
+		}
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil, eOpts...); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderDicts(dicts...))
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// zstdCodec writes zstd seekable format: data is split into independent frames
+// followed by a skippable frame holding sizes of them.
+type zstdCodec struct{}
+
+type zstdFrame struct {
+	compressedOff, compressedSize     int64
+	uncompressedOff, uncompressedSize int64
+}
+
+func (zstdCodec) Write(file io.Writer, stream io.Reader) (int64, error) {
+	enc, _, err := zstdCoders()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	var table, dst []byte
+	buf := make([]byte, zstdFrameSize)
+	for {
+		n, err := io.ReadFull(stream, buf)
+		if n > 0 {
+			dst = enc.EncodeAll(buf[:n], dst[:0])
+			if _, inner := file.Write(dst); inner != nil {
+				return total, inner
+			}
+			table = binary.LittleEndian.AppendUint32(table, uint32(len(dst)))
+			table = binary.LittleEndian.AppendUint32(table, uint32(n))
+			total += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	// seek table
+	frames := uint32(len(table) / zstdSeekEntrySize)
+	bt := binary.LittleEndian.AppendUint32(nil, zstdSkippableMagic)
+	bt = binary.LittleEndian.AppendUint32(bt, uint32(len(table)+zstdSeekFooterSize))
+	bt = append(bt, table...)
+	bt = binary.LittleEndian.AppendUint32(bt, frames)
+	bt = append(bt, 0) // descriptor without checksums
+	bt = binary.LittleEndian.AppendUint32(bt, zstdSeekableMagic)
+	_, err = file.Write(bt)
+	return total, err
+}
+
+func (zstdCodec) Read(file io.ReadSeeker, offset, size int64, writer io.Writer) error {
+	_, dec, err := zstdCoders()
+	if err != nil {
+		return err
+	}
+	frames, err := loadZstdSeekTable(file)
+	if err != nil {
+		return err
+	}
+	var src, dst []byte
+	for _, f := range frames {
+		if size <= 0 {
+			break
+		}
+		if f.uncompressedOff+f.uncompressedSize <= offset {
+			continue
+		}
+		if _, err = file.Seek(f.compressedOff, io.SeekStart); err != nil {
+			return err
+		}
+		if int64(cap(src)) < f.compressedSize {
+			src = make([]byte, f.compressedSize)
+		}
+		src = src[:f.compressedSize]
+		if _, err = io.ReadFull(file, src); err != nil {
+			return err
+		}
+		if dst, err = dec.DecodeAll(src, dst[:0]); err != nil {
+			return err
+		}
+		data := dst[math.MaxNumber(offset-f.uncompressedOff, 0):]
+		if int64(len(data)) > size {
+			data = data[:size]
+		}
+		if _, err = writer.Write(data); err != nil {
+			return err
+		}
+		size -= int64(len(data))
+	}
+	return nil
+}
+
+func loadZstdSeekTable(file io.ReadSeeker) ([]zstdFrame, error) {
+	end, err := file.Seek(-zstdSeekFooterSize, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	footer := make([]byte, zstdSeekFooterSize)
+	if _, err = io.ReadFull(file, footer); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != zstdSeekableMagic {
+		return nil, errors.New("zstd seek table not found")
+	}
+	num := int64(binary.LittleEndian.Uint32(footer))
+	if _, err = file.Seek(end-num*zstdSeekEntrySize, io.SeekStart); err != nil {
+		return nil, err
+	}
+	table := make([]byte, num*zstdSeekEntrySize)
+	if _, err = io.ReadFull(file, table); err != nil {
+		return nil, err
+	}
+	frames := make([]zstdFrame, num)
+	var cOff, uOff int64
+	for i := range frames {
+		entry := table[i*zstdSeekEntrySize:]
+		frames[i] = zstdFrame{
+			compressedOff:    cOff,
+			compressedSize:   int64(binary.LittleEndian.Uint32(entry)),
+			uncompressedOff:  uOff,
+			uncompressedSize: int64(binary.LittleEndian.Uint32(entry[4:])),
+		}
+		cOff += frames[i].compressedSize
+		uOff += frames[i].uncompressedSize
+	}
+	if cOff+zstdSkippableHeader+int64(len(table))+zstdSeekFooterSize != end+zstdSeekFooterSize {
+		return nil, errors.New("zstd seek table mismatches file size")
+	}
+	return frames, nil
+}
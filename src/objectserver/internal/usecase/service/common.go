@@ -3,6 +3,7 @@ package service
 import (
 	"common/logs"
 	"io/fs"
+	"objectserver/internal/db"
 	"objectserver/internal/entity"
 	"objectserver/internal/usecase/pool"
 	"path/filepath"
@@ -32,4 +33,12 @@ func WarmUpLocateCache() {
 			return
 		}
 	}
+	// objects packed into segments
+	if err := pool.PackDB.Range("", func(name string, entry db.PackEntry) error {
+		pool.ObjectCap.AddCap(entry.Length)
+		MarkExist(name)
+		return nil
+	}); err != nil {
+		logs.Std().Error(err)
+	}
 }
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"bytes"
+	"common/balance"
 	"common/cst"
 	"common/datasize"
 	"common/graceful"
@@ -16,7 +18,6 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"math"
 	"net/http"
 	"objectserver/internal/db"
 	"objectserver/internal/usecase/pool"
@@ -25,6 +26,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"sync/atomic"
@@ -42,11 +44,11 @@ func NewMigrationService(c *db.ObjectCapacity) *MigrationService {
 	return &MigrationService{CapacityDB: c}
 }
 
-// getPeersCapacity returns peers capacity info expect self. key=addr,value=cap
-func (ms *MigrationService) getPeersCapacity() map[string]int64 {
+// getPeersStat returns storage state of peers expect self. key=addr
+func (ms *MigrationService) getPeersStat() map[string]balance.Node {
 	ips := pool.Discovery.GetServices(pool.Config.Registry.Name)
 	selfLoc, _ := pool.Discovery.GetService(pool.Config.Registry.Name, pool.Config.Registry.SID())
-	res := make(map[string]int64, len(ips))
+	res := make(map[string]balance.Node, len(ips))
 	for _, ip := range ips {
 		// skip self
 		if ip == selfLoc {
@@ -57,30 +59,51 @@ func (ms *MigrationService) getPeersCapacity() map[string]int64 {
 			msLog.Errorf("get capacity from %s err: %s", ip, err)
 			continue
 		}
-		res[ip] = util.ToInt64(resp.Header.Get("Capacity"))
+		util.LogErr(resp.Body.Close())
+		res[ip] = balance.Node{
+			Used:  util.ToInt64(resp.Header.Get("Capacity")),
+			Total: util.ToInt64(resp.Header.Get("Total-Space")),
+			Free:  util.ToInt64(resp.Header.Get("Free-Space")),
+		}
 	}
 	return res
 }
 
-// DeviationValues calculate the required size of sending to or receiving from others depending on 'join'
+// selfStat returns storage state of this server
+func (ms *MigrationService) selfStat() balance.Node {
+	total, free := pool.DriverManager.Space()
+	return balance.Node{Used: pool.ObjectCap.Capacity(), Total: int64(total), Free: int64(free)}
+}
+
+// DeviationValues calculate the required size of sending to or receiving from others depending on 'join'.
+// objects are distributed in proportion to total space of servers, which maximizes the storage entropy.
 // return map(key=rpc-addr,value=capacity)
 func (ms *MigrationService) DeviationValues(join bool) (map[string]int64, error) {
-	capMap := ms.getPeersCapacity()
-	size := util.IfElse(join, len(capMap)+1, len(capMap))
-	if size == 0 {
+	peers := ms.getPeersStat()
+	if len(peers) == 0 {
 		return nil, fmt.Errorf("non avaliable object servers")
 	}
-	total := float64(pool.ObjectCap.Capacity())
-	for _, v := range capMap {
-		total += float64(v)
+	self := ms.selfStat()
+	addrs := make([]string, 0, len(peers))
+	nodes := make([]balance.Node, 0, len(peers)+1)
+	sum := self.Used
+	for k, v := range peers {
+		addrs = append(addrs, k)
+		nodes = append(nodes, v)
+		sum += v.Used
 	}
-	if total == 0 {
+	if sum == 0 {
 		return nil, errors.New("total cap is zero")
 	}
-	avg := int64(math.Ceil(total / float64(size)))
-	res := make(map[string]int64, len(capMap))
-	for k, v := range capMap {
-		if v = util.IfElse(join, v-avg, avg-v); v > 0 {
+	// self is the last one if joining, otherwise self will store nothing
+	if join {
+		nodes = append(nodes, self)
+	}
+	targets := balance.Targets(nodes, sum)
+	res := make(map[string]int64, len(addrs))
+	for i, k := range addrs {
+		// peers send the exceeded part to self if joining, otherwise receive the lacked part from self
+		if v := util.IfElse(join, nodes[i].Used-targets[i], targets[i]-nodes[i].Used); v > 0 {
 			res[k] = v
 		}
 	}
@@ -91,7 +114,36 @@ func (ms *MigrationService) DeviationValues(join bool) (map[string]int64, error)
 	return res, nil
 }
 
-func (ms *MigrationService) writeStream(stream pb.ObjectMigration_ReceiveDataClient, file io.Reader, name string, size int64) (err error) {
+// throttle limits bytes sent per second. nil means unlimited.
+type throttle struct {
+	mux   sync.Mutex
+	rate  int64
+	sent  int64
+	start time.Time
+}
+
+func newThrottle(rate int64) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+	return &throttle{rate: rate, start: time.Now()}
+}
+
+// Wait blocks until n bytes are allowed to send
+func (t *throttle) Wait(n int) {
+	if t == nil {
+		return
+	}
+	t.mux.Lock()
+	t.sent += int64(n)
+	wait := time.Duration(float64(t.sent)/float64(t.rate)*float64(time.Second)) - time.Since(t.start)
+	t.mux.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (ms *MigrationService) writeStream(stream pb.ObjectMigration_ReceiveDataClient, file io.Reader, name string, size int64, limit *throttle) (err error) {
 	defer func() {
 		var resp *pb.Response
 		var inner error
@@ -123,6 +175,7 @@ func (ms *MigrationService) writeStream(stream pb.ObjectMigration_ReceiveDataCli
 		if err != nil {
 			return fmt.Errorf("read file %s err: %s", name, err)
 		}
+		limit.Wait(n)
 		if err = stream.Send(&pb.ObjectData{
 			FileName: name,
 			Size:     size,
@@ -137,20 +190,21 @@ func (ms *MigrationService) writeStream(stream pb.ObjectMigration_ReceiveDataCli
 	return
 }
 
-func (ms *MigrationService) sendFileTo(path string, client pb.ObjectMigrationClient, info *pb.ObjectInfo) error {
+// sendFileTo sends data opened by open function to client
+func (ms *MigrationService) sendFileTo(open func() (io.ReadCloser, error), client pb.ObjectMigrationClient, info *pb.ObjectInfo, limit *throttle) error {
 	// open stream
 	stream, err := client.ReceiveData(context.Background())
 	if err != nil {
 		return fmt.Errorf("create stream err: %w", err)
 	}
 	// open file
-	file, err := os.Open(path)
+	file, err := open()
 	if err != nil {
-		return fmt.Errorf("open %s err: %w", path, err)
+		return fmt.Errorf("open %s err: %w", info.FileName, err)
 	}
 	defer file.Close()
 	// send data
-	if err = ms.writeStream(stream, file, info.FileName, info.Size); err != nil {
+	if err = ms.writeStream(stream, file, info.FileName, info.Size, limit); err != nil {
 		return err
 	}
 	// finish an object
@@ -160,10 +214,13 @@ func (ms *MigrationService) sendFileTo(path string, client pb.ObjectMigrationCli
 	return nil
 }
 
-func (ms *MigrationService) SendingTo(httpLocate string, sizeMap map[string]int64) error {
+// SendingTo sends local shards to servers until the size in sizeMap are reached.
+// shards will be skipped if refused by the target server. rateLimit is bytes per second of all servers, 0 means unlimited.
+func (ms *MigrationService) SendingTo(httpLocate string, sizeMap map[string]int64, rateLimit int64) error {
 	if len(sizeMap) == 0 {
 		return nil
 	}
+	limit := newThrottle(rateLimit)
 	clientMap := make(map[string]pb.ObjectMigrationClient, len(sizeMap))
 	conns := make([]*grpc.ClientConn, 0, len(sizeMap))
 	addrs := make([]string, 0, len(sizeMap))
@@ -192,6 +249,49 @@ func (ms *MigrationService) SendingTo(httpLocate string, sizeMap map[string]int6
 		cur := slices.First(addrs)
 		slices.RemoveFirst(&addrs)
 		leftSize := sizeMap[cur]
+		// send sends an object, returns io.EOF if all sizes are reached
+		send := func(name string, size int64, open func() (io.ReadCloser, error)) error {
+			client := clientMap[cur]
+			shard := &pb.ObjectInfo{
+				FileName:     name,
+				Size:         size,
+				OriginLocate: httpLocate,
+			}
+			// skip if target server holds other shards of the same stripe
+			if _, inner := proto.ResolveResponse(client.AcceptShard(context.Background(), shard)); inner != nil {
+				msLog.Debugf("skip sending %s to %s: %s", shard.FileName, cur, inner)
+				return nil
+			}
+			// transfer file async
+			dg.Todo()
+			go func(toAddr string) {
+				defer dg.Done()
+				if inner := ms.sendFileTo(open, client, shard, limit); inner != nil {
+					dg.Errors(inner)
+					return
+				}
+				// remove file async if transfer success
+				go func() {
+					defer graceful.Recover()
+					metric[toAddr].Add(1)
+					// remove local file
+					if inner := Delete(name); inner != nil {
+						msLog.Errorf("migrate %s success, but delete fail: %s", name, inner)
+					}
+				}()
+			}(cur)
+			// switch to next server if already exceeds left size
+			if leftSize -= size; leftSize <= 0 {
+				if len(addrs) > 0 {
+					cur = slices.First(addrs)
+					slices.RemoveFirst(&addrs)
+					leftSize = sizeMap[cur]
+				} else {
+					return io.EOF
+				}
+			}
+			return nil
+		}
 		var err error
 		for _, mp := range pool.DriverManager.GetAllMountPoint() {
 			err = filepath.Walk(filepath.Join(mp, pool.Config.StoragePath), func(path string, info fs.FileInfo, err error) error {
@@ -202,40 +302,7 @@ func (ms *MigrationService) SendingTo(httpLocate string, sizeMap map[string]int6
 				if info.IsDir() {
 					return nil
 				}
-				client := clientMap[cur]
-				// transfer file async
-				dg.Todo()
-				go func(toAddr string) {
-					defer dg.Done()
-					if inner := ms.sendFileTo(path, client, &pb.ObjectInfo{
-						FileName:     info.Name(),
-						Size:         info.Size(),
-						OriginLocate: httpLocate,
-					}); inner != nil {
-						dg.Errors(inner)
-						return
-					}
-					// remove file async if transfer success
-					go func() {
-						defer graceful.Recover()
-						metric[toAddr].Add(1)
-						// remove local file
-						if inner := Delete(info.Name()); inner != nil {
-							msLog.Errorf("migrate %s success, but delete fail: %s", path, err)
-						}
-					}()
-				}(cur)
-				// switch to next server if already exceeds left size
-				if leftSize -= info.Size(); leftSize <= 0 {
-					if len(addrs) > 0 {
-						cur = slices.First(addrs)
-						slices.RemoveFirst(&addrs)
-						leftSize = sizeMap[cur]
-					} else {
-						return io.EOF
-					}
-				}
-				return nil
+				return send(info.Name(), info.Size(), func() (io.ReadCloser, error) { return os.Open(path) })
 			})
 			if err == io.EOF {
 				break
@@ -244,6 +311,25 @@ func (ms *MigrationService) SendingTo(httpLocate string, sizeMap map[string]int6
 				dg.Errors(err)
 			}
 		}
+		// objects packed into segments are sent as files. names are collected first to not hold the transaction of index while sending
+		if err != io.EOF {
+			var names []string
+			var sizes []int64
+			err = pool.PackDB.Range("", func(name string, entry db.PackEntry) error {
+				names, sizes = append(names, name), append(sizes, entry.Length)
+				return nil
+			})
+			for i := 0; i < len(names) && err == nil; i++ {
+				name := names[i]
+				err = send(name, sizes[i], func() (io.ReadCloser, error) {
+					data, ok, inner := ReadPacked(name)
+					if !ok && inner == nil {
+						inner = os.ErrNotExist
+					}
+					return io.NopCloser(bytes.NewReader(data)), inner
+				})
+			}
+		}
 		dg.Wait()
 		if err != nil && err != io.EOF {
 			dg.Errors(err)
@@ -275,8 +361,17 @@ func (ms *MigrationService) FinishObject(data *pb.ObjectInfo) error {
 	if statInfo.Size() != data.Size {
 		return fmt.Errorf("file %s size excpect %d, actual %d", data.FileName, data.Size, statInfo.Size())
 	}
-	// add to capacity db
-	pool.ObjectCap.AddCap(data.Size)
+	if Packable(data.Size) {
+		// pack received file as it is, capacity is added by packing
+		if err = PackFile(data.FileName, realPath, data.Size, ""); err != nil {
+			return err
+		}
+		util.LogErr(os.Remove(realPath))
+		util.LogErrWithPre("path-db remove", pool.PathDB.Remove(data.FileName, realPath))
+	} else {
+		// add to capacity db
+		pool.ObjectCap.AddCap(data.Size)
+	}
 	newLoc, ok := pool.Discovery.GetService(pool.Config.Registry.Name, pool.Config.Registry.SID())
 	if !ok {
 		return fmt.Errorf("server unregister yet")
@@ -315,7 +410,36 @@ func (ms *MigrationService) FinishObject(data *pb.ObjectInfo) error {
 	return nil
 }
 
+// AcceptShard returns error if this server holds another shard of the same stripe.
+// shards of a stripe are named as 'hash.index'.
+func (ms *MigrationService) AcceptShard(name string) error {
+	idx := strings.LastIndexByte(name, '.')
+	if idx < 0 {
+		return nil
+	}
+	for _, mp := range pool.DriverManager.GetAllMountPoint() {
+		matches, err := filepath.Glob(filepath.Join(mp, pool.Config.StoragePath, name[:idx+1]+"*"))
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if filepath.Base(m) != name {
+				return fmt.Errorf("server holds shard %s of the same stripe", filepath.Base(m))
+			}
+		}
+	}
+	return pool.PackDB.Range(name[:idx+1], func(packed string, _ db.PackEntry) error {
+		if packed != name {
+			return fmt.Errorf("server holds shard %s of the same stripe", packed)
+		}
+		return nil
+	})
+}
+
 func (ms *MigrationService) OpenFile(name string, size int64) (*os.File, error) {
+	if entry, err := pool.PackDB.Get(name); err == nil && entry.Length == size {
+		return nil, os.ErrExist
+	}
 	path, ok := FindRealStoragePath(name)
 	if !ok {
 		path = filepath.Join(pool.DriverManager.SelectMountPointFallback(pool.Config.BaseMountPoint), pool.Config.StoragePath, name)
@@ -328,6 +452,7 @@ func (ms *MigrationService) OpenFile(name string, size int64) (*os.File, error)
 			return nil, os.ErrExist
 		}
 		// some file may migrate failure. remove it if exists.
+		uncache(name)
 		if err = os.Remove(path); err != nil {
 			return nil, err
 		}
@@ -6,6 +6,7 @@ import (
 	"common/datasize"
 	"common/graceful"
 	"common/logs"
+	"common/proto/msg"
 	"common/response"
 	"common/system/disk"
 	"common/util"
@@ -25,7 +26,11 @@ const (
 
 // Exist check if the object exists. pass to ExistPath
 func Exist(name string) bool {
-	if global.Cache.Has(LocateKeyPrefix+name) || global.Cache.Has(name) {
+	if global.Cache.Has(LocateKeyPrefix + name) {
+		return true
+	}
+	if _, err := global.PackDB.Get(name); err == nil {
+		MarkExist(name)
 		return true
 	}
 	realPath, ok := FindRealStoragePath(name)
@@ -78,18 +83,30 @@ func UnMarkExist(name string) {
 	global.Cache.Delete(LocateKeyPrefix + name)
 }
 
-// Put save object to storage path
-func Put(fileName string, fileStream io.Reader, compress bool) (err error) {
+// Put save object to storage path, compressing it by codec if codec is not empty.
+// objects not larger than Pack.MaxObjectSize are packed into segments if packing is enabled.
+func Put(fileName string, fileStream io.Reader, codec string) (err error) {
 	if Exist(fileName) {
 		return
 	}
+	if global.Config.Pack.Enabled {
+		buf := make([]byte, global.Config.Pack.MaxObjectSize.Byte()+1)
+		n, err := io.ReadFull(fileStream, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return PackObject(fileName, buf[:n], codec)
+		}
+		if err != nil {
+			return err
+		}
+		fileStream = io.MultiReader(bytes.NewReader(buf), fileStream)
+	}
 
 	mp := global.DriverManager.SelectMountPointFallback(global.Config.BaseMountPoint)
 	fullPath := filepath.Join(mp, global.Config.StoragePath, fileName)
 
 	var size int64
-	if compress {
-		size, err = WriteFileCompress(fullPath, fileStream)
+	if codec != "" {
+		size, err = WriteFileCodec(fullPath, fileStream, codec)
 	} else {
 		size, err = WriteFile(fullPath, fileStream)
 	}
@@ -105,15 +122,22 @@ func Put(fileName string, fileStream io.Reader, compress bool) (err error) {
 	return
 }
 
-// Get read object to writer with provided size. pass to GetFile
-func Get(name string, offset, size int64, compress bool, writer io.Writer) (err error) {
+// Get read object to writer with provided size. pass to GetFile or GetFileCodec if codec is not empty.
+// objects admitted by object cache are read from memory or the disk tier of cache.
+func Get(name string, offset, size int64, codec string, writer io.Writer) (err error) {
 	if !Exist(name) {
 		return response.NewError(404, "object not found")
 	}
+	if cached, err := getCached(name, offset, size, codec, writer); cached {
+		return err
+	}
+	if packed, err := GetPacked(name, offset, size, codec, writer); packed {
+		return err
+	}
 
 	fullPath, _ := FindRealStoragePath(name)
-	if compress {
-		err = GetFileCompress(fullPath, offset, size, writer)
+	if codec != "" {
+		err = GetFileCodec(fullPath, offset, size, codec, writer)
 	} else {
 		err = GetFile(fullPath, offset, size, writer)
 	}
@@ -153,9 +177,13 @@ func GetFile(fullPath string, offset, size int64, writer io.Writer) error {
 
 // Delete remove the object under the storage path
 func Delete(name string) error {
+	uncache(name)
 	if !Exist(name) {
 		return nil
 	}
+	if packed, err := DeletePacked(name); packed {
+		return err
+	}
 	fullPath, _ := FindRealStoragePath(name)
 	size, err := DeleteFile(fullPath, "")
 	if err != nil {
@@ -187,7 +215,8 @@ func DeleteFile(path, name string) (int64, error) {
 }
 
 // WriteFileWithSize will append data to file using provided curSize to remove padding of data
-// and work with DIO keeping read data aligned to multiple of 4KB
+// and work with DIO keeping read data aligned to multiple of 4KB.
+// data after curSize is overwritten, which is either padding or left by a failed write.
 func WriteFileWithSize(fullPath string, curSize int64, fileStream io.Reader, bufSize int) (int64, error) {
 	file, err := disk.OpenFileDirectIO(fullPath, os.O_RDWR|os.O_CREATE, cst.OS.ModeUser)
 	if err != nil {
@@ -198,30 +227,24 @@ func WriteFileWithSize(fullPath string, curSize int64, fileStream io.Reader, buf
 	if err != nil {
 		return 0, err
 	}
-	// paddingLen always gte 0 and lt 4096
+	if fi.Size() < curSize {
+		return 0, fmt.Errorf("file size %d is less than written size %d", fi.Size(), curSize)
+	}
+	// writing starts from the page containing the end of data
 	pageSize := int64(cst.OS.PageSize)
-	paddingLen := fi.Size() - curSize
-	if paddingLen >= pageSize {
-		return 0, fmt.Errorf("err padding length %d", paddingLen)
-	}
-	if paddingLen > 0 {
-		// read the last 4KB of data
-		if _, err = file.Seek(-pageSize, io.SeekEnd); err != nil {
-			return 0, err
-		}
-		bt := make([]byte, pageSize)
-		_, err = io.ReadFull(file, bt)
-		if err != nil {
+	offset := curSize / pageSize * pageSize
+	if tailLen := curSize - offset; tailLen > 0 {
+		// read the unaligned tail of data
+		bt := disk.AlignedBlock(int(pageSize))
+		n, err := file.ReadAt(bt, offset)
+		if int64(n) < tailLen {
 			return 0, fmt.Errorf("read tail 4KB err: %w", err)
 		}
-		// remove padding
-		bt = bt[:len(bt)-int(paddingLen)]
 		// concatenation with fileStream
-		fileStream = disk.MultiReader(bytes.NewBuffer(bt), fileStream)
-		// seek back
-		if _, err = file.Seek(-pageSize, io.SeekEnd); err != nil {
-			return 0, err
-		}
+		fileStream = disk.MultiReader(bytes.NewBuffer(bt[:tailLen]), fileStream)
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
 	}
 	return io.CopyBuffer(file, disk.PaddingReader(fileStream), disk.AlignedBlock(bufSize))
 }
@@ -241,24 +264,10 @@ func WriteFile(fullPath string, fileStream io.Reader) (int64, error) {
 	return AppendFileAligned(fullPath, fileStream, 2*cst.OS.PageSize)
 }
 
-// GetFileCompress read s2-compressed file and work with COW
+// GetFileCompress read s2-compressed file and work with COW.
+// it seeks by the index of file if exists, otherwise by skipping data from the beginning.
 func GetFileCompress(fullPath string, offset, size int64, writer io.Writer) error {
-	file, err := os.Open(fullPath)
-	if os.IsNotExist(err) {
-		return response.NewError(404, "object not found")
-	}
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	if offset > 0 {
-		if _, err = file.Seek(offset, io.SeekStart); err != nil {
-			return err
-		}
-	}
-	bufSize := math.MinNumber(8*cst.OS.PageSize, int(size))
-	_, err = io.CopyBuffer(writer, io.LimitReader(s2.NewReader(file), size), make([]byte, bufSize))
-	return err
+	return GetFileCodec(fullPath, offset, size, msg.CodecS2, writer)
 }
 
 // AppendFileCompress append data to file compressing by s2 and work with COW
@@ -282,14 +291,25 @@ func WriteFileCompress(fullPath string, fileStream io.Reader) (int64, error) {
 	return AppendFileCompress(fullPath, fileStream, 2*cst.OS.PageSize)
 }
 
-// CommitFile move the temp file to storage path with a new name
-func CommitFile(mountPoint, tmpName, fileName string, compress bool) error {
+// CommitFile move the temp file to storage path with a new name, compressing it by codec if codec is not empty.
+// small objects are packed into segments if packing is enabled, whose size is known by temp info.
+func CommitFile(mountPoint, tmpName, fileName string, codec string) error {
 	filePath := filepath.Join(mountPoint, global.Config.StoragePath, fileName)
 	tempPath := filepath.Join(mountPoint, global.Config.TempPath, tmpName)
 	if ExistPath(filePath) {
 		return nil
 	}
-	if compress {
+	if _, err := global.PackDB.Get(fileName); err == nil {
+		return nil
+	}
+	if ti, ok := GetTempInfo(tmpName); ok && Packable(ti.Size) {
+		if err := PackFile(fileName, tempPath, ti.Size, codec); err != nil {
+			return err
+		}
+		util.LogErr(os.Remove(tempPath))
+		return nil
+	}
+	if codec != "" {
 		tmp, err := os.Open(tempPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -298,12 +318,7 @@ func CommitFile(mountPoint, tmpName, fileName string, compress bool) error {
 			return err
 		}
 		defer util.CloseAndLog(tmp)
-		tmpStat, err := tmp.Stat()
-		if err != nil {
-			return err
-		}
-		bufSize := math.MinInt(4*datasize.MB.Int(), int(tmpStat.Size()))
-		_, err = AppendFileCompress(filePath, tmp, bufSize)
+		_, err = WriteFileCodec(filePath, tmp, codec)
 		return err
 	} else {
 		if err := os.Rename(tempPath, filePath); err != nil {
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"common/cst"
+	"common/graceful"
+	"common/logs"
+	"common/response"
+	"common/util"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"objectserver/internal/db"
+	global "objectserver/internal/usecase/pool"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// a record in segment is a header followed by name and data of the object.
+// header is type(1B) + length of name(2B) + length of data(8B) + crc32 of data(4B).
+// the index is the only source of live objects, deleting an object only removes it from index,
+// so a put record not referenced by index is garbage.
+const (
+	RecordPut        byte = 1
+	RecordHeaderSize      = 15
+	SegmentPrefix         = "seg-"
+)
+
+var (
+	packLog = logs.New("pack-service")
+
+	segMux   sync.Mutex
+	segments = map[string]*segment{} // segments are active segments of each segment dir
+)
+
+// segment is a file which records of small objects are appended to until it's full
+type segment struct {
+	mux  sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// EncodeRecord returns bytes of a record in segment
+func EncodeRecord(typ byte, name string, data []byte) []byte {
+	bt := make([]byte, RecordHeaderSize, RecordHeaderSize+len(name)+len(data))
+	bt[0] = typ
+	binary.BigEndian.PutUint16(bt[1:], uint16(len(name)))
+	binary.BigEndian.PutUint64(bt[3:], uint64(len(data)))
+	binary.BigEndian.PutUint32(bt[11:], crc32.ChecksumIEEE(data))
+	return append(append(bt, name...), data...)
+}
+
+// DecodeRecord reads the next record from segment, returns io.EOF if there are no more records
+func DecodeRecord(rd io.Reader) (typ byte, name string, data []byte, err error) {
+	header := make([]byte, RecordHeaderSize)
+	if _, err = io.ReadFull(rd, header); err != nil {
+		return
+	}
+	typ = header[0]
+	body := make([]byte, int(binary.BigEndian.Uint16(header[1:]))+int(binary.BigEndian.Uint64(header[3:])))
+	if _, err = io.ReadFull(rd, body); err != nil {
+		return 0, "", nil, fmt.Errorf("broken record: %w", err)
+	}
+	name = string(body[:binary.BigEndian.Uint16(header[1:])])
+	data = body[len(name):]
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[11:]) {
+		return 0, "", nil, fmt.Errorf("checksum of record %s mismatch", name)
+	}
+	return
+}
+
+// recordSize returns size of the record of a packed object
+func recordSize(name string, entry db.PackEntry) int64 {
+	return RecordHeaderSize + int64(len(name)) + entry.Length
+}
+
+// Packable returns true if objects of size should be packed into segments
+func Packable(size int64) bool {
+	return global.Config.Pack.Enabled && size >= 0 && uint64(size) <= global.Config.Pack.MaxObjectSize.Byte()
+}
+
+// appendRecord appends a record to the active segment under dir, rolls to a new segment if it's full
+func appendRecord(dir string, typ byte, name string, data []byte) (db.PackEntry, error) {
+	segMux.Lock()
+	seg := segments[dir]
+	if seg == nil || seg.size >= int64(global.Config.Pack.SegmentSize.Byte()) {
+		path := filepath.Join(dir, fmt.Sprint(SegmentPrefix, time.Now().UnixNano()))
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_EXCL, cst.OS.ModeUser)
+		if err != nil {
+			segMux.Unlock()
+			return db.PackEntry{}, err
+		}
+		if seg != nil {
+			seg.mux.Lock()
+			util.LogErrWithPre("close segment", seg.file.Close())
+			seg.mux.Unlock()
+		}
+		seg = &segment{path: path, file: file}
+		segments[dir] = seg
+	}
+	seg.mux.Lock()
+	segMux.Unlock()
+	defer seg.mux.Unlock()
+	record := EncodeRecord(typ, name, data)
+	_, err := seg.file.Write(record)
+	if err == nil {
+		// the record must be durable before it's indexed, or the index may refer to data lost on crash
+		err = seg.file.Sync()
+	}
+	if err != nil {
+		// a broken record may be left, roll to a new segment at next time
+		seg.size = int64(global.Config.Pack.SegmentSize.Byte())
+		return db.PackEntry{}, err
+	}
+	entry := db.PackEntry{
+		Segment:  seg.path,
+		Offset:   seg.size + RecordHeaderSize + int64(len(name)),
+		Length:   int64(len(data)),
+		Checksum: crc32.ChecksumIEEE(data),
+	}
+	seg.size += int64(len(record))
+	return entry, nil
+}
+
+// activeSegments returns paths of segments being appended
+func activeSegments() map[string]bool {
+	segMux.Lock()
+	defer segMux.Unlock()
+	res := make(map[string]bool, len(segments))
+	for _, seg := range segments {
+		res[seg.path] = true
+	}
+	return res
+}
+
+// PackObject appends an object to a segment, compressing it by codec if codec is not empty
+func PackObject(name string, data []byte, codec string) error {
+	if codec != "" {
+		cd, err := GetCodec(codec)
+		if err != nil {
+			return err
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+		if _, err = cd.Write(buf, bytes.NewReader(data)); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	mp := global.DriverManager.SelectMountPointFallback(global.Config.BaseMountPoint)
+	entry, err := appendRecord(filepath.Join(mp, global.Config.SegmentPath), RecordPut, name, data)
+	if err != nil {
+		return err
+	}
+	if err = global.PackDB.Put(name, entry); err != nil {
+		return err
+	}
+	global.ObjectCap.AddCap(entry.Length)
+	MarkExist(name)
+	return nil
+}
+
+// PackFile packs the first size bytes of file as an object, compressing it by codec if codec is not empty
+func PackFile(name, fullPath string, size int64, codec string) error {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return response.NewError(404, "object not found")
+		}
+		return err
+	}
+	defer file.Close()
+	data := make([]byte, size)
+	if _, err = io.ReadFull(file, data); err != nil {
+		return err
+	}
+	return PackObject(name, data, codec)
+}
+
+// ReadPacked returns stored data of a packed object, returns false if it's not packed
+func ReadPacked(name string) ([]byte, bool, error) {
+	for retry := 0; ; retry++ {
+		entry, err := global.PackDB.Get(name)
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		file, err := os.Open(entry.Segment)
+		// segment may be removed by compaction, read index again
+		if os.IsNotExist(err) && retry == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		defer file.Close()
+		data := make([]byte, entry.Length)
+		if _, err = file.ReadAt(data, entry.Offset); err != nil {
+			return nil, true, fmt.Errorf("read packed %s: %w", name, err)
+		}
+		if crc32.ChecksumIEEE(data) != entry.Checksum {
+			return nil, true, fmt.Errorf("checksum of packed %s mismatch", name)
+		}
+		return data, true, nil
+	}
+}
+
+// GetPacked read a packed object to writer with provided size, returns false if it's not packed
+func GetPacked(name string, offset, size int64, codec string, writer io.Writer) (bool, error) {
+	data, ok, err := ReadPacked(name)
+	if !ok || err != nil {
+		return ok, err
+	}
+	return true, writeStored(data, offset, size, codec, writer)
+}
+
+// DeletePacked removes a packed object from index, its record is reclaimed by compaction. returns false if it's not packed
+func DeletePacked(name string) (bool, error) {
+	entry, err := global.PackDB.Get(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	if err = global.PackDB.Remove(name); err != nil {
+		return true, err
+	}
+	global.ObjectCap.SubCap(entry.Length)
+	UnMarkExist(name)
+	return true, nil
+}
+
+// StartPackCompaction compacts segments periodically. return cancel function.
+func StartPackCompaction() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer graceful.Recover()
+		for {
+			select {
+			case <-ctx.Done():
+				packLog.Info("stop segment compaction")
+				return
+			case <-time.After(global.Config.Pack.CompactInterval):
+			}
+			if err := CompactSegments(ctx); err != nil {
+				packLog.Errorf("compact segments err: %s", err)
+			}
+		}
+	}()
+	return cancel
+}
+
+// CompactSegments rewrites sealed segments whose ratio of garbage reaches Pack.GarbageRatio.
+// live objects are appended to active segments and the old segments are removed.
+func CompactSegments(ctx context.Context) error {
+	live := make(map[string]int64)
+	if err := global.PackDB.Range("", func(name string, entry db.PackEntry) error {
+		live[entry.Segment] += recordSize(name, entry)
+		return nil
+	}); err != nil {
+		return err
+	}
+	active := activeSegments()
+	var errs []error
+	for _, mp := range global.DriverManager.GetAllMountPoint() {
+		matches, err := filepath.Glob(filepath.Join(mp, global.Config.SegmentPath, SegmentPrefix+"*"))
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if active[path] {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if info.Size() > 0 && 1-float64(live[path])/float64(info.Size()) < global.Config.Pack.GarbageRatio {
+				continue
+			}
+			if err = compactSegment(path); err != nil {
+				errs = append(errs, fmt.Errorf("compact %s: %w", path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compactSegment moves live objects of segment to the active segment of the same dir then removes it.
+// records are scanned in order, put records still referenced by index are live.
+func compactSegment(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rd := bufio.NewReader(file)
+	var offset int64
+	var moved int
+	for {
+		typ, name, data, err := DecodeRecord(rd)
+		// a broken record may be left at the end of segment if appending failed
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		from := db.PackEntry{
+			Segment:  path,
+			Offset:   offset + RecordHeaderSize + int64(len(name)),
+			Length:   int64(len(data)),
+			Checksum: crc32.ChecksumIEEE(data),
+		}
+		offset = from.Offset + from.Length
+		if typ != RecordPut {
+			continue
+		}
+		if cur, err := global.PackDB.Get(name); err != nil || cur != from {
+			continue
+		}
+		to, err := appendRecord(filepath.Dir(path), RecordPut, name, data)
+		if err != nil {
+			return err
+		}
+		// the object may be deleted during compaction, the copied record is garbage then
+		ok, err := global.PackDB.Move(name, from, to)
+		if err != nil {
+			return err
+		}
+		if ok {
+			moved++
+		}
+	}
+	if err = os.Remove(path); err != nil {
+		return err
+	}
+	packLog.Infof("compact segment %s, %d objects moved", path, moved)
+	return nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"common/balance"
+	"common/cst"
+	"common/graceful"
+	"common/logs"
+	"common/proto"
+	"common/proto/pb"
+	"common/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"objectserver/internal/usecase/pool"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"google.golang.org/grpc"
+)
+
+var rbLog = logs.New("rebalance-service")
+
+// RebalanceService coordinates moving shards between object servers continuously.
+// only the leader elected via etcd plans and executes moves, while plan and progress are saved to etcd.
+type RebalanceService struct {
+	migration *MigrationService
+}
+
+func NewRebalanceService(ms *MigrationService) *RebalanceService {
+	return &RebalanceService{migration: ms}
+}
+
+// StartAutoRebalance campaigns for the coordinator and rebalances periodically after elected.
+func (rs *RebalanceService) StartAutoRebalance() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !pool.Config.Rebalance.Enabled {
+		return cancel
+	}
+	go func() {
+		defer graceful.Recover()
+		for {
+			if err := rs.campaign(ctx); err != nil && ctx.Err() == nil {
+				rbLog.Warnf("rebalance coordinator err: %s", err)
+			}
+			select {
+			case <-ctx.Done():
+				rbLog.Info("stop auto rebalance")
+				return
+			case <-time.After(pool.Config.Rebalance.Interval):
+			}
+		}
+	}()
+	return cancel
+}
+
+func (rs *RebalanceService) campaign(ctx context.Context) error {
+	sess, err := concurrency.NewSession(pool.Etcd, concurrency.WithTTL(15))
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	self, ok := pool.Discovery.GetService(pool.Config.Registry.Name, pool.Config.Registry.SID())
+	if !ok {
+		return errors.New("server unregister yet")
+	}
+	el := concurrency.NewElection(sess, cst.EtcdPrefix.FmtRebalance(pool.Config.Registry.Group, "leader"))
+	if err = el.Campaign(ctx, self); err != nil {
+		return err
+	}
+	defer func() { util.LogErr(el.Resign(context.Background())) }()
+	rbLog.Infof("%s becomes rebalance coordinator", self)
+	tk := time.NewTicker(pool.Config.Rebalance.Interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sess.Done():
+			return errors.New("coordinator session expired")
+		case <-tk.C:
+			if err = rs.RunOnce(ctx, self); err != nil {
+				rbLog.Errorf("rebalance err: %s", err)
+			}
+		}
+	}
+}
+
+// IsPaused reports whether rebalancing is paused by admin
+func (rs *RebalanceService) IsPaused(ctx context.Context) (bool, error) {
+	resp, err := pool.Etcd.Get(ctx, cst.EtcdPrefix.FmtRebalance(pool.Config.Registry.Group, "paused"))
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Kvs) > 0, nil
+}
+
+func (rs *RebalanceService) savePlan(ctx context.Context, plan *balance.Plan) {
+	plan.UpdatedAt = time.Now()
+	bt, err := json.Marshal(plan)
+	if err != nil {
+		rbLog.Errorf("marshal rebalance plan err: %s", err)
+		return
+	}
+	_, err = pool.Etcd.Put(ctx, cst.EtcdPrefix.FmtRebalance(pool.Config.Registry.Group, "plan"), string(bt))
+	util.LogErrWithPre("save rebalance plan", err)
+}
+
+// Plan computes moves according to storage state of all servers
+func (rs *RebalanceService) Plan(self string) *balance.Plan {
+	conf := &pool.Config.Rebalance
+	peers := rs.migration.getPeersStat()
+	addrs := make([]string, 0, len(peers)+1)
+	nodes := make([]balance.Node, 0, len(peers)+1)
+	for k, v := range peers {
+		addrs = append(addrs, k)
+		nodes = append(nodes, v)
+	}
+	addrs = append(addrs, self)
+	nodes = append(nodes, rs.migration.selfStat())
+	return &balance.Plan{
+		Leader:    self,
+		Imbalance: balance.Imbalance(nodes),
+		Moves:     balance.PlanMoves(addrs, nodes, conf.Threshold, int64(conf.MaxMoveSize)),
+		CreatedAt: time.Now(),
+	}
+}
+
+// RunOnce plans and executes moves one by one. remaining moves will be abandoned if paused.
+func (rs *RebalanceService) RunOnce(ctx context.Context, self string) error {
+	if paused, err := rs.IsPaused(ctx); err != nil {
+		return err
+	} else if paused {
+		rbLog.Debug("rebalance is paused, skip")
+		return nil
+	}
+	plan := rs.Plan(self)
+	rs.savePlan(ctx, plan)
+	if len(plan.Moves) == 0 {
+		rbLog.Debugf("imbalance %.4f is acceptable, skip", plan.Imbalance)
+		return nil
+	}
+	rbLog.Infof("imbalance %.4f, start %d moves", plan.Imbalance, len(plan.Moves))
+	for _, mv := range plan.Moves {
+		paused, err := rs.IsPaused(ctx)
+		if err != nil {
+			return err
+		}
+		if paused || ctx.Err() != nil {
+			plan.Paused = true
+			rs.savePlan(context.Background(), plan)
+			rbLog.Info("rebalance paused")
+			return nil
+		}
+		mv.State = balance.MoveRunning
+		rs.savePlan(ctx, plan)
+		if err = rs.move(ctx, mv); err != nil {
+			mv.State, mv.Error = balance.MoveFailed, err.Error()
+			rbLog.Errorf("move %dB from %s to %s err: %s", mv.Size, mv.From, mv.To, err)
+		} else {
+			mv.State = balance.MoveDone
+		}
+		rs.savePlan(ctx, plan)
+	}
+	return nil
+}
+
+// move requires server From to send shards to server To
+func (rs *RebalanceService) move(ctx context.Context, mv *balance.Move) error {
+	cc, err := grpc.Dial(mv.From, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer util.CloseAndLog(cc)
+	if _, err = proto.ResolveResponse(pb.NewObjectMigrationClient(cc).RequireSend(ctx, &pb.RequiredInfo{
+		RequiredSize:  mv.Size,
+		TargetAddress: mv.To,
+		RateLimit:     int64(pool.Config.Rebalance.RateLimit),
+	})); err != nil {
+		return fmt.Errorf("require send err: %w", err)
+	}
+	return nil
+}
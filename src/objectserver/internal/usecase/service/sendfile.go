@@ -0,0 +1,88 @@
+package service
+
+import (
+	"common/response"
+	"common/util"
+	"common/util/math"
+	"io"
+	"net/http"
+	global "objectserver/internal/usecase/pool"
+	"os"
+)
+
+// ZeroCopy returns true if reading size bytes of an object compressed by codec should try SendFile.
+// compressed objects must be decoded in user space and small objects are buffered for caching.
+func ZeroCopy(codec string, size int64) bool {
+	return global.Config.ZeroCopy && codec == "" && uint64(size) > global.Config.Cache.MaxItemSize.Byte()
+}
+
+// SendObject sends object through SendFile. returns false without writing anything if it's impossible.
+func SendObject(name string, offset, size int64, w http.ResponseWriter, r *http.Request) (bool, error) {
+	if !Exist(name) {
+		return false, response.NewError(404, "object not found")
+	}
+	// packed objects are read from segments
+	if _, err := global.PackDB.Get(name); err == nil {
+		return false, nil
+	}
+	fullPath, _ := FindRealStoragePath(name)
+	ok, err := SendFile(fullPath, offset, size, w, r)
+	if ok && err == nil {
+		MarkExist(name)
+	}
+	return ok, err
+}
+
+// SendFile writes at most size bytes of file from offset to the response, the same as GetFile but data is copied
+// by sendfile(2) or splice(2) in kernel. it's only possible for http/1.x responses on plain tcp connections,
+// returns false without writing anything otherwise, GetFile should be used instead.
+// the file is read through page cache because direct-io is not supported by sendfile.
+func SendFile(fullPath string, offset, size int64, w http.ResponseWriter, r *http.Request) (bool, error) {
+	if r.TLS != nil || r.ProtoMajor != 1 {
+		return false, nil
+	}
+	rf, ok := unwrapResponse(w).(io.ReaderFrom)
+	if !ok {
+		return false, nil
+	}
+	file, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		return false, response.NewError(404, "object not found")
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	// files may be padded for direct-io, so size is required
+	n := math.MinNumber(info.Size()-offset, size)
+	if n <= 0 {
+		return false, response.NewError(416, "offset out of range")
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	// content-length disables chunked encoding which prevents sendfile
+	w.Header().Set("Content-Length", util.IntString(n))
+	if gw, ok := w.(interface{ WriteHeaderNow() }); ok {
+		gw.WriteHeaderNow()
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_, err = rf.ReadFrom(io.LimitReader(file, n))
+	return true, err
+}
+
+// unwrapResponse returns the http.ResponseWriter of net/http wrapped by frameworks
+func unwrapResponse(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = u.Unwrap()
+	}
+}
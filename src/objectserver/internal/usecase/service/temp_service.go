@@ -4,12 +4,17 @@ import (
 	"common/cache"
 	"common/graceful"
 	"common/logs"
+	"common/response"
 	"common/util"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
+	"net/http"
 	"objectserver/internal/entity"
 	"objectserver/internal/usecase/pool"
+	"path/filepath"
 	"strings"
 )
 
@@ -25,6 +30,49 @@ func RemoveTempInfo(key string) {
 	pool.Cache.Delete(key)
 }
 
+// NewTempInfo creates temp info of an object which will be uploaded to a temp file
+func NewTempInfo(name string, size int64) (*entity.TempInfo, bool) {
+	tmpInfo := &entity.TempInfo{
+		Name:       name,
+		Size:       size,
+		Id:         GenerateTempID(),
+		MountPoint: pool.DriverManager.SelectMountPointFallback(pool.Config.BaseMountPoint),
+	}
+	tmpInfo.FullPath = filepath.Join(tmpInfo.MountPoint, pool.Config.TempPath, tmpInfo.Id)
+	return tmpInfo, SetTempInfo(tmpInfo)
+}
+
+// AppendTemp appends data of stream to the temp object after data written before, which drops padding of the last append.
+// data exceeding the size of temp object fails appending. written size is saved only if appending succeeds,
+// so that a failed append is overwritten by resuming from it.
+func AppendTemp(ti *entity.TempInfo, stream io.Reader, bufSize int) (int64, error) {
+	rd := &tempReader{r: stream, remain: ti.Size - ti.Written}
+	if _, err := WriteFileWithSize(ti.FullPath, ti.Written, rd, bufSize); err != nil {
+		return rd.n, err
+	}
+	ti.Written += rd.n
+	if !SetTempInfo(ti) {
+		return rd.n, errors.New("update temp info fail")
+	}
+	return rd.n, nil
+}
+
+// tempReader counts data read and fails if it exceeds remain
+type tempReader struct {
+	r      io.Reader
+	remain int64
+	n      int64
+}
+
+func (t *tempReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.n += int64(n)
+	if t.n > t.remain {
+		return n, response.NewError(http.StatusBadRequest, fmt.Sprintf("data exceeds the size of temp object by %d bytes", t.n-t.remain))
+	}
+	return n, err
+}
+
 func GenerateTempID() string {
 	return entity.TempKeyPrefix + uuid.NewString()
 }
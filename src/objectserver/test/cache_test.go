@@ -0,0 +1,62 @@
+package test
+
+import (
+	"bytes"
+	"common/cache"
+	"common/datasize"
+	"common/performance"
+	"common/response"
+	"objectserver/internal/usecase/pool"
+	. "objectserver/internal/usecase/service"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordCollector records actions put
+type recordCollector struct {
+	performance.Collector
+	mux     sync.Mutex
+	actions map[string]int
+}
+
+func (r *recordCollector) PutAsync(action string, kindOf string, _ time.Duration) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.actions[kindOf+"-"+action]++
+}
+
+func (r *recordCollector) count(action string) int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.actions[performance.KindOfCache+"-"+action]
+}
+
+func TestWarmCache(t *testing.T) {
+	initPack(t, datasize.MB)
+	objectCache, err := cache.NewTiered(cache.TieredConfig{MaxSize: 1 << 20, MaxItemSize: 1 << 10})
+	assert.NoError(t, err)
+	collector := &recordCollector{actions: map[string]int{}}
+	pool.ObjectCache, pool.Perform = objectCache, collector
+	defer func() { pool.ObjectCache, pool.Perform = nil, nil }()
+	assert.NoError(t, PackObject("warm.0", []byte("warm object"), ""))
+	assert.NoError(t, PackObject("cold.0", []byte("cold object"), ""))
+
+	// warming all objects is rejected
+	err = WarmCache("")
+	assert.True(t, response.CheckErrStatus(400, err))
+	assert.NoError(t, WarmCache("warm"))
+	assert.Eventually(t, func() bool { return objectCache.Stats().MemItems == 1 }, time.Second, 10*time.Millisecond)
+
+	// hits and misses are fed into performance collector
+	buf := new(bytes.Buffer)
+	assert.NoError(t, Get("warm.0", 0, 11, "", buf))
+	assert.Equal(t, "warm object", buf.String())
+	buf.Reset()
+	assert.NoError(t, Get("cold.0", 0, 11, "", buf))
+	assert.Equal(t, "cold object", buf.String())
+	assert.Equal(t, 1, collector.count(performance.ActionHit))
+	assert.Equal(t, 1, collector.count(performance.ActionMiss))
+}
@@ -222,7 +222,7 @@ func TestCommitFile(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	if err = CommitFile("E:", "new_file", "new_file_compress", true); err != nil {
+	if err = CommitFile("E:", "new_file", "new_file_compress", "s2"); err != nil {
 		t.Error(err)
 		return
 	}
@@ -246,3 +246,41 @@ func TestCommitFile(t *testing.T) {
 	assert.New(t).Equal(snd, numOfB)
 	assert.New(t).Equal(0, numOfOther)
 }
+
+func TestFileCodecSeek(t *testing.T) {
+	defer func() {
+		_ = os.Remove("./codec_file")
+	}()
+	bt := make([]byte, 5*1024*1024+123)
+	for i := range bt {
+		bt[i] = byte(i % 251)
+	}
+	for _, codec := range []string{"s2", "zstd"} {
+		n, err := WriteFileCodec("./codec_file", bytes.NewReader(bt), codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.New(t).Equal(len(bt), int(n))
+		for _, rg := range [][2]int{{0, len(bt)}, {3*1024*1024 + 7, 4096}, {len(bt) - 10, 100}} {
+			buf := bytes.NewBuffer(nil)
+			if err = GetFileCodec("./codec_file", int64(rg[0]), int64(rg[1]), codec, buf); err != nil {
+				t.Fatal(codec, err)
+			}
+			end := rg[0] + rg[1]
+			if end > len(bt) {
+				end = len(bt)
+			}
+			assert.New(t).Equal(bt[rg[0]:end], buf.Bytes(), codec)
+		}
+	}
+	// s2 stream without index is readable by skipping
+	_ = os.Remove("./codec_file")
+	if _, err := WriteFileCompress("./codec_file", bytes.NewReader(bt)); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := GetFileCompress("./codec_file", 1024*1024+1, 10, buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.New(t).Equal(bt[1024*1024+1:1024*1024+11], buf.Bytes())
+}
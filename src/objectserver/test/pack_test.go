@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"common/cache"
+	"common/datasize"
+	"context"
+	"fmt"
+	"io"
+	"objectserver/config"
+	"objectserver/internal/db"
+	"objectserver/internal/usecase/component"
+	"objectserver/internal/usecase/pool"
+	. "objectserver/internal/usecase/service"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentRecord(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(EncodeRecord(RecordPut, "hash.1", []byte("small object")))
+	buf.Write(EncodeRecord(RecordPut, "hash.2", nil))
+	// a broken record left by failed appending
+	buf.Write(EncodeRecord(RecordPut, "hash.2", []byte("broken"))[:RecordHeaderSize+3])
+	typ, name, data, err := DecodeRecord(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordPut, typ)
+	assert.Equal(t, "hash.1", name)
+	assert.Equal(t, "small object", string(data))
+	typ, name, data, err = DecodeRecord(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordPut, typ)
+	assert.Equal(t, "hash.2", name)
+	assert.Empty(t, data)
+	_, _, _, err = DecodeRecord(buf)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, _, _, err = DecodeRecord(buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// initPack sets up packing into segments under a temp dir of the root mount point
+func initPack(t *testing.T, segmentSize datasize.DataSize) {
+	dir := t.TempDir()
+	pool.Config = &config.Config{BaseMountPoint: "/"}
+	pool.Config.SegmentPath = filepath.Join(dir, "segments")
+	pool.Config.Pack = config.PackConfig{Enabled: true, MaxObjectSize: 64 * datasize.KB, SegmentSize: segmentSize, GarbageRatio: 0.5}
+	assert.NoError(t, os.MkdirAll(pool.Config.SegmentPath, 0700))
+	pool.DriverManager = component.NewDriverManager(component.SpaceFirstBalancer())
+	pool.DriverManager.Includes.Add("/")
+	pool.DriverManager.Update()
+	pc, err := db.NewPathCache(filepath.Join(dir, "path-db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+	pool.PathDB, pool.PackDB = pc, db.NewPackIndex(pc)
+	pool.ObjectCap = db.NewObjectCapacity()
+	if pool.Cache == nil {
+		pool.Cache = cache.NewCache(bigcache.DefaultConfig(time.Minute))
+	}
+}
+
+func TestPackObject(t *testing.T) {
+	initPack(t, datasize.MB)
+	assert.NoError(t, PackObject("hash.0", []byte("packed object"), ""))
+	assert.NoError(t, PackObject("hash.1", []byte("another one"), ""))
+	data, ok, err := ReadPacked("hash.0")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "packed object", string(data))
+	buf := new(bytes.Buffer)
+	ok, err = GetPacked("hash.1", 8, 3, "", buf)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "one", buf.String())
+	assert.EqualValues(t, len("packed object")+len("another one"), pool.ObjectCap.Capacity())
+
+	// deleting only removes the index, nothing is appended to segment
+	entry, err := pool.PackDB.Get("hash.0")
+	assert.NoError(t, err)
+	before, err := os.Stat(entry.Segment)
+	assert.NoError(t, err)
+	ok, err = DeletePacked("hash.0")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	after, err := os.Stat(entry.Segment)
+	assert.NoError(t, err)
+	assert.Equal(t, before.Size(), after.Size())
+	_, ok, err = ReadPacked("hash.0")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = DeletePacked("hash.0")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.EqualValues(t, len("another one"), pool.ObjectCap.Capacity())
+}
+
+func TestCompactSegmentsRace(t *testing.T) {
+	// every segment holds a few records
+	initPack(t, 256)
+	name := func(i int) string { return fmt.Sprint("hash.", i) }
+	content := func(i int) []byte { return bytes.Repeat([]byte{byte(i)}, 100) }
+	for i := 0; i < 60; i++ {
+		assert.NoError(t, PackObject(name(i), content(i), ""))
+	}
+	// objects of multiples of 3 are deleted before compaction, others of odd numbers are deleted during it
+	for i := 0; i < 60; i += 3 {
+		_, err := DeletePacked(name(i))
+		assert.NoError(t, err)
+	}
+	segmentsOf := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(pool.Config.SegmentPath, SegmentPrefix+"*"))
+		return matches
+	}
+	before := len(segmentsOf())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i < 60; i += 2 {
+			if i%3 != 0 {
+				_, err := DeletePacked(name(i))
+				assert.NoError(t, err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		// objects kept are always readable while segments are removed
+		for round := 0; round < 5; round++ {
+			for i := 2; i < 60; i += 2 {
+				if i%3 == 0 {
+					continue
+				}
+				data, ok, err := ReadPacked(name(i))
+				assert.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, content(i), data)
+			}
+		}
+	}()
+	assert.NoError(t, CompactSegments(context.Background()))
+	wg.Wait()
+	assert.NoError(t, CompactSegments(context.Background()))
+
+	for i := 0; i < 60; i++ {
+		data, ok, err := ReadPacked(name(i))
+		assert.NoError(t, err)
+		if i%3 == 0 || i%2 == 1 {
+			assert.False(t, ok, "deleted %s is read", name(i))
+			continue
+		}
+		assert.True(t, ok)
+		assert.Equal(t, content(i), data)
+	}
+	assert.Less(t, len(segmentsOf()), before)
+	// index only refers to segments existing
+	assert.NoError(t, pool.PackDB.Range("", func(name string, entry db.PackEntry) error {
+		_, err := os.Stat(entry.Segment)
+		return err
+	}))
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package test
+
+import (
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	. "objectserver/internal/usecase/service"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const benchFileSize = 64 << 20
+
+// BenchmarkReadObject compares reading a large uncompressed shard over http by user-space copying (GetFile)
+// and by sendfile (SendFile). cpu-ms/GB is the cpu time of the whole process, both server and client, per GB sent.
+//
+//	go test -run NONE -bench ReadObject ./test
+func BenchmarkReadObject(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "bench-object")
+	data := make([]byte, benchFileSize)
+	_, _ = rand.Read(data)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("copy", func(b *testing.B) {
+		benchReadObject(b, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(benchFileSize))
+			if err := GetFile(path, 0, benchFileSize, w); err != nil {
+				b.Error(err)
+			}
+		})
+	})
+	b.Run("sendfile", func(b *testing.B) {
+		benchReadObject(b, func(w http.ResponseWriter, r *http.Request) {
+			if sent, err := SendFile(path, 0, benchFileSize, w, r); !sent || err != nil {
+				b.Error("sendfile fails", err)
+			}
+		})
+	})
+}
+
+func benchReadObject(b *testing.B, handler http.HandlerFunc) {
+	serv := httptest.NewServer(handler)
+	defer serv.Close()
+	b.SetBytes(benchFileSize)
+	b.ResetTimer()
+	start := cpuTime()
+	for i := 0; i < b.N; i++ {
+		resp, err := http.Get(serv.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err != nil || n != benchFileSize {
+			b.Fatal("read", n, err)
+		}
+	}
+	b.StopTimer()
+	gb := float64(b.N) * benchFileSize / (1 << 30)
+	b.ReportMetric(float64((cpuTime()-start).Milliseconds())/gb, "cpu-ms/GB")
+}
+
+func cpuTime() time.Duration {
+	var ru syscall.Rusage
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &ru)
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
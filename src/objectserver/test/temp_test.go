@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bytes"
+	"common/cache"
+	"common/proto/pb"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"objectserver/internal/controller/grpc"
+	"objectserver/internal/entity"
+	"objectserver/internal/usecase/pool"
+	. "objectserver/internal/usecase/service"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/stretchr/testify/assert"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTemp saves temp info of a new temp object in size
+func newTemp(t *testing.T, size int64) *entity.TempInfo {
+	if pool.Cache == nil {
+		pool.Cache = cache.NewCache(bigcache.DefaultConfig(time.Minute))
+	}
+	ti := &entity.TempInfo{Name: "hash.0", Id: GenerateTempID(), Size: size, FullPath: filepath.Join(t.TempDir(), "temp")}
+	assert.True(t, SetTempInfo(ti))
+	return ti
+}
+
+// failedReader returns err after data
+type failedReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failedReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestAppendTemp(t *testing.T) {
+	ti := newTemp(t, 10000)
+	appendData := func(r io.Reader) error {
+		ti, _ = GetTempInfo(ti.Id)
+		_, err := AppendTemp(ti, r, 8<<10)
+		return err
+	}
+	// appends of unaligned size don't leave padding in data
+	assert.NoError(t, appendData(bytes.NewReader(bytes.Repeat([]byte("A"), 5000))))
+	assert.NoError(t, appendData(bytes.NewReader(bytes.Repeat([]byte("B"), 3000))))
+	// data of a failed append is overwritten by resuming
+	errBroken := errors.New("broken")
+	assert.ErrorIs(t, appendData(&failedReader{data: bytes.Repeat([]byte("X"), 1500), err: errBroken}), errBroken)
+	assert.NoError(t, appendData(bytes.NewReader(bytes.Repeat([]byte("C"), 2000))))
+	// data exceeding size is rejected
+	assert.Error(t, appendData(bytes.NewReader([]byte("D"))))
+
+	ti, _ = GetTempInfo(ti.Id)
+	assert.EqualValues(t, 10000, ti.Written)
+	bt, err := os.ReadFile(ti.FullPath)
+	assert.NoError(t, err)
+	want := bytes.Join([][]byte{bytes.Repeat([]byte("A"), 5000), bytes.Repeat([]byte("B"), 3000), bytes.Repeat([]byte("C"), 2000)}, nil)
+	assert.Equal(t, want, bt[:10000])
+}
+
+func TestWriteShardSize(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	server := rpc.NewServer()
+	pb.RegisterObjectStreamServer(server, grpc.NewStreamServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+	conn, err := rpc.Dial("bufnet", rpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}), rpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// write sends data to temp object ti and the last message, returns the status code of stream
+	write := func(ti *entity.TempInfo, data []byte, last *pb.ShardWrite) codes.Code {
+		stream, err := pb.NewObjectStreamClient(conn).WriteShard(context.Background())
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Send(&pb.ShardWrite{TmpId: ti.Id}))
+		_, err = stream.Recv()
+		assert.NoError(t, err)
+		_ = stream.Send(&pb.ShardWrite{Data: data})
+		_ = stream.Send(last)
+		_ = stream.CloseSend()
+		_, err = stream.Recv()
+		return status.Code(err)
+	}
+	ti := newTemp(t, 100)
+	assert.Equal(t, codes.InvalidArgument, write(ti, make([]byte, 101), &pb.ShardWrite{}))
+	// a shard is committed only if all of its data has been written
+	assert.Equal(t, codes.FailedPrecondition, write(ti, make([]byte, 60), &pb.ShardWrite{Commit: true}))
+	assert.Equal(t, codes.OK, write(ti, make([]byte, 40), &pb.ShardWrite{Abort: true}))
+	_, ok := GetTempInfo(ti.Id)
+	assert.False(t, ok)
+}